@@ -0,0 +1,12 @@
+// Package benchmarks measures the Unit of Work against a real PostgreSQL server
+//
+// The suite lives in its own module so testcontainers stays out of the library's
+// dependency graph. A container is started once per run; set UOW_BENCH_HOST and
+// UOW_BENCH_PORT to benchmark an existing database instead (e.g. the one started
+// by make db-up). Database benchmarks are skipped when neither is available.
+// UOW_BENCH_DRIVER=sqlite runs them on a SQLite file instead, without Docker; its
+// results carry a "driver: sqlite" line and are not compared with PostgreSQL runs.
+//
+// Record a baseline with make bench-baseline and compare a change against it
+// with make bench-compare (requires golang.org/x/perf/cmd/benchstat).
+package benchmarks
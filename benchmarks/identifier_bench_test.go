@@ -0,0 +1,52 @@
+package benchmarks
+
+import (
+	"testing"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+)
+
+// Identifier compilation runs on every filtered query and needs no database
+
+func BenchmarkIdentifierToSQL(b *testing.B) {
+	cases := map[string]func() identifier.IIdentifier{
+		"equal": func() identifier.IIdentifier {
+			return identifier.New().Equal("slug", "user-1")
+		},
+		"mixed": func() identifier.IIdentifier {
+			return identifier.New().
+				Equal("name", "User 1").
+				GreaterThan("age", 18).
+				Like("email", "%@example.com").
+				IsNull("deleted_at")
+		},
+		"in_100": func() identifier.IIdentifier {
+			values := make([]interface{}, 100)
+			for i := range values {
+				values[i] = i
+			}
+			return identifier.New().In("id", values)
+		},
+	}
+
+	for name, build := range cases {
+		b.Run(name, func(b *testing.B) {
+			id := build()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				id.ToSQL()
+			}
+		})
+	}
+}
+
+func BenchmarkIdentifierBuild(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		identifier.New().
+			Equal("name", "User 1").
+			GreaterThan("age", 18).
+			IsNull("deleted_at")
+	}
+}
@@ -0,0 +1,200 @@
+package benchmarks
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// Credentials match the development database started by make db-up
+const (
+	benchUser     = "postgres"
+	benchPassword = "password"
+	benchDatabase = "unit_of_work_dev"
+)
+
+var (
+	benchConfig *postgres.Config
+	benchSQLite *gorm.DB // Set when UOW_BENCH_DRIVER=sqlite
+	skipReason  string
+)
+
+// TestMain starts one PostgreSQL container for the whole run, or opens a SQLite file when
+// UOW_BENCH_DRIVER=sqlite. The driver is printed as a benchstat configuration line, so results
+// of different drivers are never compared with each other
+func TestMain(m *testing.M) {
+	flag.Parse()
+	ctx := context.Background()
+	driver := os.Getenv("UOW_BENCH_DRIVER")
+	if driver == "" {
+		driver = "postgres"
+	}
+	if flag.Lookup("test.bench").Value.String() != "" {
+		fmt.Printf("driver: %s\n", driver)
+	}
+
+	var terminate func()
+	switch driver {
+	case "postgres":
+		config, stop, err := startDatabase(ctx)
+		if err != nil {
+			skipReason = fmt.Sprintf("PostgreSQL unavailable: %v", err)
+			break
+		}
+		benchConfig, terminate = config, stop
+		if err := migrate(config); err != nil {
+			skipReason = fmt.Sprintf("failed to migrate benchmark schema: %v", err)
+		}
+	case "sqlite":
+		db, stop, err := openSQLite()
+		if err != nil {
+			skipReason = fmt.Sprintf("SQLite unavailable: %v", err)
+			break
+		}
+		benchSQLite, terminate = db, stop
+	default:
+		skipReason = fmt.Sprintf("unknown UOW_BENCH_DRIVER %q", driver)
+	}
+
+	code := m.Run()
+	if terminate != nil {
+		terminate()
+	}
+	os.Exit(code)
+}
+
+// startDatabase returns a config for UOW_BENCH_HOST/UOW_BENCH_PORT or a fresh container
+func startDatabase(ctx context.Context) (*postgres.Config, func(), error) {
+	config := postgres.NewConfig()
+	config.User = benchUser
+	config.Password = benchPassword
+	config.Database = benchDatabase
+	config.LogLevel = logger.Silent
+
+	if host := os.Getenv("UOW_BENCH_HOST"); host != "" {
+		port, err := strconv.Atoi(os.Getenv("UOW_BENCH_PORT"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid UOW_BENCH_PORT: %w", err)
+		}
+		config.Host = host
+		config.Port = port
+		return config, nil, nil
+	}
+
+	container, err := runContainer(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	terminate := func() { _ = testcontainers.TerminateContainer(container) }
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		terminate()
+		return nil, nil, err
+	}
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		terminate()
+		return nil, nil, err
+	}
+	config.Host = host
+	config.Port = port.Int()
+	return config, terminate, nil
+}
+
+// runContainer starts PostgreSQL; testcontainers panics when no Docker host is found
+func runContainer(ctx context.Context) (container *tcpostgres.PostgresContainer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("docker unavailable: %v", r)
+		}
+	}()
+
+	return tcpostgres.Run(ctx, "postgres:15-alpine",
+		tcpostgres.WithDatabase(benchDatabase),
+		tcpostgres.WithUsername(benchUser),
+		tcpostgres.WithPassword(benchPassword),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(time.Minute),
+		),
+	)
+}
+
+// openSQLite opens and migrates a SQLite database in a temporary directory
+func openSQLite() (*gorm.DB, func(), error) {
+	dir, err := os.MkdirTemp("", "uow-bench")
+	if err != nil {
+		return nil, nil, err
+	}
+	remove := func() { _ = os.RemoveAll(dir) }
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "bench.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		remove()
+		return nil, nil, err
+	}
+	stop := func() {
+		closeDB(db)
+		remove()
+	}
+	if err := db.AutoMigrate(&BenchUser{}); err != nil {
+		stop()
+		return nil, nil, err
+	}
+	return db, stop, nil
+}
+
+// migrate creates the benchmark schema
+func migrate(config *postgres.Config) error {
+	db, err := postgres.Connect(config)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+	return db.AutoMigrate(&BenchUser{})
+}
+
+// requireDatabase skips database benchmarks when the database is unavailable; the config is nil on SQLite
+func requireDatabase(b *testing.B) *postgres.Config {
+	b.Helper()
+	if skipReason != "" {
+		b.Skip(skipReason)
+	}
+	return benchConfig
+}
+
+// resetTable empties the benchmark table between benchmarks
+func resetTable(b *testing.B, db *gorm.DB) {
+	b.Helper()
+	statements := []string{"TRUNCATE TABLE bench_users RESTART IDENTITY"}
+	if db.Dialector.Name() == "sqlite" {
+		statements = []string{"DELETE FROM bench_users", "DELETE FROM sqlite_sequence WHERE name = 'bench_users'"}
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			b.Fatalf("failed to reset table: %v", err)
+		}
+	}
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
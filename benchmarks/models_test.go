@@ -0,0 +1,27 @@
+package benchmarks
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BenchUser mirrors the shape of a typical entity
+type BenchUser struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	Email     string         `gorm:"size:100;index" json:"email"`
+	Slug      string         `gorm:"size:100;uniqueIndex" json:"slug"`
+	Age       int            `json:"age"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (u *BenchUser) GetID() int                    { return u.ID }
+func (u *BenchUser) GetSlug() string               { return u.Slug }
+func (u *BenchUser) SetSlug(slug string)           { u.Slug = slug }
+func (u *BenchUser) GetCreatedAt() time.Time       { return u.CreatedAt }
+func (u *BenchUser) GetUpdatedAt() time.Time       { return u.UpdatedAt }
+func (u *BenchUser) GetArchivedAt() gorm.DeletedAt { return u.DeletedAt }
+func (u *BenchUser) GetName() string               { return u.Name }
@@ -0,0 +1,191 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// newBenchUoW creates a Unit of Work on an empty table
+func newBenchUoW(b *testing.B) (persistence.IUnitOfWork[*BenchUser], func()) {
+	b.Helper()
+	config := requireDatabase(b)
+	if benchSQLite != nil {
+		resetTable(b, benchSQLite)
+		factory := postgres.NewUnitOfWorkFactoryFromDB[*BenchUser](benchSQLite)
+		return factory.Create(), func() { factory.Close() }
+	}
+
+	factory := postgres.NewUnitOfWorkFactory[*BenchUser](config)
+	uow := factory.Create()
+
+	db, err := postgres.Connect(config)
+	if err != nil {
+		b.Fatalf("failed to connect: %v", err)
+	}
+	resetTable(b, db)
+	closeDB(db)
+
+	return uow, func() { factory.Close() }
+}
+
+func newBenchUser(i int) *BenchUser {
+	return &BenchUser{
+		Name:  fmt.Sprintf("User %d", i),
+		Email: fmt.Sprintf("user%d@example.com", i),
+		Slug:  fmt.Sprintf("user-%d", i),
+		Age:   18 + i%60,
+	}
+}
+
+// seed inserts n rows in batches
+func seed(b *testing.B, uow persistence.IUnitOfWork[*BenchUser], n int) {
+	b.Helper()
+	users := make([]*BenchUser, n)
+	for i := range users {
+		users[i] = newBenchUser(i)
+	}
+	if _, err := uow.BulkInsert(context.Background(), users); err != nil {
+		b.Fatalf("failed to seed: %v", err)
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	uow, cleanup := newBenchUoW(b)
+	defer cleanup()
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := uow.Insert(ctx, newBenchUser(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBulkInsert(b *testing.B) {
+	for _, size := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			uow, cleanup := newBenchUoW(b)
+			defer cleanup()
+			ctx := context.Background()
+
+			next := 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				users := make([]*BenchUser, size)
+				for j := range users {
+					users[j] = newBenchUser(next)
+					next++
+				}
+				b.StartTimer()
+
+				if _, err := uow.BulkInsert(ctx, users); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFindAllWithPagination(b *testing.B) {
+	uow, cleanup := newBenchUoW(b)
+	defer cleanup()
+	seed(b, uow, 10000)
+	ctx := context.Background()
+
+	cases := []struct {
+		name  string
+		query domain.QueryParams[*BenchUser]
+	}{
+		{"first_page", domain.QueryParams[*BenchUser]{Limit: 20}},
+		{"deep_offset", domain.QueryParams[*BenchUser]{Limit: 20, Offset: 9000}},
+		{"sorted", domain.QueryParams[*BenchUser]{Limit: 20, Sort: domain.SortMap{"name": domain.SortDesc}}},
+		{"filtered", domain.QueryParams[*BenchUser]{Limit: 20, Filter: &BenchUser{Age: 30}}},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := uow.FindAllWithPagination(ctx, tc.query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFindOneByIdentifier(b *testing.B) {
+	uow, cleanup := newBenchUoW(b)
+	defer cleanup()
+	seed(b, uow, 1000)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := uow.FindOneByIdentifier(ctx, identifier.BySlug(fmt.Sprintf("user-%d", i%1000))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSoftDelete(b *testing.B) {
+	uow, cleanup := newBenchUoW(b)
+	defer cleanup()
+	ctx := context.Background()
+
+	b.StopTimer()
+	seed(b, uow, b.N)
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := uow.SoftDelete(ctx, identifier.ByID(i+1)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSoftDeleteRestore(b *testing.B) {
+	uow, cleanup := newBenchUoW(b)
+	defer cleanup()
+	seed(b, uow, 1000)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := identifier.ByID(i%1000 + 1)
+		if _, err := uow.SoftDelete(ctx, id); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := uow.Restore(ctx, id); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetTrashedWithPagination(b *testing.B) {
+	uow, cleanup := newBenchUoW(b)
+	defer cleanup()
+	seed(b, uow, 10000)
+	ctx := context.Background()
+
+	trashed := make([]identifier.IIdentifier, 0, 1000)
+	for i := 1; i <= 1000; i++ {
+		trashed = append(trashed, identifier.ByID(i))
+	}
+	if err := uow.BulkSoftDelete(ctx, trashed); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := uow.GetTrashedWithPagination(ctx, domain.QueryParams[*BenchUser]{Limit: 20}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Command uow inspects and repairs the example entities from the command line
+//
+//	go run ./cmd/uow -database unit_of_work_dev list users -sort -created_at
+//	go run ./cmd/uow query users "email like '%@example.com'"
+//	go run ./cmd/uow purge users -older-than 720h -yes
+//	go run ./cmd/uow seed -profile demo
+package main
+
+import (
+	"os"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/examples"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/uowcli"
+)
+
+func main() {
+	app := uowcli.New(postgres.NewConfig())
+	uowcli.Register[*examples.User](app, "users")
+	uowcli.Register[*examples.Post](app, "posts")
+	uowcli.Register[*examples.Tag](app, "tags")
+	examples.RegisterSeeders(app.Config.ModelRegistry())
+
+	os.Exit(app.Main(os.Args[1:]))
+}
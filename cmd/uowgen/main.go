@@ -0,0 +1,50 @@
+// Command uowgen generates a Unit of Work backed repository for a model
+//
+// Add a directive next to the model and run go generate:
+//
+//	//go:generate go run github.com/arash-mosavi/postgrs-unit-of-work-system/cmd/uowgen -type=User
+//
+// The repository is written to <type>_repository.go in the model's directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/scaffold"
+)
+
+func main() {
+	typeName := flag.String("type", "", "model type name (required)")
+	source := flag.String("source", os.Getenv("GOFILE"), "file declaring the model, defaults to $GOFILE")
+	output := flag.String("output", "", "output file, defaults to <type>_repository.go next to the source")
+	flag.Parse()
+
+	if *typeName == "" || *source == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*source, *typeName, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "uowgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(source, typeName, output string) error {
+	model, err := scaffold.ParseFile(source, typeName)
+	if err != nil {
+		return err
+	}
+	code, err := scaffold.Generate(model)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = filepath.Join(filepath.Dir(source), model.FileName())
+	}
+	return os.WriteFile(output, code, 0o644)
+}
@@ -0,0 +1,63 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// seedBatchSize bounds the rows per INSERT so load-test profiles stay within parameter limits
+const seedBatchSize = 1000
+
+// referenceTags are present in every profile
+var referenceTags = []string{"go", "postgres", "architecture", "testing"}
+
+// RegisterSeeders adds the example data to a registry's profiles:
+// tags everywhere, profile.Size users with posts_per_user posts each (default 3) in demo and load-test
+//
+//	go run ./cmd/uow seed -profile demo -size 20 -set posts_per_user=5
+func RegisterSeeders(registry *postgres.ModelRegistry) {
+	registry.RegisterProfileSeeder(&Tag{}, seedTags)
+	registry.RegisterProfileSeeder(&User{}, seedUsers, postgres.SeedDemo, postgres.SeedLoadTest)
+	registry.RegisterProfileSeeder(&Post{}, seedPosts, postgres.SeedDemo, postgres.SeedLoadTest)
+}
+
+func seedTags(ctx context.Context, tx *gorm.DB, _ postgres.SeedProfile) error {
+	tags := make([]*Tag, len(referenceTags))
+	for i, name := range referenceTags {
+		tags[i] = &Tag{Name: name, Slug: name}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error
+}
+
+func seedUsers(ctx context.Context, tx *gorm.DB, profile postgres.SeedProfile) error {
+	users := make([]*User, profile.Size)
+	for i := range users {
+		slug := fmt.Sprintf("%s-user-%d", profile.Name, i+1)
+		users[i] = &User{Name: fmt.Sprintf("User %d", i+1), Email: slug + "@example.com", Slug: slug}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&users, seedBatchSize).Error
+}
+
+func seedPosts(ctx context.Context, tx *gorm.DB, profile postgres.SeedProfile) error {
+	var userIDs []int
+	if err := tx.Model(&User{}).Where("slug LIKE ?", profile.Name+"-user-%").Order("id").Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+	perUser := profile.Param("posts_per_user", 3)
+	posts := make([]*Post, 0, len(userIDs)*perUser)
+	for _, userID := range userIDs {
+		for i := 0; i < perUser; i++ {
+			slug := fmt.Sprintf("%s-post-%d-%d", profile.Name, userID, i+1)
+			posts = append(posts, &Post{Name: fmt.Sprintf("Post %d", i+1), Content: "Seeded content", Slug: slug, UserID: userID})
+		}
+	}
+	if len(posts) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&posts, seedBatchSize).Error
+}
@@ -12,6 +12,7 @@ import (
 type UserService struct {
 	uowFactory  persistence.IUnitOfWorkFactory[*User]
 	postFactory persistence.IUnitOfWorkFactory[*Post]
+	txManager   *postgres.TransactionManager
 }
 
 func NewUserService(
@@ -24,7 +25,16 @@ func NewUserService(
 	}
 }
 
+// WithTransactionManager makes CreateUserWithPosts write the user and the posts in one transaction
+func (s *UserService) WithTransactionManager(manager *postgres.TransactionManager) *UserService {
+	s.txManager = manager
+	return s
+}
+
 func (s *UserService) CreateUserWithPosts(ctx context.Context, user *User, posts []*Post) error {
+	if s.txManager != nil {
+		return s.createUserWithPostsAtomically(ctx, user, posts)
+	}
 
 	userUow := s.uowFactory.CreateWithContext(ctx)
 	postUow := s.postFactory.CreateWithContext(ctx)
@@ -83,6 +93,26 @@ func (s *UserService) CreateUserWithPosts(ctx context.Context, user *User, posts
 	return nil
 }
 
+// createUserWithPostsAtomically commits the user and the posts together or not at all
+func (s *UserService) createUserWithPostsAtomically(ctx context.Context, user *User, posts []*Post) error {
+	return s.txManager.Run(ctx, func(tx *postgres.TransactionManager) error {
+		userRepo := NewUserRepository(postgres.UnitOfWorkIn[*User](tx))
+		postRepo := NewPostRepository(postgres.UnitOfWorkIn[*Post](tx))
+
+		createdUser, err := userRepo.Create(ctx, user)
+		if err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		for _, post := range posts {
+			post.UserID = createdUser.ID
+			if _, err := postRepo.Create(ctx, post); err != nil {
+				return fmt.Errorf("failed to create post: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
 func (s *UserService) ListUsers(ctx context.Context, page, pageSize int) ([]*User, uint, error) {
 
 	uow := s.uowFactory.CreateWithContext(ctx)
@@ -118,27 +148,18 @@ func (s *UserService) SearchUsers(ctx context.Context, name, email string, activ
 func (s *UserService) BatchCreateUsers(ctx context.Context, users []*User) ([]*User, error) {
 
 	uow := s.uowFactory.CreateWithContext(ctx)
-	userRepo := NewUserRepository(uow)
-
-	if err := uow.BeginTransaction(ctx); err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			uow.RollbackTransaction(ctx)
-			panic(r)
+	var createdUsers []*User
+	err := uow.Transaction(ctx, func(txUow persistence.IUnitOfWork[*User]) error {
+		var err error
+		createdUsers, err = NewUserRepository(txUow).BatchCreate(ctx, users)
+		if err != nil {
+			return fmt.Errorf("failed to batch create users: %w", err)
 		}
-	}()
-
-	createdUsers, err := userRepo.BatchCreate(ctx, users)
+		return nil
+	})
 	if err != nil {
-		uow.RollbackTransaction(ctx)
-		return nil, fmt.Errorf("failed to batch create users: %w", err)
-	}
-
-	if err := uow.CommitTransaction(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, err
 	}
 
 	return createdUsers, nil
@@ -189,27 +210,18 @@ func (s *PostService) GetUserPosts(ctx context.Context, userID int) ([]*Post, er
 func (s *PostService) BatchCreatePosts(ctx context.Context, posts []*Post) ([]*Post, error) {
 
 	uow := s.uowFactory.CreateWithContext(ctx)
-	postRepo := NewPostRepository(uow)
 
-	if err := uow.BeginTransaction(ctx); err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			uow.RollbackTransaction(ctx)
-			panic(r)
+	var createdPosts []*Post
+	err := uow.Transaction(ctx, func(txUow persistence.IUnitOfWork[*Post]) error {
+		var err error
+		createdPosts, err = NewPostRepository(txUow).BatchCreate(ctx, posts)
+		if err != nil {
+			return fmt.Errorf("failed to batch create posts: %w", err)
 		}
-	}()
-
-	createdPosts, err := postRepo.BatchCreate(ctx, posts)
+		return nil
+	})
 	if err != nil {
-		uow.RollbackTransaction(ctx)
-		return nil, fmt.Errorf("failed to batch create posts: %w", err)
-	}
-
-	if err := uow.CommitTransaction(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, err
 	}
 
 	return createdPosts, nil
@@ -228,7 +240,12 @@ func Example() {
 	userFactory := postgres.NewUnitOfWorkFactory[*User](config)
 	postFactory := postgres.NewUnitOfWorkFactory[*Post](config)
 
-	userService := NewUserService(userFactory, postFactory)
+	txManager, err := userFactory.TransactionManager()
+	if err != nil {
+		log.Printf("Failed to connect: %v", err)
+		return
+	}
+	userService := NewUserService(userFactory, postFactory).WithTransactionManager(txManager)
 	postService := NewPostService(postFactory)
 
 	ctx := context.Background()
@@ -0,0 +1,103 @@
+package anonymize
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type customer struct {
+	ID        int `gorm:"primaryKey"`
+	Name      string
+	Email     string `gorm:"uniqueIndex"`
+	Phone     *string
+	TaxID     *string
+	DeletedAt gorm.DeletedAt
+}
+
+func setupDB(t *testing.T, rows int) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&customer{}))
+	for i := 1; i <= rows; i++ {
+		phone, tax := "+49 170 1234567", "DE-SAME"
+		require.NoError(t, db.Create(&customer{ID: i, Name: "Real Person", Email: "real" + string(rune('a'+i)) + "@corp.com", Phone: &phone, TaxID: &tax}).Error)
+	}
+	return db
+}
+
+func TestAnonymizerRewritesColumnsInBatches(t *testing.T) {
+	db := setupDB(t, 5)
+	require.NoError(t, db.Delete(&customer{ID: 2}).Error)
+
+	var progress []int64
+	report, err := New(db, Options{BatchSize: 2, Progress: func(table string, rows int64) {
+		progress = append(progress, rows)
+	}}).Run(context.Background(), Table{Name: "customers", Columns: map[string]Strategy{
+		"name":   Fake(FakeName),
+		"email":  Fake(FakeEmail),
+		"phone":  Null(),
+		"tax_id": Hash("salt"),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, Report{"customers": 5}, report)
+	assert.Equal(t, []int64{2, 4, 5}, progress)
+
+	var rows []customer
+	require.NoError(t, db.Unscoped().Order("id").Find(&rows).Error)
+	require.Len(t, rows, 5)
+	emails := map[string]bool{}
+	for _, row := range rows {
+		assert.NotEqual(t, "Real Person", row.Name)
+		assert.Contains(t, row.Email, "@example.invalid")
+		assert.Nil(t, row.Phone)
+		require.NotNil(t, row.TaxID)
+		assert.Len(t, *row.TaxID, 32)
+		assert.Equal(t, *rows[0].TaxID, *row.TaxID, "equal inputs hash alike")
+		emails[row.Email] = true
+	}
+	assert.Len(t, emails, 5, "fake emails stay unique")
+	assert.True(t, rows[1].DeletedAt.Valid, "trashed rows are anonymized in place")
+
+	// Reruns produce the same fake values
+	before := rows[0]
+	_, err = New(db, Options{}).Run(context.Background(), Table{Name: "customers", Columns: map[string]Strategy{
+		"name":  Fake(FakeName),
+		"email": Fake(FakeEmail),
+	}})
+	require.NoError(t, err)
+	var after customer
+	require.NoError(t, db.First(&after, 1).Error)
+	assert.Equal(t, before.Name, after.Name)
+	assert.Equal(t, before.Email, after.Email)
+}
+
+func TestAnonymizerRollsBackFailedBatch(t *testing.T) {
+	db := setupDB(t, 4)
+	failing := StrategyFunc(func(column string, key, value interface{}) (interface{}, error) {
+		if key.(int64) == 4 {
+			return nil, errors.New("boom")
+		}
+		return "x", nil
+	})
+
+	report, err := New(db, Options{BatchSize: 2}).Run(context.Background(), Table{Name: "customers", Columns: map[string]Strategy{"name": failing}})
+	require.Error(t, err)
+	assert.Equal(t, int64(2), report["customers"])
+
+	var names []string
+	require.NoError(t, db.Model(&customer{}).Order("id").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"x", "x", "Real Person", "Real Person"}, names)
+}
+
+func TestAnonymizerRejectsInvalidIdentifiers(t *testing.T) {
+	db := setupDB(t, 0)
+	_, err := New(db, Options{}).Run(context.Background(), Table{Name: "customers; drop table x", Columns: map[string]Strategy{"name": Null()}})
+	assert.ErrorContains(t, err, "invalid identifier")
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// AggregateFunc is an SQL aggregate function
+type AggregateFunc string
+
+const (
+	Count AggregateFunc = "count"
+	Sum   AggregateFunc = "sum"
+	Avg   AggregateFunc = "avg"
+	Min   AggregateFunc = "min"
+	Max   AggregateFunc = "max"
+)
+
+// Metric is one aggregated output column, e.g. {Func: Sum, Field: "amount"}
+type Metric struct {
+	Func  AggregateFunc `json:"func"`
+	Field string        `json:"field,omitempty"` // Empty with Count counts rows
+	As    string        `json:"as,omitempty"`    // Output name, default "<func>_<field>" or "count"
+}
+
+// Alias returns the metric's output name
+func (m Metric) Alias() string {
+	if m.As != "" {
+		return m.As
+	}
+	if m.Field == "" {
+		return string(m.Func)
+	}
+	return string(m.Func) + "_" + m.Field
+}
+
+// TimeBucket groups rows into fixed-width time intervals with TimescaleDB's time_bucket
+type TimeBucket struct {
+	Field string        `json:"field"`        // Timestamp column
+	Width time.Duration `json:"width"`        // Bucket width, e.g. time.Hour
+	As    string        `json:"as,omitempty"` // Output name, default "bucket"
+}
+
+// Alias returns the bucket's output name
+func (b TimeBucket) Alias() string {
+	if b.As != "" {
+		return b.As
+	}
+	return "bucket"
+}
+
+// AggregateParams configures a grouped aggregate query
+// Output rows hold the group fields (by column name), the bucket and the metrics (by alias)
+type AggregateParams[E BaseModel] struct {
+	Filter  E            `json:"filter,omitempty"`
+	GroupBy []string     `json:"group_by,omitempty"`
+	Bucket  *TimeBucket  `json:"bucket,omitempty"`
+	Metrics []Metric     `json:"metrics"`
+	OrderBy []OrderField `json:"order_by,omitempty"` // Output names; default the bucket and group fields
+	Limit   int          `json:"limit,omitempty"`    // Max rows (max 10000), default 1000
+}
+
+// Validate applies defaults and checks the parameters
+func (p *AggregateParams[E]) Validate() error {
+	if len(p.Metrics) == 0 {
+		return fmt.Errorf("aggregate requires at least one metric")
+	}
+	for _, metric := range p.Metrics {
+		switch metric.Func {
+		case Count:
+		case Sum, Avg, Min, Max:
+			if metric.Field == "" {
+				return fmt.Errorf("%s requires a field", metric.Func)
+			}
+		default:
+			return fmt.Errorf("unsupported aggregate function %q", metric.Func)
+		}
+	}
+	if p.Bucket != nil && p.Bucket.Width <= 0 {
+		return fmt.Errorf("time bucket width must be positive")
+	}
+	if p.Limit <= 0 {
+		p.Limit = 1000
+	}
+	if p.Limit > 10000 {
+		p.Limit = 10000
+	}
+	return nil
+}
+
+// AggregateRow is one output row of an aggregate query
+type AggregateRow map[string]interface{}
@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// ArchiveMetadata records why and by whom an entity was soft-deleted
+// Used by SoftDeleteWithReason for entities without deleted_reason/deleted_by columns;
+// migrate it alongside the entities, e.g. db.AutoMigrate(&domain.ArchiveMetadata{})
+type ArchiveMetadata struct {
+	EntityTable string    `gorm:"primaryKey;size:128" json:"entity_table"`
+	EntityID    int       `gorm:"primaryKey;autoIncrement:false" json:"entity_id"`
+	Reason      string    `json:"reason"`
+	Actor       string    `gorm:"size:255" json:"actor"`
+	ArchivedAt  time.Time `json:"archived_at"`
+}
+
+// TableName implements gorm's Tabler
+func (ArchiveMetadata) TableName() string {
+	return "uow_archive_metadata"
+}
+
+// ArchiveInfoSetter is implemented by entities that want archive metadata attached to trashed query results
+// Typically backed by fields tagged gorm:"-"
+type ArchiveInfoSetter interface {
+	SetArchiveInfo(reason, actor string)
+}
+
+// SoftDeleteResult reports a SoftDeleteWhere call
+// In preview mode nothing is deleted: Affected is the number of matching rows and Sample shows some of them
+type SoftDeleteResult[E BaseModel] struct {
+	Preview  bool  `json:"preview"`
+	Affected int64 `json:"affected"`
+	Sample   []E   `json:"sample,omitempty"`
+}
+
+// DeletePolicy decides what HardDeleteWithDependents does with rows referencing the deleted entities
+type DeletePolicy string
+
+const (
+	DeleteRestrict DeletePolicy = "restrict" // Refuse while dependent rows exist
+	DeleteCascade  DeletePolicy = "cascade"  // Delete dependent rows first, recursively
+)
+
+// DependentDeleteResult reports a HardDeleteWithDependents call
+type DependentDeleteResult struct {
+	Deleted map[string]int64 `json:"deleted"` // Hard-deleted rows per table, the entity's own table included
+}
+
+// Total counts the deleted rows across tables
+func (r DependentDeleteResult) Total() int64 {
+	var total int64
+	for _, rows := range r.Deleted {
+		total += rows
+	}
+	return total
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"database/sql"
 	"time"
 
 	"gorm.io/gorm"
@@ -36,8 +37,66 @@ type QueryParams[E BaseModel] struct {
 	Filter  E        `json:"filter,omitempty"`
 	Sort    SortMap  `json:"sort,omitempty"`
 	Include []string `json:"include,omitempty"` // Eager loading relationships
+	Counts  []string `json:"counts,omitempty"`  // Has-one/has-many/many-to-many relations to count into <relation>_count fields
 	Limit   int      `json:"limit,omitempty"`   // Pagination size (max 1000 for performance)
 	Offset  int      `json:"offset,omitempty"`  // Pagination offset
+
+	// Soft-deleted rows are excluded unless IncludeTrashed (live and trashed) or OnlyTrashed (trashed) is set
+	IncludeTrashed bool `json:"include_trashed,omitempty"`
+	OnlyTrashed    bool `json:"only_trashed,omitempty"` // Takes precedence over IncludeTrashed
+
+	// Hints steer the planner for this query; never decoded from requests
+	Hints *QueryHints `json:"-"`
+
+	// TxOptions runs the count and the page in one transaction opened with these options, e.g.
+	// RepeatableRead for a total consistent with the page; ignored inside a transaction
+	TxOptions *sql.TxOptions `json:"-"`
+}
+
+// WithCounts returns a copy of the query that also loads the number of live (not soft-deleted)
+// children per relation into the entity's <relation>_count field, e.g. Posts into PostsCount.
+// Declare such fields read-only and outside migrations: `gorm:"->;-:migration"`
+func (q QueryParams[E]) WithCounts(relations ...string) QueryParams[E] {
+	q.Counts = append(append([]string(nil), q.Counts...), relations...)
+	return q
+}
+
+// QueryHints are per-query planner controls for performance firefighting
+type QueryHints struct {
+	// Settings are applied with SET LOCAL for this query only, e.g. {"enable_seqscan": "off"}
+	// They need a transaction: one is opened around the query when none is active
+	Settings map[string]string
+	// Plan is a pg_hint_plan hint, e.g. "IndexScan(users idx_users_email)", sent as /*+ ... */
+	Plan string
+}
+
+// QueryOptions exposes query parameters without generics or reflection
+// Implemented by QueryParams so non-generic code can read them cheaply
+type QueryOptions interface {
+	FilterValue() interface{}
+	SortFields() SortMap
+	IncludeRelations() []string
+	Pagination() (limit, offset int)
+}
+
+// FilterValue returns the filter entity
+func (q QueryParams[E]) FilterValue() interface{} {
+	return q.Filter
+}
+
+// SortFields returns the sort configuration
+func (q QueryParams[E]) SortFields() SortMap {
+	return q.Sort
+}
+
+// IncludeRelations returns the relationships to eager load
+func (q QueryParams[E]) IncludeRelations() []string {
+	return q.Include
+}
+
+// Pagination returns the limit and offset
+func (q QueryParams[E]) Pagination() (limit, offset int) {
+	return q.Limit, q.Offset
 }
 
 // Validate ensures query parameters are within acceptable bounds
@@ -64,3 +123,37 @@ func (q *QueryParams[E]) GetPageInfo() (page int, size int) {
 	page = (q.Offset / size) + 1
 	return page, size
 }
+
+// CSVWriterOptions configures CSV exports
+type CSVWriterOptions struct {
+	Columns    []string                                  // Columns (or Go field names) to export, in order; default all
+	Headers    map[string]string                         // Header text per column; default the column name
+	NoHeader   bool                                      // Omit the header row
+	Comma      rune                                      // Field delimiter; default ','
+	UseCRLF    bool                                      // Terminate lines with \r\n
+	TimeFormat string                                    // Layout for time values; default time.RFC3339
+	NullValue  string                                    // Written for NULL values
+	Formatters map[string]func(value interface{}) string // Custom formatting per column
+}
+
+// ProgressFunc reports how many records an export or import has processed so far
+type ProgressFunc func(processed int64)
+
+// JSONExportOptions configures newline-delimited JSON exports
+type JSONExportOptions struct {
+	Progress      ProgressFunc // Called every ProgressEvery records and once at the end
+	ProgressEvery int          // Default 1000
+	// IncludeTrashed exports soft-deleted rows too, with their deleted_at, for cloning an environment;
+	// the entity's DeletedAt field must not be tagged json:"-"
+	IncludeTrashed bool
+}
+
+// JSONImportOptions configures newline-delimited JSON imports
+type JSONImportOptions struct {
+	BatchSize       int          // Records per upsert statement; default 500
+	ConflictColumns []string     // Columns identifying an existing row; default the primary key
+	Progress        ProgressFunc // Called after every batch
+	// ResetSequence moves the primary key sequence past the highest imported ID once the import is done,
+	// so rows inserted afterwards do not collide with the preserved IDs (PostgreSQL only)
+	ResetSequence bool
+}
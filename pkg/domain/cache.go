@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Cacheable opts an entity type into the second-level cache
+// Types that do not implement it are always read from the database
+type Cacheable interface {
+	CachePolicy() CachePolicy
+}
+
+// CachePolicy controls how an entity type is cached
+type CachePolicy struct {
+	TTL time.Duration // How long a cached entity is served; zero disables caching
+	// KeyFields are unique columns (besides the ID) whose FindOne lookups are cached, e.g. "slug"
+	KeyFields []string
+}
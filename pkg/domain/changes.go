@@ -0,0 +1,22 @@
+package domain
+
+// ChangeKind classifies an entity change reported to change listeners
+type ChangeKind string
+
+const (
+	ChangeCreated ChangeKind = "created"
+	ChangeUpdated ChangeKind = "updated" // Includes upserts and restores
+	ChangeDeleted ChangeKind = "deleted" // Soft or hard delete
+)
+
+// Change is one committed entity change
+type Change[E BaseModel] struct {
+	Kind   ChangeKind
+	Entity E
+}
+
+// FieldChange is the before and after value of one column
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+)
+
+// Step is one step of RunWithCompensation
+// Database steps leave Compensate nil: rolling back the transaction undoes them. Steps calling
+// external systems (payments, emails, other services) set Compensate to undo their effect
+type Step struct {
+	Name       string
+	Run        func(ctx context.Context) error
+	Compensate func(ctx context.Context) error
+}
+
+// CompensationError reports a compensation that failed; the external effect may remain
+type CompensationError struct {
+	Step string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *CompensationError) Error() string {
+	return fmt.Sprintf("compensation of step %s failed: %v", e.Step, e.Err)
+}
+
+// Unwrap returns the compensation's error
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,35 @@
+package domain
+
+// FindOptions configures single-entity lookups
+type FindOptions struct {
+	Preload []string // Relations to eager load, e.g. "Posts.Tags"
+	Select  []string // Columns to read; the primary key is always included
+}
+
+// FindOption adjusts FindOptions
+type FindOption func(*FindOptions)
+
+// WithPreload eager loads relations, using dots for nested relations
+func WithPreload(relations ...string) FindOption {
+	return func(o *FindOptions) {
+		o.Preload = append(o.Preload, relations...)
+	}
+}
+
+// WithSelect reads only the given columns (or Go field names)
+func WithSelect(fields ...string) FindOption {
+	return func(o *FindOptions) {
+		o.Select = append(o.Select, fields...)
+	}
+}
+
+// ApplyFindOptions collects options into FindOptions
+func ApplyFindOptions(options ...FindOption) FindOptions {
+	var result FindOptions
+	for _, option := range options {
+		if option != nil {
+			option(&result)
+		}
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONB stores a value of type T in a jsonb column, for semi-structured attributes on entities
+//
+//	type Product struct {
+//		...
+//		Attributes domain.JSONB[ProductAttributes]
+//	}
+//
+// It marshals to and from JSON as T itself, so API payloads do not see the wrapper
+type JSONB[T any] struct {
+	Data T
+}
+
+// NewJSONB wraps a value for a jsonb column
+func NewJSONB[T any](data T) JSONB[T] {
+	return JSONB[T]{Data: data}
+}
+
+// GormDataType declares the column type for migrations
+func (JSONB[T]) GormDataType() string {
+	return "jsonb"
+}
+
+// Value implements driver.Valuer
+func (j JSONB[T]) Value() (driver.Value, error) {
+	encoded, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode jsonb value: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// Scan implements sql.Scanner; NULL leaves the zero value
+func (j *JSONB[T]) Scan(src interface{}) error {
+	var zero T
+	j.Data = zero
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, &j.Data)
+	case string:
+		return json.Unmarshal([]byte(v), &j.Data)
+	default:
+		return fmt.Errorf("cannot scan %T into jsonb value", src)
+	}
+}
+
+// MarshalJSON encodes the wrapped value
+func (j JSONB[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Data)
+}
+
+// UnmarshalJSON decodes into the wrapped value
+func (j *JSONB[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &j.Data)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor marks a row's position in a keyset-ordered result
+// Value holds the sort column's value, ID breaks ties between equal values
+type Cursor struct {
+	Value interface{} `json:"v,omitempty"`
+	ID    int         `json:"id"`
+}
+
+// KeysetParams configures keyset (cursor) pagination
+// Rows are ordered by SortField then by primary key, so positions stay stable under inserts
+type KeysetParams[E BaseModel] struct {
+	Filter    E             `json:"filter,omitempty"`
+	SortField string        `json:"sort_field,omitempty"` // Column to order by, default "id"
+	Direction SortDirection `json:"direction,omitempty"`  // Default ascending
+	After     *Cursor       `json:"after,omitempty"`      // Only rows after this position
+	Before    *Cursor       `json:"before,omitempty"`     // Only rows before this position
+	Backward  bool          `json:"backward,omitempty"`   // Take Limit rows from the end of the range
+	Limit     int           `json:"limit,omitempty"`      // Page size (max 1000)
+}
+
+// Validate applies defaults and bounds to keyset parameters
+func (q *KeysetParams[E]) Validate() error {
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+	if q.Limit > 1000 {
+		q.Limit = 1000
+	}
+	if q.SortField == "" {
+		q.SortField = "id"
+	}
+	if q.Direction != SortDesc {
+		q.Direction = SortAsc
+	}
+	return nil
+}
+
+// KeysetPage is one page of keyset pagination results
+type KeysetPage[E BaseModel] struct {
+	Items       []E
+	Cursors     []Cursor // Cursors[i] is the position of Items[i]
+	HasNext     bool
+	HasPrevious bool
+}
+
+// EncodeCursor renders a cursor as an opaque URL-safe string
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a string produced by EncodeCursor
+func DecodeCursor(encoded string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return cursor, nil
+}
@@ -0,0 +1,64 @@
+package domain
+
+import "time"
+
+// ReassignResult reports a Reassign call
+type ReassignResult struct {
+	From  []int            `json:"from"`  // Owner keys the child rows were moved away from
+	To    int              `json:"to"`    // Owner key the child rows now reference
+	Moved map[string]int64 `json:"moved"` // Moved rows per relation, keyed "child.fk -> parent.key"
+}
+
+// Total counts the moved rows across relations
+func (r ReassignResult) Total() int64 {
+	var total int64
+	for _, rows := range r.Moved {
+		total += rows
+	}
+	return total
+}
+
+// MergeRule decides which value a survivor column keeps when duplicates are merged into it
+type MergeRule string
+
+const (
+	MergeKeepSurvivor    MergeRule = ""                 // Keep the survivor's value (default)
+	MergeFillEmpty       MergeRule = "fill_empty"       // Take a duplicate's value when the survivor's is empty
+	MergePreferDuplicate MergeRule = "prefer_duplicate" // Take a duplicate's value whenever it is set
+	MergeNewest          MergeRule = "newest"           // Take the set value of the most recently updated entity
+)
+
+// MergeStrategy configures a Merge call
+// Columns maps column or field names to their rule; unlisted columns follow Default. The primary key,
+// created_at, updated_at and the soft-delete column are never merged unless listed
+type MergeStrategy struct {
+	Default MergeRule
+	Columns map[string]MergeRule
+	Actor   string // Recorded in the merge log
+}
+
+// MergeResult reports a Merge call
+type MergeResult[E BaseModel] struct {
+	Survivor   E                      `json:"survivor"`
+	Duplicates []int                  `json:"duplicates"` // Soft-deleted duplicate keys
+	Changes    map[string]FieldChange `json:"changes"`    // Survivor columns changed by the strategy
+	Moved      map[string]int64       `json:"moved"`      // Child rows re-pointed per relation
+}
+
+// MergeRecord is one duplicate merged into a survivor, as recorded in the merge log
+// Migrate it alongside the entities, e.g. db.AutoMigrate(&domain.MergeRecord{})
+type MergeRecord struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	EntityTable string    `gorm:"size:128;not null;index:,composite:merge_survivor" json:"entity_table"`
+	SurvivorID  int       `gorm:"not null;index:,composite:merge_survivor" json:"survivor_id"`
+	DuplicateID int       `gorm:"not null" json:"duplicate_id"`
+	Changes     string    `gorm:"type:jsonb;not null" json:"changes"` // Survivor columns taken from this duplicate, as {"column": {"old": ..., "new": ...}}
+	Moved       string    `gorm:"type:jsonb;not null" json:"moved"`   // Child rows re-pointed per relation
+	Actor       string    `gorm:"size:255" json:"actor"`
+	MergedAt    time.Time `json:"merged_at"`
+}
+
+// TableName implements gorm's Tabler
+func (MergeRecord) TableName() string {
+	return "uow_merge_log"
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "encoding/json"
+
+// Page is the list payload services return: one page of entities with what a client needs to
+// fetch the next. Offset pages set Total, Limit and Offset; keyset pages set Limit and NextCursor
+type Page[E BaseModel] struct {
+	Items      []E    `json:"items"`
+	Total      uint   `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"` // Opaque; pass to DecodeCursor for KeysetParams.After
+}
+
+// NewPage wraps the results of an offset-paginated query
+func NewPage[E BaseModel](items []E, total uint, query QueryParams[E]) Page[E] {
+	return Page[E]{Items: items, Total: total, Limit: query.Limit, Offset: query.Offset}
+}
+
+// NewKeysetPage wraps a keyset page; NextCursor is set when more rows follow and Total stays zero,
+// as keyset queries do not count
+func NewKeysetPage[E BaseModel](page KeysetPage[E], query KeysetParams[E]) Page[E] {
+	result := Page[E]{Items: page.Items, Limit: query.Limit}
+	if page.HasNext && len(page.Cursors) > 0 {
+		result.NextCursor = EncodeCursor(page.Cursors[len(page.Cursors)-1])
+	}
+	return result
+}
+
+// HasMore reports whether another page follows this one
+func (p Page[E]) HasMore() bool {
+	if p.NextCursor != "" {
+		return true
+	}
+	return p.Limit > 0 && uint(p.Offset+len(p.Items)) < p.Total
+}
+
+// MarshalJSON renders an empty page with "items": [] rather than null
+func (p Page[E]) MarshalJSON() ([]byte, error) {
+	type page Page[E]
+	if p.Items == nil {
+		p.Items = []E{}
+	}
+	return json.Marshal(page(p))
+}
@@ -0,0 +1,86 @@
+package domain
+
+import "time"
+
+// Watermark marks how far an incremental sync has progressed
+// Changes are ordered by change time, then by ID, so equal timestamps never skip rows
+type Watermark struct {
+	Time time.Time `json:"time"`
+	ID   int       `json:"id"`
+}
+
+// SyncParams configures an incremental sync
+type SyncParams struct {
+	Since Watermark `json:"since"`           // Zero value starts from the beginning
+	Limit int       `json:"limit,omitempty"` // Changes per call (max 10000), default 1000
+}
+
+// Validate applies defaults and bounds to sync parameters
+func (q *SyncParams) Validate() error {
+	if q.Limit <= 0 {
+		q.Limit = 1000
+	}
+	if q.Limit > 10000 {
+		q.Limit = 10000
+	}
+	return nil
+}
+
+// SyncResult holds the changes after a watermark, classified by kind
+// Persist Watermark and pass it as Since on the next call; repeat while HasMore
+type SyncResult[E BaseModel] struct {
+	Created   []E       // Created after the watermark (and possibly updated since)
+	Updated   []E       // Created before the watermark, updated after it
+	Deleted   []E       // Soft-deleted after the watermark
+	Watermark Watermark // Position of the last change returned
+	HasMore   bool      // More changes are pending beyond Watermark
+}
+
+// SyncOperation is the kind of change an offline client made
+type SyncOperation string
+
+const (
+	SyncCreate SyncOperation = "create"
+	SyncUpdate SyncOperation = "update"
+	SyncDelete SyncOperation = "delete"
+)
+
+// ClientChange is one change submitted by an offline client
+type ClientChange[E BaseModel] struct {
+	Operation   SyncOperation `json:"operation"`
+	Entity      E             `json:"entity"`                 // Client state; its ID identifies the row (client-generated for creates)
+	BaseVersion int64         `json:"base_version,omitempty"` // Server version the client started from; ignored for creates
+	Fields      []string      `json:"fields,omitempty"`       // Columns the client changed; empty writes every column
+}
+
+// ConflictResolution decides what happens when a client change was based on an outdated version
+type ConflictResolution string
+
+const (
+	ServerWins ConflictResolution = "server_wins" // Keep the server row and report the conflict (default)
+	ClientWins ConflictResolution = "client_wins" // Apply the client change over the server row
+	MergeWith  ConflictResolution = "merge"       // Write what SyncOptions.Merge returns
+)
+
+// SyncOptions configures how client changes are applied
+type SyncOptions[E BaseModel] struct {
+	Resolution ConflictResolution
+	// Merge combines the server row and the client's entity for update conflicts under MergeWith;
+	// delete conflicts keep the server row
+	Merge func(server, client E) (E, error)
+}
+
+// SyncConflict reports a client change that did not match the server version
+type SyncConflict[E BaseModel] struct {
+	Change        ClientChange[E]    `json:"change"`
+	Server        E                  `json:"server,omitempty"` // Server row before resolution; nil when it no longer exists
+	ServerVersion int64              `json:"server_version"`
+	Reason        string             `json:"reason"`
+	Resolution    ConflictResolution `json:"resolution"` // How it was resolved
+}
+
+// SyncApplyResult reports an ApplyChanges call
+type SyncApplyResult[E BaseModel] struct {
+	Applied   []E               `json:"applied"`   // Server state of every row written, in submission order
+	Conflicts []SyncConflict[E] `json:"conflicts"` // Including the ones resolved by writing
+}
@@ -0,0 +1,18 @@
+package domain
+
+// TreeOptions configures hierarchy queries over a self-referencing parent column
+type TreeOptions struct {
+	ParentField string `json:"parent_field,omitempty"` // Column or Go field holding the parent ID, default "parent_id"
+	MaxDepth    int    `json:"max_depth,omitempty"`    // Levels to walk (max 1000), default 1000
+}
+
+// Validate applies defaults and bounds to tree options
+func (o *TreeOptions) Validate() error {
+	if o.ParentField == "" {
+		o.ParentField = "parent_id"
+	}
+	if o.MaxDepth <= 0 || o.MaxDepth > 1000 {
+		o.MaxDepth = 1000
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package domain
+
+// UpsertResult counts what an upsert did with each row
+type UpsertResult struct {
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"` // Rows that conflicted and were overwritten
+	Skipped  int64 `json:"skipped"` // Rows that conflicted and were left as they were
+}
+
+// Add accumulates another batch's counts
+func (r *UpsertResult) Add(other UpsertResult) {
+	r.Inserted += other.Inserted
+	r.Updated += other.Updated
+	r.Skipped += other.Skipped
+}
+
+// Total is the number of rows the upsert was given
+func (r UpsertResult) Total() int64 {
+	return r.Inserted + r.Updated + r.Skipped
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "fmt"
+
+// RankFunction is the window function ranking rows within a group
+type RankFunction string
+
+const (
+	RowNumber RankFunction = "row_number" // 1, 2, 3: exactly N rows per group
+	Rank      RankFunction = "rank"       // 1, 1, 3: ties share a rank, so a group may return more than N rows
+	DenseRank RankFunction = "dense_rank" // 1, 1, 2: the top N distinct values
+)
+
+// OrderField is one ORDER BY term; unlike SortMap its position is significant
+type OrderField struct {
+	Field     string        `json:"field"`
+	Direction SortDirection `json:"direction,omitempty"` // Default ascending
+}
+
+// TopNParams selects the first rows of every group, e.g. the three newest posts per author
+// It ranks rows with <Function>() OVER (PARTITION BY <PartitionBy> ORDER BY <OrderBy>) and keeps ranks <= N
+type TopNParams[E BaseModel] struct {
+	Filter      E            `json:"filter,omitempty"`
+	PartitionBy []string     `json:"partition_by"`       // Columns defining a group; empty ranks the whole table
+	OrderBy     []OrderField `json:"order_by"`           // Ranking order within a group; ties are broken by primary key
+	Function    RankFunction `json:"function,omitempty"` // Default RowNumber
+	N           int          `json:"n"`                  // Rows kept per group (max 1000)
+}
+
+// Validate applies defaults and checks the parameters
+func (p *TopNParams[E]) Validate() error {
+	if p.Function == "" {
+		p.Function = RowNumber
+	}
+	switch p.Function {
+	case RowNumber, Rank, DenseRank:
+	default:
+		return fmt.Errorf("unsupported rank function %q", p.Function)
+	}
+	if p.N <= 0 || p.N > 1000 {
+		return fmt.Errorf("rows per group must be between 1 and 1000, got %d", p.N)
+	}
+	if len(p.OrderBy) == 0 {
+		return fmt.Errorf("ranking requires at least one order field")
+	}
+	for _, order := range p.OrderBy {
+		if order.Direction != "" && order.Direction != SortAsc && order.Direction != SortDesc {
+			return fmt.Errorf("invalid sort direction %q", order.Direction)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Package dto maps entities to transport structs (protobuf messages, API DTOs) and back
+//
+// A Mapper is registered once per entity/DTO pair. Updates apply a field mask, so only
+// the listed DTO fields are written through the Unit of Work's partial-update API:
+//
+//	users := dto.Register(toUserProto, fromUserProto)
+//	updated, err := users.Update(ctx, uow, identifier.ByID(id), req.User, req.UpdateMask.Paths)
+package dto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+// readOnlyColumns are never written through a field mask
+var readOnlyColumns = map[string]bool{"id": true, "created_at": true, "updated_at": true, "deleted_at": true}
+
+// readOnlyFields are the Go fields of readOnlyColumns, whatever the naming strategy calls them
+var readOnlyFields = map[string]bool{"ID": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true}
+
+// Mapper converts between an entity and a DTO and turns field masks into column updates
+type Mapper[T domain.BaseModel, D any] struct {
+	toDTO   func(T) D
+	fromDTO func(D) T
+
+	mu     sync.RWMutex
+	fields map[string]string // DTO path -> entity field name overrides
+	namer  schema.Namer      // Names columns; the unit of work's or GORM's default when nil
+}
+
+// namingStrategist is implemented by units of work that expose their connection's naming strategy
+type namingStrategist interface {
+	NamingStrategy() schema.Namer
+}
+
+type mapperKey struct {
+	entity reflect.Type
+	dto    reflect.Type
+}
+
+var registry sync.Map // mapperKey -> *Mapper[T, D]
+
+// NewMapper creates an unregistered mapper
+func NewMapper[T domain.BaseModel, D any](toDTO func(T) D, fromDTO func(D) T) *Mapper[T, D] {
+	return &Mapper[T, D]{toDTO: toDTO, fromDTO: fromDTO, fields: make(map[string]string)}
+}
+
+// Register creates a mapper and makes it available to ToDTO, FromDTO and MapperFor
+// Registering the same pair again replaces the previous mapper
+func Register[T domain.BaseModel, D any](toDTO func(T) D, fromDTO func(D) T) *Mapper[T, D] {
+	mapper := NewMapper(toDTO, fromDTO)
+	registry.Store(keyOf[T, D](), mapper)
+	return mapper
+}
+
+// MapperFor returns the registered mapper for an entity/DTO pair
+func MapperFor[T domain.BaseModel, D any]() (*Mapper[T, D], bool) {
+	mapper, ok := registry.Load(keyOf[T, D]())
+	if !ok {
+		return nil, false
+	}
+	return mapper.(*Mapper[T, D]), true
+}
+
+// ToDTO converts an entity with the registered mapper
+func ToDTO[D any, T domain.BaseModel](entity T) (D, error) {
+	mapper, ok := MapperFor[T, D]()
+	if !ok {
+		var zero D
+		return zero, fmt.Errorf("dto: no mapper registered for %T -> %T", entity, zero)
+	}
+	return mapper.ToDTO(entity), nil
+}
+
+// FromDTO converts a DTO with the registered mapper
+func FromDTO[T domain.BaseModel, D any](value D) (T, error) {
+	mapper, ok := MapperFor[T, D]()
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("dto: no mapper registered for %T -> %T", value, zero)
+	}
+	return mapper.FromDTO(value), nil
+}
+
+func keyOf[T, D any]() mapperKey {
+	return mapperKey{
+		entity: reflect.TypeOf((*T)(nil)).Elem(),
+		dto:    reflect.TypeOf((*D)(nil)).Elem(),
+	}
+}
+
+// Field maps a DTO path to an entity field whose name differs, e.g. Field("display_name", "Name")
+// Paths that are not mapped explicitly match an entity field by json name, column or Go name
+func (m *Mapper[T, D]) Field(path, entityField string) *Mapper[T, D] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fields[path] = entityField
+	return m
+}
+
+// NamingStrategy sets the strategy column names are resolved with, for a connection configured
+// with one; Update otherwise uses the unit of work's strategy
+func (m *Mapper[T, D]) NamingStrategy(namer schema.Namer) *Mapper[T, D] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.namer = namer
+	return m
+}
+
+// ToDTO converts an entity
+func (m *Mapper[T, D]) ToDTO(entity T) D {
+	return m.toDTO(entity)
+}
+
+// ToDTOs converts a slice of entities
+func (m *Mapper[T, D]) ToDTOs(entities []T) []D {
+	dtos := make([]D, len(entities))
+	for i, entity := range entities {
+		dtos[i] = m.toDTO(entity)
+	}
+	return dtos
+}
+
+// FromDTO converts a DTO
+func (m *Mapper[T, D]) FromDTO(value D) T {
+	return m.fromDTO(value)
+}
+
+// Changes converts the masked fields of a DTO into a column → value map for UpdateWhere
+// Mask paths name DTO fields; nested paths, unknown fields and read-only columns are
+// rejected with errors.ErrInvalidQueryParams. An empty mask is rejected as well, so a
+// missing mask never overwrites every column with zero values.
+func (m *Mapper[T, D]) Changes(value D, mask []string) (map[string]interface{}, error) {
+	m.mu.RLock()
+	namer := m.namer
+	m.mu.RUnlock()
+	return m.changes(value, mask, namer)
+}
+
+// changes builds the column map of Changes with columns named by namer, GORM's default when nil
+func (m *Mapper[T, D]) changes(value D, mask []string, namer schema.Namer) (map[string]interface{}, error) {
+	if namer == nil {
+		namer = schema.NamingStrategy{}
+	}
+	if len(mask) == 0 {
+		return nil, fmt.Errorf("%w: update mask is empty", uowerrors.ErrInvalidQueryParams)
+	}
+
+	entity := m.fromDTO(value)
+	v := reflect.ValueOf(entity)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("%w: mapper returned a nil entity", uowerrors.ErrInvalidQueryParams)
+		}
+		v = v.Elem()
+	}
+
+	s, err := schema.Parse(entity, &sync.Map{}, namer)
+	if err != nil {
+		return nil, fmt.Errorf("dto: %w", err)
+	}
+
+	changes := make(map[string]interface{}, len(mask))
+	for _, path := range mask {
+		if strings.Contains(path, ".") {
+			return nil, fmt.Errorf("%w: nested update path %q is not supported", uowerrors.ErrInvalidQueryParams, path)
+		}
+		field := m.lookup(s, path)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("%w: unknown update path %q", uowerrors.ErrInvalidQueryParams, path)
+		}
+		if readOnlyColumns[field.DBName] || readOnlyFields[field.Name] || field.PrimaryKey {
+			return nil, fmt.Errorf("%w: %q cannot be updated", uowerrors.ErrInvalidQueryParams, path)
+		}
+		changes[field.DBName] = v.FieldByIndex(field.StructField.Index).Interface()
+	}
+	return changes, nil
+}
+
+// Update applies the masked DTO fields to the matching rows and returns the updated entity
+func (m *Mapper[T, D]) Update(ctx context.Context, uow persistence.IUnitOfWork[T], id identifier.IIdentifier, value D, mask []string) (T, error) {
+	var zero T
+	m.mu.RLock()
+	namer := m.namer
+	m.mu.RUnlock()
+	if named, ok := uow.(namingStrategist); ok && namer == nil {
+		namer = named.NamingStrategy()
+	}
+	changes, err := m.changes(value, mask, namer)
+	if err != nil {
+		return zero, err
+	}
+
+	affected, err := uow.UpdateWhere(ctx, id, changes)
+	if err != nil {
+		return zero, err
+	}
+	if affected == 0 {
+		return zero, fmt.Errorf("%w: no rows matched the update", uowerrors.ErrEntityNotFound)
+	}
+	return uow.FindOneByIdentifier(ctx, id)
+}
+
+// lookup resolves a DTO path to an entity field
+func (m *Mapper[T, D]) lookup(s *schema.Schema, path string) *schema.Field {
+	m.mu.RLock()
+	name, ok := m.fields[path]
+	m.mu.RUnlock()
+	if ok {
+		if field := s.LookUpField(name); field != nil {
+			return field
+		}
+		path = name
+	}
+
+	for _, field := range s.Fields {
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == path || field.DBName == path || strings.EqualFold(field.Name, path) {
+			return field
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package dto
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+type account struct {
+	ID        int            `gorm:"primaryKey" json:"id"`
+	Name      string         `json:"name"`
+	Email     string         `json:"email"`
+	Active    bool           `json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
+
+func (a *account) GetID() int                    { return a.ID }
+func (a *account) GetSlug() string               { return "" }
+func (a *account) SetSlug(string)                {}
+func (a *account) GetCreatedAt() time.Time       { return a.CreatedAt }
+func (a *account) GetUpdatedAt() time.Time       { return a.CreatedAt }
+func (a *account) GetArchivedAt() gorm.DeletedAt { return a.DeletedAt }
+func (a *account) GetName() string               { return a.Name }
+
+type accountMessage struct {
+	Id          int64
+	DisplayName string
+	Email       string
+	Active      bool
+}
+
+func toMessage(a *account) accountMessage {
+	return accountMessage{Id: int64(a.ID), DisplayName: a.Name, Email: a.Email, Active: a.Active}
+}
+
+func fromMessage(m accountMessage) *account {
+	return &account{ID: int(m.Id), Name: m.DisplayName, Email: m.Email, Active: m.Active}
+}
+
+// stubUnitOfWork records partial updates; unused methods panic through the nil embedded interface
+type stubUnitOfWork struct {
+	persistence.IUnitOfWork[*account]
+	updates  map[string]interface{}
+	affected int64
+}
+
+func (s *stubUnitOfWork) UpdateWhere(_ context.Context, _ identifier.IIdentifier, updates map[string]interface{}) (int64, error) {
+	s.updates = updates
+	return s.affected, nil
+}
+
+func (s *stubUnitOfWork) FindOneByIdentifier(context.Context, identifier.IIdentifier, ...domain.FindOption) (*account, error) {
+	return &account{ID: 1, Name: s.updates["name"].(string)}, nil
+}
+
+// namedUnitOfWork is a stub whose connection names columns with a custom strategy
+type namedUnitOfWork struct {
+	stubUnitOfWork
+	namer schema.Namer
+}
+
+func (n *namedUnitOfWork) NamingStrategy() schema.Namer { return n.namer }
+
+func TestRegistry(t *testing.T) {
+	Register(toMessage, fromMessage)
+
+	message, err := ToDTO[accountMessage](&account{ID: 3, Name: "Ada"})
+	require.NoError(t, err)
+	assert.Equal(t, accountMessage{Id: 3, DisplayName: "Ada"}, message)
+
+	entity, err := FromDTO[*account](accountMessage{Id: 4, Email: "a@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, entity.ID)
+	assert.Equal(t, "a@example.com", entity.Email)
+
+	_, err = ToDTO[string](&account{})
+	assert.Error(t, err)
+}
+
+func TestMapper_Changes(t *testing.T) {
+	mapper := NewMapper(toMessage, fromMessage).Field("display_name", "Name")
+	message := accountMessage{Id: 1, DisplayName: "Grace", Email: "g@example.com"}
+
+	changes, err := mapper.Changes(message, []string{"display_name", "active"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Grace", "active": false}, changes)
+
+	for _, mask := range [][]string{nil, {"id"}, {"created_at"}, {"unknown"}, {"email.domain"}} {
+		_, err := mapper.Changes(message, mask)
+		assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams, mask)
+	}
+}
+
+func TestMapper_Update(t *testing.T) {
+	mapper := NewMapper(toMessage, fromMessage).Field("display_name", "Name")
+	uow := &stubUnitOfWork{affected: 1}
+
+	updated, err := mapper.Update(context.Background(), uow, identifier.ByID(1), accountMessage{DisplayName: "Linus", Email: "ignored"}, []string{"display_name"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Linus"}, uow.updates)
+	assert.Equal(t, "Linus", updated.Name)
+
+	uow.affected = 0
+	_, err = mapper.Update(context.Background(), uow, identifier.ByID(2), accountMessage{DisplayName: "Nobody"}, []string{"display_name"})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityNotFound)
+}
+
+func TestMapper_NamingStrategy(t *testing.T) {
+	namer := schema.NamingStrategy{NameReplacer: strings.NewReplacer("Name", "Label", "CreatedAt", "Inserted")}
+	message := accountMessage{DisplayName: "Grace"}
+
+	changes, err := NewMapper(toMessage, fromMessage).Field("display_name", "Name").NamingStrategy(namer).Changes(message, []string{"display_name"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"label": "Grace"}, changes)
+
+	// Read-only fields stay read-only under their renamed columns
+	_, err = NewMapper(toMessage, fromMessage).NamingStrategy(namer).Changes(message, []string{"inserted"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	// Without an explicit strategy, Update names columns the way the unit of work's connection does
+	uow := &namedUnitOfWork{namer: namer}
+	_, err = NewMapper(toMessage, fromMessage).Field("display_name", "Name").Update(context.Background(), uow, identifier.ByID(1), message, []string{"display_name"})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityNotFound)
+	assert.Equal(t, map[string]interface{}{"label": "Grace"}, uow.updates)
+}
@@ -0,0 +1,208 @@
+// Package enum maps Go string constants to native PostgreSQL enum types
+//
+// Declare the type once, wire its methods on the Go type, and migrate it before the tables using it:
+//
+//	type Status string
+//
+//	var Statuses = enum.Define[Status]("post_status", "draft", "published", "archived")
+//
+//	func (s Status) Valid() bool                  { return Statuses.Valid(s) }
+//	func (s Status) Value() (driver.Value, error) { return Statuses.Value(s) }
+//	func (s *Status) Scan(src interface{}) error  { return Statuses.Scan(s, src) }
+//	func (Status) GormDataType() string           { return Statuses.Name() }
+//
+//	err := enum.Migrate(ctx, db, enum.All()...)
+//
+// Units of work reject identifiers comparing an enum column with an undeclared value
+// before the query is sent, e.g. identifier.New().Equal("status", "publisehd").
+package enum
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// Validator is implemented by Go enum types; queries use it to reject undeclared values
+type Validator interface {
+	Valid() bool
+}
+
+// Definition is a declared enum type, independent of its Go type
+type Definition interface {
+	Name() string
+	Labels() []string
+}
+
+var namePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+var (
+	registryMu sync.Mutex
+	registry   []Definition
+)
+
+// Type is a PostgreSQL enum type backed by a Go string type
+type Type[E ~string] struct {
+	name   string
+	values []E
+	index  map[E]struct{}
+}
+
+// Define declares an enum type with its values in sort order and registers it for All
+// It panics on an invalid type name, an empty or duplicate value, like regexp.MustCompile,
+// because definitions are package-level declarations
+func Define[E ~string](name string, values ...E) *Type[E] {
+	if !namePattern.MatchString(name) {
+		panic(fmt.Sprintf("enum: invalid type name %q", name))
+	}
+	if len(values) == 0 {
+		panic(fmt.Sprintf("enum: %s has no values", name))
+	}
+
+	t := &Type[E]{name: name, values: values, index: make(map[E]struct{}, len(values))}
+	for _, value := range values {
+		if value == "" {
+			panic(fmt.Sprintf("enum: %s has an empty value", name))
+		}
+		if _, dup := t.index[value]; dup {
+			panic(fmt.Sprintf("enum: %s declares %q twice", name, value))
+		}
+		t.index[value] = struct{}{}
+	}
+
+	registryMu.Lock()
+	registry = append(registry, t)
+	registryMu.Unlock()
+	return t
+}
+
+// All returns every defined enum type in definition order
+func All() []Definition {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return append([]Definition(nil), registry...)
+}
+
+// Name returns the database type name
+func (t *Type[E]) Name() string {
+	return t.name
+}
+
+// Values returns the declared values in sort order
+func (t *Type[E]) Values() []E {
+	return append([]E(nil), t.values...)
+}
+
+// Labels returns the declared values as strings
+func (t *Type[E]) Labels() []string {
+	labels := make([]string, len(t.values))
+	for i, value := range t.values {
+		labels[i] = string(value)
+	}
+	return labels
+}
+
+// Valid reports whether a value is declared
+func (t *Type[E]) Valid(value E) bool {
+	_, ok := t.index[value]
+	return ok
+}
+
+// Parse converts input such as a query parameter, rejecting undeclared values with errors.ErrEntityValidation
+func (t *Type[E]) Parse(s string) (E, error) {
+	value := E(s)
+	if !t.Valid(value) {
+		return "", t.invalid(s)
+	}
+	return value, nil
+}
+
+// Value implements driver.Valuer for the Go type; the zero value is stored as NULL
+func (t *Type[E]) Value(value E) (driver.Value, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if !t.Valid(value) {
+		return nil, t.invalid(string(value))
+	}
+	return string(value), nil
+}
+
+// Scan implements sql.Scanner for the Go type; NULL scans as the zero value
+func (t *Type[E]) Scan(dest *E, src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*dest = ""
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("enum: cannot scan %T into %s", src, t.name)
+	}
+
+	value, err := t.Parse(s)
+	if err != nil {
+		return err
+	}
+	*dest = value
+	return nil
+}
+
+func (t *Type[E]) invalid(value string) error {
+	return fmt.Errorf("%w: %q is not a %s value (want one of %s)", uowerrors.ErrEntityValidation, value, t.name, strings.Join(t.Labels(), ", "))
+}
+
+// Statements returns the idempotent SQL creating the type and adding values declared since it was created
+// New values are appended after the existing ones; reordering or removing values needs a hand-written migration
+func Statements(definition Definition) []string {
+	labels := definition.Labels()
+	quoted := make([]string, len(labels))
+	for i, label := range labels {
+		quoted[i] = quoteLiteral(label)
+	}
+
+	name := quoteName(definition.Name())
+	statements := []string{fmt.Sprintf(
+		"DO $$ BEGIN CREATE TYPE %s AS ENUM (%s); EXCEPTION WHEN duplicate_object THEN NULL; END $$",
+		name, strings.Join(quoted, ", "),
+	)}
+	for _, label := range quoted {
+		statements = append(statements, fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s", name, label))
+	}
+	return statements
+}
+
+// Migrate creates or extends the enum types; run it before AutoMigrate of the tables using them
+// ALTER TYPE ... ADD VALUE cannot run inside a transaction block before PostgreSQL 12, so pass a non-transactional db
+func Migrate(ctx context.Context, db *gorm.DB, definitions ...Definition) error {
+	for _, definition := range definitions {
+		for _, statement := range Statements(definition) {
+			if err := db.WithContext(ctx).Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to migrate enum %s: %w", definition.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func quoteName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + part + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,61 @@
+package enum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+type color string
+
+var colors = Define[color]("test_color", "red", "green", "o'range")
+
+func TestType(t *testing.T) {
+	assert.Equal(t, "test_color", colors.Name())
+	assert.Equal(t, []color{"red", "green", "o'range"}, colors.Values())
+	assert.True(t, colors.Valid("red"))
+	assert.False(t, colors.Valid("blue"))
+	assert.Contains(t, All(), Definition(colors))
+
+	parsed, err := colors.Parse("green")
+	require.NoError(t, err)
+	assert.Equal(t, color("green"), parsed)
+	_, err = colors.Parse("blue")
+	assert.ErrorIs(t, err, uowerrors.ErrEntityValidation)
+
+	value, err := colors.Value("red")
+	require.NoError(t, err)
+	assert.Equal(t, "red", value)
+	value, err = colors.Value("")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	_, err = colors.Value("blue")
+	assert.ErrorIs(t, err, uowerrors.ErrEntityValidation)
+
+	var scanned color
+	require.NoError(t, colors.Scan(&scanned, []byte("green")))
+	assert.Equal(t, color("green"), scanned)
+	require.NoError(t, colors.Scan(&scanned, nil))
+	assert.Equal(t, color(""), scanned)
+	assert.Error(t, colors.Scan(&scanned, "blue"))
+	assert.Error(t, colors.Scan(&scanned, 42))
+}
+
+func TestDefine_Rejects(t *testing.T) {
+	assert.Panics(t, func() { Define[color]("Bad Name", "a") })
+	assert.Panics(t, func() { Define[color]("no_values") })
+	assert.Panics(t, func() { Define[color]("dup", "a", "a") })
+	assert.Panics(t, func() { Define[color]("empty", "") })
+}
+
+func TestStatements(t *testing.T) {
+	assert.Equal(t, []string{
+		`DO $$ BEGIN CREATE TYPE "test_color" AS ENUM ('red', 'green', 'o''range'); EXCEPTION WHEN duplicate_object THEN NULL; END $$`,
+		`ALTER TYPE "test_color" ADD VALUE IF NOT EXISTS 'red'`,
+		`ALTER TYPE "test_color" ADD VALUE IF NOT EXISTS 'green'`,
+		`ALTER TYPE "test_color" ADD VALUE IF NOT EXISTS 'o''range'`,
+	}, Statements(colors))
+}
@@ -12,12 +12,15 @@ var (
 	ErrTransactionAlreadyOpen    = errors.New("transaction is already open")
 	ErrTransactionCommitFailed   = errors.New("failed to commit transaction")
 	ErrTransactionRollbackFailed = errors.New("failed to rollback transaction")
+	ErrSavepointNotFound         = errors.New("savepoint not found")
 
 	// Entity errors
-	ErrEntityNotFound   = errors.New("entity not found")
-	ErrEntityExists     = errors.New("entity already exists")
-	ErrInvalidEntity    = errors.New("invalid entity")
-	ErrEntityValidation = errors.New("entity validation failed")
+	ErrEntityNotFound    = errors.New("entity not found")
+	ErrEntityExists      = errors.New("entity already exists")
+	ErrInvalidEntity     = errors.New("invalid entity")
+	ErrEntityValidation  = errors.New("entity validation failed")
+	ErrInvalidTransition = errors.New("invalid state transition")
+	ErrHasDependents     = errors.New("entity has dependent rows")
 
 	// Repository errors
 	ErrRepositoryNotFound    = errors.New("repository not found")
@@ -25,15 +28,19 @@ var (
 	ErrRepositoryOperation   = errors.New("repository operation failed")
 
 	// Database errors
-	ErrDatabaseConnection = errors.New("database connection failed")
-	ErrDatabaseTimeout    = errors.New("database operation timeout")
-	ErrDatabaseConstraint = errors.New("database constraint violation")
-	ErrDatabaseDeadlock   = errors.New("database deadlock detected")
+	ErrDatabaseConnection     = errors.New("database connection failed")
+	ErrDatabaseTimeout        = errors.New("database operation timeout")
+	ErrDatabaseConstraint     = errors.New("database constraint violation")
+	ErrDatabaseDeadlock       = errors.New("database deadlock detected")
+	ErrSerializationFailure   = errors.New("could not serialize access due to concurrent update")
+	ErrRateLimited            = errors.New("database operation rate limited")
+	ErrDeadlineBudgetExceeded = errors.New("deadline budget exceeded")
 
 	// Query errors
 	ErrInvalidQuery       = errors.New("invalid query")
 	ErrQueryExecution     = errors.New("query execution failed")
 	ErrInvalidQueryParams = errors.New("invalid query parameters")
+	ErrUnscopedOperation  = errors.New("operation would affect the whole table; call AllowFullTableOperation() to confirm")
 )
 
 // UnitOfWorkError wraps errors with context information
@@ -81,6 +88,26 @@ func (e *UnitOfWorkError) Is(target error) bool {
 	return errors.Is(e.Err, target)
 }
 
+// TransitionError reports a state change an entity's state machine does not allow
+// It matches ErrInvalidTransition with errors.Is
+type TransitionError struct {
+	Entity   string // Entity table
+	EntityID int
+	Field    string // State column
+	From     string
+	To       string
+}
+
+// Error implements the error interface
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%v: %s %d cannot move %s from %q to %q", ErrInvalidTransition, e.Entity, e.EntityID, e.Field, e.From, e.To)
+}
+
+// Unwrap returns ErrInvalidTransition
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
 // NewUnitOfWorkError creates a new structured error
 func NewUnitOfWorkError(op, entity string, err error, code ErrorCode) *UnitOfWorkError {
 	return &UnitOfWorkError{
@@ -181,3 +208,24 @@ func IsDeadlock(err error) bool {
 	}
 	return errors.Is(err, ErrDatabaseDeadlock)
 }
+
+// sqlStateError is implemented by driver errors carrying a SQLSTATE, such as pgconn.PgError
+type sqlStateError interface {
+	SQLState() string
+}
+
+// IsRetryable checks if a transaction failed on a serialization failure (SQLSTATE 40001) or a
+// deadlock (40P01); running it again from the start may succeed
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var stateErr sqlStateError
+	if errors.As(err, &stateErr) {
+		switch stateErr.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+	return IsDeadlock(err) || errors.Is(err, ErrSerializationFailure)
+}
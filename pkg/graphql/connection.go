@@ -0,0 +1,146 @@
+// Package graphql maps Unit of Work queries to Relay-style connections
+// and provides a per-request dataloader for resolving entities by ID
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+// MaxPageSize caps first/last when QueryParams.Limit does not set a lower bound
+const MaxPageSize = 100
+
+// ConnectionArgs are the Relay pagination arguments of a connection field
+type ConnectionArgs struct {
+	First  *int    `json:"first,omitempty"`
+	After  *string `json:"after,omitempty"`
+	Last   *int    `json:"last,omitempty"`
+	Before *string `json:"before,omitempty"`
+}
+
+// PageInfo is the Relay PageInfo object
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// Edge pairs a node with its cursor
+type Edge[T any] struct {
+	Node   T      `json:"node"`
+	Cursor string `json:"cursor"`
+}
+
+// Connection is a Relay connection over entities
+type Connection[T any] struct {
+	Edges    []Edge[T] `json:"edges"`
+	PageInfo PageInfo  `json:"pageInfo"`
+}
+
+// Nodes returns the connection's nodes without edges
+func (c Connection[T]) Nodes() []T {
+	nodes := make([]T, len(c.Edges))
+	for i, edge := range c.Edges {
+		nodes[i] = edge.Node
+	}
+	return nodes
+}
+
+// KeysetParams converts Relay arguments plus QueryParams into keyset parameters
+// The filter comes from query.Filter, ordering from at most one query.Sort entry,
+// and query.Limit (if set) caps first/last, defaulting to MaxPageSize
+func KeysetParams[T domain.BaseModel](args ConnectionArgs, query domain.QueryParams[T]) (domain.KeysetParams[T], error) {
+	params := domain.KeysetParams[T]{Filter: query.Filter}
+
+	if len(query.Sort) > 1 {
+		return params, fmt.Errorf("%w: connections support a single sort field", uowerrors.ErrInvalidQueryParams)
+	}
+	for field, direction := range query.Sort {
+		params.SortField, params.Direction = field, direction
+	}
+
+	maxSize := query.Limit
+	if maxSize <= 0 || maxSize > MaxPageSize {
+		maxSize = MaxPageSize
+	}
+
+	switch {
+	case args.First != nil && args.Last != nil:
+		return params, fmt.Errorf("%w: first and last cannot be combined", uowerrors.ErrInvalidQueryParams)
+	case args.First != nil:
+		if *args.First < 0 {
+			return params, fmt.Errorf("%w: first must be non-negative", uowerrors.ErrInvalidQueryParams)
+		}
+		params.Limit = *args.First
+	case args.Last != nil:
+		if *args.Last < 0 {
+			return params, fmt.Errorf("%w: last must be non-negative", uowerrors.ErrInvalidQueryParams)
+		}
+		params.Limit = *args.Last
+		params.Backward = true
+	default:
+		params.Limit = maxSize
+	}
+	if params.Limit > maxSize {
+		params.Limit = maxSize
+	}
+
+	if args.After != nil {
+		cursor, err := domain.DecodeCursor(*args.After)
+		if err != nil {
+			return params, fmt.Errorf("%w: after: %v", uowerrors.ErrInvalidQueryParams, err)
+		}
+		params.After = &cursor
+	}
+	if args.Before != nil {
+		cursor, err := domain.DecodeCursor(*args.Before)
+		if err != nil {
+			return params, fmt.Errorf("%w: before: %v", uowerrors.ErrInvalidQueryParams, err)
+		}
+		params.Before = &cursor
+	}
+
+	return params, nil
+}
+
+// NewConnection builds a connection from a keyset page
+func NewConnection[T domain.BaseModel](page domain.KeysetPage[T]) Connection[T] {
+	connection := Connection[T]{
+		Edges: make([]Edge[T], len(page.Items)),
+		PageInfo: PageInfo{
+			HasNextPage:     page.HasNext,
+			HasPreviousPage: page.HasPrevious,
+		},
+	}
+	for i, item := range page.Items {
+		connection.Edges[i] = Edge[T]{Node: item, Cursor: domain.EncodeCursor(page.Cursors[i])}
+	}
+	if n := len(connection.Edges); n > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[n-1].Cursor
+	}
+	return connection
+}
+
+// Paginate resolves a connection field through a unit of work
+func Paginate[T domain.BaseModel](ctx context.Context, uow persistence.IUnitOfWork[T], args ConnectionArgs, query domain.QueryParams[T]) (Connection[T], error) {
+	params, err := KeysetParams(args, query)
+	if err != nil {
+		return Connection[T]{}, err
+	}
+	// first: 0 is valid Relay and returns an empty connection
+	if params.Limit == 0 {
+		return Connection[T]{Edges: []Edge[T]{}}, nil
+	}
+
+	page, err := uow.FindAllWithKeyset(ctx, params)
+	if err != nil {
+		return Connection[T]{}, err
+	}
+	return NewConnection(page), nil
+}
@@ -0,0 +1,64 @@
+package graphql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+type node struct {
+	ID   int
+	Name string
+}
+
+func (n *node) GetID() int                    { return n.ID }
+func (n *node) GetSlug() string               { return "" }
+func (n *node) SetSlug(string)                {}
+func (n *node) GetCreatedAt() time.Time       { return time.Time{} }
+func (n *node) GetUpdatedAt() time.Time       { return time.Time{} }
+func (n *node) GetArchivedAt() gorm.DeletedAt { return gorm.DeletedAt{} }
+func (n *node) GetName() string               { return n.Name }
+
+func TestKeysetParams(t *testing.T) {
+	last, before := 500, domain.EncodeCursor(domain.Cursor{Value: "m", ID: 7})
+	query := domain.QueryParams[*node]{Sort: domain.SortMap{"name": domain.SortDesc}, Limit: 50}
+
+	params, err := KeysetParams(ConnectionArgs{Last: &last, Before: &before}, query)
+	require.NoError(t, err)
+	assert.Equal(t, 50, params.Limit)
+	assert.True(t, params.Backward)
+	assert.Equal(t, "name", params.SortField)
+	assert.Equal(t, domain.SortDesc, params.Direction)
+	assert.Equal(t, &domain.Cursor{Value: "m", ID: 7}, params.Before)
+
+	first, garbage := 1, "not a cursor!"
+	_, err = KeysetParams(ConnectionArgs{First: &first, Last: &last}, query)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = KeysetParams(ConnectionArgs{After: &garbage}, query)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestNewConnection(t *testing.T) {
+	connection := NewConnection(domain.KeysetPage[*node]{
+		Items:   []*node{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}},
+		Cursors: []domain.Cursor{{Value: "a", ID: 1}, {Value: "b", ID: 2}},
+		HasNext: true,
+	})
+
+	require.Len(t, connection.Edges, 2)
+	assert.Equal(t, connection.Edges[0].Cursor, *connection.PageInfo.StartCursor)
+	assert.Equal(t, connection.Edges[1].Cursor, *connection.PageInfo.EndCursor)
+	assert.True(t, connection.PageInfo.HasNextPage)
+	assert.False(t, connection.PageInfo.HasPreviousPage)
+
+	cursor, err := domain.DecodeCursor(*connection.PageInfo.EndCursor)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cursor.ID)
+	assert.Equal(t, "b", connection.Nodes()[1].Name)
+}
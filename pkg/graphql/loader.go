@@ -0,0 +1,45 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// loaderKey is a per-entity-type context key
+type loaderKey[T domain.BaseModel] struct{}
+
+// WithLoader returns a context carrying a dataloader for the entity type
+func WithLoader[T domain.BaseModel](ctx context.Context, loader *postgres.Loader[T]) context.Context {
+	return context.WithValue(ctx, loaderKey[T]{}, loader)
+}
+
+// LoaderFrom returns the dataloader attached to the context for the entity type
+func LoaderFrom[T domain.BaseModel](ctx context.Context) (*postgres.Loader[T], bool) {
+	loader, ok := ctx.Value(loaderKey[T]{}).(*postgres.Loader[T])
+	return loader, ok
+}
+
+// Load resolves an entity through the request's dataloader
+// Falls back to FindOneById on the given unit of work when no loader is attached
+func Load[T domain.BaseModel](ctx context.Context, uow persistence.IUnitOfWork[T], id int) (T, error) {
+	if loader, ok := LoaderFrom[T](ctx); ok {
+		return loader.Load(ctx, id)
+	}
+	return uow.FindOneById(ctx, id)
+}
+
+// LoaderMiddleware attaches a fresh dataloader per request so resolvers
+// fetching the same entity type share batched queries within that request
+func LoaderMiddleware[T domain.BaseModel](factory persistence.IUnitOfWorkFactory[T]) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			loader := postgres.NewLoader(factory.CreateWithContext(ctx), 0, 0)
+			next.ServeHTTP(w, r.WithContext(WithLoader(ctx, loader)))
+		})
+	}
+}
@@ -0,0 +1,359 @@
+// Package httpquery converts list-endpoint requests into QueryParams and renders paginated responses
+//
+// Supported query string:
+//
+//	?limit=20&offset=40          or  ?page=3&page_size=20
+//	?sort=name,-created_at       comma separated, "-" for descending
+//	?filter[name]=Ada            equality on whitelisted fields (json names)
+//	?include=posts,tags          whitelisted relations to preload
+package httpquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// Defaults applied when Options leaves pagination unset
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Options configures which parts of a request are accepted
+// Anything not whitelisted is rejected rather than passed to the database
+type Options struct {
+	DefaultLimit int            // Page size when none is requested, default 20
+	MaxLimit     int            // Requested page sizes are capped here, default 100
+	SortFields   []string       // Columns clients may sort by; empty disables sorting
+	FilterFields []string       // Fields (json names) clients may filter on; empty disables filtering
+	Includes     []string       // Relations clients may preload
+	DefaultSort  domain.SortMap // Used when the request has no sort parameter
+}
+
+func (o Options) limits() (defaultLimit, maxLimit int) {
+	defaultLimit, maxLimit = o.DefaultLimit, o.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = MaxLimit
+	}
+	if defaultLimit <= 0 {
+		defaultLimit = DefaultLimit
+	}
+	if defaultLimit > maxLimit {
+		defaultLimit = maxLimit
+	}
+	return defaultLimit, maxLimit
+}
+
+// Parse builds validated QueryParams from a request's query string
+// Errors wrap errors.ErrInvalidQueryParams and are safe to return to clients
+func Parse[T domain.BaseModel](r *http.Request, opts Options) (domain.QueryParams[T], error) {
+	return ParseValues[T](r.URL.Query(), opts)
+}
+
+// ParseValues builds validated QueryParams from already parsed query values
+func ParseValues[T domain.BaseModel](values url.Values, opts Options) (domain.QueryParams[T], error) {
+	var params domain.QueryParams[T]
+
+	limit, offset, err := parsePagination(values, opts)
+	if err != nil {
+		return params, err
+	}
+	params.Limit, params.Offset = limit, offset
+
+	if params.Sort, err = parseSort(values.Get("sort"), opts); err != nil {
+		return params, err
+	}
+	if params.Include, err = parseIncludes(values.Get("include"), opts.Includes); err != nil {
+		return params, err
+	}
+	if params.Filter, err = parseFilter[T](values, opts.FilterFields); err != nil {
+		return params, err
+	}
+
+	if err := params.Validate(); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// parsePagination reads limit/offset or page/page_size and applies the caps
+func parsePagination(values url.Values, opts Options) (limit, offset int, err error) {
+	defaultLimit, maxLimit := opts.limits()
+
+	limit = defaultLimit
+	if raw := firstOf(values, "limit", "page_size"); raw != "" {
+		if limit, err = nonNegative(raw, "limit"); err != nil {
+			return 0, 0, err
+		}
+		if limit == 0 {
+			limit = defaultLimit
+		}
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	if raw := values.Get("offset"); raw != "" {
+		if offset, err = nonNegative(raw, "offset"); err != nil {
+			return 0, 0, err
+		}
+	} else if raw := values.Get("page"); raw != "" {
+		page, err := nonNegative(raw, "page")
+		if err != nil {
+			return 0, 0, err
+		}
+		if page > 1 {
+			offset = (page - 1) * limit
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// parseSort parses "name,-created_at" against the sort whitelist
+func parseSort(raw string, opts Options) (domain.SortMap, error) {
+	if raw == "" {
+		return opts.DefaultSort, nil
+	}
+
+	sort := make(domain.SortMap)
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		direction := domain.SortAsc
+		if strings.HasPrefix(field, "-") {
+			field, direction = field[1:], domain.SortDesc
+		} else if strings.HasPrefix(field, "+") {
+			field = field[1:]
+		}
+		if field == "" {
+			continue
+		}
+		if !contains(opts.SortFields, field) {
+			return nil, fmt.Errorf("%w: sorting by %q is not allowed", uowerrors.ErrInvalidQueryParams, field)
+		}
+		sort[field] = direction
+	}
+	return sort, nil
+}
+
+// parseIncludes parses "posts,tags" against the include whitelist
+func parseIncludes(raw string, allowed []string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var includes []string
+	for _, include := range strings.Split(raw, ",") {
+		include = strings.TrimSpace(include)
+		if include == "" {
+			continue
+		}
+		if !contains(allowed, include) {
+			return nil, fmt.Errorf("%w: including %q is not allowed", uowerrors.ErrInvalidQueryParams, include)
+		}
+		includes = append(includes, include)
+	}
+	return includes, nil
+}
+
+// parseFilter sets filter[field]=value pairs on a new entity
+// Zero values (e.g. filter[active]=false) are ignored by the repositories' struct filters
+func parseFilter[T domain.BaseModel](values url.Values, allowed []string) (T, error) {
+	var filter T
+	var target reflect.Value
+
+	for key, raw := range values {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		name := key[len("filter[") : len(key)-1]
+		if !contains(allowed, name) {
+			return filter, fmt.Errorf("%w: filtering by %q is not allowed", uowerrors.ErrInvalidQueryParams, name)
+		}
+
+		if !target.IsValid() {
+			filter, target = newFilter[T]()
+			if !target.IsValid() {
+				return filter, fmt.Errorf("%w: %T does not support filters", uowerrors.ErrInvalidQueryParams, filter)
+			}
+		}
+
+		field, ok := fieldByJSONName(target, name)
+		if !ok {
+			return filter, fmt.Errorf("%w: unknown filter field %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		if err := setFromString(field, raw[0]); err != nil {
+			return filter, fmt.Errorf("%w: filter %q: %v", uowerrors.ErrInvalidQueryParams, name, err)
+		}
+	}
+
+	return filter, nil
+}
+
+// newFilter allocates a filter entity and returns its addressable struct value
+func newFilter[T domain.BaseModel]() (T, reflect.Value) {
+	var filter T
+	t := reflect.TypeOf(&filter).Elem()
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		v := reflect.New(t.Elem())
+		return v.Interface().(T), v.Elem()
+	}
+	return filter, reflect.Value{}
+}
+
+// fieldByJSONName finds a top-level field by its json tag (or Go name)
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == name || (jsonName == "" && strings.EqualFold(field.Name, name)) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setFromString converts a query string value into the field's type
+func setFromString(field reflect.Value, raw string) error {
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Envelope is the standard paginated JSON response body
+type Envelope[T any] struct {
+	Data []T  `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+// Meta describes the returned page
+type Meta struct {
+	Total   uint `json:"total"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	Page    int  `json:"page"`
+	Pages   int  `json:"pages"`
+	HasMore bool `json:"has_more"`
+}
+
+// NewEnvelope wraps a page of results with pagination metadata
+func NewEnvelope[T domain.BaseModel](data []T, total uint, params domain.QueryParams[T]) Envelope[T] {
+	if data == nil {
+		data = []T{}
+	}
+	page, size := params.GetPageInfo()
+
+	pages := 0
+	if size > 0 {
+		pages = int((total + uint(size) - 1) / uint(size))
+	}
+
+	return Envelope[T]{
+		Data: data,
+		Meta: Meta{
+			Total:   total,
+			Limit:   size,
+			Offset:  params.Offset,
+			Page:    page,
+			Pages:   pages,
+			HasMore: uint(params.Offset+len(data)) < total,
+		},
+	}
+}
+
+// Write renders a page of results as the standard JSON envelope
+func Write[T domain.BaseModel](w http.ResponseWriter, data []T, total uint, params domain.QueryParams[T]) error {
+	return writeJSON(w, http.StatusOK, NewEnvelope(data, total, params))
+}
+
+// WriteError renders an error as {"error": "..."}
+// Parse errors map to 400 Bad Request, everything else to 500 without leaking details
+func WriteError(w http.ResponseWriter, err error) error {
+	if errors.Is(err, uowerrors.ErrInvalidQueryParams) {
+		return writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return writeJSON(w, http.StatusInternalServerError, map[string]string{"error": http.StatusText(http.StatusInternalServerError)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(body)
+}
+
+func firstOf(values url.Values, keys ...string) string {
+	for _, key := range keys {
+		if value := values.Get(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func nonNegative(raw, name string) (int, error) {
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s must be a non-negative integer", uowerrors.ErrInvalidQueryParams, name)
+	}
+	return n, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
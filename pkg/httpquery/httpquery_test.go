@@ -0,0 +1,92 @@
+package httpquery
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+type listUser struct {
+	ID        int            `json:"id"`
+	Name      string         `json:"name"`
+	Slug      string         `json:"slug"`
+	Age       int            `json:"age"`
+	Active    bool           `json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
+
+func (u *listUser) GetID() int                    { return u.ID }
+func (u *listUser) GetSlug() string               { return u.Slug }
+func (u *listUser) SetSlug(slug string)           { u.Slug = slug }
+func (u *listUser) GetCreatedAt() time.Time       { return u.CreatedAt }
+func (u *listUser) GetUpdatedAt() time.Time       { return u.CreatedAt }
+func (u *listUser) GetArchivedAt() gorm.DeletedAt { return u.DeletedAt }
+func (u *listUser) GetName() string               { return u.Name }
+
+var listOptions = Options{
+	MaxLimit:     50,
+	SortFields:   []string{"name", "created_at"},
+	FilterFields: []string{"name", "age", "active"},
+	Includes:     []string{"posts"},
+}
+
+func TestParse(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users?page=3&page_size=500&sort=-created_at,name&filter[name]=Ada&filter[age]=36&include=posts", nil)
+
+	params, err := Parse[*listUser](r, listOptions)
+	require.NoError(t, err)
+
+	assert.Equal(t, 50, params.Limit)
+	assert.Equal(t, 100, params.Offset)
+	assert.Equal(t, domain.SortMap{"created_at": domain.SortDesc, "name": domain.SortAsc}, params.Sort)
+	assert.Equal(t, []string{"posts"}, params.Include)
+	require.NotNil(t, params.Filter)
+	assert.Equal(t, "Ada", params.Filter.Name)
+	assert.Equal(t, 36, params.Filter.Age)
+
+	params, err = Parse[*listUser](httptest.NewRequest("GET", "/users", nil), listOptions)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLimit, params.Limit)
+	assert.Nil(t, params.Filter)
+}
+
+func TestParse_RejectsUnlistedInput(t *testing.T) {
+	for _, query := range []string{
+		"sort=password",
+		"filter[slug]=x",
+		"include=secrets",
+		"limit=-1",
+		"offset=abc",
+		"filter[age]=old",
+	} {
+		_, err := Parse[*listUser](httptest.NewRequest("GET", "/users?"+query, nil), listOptions)
+		assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams, query)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	params := domain.QueryParams[*listUser]{Limit: 2, Offset: 2}
+	w := httptest.NewRecorder()
+	require.NoError(t, Write(w, []*listUser{{ID: 3}, {ID: 4}}, 5, params))
+
+	var envelope Envelope[*listUser]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Len(t, envelope.Data, 2)
+	assert.Equal(t, Meta{Total: 5, Limit: 2, Offset: 2, Page: 2, Pages: 3, HasMore: true}, envelope.Meta)
+
+	w = httptest.NewRecorder()
+	require.NoError(t, WriteError(w, errors.New("connection refused")))
+	assert.Equal(t, 500, w.Code)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
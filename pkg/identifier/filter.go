@@ -0,0 +1,299 @@
+package identifier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// FilterFields maps the field names accepted in filter expressions to database columns
+// Fields not listed are rejected, so client input never reaches SQL as an identifier
+type FilterFields map[string]string
+
+// AllowFields whitelists fields whose API name equals the column name
+func AllowFields(names ...string) FilterFields {
+	fields := make(FilterFields, len(names))
+	for _, name := range names {
+		fields[name] = name
+	}
+	return fields
+}
+
+// filterOperators maps comparison keywords to identifier operators ("" is equality)
+var filterOperators = map[string]string{
+	"eq":   "",
+	"ne":   "!=",
+	"gt":   ">",
+	"ge":   ">=",
+	"lt":   "<",
+	"le":   "<=",
+	"like": "LIKE",
+}
+
+// ParseFilter parses a filter expression into an identifier
+//
+// Grammar (keywords are case-insensitive, conditions are joined with "and"):
+//
+//	status eq 'active' and created_at gt '2024-01-01'
+//	age ge 18 and age lt 65
+//	name like 'Ada%'
+//	name eq 'O''Brien'
+//	role in ('admin', 'owner')
+//	score between 10 and 20
+//	deleted_at is null / deleted_at is not null
+//
+// Values are single-quoted strings (a quote inside one is doubled, as in the example above), numbers, true or false.
+// Errors wrap errors.ErrInvalidQuery and are safe to return to clients.
+func ParseFilter(expr string, fields FilterFields) (IIdentifier, error) {
+	p := &filterParser{input: expr, fields: fields}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+
+	id := New()
+	if len(p.tokens) == 0 {
+		return id, nil
+	}
+	for {
+		if err := p.condition(id); err != nil {
+			return nil, err
+		}
+		if p.done() {
+			return id, nil
+		}
+		if !p.keyword("and") {
+			return nil, p.errorf("expected \"and\"")
+		}
+	}
+}
+
+// filterToken is a lexical token with its byte offset for error messages
+type filterToken struct {
+	kind  byte // 'w' word, 's' string, 'n' number, or the punctuation itself
+	text  string
+	value interface{}
+	pos   int
+}
+
+type filterParser struct {
+	input  string
+	fields FilterFields
+	tokens []filterToken
+	next   int
+	used   map[string]bool
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	pos := len(p.input)
+	if p.next < len(p.tokens) {
+		pos = p.tokens[p.next].pos
+	}
+	return fmt.Errorf("%w: %s at position %d", uowerrors.ErrInvalidQuery, fmt.Sprintf(format, args...), pos)
+}
+
+func (p *filterParser) done() bool {
+	return p.next >= len(p.tokens)
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.done() {
+		return filterToken{}
+	}
+	return p.tokens[p.next]
+}
+
+// keyword consumes the next token if it is the given case-insensitive word
+func (p *filterParser) keyword(word string) bool {
+	token := p.peek()
+	if token.kind == 'w' && strings.EqualFold(token.text, word) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+// punct consumes the next token if it is the given punctuation
+func (p *filterParser) punct(c byte) bool {
+	if p.peek().kind == c {
+		p.next++
+		return true
+	}
+	return false
+}
+
+// condition parses one "field op value" clause into the identifier
+func (p *filterParser) condition(id IIdentifier) error {
+	token := p.peek()
+	if token.kind != 'w' {
+		return p.errorf("expected field name")
+	}
+	column, ok := p.fields[token.text]
+	if !ok {
+		return p.errorf("unknown filter field %q", token.text)
+	}
+	p.next++
+
+	opToken := p.peek()
+	if opToken.kind != 'w' {
+		return p.errorf("expected operator")
+	}
+	op := strings.ToLower(opToken.text)
+	p.next++
+
+	var key string
+	var value interface{}
+	switch op {
+	case "in":
+		values, err := p.list()
+		if err != nil {
+			return err
+		}
+		key, value = column+" IN", values
+	case "between":
+		start, err := p.value()
+		if err != nil {
+			return err
+		}
+		if !p.keyword("and") {
+			return p.errorf("expected \"and\" in between")
+		}
+		end, err := p.value()
+		if err != nil {
+			return err
+		}
+		key, value = column+" BETWEEN", []interface{}{start, end}
+	case "is":
+		operator := "IS NULL"
+		if p.keyword("not") {
+			operator = "IS NOT NULL"
+		}
+		if !p.keyword("null") {
+			return p.errorf("expected \"null\"")
+		}
+		key, value = column+" "+operator, true
+	default:
+		operator, ok := filterOperators[op]
+		if !ok {
+			p.next--
+			return p.errorf("unknown operator %q", opToken.text)
+		}
+		v, err := p.value()
+		if err != nil {
+			return err
+		}
+		key, value = column, v
+		if operator != "" {
+			key = column + " " + operator
+		}
+	}
+
+	if p.used == nil {
+		p.used = make(map[string]bool)
+	}
+	if p.used[key] {
+		return fmt.Errorf("%w: duplicate condition on %q", uowerrors.ErrInvalidQuery, token.text)
+	}
+	p.used[key] = true
+	id.Add(key, value)
+	return nil
+}
+
+// list parses "(value, value, ...)"
+func (p *filterParser) list() ([]interface{}, error) {
+	if !p.punct('(') {
+		return nil, p.errorf("expected \"(\"")
+	}
+	var values []interface{}
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.punct(')') {
+			return values, nil
+		}
+		if !p.punct(',') {
+			return nil, p.errorf("expected \",\" or \")\"")
+		}
+	}
+}
+
+// value parses a literal
+func (p *filterParser) value() (interface{}, error) {
+	token := p.peek()
+	switch {
+	case token.kind == 's' || token.kind == 'n':
+		p.next++
+		return token.value, nil
+	case p.keyword("true"):
+		return true, nil
+	case p.keyword("false"):
+		return false, nil
+	}
+	return nil, p.errorf("expected value")
+}
+
+// tokenize splits the input into words, literals and punctuation
+func (p *filterParser) tokenize() error {
+	s := p.input
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == ',':
+			p.tokens = append(p.tokens, filterToken{kind: c, text: string(c), pos: i})
+			i++
+		case c == '\'':
+			var b strings.Builder
+			start := i
+			i++
+			for {
+				if i >= len(s) {
+					return fmt.Errorf("%w: unterminated string at position %d", uowerrors.ErrInvalidQuery, start)
+				}
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			p.tokens = append(p.tokens, filterToken{kind: 's', text: b.String(), value: b.String(), pos: start})
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(s) && (s[i] == '.' || s[i] == 'e' || s[i] == 'E' || (s[i] >= '0' && s[i] <= '9')) {
+				i++
+			}
+			text := s[start:i]
+			var value interface{}
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				value = n
+			} else if f, err := strconv.ParseFloat(text, 64); err == nil {
+				value = f
+			} else {
+				return fmt.Errorf("%w: invalid number %q at position %d", uowerrors.ErrInvalidQuery, text, start)
+			}
+			p.tokens = append(p.tokens, filterToken{kind: 'n', text: text, value: value, pos: start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(s) && (s[i] == '_' || s[i] == '.' || unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i]))) {
+				i++
+			}
+			p.tokens = append(p.tokens, filterToken{kind: 'w', text: s[start:i], pos: start})
+		default:
+			return fmt.Errorf("%w: unexpected character %q at position %d", uowerrors.ErrInvalidQuery, c, i)
+		}
+	}
+	return nil
+}
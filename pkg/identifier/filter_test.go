@@ -0,0 +1,80 @@
+package identifier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+func TestParseFilter(t *testing.T) {
+	fields := AllowFields("status", "age", "name", "role", "deleted_at")
+	fields["created"] = "created_at"
+
+	id, err := ParseFilter(`status eq 'active' AND created gt '2024-01-01' and age ge 18 and age lt 65.5 `+
+		`and name like 'O''Brien%' and role in ('admin', 'owner') and deleted_at is not null`, fields)
+	require.NoError(t, err)
+
+	sql, args := id.ToSQL()
+	assert.Equal(t, "age < ? AND age >= ? AND created_at > ? AND deleted_at IS NOT NULL AND name LIKE ? AND role IN (?,?) AND status = ?", sql)
+	assert.Equal(t, []interface{}{65.5, int64(18), "2024-01-01", "O'Brien%", "admin", "owner", "active"}, args)
+
+	id, err = ParseFilter("age between 1 and 9 and status ne 'banned'", fields)
+	require.NoError(t, err)
+	sql, args = id.ToSQL()
+	assert.Equal(t, "age BETWEEN ? AND ? AND status != ?", sql)
+	assert.Equal(t, []interface{}{int64(1), int64(9), "banned"}, args)
+
+	id, err = ParseFilter("  ", fields)
+	require.NoError(t, err)
+	assert.True(t, id.IsEmpty())
+}
+
+func TestRender(t *testing.T) {
+	for name, id := range map[string]IIdentifier{
+		"unknown operator":  New().Equal("id", 1).Add("name ~", "^a"),
+		"scalar IN":         New().Add("id IN", 7),
+		"one BETWEEN bound": New().Add("age BETWEEN", []interface{}{1}),
+		"injected field":    New().Equal("(1=1)OR(id", 1),
+		"injected operator": New().Add("id = 1 OR", 1),
+	} {
+		_, _, err := Render(id, nil)
+		assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery, name)
+		sql, args := id.ToSQL()
+		assert.Equal(t, "1 = 0", sql, name)
+		assert.Empty(t, args, name)
+	}
+
+	sql, args, err := Render(New().Add("id IN", []int{1, 2}).Equal("deleted_at", nil).Add("name !=", nil).Equal("role", []string{"a", "b"}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "deleted_at IS NULL AND id IN (?,?) AND name IS NOT NULL AND role IN (?,?)", sql)
+	assert.Equal(t, []interface{}{1, 2, "a", "b"}, args)
+
+	quoted := PlainColumns(func(name string) string { return `"` + name + `"` })
+	sql, args, err = Render(New().Equal("users.id", 1), quoted)
+	require.NoError(t, err)
+	assert.Equal(t, `"users.id" = ?`, sql)
+	assert.Equal(t, []interface{}{1}, args)
+}
+
+func TestParseFilter_Rejects(t *testing.T) {
+	fields := AllowFields("status", "age")
+
+	for _, expr := range []string{
+		"password eq 'x'",                 // not whitelisted
+		"status = 'active'",               // unsupported syntax
+		"status eq 'active' or age gt 1",  // only "and" joins conditions
+		"status eq 'active",               // unterminated string
+		"age gt",                          // missing value
+		"age in (1, 2",                    // unterminated list
+		"status eq 'a' and status eq 'b'", // duplicate condition
+		"status eq 'a'; drop table users", // injection attempt
+		"age gt 1 and",                    // dangling conjunction
+		"status eq active",                // bare word value
+	} {
+		_, err := ParseFilter(expr, fields)
+		assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery, expr)
+	}
+}
@@ -2,7 +2,13 @@ package identifier
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
 )
 
 // IIdentifier defines the interface for query building and identification
@@ -20,6 +26,7 @@ type IIdentifier interface {
 	// Utility methods
 	Add(key string, value interface{}) IIdentifier
 	AddIf(condition bool, key string, value interface{}) IIdentifier
+	AllowFullTableOperation() IIdentifier
 
 	// Query access methods
 	ToMap() map[string]interface{}
@@ -27,12 +34,15 @@ type IIdentifier interface {
 	GetQuery() map[string]interface{}
 	Has(key string) bool
 	Get(key string) (interface{}, bool)
+	IsEmpty() bool
+	IsFullTableOperationAllowed() bool
 	String() string
 }
 
 // Identifier provides flexible query building with O(1) operations
 type Identifier struct {
-	query map[string]interface{}
+	query          map[string]interface{}
+	allowFullTable bool
 }
 
 // New creates a new identifier instance
@@ -104,51 +114,147 @@ func (i *Identifier) AddIf(condition bool, key string, value interface{}) IIdent
 	return i
 }
 
+// AllowFullTableOperation acknowledges that an empty identifier may target every row
+// Required by guarded mutations (Delete, HardDelete, UpdateWhere) to run unscoped
+func (i *Identifier) AllowFullTableOperation() IIdentifier {
+	i.allowFullTable = true
+	return i
+}
+
 // ToMap returns the query map for use with GORM
 func (i *Identifier) ToMap() map[string]interface{} {
 	return i.query
 }
 
-// ToSQL converts the identifier to SQL conditions (basic implementation)
+// ToSQL converts the identifier to SQL conditions, with fields written as given
+// An identifier that Render rejects, e.g. for an unknown operator or a field that is not a plain
+// column name, renders as a condition matching nothing rather than losing its conditions
 func (i *Identifier) ToSQL() (string, []interface{}) {
+	conditions, args, err := Render(i, nil)
+	if err != nil {
+		return "1 = 0", nil
+	}
+	return conditions, args
+}
+
+// ColumnFunc validates the field of a condition and returns it as it goes into the statement,
+// e.g. checked against the entity's columns and quoted
+type ColumnFunc func(field string) (string, error)
+
+// plainColumn matches an unquoted column name, optionally qualified by its table
+var plainColumn = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PlainColumns accepts plain column names, optionally qualified by their table, passed through
+// quote, e.g. the dialect's; a nil quote writes them as given
+func PlainColumns(quote func(name string) string) ColumnFunc {
+	return func(field string) (string, error) {
+		if !plainColumn.MatchString(field) {
+			return "", fmt.Errorf("%w: %q is not a column name", uowerrors.ErrInvalidQuery, field)
+		}
+		if quote == nil {
+			return field, nil
+		}
+		return quote(field), nil
+	}
+}
+
+// Render converts an identifier to SQL conditions joined with AND, passing each field through
+// column; a nil column accepts plain column names only, written as given.
+// A nil value compares with IS NULL (IS NOT NULL for "!=") and a list value with IN.
+// Conditions it cannot render, such as an unknown operator, a rejected field or an IN value that
+// is not a list, fail with errors.ErrInvalidQuery rather than being left out of the WHERE clause
+func Render(id IIdentifier, column ColumnFunc) (string, []interface{}, error) {
+	if id == nil {
+		return "", nil, nil
+	}
+	if column == nil {
+		column = PlainColumns(nil)
+	}
+	query := id.ToMap()
+
 	var conditions []string
 	var args []interface{}
 
-	for key, value := range i.query {
-		if strings.Contains(key, " ") {
-			// Handle operators
-			parts := strings.SplitN(key, " ", 2)
-			field, operator := parts[0], parts[1]
-
-			switch operator {
-			case "IN":
-				if vals, ok := value.([]interface{}); ok {
-					placeholders := strings.Repeat("?,", len(vals)-1) + "?"
-					conditions = append(conditions, fmt.Sprintf("%s IN (%s)", field, placeholders))
-					args = append(args, vals...)
-				}
-			case "LIKE":
-				conditions = append(conditions, fmt.Sprintf("%s LIKE ?", field))
-				args = append(args, value)
-			case ">", "<", ">=", "<=":
-				conditions = append(conditions, fmt.Sprintf("%s %s ?", field, operator))
-				args = append(args, value)
-			case "BETWEEN":
-				if vals, ok := value.([]interface{}); ok && len(vals) == 2 {
-					conditions = append(conditions, fmt.Sprintf("%s BETWEEN ? AND ?", field))
-					args = append(args, vals[0], vals[1])
+	// Sorted keys keep the statement text stable so prepared statements are reused
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := query[key]
+		field, operator, _ := strings.Cut(key, " ")
+		name, err := column(field)
+		if err != nil {
+			return "", nil, err
+		}
+		if operator == "" {
+			operator = "="
+			if vals, ok := listValues(value); ok {
+				operator, value = "IN", vals
+			}
+		}
+
+		switch operator {
+		case "IN":
+			vals, ok := listValues(value)
+			if !ok {
+				return "", nil, fmt.Errorf("%w: IN condition on %q needs a list, got %T", uowerrors.ErrInvalidQuery, field, value)
+			}
+			if len(vals) == 0 {
+				// An empty IN list matches nothing
+				conditions = append(conditions, "1 = 0")
+				continue
+			}
+			placeholders := strings.Repeat("?,", len(vals)-1) + "?"
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", name, placeholders))
+			args = append(args, vals...)
+		case "=", "!=":
+			if value == nil {
+				if operator == "=" {
+					conditions = append(conditions, name+" IS NULL")
+				} else {
+					conditions = append(conditions, name+" IS NOT NULL")
 				}
-			case "IS NULL", "IS NOT NULL":
-				conditions = append(conditions, fmt.Sprintf("%s %s", field, operator))
+				continue
 			}
-		} else {
-			// Simple equality
-			conditions = append(conditions, fmt.Sprintf("%s = ?", key))
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", name, operator))
 			args = append(args, value)
+		case "LIKE", ">", "<", ">=", "<=":
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", name, operator))
+			args = append(args, value)
+		case "BETWEEN":
+			vals, ok := listValues(value)
+			if !ok || len(vals) != 2 {
+				return "", nil, fmt.Errorf("%w: BETWEEN condition on %q needs two bounds, got %v", uowerrors.ErrInvalidQuery, field, value)
+			}
+			conditions = append(conditions, fmt.Sprintf("%s BETWEEN ? AND ?", name))
+			args = append(args, vals[0], vals[1])
+		case "IS NULL", "IS NOT NULL":
+			conditions = append(conditions, fmt.Sprintf("%s %s", name, operator))
+		default:
+			return "", nil, fmt.Errorf("%w: unsupported operator %q on %q", uowerrors.ErrInvalidQuery, operator, field)
 		}
 	}
 
-	return strings.Join(conditions, " AND "), args
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// listValues returns the elements of a slice or array value, e.g. []interface{} or []int
+func listValues(value interface{}) ([]interface{}, bool) {
+	if values, ok := value.([]interface{}); ok {
+		return values, true
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values, true
 }
 
 // Convenience constructors
@@ -172,6 +278,17 @@ func Inactive() IIdentifier {
 	return New().Equal("active", false)
 }
 
+// DeletedBetween matches rows soft-deleted between from and to, inclusive
+// Use it with GetTrashedWhere, RestoreAllWhere or PurgeTrashed
+func DeletedBetween(from, to time.Time) IIdentifier {
+	return New().Between("deleted_at", from, to)
+}
+
+// TrashedSince matches rows soft-deleted at or after since, e.g. a "recently deleted" view
+func TrashedSince(since time.Time) IIdentifier {
+	return New().Add("deleted_at >=", since)
+}
+
 // NewIdentifier creates a new identifier
 func NewIdentifier() IIdentifier {
 	return &Identifier{
@@ -216,6 +333,16 @@ func (i *Identifier) Has(key string) bool {
 	return exists
 }
 
+// IsEmpty reports whether the identifier has no conditions
+func (i *Identifier) IsEmpty() bool {
+	return len(i.query) == 0
+}
+
+// IsFullTableOperationAllowed reports whether an unscoped operation was acknowledged
+func (i *Identifier) IsFullTableOperationAllowed() bool {
+	return i.allowFullTable
+}
+
 // Get retrieves a value by key
 func (i *Identifier) Get(key string) (interface{}, bool) {
 	value, exists := i.query[key]
@@ -231,3 +358,21 @@ func NewIDIdentifier(id int64) IIdentifier {
 func NewSlugIdentifier(slug string) IIdentifier {
 	return NewIdentifier().Add("slug", slug)
 }
+
+// MapFields returns a copy of the identifier with the field of every condition passed through
+// mapping, e.g. to turn Go field names into columns; operators and values are kept
+func MapFields(id IIdentifier, mapping func(field string) string) IIdentifier {
+	if id == nil {
+		return nil
+	}
+	mapped := New()
+	for key, value := range id.ToMap() {
+		field, operator, hasOperator := strings.Cut(key, " ")
+		if field = mapping(field); hasOperator {
+			field += " " + operator
+		}
+		mapped.query[field] = value
+	}
+	mapped.allowFullTable = id.IsFullTableOperationAllowed()
+	return mapped
+}
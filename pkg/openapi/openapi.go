@@ -0,0 +1,401 @@
+// Package openapi generates OpenAPI 3 component schemas from domain models
+//
+// Schemas follow the models' json tags, so the documented bodies are exactly what
+// encoding/json produces. Registering a model also emits its list envelope
+// (httpquery.Envelope) and the list query parameters accepted by httpquery.Parse:
+//
+//	gen := openapi.NewGenerator()
+//	openapi.Register[*User](gen, "User", httpquery.Options{SortFields: []string{"name"}})
+//	doc := gen.Document("Users API", "1.0.0")
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/httpquery"
+)
+
+// Version is the OpenAPI specification version of generated documents
+const Version = "3.0.3"
+
+// Names of the shared components emitted by every generator
+const (
+	PageMetaSchema = "PageMeta"
+	ErrorSchema    = "Error"
+)
+
+// Document is a minimal OpenAPI document; Paths is left for the service to fill in
+type Document struct {
+	OpenAPI    string                 `json:"openapi"`
+	Info       Info                   `json:"info"`
+	Paths      map[string]interface{} `json:"paths"`
+	Components Components             `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the reusable schemas and parameters
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas,omitempty"`
+	Parameters map[string]*Parameter `json:"parameters,omitempty"`
+}
+
+// Schema is an OpenAPI schema object
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	ReadOnly    bool               `json:"readOnly,omitempty"`
+
+	AdditionalProperties *Schema `json:"additionalProperties,omitempty"`
+}
+
+// Parameter is an OpenAPI parameter object
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Generator collects component schemas for registered models
+// Not safe for concurrent use; build the document once at startup
+type Generator struct {
+	schemas    map[string]*Schema
+	parameters map[string]*Parameter
+	names      map[reflect.Type]string
+}
+
+// NewGenerator creates a generator holding the shared pagination and error schemas
+func NewGenerator() *Generator {
+	g := &Generator{
+		schemas:    make(map[string]*Schema),
+		parameters: make(map[string]*Parameter),
+		names:      make(map[reflect.Type]string),
+	}
+	g.schemas[PageMetaSchema] = g.objectSchema(reflect.TypeOf(httpquery.Meta{}))
+	g.schemas[ErrorSchema] = &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"error": {Type: "string"}},
+		Required:   []string{"error"},
+	}
+	return g
+}
+
+// Register adds a model schema, its list envelope ("<name>List") and list parameters ("<name>.sort", ...)
+// The sort, filter and include parameters document exactly what opts whitelists
+func Register[T domain.BaseModel](g *Generator, name string, opts httpquery.Options) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	g.names[t] = name
+	g.schemas[name] = g.objectSchema(t)
+
+	g.schemas[name+"List"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"data": {Type: "array", Items: ref(name)},
+			"meta": ref(PageMetaSchema),
+		},
+		Required: []string{"data", "meta"},
+	}
+
+	g.registerParameters(name, opts)
+}
+
+// ListParameters returns $ref objects for every list parameter of a registered model
+// Use it to fill the "parameters" of the model's list operation
+func (g *Generator) ListParameters(name string) []*Schema {
+	prefix := name + "."
+	var refs []*Schema
+	for _, key := range sortedKeys(g.parameters) {
+		if isPaginationParameter(key) || strings.HasPrefix(key, prefix) {
+			refs = append(refs, &Schema{Ref: "#/components/parameters/" + key})
+		}
+	}
+	return refs
+}
+
+// Components returns the collected schemas and parameters
+func (g *Generator) Components() Components {
+	return Components{Schemas: g.schemas, Parameters: g.parameters}
+}
+
+// Document wraps the components in an OpenAPI document
+func (g *Generator) Document(title, version string) Document {
+	return Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: version},
+		Paths:      map[string]interface{}{},
+		Components: g.Components(),
+	}
+}
+
+// WriteJSON renders the document as indented JSON
+func (g *Generator) WriteJSON(w io.Writer, title, version string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g.Document(title, version))
+}
+
+// registerParameters emits the pagination, sort, filter and include query parameters
+func (g *Generator) registerParameters(name string, opts httpquery.Options) {
+	defaultLimit, maxLimit := opts.DefaultLimit, opts.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = httpquery.MaxLimit
+	}
+	if defaultLimit <= 0 || defaultLimit > maxLimit {
+		defaultLimit = min(httpquery.DefaultLimit, maxLimit)
+	}
+
+	pageSize := &Schema{Type: "integer", Minimum: float(1), Maximum: float(maxLimit), Default: defaultLimit}
+	g.parameters[name+".limit"] = &Parameter{Name: "limit", In: "query", Description: "Maximum number of items to return", Schema: pageSize}
+	g.parameters[name+".page_size"] = &Parameter{Name: "page_size", In: "query", Description: "Page size, used with page", Schema: pageSize}
+	g.parameters["offset"] = &Parameter{Name: "offset", In: "query", Description: "Number of items to skip", Schema: &Schema{Type: "integer", Minimum: float(0)}}
+	g.parameters["page"] = &Parameter{Name: "page", In: "query", Description: "1-based page number, alternative to offset", Schema: &Schema{Type: "integer", Minimum: float(1)}}
+
+	if len(opts.SortFields) > 0 {
+		g.parameters[name+".sort"] = &Parameter{
+			Name:        "sort",
+			In:          "query",
+			Description: "Comma separated fields, prefix with - for descending. Allowed: " + strings.Join(opts.SortFields, ", "),
+			Schema:      &Schema{Type: "string", Pattern: sortPattern(opts.SortFields)},
+		}
+	}
+
+	if len(opts.Includes) > 0 {
+		g.parameters[name+".include"] = &Parameter{
+			Name:        "include",
+			In:          "query",
+			Description: "Comma separated relations to embed. Allowed: " + strings.Join(opts.Includes, ", "),
+			Schema:      &Schema{Type: "string"},
+		}
+	}
+
+	properties := g.schemas[name].Properties
+	for _, field := range opts.FilterFields {
+		schema := &Schema{Type: "string"}
+		if property, ok := properties[field]; ok && property.Ref == "" {
+			schema = &Schema{Type: property.Type, Format: property.Format}
+		}
+		g.parameters[name+".filter."+field] = &Parameter{
+			Name:        "filter[" + field + "]",
+			In:          "query",
+			Description: "Only return items whose " + field + " equals this value",
+			Schema:      schema,
+		}
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaOf describes a Go type; structs registered under a name are referenced
+func (g *Generator) schemaOf(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema *Schema
+	switch {
+	case t == timeType:
+		schema = &Schema{Type: "string", Format: "date-time"}
+	case t == deletedAtType:
+		schema = &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case t == rawJSONType:
+		schema = &Schema{}
+	default:
+		schema = g.kindSchema(t)
+	}
+
+	if nullable && schema.Ref == "" {
+		schema.Nullable = true
+	}
+	return schema
+}
+
+func (g *Generator) kindSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if name, ok := g.names[t]; ok {
+			return ref(name)
+		}
+		if reflect.PointerTo(t).Implements(marshalerType) || t.Implements(marshalerType) {
+			return &Schema{}
+		}
+		if t.Name() == "" {
+			return g.objectSchema(t)
+		}
+		// Named structs (e.g. related models not registered yet) become components,
+		// which also terminates cycles such as User.Posts -> Post.User
+		g.names[t] = t.Name()
+		g.schemas[t.Name()] = g.objectSchema(t)
+		return ref(t.Name())
+	default:
+		return &Schema{}
+	}
+}
+
+// objectSchema describes a struct's json-visible fields, flattening embedded structs
+func (g *Generator) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(schema, t)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (g *Generator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded != timeType && embedded != deletedAtType {
+				g.addFields(schema, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schemaOf(field.Type)
+		if property.Ref == "" {
+			applyGormTag(property, field.Tag.Get("gorm"))
+		}
+		schema.Properties[name] = property
+
+		if !strings.Contains(options, "omitempty") && isRequired(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyGormTag maps column constraints onto the schema (size → maxLength, generated columns → readOnly)
+func applyGormTag(schema *Schema, tag string) {
+	for _, setting := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(setting), ":")
+		switch strings.ToLower(key) {
+		case "size":
+			if n, err := strconv.Atoi(value); err == nil && schema.Type == "string" {
+				schema.MaxLength = &n
+			}
+		case "autoincrement", "autocreatetime", "autoupdatetime":
+			schema.ReadOnly = true
+		case "->":
+			schema.ReadOnly = true
+		}
+	}
+}
+
+// isRequired reports whether a field is always present in the encoded JSON
+// Pointers, slices, maps and soft-delete timestamps may be null
+func isRequired(field reflect.StructField) bool {
+	if field.Type == deletedAtType {
+		return false
+	}
+	switch field.Type.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+// sortPattern builds a regular expression accepting only whitelisted sort fields
+func sortPattern(fields []string) string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	field := "-?(" + strings.Join(quoted, "|") + ")"
+	return "^" + field + "(," + field + ")*$"
+}
+
+func isPaginationParameter(key string) bool {
+	return key == "offset" || key == "page"
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func float(n int) *float64 {
+	f := float64(n)
+	return &f
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/httpquery"
+)
+
+type author struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string         `gorm:"size:100;not null" json:"name"`
+	Age       int            `json:"age"`
+	Nickname  *string        `json:"nickname"`
+	Secret    string         `json:"-"`
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	Books     []book         `json:"books,omitempty"`
+}
+
+func (a *author) GetID() int                    { return a.ID }
+func (a *author) GetSlug() string               { return "" }
+func (a *author) SetSlug(string)                {}
+func (a *author) GetCreatedAt() time.Time       { return a.CreatedAt }
+func (a *author) GetUpdatedAt() time.Time       { return a.CreatedAt }
+func (a *author) GetArchivedAt() gorm.DeletedAt { return a.DeletedAt }
+func (a *author) GetName() string               { return a.Name }
+
+type book struct {
+	ID     int     `json:"id"`
+	Title  string  `json:"title"`
+	Author *author `json:"author,omitempty"`
+}
+
+func TestRegister(t *testing.T) {
+	gen := NewGenerator()
+	Register[*author](gen, "Author", httpquery.Options{
+		MaxLimit:     50,
+		SortFields:   []string{"name", "created_at"},
+		FilterFields: []string{"name", "age"},
+	})
+
+	components := gen.Components()
+	schema := components.Schemas["Author"]
+	require.NotNil(t, schema)
+
+	assert.Equal(t, []string{"age", "created_at", "id", "name"}, schema.Required)
+	assert.NotContains(t, schema.Properties, "Secret")
+	assert.Equal(t, "integer", schema.Properties["id"].Type)
+	assert.True(t, schema.Properties["id"].ReadOnly)
+	assert.Equal(t, 100, *schema.Properties["name"].MaxLength)
+	assert.True(t, schema.Properties["nickname"].Nullable)
+	assert.Equal(t, "date-time", schema.Properties["created_at"].Format)
+	assert.True(t, schema.Properties["deleted_at"].Nullable)
+
+	// Related structs become components; the back reference resolves to the registered model
+	assert.Equal(t, "#/components/schemas/book", schema.Properties["books"].Items.Ref)
+	assert.Equal(t, "#/components/schemas/Author", components.Schemas["book"].Properties["author"].Ref)
+
+	list := components.Schemas["AuthorList"]
+	assert.Equal(t, "#/components/schemas/Author", list.Properties["data"].Items.Ref)
+	assert.Equal(t, "#/components/schemas/PageMeta", list.Properties["meta"].Ref)
+	assert.Contains(t, components.Schemas[PageMetaSchema].Properties, "has_more")
+
+	assert.Equal(t, float64(50), *components.Parameters["Author.limit"].Schema.Maximum)
+	assert.Equal(t, "^-?(name|created_at)(,-?(name|created_at))*$", components.Parameters["Author.sort"].Schema.Pattern)
+	assert.Equal(t, "filter[age]", components.Parameters["Author.filter.age"].Name)
+	assert.Equal(t, "integer", components.Parameters["Author.filter.age"].Schema.Type)
+	assert.NotContains(t, components.Parameters, "Author.include")
+
+	var refs []string
+	for _, parameter := range gen.ListParameters("Author") {
+		refs = append(refs, strings.TrimPrefix(parameter.Ref, "#/components/parameters/"))
+	}
+	assert.Equal(t, []string{"Author.filter.age", "Author.filter.name", "Author.limit", "Author.page_size", "Author.sort", "offset", "page"}, refs)
+}
+
+func TestWriteJSON(t *testing.T) {
+	gen := NewGenerator()
+	Register[*author](gen, "Author", httpquery.Options{})
+
+	var out strings.Builder
+	require.NoError(t, gen.WriteJSON(&out, "Authors", "1.0.0"))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &doc))
+	assert.Equal(t, Version, doc["openapi"])
+	assert.Equal(t, "Authors", doc["info"].(map[string]interface{})["title"])
+}
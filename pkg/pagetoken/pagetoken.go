@@ -0,0 +1,185 @@
+// Package pagetoken implements opaque page tokens with Google AIP-158 list semantics
+//
+// A List RPC passes its page_size and page_token together with a fingerprint of the
+// parameters that must not change between pages (typically filter and order_by).
+// Tokens carry either an offset or a keyset cursor and are rejected when replayed
+// against a request with a different fingerprint.
+package pagetoken
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+// Page size defaults used when Options leaves them unset
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 1000
+)
+
+// Request holds the AIP-158 pagination fields of a List request
+type Request struct {
+	PageSize    int32  // 0 selects the default; values above the maximum are coerced down
+	PageToken   string // Empty for the first page
+	Fingerprint string // Request parameters the token is bound to, e.g. filter + order_by
+}
+
+// Options configures page sizes
+type Options struct {
+	DefaultPageSize int
+	MaxPageSize     int
+}
+
+// pageSize applies the AIP-158 rules to a requested page size
+func (o Options) pageSize(requested int32) (int, error) {
+	if requested < 0 {
+		return 0, fmt.Errorf("%w: page_size must not be negative", uowerrors.ErrInvalidQueryParams)
+	}
+	maxSize := o.MaxPageSize
+	if maxSize <= 0 {
+		maxSize = MaxPageSize
+	}
+	size := int(requested)
+	if size == 0 {
+		size = o.DefaultPageSize
+		if size <= 0 {
+			size = DefaultPageSize
+		}
+	}
+	if size > maxSize {
+		size = maxSize
+	}
+	return size, nil
+}
+
+// Token is the decoded content of a page token
+type Token struct {
+	Offset   int            `json:"o,omitempty"`
+	Cursor   *domain.Cursor `json:"c,omitempty"`
+	Checksum uint32         `json:"h"`
+}
+
+// Encode renders a token as an opaque URL-safe string
+func Encode(token Token) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a token and checks it was issued for the same fingerprint
+func Decode(encoded, fingerprint string) (Token, error) {
+	var token Token
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = json.Unmarshal(data, &token)
+	}
+	if err != nil {
+		return token, fmt.Errorf("%w: malformed page_token", uowerrors.ErrInvalidQueryParams)
+	}
+	if token.Checksum != checksum(fingerprint) {
+		return token, fmt.Errorf("%w: page_token does not match the request parameters", uowerrors.ErrInvalidQueryParams)
+	}
+	if token.Offset < 0 {
+		return token, fmt.Errorf("%w: malformed page_token", uowerrors.ErrInvalidQueryParams)
+	}
+	return token, nil
+}
+
+func checksum(fingerprint string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(fingerprint))
+	return h.Sum32()
+}
+
+// OffsetParams applies a request's page size and offset token to query parameters
+func OffsetParams[T domain.BaseModel](req Request, query domain.QueryParams[T], opts Options) (domain.QueryParams[T], error) {
+	size, err := opts.pageSize(req.PageSize)
+	if err != nil {
+		return query, err
+	}
+	query.Limit, query.Offset = size, 0
+
+	if req.PageToken != "" {
+		token, err := Decode(req.PageToken, req.Fingerprint)
+		if err != nil {
+			return query, err
+		}
+		if token.Cursor != nil {
+			return query, fmt.Errorf("%w: page_token is not an offset token", uowerrors.ErrInvalidQueryParams)
+		}
+		query.Offset = token.Offset
+	}
+	return query, nil
+}
+
+// NextOffsetToken returns the token for the page after an offset query, or "" on the last page
+func NextOffsetToken[T domain.BaseModel](query domain.QueryParams[T], returned int, total uint, fingerprint string) string {
+	next := query.Offset + returned
+	if returned == 0 || uint(next) >= total {
+		return ""
+	}
+	return Encode(Token{Offset: next, Checksum: checksum(fingerprint)})
+}
+
+// KeysetParams applies a request's page size and cursor token to keyset parameters
+func KeysetParams[T domain.BaseModel](req Request, params domain.KeysetParams[T], opts Options) (domain.KeysetParams[T], error) {
+	size, err := opts.pageSize(req.PageSize)
+	if err != nil {
+		return params, err
+	}
+	params.Limit, params.After, params.Before, params.Backward = size, nil, nil, false
+
+	if req.PageToken != "" {
+		token, err := Decode(req.PageToken, req.Fingerprint)
+		if err != nil {
+			return params, err
+		}
+		if token.Cursor == nil {
+			return params, fmt.Errorf("%w: page_token is not a keyset token", uowerrors.ErrInvalidQueryParams)
+		}
+		params.After = token.Cursor
+	}
+	return params, nil
+}
+
+// NextKeysetToken returns the token for the page after a keyset page, or "" on the last page
+func NextKeysetToken[T domain.BaseModel](page domain.KeysetPage[T], fingerprint string) string {
+	if !page.HasNext || len(page.Cursors) == 0 {
+		return ""
+	}
+	cursor := page.Cursors[len(page.Cursors)-1]
+	return Encode(Token{Cursor: &cursor, Checksum: checksum(fingerprint)})
+}
+
+// ListOffset runs an offset-paginated List call and returns the next_page_token
+func ListOffset[T domain.BaseModel](ctx context.Context, uow persistence.IUnitOfWork[T], req Request, query domain.QueryParams[T], opts Options) ([]T, string, uint, error) {
+	query, err := OffsetParams(req, query, opts)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	items, total, err := uow.FindAllWithPagination(ctx, query)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return items, NextOffsetToken(query, len(items), total, req.Fingerprint), total, nil
+}
+
+// ListKeyset runs a keyset-paginated List call and returns the next_page_token
+// Prefer it over ListOffset for large or frequently changing collections
+func ListKeyset[T domain.BaseModel](ctx context.Context, uow persistence.IUnitOfWork[T], req Request, params domain.KeysetParams[T], opts Options) ([]T, string, error) {
+	params, err := KeysetParams(req, params, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	page, err := uow.FindAllWithKeyset(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
+	return page.Items, NextKeysetToken(page, req.Fingerprint), nil
+}
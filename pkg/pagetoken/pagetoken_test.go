@@ -0,0 +1,69 @@
+package pagetoken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+type item struct{ ID int }
+
+func (i *item) GetID() int                    { return i.ID }
+func (i *item) GetSlug() string               { return "" }
+func (i *item) SetSlug(string)                {}
+func (i *item) GetCreatedAt() time.Time       { return time.Time{} }
+func (i *item) GetUpdatedAt() time.Time       { return time.Time{} }
+func (i *item) GetArchivedAt() gorm.DeletedAt { return gorm.DeletedAt{} }
+func (i *item) GetName() string               { return "" }
+
+func TestOffsetTokens(t *testing.T) {
+	opts := Options{DefaultPageSize: 10, MaxPageSize: 25}
+	fingerprint := `filter="active" order_by="name"`
+
+	query, err := OffsetParams(Request{PageSize: 100, Fingerprint: fingerprint}, domain.QueryParams[*item]{}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 25, query.Limit)
+
+	next := NextOffsetToken(query, 25, 60, fingerprint)
+	require.NotEmpty(t, next)
+
+	query, err = OffsetParams(Request{PageToken: next, Fingerprint: fingerprint}, query, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 10, query.Limit)
+	assert.Equal(t, 25, query.Offset)
+	assert.Empty(t, NextOffsetToken(domain.QueryParams[*item]{Offset: 50}, 10, 60, fingerprint))
+
+	// Tokens are bound to the parameters they were issued for
+	_, err = OffsetParams(Request{PageToken: next, Fingerprint: `filter="inactive"`}, query, opts)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = OffsetParams(Request{PageToken: "garbage", Fingerprint: fingerprint}, query, opts)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = OffsetParams(Request{PageSize: -1}, query, opts)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestKeysetTokens(t *testing.T) {
+	page := domain.KeysetPage[*item]{
+		Items:   []*item{{ID: 1}, {ID: 2}},
+		Cursors: []domain.Cursor{{ID: 1}, {ID: 2}},
+		HasNext: true,
+	}
+	next := NextKeysetToken(page, "")
+	require.NotEmpty(t, next)
+
+	params, err := KeysetParams(Request{PageSize: 2, PageToken: next}, domain.KeysetParams[*item]{}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, &domain.Cursor{ID: 2}, params.After)
+
+	_, err = OffsetParams(Request{PageToken: next}, domain.QueryParams[*item]{}, Options{})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	page.HasNext = false
+	assert.Empty(t, NextKeysetToken(page, ""))
+}
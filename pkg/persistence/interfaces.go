@@ -2,51 +2,137 @@ package persistence
 
 import (
 	"context"
+	"database/sql"
+	"io"
+	"time"
+
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
 )
 
-// IUnitOfWork defines the comprehensive Unit of Work pattern interface with generics
-type IUnitOfWork[T domain.BaseModel] interface {
+// IReadOnlyUnitOfWork is the query-only subset of IUnitOfWork
+// Hand it to reporting code that must not write; its transactions are opened READ ONLY
+type IReadOnlyUnitOfWork[T domain.BaseModel] interface {
 	// Transaction control
 	BeginTransaction(ctx context.Context) error
+	BeginTransactionWithOptions(ctx context.Context, options *sql.TxOptions) error
+	BeginReadOnlyTransaction(ctx context.Context) error
 	CommitTransaction(ctx context.Context) error
 	RollbackTransaction(ctx context.Context)
+	SetSessionVar(ctx context.Context, key string, value interface{}) error
+	SessionVar(ctx context.Context, key string) (string, bool, error)
+	SessionVarInt(ctx context.Context, key string) (int64, bool, error)
+	SessionVarBool(ctx context.Context, key string) (bool, bool, error)
 
 	// Queries
 	FindAll(ctx context.Context) ([]T, error)
 	FindAllWithPagination(ctx context.Context, query domain.QueryParams[T]) ([]T, uint, error)
-	FindOne(ctx context.Context, filter T) (T, error)
-	FindOneById(ctx context.Context, id int) (T, error)
-	FindOneByIdentifier(ctx context.Context, identifier identifier.IIdentifier) (T, error)
+	FindAllWithKeyset(ctx context.Context, query domain.KeysetParams[T]) (domain.KeysetPage[T], error)
+	FindPage(ctx context.Context, query domain.QueryParams[T]) (domain.Page[T], error)
+	FindPageByIdentifier(ctx context.Context, identifier identifier.IIdentifier, query domain.QueryParams[T]) (domain.Page[T], error)
+	FindPageWithKeyset(ctx context.Context, query domain.KeysetParams[T]) (domain.Page[T], error)
+	FindOne(ctx context.Context, filter T, options ...domain.FindOption) (T, error)
+	FindOneById(ctx context.Context, id int, options ...domain.FindOption) (T, error)
+	FindByIDs(ctx context.Context, ids []int) ([]T, error)
+	FindOneByIdentifier(ctx context.Context, identifier identifier.IIdentifier, options ...domain.FindOption) (T, error)
+	FindAllByIdentifier(ctx context.Context, identifier identifier.IIdentifier, query domain.QueryParams[T]) ([]T, uint, error)
+	SyncSince(ctx context.Context, params domain.SyncParams) (domain.SyncResult[T], error)
+	FindTopPerGroup(ctx context.Context, identifier identifier.IIdentifier, params domain.TopNParams[T]) ([]T, error)
+	Aggregate(ctx context.Context, identifier identifier.IIdentifier, params domain.AggregateParams[T]) ([]domain.AggregateRow, error)
 	ResolveIDByUniqueField(ctx context.Context, model domain.BaseModel, field string, value interface{}) (int, error)
+	ResolveIDByUniqueFields(ctx context.Context, fields map[string]interface{}) (int, error)
+	ResolveIDsByUniqueField(ctx context.Context, field string, values []interface{}) (map[interface{}]int, error)
+	ResolveIDByIdentifier(ctx context.Context, identifier identifier.IIdentifier) (int, error)
+	IsUnique(ctx context.Context, field string, value interface{}, excludingID int) (bool, error)
+
+	// History
+	FindOneAsOf(ctx context.Context, identifier identifier.IIdentifier, at time.Time) (T, error)
+	DiffBetween(ctx context.Context, identifier identifier.IIdentifier, from, to time.Time) (map[string]domain.FieldChange, error)
+
+	// Trashed Data
+	GetTrashed(ctx context.Context) ([]T, error)
+	GetTrashedWithPagination(ctx context.Context, query domain.QueryParams[T]) ([]T, uint, error)
+	GetTrashedWhere(ctx context.Context, identifier identifier.IIdentifier, query domain.QueryParams[T]) ([]T, uint, error)
+
+	// Hierarchies
+	Ancestors(ctx context.Context, id int, options domain.TreeOptions) ([]T, error)
+	Descendants(ctx context.Context, id int, options domain.TreeOptions) ([]T, error)
+
+	// Lifecycle
+	Close() error
+
+	// Export
+	Export(ctx context.Context, query domain.QueryParams[T], options domain.CSVWriterOptions, w io.Writer) error
+	ExportJSON(ctx context.Context, query domain.QueryParams[T], options domain.JSONExportOptions, w io.Writer) (int64, error)
+	ExportSnapshot(ctx context.Context, query domain.QueryParams[T], fn func(entity T) error) (int64, error)
+}
+
+// IUnitOfWork defines the comprehensive Unit of Work pattern interface with generics
+type IUnitOfWork[T domain.BaseModel] interface {
+	IReadOnlyUnitOfWork[T]
+
+	// Transaction control
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
+	AfterRollback(ctx context.Context, fn func(ctx context.Context))
+	AsRole(ctx context.Context, role string) error
+	DeferConstraints(ctx context.Context, names ...string) error
+	Savepoint(ctx context.Context, name string) error
+	RollbackTo(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
+	RunWithCompensation(ctx context.Context, steps []domain.Step) error
+	Transaction(ctx context.Context, fn func(txUow IUnitOfWork[T]) error) error
 
 	// Mutations
 	Insert(ctx context.Context, entity T) (T, error)
+	InsertReturning(ctx context.Context, entity T, columns ...string) (T, error)
+	Clone(ctx context.Context, identifier identifier.IIdentifier, mutators ...func(T)) (T, error)
 	Update(ctx context.Context, identifier identifier.IIdentifier, entity T) (T, error)
 	Delete(ctx context.Context, identifier identifier.IIdentifier) error
+	UpdateWhere(ctx context.Context, identifier identifier.IIdentifier, updates map[string]interface{}) (int64, error)
+	Touch(ctx context.Context, identifier identifier.IIdentifier, columns ...string) (int64, error)
+	JSONBSet(ctx context.Context, identifier identifier.IIdentifier, path string, value interface{}) (int64, error)
+	JSONBRemove(ctx context.Context, identifier identifier.IIdentifier, path string) (int64, error)
+	Reassign(ctx context.Context, from identifier.IIdentifier, toOwnerID int) (domain.ReassignResult, error)
+	Merge(ctx context.Context, survivor identifier.IIdentifier, duplicates identifier.IIdentifier, strategy domain.MergeStrategy) (domain.MergeResult[T], error)
+	ApplyChanges(ctx context.Context, changes []domain.ClientChange[T], options domain.SyncOptions[T]) (domain.SyncApplyResult[T], error)
 
 	// Soft & Hard Delete
 	SoftDelete(ctx context.Context, identifier identifier.IIdentifier) (T, error)
+	SoftDeleteWithReason(ctx context.Context, identifier identifier.IIdentifier, reason, actor string) (T, error)
+	SoftDeleteWhere(ctx context.Context, identifier identifier.IIdentifier, preview bool) (domain.SoftDeleteResult[T], error)
 	HardDelete(ctx context.Context, identifier identifier.IIdentifier) (T, error)
+	HardDeleteWithDependents(ctx context.Context, identifier identifier.IIdentifier, policy domain.DeletePolicy) (domain.DependentDeleteResult, error)
 
 	// Bulk operations
 	BulkInsert(ctx context.Context, entities []T) ([]T, error)
 	BulkUpdate(ctx context.Context, entities []T) ([]T, error)
+	Upsert(ctx context.Context, entity T, conflictColumns ...string) (T, domain.UpsertResult, error)
+	BulkUpsert(ctx context.Context, entities []T, conflictColumns ...string) ([]T, domain.UpsertResult, error)
+	CopyInsert(ctx context.Context, entities []T, conflictColumns ...string) (int64, error)
 	BulkSoftDelete(ctx context.Context, identifiers []identifier.IIdentifier) error
 	BulkHardDelete(ctx context.Context, identifiers []identifier.IIdentifier) error
 
-	// Trashed Data
-	GetTrashed(ctx context.Context) ([]T, error)
-	GetTrashedWithPagination(ctx context.Context, query domain.QueryParams[T]) ([]T, uint, error)
-
 	// Restore
 	Restore(ctx context.Context, identifier identifier.IIdentifier) (T, error)
+	RestoreAllWhere(ctx context.Context, identifier identifier.IIdentifier) (int64, error)
+	// Deprecated: use RestoreAllWhere, with identifier.New().AllowFullTableOperation() to restore every row
 	RestoreAll(ctx context.Context) error
+	PurgeTrashed(ctx context.Context, identifier identifier.IIdentifier) (int64, error)
+
+	// Hierarchies
+	MoveSubtree(ctx context.Context, id, newParentID int, options domain.TreeOptions) error
+
+	// Import
+	ImportJSON(ctx context.Context, r io.Reader, options domain.JSONImportOptions) (int64, error)
+
+	// Concurrency
+	Session() IUnitOfWork[T]
+	Parallel(ctx context.Context, funcs ...func(IUnitOfWork[T]) error) error
 }
 
 // IUnitOfWorkFactory creates Unit of Work instances with generics
 type IUnitOfWorkFactory[T domain.BaseModel] interface {
 	Create() IUnitOfWork[T]
 	CreateWithContext(ctx context.Context) IUnitOfWork[T]
+	CreateReadOnly(ctx context.Context) IReadOnlyUnitOfWork[T]
 }
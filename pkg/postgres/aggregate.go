@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+)
+
+// Aggregate groups the live entities matching the identifier and computes metrics per group
+// With params.Bucket the rows are also grouped by time_bucket, which requires TimescaleDB
+func (uow *UnitOfWork[T]) Aggregate(ctx context.Context, identifier identifier.IIdentifier, params domain.AggregateParams[T]) ([]domain.AggregateRow, error) {
+	db, err := aggregateQuery[T](uow.getActiveDB(ctx), identifier, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate entities: %w", err)
+	}
+
+	result := make([]domain.AggregateRow, len(rows))
+	for i, row := range rows {
+		result[i] = row
+	}
+	return result, nil
+}
+
+// aggregateQuery builds the grouped SELECT; groups are referenced by position so bucket arguments are bound once
+func aggregateQuery[T domain.BaseModel](db *gorm.DB, id identifier.IIdentifier, params domain.AggregateParams[T]) (*gorm.DB, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", uowerrors.ErrInvalidQueryParams, err)
+	}
+
+	meta := metadataIn[T](db)
+	var selects, groups []string
+	var args []interface{}
+	outputs := make(map[string]bool)
+
+	if bucket := params.Bucket; bucket != nil {
+		column, ok := meta.Field(bucket.Field)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown bucket field %q", uowerrors.ErrInvalidQueryParams, bucket.Field)
+		}
+		selects = append(selects, fmt.Sprintf("time_bucket(CAST(? AS interval), %s) AS %s", column.Qualified, quoteIdentifier(bucket.Alias())))
+		args = append(args, fmt.Sprintf("%d microseconds", bucket.Width.Microseconds()))
+		outputs[bucket.Alias()] = true
+	}
+	for _, field := range params.GroupBy {
+		column, ok := meta.Field(field)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown group field %q", uowerrors.ErrInvalidQueryParams, field)
+		}
+		selects = append(selects, column.Qualified+" AS "+quoteIdentifier(column.Column))
+		outputs[column.Column] = true
+	}
+	for i := range selects {
+		groups = append(groups, strconv.Itoa(i+1))
+	}
+	defaultOrder := strings.Join(groups, ", ")
+
+	for _, metric := range params.Metrics {
+		expression := "*"
+		if metric.Field != "" {
+			column, ok := meta.Field(metric.Field)
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown metric field %q", uowerrors.ErrInvalidQueryParams, metric.Field)
+			}
+			expression = column.Qualified
+		}
+		alias := metric.Alias()
+		if outputs[alias] {
+			return nil, fmt.Errorf("%w: duplicate output name %q", uowerrors.ErrInvalidQueryParams, alias)
+		}
+		selects = append(selects, fmt.Sprintf("%s(%s) AS %s", strings.ToUpper(string(metric.Func)), expression, quoteIdentifier(alias)))
+		outputs[alias] = true
+	}
+
+	order := make([]string, 0, len(params.OrderBy))
+	for _, term := range params.OrderBy {
+		if !outputs[term.Field] {
+			return nil, fmt.Errorf("%w: unknown order field %q", uowerrors.ErrInvalidQueryParams, term.Field)
+		}
+		direction := "ASC"
+		if term.Direction == domain.SortDesc {
+			direction = "DESC"
+		}
+		order = append(order, quoteIdentifier(term.Field)+" "+direction)
+	}
+
+	db = applyEntityIdentifier[T](db.Model(new(T)), id).Select(strings.Join(selects, ", "), args...)
+	if conditions, conditionArgs := meta.filterConditions(params.Filter, false); conditions != "" {
+		db = db.Where(conditions, conditionArgs...)
+	}
+	if len(groups) > 0 {
+		db = db.Clauses(clause.GroupBy{Columns: []clause.Column{{Name: strings.Join(groups, ", "), Raw: true}}})
+	}
+	switch {
+	case len(order) > 0:
+		db = db.Order(strings.Join(order, ", "))
+	case defaultOrder != "":
+		db = db.Order(defaultOrder)
+	}
+	return db.Limit(params.Limit), nil
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+)
+
+// Dedicated archive columns; entities having both store the reason and actor in their own row
+const (
+	deletedReasonColumn = "deleted_reason"
+	deletedByColumn     = "deleted_by"
+)
+
+// hasArchiveColumns reports whether an entity stores archive metadata in its own row
+func (uow *UnitOfWork[T]) hasArchiveColumns() bool {
+	meta := uow.metadata()
+	_, hasReason := meta.Field(deletedReasonColumn)
+	_, hasActor := meta.Field(deletedByColumn)
+	return hasReason && hasActor
+}
+
+// restoreUpdates returns the columns a restore resets: deleted_at, and the archive columns when the
+// entity has them
+func (uow *UnitOfWork[T]) restoreUpdates() map[string]interface{} {
+	updates := map[string]interface{}{"deleted_at": nil}
+	if uow.hasArchiveColumns() {
+		updates[deletedReasonColumn] = ""
+		updates[deletedByColumn] = ""
+	}
+	return updates
+}
+
+// SoftDeleteWithReason soft-deletes an entity and records why and by whom
+// Entities with deleted_reason and deleted_by columns get them set in the same update; others get a
+// row in the uow_archive_metadata table (domain.ArchiveMetadata), replacing any earlier one
+func (uow *UnitOfWork[T]) SoftDeleteWithReason(ctx context.Context, identifier identifier.IIdentifier, reason, actor string) (T, error) {
+	var entity T
+	db := uow.getActiveDB(ctx)
+
+	if err := applyEntityIdentifier[T](db, identifier).First(&entity).Error; err != nil {
+		return entity, fmt.Errorf("failed to find entity for soft delete: %w", err)
+	}
+
+	now := time.Now()
+	if uow.hasArchiveColumns() {
+		updates := map[string]interface{}{
+			"deleted_at":        now,
+			deletedReasonColumn: reason,
+			deletedByColumn:     actor,
+		}
+		if err := db.Model(&entity).UpdateColumns(updates).Error; err != nil {
+			return entity, fmt.Errorf("failed to soft delete entity: %w", err)
+		}
+		if err := db.Unscoped().First(&entity, entity.GetID()).Error; err != nil {
+			return entity, fmt.Errorf("failed to reload soft-deleted entity: %w", err)
+		}
+	} else {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&entity).Error; err != nil {
+				return fmt.Errorf("failed to soft delete entity: %w", err)
+			}
+			record := &domain.ArchiveMetadata{
+				EntityTable: uow.metadata().Table,
+				EntityID:    entity.GetID(),
+				Reason:      reason,
+				Actor:       actor,
+				ArchivedAt:  now,
+			}
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(record).Error; err != nil {
+				return fmt.Errorf("failed to record archive metadata: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return entity, err
+		}
+		if setter, ok := any(entity).(domain.ArchiveInfoSetter); ok {
+			setter.SetArchiveInfo(reason, actor)
+		}
+	}
+
+	uow.recordChanges(ctx, domain.ChangeDeleted, entity)
+	return entity, nil
+}
+
+// attachArchiveInfo fills trashed entities implementing domain.ArchiveInfoSetter from the archive metadata table
+func (uow *UnitOfWork[T]) attachArchiveInfo(ctx context.Context, entities []T) error {
+	if len(entities) == 0 || uow.hasArchiveColumns() {
+		return nil
+	}
+	if _, ok := any(entities[0]).(domain.ArchiveInfoSetter); !ok {
+		return nil
+	}
+
+	ids := make([]int, len(entities))
+	for i, entity := range entities {
+		ids[i] = entity.GetID()
+	}
+
+	var records []domain.ArchiveMetadata
+	err := uow.getActiveDB(ctx).Session(&gorm.Session{NewDB: true}).
+		Where("entity_table = ? AND entity_id IN ?", uow.metadata().Table, ids).
+		Find(&records).Error
+	if err != nil {
+		return fmt.Errorf("failed to load archive metadata: %w", err)
+	}
+
+	byID := make(map[int]domain.ArchiveMetadata, len(records))
+	for _, record := range records {
+		byID[record.EntityID] = record
+	}
+	for _, entity := range entities {
+		if record, ok := byID[entity.GetID()]; ok {
+			any(entity).(domain.ArchiveInfoSetter).SetArchiveInfo(record.Reason, record.Actor)
+		}
+	}
+	return nil
+}
+
+// clearArchiveInfo drops the archive metadata of restored entities implementing domain.ArchiveInfoSetter
+func (uow *UnitOfWork[T]) clearArchiveInfo(ctx context.Context, ids []int) error {
+	if len(ids) == 0 || uow.hasArchiveColumns() {
+		return nil
+	}
+	if _, ok := any(newEntity[T]()).(domain.ArchiveInfoSetter); !ok {
+		return nil
+	}
+	err := uow.getActiveDB(ctx).Session(&gorm.Session{NewDB: true}).
+		Where("entity_table = ? AND entity_id IN ?", uow.metadata().Table, ids).
+		Delete(&domain.ArchiveMetadata{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to clear archive metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlobStore is the object storage (S3, GCS, a directory) holding files that rows refer to by key
+// Deleting a missing object must succeed, as a deletion may be retried
+type BlobStore interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// OrphanBlob is an object that no committed row may refer to, awaiting deletion
+type OrphanBlob struct {
+	Key       string    `gorm:"primaryKey;size:1024"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+// TableName implements schema.Tabler
+func (OrphanBlob) TableName() string {
+	return "uow_orphan_blobs"
+}
+
+// MigrateBlobRefs creates the table of orphaned objects used by BlobRefs
+func MigrateBlobRefs(ctx context.Context, db *gorm.DB) error {
+	if err := db.WithContext(ctx).AutoMigrate(&OrphanBlob{}); err != nil {
+		return fmt.Errorf("failed to create orphan blob table: %w", err)
+	}
+	return nil
+}
+
+// BlobRefs keeps objects in blob storage in step with the rows of a unit of work's transaction.
+// Objects a rolled-back transaction uploaded, and objects a committed one stopped referring to,
+// are deleted right after the transaction ends. Each is recorded in uow_orphan_blobs first, so
+// when that deletion fails or the process dies mid-transaction, SweepOrphanBlobs deletes it later
+type BlobRefs[T domain.BaseModel] struct {
+	uow   *UnitOfWork[T]
+	store BlobStore
+}
+
+// BlobRefs coordinates the store's objects with the unit of work's transactions
+// Create the table with MigrateBlobRefs first
+func (uow *UnitOfWork[T]) BlobRefs(store BlobStore) *BlobRefs[T] {
+	return &BlobRefs[T]{uow: uow, store: store}
+}
+
+// Track registers an object the open transaction's rows will refer to; call it before uploading.
+// The object is kept when the transaction commits and deleted when it rolls back
+func (b *BlobRefs[T]) Track(ctx context.Context, key string) error {
+	uow := b.uow
+	if !uow.inTx || uow.tx == nil {
+		return fmt.Errorf("failed to track blob %q: %w", key, uowerrors.ErrTransactionNotStarted)
+	}
+
+	// Recorded outside the transaction, so the object counts as orphaned unless the transaction
+	// commits the removal below
+	orphan := OrphanBlob{Key: key, CreatedAt: time.Now()}
+	if err := uow.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&orphan).Error; err != nil {
+		return fmt.Errorf("failed to track blob %q: %w", key, err)
+	}
+	if err := uow.connection(ctx).Delete(&OrphanBlob{Key: key}).Error; err != nil {
+		return fmt.Errorf("failed to track blob %q: %w", key, err)
+	}
+
+	uow.AfterRollback(ctx, func(ctx context.Context) {
+		b.remove(ctx, key)
+	})
+	return nil
+}
+
+// Release marks an object the transaction's rows no longer refer to, e.g. a replaced avatar
+// It is deleted when the transaction commits and kept when it rolls back; outside a transaction
+// it is deleted right away
+func (b *BlobRefs[T]) Release(ctx context.Context, key string) error {
+	orphan := OrphanBlob{Key: key, CreatedAt: time.Now()}
+	if err := b.uow.connection(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&orphan).Error; err != nil {
+		return fmt.Errorf("failed to release blob %q: %w", key, err)
+	}
+	b.uow.AfterCommit(ctx, func(ctx context.Context) {
+		b.remove(ctx, key)
+	})
+	return nil
+}
+
+// remove deletes an orphaned object and its record; on failure the record stays for SweepOrphanBlobs
+// After a RollbackTo the record is deleted in the still open transaction, which sees it again
+func (b *BlobRefs[T]) remove(ctx context.Context, key string) {
+	if err := b.store.Delete(ctx, key); err != nil {
+		return
+	}
+	b.uow.connection(ctx).Delete(&OrphanBlob{Key: key})
+}
+
+// SweepOrphanBlobs deletes the objects recorded as orphaned more than olderThan ago, returning how
+// many were deleted. olderThan must exceed the longest transaction, whose tracked uploads are
+// recorded until it commits. Objects that fail to delete stay recorded and are retried next time
+func SweepOrphanBlobs(ctx context.Context, db *gorm.DB, store BlobStore, olderThan time.Duration) (int, error) {
+	var orphans []OrphanBlob
+	if err := db.WithContext(ctx).Where("created_at < ?", time.Now().Add(-olderThan)).Order("created_at").Find(&orphans).Error; err != nil {
+		return 0, fmt.Errorf("failed to load orphaned blobs: %w", err)
+	}
+
+	deleted := 0
+	var errs []error
+	for _, orphan := range orphans {
+		if err := store.Delete(ctx, orphan.Key); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete blob %q: %w", orphan.Key, err))
+			continue
+		}
+		if err := db.WithContext(ctx).Delete(&OrphanBlob{Key: orphan.Key}).Error; err != nil {
+			errs = append(errs, fmt.Errorf("failed to forget blob %q: %w", orphan.Key, err))
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
@@ -0,0 +1,305 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+
+	"gorm.io/gorm"
+)
+
+const cacheName = "uow:cache"
+
+// Cache is a shared second-level cache (Redis, Memcached, in-process) for entities implementing domain.Cacheable
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error // A zero ttl never expires
+}
+
+// MemoryCache is an in-process Cache, suitable for a single instance and tests
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty in-process cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+// Get implements Cache
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set implements Cache
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := memoryCacheEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
+
+// UseCache invalidates cached entities of every table written through db
+// Connect calls it when Config.Cache is set; use it directly with externally managed pools
+//
+// Entries are keyed by a per-table generation; a write moves the table to a new generation,
+// once its transaction has committed, which orphans every entry cached before it
+func UseCache(db *gorm.DB, cache Cache) error {
+	if cache == nil {
+		return fmt.Errorf("no cache given")
+	}
+	return db.Use(&cachePlugin{cache: cache})
+}
+
+// cachePlugin is the gorm plugin behind UseCache
+type cachePlugin struct {
+	cache Cache
+}
+
+// Name implements gorm.Plugin
+func (p *cachePlugin) Name() string {
+	return cacheName
+}
+
+// Initialize implements gorm.Plugin
+func (p *cachePlugin) Initialize(db *gorm.DB) error {
+	// Registered after gorm's own transaction wrapper so single statements invalidate once committed
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:commit_or_rollback_transaction").Register(cacheName+":create", p.written); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:commit_or_rollback_transaction").Register(cacheName+":update", p.written); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register(cacheName+":delete", p.written); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register(cacheName+":raw", p.written)
+}
+
+// written invalidates the statement's table now, or when the unit of work's transaction commits
+// Raw statements (Exec) carry no table, so it is read from the SQL
+func (p *cachePlugin) written(db *gorm.DB) {
+	if db.Error != nil || db.DryRun {
+		return
+	}
+	table := db.Statement.Table
+	if table == "" {
+		table = writtenTable(db.Statement.SQL.String())
+	}
+	if table == "" {
+		return
+	}
+	if pending, ok := db.Statement.Context.Value(cacheWritesKey{}).(*cacheWrites); ok && inTransaction(db) {
+		pending.add(table)
+		return
+	}
+	bumpGeneration(db.Statement.Context, p.cache, table)
+}
+
+// writtenStatement matches the target table of an INSERT, UPDATE, DELETE, TRUNCATE or MERGE
+var writtenStatement = regexp.MustCompile(`(?i)^\s*(?:INSERT\s+INTO|UPDATE|DELETE\s+FROM|TRUNCATE(?:\s+TABLE)?|MERGE\s+INTO)\s+(?:ONLY\s+)?((?:"(?:[^"]|"")+"|[\w$]+)(?:\.(?:"(?:[^"]|"")+"|[\w$]+))*)`)
+
+// writtenTable returns the table a raw write statement targets, as cacheTable names it, or "" for other statements
+func writtenTable(sql string) string {
+	match := writtenStatement.FindStringSubmatch(sql)
+	if match == nil {
+		return ""
+	}
+	return cacheTable(match[1])
+}
+
+// cacheTable names a table the same way whether it comes from metadata, a statement or raw SQL:
+// unquoted and without its schema, so "public"."users" and users share one generation
+func cacheTable(table string) string {
+	var name strings.Builder
+	for i := 0; i < len(table); i++ {
+		switch c := table[i]; {
+		case c == '"':
+			for i++; i < len(table); i++ {
+				if table[i] == '"' {
+					if i+1 < len(table) && table[i+1] == '"' {
+						i++
+					} else {
+						break
+					}
+				}
+				name.WriteByte(table[i])
+			}
+		case c == '.':
+			name.Reset()
+		default:
+			name.WriteByte(c)
+		}
+	}
+	return name.String()
+}
+
+// InvalidateCache orphans the cached entities of the given tables, for writes gorm does not see,
+// such as COPY through pgx, database/sql or other services. It does nothing without UseCache
+func InvalidateCache(ctx context.Context, db *gorm.DB, tables ...string) {
+	plugin, ok := db.Config.Plugins[cacheName].(*cachePlugin)
+	if !ok {
+		return
+	}
+	for _, table := range tables {
+		bumpGeneration(ctx, plugin.cache, table)
+	}
+}
+
+// invalidateCache invalidates tables written outside gorm now, or when the transaction commits
+func (uow *UnitOfWork[T]) invalidateCache(ctx context.Context, tables ...string) {
+	if uow.inTx && uow.cacheWrites != nil {
+		for _, table := range tables {
+			uow.cacheWrites.add(table)
+		}
+		return
+	}
+	InvalidateCache(ctx, uow.db, tables...)
+}
+
+// cacheWritesKey carries the tables written inside a unit of work's transaction
+type cacheWritesKey struct{}
+
+// cacheWrites collects tables to invalidate on commit
+type cacheWrites struct {
+	mu     sync.Mutex
+	tables map[string]struct{}
+}
+
+func (w *cacheWrites) add(table string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.tables == nil {
+		w.tables = make(map[string]struct{})
+	}
+	w.tables[table] = struct{}{}
+}
+
+// drain returns and forgets the collected tables
+func (w *cacheWrites) drain() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tables := make([]string, 0, len(w.tables))
+	for table := range w.tables {
+		tables = append(tables, table)
+	}
+	w.tables = nil
+	return tables
+}
+
+// bumpGeneration orphans the table's cached entries; a failed write leaves them to expire
+func bumpGeneration(ctx context.Context, cache Cache, table string) {
+	cache.Set(ctx, generationKey(table), []byte(strconv.FormatInt(time.Now().UnixNano(), 36)), 0)
+}
+
+func generationKey(table string) string {
+	return cacheName + ":" + cacheTable(table) + ":generation"
+}
+
+// cachePolicyOf returns the entity's cache policy when caching applies to this unit of work
+// Reads inside a transaction and through default scopes (e.g. tenant filters) bypass the cache
+func (uow *UnitOfWork[T]) cachePolicyOf(ctx context.Context) (domain.CachePolicy, bool) {
+	if uow.config == nil || uow.config.Cache == nil || uow.inTx || uow.settings.hasScopes(ctx) {
+		return domain.CachePolicy{}, false
+	}
+	cacheable, ok := any(newEntity[T]()).(domain.Cacheable)
+	if !ok {
+		return domain.CachePolicy{}, false
+	}
+	policy := cacheable.CachePolicy()
+	return policy, policy.TTL > 0
+}
+
+// cachedFind serves a lookup by one column from the cache, loading and storing it on a miss
+func (uow *UnitOfWork[T]) cachedFind(ctx context.Context, policy domain.CachePolicy, column string, value interface{}, load func() (T, error)) (T, error) {
+	cache := uow.config.Cache
+	table := uow.metadata().Table
+	generation, _, err := cache.Get(ctx, generationKey(table))
+	if err != nil {
+		return load()
+	}
+	key := fmt.Sprintf("%s:%s:%s:%s=%v", cacheName, table, generation, column, value)
+
+	if data, ok, err := cache.Get(ctx, key); err == nil && ok {
+		entity := newEntity[T]()
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(entity); err == nil {
+			return entity, nil
+		}
+	}
+
+	entity, err := load()
+	if err != nil {
+		return entity, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entity); err == nil {
+		cache.Set(ctx, key, buf.Bytes(), policy.TTL)
+	}
+	return entity, nil
+}
+
+// cacheKeyOf returns the single key field a lookup filter sets, if any
+func cacheKeyOf[T domain.BaseModel](meta *modelMetadata, policy domain.CachePolicy, filter T) (string, interface{}, bool) {
+	v, ok := meta.structValue(filter)
+	if !ok {
+		return "", nil, false
+	}
+	var column string
+	var value interface{}
+	for _, field := range meta.Fields {
+		fv := v.FieldByIndex(field.Index)
+		if fv.IsZero() {
+			continue
+		}
+		if column != "" {
+			return "", nil, false
+		}
+		column, value = field.Column, fv.Interface()
+	}
+	for _, name := range policy.KeyFields {
+		if field, ok := meta.Field(name); ok && field.Column == column && column != "" {
+			return column, value, isScalar(value)
+		}
+	}
+	return "", nil, false
+}
+
+// isScalar reports whether a value prints as a stable cache key
+func isScalar(value interface{}) bool {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
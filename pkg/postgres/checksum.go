@@ -0,0 +1,473 @@
+package postgres
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	rowChecksumName    = "uow:row_checksum"
+	rowChecksumPending = rowChecksumName + ":pending"
+
+	// ChecksumColumn holds a row's checksum in models with `checksum:"true"` fields
+	ChecksumColumn = "row_checksum"
+
+	// DefaultSignBatchSize is how many rows SignRows reads and signs per statement
+	DefaultSignBatchSize = 500
+)
+
+// UseRowChecksums maintains a keyed checksum (HMAC-SHA256) over the fields tagged `checksum:"true"`
+// of every row written through db, stored in the model's row_checksum column:
+//
+//	type Payment struct {
+//		ID          int    `gorm:"primaryKey"`
+//		Amount      int64  `checksum:"true"`
+//		Beneficiary string `checksum:"true"`
+//		RowChecksum string `gorm:"size:64"`
+//	}
+//
+// Rows are re-signed from their stored values after each create and update, inside the statement's
+// transaction. A row that fails verification before an update is not re-signed, so application
+// writes never launder a tampered row. Writes bypassing GORM's create and update (Exec, raw SQL,
+// other clients) leave the checksum stale and show up in VerifyIntegrity. Rows written before
+// checksums were enabled stay unsigned until SignRows backfills them.
+// Connect calls it when Config.ChecksumKey is set; use it directly with externally managed pools
+func UseRowChecksums(db *gorm.DB, key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("no checksum key given")
+	}
+	return db.Use(&rowChecksumPlugin{key: key})
+}
+
+// rowChecksumPlugin is the gorm plugin behind UseRowChecksums
+type rowChecksumPlugin struct {
+	key     []byte
+	enabled time.Time // When the plugin was installed; rows updated since are signed on write
+}
+
+// Name implements gorm.Plugin
+func (p *rowChecksumPlugin) Name() string {
+	return rowChecksumName
+}
+
+// Initialize implements gorm.Plugin
+func (p *rowChecksumPlugin) Initialize(db *gorm.DB) error {
+	p.enabled = db.NowFunc()
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register(rowChecksumName+":create", p.created); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register(rowChecksumName+":before_update", p.updating); err != nil {
+		return err
+	}
+	return callbacks.Update().After("gorm:update").Register(rowChecksumName+":update", p.updated)
+}
+
+func rowChecksumPluginOf(db *gorm.DB) *rowChecksumPlugin {
+	if db == nil || db.Config == nil {
+		return nil
+	}
+	plugin, _ := db.Config.Plugins[rowChecksumName].(*rowChecksumPlugin)
+	return plugin
+}
+
+// checksumLayout is the part of a schema that checksums cover
+type checksumLayout struct {
+	schema     *schema.Schema
+	primaryKey *schema.Field
+	checksum   *schema.Field
+	covered    []*schema.Field
+}
+
+// checksumLayoutOf returns the schema's layout, false when it has no covered fields, no checksum
+// column or no single primary key
+func checksumLayoutOf(s *schema.Schema) (checksumLayout, bool) {
+	if s == nil || s.PrioritizedPrimaryField == nil {
+		return checksumLayout{}, false
+	}
+	layout := checksumLayout{schema: s, primaryKey: s.PrioritizedPrimaryField, checksum: s.LookUpField(ChecksumColumn)}
+	for _, field := range s.Fields {
+		if field.DBName != "" && field.Tag.Get("checksum") == "true" {
+			layout.covered = append(layout.covered, field)
+		}
+	}
+	return layout, layout.checksum != nil && len(layout.covered) > 0
+}
+
+// sum computes the checksum of a row read from the table
+func (p *rowChecksumPlugin) sum(ctx context.Context, layout checksumLayout, row reflect.Value) string {
+	mac := hmac.New(sha256.New, p.key)
+	writeChecksumPart(mac, layout.schema.Table)
+	for _, field := range layout.covered {
+		value, _ := field.ValueOf(ctx, row)
+		writeChecksumPart(mac, field.DBName)
+		writeChecksumPart(mac, canonicalValue(value))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify reports whether a row carries a checksum matching its values; unsigned rows do not
+func (p *rowChecksumPlugin) verify(ctx context.Context, layout checksumLayout, row reflect.Value) (signed, valid bool) {
+	stored, _ := layout.checksum.ValueOf(ctx, row)
+	checksum := canonicalValue(stored)
+	if checksum == "null" || checksum == `""` {
+		return false, false
+	}
+	return true, hmac.Equal([]byte(checksum), []byte(canonicalValue(p.sum(ctx, layout, row))))
+}
+
+func writeChecksumPart(h hash.Hash, part string) {
+	h.Write([]byte(part))
+	h.Write([]byte{0})
+}
+
+// canonicalValue renders a field value the same way whether it was just written or read back
+func canonicalValue(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v := reflect.ValueOf(value); v.Kind() != reflect.Ptr || !v.IsNil() {
+			if converted, err := valuer.Value(); err == nil {
+				value = converted
+			}
+		}
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+		value = v.Interface()
+	}
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// created signs the inserted rows
+func (p *rowChecksumPlugin) created(tx *gorm.DB) {
+	if tx.Error != nil || tx.DryRun || tx.Statement.Schema == nil {
+		return
+	}
+	layout, ok := checksumLayoutOf(tx.Statement.Schema)
+	if !ok {
+		return
+	}
+	if err := p.sign(tx, layout, primaryKeysOf(tx, layout)); err != nil {
+		tx.AddError(fmt.Errorf("failed to sign rows: %w", err))
+	}
+}
+
+// updating records which of the rows about to be updated verify, for updated to re-sign
+func (p *rowChecksumPlugin) updating(tx *gorm.DB) {
+	if tx.Error != nil || tx.DryRun || tx.Statement.Schema == nil {
+		return
+	}
+	layout, ok := checksumLayoutOf(tx.Statement.Schema)
+	if !ok {
+		return
+	}
+
+	// The same rows gorm:update will match: its WHERE clause plus the primary keys of the model
+	_, filtered := tx.Statement.Clauses["WHERE"]
+	keys := primaryKeysOf(tx, layout)
+	if !filtered && len(keys) == 0 && !tx.AllowGlobalUpdate {
+		return
+	}
+	db := tx.Session(&gorm.Session{NewDB: true}).Table(layout.schema.Table)
+	if tx.Statement.Unscoped {
+		db = db.Unscoped()
+	}
+	if where, ok := tx.Statement.Clauses["WHERE"]; ok {
+		if conditions, ok := where.Expression.(clause.Where); ok {
+			db = db.Clauses(conditions)
+		}
+	}
+	if len(keys) > 0 {
+		db = db.Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: layout.primaryKey.DBName}, Values: keys})
+	}
+	if tx.Dialector.Name() == "postgres" {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	rows := reflect.New(reflect.SliceOf(layout.schema.ModelType))
+	if err := db.Find(rows.Interface()).Error; err != nil {
+		tx.AddError(fmt.Errorf("failed to load rows to re-sign: %w", err))
+		return
+	}
+	var valid []interface{}
+	for i := 0; i < rows.Elem().Len(); i++ {
+		row := rows.Elem().Index(i)
+		if _, ok := p.verify(tx.Statement.Context, layout, row); ok {
+			key, _ := layout.primaryKey.ValueOf(tx.Statement.Context, row)
+			valid = append(valid, key)
+		}
+	}
+	tx.InstanceSet(rowChecksumPending, valid)
+}
+
+// updated re-signs the updated rows that verified beforehand
+func (p *rowChecksumPlugin) updated(tx *gorm.DB) {
+	if tx.Error != nil || tx.DryRun {
+		return
+	}
+	value, ok := tx.InstanceGet(rowChecksumPending)
+	if !ok {
+		return
+	}
+	layout, _ := checksumLayoutOf(tx.Statement.Schema)
+	if err := p.sign(tx, layout, value.([]interface{})); err != nil {
+		tx.AddError(fmt.Errorf("failed to re-sign rows: %w", err))
+	}
+}
+
+// primaryKeysOf returns the non-zero primary keys of the statement's model value
+func primaryKeysOf(tx *gorm.DB, layout checksumLayout) []interface{} {
+	var keys []interface{}
+	add := func(row reflect.Value) {
+		if key, zero := layout.primaryKey.ValueOf(tx.Statement.Context, row); !zero {
+			keys = append(keys, key)
+		}
+	}
+	switch value := reflect.Indirect(tx.Statement.ReflectValue); value.Kind() {
+	case reflect.Struct:
+		if value.Type() == layout.schema.ModelType {
+			add(value)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if row := reflect.Indirect(value.Index(i)); row.Kind() == reflect.Struct {
+				add(row)
+			}
+		}
+	}
+	return keys
+}
+
+// sign stores the checksum of the rows with the given keys, as read back on the statement's connection
+func (p *rowChecksumPlugin) sign(tx *gorm.DB, layout checksumLayout, keys []interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	db := tx.Session(&gorm.Session{NewDB: true}).Table(layout.schema.Table).Unscoped()
+	if tx.Dialector.Name() == "postgres" {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	for start := 0; start < len(keys); start += DefaultSignBatchSize {
+		batch := keys[start:min(start+DefaultSignBatchSize, len(keys))]
+		rows := reflect.New(reflect.SliceOf(layout.schema.ModelType))
+		if err := db.Where(clause.IN{Column: clause.Column{Name: layout.primaryKey.DBName}, Values: batch}).Find(rows.Interface()).Error; err != nil {
+			return err
+		}
+		if _, err := p.store(tx, layout, rows.Elem(), ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store writes the checksums of rows read from the table in one UPDATE, limited to the rows that
+// also match condition when given; Exec keeps it out of the update callbacks, and their timestamps
+func (p *rowChecksumPlugin) store(tx *gorm.DB, layout checksumLayout, rows reflect.Value, condition string) (int64, error) {
+	if rows.Len() == 0 {
+		return 0, nil
+	}
+	ctx := tx.Statement.Context
+	checksum, primaryKey := quoteIdentifier(layout.checksum.DBName), quoteIdentifier(layout.primaryKey.DBName)
+
+	var cases strings.Builder
+	args := make([]interface{}, 0, 2*rows.Len()+1)
+	keys := make([]interface{}, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		keys[i], _ = layout.primaryKey.ValueOf(ctx, row)
+		cases.WriteString(" WHEN ? THEN ?")
+		args = append(args, keys[i], p.sum(ctx, layout, row))
+	}
+	statement := fmt.Sprintf("UPDATE %s SET %s = CASE %s%s END WHERE %s IN ?",
+		quoteIdentifier(layout.schema.Table), checksum, primaryKey, cases.String(), primaryKey)
+	if condition != "" {
+		statement += " AND " + condition
+	}
+	result := tx.Session(&gorm.Session{NewDB: true}).Exec(statement, append(args, keys)...)
+	return result.RowsAffected, result.Error
+}
+
+// ChecksumReport is the outcome of VerifyIntegrity; rows are identified by primary key
+type ChecksumReport struct {
+	Checked    int64         `json:"checked"`
+	Mismatched []interface{} `json:"mismatched,omitempty"` // Changed since the application last wrote them
+	Unsigned   []interface{} `json:"unsigned,omitempty"`   // No checksum, e.g. written before checksums were enabled; see SignRows
+}
+
+// Clean reports whether every checked row verified
+func (r ChecksumReport) Clean() bool {
+	return len(r.Mismatched) == 0 && len(r.Unsigned) == 0
+}
+
+// VerifyIntegrity recomputes the checksums of the rows matching the query's filter, sorting and
+// pagination and reports those modified outside the application. Soft-deleted rows are checked too.
+// Requires UseRowChecksums (Config.ChecksumKey) and a model with `checksum:"true"` fields and a
+// row_checksum column
+func (uow *UnitOfWork[T]) VerifyIntegrity(ctx context.Context, query domain.QueryParams[T]) (ChecksumReport, error) {
+	var report ChecksumReport
+	plugin := rowChecksumPluginOf(uow.db)
+	if plugin == nil {
+		return report, fmt.Errorf("failed to verify integrity: row checksums are not enabled")
+	}
+	db := uow.exportQuery(ctx, query).Unscoped()
+	if err := db.Statement.Parse(newEntity[T]()); err != nil {
+		return report, fmt.Errorf("failed to verify integrity: %w", err)
+	}
+	layout, ok := checksumLayoutOf(db.Statement.Schema)
+	if !ok {
+		return report, fmt.Errorf("failed to verify integrity: %s has no checksummed fields or %s column", db.Statement.Schema.Name, ChecksumColumn)
+	}
+
+	rows, err := db.Rows()
+	if err != nil {
+		return report, fmt.Errorf("failed to verify integrity: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := reflect.New(layout.schema.ModelType)
+		if err := db.ScanRows(rows, row.Interface()); err != nil {
+			return report, fmt.Errorf("failed to verify integrity: %w", err)
+		}
+		report.Checked++
+		signed, valid := plugin.verify(ctx, layout, row.Elem())
+		if valid {
+			continue
+		}
+		key, _ := layout.primaryKey.ValueOf(ctx, row.Elem())
+		if signed {
+			report.Mismatched = append(report.Mismatched, key)
+		} else {
+			report.Unsigned = append(report.Unsigned, key)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("failed to verify integrity: %w", err)
+	}
+	return report, nil
+}
+
+// SignOptions selects the unsigned rows SignRows backfills; IDs or CreatedBefore is required, so a
+// checksum cleared outside the application is never re-signed wholesale
+type SignOptions struct {
+	IDs           []interface{} // Primary keys of rows to sign, e.g. rows an operator reviewed
+	CreatedBefore time.Time     // Sign rows created before this instant, e.g. when checksums were enabled
+	BatchSize     int           // Rows read and signed per statement; default DefaultSignBatchSize
+}
+
+// SignRows backfills the checksums of unsigned rows, such as rows written before checksums were
+// enabled, and returns how many it signed. Only rows selected by the options are signed, soft-deleted
+// ones included. Rows updated since UseRowChecksums was installed are never signed: an application
+// write would have signed them, so a missing checksum there means the row was changed outside it.
+// Rows whose checksum does not verify are left as they are. Each batch is signed in its own
+// transaction, or inside the unit of work's transaction when one is open
+func (uow *UnitOfWork[T]) SignRows(ctx context.Context, options SignOptions) (int64, error) {
+	plugin := rowChecksumPluginOf(uow.db)
+	if plugin == nil {
+		return 0, fmt.Errorf("failed to sign rows: row checksums are not enabled")
+	}
+	if len(options.IDs) == 0 && options.CreatedBefore.IsZero() {
+		return 0, fmt.Errorf("%w: failed to sign rows: give the rows' IDs or a creation cutoff", uowerrors.ErrInvalidQueryParams)
+	}
+	statement := uow.connection(ctx).Statement
+	if err := statement.Parse(newEntity[T]()); err != nil {
+		return 0, fmt.Errorf("failed to sign rows: %w", err)
+	}
+	layout, ok := checksumLayoutOf(statement.Schema)
+	if !ok {
+		return 0, fmt.Errorf("failed to sign rows: %s has no checksummed fields or %s column", statement.Schema.Name, ChecksumColumn)
+	}
+	createdAt, updatedAt := layout.schema.LookUpField("CreatedAt"), layout.schema.LookUpField("UpdatedAt")
+	if !options.CreatedBefore.IsZero() && createdAt == nil {
+		return 0, fmt.Errorf("%w: failed to sign rows: %s has no CreatedAt field", uowerrors.ErrInvalidQueryParams, layout.schema.Name)
+	}
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultSignBatchSize
+	}
+
+	checksum := quoteIdentifier(layout.checksum.DBName)
+	unsigned := fmt.Sprintf("(%s IS NULL OR %s = '')", checksum, checksum)
+	column := func(field *schema.Field) clause.Column {
+		return clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	}
+
+	var signed int64
+	var after interface{}
+	page := func(tx *gorm.DB) (n int, affected int64, err error) {
+		db := tx.Session(&gorm.Session{NewDB: true}).Table(layout.schema.Table).Unscoped().Where(unsigned)
+		if len(options.IDs) > 0 {
+			db = db.Where(clause.IN{Column: column(layout.primaryKey), Values: options.IDs})
+		}
+		if !options.CreatedBefore.IsZero() {
+			db = db.Where(clause.Lt{Column: column(createdAt), Value: options.CreatedBefore})
+		}
+		if updatedAt != nil {
+			db = db.Where(clause.Or(clause.Eq{Column: column(updatedAt), Value: nil}, clause.Lte{Column: column(updatedAt), Value: plugin.enabled}))
+		}
+		if after != nil {
+			db = db.Where(clause.Gt{Column: column(layout.primaryKey), Value: after})
+		}
+		if tx.Dialector.Name() == "postgres" {
+			db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+
+		rows := reflect.New(reflect.SliceOf(layout.schema.ModelType))
+		if err := db.Order(clause.OrderByColumn{Column: column(layout.primaryKey)}).Limit(batchSize).Find(rows.Interface()).Error; err != nil {
+			return 0, 0, err
+		}
+		if n = rows.Elem().Len(); n > 0 {
+			after, _ = layout.primaryKey.ValueOf(ctx, rows.Elem().Index(n-1))
+		}
+		affected, err = plugin.store(tx, layout, rows.Elem(), unsigned)
+		return n, affected, err
+	}
+
+	db := uow.connection(ctx)
+	for {
+		var n int
+		var affected int64
+		var err error
+		if uow.inTx && uow.tx != nil {
+			n, affected, err = page(db)
+		} else {
+			err = db.Transaction(func(tx *gorm.DB) error {
+				n, affected, err = page(tx)
+				return err
+			})
+		}
+		if err != nil {
+			return signed, fmt.Errorf("failed to sign rows: %w", err)
+		}
+		signed += affected
+		if n < batchSize {
+			return signed, nil
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// RunWithCompensation runs the steps in order inside one transaction of this unit of work and
+// commits it. When a step fails, panics, or the commit fails, the transaction is rolled back and
+// the compensations of the external steps that ran are executed in reverse order.
+// The returned error joins the cause with any *domain.CompensationError
+func (uow *UnitOfWork[T]) RunWithCompensation(ctx context.Context, steps []domain.Step) (err error) {
+	if uow.inTx {
+		return fmt.Errorf("%w: compensated steps need a transaction of their own", uowerrors.ErrTransactionAlreadyOpen)
+	}
+	for i, step := range steps {
+		if step.Run == nil {
+			return fmt.Errorf("%w: step %d (%s) has nothing to run", uowerrors.ErrInvalidQueryParams, i, step.Name)
+		}
+	}
+	if err := uow.BeginTransaction(ctx); err != nil {
+		return err
+	}
+
+	var ran []domain.Step
+	defer func() {
+		recovered := recover()
+		if err == nil && recovered == nil {
+			return
+		}
+		uow.RollbackTransaction(ctx)
+		errs := []error{err}
+		for i := len(ran) - 1; i >= 0; i-- {
+			if ran[i].Compensate == nil {
+				continue
+			}
+			if cerr := ran[i].Compensate(ctx); cerr != nil {
+				errs = append(errs, &domain.CompensationError{Step: ran[i].Name, Err: cerr})
+			}
+		}
+		if recovered != nil {
+			panic(recovered)
+		}
+		err = errors.Join(errs...)
+	}()
+
+	for _, step := range steps {
+		if err := step.Run(ctx); err != nil {
+			return fmt.Errorf("step %s failed: %w", step.Name, err)
+		}
+		ran = append(ran, step)
+	}
+	return uow.CommitTransaction(ctx)
+}
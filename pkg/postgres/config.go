@@ -1,12 +1,15 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 // Config holds PostgreSQL connection configuration
@@ -24,6 +27,73 @@ type Config struct {
 	ConnMaxLifetime time.Duration   `json:"conn_max_lifetime"`  // Default: 1 hour
 	ConnMaxIdleTime time.Duration   `json:"conn_max_idle_time"` // Default: 30 minutes
 	LogLevel        logger.LogLevel `json:"log_level"`          // Default: Silent in production
+
+	// Warm-up: connections opened by Connect before it returns, and statements prepared on each
+	// so the server loads catalog metadata before the first request
+	WarmUpConnections int      `json:"warm_up_connections"` // Capped at MaxIdleConns
+	WarmUpStatements  []string `json:"warm_up_statements"`
+
+	// Prepared statement cache; statements are prepared once per pool and reused
+	DisablePreparedStatements bool          `json:"disable_prepared_statements"`
+	StatementCacheSize        int           `json:"statement_cache_size"` // Default: unbounded
+	StatementCacheTTL         time.Duration `json:"statement_cache_ttl"`  // Default: no expiry
+
+	// GuardUnscopedMutations rejects Delete/HardDelete/UpdateWhere calls with an empty identifier
+	// unless the identifier was built with AllowFullTableOperation(); units of work built by
+	// NewUnitOfWorkFromDB, which have no Config, always guard
+	GuardUnscopedMutations bool `json:"guard_unscoped_mutations"`
+
+	// TxOptions are the isolation level and read-only mode of BeginTransaction; default: READ COMMITTED
+	TxOptions *sql.TxOptions `json:"-"`
+
+	// TransactionRetry makes Transaction retry serialization failures and deadlocks; default: no retries
+	TransactionRetry *RetryPolicy `json:"-"`
+
+	// DeadlineReserve is kept back from the context deadline when bulk operations budget their
+	// batches, leaving time to commit; default: DefaultDeadlineReserve
+	DeadlineReserve time.Duration `json:"deadline_reserve"`
+
+	// NamingStrategy maps models to tables and columns, for GORM and for the filters, sorts and
+	// identifiers resolved by this package; default: GORM's snake_case strategy
+	NamingStrategy schema.Namer `json:"-"`
+
+	// Masking redacts PII-tagged fields in SQL logs and, optionally, in query results
+	Masking *MaskingPolicy `json:"-"`
+
+	// Metrics receives operational counters (statement cache, ...)
+	Metrics Metrics `json:"-"`
+
+	// Cache is the second-level cache for entities implementing domain.Cacheable
+	Cache Cache `json:"-"`
+
+	// NPlusOne reports identical single-row queries repeated within a unit of work; for development
+	NPlusOne *NPlusOneDetection `json:"-"`
+
+	// SlowQueries records statements slower than its threshold
+	SlowQueries *SlowQueryLog `json:"-"`
+
+	// StatementLog sends a sample of all statements, with redacted arguments, to a sink
+	StatementLog *StatementLog `json:"-"`
+
+	// RateLimiter is consulted before every statement, keyed by the context's tenant and user
+	RateLimiter RateLimiter `json:"-"`
+
+	// ChecksumKey signs the `checksum:"true"` fields of written rows, see UseRowChecksums
+	ChecksumKey []byte `json:"-"`
+
+	// LeakDetection reports units of work and transactions left open past a grace period
+	LeakDetection *LeakDetection `json:"-"`
+
+	// Models collects the models factories register with RegisterModel; default: DefaultModelRegistry
+	Models *ModelRegistry `json:"-"`
+
+	// Read replicas; reads outside transactions are spread across them round-robin.
+	// After a write, reads through the same unit of work (or ReadYourWrites context) stay on the
+	// primary for ReadYourWritesWindow, or until the replica has replayed the write with TrackReplicaLSN
+	Replicas             []ReplicaConfig `json:"replicas"`
+	ReadYourWritesWindow time.Duration   `json:"read_your_writes_window"` // Default: 5 seconds
+	TrackReplicaLSN      bool            `json:"track_replica_lsn"`
+	MaxReplicaLagBytes   int64           `json:"max_replica_lag_bytes"` // Reads fall back to the primary when replicas trail it by more WAL; default: unchecked
 }
 
 // NewConfig creates a new PostgreSQL configuration with production defaults
@@ -54,21 +124,19 @@ func (c *Config) DSN() string {
 
 // Connect establishes a connection to PostgreSQL with optimized settings
 func Connect(config *Config) (*gorm.DB, error) {
-	// Configure GORM
-	gormConfig := &gorm.Config{
-		Logger:                                   logger.Default.LogMode(config.LogLevel),
-		DisableForeignKeyConstraintWhenMigrating: false,
-		CreateBatchSize:                          1000,  // Optimize batch operations
-		PrepareStmt:                              true,  // Use prepared statements for better performance
-		SkipDefaultTransaction:                   false, // Maintain ACID compliance
-	}
-
 	// Open connection
-	db, err := gorm.Open(postgres.Open(config.DSN()), gormConfig)
+	db, err := gorm.Open(postgres.Open(config.DSN()), config.gormConfig())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
+	// Observe prepared statement reuse
+	if !config.DisablePreparedStatements {
+		if err := db.Use(&statementCache{metrics: metricsOf(config)}); err != nil {
+			return nil, fmt.Errorf("failed to instrument statement cache: %w", err)
+		}
+	}
+
 	// Configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -86,9 +154,126 @@ func Connect(config *Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
 	}
 
+	// Pre-establish connections to avoid first-request latency spikes
+	if err := warmUp(context.Background(), sqlDB, config.warmUpTarget(), config.WarmUpStatements); err != nil {
+		return nil, err
+	}
+
+	// Invalidate cached entities on writes
+	if config.Cache != nil {
+		if err := UseCache(db, config.Cache); err != nil {
+			return nil, fmt.Errorf("failed to install cache invalidation: %w", err)
+		}
+	}
+
+	// Record slow statements for the admin endpoints
+	if config.SlowQueries != nil {
+		if err := UseSlowQueryLog(db, config.SlowQueries); err != nil {
+			return nil, fmt.Errorf("failed to install slow query log: %w", err)
+		}
+	}
+
+	// Sample statements for audit sinks
+	if config.StatementLog != nil {
+		masking := config.StatementLog.Masking
+		if masking == nil {
+			masking = config.Masking
+		}
+		if err := useStatementLog(db, config.StatementLog, masking); err != nil {
+			return nil, fmt.Errorf("failed to install statement log: %w", err)
+		}
+	}
+
+	// Flag N+1 query patterns
+	if config.NPlusOne != nil {
+		if err := UseNPlusOneDetection(db, config.NPlusOne); err != nil {
+			return nil, fmt.Errorf("failed to install N+1 detection: %w", err)
+		}
+	}
+
+	// Throttle noisy tenants before they reach the pool
+	if config.RateLimiter != nil {
+		if err := UseRateLimiter(db, config.RateLimiter); err != nil {
+			return nil, fmt.Errorf("failed to install rate limiter: %w", err)
+		}
+	}
+
+	// Sign checksummed rows on write
+	if len(config.ChecksumKey) > 0 {
+		if err := UseRowChecksums(db, config.ChecksumKey); err != nil {
+			return nil, fmt.Errorf("failed to install row checksums: %w", err)
+		}
+	}
+
+	// Route reads to replicas
+	if len(config.Replicas) > 0 {
+		if err := connectReplicas(config, db); err != nil {
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
 
+// warmUpTarget returns how many connections to pre-establish
+// Connections beyond MaxIdleConns would be closed as soon as they are released
+func (c *Config) warmUpTarget() int {
+	n := c.WarmUpConnections
+	if n > c.MaxIdleConns {
+		n = c.MaxIdleConns
+	}
+	if c.MaxOpenConns > 0 && n > c.MaxOpenConns {
+		n = c.MaxOpenConns
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// warmUp opens n pooled connections at once and prepares the statements on each
+// All connections are held until the last one is ready, then returned to the idle pool
+func warmUp(ctx context.Context, sqlDB *sql.DB, n int, statements []string) error {
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to open warm-up connection %d: %w", i+1, err)
+		}
+		conns = append(conns, conn)
+
+		for _, statement := range statements {
+			stmt, err := conn.PrepareContext(ctx, statement)
+			if err != nil {
+				return fmt.Errorf("failed to prepare warm-up statement %q: %w", statement, err)
+			}
+			stmt.Close()
+		}
+	}
+
+	return nil
+}
+
+// gormConfig builds the GORM configuration for a connection
+func (c *Config) gormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger:                                   newLogger(c),
+		DisableForeignKeyConstraintWhenMigrating: false,
+		CreateBatchSize:                          1000,                         // Optimize batch operations
+		PrepareStmt:                              !c.DisablePreparedStatements, // Prepare once per pool, reuse afterwards
+		PrepareStmtMaxSize:                       c.StatementCacheSize,
+		PrepareStmtTTL:                           c.StatementCacheTTL,
+		SkipDefaultTransaction:                   false, // Maintain ACID compliance
+		NamingStrategy:                           c.NamingStrategy,
+	}
+}
+
 // MustConnect is like Connect but panics on error
 // Useful for application startup where DB connectivity is critical
 func MustConnect(config *Config) *gorm.DB {
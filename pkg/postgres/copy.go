@@ -0,0 +1,219 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm/clause"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// copySequenceColumn orders staged rows so the last duplicate of a key wins the merge
+const copySequenceColumn = "uow_copy_seq"
+
+// CopyInsert loads entities with the COPY protocol, the fastest path for large imports
+// When the plain COPY hits a unique violation it falls back automatically to copying into a
+// temporary table and merging with INSERT ... ON CONFLICT DO UPDATE, so repeated imports are idempotent.
+// Conflict columns default to the primary key; within one batch the last entity for a key wins.
+// Returns the number of rows written. Generated IDs are not read back, and hooks and serializers do not run.
+// Inside a transaction, or on a driver other than pgx, it falls back to batched INSERT ... ON CONFLICT.
+func (uow *UnitOfWork[T]) CopyInsert(ctx context.Context, entities []T, conflictColumns ...string) (int64, error) {
+	if len(entities) == 0 {
+		return 0, nil
+	}
+
+	meta := uow.metadata()
+	onConflict, err := upsertClause(meta, conflictColumns)
+	if err != nil {
+		return 0, err
+	}
+	if err := uow.assignIDs(ctx, entities...); err != nil {
+		return 0, err
+	}
+	if err := uow.validate(false, entities...); err != nil {
+		return 0, err
+	}
+
+	columns, rows, err := copyRows(meta, entities)
+	if err != nil {
+		return 0, err
+	}
+	db := uow.getActiveDB(ctx)
+	if uow.inTx || db.Dialector.Name() != "postgres" {
+		return uow.copyFallback(ctx, entities, onConflict)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get SQL DB instance: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection for COPY: %w", err)
+	}
+	defer conn.Close()
+
+	var written int64
+	usedCopy := false
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return nil
+		}
+		usedCopy = true
+
+		written, err = copyPlain(ctx, pgxConn.Conn(), meta.Table, columns, rows)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			written, err = copyMerge(ctx, pgxConn.Conn(), meta.Table, columns, rows, onConflict)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy entities: %w", err)
+	}
+	if !usedCopy {
+		return uow.copyFallback(ctx, entities, onConflict)
+	}
+
+	uow.invalidateCache(ctx, meta.Table)
+	uow.recordChanges(ctx, domain.ChangeUpdated, entities...)
+	return written, nil
+}
+
+// copyFallback writes entities with batched INSERT ... ON CONFLICT DO UPDATE
+func (uow *UnitOfWork[T]) copyFallback(ctx context.Context, entities []T, onConflict clause.OnConflict) (int64, error) {
+	result := uow.getActiveDB(ctx).Clauses(onConflict).CreateInBatches(&entities, 500)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to copy entities: %w", result.Error)
+	}
+	uow.recordChanges(ctx, domain.ChangeUpdated, entities...)
+	return result.RowsAffected, nil
+}
+
+// copyPlain COPYs rows straight into the table
+func copyPlain(ctx context.Context, conn *pgx.Conn, table string, columns []string, rows [][]any) (int64, error) {
+	return conn.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+}
+
+// copyMerge COPYs rows into a temporary staging table and merges them with INSERT ... ON CONFLICT
+func copyMerge(ctx context.Context, conn *pgx.Conn, table string, columns []string, rows [][]any, onConflict clause.OnConflict) (int64, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	staging := "uow_copy_" + table
+	create := fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS, %s bigserial) ON COMMIT DROP",
+		quoteIdentifier(staging), quoteIdentifier(table), copySequenceColumn)
+	if _, err := tx.Exec(ctx, create); err != nil {
+		return 0, err
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{staging}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, mergeStatement(table, staging, columns, onConflict))
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// mergeStatement upserts the latest staged row per conflict key into the table
+func mergeStatement(table, staging string, columns []string, onConflict clause.OnConflict) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	keys := make([]string, len(onConflict.Columns))
+	for i, column := range onConflict.Columns {
+		keys[i] = quoteIdentifier(column.Name)
+	}
+
+	copied := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		copied[column] = true
+	}
+	var updates []string
+	for _, assignment := range onConflict.DoUpdates {
+		if copied[assignment.Column.Name] {
+			name := quoteIdentifier(assignment.Column.Name)
+			updates = append(updates, name+" = EXCLUDED."+name)
+		}
+	}
+	action := "DO NOTHING"
+	if len(updates) > 0 {
+		action = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	columnList, keyList := strings.Join(quoted, ", "), strings.Join(keys, ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT DISTINCT ON (%s) %s FROM %s ORDER BY %s, %s DESC ON CONFLICT (%s) %s",
+		quoteIdentifier(table), columnList, keyList, columnList, quoteIdentifier(staging), keyList, copySequenceColumn, keyList, action)
+}
+
+// copyRows flattens entities into COPY rows
+// A zero primary key on the first entity leaves the column out so the database assigns IDs;
+// zero created_at/updated_at timestamps are set to now as GORM would on insert
+func copyRows[T domain.BaseModel](meta *modelMetadata, entities []T) ([]string, [][]any, error) {
+	first, ok := meta.structValue(entities[0])
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: nil entity at index 0", uowerrors.ErrInvalidQueryParams)
+	}
+	now := time.Now()
+
+	fields := make([]fieldMetadata, 0, len(meta.Fields))
+	columns := make([]string, 0, len(meta.Fields))
+	for _, field := range meta.Fields {
+		if field.PrimaryKey && first.FieldByIndex(field.Index).IsZero() {
+			continue
+		}
+		fields = append(fields, field)
+		columns = append(columns, field.Column)
+	}
+
+	rows := make([][]any, len(entities))
+	for i, entity := range entities {
+		value, ok := meta.structValue(entity)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: nil entity at index %d", uowerrors.ErrInvalidQueryParams, i)
+		}
+		row := make([]any, len(fields))
+		for j, field := range fields {
+			fieldValue := value.FieldByIndex(field.Index)
+			if (field.Column == "created_at" || field.Column == "updated_at") && fieldValue.IsZero() && fieldValue.Type() == reflect.TypeOf(now) {
+				row[j] = now
+				continue
+			}
+			row[j] = copyValue(fieldValue.Interface())
+		}
+		rows[i] = row
+	}
+	return columns, rows, nil
+}
+
+// copyValue resolves driver.Valuer types (gorm.DeletedAt, enums) to values pgx can encode
+func copyValue(value any) any {
+	valuer, ok := value.(driver.Valuer)
+	if !ok {
+		return value
+	}
+	resolved, err := valuer.Value()
+	if err != nil {
+		return value
+	}
+	return resolved
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultCounterShards is how many rows a counter spreads its increments over when no count is given
+const DefaultCounterShards = 16
+
+// CounterShard is one row of a sharded counter; a counter's value is the sum of its shards
+type CounterShard struct {
+	Name  string `gorm:"primaryKey;size:255"`
+	Shard int    `gorm:"primaryKey;autoIncrement:false"`
+	Value int64  `gorm:"not null;default:0"`
+}
+
+// TableName implements schema.Tabler
+func (CounterShard) TableName() string {
+	return "uow_counter_shards"
+}
+
+// MigrateCounters creates the table backing sharded counters
+func MigrateCounters(ctx context.Context, db *gorm.DB) error {
+	if err := db.WithContext(ctx).AutoMigrate(&CounterShard{}); err != nil {
+		return fmt.Errorf("failed to create counter table: %w", err)
+	}
+	return nil
+}
+
+// Counter is a named counter for hot values such as view counts. Each increment updates one of
+// several shard rows picked at random, so concurrent writers rarely wait on the same row lock.
+// Writes go through the unit of work it was created from and join its transaction
+type Counter[T domain.BaseModel] struct {
+	uow    *UnitOfWork[T]
+	name   string
+	shards int
+}
+
+// Counter returns the named sharded counter; shards below 1 use DefaultCounterShards
+// Create the table with MigrateCounters first
+func (uow *UnitOfWork[T]) Counter(name string, shards int) *Counter[T] {
+	if shards < 1 {
+		shards = DefaultCounterShards
+	}
+	return &Counter[T]{uow: uow, name: name, shards: shards}
+}
+
+// Name returns the counter's name
+func (c *Counter[T]) Name() string {
+	return c.name
+}
+
+// Add adds delta, which may be negative, to a random shard
+func (c *Counter[T]) Add(ctx context.Context, delta int64) error {
+	if c.name == "" {
+		return fmt.Errorf("%w: counter needs a name", uowerrors.ErrInvalidQueryParams)
+	}
+	shard := CounterShard{Name: c.name, Shard: rand.IntN(c.shards), Value: delta}
+	err := c.uow.connection(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "shard"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"value": gorm.Expr(quoteIdentifier(shard.TableName()) + ".value + excluded.value")}),
+	}).Create(&shard).Error
+	if err != nil {
+		return fmt.Errorf("failed to add to counter %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// Increment adds one
+func (c *Counter[T]) Increment(ctx context.Context) error {
+	return c.Add(ctx, 1)
+}
+
+// ReadApprox sums the shards; increments in transactions that have not committed are not seen,
+// so under concurrent writes the value is a snapshot rather than exact
+func (c *Counter[T]) ReadApprox(ctx context.Context) (int64, error) {
+	var value int64
+	err := c.uow.connection(ctx).Model(&CounterShard{}).
+		Where("name = ?", c.name).
+		Select("COALESCE(SUM(value), 0)").Scan(&value).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to read counter %s: %w", c.name, err)
+	}
+	return value, nil
+}
+
+// ConsolidateShards folds the counter's shards into shard 0, keeping the value; run it from
+// time to time for counters with many rows. Shards are locked FOR UPDATE on PostgreSQL.
+// Inside a transaction it joins it
+func (c *Counter[T]) ConsolidateShards(ctx context.Context) error {
+	uow := c.uow
+	consolidate := func(tx *gorm.DB) error {
+		db := tx.Where("name = ?", c.name)
+		if tx.Dialector.Name() == "postgres" {
+			db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var shards []CounterShard
+		if err := db.Find(&shards).Error; err != nil {
+			return err
+		}
+		if len(shards) == 0 || (len(shards) == 1 && shards[0].Shard == 0) {
+			return nil
+		}
+
+		total := CounterShard{Name: c.name}
+		for _, shard := range shards {
+			total.Value += shard.Value
+		}
+		if err := tx.Where("name = ?", c.name).Delete(&CounterShard{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&total).Error
+	}
+
+	var err error
+	if uow.inTx && uow.tx != nil {
+		err = consolidate(c.uow.connection(ctx))
+	} else {
+		err = c.uow.connection(ctx).Transaction(consolidate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to consolidate counter %s: %w", c.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// withCounts selects the entity's columns plus one correlated COUNT subquery per relation, aliased to
+// the relation's <relation>_count field; soft-deleted children are not counted
+func withCounts[T domain.BaseModel](db *gorm.DB, relations []string) (*gorm.DB, error) {
+	if len(relations) == 0 {
+		return db, nil
+	}
+	s, err := schema.Parse(newEntity[T](), &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse model for counts: %w", err)
+	}
+	if s.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("%w: counting relations requires a primary key", uowerrors.ErrInvalidQueryParams)
+	}
+
+	columns := []string{quoteIdentifier(s.Table) + ".*"}
+	for _, name := range relations {
+		rel, ok := s.Relationships.Relations[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown relation %q to count", uowerrors.ErrInvalidQueryParams, name)
+		}
+		field := s.LookUpField(name + "Count")
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("%w: %s has no %sCount field to count %s into", uowerrors.ErrInvalidQueryParams, s.Name, name, name)
+		}
+		subquery, err := countSubquery(s, rel)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, fmt.Sprintf("(%s) AS %s", subquery, quoteIdentifier(field.DBName)))
+	}
+	return db.Select(strings.Join(columns, ", ")), nil
+}
+
+// countSubquery counts the live children of one relation for the current row of the parent table
+func countSubquery(parent *schema.Schema, rel *schema.Relationship) (string, error) {
+	child := rel.FieldSchema
+	parentColumn := func(name string) string {
+		return quoteIdentifier(parent.Table) + "." + quoteIdentifier(name)
+	}
+	live := ""
+	if deletedAt := softDeleteColumn(child); deletedAt != "" {
+		live = " AND uow_child." + quoteIdentifier(deletedAt) + " IS NULL"
+	}
+
+	switch rel.Type {
+	case schema.HasOne, schema.HasMany:
+		var conditions []string
+		for _, ref := range rel.References {
+			switch {
+			case ref.OwnPrimaryKey:
+				conditions = append(conditions, fmt.Sprintf("uow_child.%s = %s", quoteIdentifier(ref.ForeignKey.DBName), parentColumn(ref.PrimaryKey.DBName)))
+			case ref.PrimaryValue != "":
+				// Polymorphic type column
+				conditions = append(conditions, fmt.Sprintf("uow_child.%s = '%s'", quoteIdentifier(ref.ForeignKey.DBName), strings.ReplaceAll(ref.PrimaryValue, "'", "''")))
+			}
+		}
+		return fmt.Sprintf("SELECT COUNT(*) FROM %s AS uow_child WHERE %s%s", quoteIdentifier(child.Table), strings.Join(conditions, " AND "), live), nil
+
+	case schema.Many2Many:
+		var own, other []string
+		for _, ref := range rel.References {
+			if ref.OwnPrimaryKey {
+				own = append(own, fmt.Sprintf("uow_join.%s = %s", quoteIdentifier(ref.ForeignKey.DBName), parentColumn(ref.PrimaryKey.DBName)))
+			} else {
+				other = append(other, fmt.Sprintf("uow_join.%s = uow_child.%s", quoteIdentifier(ref.ForeignKey.DBName), quoteIdentifier(ref.PrimaryKey.DBName)))
+			}
+		}
+		return fmt.Sprintf("SELECT COUNT(*) FROM %s AS uow_join JOIN %s AS uow_child ON %s WHERE %s%s",
+			quoteIdentifier(rel.JoinTable.Table), quoteIdentifier(child.Table), strings.Join(other, " AND "), strings.Join(own, " AND "), live), nil
+	}
+	return "", fmt.Errorf("%w: %s is a belongs-to relation and has nothing to count", uowerrors.ErrInvalidQueryParams, rel.Name)
+}
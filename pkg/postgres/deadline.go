@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// DefaultDeadlineReserve is kept back from the context deadline when Config.DeadlineReserve is not set
+const DefaultDeadlineReserve = 100 * time.Millisecond
+
+// DeadlineBudgetError stops a bulk operation before a batch that is not expected to finish before
+// the context deadline. Rows before Processed were written; resume with the rest under a new deadline.
+// It matches errors.ErrDeadlineBudgetExceeded with errors.Is
+type DeadlineBudgetError struct {
+	Operation string
+	Processed int
+	Total     int           // Zero when not known up front, as for streamed imports
+	Remaining time.Duration // Time left before the deadline when stopped
+}
+
+// Error implements the error interface
+func (e *DeadlineBudgetError) Error() string {
+	total := "?"
+	if e.Total > 0 {
+		total = fmt.Sprint(e.Total)
+	}
+	return fmt.Sprintf("%v: %s stopped after %d of %s rows with %s left",
+		uowerrors.ErrDeadlineBudgetExceeded, e.Operation, e.Processed, total, e.Remaining.Round(time.Millisecond))
+}
+
+// Is matches errors.ErrDeadlineBudgetExceeded
+func (e *DeadlineBudgetError) Is(target error) bool {
+	return target == uowerrors.ErrDeadlineBudgetExceeded
+}
+
+// deadlineBudget spreads the time left before a context deadline over the batches of a bulk
+// operation, estimating each batch from the pace of the rows processed so far
+type deadlineBudget struct {
+	operation string
+	total     int
+	start     time.Time
+	deadline  time.Time
+	reserve   time.Duration
+}
+
+// deadlineBudget returns nil, a budget admitting every batch, when the context has no deadline
+func (uow *UnitOfWork[T]) deadlineBudget(ctx context.Context, operation string, total int) *deadlineBudget {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	reserve := DefaultDeadlineReserve
+	if uow.config != nil && uow.config.DeadlineReserve > 0 {
+		reserve = uow.config.DeadlineReserve
+	}
+	return &deadlineBudget{operation: operation, total: total, start: time.Now(), deadline: deadline, reserve: reserve}
+}
+
+// admit returns a *DeadlineBudgetError unless the next rows are expected to finish, with the
+// reserve to spare, before the deadline
+func (b *deadlineBudget) admit(processed, next int) error {
+	if b == nil {
+		return nil
+	}
+	left := time.Until(b.deadline)
+	var estimate time.Duration
+	if processed > 0 {
+		estimate = time.Since(b.start) / time.Duration(processed) * time.Duration(next)
+	}
+	if left-b.reserve > 0 && estimate <= left-b.reserve {
+		return nil
+	}
+	return &DeadlineBudgetError{Operation: b.operation, Processed: processed, Total: b.total, Remaining: max(left, 0)}
+}
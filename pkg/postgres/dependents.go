@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dependentSampleSize is how many dependent keys a DependentsError lists per relation
+const dependentSampleSize = 10
+
+// Dependents are the rows of one relation that reference the entities being deleted
+type Dependents struct {
+	Relation Relation
+	Count    int64
+	Sample   []interface{} // Child keys, at most 10
+}
+
+// DependentsError refuses a restricted hard delete and lists what references the entities
+// It matches errors.ErrHasDependents with errors.Is
+type DependentsError struct {
+	Entity     string
+	Dependents []Dependents
+}
+
+// Error implements the error interface
+func (e *DependentsError) Error() string {
+	parts := make([]string, len(e.Dependents))
+	for i, dependents := range e.Dependents {
+		parts[i] = fmt.Sprintf("%d in %s %v", dependents.Count, dependents.Relation, dependents.Sample)
+	}
+	return fmt.Sprintf("%v: %s: %s", uowerrors.ErrHasDependents, e.Entity, strings.Join(parts, "; "))
+}
+
+// Is matches errors.ErrHasDependents
+func (e *DependentsError) Is(target error) bool {
+	return target == uowerrors.ErrHasDependents
+}
+
+// dependentRows are rows of one table found while walking relations, deleted by their keys
+type dependentRows struct {
+	table string
+	key   string
+	ids   []interface{}
+}
+
+// HardDeleteWithDependents hard deletes the entities matching the identifier, trashed ones
+// included, deciding by policy what happens to rows referencing them through the relations of the
+// config's model registry (associations and DeclareRelations entries). DeleteRestrict fails with a
+// *DependentsError when any exist; DeleteCascade deletes them first, children before parents, all in
+// one transaction. Many-to-many join tables are not relations: declare them to have them covered.
+// Inside a transaction it joins it
+func (uow *UnitOfWork[T]) HardDeleteWithDependents(ctx context.Context, identifier identifier.IIdentifier, policy domain.DeletePolicy) (domain.DependentDeleteResult, error) {
+	result := domain.DependentDeleteResult{Deleted: make(map[string]int64)}
+	if policy != domain.DeleteRestrict && policy != domain.DeleteCascade {
+		return result, fmt.Errorf("%w: unknown delete policy %q", uowerrors.ErrInvalidQueryParams, policy)
+	}
+	meta := uow.metadata()
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return result, fmt.Errorf("%w: %s has no primary key", uowerrors.ErrInvalidEntity, meta.Table)
+	}
+
+	db := uow.getActiveDB(ctx)
+	if _, err := uow.scopedMutation(db, "hard delete with dependents", identifier); err != nil {
+		return result, err
+	}
+	var entities []T
+	err := applyEntityIdentifier[T](db.Unscoped(), identifier).Order(primaryKey.Qualified).Find(&entities).Error
+	if err != nil {
+		return result, fmt.Errorf("failed to find entities for hard delete: %w", err)
+	}
+	if len(entities) == 0 {
+		return result, nil
+	}
+	ids := make([]interface{}, len(entities))
+	for i, entity := range entities {
+		v, _ := meta.structValue(entity)
+		ids[i] = v.FieldByIndex(primaryKey.Index).Interface()
+	}
+	relations, err := uow.config.ModelRegistry().Relations(db)
+	if err != nil {
+		return result, err
+	}
+
+	remove := func(tx *gorm.DB) error {
+		root := dependentRows{table: meta.Table, key: primaryKey.Column, ids: ids}
+		if policy == domain.DeleteRestrict {
+			found, err := directDependents(tx, relations, root)
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 {
+				return &DependentsError{Entity: meta.Table, Dependents: found}
+			}
+		} else {
+			found, err := collectDependents(tx, relations, root)
+			if err != nil {
+				return err
+			}
+			for i := len(found) - 1; i >= 0; i-- {
+				rows := found[i]
+				deleted := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s IN ?", quoteIdentifier(rows.table), quoteIdentifier(rows.key)), rows.ids)
+				if deleted.Error != nil {
+					return fmt.Errorf("failed to delete dependents in %s: %w", rows.table, deleted.Error)
+				}
+				result.Deleted[rows.table] += deleted.RowsAffected
+			}
+		}
+
+		deleted := tx.Unscoped().Where(clause.IN{Column: clause.Column{Table: meta.Table, Name: primaryKey.Column}, Values: ids}).Delete(newEntity[T]())
+		if deleted.Error != nil {
+			return fmt.Errorf("failed to hard delete entities: %w", deleted.Error)
+		}
+		result.Deleted[meta.Table] += deleted.RowsAffected
+		return nil
+	}
+
+	// Dependent tables are written without this entity's default scopes
+	if uow.inTx && uow.tx != nil {
+		err = remove(uow.tx.WithContext(ctx))
+	} else {
+		err = uow.db.WithContext(uow.pinned(ctx)).Transaction(remove)
+	}
+	if err != nil {
+		result.Deleted = make(map[string]int64)
+		return result, err
+	}
+	uow.recordChanges(ctx, domain.ChangeDeleted, entities...)
+	return result, nil
+}
+
+// directDependents counts and samples the rows referencing parent through each relation
+func directDependents(tx *gorm.DB, relations []Relation, parent dependentRows) ([]Dependents, error) {
+	var found []Dependents
+	for _, relation := range relations {
+		if relation.Parent != parent.table || relation.ParentKey != parent.key {
+			continue
+		}
+		referencing := tx.Table(relation.Child).Where(clause.IN{Column: clause.Column{Name: relation.ForeignKey}, Values: parent.ids})
+		var count int64
+		if err := referencing.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to count dependents in %s: %w", relation, err)
+		}
+		if count == 0 {
+			continue
+		}
+		dependents := Dependents{Relation: relation, Count: count}
+		if err := referencing.Order(relation.ChildKey).Limit(dependentSampleSize).Pluck(relation.ChildKey, &dependents.Sample).Error; err != nil {
+			return nil, fmt.Errorf("failed to sample dependents in %s: %w", relation, err)
+		}
+		found = append(found, dependents)
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Relation.String() < found[j].Relation.String() })
+	return found, nil
+}
+
+// collectDependents walks the relations breadth first from root, returning the dependent rows in
+// discovery order so deleting them in reverse removes children before their parents. Rows already
+// found are not revisited, which ends self-referencing and cyclic walks
+func collectDependents(tx *gorm.DB, relations []Relation, root dependentRows) ([]dependentRows, error) {
+	// Keys are compared as text: drivers scan integers as int64 while entities hold int
+	seen := map[string]map[string]bool{root.table: make(map[string]bool)}
+	for _, id := range root.ids {
+		seen[root.table][fmt.Sprint(id)] = true
+	}
+	var found []dependentRows
+	queue := []dependentRows{root}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, relation := range relations {
+			if relation.Parent != parent.table {
+				continue
+			}
+			keys := parent.ids
+			if relation.ParentKey != parent.key {
+				keys = nil
+				err := tx.Table(parent.table).Where(clause.IN{Column: clause.Column{Name: parent.key}, Values: parent.ids}).
+					Pluck(relation.ParentKey, &keys).Error
+				if err != nil {
+					return nil, fmt.Errorf("failed to read %s.%s: %w", parent.table, relation.ParentKey, err)
+				}
+			}
+			var childIDs []interface{}
+			err := tx.Table(relation.Child).Where(clause.IN{Column: clause.Column{Name: relation.ForeignKey}, Values: keys}).
+				Order(relation.ChildKey).Pluck(relation.ChildKey, &childIDs).Error
+			if err != nil {
+				return nil, fmt.Errorf("failed to find dependents in %s: %w", relation, err)
+			}
+
+			if seen[relation.Child] == nil {
+				seen[relation.Child] = make(map[string]bool)
+			}
+			rows := dependentRows{table: relation.Child, key: relation.ChildKey}
+			for _, id := range childIDs {
+				if key := fmt.Sprint(id); !seen[relation.Child][key] {
+					seen[relation.Child][key] = true
+					rows.ids = append(rows.ids, id)
+				}
+			}
+			if len(rows.ids) > 0 {
+				found = append(found, rows)
+				queue = append(queue, rows)
+			}
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// Diff compares two versions of an entity column by column using the cached metadata
+// Returns the changed columns keyed by column name; associations are not compared
+func Diff[T domain.BaseModel](before, after T) (map[string]domain.FieldChange, error) {
+	meta := metadataOf[T]()
+	old, ok := meta.structValue(before)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot diff a nil %T", uowerrors.ErrInvalidEntity, before)
+	}
+	current, ok := meta.structValue(after)
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot diff a nil %T", uowerrors.ErrInvalidEntity, after)
+	}
+
+	changes := make(map[string]domain.FieldChange)
+	for _, field := range meta.Fields {
+		a := old.FieldByIndex(field.Index).Interface()
+		b := current.FieldByIndex(field.Index).Interface()
+		if !valuesEqual(a, b) {
+			changes[field.Column] = domain.FieldChange{Old: a, New: b}
+		}
+	}
+	return changes, nil
+}
+
+// valuesEqual compares column values the way the database would store them
+// Times compare by instant and valuers (gorm.DeletedAt, sql.Null*) by their driver value
+func valuesEqual(a, b interface{}) bool {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Equal(tb)
+		}
+	}
+	if va, ok := a.(driver.Valuer); ok && !isNilPointer(a) {
+		if vb, ok := b.(driver.Valuer); ok && !isNilPointer(b) {
+			da, errA := va.Value()
+			db, errB := vb.Value()
+			if errA == nil && errB == nil {
+				return valuesEqual(da, db)
+			}
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
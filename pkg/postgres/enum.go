@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/enum"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+)
+
+var validatorType = reflect.TypeOf((*enum.Validator)(nil)).Elem()
+
+// enumType returns the string-based field type implementing enum.Validator, if any
+func enumType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.String || !t.Implements(validatorType) {
+		return nil
+	}
+	return t
+}
+
+// applyEntityIdentifier applies an identifier, with Go field names resolved to columns, after checking
+// its values against the entity's enum columns
+// An undeclared value fails the query with errors.ErrInvalidQueryParams instead of reaching the database
+func applyEntityIdentifier[T any](db *gorm.DB, id identifier.IIdentifier) *gorm.DB {
+	meta := metadataIn[T](db)
+	id = meta.resolveFields(id)
+	if err := checkEnumValues(meta, id); err != nil {
+		db.AddError(err)
+		return db
+	}
+	return applyRendered(db, id, meta.quotedColumns(db))
+}
+
+// checkEnumValues rejects conditions comparing an enum column, or any enum-typed value, with an undeclared value
+func checkEnumValues(meta *modelMetadata, id identifier.IIdentifier) error {
+	if id == nil {
+		return nil
+	}
+	for key, value := range id.ToMap() {
+		field, _, _ := strings.Cut(key, " ")
+		var column reflect.Type
+		if metadata, ok := meta.Field(field); ok {
+			column = metadata.Enum
+		}
+
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			if !validEnumValue(column, v) {
+				return fmt.Errorf("%w: %v is not a valid value for %s", uowerrors.ErrInvalidQueryParams, v, field)
+			}
+		}
+	}
+	return nil
+}
+
+func validEnumValue(column reflect.Type, value interface{}) bool {
+	if validator, ok := value.(enum.Validator); ok {
+		return validator.Valid()
+	}
+	if column == nil || value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.String {
+		return true
+	}
+	return v.Convert(column).Interface().(enum.Validator).Valid()
+}
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// exportFlushEvery is the number of rows buffered before the writer is flushed
+const exportFlushEvery = 500
+
+// flusher is implemented by writers that buffer internally (http.ResponseWriter, bufio.Writer)
+type flusher interface {
+	Flush()
+}
+
+// Export streams the entities matching a query to w as CSV
+// Rows are read from the driver one at a time, so memory stays flat for large exports;
+// query.Limit of 0 exports every matching row
+func (uow *UnitOfWork[T]) Export(ctx context.Context, query domain.QueryParams[T], options domain.CSVWriterOptions, w io.Writer) error {
+	meta := uow.metadata()
+	fields, err := exportFields(meta, options.Columns)
+	if err != nil {
+		return err
+	}
+
+	db := uow.exportQuery(ctx, query)
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Column
+	}
+
+	writer := csv.NewWriter(w)
+	if options.Comma != 0 {
+		writer.Comma = options.Comma
+	}
+	writer.UseCRLF = options.UseCRLF
+
+	record := make([]string, len(fields))
+	if !options.NoHeader {
+		for i, field := range fields {
+			record[i] = field.Column
+			if header, ok := options.Headers[field.Column]; ok {
+				record[i] = header
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	count := 0
+	err = uow.streamEntities(ctx, db.Select(columns), func(entity T) error {
+		v, _ := meta.structValue(entity)
+		for i, field := range fields {
+			record[i] = formatCSVValue(v.FieldByIndex(field.Index).Interface(), field.Column, options)
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+
+		if count++; count%exportFlushEvery == 0 {
+			return flushCSV(writer, w)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return flushCSV(writer, w)
+}
+
+// streamEntities scans a query's rows one at a time, applying result masking
+func (uow *UnitOfWork[T]) streamEntities(ctx context.Context, db *gorm.DB, fn func(T) error) error {
+	rows, err := db.Rows()
+	if err != nil {
+		return fmt.Errorf("failed to export entities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entity := newEntity[T]()
+		if err := db.ScanRows(rows, &entity); err != nil {
+			return fmt.Errorf("failed to scan exported row: %w", err)
+		}
+		uow.maskResults(ctx, entity)
+
+		if err := fn(entity); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export entities: %w", err)
+	}
+	return nil
+}
+
+// exportQuery applies a query's filter, sorting and pagination for exports
+func (uow *UnitOfWork[T]) exportQuery(ctx context.Context, query domain.QueryParams[T]) *gorm.DB {
+	db := uow.getActiveDB(ctx).Model(newEntity[T]())
+
+	if conditions, args := uow.metadata().filterConditions(query.Filter, false); conditions != "" {
+		db = db.Where(conditions, args...)
+	}
+	for field, direction := range query.Sort {
+		db = db.Order(fmt.Sprintf("%s %s", field, direction))
+	}
+	if len(query.Sort) == 0 {
+		if primaryKey, ok := uow.metadata().primaryKey(); ok {
+			db = db.Order(primaryKey.Qualified)
+		}
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+	return db
+}
+
+// exportFields resolves the selected columns, defaulting to every persisted top-level field
+func exportFields(meta *modelMetadata, columns []string) ([]fieldMetadata, error) {
+	if len(columns) == 0 {
+		fields := make([]fieldMetadata, 0, len(meta.Fields))
+		for _, field := range meta.Fields {
+			if len(field.Index) == 1 {
+				fields = append(fields, field)
+			}
+		}
+		return fields, nil
+	}
+
+	fields := make([]fieldMetadata, len(columns))
+	for i, column := range columns {
+		field, ok := meta.Field(column)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown export column %q", uowerrors.ErrInvalidQueryParams, column)
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
+
+// flushCSV flushes the CSV buffer and, for HTTP responses, the underlying writer
+func flushCSV(writer *csv.Writer, w io.Writer) error {
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if f, ok := w.(flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// formatCSVValue renders a field value as CSV text
+func formatCSVValue(value interface{}, column string, options domain.CSVWriterOptions) string {
+	if format, ok := options.Formatters[column]; ok {
+		return format(value)
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil || v == nil {
+			return options.NullValue
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return options.NullValue
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		layout := options.TimeFormat
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return v.Format(layout)
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return options.NullValue
+		}
+		return formatCSVValue(rv.Elem().Interface(), column, domain.CSVWriterOptions{NullValue: options.NullValue, TimeFormat: options.TimeFormat})
+	}
+	return fmt.Sprint(value)
+}
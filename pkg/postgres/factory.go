@@ -2,39 +2,128 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"sync"
+
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // UnitOfWorkFactory implements IUnitOfWorkFactory for PostgreSQL with generics
+// All units of work created by a factory share one connection pool,
+// so prepared statements are reused across them
 type UnitOfWorkFactory[T domain.BaseModel] struct {
 	Config *Config
+
+	mu       sync.Mutex
+	db       *gorm.DB
+	external bool // The pool came from NewUnitOfWorkFactoryFromDB; Close leaves it open
+	settings *entitySettings[T]
 }
 
 // NewUnitOfWorkFactory creates a new PostgreSQL unit of work factory
 func NewUnitOfWorkFactory[T domain.BaseModel](config *Config) *UnitOfWorkFactory[T] {
+	// Reflect over the entity once up front instead of on the first query
+	var namer schema.Namer
+	if config != nil {
+		namer = config.NamingStrategy
+	}
+	metadataWith[T](namer)
+
 	return &UnitOfWorkFactory[T]{
-		Config: config,
+		Config:   config,
+		settings: newEntitySettings[T](),
 	}
 }
 
+// NewUnitOfWorkFactoryFromDB creates a factory over an externally managed pool, such as an
+// application-wide pool or SQLite in tests; Close leaves it open
+func NewUnitOfWorkFactoryFromDB[T domain.BaseModel](db *gorm.DB) *UnitOfWorkFactory[T] {
+	f := NewUnitOfWorkFactory[T](nil)
+	metadataIn[T](db)
+	f.db = db
+	f.external = true
+	return f
+}
+
 // Create creates a new unit of work instance
 func (f *UnitOfWorkFactory[T]) Create() persistence.IUnitOfWork[T] {
-	uow, err := NewUnitOfWork[T](f.Config)
-	if err != nil {
-		// In a production environment, you might want to handle this differently
-		panic(err)
-	}
-	return uow
+	return f.CreateWithContext(context.Background())
 }
 
 // CreateWithContext creates a new unit of work instance with context
 func (f *UnitOfWorkFactory[T]) CreateWithContext(ctx context.Context) persistence.IUnitOfWork[T] {
-	uow, err := NewUnitOfWork[T](f.Config)
+	db, err := f.connection()
 	if err != nil {
 		// In a production environment, you might want to handle this differently
 		panic(err)
 	}
+	uow := newUnitOfWork[T](f.Config, db)
 	uow.ctx = ctx
+	uow.settings = f.settings
 	return uow
 }
+
+// NamingStrategy returns the naming strategy of the factory's pool
+func (f *UnitOfWorkFactory[T]) NamingStrategy() schema.Namer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.db != nil {
+		return namerOf(f.db)
+	}
+	if f.Config != nil && f.Config.NamingStrategy != nil {
+		return f.Config.NamingStrategy
+	}
+	return schema.NamingStrategy{}
+}
+
+// StatementCacheStats reports prepared statement reuse on the factory's pool
+func (f *UnitOfWorkFactory[T]) StatementCacheStats() (StatementCacheStats, bool) {
+	f.mu.Lock()
+	db := f.db
+	f.mu.Unlock()
+
+	if db == nil {
+		return StatementCacheStats{}, false
+	}
+	return StatementCacheStatsOf(db)
+}
+
+// Close closes the shared connection pool, unless it came from NewUnitOfWorkFactoryFromDB
+func (f *UnitOfWorkFactory[T]) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.db == nil || f.external {
+		return nil
+	}
+	sqlDB, err := f.db.DB()
+	if err != nil {
+		return err
+	}
+	if err := closeReplicas(f.db); err != nil {
+		return err
+	}
+	f.db = nil
+	return sqlDB.Close()
+}
+
+// connection lazily opens the shared pool; a failed attempt is retried on the next call
+func (f *UnitOfWorkFactory[T]) connection() (*gorm.DB, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.db != nil {
+		return f.db, nil
+	}
+	db, err := Connect(f.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	f.db = db
+	return db, nil
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// settingName matches PostgreSQL configuration parameter names, including custom "ext.name" ones
+var settingName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// planHint renders a pg_hint_plan comment in front of SELECT statements
+type planHint string
+
+// ModifyStatement implements gorm.StatementModifier
+func (h planHint) ModifyStatement(stmt *gorm.Statement) {
+	selectClause := stmt.Clauses["SELECT"]
+	selectClause.BeforeExpression = clause.Expr{SQL: "/*+ " + string(h) + " */"}
+	stmt.Clauses["SELECT"] = selectClause
+}
+
+// Build implements clause.Expression; the hint is written by the SELECT clause
+func (planHint) Build(clause.Builder) {}
+
+// hintedDB returns the base query for a read carrying planner hints, and a function to call once the read is done
+// Settings are scoped to the query: inside a transaction the previous values are restored afterwards,
+// outside one the query runs in a short transaction of its own; other dialects ignore them.
+// Transaction options, when given outside a transaction, run the read in a short transaction opened with them
+func (uow *UnitOfWork[T]) hintedDB(ctx context.Context, hints *domain.QueryHints, options *sql.TxOptions) (*gorm.DB, func(), error) {
+	if hints == nil {
+		hints = &domain.QueryHints{}
+		if options == nil || uow.inTx {
+			return uow.getActiveDB(ctx), func() {}, nil
+		}
+	}
+	if strings.Contains(hints.Plan, "*/") {
+		return nil, nil, fmt.Errorf("%w: plan hint must not close its comment", uowerrors.ErrInvalidQueryParams)
+	}
+	names := make([]string, 0, len(hints.Settings))
+	for name := range hints.Settings {
+		if !settingName.MatchString(name) {
+			return nil, nil, fmt.Errorf("%w: invalid planner setting %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	withPlan := func(db *gorm.DB) *gorm.DB {
+		if hints.Plan == "" {
+			return db
+		}
+		return db.Clauses(planHint(hints.Plan))
+	}
+	if uow.db.Dialector.Name() != "postgres" {
+		names = nil
+	}
+	if len(names) == 0 && (options == nil || uow.inTx) {
+		return withPlan(uow.getActiveDB(ctx)), func() {}, nil
+	}
+
+	if uow.inTx && uow.tx != nil {
+		tx := uow.tx.WithContext(ctx)
+		previous := make(map[string]string, len(names))
+		for _, name := range names {
+			var value string
+			if err := tx.Raw("SELECT current_setting(?)", name).Scan(&value).Error; err != nil {
+				return nil, nil, fmt.Errorf("failed to read planner setting %s: %w", name, err)
+			}
+			previous[name] = value
+			if err := tx.Exec("SELECT set_config(?, ?, true)", name, hints.Settings[name]).Error; err != nil {
+				return nil, nil, fmt.Errorf("failed to apply planner setting %s: %w", name, err)
+			}
+		}
+		restore := func() {
+			for _, name := range names {
+				tx.Exec("SELECT set_config(?, ?, true)", name, previous[name])
+			}
+		}
+		return withPlan(uow.getActiveDB(ctx)), restore, nil
+	}
+
+	ctx = uow.pinned(ctx)
+	tx := uow.db.WithContext(ctx).Begin(options)
+	if tx.Error != nil {
+		return nil, nil, fmt.Errorf("failed to begin hinted query: %w", tx.Error)
+	}
+	for _, name := range names {
+		if err := tx.Exec("SELECT set_config(?, ?, true)", name, hints.Settings[name]).Error; err != nil {
+			tx.Rollback()
+			return nil, nil, fmt.Errorf("failed to apply planner setting %s: %w", name, err)
+		}
+	}
+	// The transaction only reads, so ending it with a rollback is enough
+	done := func() { tx.Rollback() }
+	return withPlan(uow.settings.applyScopes(ctx, tx)), done, nil
+}
@@ -0,0 +1,300 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+
+	"gorm.io/gorm"
+)
+
+// HistoryRecord is one version of a row in an entity's history table (<table>_history)
+// A version is valid from ValidFrom until ValidTo, which stays NULL for the current one;
+// a hard delete closes the last version
+type HistoryRecord struct {
+	HistoryID int64     `gorm:"column:history_id;primaryKey;autoIncrement"`
+	EntityID  int64     `gorm:"not null;index:,composite:entity_valid"`
+	Operation string    `gorm:"size:10;not null"` // insert, update or snapshot (rows present when history was enabled)
+	ValidFrom time.Time `gorm:"not null;index:,composite:entity_valid"`
+	ValidTo   *time.Time
+	Data      string `gorm:"type:jsonb;not null"` // The row as a JSON object keyed by column
+}
+
+// historyTable names the history table of an entity table
+func historyTable(table string) string {
+	return table + "_history"
+}
+
+// EnableHistory creates T's history table and the triggers that version every insert, update and
+// delete of its rows, whatever writes them; rows already present are recorded as a first version.
+// Runs on PostgreSQL and SQLite and is safe to repeat after schema changes
+func EnableHistory[T domain.BaseModel](ctx context.Context, db *gorm.DB) error {
+	meta := metadataIn[T](db)
+	primaryKey, ok := meta.primaryKey()
+	if !ok || meta.Table == "" {
+		return fmt.Errorf("%w: history needs a table with a primary key", uowerrors.ErrInvalidEntity)
+	}
+	table, history := meta.Table, historyTable(meta.Table)
+
+	db = db.WithContext(ctx)
+	if err := db.Table(history).AutoMigrate(&HistoryRecord{}); err != nil {
+		return fmt.Errorf("failed to create history table %s: %w", history, err)
+	}
+
+	var statements []string
+	if db.Dialector.Name() == "sqlite" {
+		statements = sqliteHistoryTriggers(meta, table, history, primaryKey.Column)
+	} else {
+		statements = postgresHistoryTriggers(table, history, primaryKey.Column)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to enable history for %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
+
+// EnableHistory enables history for the factory's entity type; see EnableHistory
+func (f *UnitOfWorkFactory[T]) EnableHistory(ctx context.Context) error {
+	db, err := f.connection()
+	if err != nil {
+		return err
+	}
+	return EnableHistory[T](ctx, db)
+}
+
+// postgresHistoryTriggers versions rows with one plpgsql trigger storing to_jsonb(NEW)
+func postgresHistoryTriggers(table, history, pk string) []string {
+	t, h, k := quoteIdentifier(table), quoteIdentifier(history), quoteIdentifier(pk)
+	function := quoteIdentifier("uow_history_" + table)
+	trigger := quoteIdentifier(history)
+	return []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		UPDATE %[2]s SET valid_to = clock_timestamp() WHERE entity_id = OLD.%[3]s AND valid_to IS NULL;
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		INSERT INTO %[2]s (entity_id, operation, valid_from, data) VALUES (NEW.%[3]s, lower(TG_OP), clock_timestamp(), to_jsonb(NEW));
+	END IF;
+	RETURN NULL;
+END $$`, function, h, k),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", trigger, t),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", trigger, t, function),
+		fmt.Sprintf(`INSERT INTO %[1]s (entity_id, operation, valid_from, data)
+SELECT entity.%[3]s, 'snapshot', clock_timestamp(), to_jsonb(entity) FROM %[2]s AS entity
+WHERE NOT EXISTS (SELECT 1 FROM %[1]s h WHERE h.entity_id = entity.%[3]s AND h.valid_to IS NULL)`, h, t, k),
+	}
+}
+
+// sqliteHistoryTriggers versions rows with one trigger per operation building the row with json_object
+func sqliteHistoryTriggers(meta *modelMetadata, table, history, pk string) []string {
+	t, h, k := quoteIdentifier(table), quoteIdentifier(history), quoteIdentifier(pk)
+	now := "strftime('%Y-%m-%d %H:%M:%f', 'now')"
+	row := func(alias string) string {
+		pairs := make([]string, len(meta.Fields))
+		for i, field := range meta.Fields {
+			pairs[i] = fmt.Sprintf("'%s', %s.%s", field.Column, alias, quoteIdentifier(field.Column))
+		}
+		return "json_object(" + strings.Join(pairs, ", ") + ")"
+	}
+	closeOld := fmt.Sprintf("UPDATE %s SET valid_to = %s WHERE entity_id = OLD.%s AND valid_to IS NULL;", h, now, k)
+	insertNew := func(operation string) string {
+		return fmt.Sprintf("INSERT INTO %s (entity_id, operation, valid_from, data) VALUES (NEW.%s, '%s', %s, %s);", h, k, operation, now, row("NEW"))
+	}
+
+	statements := []string{}
+	for _, op := range []string{"insert", "update", "delete"} {
+		name := quoteIdentifier(history + "_" + op)
+		var body string
+		switch op {
+		case "insert":
+			body = insertNew("insert")
+		case "update":
+			body = closeOld + " " + insertNew("update")
+		case "delete":
+			body = closeOld
+		}
+		statements = append(statements,
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name),
+			fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW BEGIN %s END", name, strings.ToUpper(op), t, body),
+		)
+	}
+	return append(statements, fmt.Sprintf(`INSERT INTO %[1]s (entity_id, operation, valid_from, data)
+SELECT entity.%[3]s, 'snapshot', %[4]s, %[5]s FROM %[2]s AS entity
+WHERE NOT EXISTS (SELECT 1 FROM %[1]s h WHERE h.entity_id = entity.%[3]s AND h.valid_to IS NULL)`, h, t, k, now, row("entity")))
+}
+
+// FindOneAsOf returns the entity as it was at the given time, from its history table
+// The identifier is resolved among current and soft-deleted rows; fails with ErrEntityNotFound when
+// the entity had no version at that time (not yet created, or hard-deleted by then)
+func (uow *UnitOfWork[T]) FindOneAsOf(ctx context.Context, identifier identifier.IIdentifier, at time.Time) (T, error) {
+	var zero T
+	id, err := uow.resolveHistoryID(ctx, identifier)
+	if err != nil {
+		return zero, err
+	}
+	entity, err := uow.versionAt(ctx, id, at)
+	if err != nil {
+		return zero, err
+	}
+	uow.maskResults(ctx, entity)
+	return entity, nil
+}
+
+// DiffBetween compares the versions of an entity at two points in time column by column
+func (uow *UnitOfWork[T]) DiffBetween(ctx context.Context, identifier identifier.IIdentifier, from, to time.Time) (map[string]domain.FieldChange, error) {
+	id, err := uow.resolveHistoryID(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	before, err := uow.versionAt(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := uow.versionAt(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	// Masked PII compares equal, so its changes stay hidden from callers without access
+	uow.maskResults(ctx, before, after)
+	return Diff(before, after)
+}
+
+// resolveHistoryID resolves the identifier to a primary key, including soft-deleted rows
+func (uow *UnitOfWork[T]) resolveHistoryID(ctx context.Context, id identifier.IIdentifier) (int, error) {
+	if id == nil || id.IsEmpty() {
+		return 0, fmt.Errorf("%w: empty identifier", uowerrors.ErrInvalidQueryParams)
+	}
+	return uow.resolveID(applyEntityIdentifier[T](uow.getActiveDB(ctx).Unscoped(), id), id.String())
+}
+
+// versionAt loads and decodes the version of an entity valid at the given time
+func (uow *UnitOfWork[T]) versionAt(ctx context.Context, id int, at time.Time) (T, error) {
+	var zero T
+	meta := uow.metadata()
+	db := uow.db.WithContext(uow.pinned(ctx))
+	if uow.inTx && uow.tx != nil {
+		db = uow.tx.WithContext(ctx)
+	}
+
+	at = at.UTC()
+	var record HistoryRecord
+	err := db.Table(historyTable(meta.Table)).
+		Where("entity_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", id, at, at).
+		Order("valid_from DESC, history_id DESC").
+		Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return zero, fmt.Errorf("%w: %s %d has no version at %s", uowerrors.ErrEntityNotFound, meta.Table, id, at.Format(time.RFC3339))
+	}
+	if err != nil {
+		return zero, fmt.Errorf("failed to read history of %s %d: %w", meta.Table, id, err)
+	}
+
+	entity := newEntity[T]()
+	target, _ := meta.structValue(entity)
+	if err := meta.decodeColumns([]byte(record.Data), target); err != nil {
+		return zero, fmt.Errorf("failed to decode history of %s %d: %w", meta.Table, id, err)
+	}
+	return entity, nil
+}
+
+// decodeColumns fills a struct from a JSON object keyed by column, as stored by the history triggers
+func (m *modelMetadata) decodeColumns(data []byte, target reflect.Value) error {
+	var columns map[string]json.RawMessage
+	if err := json.Unmarshal(data, &columns); err != nil {
+		return err
+	}
+	for _, field := range m.Fields {
+		raw, ok := columns[field.Column]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		if err := decodeColumn(raw, target.FieldByIndex(field.Index)); err != nil {
+			return fmt.Errorf("column %s: %w", field.Column, err)
+		}
+	}
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+)
+
+// historyTimeLayouts are the timestamp renderings of to_jsonb, the SQLite driver and strftime
+var historyTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// decodeColumn sets one field from its JSON rendering
+func decodeColumn(raw json.RawMessage, dest reflect.Value) error {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+
+	// Timestamps, including the time inside gorm.DeletedAt and sql.NullTime
+	if text, ok := value.(string); ok {
+		switch {
+		case dest.Type() == timeType,
+			dest.Kind() == reflect.Ptr && dest.Type().Elem() == timeType,
+			dest.Type() == deletedAtType, dest.Type() == nullTimeType:
+			for _, layout := range historyTimeLayouts {
+				if parsed, err := time.Parse(layout, text); err == nil {
+					return setTime(dest, parsed)
+				}
+			}
+			return fmt.Errorf("unrecognized timestamp %q", text)
+		}
+	}
+
+	if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
+		switch v := value.(type) {
+		case map[string]interface{}, []interface{}:
+			return scanner.Scan([]byte(raw))
+		case float64:
+			if v == float64(int64(v)) {
+				return scanner.Scan(int64(v))
+			}
+		}
+		return scanner.Scan(value)
+	}
+
+	// SQLite renders booleans as 0 and 1
+	if dest.Kind() == reflect.Bool {
+		if number, ok := value.(float64); ok {
+			dest.SetBool(number != 0)
+			return nil
+		}
+	}
+	return json.Unmarshal(raw, dest.Addr().Interface())
+}
+
+// setTime stores a time into the supported timestamp field types
+func setTime(dest reflect.Value, t time.Time) error {
+	switch {
+	case dest.Type() == timeType:
+		dest.Set(reflect.ValueOf(t))
+	case dest.Kind() == reflect.Ptr:
+		dest.Set(reflect.ValueOf(&t))
+	default:
+		return dest.Addr().Interface().(sql.Scanner).Scan(t)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+)
+
+// ChangeListener receives entity changes after they are committed
+// Changes made inside a transaction are delivered once on commit and dropped on rollback;
+// changes made outside a transaction are delivered right after the statement succeeds.
+// Bulk operations without entities (UpdateWhere, Delete, PurgeTrashed) are not reported.
+type ChangeListener[T domain.BaseModel] func(ctx context.Context, changes []domain.Change[T])
+
+// OnChange registers a listener for changes made by units of work created by the factory
+func (f *UnitOfWorkFactory[T]) OnChange(listener ChangeListener[T]) *UnitOfWorkFactory[T] {
+	f.settings.addListener(listener)
+	return f
+}
+
+func (s *entitySettings[T]) addListener(listener ChangeListener[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *entitySettings[T]) changeListeners() []ChangeListener[T] {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listeners
+}
+
+// AfterCommit registers a function run after the current transaction commits
+// It is discarded on rollback; outside a transaction it runs immediately
+func (uow *UnitOfWork[T]) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if !uow.inTx {
+		fn(ctx)
+		return
+	}
+	uow.afterCommit = append(uow.afterCommit, fn)
+}
+
+// AfterRollback registers a function run after the current transaction rolls back, or after a
+// RollbackTo undoes the work done since it was registered; hooks run newest first.
+// It is discarded on commit; outside a transaction there is nothing to roll back and it never runs
+func (uow *UnitOfWork[T]) AfterRollback(ctx context.Context, fn func(ctx context.Context)) {
+	if !uow.inTx {
+		return
+	}
+	uow.afterRollback = append(uow.afterRollback, fn)
+}
+
+// recordChanges reports changed entities to the factory's listeners, deferring them to commit inside a transaction
+func (uow *UnitOfWork[T]) recordChanges(ctx context.Context, kind domain.ChangeKind, entities ...T) {
+	if len(entities) == 0 || len(uow.settings.changeListeners()) == 0 {
+		return
+	}
+
+	changes := make([]domain.Change[T], len(entities))
+	for i, entity := range entities {
+		// Results may be masked in place before the listeners run
+		changes[i] = domain.Change[T]{Kind: kind, Entity: cloneEntity(entity)}
+	}
+
+	if uow.inTx {
+		uow.pendingChanges = append(uow.pendingChanges, changes...)
+		return
+	}
+	uow.dispatchChanges(ctx, changes)
+}
+
+func (uow *UnitOfWork[T]) dispatchChanges(ctx context.Context, changes []domain.Change[T]) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, listener := range uow.settings.changeListeners() {
+		listener(ctx, changes)
+	}
+}
+
+// committed delivers what was deferred until commit
+func (uow *UnitOfWork[T]) committed(ctx context.Context) {
+	changes, hooks := uow.pendingChanges, uow.afterCommit
+	uow.pendingChanges, uow.afterCommit, uow.afterRollback = nil, nil, nil
+
+	if uow.cacheWrites != nil {
+		for _, table := range uow.cacheWrites.drain() {
+			bumpGeneration(ctx, uow.config.Cache, table)
+		}
+	}
+
+	uow.dispatchChanges(ctx, changes)
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+}
+
+// rolledBack drops what was deferred until commit and runs the rollback hooks
+func (uow *UnitOfWork[T]) rolledBack(ctx context.Context) {
+	undo := uow.afterRollback
+	uow.pendingChanges, uow.afterCommit, uow.afterRollback = nil, nil, nil
+	if uow.cacheWrites != nil {
+		uow.cacheWrites.drain()
+	}
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i](ctx)
+	}
+}
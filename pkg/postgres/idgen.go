@@ -0,0 +1,303 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// IDGenerator produces column values client-side, before rows are inserted
+// Needed when IDs must be unique across shards or known before the row reaches the database (offline sync)
+type IDGenerator interface {
+	// NextIDs returns n new values, assignable to the column's field type
+	NextIDs(ctx context.Context, n int) ([]interface{}, error)
+}
+
+// IDGeneratorFunc adapts a function returning one value at a time to IDGenerator
+type IDGeneratorFunc func(ctx context.Context) (interface{}, error)
+
+// NextIDs implements IDGenerator
+func (f IDGeneratorFunc) NextIDs(ctx context.Context, n int) ([]interface{}, error) {
+	ids := make([]interface{}, n)
+	for i := range ids {
+		id, err := f(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// GenerateIDs fills the column (usually the primary key) of inserted entities from the generator
+// when it is zero; without it the database assigns IDs, e.g. from the column's own sequence.
+// Applies to Insert, InsertReturning, Clone, BulkInsert, BulkUpsert and CopyInsert
+func (f *UnitOfWorkFactory[T]) GenerateIDs(column string, generator IDGenerator) *UnitOfWorkFactory[T] {
+	f.settings.setIDGenerator(column, generator)
+	return f
+}
+
+// GenerateIDs fills the column of inserted entities from the generator; see UnitOfWorkFactory.GenerateIDs
+func (f *ShardedFactory[T]) GenerateIDs(column string, generator IDGenerator) *ShardedFactory[T] {
+	f.settings.setIDGenerator(column, generator)
+	return f
+}
+
+func (s *entitySettings[T]) setIDGenerator(column string, generator IDGenerator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.idGenerators == nil {
+		s.idGenerators = make(map[string]IDGenerator)
+	}
+	s.idGenerators[column] = generator
+}
+
+func (s *entitySettings[T]) idGeneratorsOf() map[string]IDGenerator {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.idGenerators
+}
+
+// assignIDs fills the generated columns that are still zero on the entities
+func (uow *UnitOfWork[T]) assignIDs(ctx context.Context, entities ...T) error {
+	generators := uow.settings.idGeneratorsOf()
+	if len(generators) == 0 {
+		return nil
+	}
+	meta := uow.metadata()
+	for column, generator := range generators {
+		field, ok := meta.Field(column)
+		if !ok {
+			return fmt.Errorf("%w: unknown generated ID column %q", uowerrors.ErrInvalidQueryParams, column)
+		}
+		var missing []reflect.Value
+		for _, entity := range entities {
+			if v, ok := meta.structValue(entity); ok && v.FieldByIndex(field.Index).IsZero() {
+				missing = append(missing, v.FieldByIndex(field.Index))
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		ids, err := generator.NextIDs(ctx, len(missing))
+		if err != nil {
+			return fmt.Errorf("failed to generate %s: %w", column, err)
+		}
+		if len(ids) != len(missing) {
+			return fmt.Errorf("failed to generate %s: got %d values for %d entities", column, len(ids), len(missing))
+		}
+		for i, target := range missing {
+			value := reflect.ValueOf(field.convert(meta.Type, ids[i]))
+			if !value.IsValid() || !value.Type().AssignableTo(target.Type()) {
+				return fmt.Errorf("%w: generated %s value %v does not fit %s", uowerrors.ErrInvalidEntity, column, ids[i], target.Type())
+			}
+			target.Set(value)
+		}
+	}
+	return nil
+}
+
+// Sequence draws IDs from a named database sequence, e.g. one global sequence shared by several
+// tables or shards; db is the database holding it. Values are fetched in one round trip per batch
+func Sequence(db *gorm.DB, name string) IDGenerator {
+	return &sequenceGenerator{db: db, name: name}
+}
+
+type sequenceGenerator struct {
+	db   *gorm.DB
+	name string
+}
+
+// NextIDs implements IDGenerator
+func (g *sequenceGenerator) NextIDs(ctx context.Context, n int) ([]interface{}, error) {
+	var values []int64
+	err := g.db.WithContext(ctx).Raw("SELECT nextval(?) FROM generate_series(1, ?)", g.name, n).Scan(&values).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sequence %s: %w", g.name, err)
+	}
+	ids := make([]interface{}, len(values))
+	for i, value := range values {
+		ids[i] = value
+	}
+	return ids, nil
+}
+
+// Snowflake layout: 41 bits of milliseconds since SnowflakeEpoch, 10 bits of node, 12 bits of sequence
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	MaxSnowflakeNode      = 1<<snowflakeNodeBits - 1
+)
+
+// SnowflakeEpoch is the zero time of Snowflake IDs
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates time-ordered int64 IDs, unique as long as every process uses its own node
+// number; up to 4096 IDs per node and millisecond, after which it waits for the next millisecond
+type Snowflake struct {
+	mu       sync.Mutex
+	node     int64
+	last     int64 // Millisecond of the last ID
+	sequence int64
+	now      func() time.Time
+}
+
+// NewSnowflake creates a generator for a node number between 0 and MaxSnowflakeNode
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node %d is outside 0..%d", node, MaxSnowflakeNode)
+	}
+	return &Snowflake{node: node, now: time.Now}, nil
+}
+
+// Next returns a new ID
+func (s *Snowflake) Next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := s.now().Sub(SnowflakeEpoch).Milliseconds()
+	if ms < s.last {
+		ms = s.last // The clock went back; keep IDs increasing
+	}
+	if ms == s.last {
+		s.sequence = (s.sequence + 1) & (1<<snowflakeSequenceBits - 1)
+		if s.sequence == 0 {
+			for ms <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				ms = s.now().Sub(SnowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.last = ms
+	return ms<<(snowflakeNodeBits+snowflakeSequenceBits) | s.node<<snowflakeSequenceBits | s.sequence
+}
+
+// NextIDs implements IDGenerator
+func (s *Snowflake) NextIDs(_ context.Context, n int) ([]interface{}, error) {
+	ids := make([]interface{}, n)
+	for i := range ids {
+		ids[i] = s.Next()
+	}
+	return ids, nil
+}
+
+// crockford is the ULID alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID generates 26-character, lexicographically sortable IDs: 48 bits of milliseconds and 80
+// random bits, incremented within a millisecond so IDs of one generator stay ordered
+type ULID struct {
+	mu      sync.Mutex
+	last    int64
+	entropy [10]byte
+	now     func() time.Time
+}
+
+// NewULID creates a ULID generator
+func NewULID() *ULID {
+	return &ULID{now: time.Now}
+}
+
+// Next returns a new ULID
+func (u *ULID) Next() (string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	ms := u.now().UnixMilli()
+	if ms <= u.last {
+		ms = u.last
+		// Increment the 80-bit entropy; overflow within one millisecond is practically impossible
+		for i := len(u.entropy) - 1; i >= 0; i-- {
+			u.entropy[i]++
+			if u.entropy[i] != 0 {
+				break
+			}
+		}
+	} else if _, err := rand.Read(u.entropy[:]); err != nil {
+		return "", fmt.Errorf("failed to read ULID entropy: %w", err)
+	}
+	u.last = ms
+
+	var raw [16]byte
+	binary.BigEndian.PutUint16(raw[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(raw[2:6], uint32(ms))
+	copy(raw[6:], u.entropy[:])
+
+	// 128 bits as 26 base32 digits, the first carrying the top 3 bits
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+// NextIDs implements IDGenerator
+func (u *ULID) NextIDs(_ context.Context, n int) ([]interface{}, error) {
+	ids := make([]interface{}, n)
+	for i := range ids {
+		id, err := u.Next()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// UUIDv7 generates RFC 9562 version 7 UUIDs in canonical text form: 48 bits of milliseconds
+// followed by random bits, so they sort by creation time and index well
+type UUIDv7 struct {
+	now func() time.Time
+}
+
+// NewUUIDv7 creates a UUIDv7 generator
+func NewUUIDv7() *UUIDv7 {
+	return &UUIDv7{now: time.Now}
+}
+
+// Next returns a new UUID
+func (g *UUIDv7) Next() (string, error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[6:]); err != nil {
+		return "", fmt.Errorf("failed to read UUID entropy: %w", err)
+	}
+	ms := g.now().UnixMilli()
+	binary.BigEndian.PutUint16(raw[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(raw[2:6], uint32(ms))
+	raw[6] = raw[6]&0x0f | 0x70 // Version 7
+	raw[8] = raw[8]&0x3f | 0x80 // RFC 9562 variant
+
+	text := hex.EncodeToString(raw[:])
+	return text[0:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:32], nil
+}
+
+// NextIDs implements IDGenerator
+func (g *UUIDv7) NextIDs(_ context.Context, n int) ([]interface{}, error) {
+	ids := make([]interface{}, n)
+	for i := range ids {
+		id, err := g.Next()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
@@ -0,0 +1,282 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// OrphanRepair is what an integrity repair does with orphaned child rows
+type OrphanRepair string
+
+const (
+	RepairNone       OrphanRepair = ""            // Report only
+	RepairNullify    OrphanRepair = "nullify"     // Clear the foreign key
+	RepairSoftDelete OrphanRepair = "soft_delete" // Trash the child; needs a deleted_at column on it
+	RepairDelete     OrphanRepair = "delete"      // Hard delete the child
+)
+
+// Relation is a child-to-parent reference checked by the integrity audit, with or without a
+// database constraint behind it. Only live children (not soft-deleted) are checked
+type Relation struct {
+	Child           string // Child table
+	ChildKey        string // Child primary key column, reported in samples
+	ForeignKey      string // Child column holding the parent key
+	Parent          string // Parent table
+	ParentKey       string // Referenced parent column
+	ChildDeletedAt  string // Soft-delete column of the child, empty when it has none
+	ParentDeletedAt string // Soft-delete column of the parent, empty when it has none
+	Repair          OrphanRepair
+}
+
+// String renders the relation as child.fk -> parent.key
+func (r Relation) String() string {
+	return fmt.Sprintf("%s.%s -> %s.%s", r.Child, r.ForeignKey, r.Parent, r.ParentKey)
+}
+
+// IntegrityOptions configures an integrity audit
+type IntegrityOptions struct {
+	Repair     bool // Apply each relation's Repair to its orphans; otherwise only report
+	SampleSize int  // Orphan child keys reported per relation and kind, default 10
+}
+
+// RelationIntegrity reports the orphans of one relation
+type RelationIntegrity struct {
+	Relation      Relation
+	Missing       int64         // Children whose parent row no longer exists (hard-deleted)
+	Trashed       int64         // Children whose parent row is soft-deleted
+	MissingSample []interface{} // Child keys of missing-parent orphans
+	TrashedSample []interface{} // Child keys of trashed-parent orphans
+	Repaired      int64         // Children changed by the repair
+	RepairSkipped string        // Why a requested repair did not run
+}
+
+// Orphans counts both kinds of orphans
+func (r RelationIntegrity) Orphans() int64 {
+	return r.Missing + r.Trashed
+}
+
+// IntegrityReport is the result of an integrity audit
+type IntegrityReport struct {
+	Relations []RelationIntegrity
+}
+
+// Clean reports whether no relation has orphans left
+func (r IntegrityReport) Clean() bool {
+	for _, relation := range r.Relations {
+		if relation.Orphans() > relation.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckIntegrity scans the relations for orphaned child rows and, with options.Repair, fixes them
+// Run it after bulk purges: PurgeTrashed and HardDelete leave children of soft foreign keys behind.
+// Each relation's repair runs in its own transaction
+func CheckIntegrity(ctx context.Context, db *gorm.DB, relations []Relation, options IntegrityOptions) (IntegrityReport, error) {
+	if options.SampleSize <= 0 {
+		options.SampleSize = 10
+	}
+	report := IntegrityReport{Relations: make([]RelationIntegrity, 0, len(relations))}
+	for _, relation := range relations {
+		result, err := checkRelation(ctx, db.WithContext(ctx), relation, options)
+		report.Relations = append(report.Relations, result)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// checkRelation audits and optionally repairs one relation
+func checkRelation(ctx context.Context, db *gorm.DB, relation Relation, options IntegrityOptions) (RelationIntegrity, error) {
+	result := RelationIntegrity{Relation: relation}
+	missing, trashed := orphanConditions(relation)
+
+	var err error
+	if result.Missing, result.MissingSample, err = countOrphans(db, relation, missing, options.SampleSize); err != nil {
+		return result, err
+	}
+	if trashed != "" {
+		if result.Trashed, result.TrashedSample, err = countOrphans(db, relation, trashed, options.SampleSize); err != nil {
+			return result, err
+		}
+	}
+
+	if !options.Repair || result.Orphans() == 0 {
+		return result, nil
+	}
+	if relation.Repair == RepairNone {
+		result.RepairSkipped = "no repair declared"
+		return result, nil
+	}
+	if relation.Repair == RepairSoftDelete && relation.ChildDeletedAt == "" {
+		result.RepairSkipped = "child has no soft-delete column"
+		return result, nil
+	}
+
+	condition := missing
+	if trashed != "" {
+		condition = "(" + missing + ") OR (" + trashed + ")"
+	}
+	child := quoteIdentifier(relation.Child)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var statement string
+		var args []interface{}
+		switch relation.Repair {
+		case RepairNullify:
+			statement = fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s", child, quoteIdentifier(relation.ForeignKey), condition)
+		case RepairSoftDelete:
+			statement = fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s", child, quoteIdentifier(relation.ChildDeletedAt), condition)
+			args = append(args, time.Now())
+		case RepairDelete:
+			statement = fmt.Sprintf("DELETE FROM %s WHERE %s", child, condition)
+		default:
+			return fmt.Errorf("unknown orphan repair %q", relation.Repair)
+		}
+		exec := tx.Exec(statement, args...)
+		if exec.Error != nil {
+			return exec.Error
+		}
+		result.Repaired = exec.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to repair orphans of %s: %w", relation, err)
+	}
+	return result, nil
+}
+
+// orphanConditions builds the WHERE conditions, over the unaliased child table, for children with a
+// missing parent and for children with a trashed parent (empty when the parent has no soft delete)
+func orphanConditions(relation Relation) (missing, trashed string) {
+	child := quoteIdentifier(relation.Child)
+	fk := child + "." + quoteIdentifier(relation.ForeignKey)
+	parent := quoteIdentifier(relation.Parent) + " AS uow_parent"
+	parentKey := "uow_parent." + quoteIdentifier(relation.ParentKey)
+
+	live := ""
+	if relation.ChildDeletedAt != "" {
+		live = " AND " + child + "." + quoteIdentifier(relation.ChildDeletedAt) + " IS NULL"
+	}
+
+	missing = fmt.Sprintf("%s IS NOT NULL%s AND NOT EXISTS (SELECT 1 FROM %s WHERE %s = %s)", fk, live, parent, parentKey, fk)
+	if relation.ParentDeletedAt != "" {
+		trashed = fmt.Sprintf("%s IS NOT NULL%s AND EXISTS (SELECT 1 FROM %s WHERE %s = %s AND uow_parent.%s IS NOT NULL)",
+			fk, live, parent, parentKey, fk, quoteIdentifier(relation.ParentDeletedAt))
+	}
+	return missing, trashed
+}
+
+// countOrphans counts the children matching condition and samples their keys
+func countOrphans(db *gorm.DB, relation Relation, condition string, sampleSize int) (int64, []interface{}, error) {
+	var count int64
+	if err := db.Table(relation.Child).Where(condition).Count(&count).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to count orphans of %s: %w", relation, err)
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+
+	var sample []interface{}
+	key := quoteIdentifier(relation.Child) + "." + quoteIdentifier(relation.ChildKey)
+	if err := db.Table(relation.Child).Where(condition).Order(key).Limit(sampleSize).Pluck(key, &sample).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to sample orphans of %s: %w", relation, err)
+	}
+	return count, sample, nil
+}
+
+// DeclareRelations adds child-to-parent references the models do not express as associations,
+// e.g. a plain owner_id column; Relations reports them along with the derived ones
+func (r *ModelRegistry) DeclareRelations(relations ...Relation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.relations = append(r.relations, relations...)
+}
+
+// Relations derives the child-to-parent references between registered models from their
+// belongs-to, has-one and has-many associations, followed by the declared ones; repair actions
+// are left for the caller to set
+func (r *ModelRegistry) Relations(db *gorm.DB) ([]Relation, error) {
+	var relations []Relation
+	seen := make(map[string]bool)
+	cache := &sync.Map{}
+	for _, model := range r.Models() {
+		s, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		for _, rel := range s.Relationships.Relations {
+			if len(rel.References) != 1 || rel.JoinTable != nil {
+				continue
+			}
+			var childSchema, parentSchema *schema.Schema
+			switch rel.Type {
+			case schema.BelongsTo:
+				childSchema, parentSchema = rel.Schema, rel.FieldSchema
+			case schema.HasOne, schema.HasMany:
+				childSchema, parentSchema = rel.FieldSchema, rel.Schema
+			default:
+				continue
+			}
+			reference := rel.References[0]
+			if reference.PrimaryKey == nil || childSchema.PrioritizedPrimaryField == nil {
+				continue
+			}
+			relation := Relation{
+				Child:           childSchema.Table,
+				ChildKey:        childSchema.PrioritizedPrimaryField.DBName,
+				ForeignKey:      reference.ForeignKey.DBName,
+				Parent:          parentSchema.Table,
+				ParentKey:       reference.PrimaryKey.DBName,
+				ChildDeletedAt:  softDeleteColumn(childSchema),
+				ParentDeletedAt: softDeleteColumn(parentSchema),
+			}
+			if key := relation.String(); !seen[key] {
+				seen[key] = true
+				relations = append(relations, relation)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, relation := range r.relations {
+		if key := relation.String(); !seen[key] {
+			seen[key] = true
+			relations = append(relations, relation)
+		}
+	}
+	return relations, nil
+}
+
+// CheckIntegrity audits the relations between registered models; see CheckIntegrity
+// repairs maps a relation's String() to the repair applied to its orphans
+func (r *ModelRegistry) CheckIntegrity(ctx context.Context, db *gorm.DB, repairs map[string]OrphanRepair, options IntegrityOptions) (IntegrityReport, error) {
+	relations, err := r.Relations(db)
+	if err != nil {
+		return IntegrityReport{}, err
+	}
+	for i := range relations {
+		relations[i].Repair = repairs[relations[i].String()]
+	}
+	return CheckIntegrity(ctx, db, relations, options)
+}
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// softDeleteColumn returns the gorm.DeletedAt column of a schema, or ""
+func softDeleteColumn(s *schema.Schema) string {
+	for _, field := range s.Fields {
+		if field.FieldType == deletedAtType && field.DBName != "" {
+			return field.DBName
+		}
+	}
+	return ""
+}
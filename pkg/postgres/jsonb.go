@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JSONBSet sets the value at path inside a jsonb column of the matching entities, in one statement
+// without reading them first. path starts with the field, then the keys to descend ("attributes.color",
+// "attributes.sizes.0"); the value is JSON-encoded. Parents of the target must exist; a NULL column
+// is treated as an empty object. Compiles to jsonb_set, or json_set on SQLite
+func (uow *UnitOfWork[T]) JSONBSet(ctx context.Context, identifier identifier.IIdentifier, path string, value interface{}) (int64, error) {
+	column, keys, err := jsonbPath(uow.metadata(), path)
+	if err != nil {
+		return 0, err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: jsonb value for %s: %v", uowerrors.ErrInvalidQueryParams, path, err)
+	}
+
+	db := uow.getActiveDB(ctx)
+	var expr clause.Expr
+	if db.Dialector.Name() == "sqlite" {
+		expr = gorm.Expr("json_set(COALESCE(?, '{}'), ?, json(?))", clause.Column{Name: column}, sqliteJSONPath(keys), string(encoded))
+	} else {
+		expr = gorm.Expr("jsonb_set(COALESCE(?, '{}'::jsonb), ?::text[], ?::jsonb, true)", clause.Column{Name: column}, postgresTextArray(keys), string(encoded))
+	}
+	return uow.updateJSONB(db, "jsonb set", identifier, column, expr)
+}
+
+// JSONBRemove deletes the key or array element at path inside a jsonb column of the matching entities
+// Compiles to the #- operator, or json_remove on SQLite
+func (uow *UnitOfWork[T]) JSONBRemove(ctx context.Context, identifier identifier.IIdentifier, path string) (int64, error) {
+	column, keys, err := jsonbPath(uow.metadata(), path)
+	if err != nil {
+		return 0, err
+	}
+
+	db := uow.getActiveDB(ctx)
+	var expr clause.Expr
+	if db.Dialector.Name() == "sqlite" {
+		expr = gorm.Expr("json_remove(?, ?)", clause.Column{Name: column}, sqliteJSONPath(keys))
+	} else {
+		expr = gorm.Expr("? #- ?::text[]", clause.Column{Name: column}, postgresTextArray(keys))
+	}
+	return uow.updateJSONB(db, "jsonb remove", identifier, column, expr)
+}
+
+// updateJSONB runs a single-column jsonb update over the matching entities
+func (uow *UnitOfWork[T]) updateJSONB(db *gorm.DB, op string, id identifier.IIdentifier, column string, expr clause.Expr) (int64, error) {
+	db, err := uow.scopedMutation(db.Model(newEntity[T]()), op, id)
+	if err != nil {
+		return 0, err
+	}
+	result := db.Update(column, expr)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to %s %s: %w", op, column, result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// jsonbPath splits "field.key.key" into the field's column and the keys inside the document
+func jsonbPath(meta *modelMetadata, path string) (string, []string, error) {
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("%w: jsonb path %q needs a field and at least one key", uowerrors.ErrInvalidQueryParams, path)
+	}
+	field, ok := meta.Field(parts[0])
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown jsonb field %q", uowerrors.ErrInvalidQueryParams, parts[0])
+	}
+	for _, key := range parts[1:] {
+		if key == "" {
+			return "", nil, fmt.Errorf("%w: jsonb path %q has an empty key", uowerrors.ErrInvalidQueryParams, path)
+		}
+	}
+	return field.Column, parts[1:], nil
+}
+
+// postgresTextArray renders keys as a text[] literal with every element quoted
+func postgresTextArray(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		key = strings.ReplaceAll(key, `\`, `\\`)
+		quoted[i] = `"` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+// sqliteJSONPath renders keys as a SQLite JSON path; numeric keys address array elements
+func sqliteJSONPath(keys []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, key := range keys {
+		if _, err := strconv.Atoi(key); err == nil {
+			b.WriteString("[" + key + "]")
+			continue
+		}
+		b.WriteString(`."` + strings.ReplaceAll(key, `"`, `\"`) + `"`)
+	}
+	return b.String()
+}
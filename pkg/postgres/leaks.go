@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// LeakDetection reports units of work that are never closed and transactions that are never finished,
+// the usual causes of connection pool exhaustion
+type LeakDetection struct {
+	// GracePeriod is how long a unit of work may stay open, or a transaction unfinished, before it is reported
+	GracePeriod time.Duration
+	// CaptureStacks records where each unit of work and transaction was created; meant for debugging
+	// as it costs a stack capture per call
+	CaptureStacks bool
+	// Report receives each leak once; default: the standard logger
+	Report func(LeakReport)
+}
+
+// LeakKind tells what was left open
+type LeakKind string
+
+const (
+	LeakUnitOfWork  LeakKind = "unit_of_work" // Created and never closed
+	LeakTransaction LeakKind = "transaction"  // Begun and neither committed nor rolled back
+)
+
+// LeakReport describes one suspected leak
+type LeakReport struct {
+	Kind     LeakKind
+	Entity   string
+	OpenedAt time.Time
+	OpenFor  time.Duration
+	Stack    string // Empty unless CaptureStacks is set
+}
+
+// String formats the report for logs
+func (r LeakReport) String() string {
+	message := fmt.Sprintf("postgres: %s for %s opened %s ago was never finished", r.Kind, r.Entity, r.OpenFor.Round(time.Millisecond))
+	if r.Stack != "" {
+		message += "\n" + r.Stack
+	}
+	return message
+}
+
+// leakWatch reports an open resource unless stopped within the grace period
+type leakWatch struct {
+	once  sync.Once
+	timer *time.Timer
+}
+
+// watchLeak starts a watch for a resource opened now; returns nil when leak detection is off
+func watchLeak(config *Config, kind LeakKind, entity string) *leakWatch {
+	if config == nil || config.LeakDetection == nil || config.LeakDetection.GracePeriod <= 0 {
+		return nil
+	}
+	detection := config.LeakDetection
+	report := LeakReport{Kind: kind, Entity: entity, OpenedAt: time.Now()}
+	if detection.CaptureStacks {
+		report.Stack = string(debug.Stack())
+	}
+
+	metrics := metricsOf(config)
+	watch := &leakWatch{}
+	watch.timer = time.AfterFunc(detection.GracePeriod, func() {
+		report.OpenFor = time.Since(report.OpenedAt)
+		metrics.IncCounter("uow_leaks_total", 1, map[string]string{"kind": string(kind), "entity": entity})
+		if detection.Report != nil {
+			detection.Report(report)
+			return
+		}
+		log.Print(report.String())
+	})
+	return watch
+}
+
+// stop marks the resource as finished; safe on a nil watch and when called repeatedly
+func (w *leakWatch) stop() {
+	if w == nil {
+		return
+	}
+	w.once.Do(func() { w.timer.Stop() })
+}
+
+// entityName names an entity type for reports and metrics
+func entityName[T any]() string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
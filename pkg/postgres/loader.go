@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+
+	"gorm.io/gorm"
+)
+
+// Loader defaults
+const (
+	DefaultLoaderWait     = 2 * time.Millisecond
+	DefaultLoaderMaxBatch = 500
+)
+
+// Loader coalesces FindOneById calls issued within a short window into one
+// WHERE id IN (...) query and fans the results back out (dataloader pattern)
+// Create one Loader per request; results are not cached across batches
+type Loader[T domain.BaseModel] struct {
+	uow      persistence.IUnitOfWork[T]
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending *loaderBatch[T]
+}
+
+// loaderBatch collects the IDs requested during one window
+type loaderBatch[T domain.BaseModel] struct {
+	ctx     context.Context
+	ids     []int
+	seen    map[int]struct{}
+	done    chan struct{}
+	results map[int]T
+	err     error
+}
+
+// NewLoader creates a batching loader over a unit of work
+// Zero values for wait and maxBatch select the package defaults
+func NewLoader[T domain.BaseModel](uow persistence.IUnitOfWork[T], wait time.Duration, maxBatch int) *Loader[T] {
+	if wait <= 0 {
+		wait = DefaultLoaderWait
+	}
+	if maxBatch <= 0 {
+		maxBatch = DefaultLoaderMaxBatch
+	}
+	return &Loader[T]{
+		uow:      uow,
+		wait:     wait,
+		maxBatch: maxBatch,
+	}
+}
+
+// Load returns the entity with the given ID, batching with concurrent calls
+func (l *Loader[T]) Load(ctx context.Context, id int) (T, error) {
+	batch := l.enqueue(ctx, id)
+
+	var zero T
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	if batch.err != nil {
+		return zero, batch.err
+	}
+	entity, ok := batch.results[id]
+	if !ok {
+		return zero, fmt.Errorf("failed to find entity by id: %w", gorm.ErrRecordNotFound)
+	}
+	return entity, nil
+}
+
+// LoadMany returns the entities for the given IDs in request order
+// The error slice holds a per-ID error (nil on success)
+func (l *Loader[T]) LoadMany(ctx context.Context, ids []int) ([]T, []error) {
+	entities := make([]T, len(ids))
+	errs := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			entities[i], errs[i] = l.Load(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	return entities, errs
+}
+
+// enqueue adds an ID to the pending batch, starting a new window when needed
+func (l *Loader[T]) enqueue(ctx context.Context, id int) *loaderBatch[T] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batch := l.pending
+	if batch == nil {
+		batch = &loaderBatch[T]{
+			// The batch outlives the first caller, so it must not inherit its cancellation
+			ctx:  context.WithoutCancel(ctx),
+			seen: make(map[int]struct{}),
+			done: make(chan struct{}),
+		}
+		l.pending = batch
+		time.AfterFunc(l.wait, func() { l.dispatch(batch) })
+	}
+
+	if _, ok := batch.seen[id]; !ok {
+		batch.seen[id] = struct{}{}
+		batch.ids = append(batch.ids, id)
+	}
+
+	if len(batch.ids) >= l.maxBatch {
+		l.pending = nil
+		go l.fetch(batch)
+	}
+	return batch
+}
+
+// dispatch closes the window of a batch unless it was already flushed
+func (l *Loader[T]) dispatch(batch *loaderBatch[T]) {
+	l.mu.Lock()
+	if l.pending != batch {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+
+	l.fetch(batch)
+}
+
+// fetch runs the batched query and releases the waiters
+func (l *Loader[T]) fetch(batch *loaderBatch[T]) {
+	defer close(batch.done)
+
+	entities, err := l.uow.FindByIDs(batch.ctx, batch.ids)
+	if err != nil {
+		batch.err = err
+		return
+	}
+
+	batch.results = make(map[int]T, len(entities))
+	for _, entity := range entities {
+		batch.results[entity.GetID()] = entity
+	}
+}
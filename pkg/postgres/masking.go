@@ -0,0 +1,372 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"weak"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultMask is the replacement text used when a MaskingPolicy has no Mask set
+const DefaultMask = "***"
+
+// MaskingPolicy redacts fields tagged with `pii:"true"`
+// Applied to debug SQL logs, change maps and (optionally) query results
+type MaskingPolicy struct {
+	Mask        string   // Replacement text, default "***"
+	Columns     []string // Extra column names to treat as PII regardless of tags
+	MaskResults bool     // Redact returned entities unless the context grants PII access
+
+	mu         sync.RWMutex
+	columns    map[string]struct{}
+	types      sync.Map // reflect.Type -> []int (indices of PII fields)
+	registered sync.Map // metadataKey -> struct{} (types whose columns are recorded, per naming strategy)
+	masked     sync.Map // weak.Pointer[byte] -> struct{} (entities redacted by MaskEntity, until collected)
+}
+
+// piiAccessKey is the context key granting access to unmasked PII
+type piiAccessKey struct{}
+
+// WithPIIAccess returns a context whose queries receive unmasked PII values
+func WithPIIAccess(ctx context.Context) context.Context {
+	return context.WithValue(ctx, piiAccessKey{}, true)
+}
+
+// HasPIIAccess reports whether the context was granted access to unmasked PII
+func HasPIIAccess(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	allowed, _ := ctx.Value(piiAccessKey{}).(bool)
+	return allowed
+}
+
+// mask returns the configured replacement text
+func (p *MaskingPolicy) mask() string {
+	if p.Mask == "" {
+		return DefaultMask
+	}
+	return p.Mask
+}
+
+// Register records the PII columns declared by a model, named by GORM's default naming strategy
+func (p *MaskingPolicy) Register(model interface{}) {
+	p.RegisterWith(model, nil)
+}
+
+// RegisterWith records the PII columns declared by a model as namer names them, GORM's default when nil
+// Called automatically for every entity type handled by a Unit of Work, with its connection's strategy
+func (p *MaskingPolicy) RegisterWith(model interface{}, namer schema.Namer) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return
+	}
+	if namer == nil {
+		namer = schema.NamingStrategy{}
+	}
+	if _, loaded := p.registered.LoadOrStore(metadataKey{t: t, namer: namerKey(namer)}, struct{}{}); loaded {
+		return
+	}
+
+	s, err := schema.Parse(reflect.New(t).Interface(), &sync.Map{}, namer)
+	if err != nil {
+		return
+	}
+
+	indices, columns := piiFields(s)
+	p.types.LoadOrStore(t, indices)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.columns == nil {
+		p.columns = make(map[string]struct{})
+	}
+	for _, column := range columns {
+		p.columns[column] = struct{}{}
+	}
+}
+
+// piiIndices returns the indices of the PII fields of a struct type
+func (p *MaskingPolicy) piiIndices(t reflect.Type) []int {
+	if cached, ok := p.types.Load(t); ok {
+		return cached.([]int)
+	}
+	var indices []int
+	if s, err := schema.Parse(reflect.New(t).Interface(), &sync.Map{}, schema.NamingStrategy{}); err == nil {
+		indices, _ = piiFields(s)
+	}
+	actual, _ := p.types.LoadOrStore(t, indices)
+	return actual.([]int)
+}
+
+// piiFields returns the indices and columns of a parsed model's top-level fields tagged `pii:"true"`
+func piiFields(s *schema.Schema) (indices []int, columns []string) {
+	for _, field := range s.Fields {
+		if field.Tag.Get("pii") != "true" || len(field.StructField.Index) != 1 {
+			continue
+		}
+		indices = append(indices, field.StructField.Index[0])
+		if field.DBName != "" {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return indices, columns
+}
+
+// IsPIIColumn reports whether a column is treated as PII
+func (p *MaskingPolicy) IsPIIColumn(column string) bool {
+	column = strings.ToLower(unquoteColumn(column))
+	for _, c := range p.Columns {
+		if strings.EqualFold(c, column) {
+			return true
+		}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.columns[column]
+	return ok
+}
+
+// MaskMap returns a copy of a column/value map with PII values redacted
+// Intended for change sets and audit payloads before they leave the process
+func (p *MaskingPolicy) MaskMap(values map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(values))
+	for column, value := range values {
+		if p.IsPIIColumn(column) {
+			masked[column] = p.mask()
+			continue
+		}
+		masked[column] = value
+	}
+	return masked
+}
+
+// MaskEntity redacts the PII fields of an entity in place
+// String fields receive the mask text, other kinds are reset to their zero value. The entity is
+// remembered as masked, and units of work refuse to write it while a PII field still holds its
+// redacted value, see MaskedField
+func (p *MaskingPolicy) MaskEntity(entity interface{}) {
+	v := reflect.ValueOf(entity)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || !v.CanSet() {
+		return
+	}
+
+	indices := p.piiIndices(v.Type())
+	for _, i := range indices {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		if field.Kind() == reflect.String {
+			field.SetString(p.mask())
+			continue
+		}
+		field.Set(reflect.Zero(field.Type()))
+	}
+	if len(indices) == 0 || v.Type().Size() == 0 {
+		return
+	}
+	ptr := (*byte)(v.Addr().UnsafePointer())
+	key := weak.Make(ptr)
+	if _, loaded := p.masked.LoadOrStore(key, struct{}{}); !loaded {
+		runtime.AddCleanup(ptr, func(key weak.Pointer[byte]) { p.masked.Delete(key) }, key)
+	}
+}
+
+// MaskedField returns the name of a PII field of an entity redacted by MaskEntity that still holds
+// its redacted value, telling it apart from an entity safe to write back. Entities that were never
+// masked pass, even when a PII value happens to equal the mask text
+func (p *MaskingPolicy) MaskedField(entity interface{}) (string, bool) {
+	v := reflect.ValueOf(entity)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || !v.CanAddr() || v.Type().Size() == 0 {
+		return "", false
+	}
+	if _, masked := p.masked.Load(weak.Make((*byte)(v.Addr().UnsafePointer()))); !masked {
+		return "", false
+	}
+
+	for _, i := range p.piiIndices(v.Type()) {
+		field := v.Field(i)
+		if field.Kind() == reflect.String && field.String() == p.mask() || field.Kind() != reflect.String && field.IsZero() {
+			return v.Type().Field(i).Name, true
+		}
+	}
+	return "", false
+}
+
+// MaskStatement redacts bound parameters that target PII columns
+// Handles comparison predicates (col = $1, col IN (?, ?)) and INSERT column lists
+func (p *MaskingPolicy) MaskStatement(sql string, params []interface{}) []interface{} {
+	if len(params) == 0 {
+		return params
+	}
+
+	masked := make([]interface{}, len(params))
+	copy(masked, params)
+
+	insertColumns := parseInsertColumns(sql)
+	valuesAt := -1
+	if insertColumns != nil {
+		valuesAt = indexFold(sql, " VALUES ")
+	}
+
+	next := 0
+	depth := 0
+	position := 0
+	inString := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		if c == '\'' {
+			inString = !inString
+			continue
+		}
+		if inString {
+			continue
+		}
+
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				position = 0
+			}
+			continue
+		case ')':
+			depth--
+			continue
+		case ',':
+			if depth == 1 {
+				position++
+			}
+			continue
+		case '?', '$':
+		default:
+			continue
+		}
+
+		index := -1
+		if c == '?' {
+			index = next
+			next++
+		} else {
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				continue
+			}
+			n, _ := strconv.Atoi(sql[i+1 : j])
+			index = n - 1
+		}
+		if index < 0 || index >= len(masked) {
+			continue
+		}
+
+		var column string
+		if valuesAt >= 0 && i > valuesAt && depth == 1 && position < len(insertColumns) {
+			column = insertColumns[position]
+		} else {
+			column = predicateColumn(sql[:i])
+		}
+		if column != "" && p.IsPIIColumn(column) {
+			masked[index] = p.mask()
+		}
+	}
+
+	return masked
+}
+
+var (
+	insertColumnsPattern   = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+\S+\s*\(([^)]*)\)\s*VALUES`)
+	predicateColumnPattern = regexp.MustCompile(`(?is)([\w."` + "`" + `]+)\s*(?:=|<>|!=|<=|>=|<|>|\bNOT\s+LIKE\b|\bLIKE\b|\bILIKE\b|\bIN\s*\((?:\s*(?:\$\d+|\?)\s*,)*)\s*$`)
+)
+
+// parseInsertColumns extracts the column list of an INSERT statement
+func parseInsertColumns(sql string) []string {
+	match := insertColumnsPattern.FindStringSubmatch(sql)
+	if match == nil {
+		return nil
+	}
+	columns := strings.Split(match[1], ",")
+	for i, column := range columns {
+		columns[i] = unquoteColumn(strings.TrimSpace(column))
+	}
+	return columns
+}
+
+// predicateColumn returns the column compared against the placeholder that follows prefix
+func predicateColumn(prefix string) string {
+	match := predicateColumnPattern.FindStringSubmatch(prefix)
+	if match == nil {
+		return ""
+	}
+	return unquoteColumn(match[1])
+}
+
+// unquoteColumn strips quoting and table qualification from a column reference
+func unquoteColumn(column string) string {
+	if dot := strings.LastIndexByte(column, '.'); dot >= 0 {
+		column = column[dot+1:]
+	}
+	return strings.Trim(column, "\"`")
+}
+
+// indexFold is a case-insensitive strings.Index
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToUpper(s), strings.ToUpper(substr))
+}
+
+// maskingLogger wraps a GORM logger and redacts PII parameters before they are rendered
+type maskingLogger struct {
+	logger.Interface
+	policy *MaskingPolicy
+}
+
+// newLogger builds the GORM logger for a configuration
+// Wraps the default logger with PII redaction when a masking policy is configured
+func newLogger(config *Config) logger.Interface {
+	base := logger.Default.LogMode(config.LogLevel)
+	if config.Masking == nil {
+		return base
+	}
+	return &maskingLogger{Interface: base, policy: config.Masking}
+}
+
+// LogMode keeps redaction in place when the log level changes (e.g. db.Debug())
+func (l *maskingLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &maskingLogger{Interface: l.Interface.LogMode(level), policy: l.policy}
+}
+
+// ParamsFilter implements gorm.ParamsFilter
+func (l *maskingLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	params = l.policy.MaskStatement(sql, params)
+	if filter, ok := l.Interface.(gorm.ParamsFilter); ok {
+		return filter.ParamsFilter(ctx, sql, params...)
+	}
+	return sql, params
+}
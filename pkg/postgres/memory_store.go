@@ -0,0 +1,289 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+
+	"gorm.io/gorm"
+)
+
+// FakeClock is a deterministic clock for tests; every reading advances it by a fixed step,
+// so rows stamped one after another get distinct, ordered timestamps
+type FakeClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewFakeClock creates a clock reading start first, then start+step, start+2*step, ...
+func NewFakeClock(start time.Time, step time.Duration) *FakeClock {
+	return &FakeClock{now: start, step: step}
+}
+
+// Now returns the current reading and advances the clock by its step
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// Advance moves the clock forward
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// MemoryStore is an in-memory table for handler tests of paginated APIs that should not need a
+// database. It is a persistence.IUnitOfWorkFactory whose units of work implement Insert,
+// BulkInsert, FindOneById and FindAllWithKeyset; other methods panic.
+//
+// Keyset pagination follows FindAllWithKeyset on PostgreSQL: filter entities match on their
+// non-zero fields, soft-deleted rows are hidden, rows order by the sort column then the primary
+// key with NULLs last ascending and first descending, rows with a NULL sort value never match a
+// cursor, and pages and cursors are built by the same code. Strings compare bytewise, like the
+// "C" collation. Column defaults, hooks and constraints are not applied
+type MemoryStore[T domain.BaseModel] struct {
+	mu     sync.Mutex
+	clock  *FakeClock
+	rows   map[int]T
+	nextID int
+}
+
+// NewMemoryStore creates an empty store stamping created_at, updated_at and deleted_at from the clock
+func NewMemoryStore[T domain.BaseModel](clock *FakeClock) *MemoryStore[T] {
+	return &MemoryStore[T]{clock: clock, rows: make(map[int]T), nextID: 1}
+}
+
+// Add stores entities as they are, assigning IDs and timestamps that are still zero
+func (s *MemoryStore[T]) Add(entities ...T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entity := range entities {
+		s.add(entity)
+	}
+}
+
+// SoftDelete marks the entity with the ID deleted at the clock's time
+func (s *MemoryStore[T]) SoftDelete(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entity, ok := s.rows[id]
+	if !ok {
+		return
+	}
+	meta := metadataOf[T]()
+	v, _ := meta.structValue(entity)
+	for _, field := range meta.Fields {
+		if target := v.FieldByIndex(field.Index); target.Type() == deletedAtType {
+			target.Set(reflect.ValueOf(gorm.DeletedAt{Time: s.clock.Now(), Valid: true}))
+		}
+	}
+}
+
+func (s *MemoryStore[T]) add(entity T) {
+	meta := metadataOf[T]()
+	v, ok := meta.structValue(entity)
+	if !ok {
+		return
+	}
+	if primaryKey, ok := meta.primaryKey(); ok {
+		if id := v.FieldByIndex(primaryKey.Index); id.IsZero() && id.CanInt() {
+			id.SetInt(int64(s.nextID))
+		}
+	}
+	now := s.clock.Now()
+	for _, column := range []string{"created_at", "updated_at"} {
+		if field, ok := meta.Field(column); ok {
+			if target := v.FieldByIndex(field.Index); target.IsZero() && target.Type() == reflect.TypeOf(now) {
+				target.Set(reflect.ValueOf(now))
+			}
+		}
+	}
+	if id := entity.GetID(); id >= s.nextID {
+		s.nextID = id + 1
+	}
+	s.rows[entity.GetID()] = cloneEntity(entity)
+}
+
+// Create implements persistence.IUnitOfWorkFactory
+func (s *MemoryStore[T]) Create() persistence.IUnitOfWork[T] {
+	return &memoryUnitOfWork[T]{store: s}
+}
+
+// CreateWithContext implements persistence.IUnitOfWorkFactory
+func (s *MemoryStore[T]) CreateWithContext(context.Context) persistence.IUnitOfWork[T] {
+	return s.Create()
+}
+
+// CreateReadOnly implements persistence.IUnitOfWorkFactory
+func (s *MemoryStore[T]) CreateReadOnly(context.Context) persistence.IReadOnlyUnitOfWork[T] {
+	return s.Create()
+}
+
+// memoryUnitOfWork serves a MemoryStore; writes apply immediately and transactions are no-ops
+type memoryUnitOfWork[T domain.BaseModel] struct {
+	persistence.IUnitOfWork[T]
+	store *MemoryStore[T]
+}
+
+func (u *memoryUnitOfWork[T]) BeginTransaction(context.Context) error         { return nil }
+func (u *memoryUnitOfWork[T]) BeginReadOnlyTransaction(context.Context) error { return nil }
+func (u *memoryUnitOfWork[T]) CommitTransaction(context.Context) error        { return nil }
+func (u *memoryUnitOfWork[T]) RollbackTransaction(context.Context)            {}
+func (u *memoryUnitOfWork[T]) Close() error                                   { return nil }
+
+func (u *memoryUnitOfWork[T]) BeginTransactionWithOptions(context.Context, *sql.TxOptions) error {
+	return nil
+}
+
+func (u *memoryUnitOfWork[T]) Transaction(_ context.Context, fn func(persistence.IUnitOfWork[T]) error) error {
+	return fn(u)
+}
+
+func (u *memoryUnitOfWork[T]) Insert(_ context.Context, entity T) (T, error) {
+	u.store.Add(entity)
+	return entity, nil
+}
+
+func (u *memoryUnitOfWork[T]) BulkInsert(_ context.Context, entities []T) ([]T, error) {
+	u.store.Add(entities...)
+	return entities, nil
+}
+
+func (u *memoryUnitOfWork[T]) FindOneById(_ context.Context, id int, _ ...domain.FindOption) (T, error) {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	entity, ok := u.store.rows[id]
+	if !ok || entity.GetArchivedAt().Valid {
+		var zero T
+		return zero, fmt.Errorf("failed to find entity by id: %w", gorm.ErrRecordNotFound)
+	}
+	return cloneEntity(entity), nil
+}
+
+func (u *memoryUnitOfWork[T]) FindAllWithKeyset(_ context.Context, query domain.KeysetParams[T]) (domain.KeysetPage[T], error) {
+	var page domain.KeysetPage[T]
+	query.Validate()
+
+	meta := metadataOf[T]()
+	sortField, ok := meta.Field(query.SortField)
+	if !ok {
+		return page, fmt.Errorf("%w: unknown keyset sort field %q", uowerrors.ErrInvalidQueryParams, query.SortField)
+	}
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return page, fmt.Errorf("%w: keyset pagination requires a primary key", uowerrors.ErrInvalidQueryParams)
+	}
+
+	// key returns the (sort column, primary key) row value
+	key := func(entity T) [2]interface{} {
+		v, _ := meta.structValue(entity)
+		return [2]interface{}{v.FieldByIndex(sortField.Index).Interface(), entity.GetID()}
+	}
+	cursorKey := func(cursor *domain.Cursor) [2]interface{} {
+		if sortField.Column == primaryKey.Column {
+			return [2]interface{}{cursor.ID, cursor.ID}
+		}
+		return [2]interface{}{sortField.convert(meta.Type, cursor.Value), cursor.ID}
+	}
+	sign := 1
+	if query.Direction == domain.SortDesc {
+		sign = -1
+	}
+
+	u.store.mu.Lock()
+	var entities []T
+	for _, entity := range u.store.rows {
+		if entity.GetArchivedAt().Valid || !matchesFilter(meta, query.Filter, entity) {
+			continue
+		}
+		if query.After != nil {
+			if c, known := compareRows(key(entity), cursorKey(query.After)); !known || c*sign <= 0 {
+				continue
+			}
+		}
+		if query.Before != nil {
+			if c, known := compareRows(key(entity), cursorKey(query.Before)); !known || c*sign >= 0 {
+				continue
+			}
+		}
+		entities = append(entities, cloneEntity(entity))
+	}
+	u.store.mu.Unlock()
+
+	if query.Backward {
+		sign = -sign
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		a, b := key(entities[i]), key(entities[j])
+		if c := compareSortValues(a[0], b[0]); c != 0 {
+			return c*sign < 0
+		}
+		return compareOrdered(a[1].(int), b[1].(int))*sign < 0
+	})
+	if len(entities) > query.Limit+1 {
+		entities = entities[:query.Limit+1]
+	}
+	return keysetPage(query, entities, meta, sortField, primaryKey), nil
+}
+
+// matchesFilter mirrors filterConditions: every non-zero filter field must be equal
+func matchesFilter[T domain.BaseModel](meta *modelMetadata, filter, entity T) bool {
+	f, ok := meta.structValue(filter)
+	if !ok {
+		return true
+	}
+	v, _ := meta.structValue(entity)
+	for _, field := range meta.Fields {
+		want := f.FieldByIndex(field.Index)
+		if !want.IsZero() && !valuesEqual(want.Interface(), v.FieldByIndex(field.Index).Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareRows compares row values like PostgreSQL; known is false when a NULL makes the result NULL
+func compareRows(a, b [2]interface{}) (result int, known bool) {
+	if isNull(a[0]) || isNull(b[0]) {
+		return 0, false
+	}
+	if c := compareValues(a[0], b[0]); c != 0 {
+		return c, true
+	}
+	return compareValues(a[1], b[1]), true
+}
+
+// compareSortValues orders NULLs after every value, as PostgreSQL does by default
+func compareSortValues(a, b interface{}) int {
+	switch nullA, nullB := isNull(a), isNull(b); {
+	case nullA && nullB:
+		return 0
+	case nullA:
+		return 1
+	case nullB:
+		return -1
+	}
+	return compareValues(a, b)
+}
+
+func isNull(v interface{}) bool {
+	if valuer, ok := v.(driver.Valuer); ok && !isNilPointer(v) {
+		value, err := valuer.Value()
+		return err == nil && value == nil
+	}
+	return v == nil || isNilPointer(v)
+}
@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Merge folds the entities matching duplicates into the one matching survivor, in one transaction:
+// survivor columns take duplicate values as the strategy says (duplicates are considered in key
+// order), child rows are re-pointed with Reassign, the duplicates are soft-deleted, and each
+// duplicate gets a row in the uow_merge_log table (domain.MergeRecord). PII columns are masked in
+// the log when the config has a masking policy. Inside a transaction it joins it
+func (uow *UnitOfWork[T]) Merge(ctx context.Context, survivor identifier.IIdentifier, duplicates identifier.IIdentifier, strategy domain.MergeStrategy) (result domain.MergeResult[T], err error) {
+	if survivor == nil || survivor.IsEmpty() || duplicates == nil || duplicates.IsEmpty() {
+		return result, fmt.Errorf("%w: merge needs identifiers for the survivor and the duplicates", uowerrors.ErrInvalidQueryParams)
+	}
+	meta := uow.metadata()
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return result, fmt.Errorf("%w: %s has no primary key", uowerrors.ErrInvalidEntity, meta.Table)
+	}
+	if !hasSoftDelete(meta) {
+		return result, fmt.Errorf("%w: %s has no soft-delete column to retire duplicates with", uowerrors.ErrInvalidEntity, meta.Table)
+	}
+	rules, err := mergeRules(meta, strategy)
+	if err != nil {
+		return result, err
+	}
+
+	if !uow.inTx {
+		if err := uow.BeginTransaction(ctx); err != nil {
+			return result, err
+		}
+		defer func() {
+			if err != nil {
+				uow.RollbackTransaction(ctx)
+				return
+			}
+			err = uow.CommitTransaction(ctx)
+		}()
+	}
+
+	db := uow.getActiveDB(ctx)
+	var kept T
+	if err := applyEntityIdentifier[T](db, survivor).First(&kept).Error; err != nil {
+		return result, fmt.Errorf("%w: failed to find merge survivor: %v", uowerrors.ErrEntityNotFound, err)
+	}
+	var merged []T
+	err = applyEntityIdentifier[T](db, duplicates).
+		Where(primaryKey.Qualified+" <> ?", kept.GetID()).
+		Order(primaryKey.Qualified).
+		Find(&merged).Error
+	if err != nil {
+		return result, fmt.Errorf("failed to find merge duplicates: %w", err)
+	}
+	if len(merged) == 0 {
+		return result, fmt.Errorf("%w: no duplicates of %s %d", uowerrors.ErrEntityNotFound, meta.Table, kept.GetID())
+	}
+
+	// Pick the merged values and remember which duplicate supplied each
+	before := cloneEntity(kept)
+	target, _ := meta.structValue(kept)
+	taken := make(map[int]map[string]domain.FieldChange, len(merged))
+	updates := make(map[string]interface{})
+	for i, field := range meta.Fields {
+		source := mergeSource(meta, rules[i], field, kept, merged)
+		if source == nil {
+			continue
+		}
+		value, _ := meta.structValue(*source)
+		old := target.FieldByIndex(field.Index).Interface()
+		target.FieldByIndex(field.Index).Set(value.FieldByIndex(field.Index))
+		change := domain.FieldChange{Old: old, New: target.FieldByIndex(field.Index).Interface()}
+		if valuesEqual(change.Old, change.New) {
+			continue
+		}
+		updates[field.Column] = change.New
+		id := (*source).GetID()
+		if taken[id] == nil {
+			taken[id] = make(map[string]domain.FieldChange)
+		}
+		taken[id][field.Column] = change
+	}
+
+	if len(updates) > 0 {
+		if err := db.Model(newEntity[T]()).Where(primaryKey.Qualified+" = ?", kept.GetID()).Updates(updates).Error; err != nil {
+			return result, fmt.Errorf("failed to update merge survivor: %w", err)
+		}
+	}
+	if err := db.First(&kept, primaryKey.Qualified+" = ?", kept.GetID()).Error; err != nil {
+		return result, fmt.Errorf("failed to reload merge survivor: %w", err)
+	}
+	if result.Changes, err = Diff(before, kept); err != nil {
+		return result, err
+	}
+	delete(result.Changes, "updated_at")
+
+	result.Moved = make(map[string]int64)
+	now := time.Now()
+	records := make([]domain.MergeRecord, 0, len(merged))
+	for _, duplicate := range merged {
+		reassigned, err := uow.Reassign(ctx, identifier.New().Equal(primaryKey.Column, duplicate.GetID()), kept.GetID())
+		if err != nil {
+			return result, err
+		}
+		for relation, rows := range reassigned.Moved {
+			result.Moved[relation] += rows
+		}
+		record, err := uow.mergeRecord(meta.Table, kept.GetID(), duplicate.GetID(), taken[duplicate.GetID()], reassigned.Moved)
+		if err != nil {
+			return result, err
+		}
+		record.Actor, record.MergedAt = strategy.Actor, now
+		records = append(records, record)
+		result.Duplicates = append(result.Duplicates, duplicate.GetID())
+	}
+
+	column := clause.Column{Table: clause.CurrentTable, Name: primaryKey.Column}
+	ids := make([]interface{}, len(result.Duplicates))
+	for i, id := range result.Duplicates {
+		ids[i] = id
+	}
+	if err := db.Where(clause.IN{Column: column, Values: ids}).Delete(newEntity[T]()).Error; err != nil {
+		return result, fmt.Errorf("failed to soft delete merge duplicates: %w", err)
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&records).Error; err != nil {
+		return result, fmt.Errorf("failed to record merge: %w", err)
+	}
+
+	uow.recordChanges(ctx, domain.ChangeUpdated, kept)
+	uow.recordChanges(ctx, domain.ChangeDeleted, merged...)
+	uow.maskResults(ctx, kept)
+	result.Survivor = kept
+	return result, nil
+}
+
+// mergeRules resolves the rule of every field, indexed like meta.Fields
+func mergeRules(meta *modelMetadata, strategy domain.MergeStrategy) ([]domain.MergeRule, error) {
+	for name, rule := range strategy.Columns {
+		if _, ok := meta.Field(name); !ok {
+			return nil, fmt.Errorf("%w: unknown merge column %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		if !validMergeRule(rule) {
+			return nil, fmt.Errorf("%w: unknown merge rule %q for %s", uowerrors.ErrInvalidQueryParams, rule, name)
+		}
+	}
+	if !validMergeRule(strategy.Default) {
+		return nil, fmt.Errorf("%w: unknown merge rule %q", uowerrors.ErrInvalidQueryParams, strategy.Default)
+	}
+
+	rules := make([]domain.MergeRule, len(meta.Fields))
+	for i, field := range meta.Fields {
+		if rule, ok := strategy.Columns[field.Column]; ok {
+			rules[i] = rule
+			continue
+		}
+		if rule, ok := strategy.Columns[field.Name]; ok {
+			rules[i] = rule
+			continue
+		}
+		managed := field.PrimaryKey || field.Column == "created_at" || field.Column == "updated_at" ||
+			meta.Type.FieldByIndex(field.Index).Type == deletedAtType
+		if !managed {
+			rules[i] = strategy.Default
+		}
+	}
+	return rules, nil
+}
+
+func validMergeRule(rule domain.MergeRule) bool {
+	switch rule {
+	case domain.MergeKeepSurvivor, domain.MergeFillEmpty, domain.MergePreferDuplicate, domain.MergeNewest:
+		return true
+	}
+	return false
+}
+
+// mergeSource returns the duplicate whose value the survivor takes for a field, or nil to keep its own
+func mergeSource[T domain.BaseModel](meta *modelMetadata, rule domain.MergeRule, field fieldMetadata, survivor T, duplicates []T) *T {
+	set := func(entity T) bool {
+		v, _ := meta.structValue(entity)
+		return !v.FieldByIndex(field.Index).IsZero()
+	}
+	switch rule {
+	case domain.MergeFillEmpty:
+		if set(survivor) {
+			return nil
+		}
+		fallthrough
+	case domain.MergePreferDuplicate:
+		for i := range duplicates {
+			if set(duplicates[i]) {
+				return &duplicates[i]
+			}
+		}
+	case domain.MergeNewest:
+		var newest *T
+		latest := survivor.GetUpdatedAt()
+		if !set(survivor) {
+			latest = time.Time{}
+		}
+		for i := range duplicates {
+			if set(duplicates[i]) && duplicates[i].GetUpdatedAt().After(latest) {
+				newest, latest = &duplicates[i], duplicates[i].GetUpdatedAt()
+			}
+		}
+		return newest
+	}
+	return nil
+}
+
+// mergeRecord builds the merge log row of one duplicate
+func (uow *UnitOfWork[T]) mergeRecord(table string, survivorID, duplicateID int, changes map[string]domain.FieldChange, moved map[string]int64) (domain.MergeRecord, error) {
+	logged := make(map[string]interface{}, len(changes))
+	for column, change := range changes {
+		logged[column] = change
+	}
+	if uow.config != nil && uow.config.Masking != nil {
+		uow.config.Masking.RegisterWith(newEntity[T](), namerOf(uow.db))
+		logged = uow.config.Masking.MaskMap(logged)
+	}
+	encodedChanges, err := json.Marshal(logged)
+	if err != nil {
+		return domain.MergeRecord{}, fmt.Errorf("failed to encode merge changes: %w", err)
+	}
+	encodedMoved, err := json.Marshal(moved)
+	if err != nil {
+		return domain.MergeRecord{}, fmt.Errorf("failed to encode merge moves: %w", err)
+	}
+	return domain.MergeRecord{
+		EntityTable: table,
+		SurvivorID:  survivorID,
+		DuplicateID: duplicateID,
+		Changes:     string(encodedChanges),
+		Moved:       string(encodedMoved),
+	}, nil
+}
+
+// hasSoftDelete reports whether the entity has a gorm.DeletedAt column
+func hasSoftDelete(meta *modelMetadata) bool {
+	for _, field := range meta.Fields {
+		if meta.Type.FieldByIndex(field.Index).Type == deletedAtType {
+			return true
+		}
+	}
+	return false
+}
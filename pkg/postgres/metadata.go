@@ -0,0 +1,250 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// modelMetadata caches the reflection-derived layout of an entity type
+// Built once per type so list queries don't walk struct fields on every call
+type modelMetadata struct {
+	Type     reflect.Type // Struct type (pointer element for *T entities)
+	Table    string
+	Fields   []fieldMetadata
+	byColumn map[string]int
+	byName   map[string]int
+	namer    schema.Namer // Strategy the columns were resolved with
+}
+
+// fieldMetadata describes one persisted field of an entity
+type fieldMetadata struct {
+	Name       string // Go field name
+	Index      []int  // Index path for reflect.Value.FieldByIndex
+	Column     string // Database column resolved by GORM's naming strategy
+	Qualified  string // Quoted, table-qualified column
+	TagColumn  string // Column derived from the json tag (BaseRepository filter convention)
+	PrimaryKey bool
+	Enum       reflect.Type // Field type implementing enum.Validator, nil otherwise
+	UniqueIf   string       // Condition of a partial unique index on this column alone, e.g. "deleted_at IS NULL"
+}
+
+var metadataCache sync.Map // metadataKey -> *modelMetadata
+
+// metadataKey caches metadata per type and naming strategy, so pools named differently coexist
+type metadataKey struct {
+	t     reflect.Type
+	namer interface{}
+}
+
+// metadataOf returns the cached metadata for an entity type parameter under GORM's default naming
+// strategy; code with a connection at hand uses metadataIn
+func metadataOf[T any]() *modelMetadata {
+	return metadataFor(reflect.TypeOf((*T)(nil)).Elem(), nil)
+}
+
+// metadataIn returns the cached metadata for an entity type parameter, with columns named the way db names them
+func metadataIn[T any](db *gorm.DB) *modelMetadata {
+	return metadataWith[T](namerOf(db))
+}
+
+// metadataWith returns the cached metadata for an entity type parameter under a naming strategy
+func metadataWith[T any](namer schema.Namer) *modelMetadata {
+	return metadataFor(reflect.TypeOf((*T)(nil)).Elem(), namer)
+}
+
+// metadata returns the entity's metadata under the naming strategy of the unit of work's pool
+func (uow *UnitOfWork[T]) metadata() *modelMetadata {
+	return metadataIn[T](uow.db)
+}
+
+// namerOf returns the naming strategy of a connection, GORM's default without one
+func namerOf(db *gorm.DB) schema.Namer {
+	if db == nil || db.Config == nil || db.NamingStrategy == nil {
+		return schema.NamingStrategy{}
+	}
+	return db.NamingStrategy
+}
+
+// namerKey identifies a naming strategy in cache keys; strategies that cannot be compared,
+// e.g. structs holding funcs, are told apart by their printed form
+func namerKey(namer schema.Namer) interface{} {
+	if namer == nil {
+		return schema.NamingStrategy{}
+	}
+	if reflect.TypeOf(namer).Comparable() {
+		return namer
+	}
+	return fmt.Sprintf("%T%#v", namer, namer)
+}
+
+// metadataFor returns the cached metadata for a type under a naming strategy, building it on
+// first use; a nil namer is GORM's default. Pointer types resolve to their element; non-struct
+// types yield empty metadata
+func metadataFor(t reflect.Type, namer schema.Namer) *modelMetadata {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if namer == nil {
+		namer = schema.NamingStrategy{}
+	}
+	key := metadataKey{t: t, namer: namerKey(namer)}
+	if cached, ok := metadataCache.Load(key); ok {
+		return cached.(*modelMetadata)
+	}
+
+	meta := buildMetadata(t, namer)
+	actual, _ := metadataCache.LoadOrStore(key, meta)
+	return actual.(*modelMetadata)
+}
+
+// buildMetadata reflects over a struct type once
+func buildMetadata(t reflect.Type, namer schema.Namer) *modelMetadata {
+	meta := &modelMetadata{
+		Type:     t,
+		namer:    namer,
+		byColumn: make(map[string]int),
+		byName:   make(map[string]int),
+	}
+	if t.Kind() != reflect.Struct {
+		return meta
+	}
+
+	s, err := schema.Parse(reflect.New(t).Interface(), &sync.Map{}, namer)
+	if err != nil {
+		return meta
+	}
+	meta.Table = s.Table
+
+	for _, field := range s.Fields {
+		if field.DBName == "" || len(field.StructField.Index) == 0 {
+			continue
+		}
+
+		columnName := field.Name
+		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
+			if tagName := strings.Split(jsonTag, ",")[0]; tagName != "-" && tagName != "" {
+				columnName = tagName
+			}
+		}
+
+		meta.byColumn[field.DBName] = len(meta.Fields)
+		meta.byName[field.Name] = len(meta.Fields)
+		meta.Fields = append(meta.Fields, fieldMetadata{
+			Name:       field.Name,
+			Index:      field.StructField.Index,
+			Column:     field.DBName,
+			Qualified:  quoteIdentifier(s.Table) + "." + quoteIdentifier(field.DBName),
+			TagColumn:  namer.ColumnName(s.Table, columnName),
+			PrimaryKey: field.PrimaryKey,
+			Enum:       enumType(field.FieldType),
+		})
+	}
+
+	for _, index := range s.ParseIndexes() {
+		if index.Class != "UNIQUE" || index.Where == "" || len(index.Fields) != 1 || index.Fields[0].Field == nil {
+			continue
+		}
+		if i, ok := meta.byColumn[index.Fields[0].DBName]; ok {
+			meta.Fields[i].UniqueIf = index.Where
+		}
+	}
+
+	return meta
+}
+
+// Field returns the metadata for a column or Go field name
+func (m *modelMetadata) Field(name string) (fieldMetadata, bool) {
+	if i, ok := m.byColumn[name]; ok {
+		return m.Fields[i], true
+	}
+	if i, ok := m.byName[name]; ok {
+		return m.Fields[i], true
+	}
+	return fieldMetadata{}, false
+}
+
+// primaryKey returns the metadata of the primary key column
+func (m *modelMetadata) primaryKey() (fieldMetadata, bool) {
+	for _, field := range m.Fields {
+		if field.PrimaryKey {
+			return field, true
+		}
+	}
+	return fieldMetadata{}, false
+}
+
+// convert coerces a decoded value (e.g. a JSON cursor's string or float64) to the field's Go type
+// Values that cannot be converted are returned unchanged
+func (f fieldMetadata) convert(t reflect.Type, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	fieldType := t.FieldByIndex(f.Index).Type
+	if reflect.TypeOf(value).AssignableTo(fieldType) {
+		return value
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	converted := reflect.New(fieldType)
+	if err := json.Unmarshal(data, converted.Interface()); err != nil {
+		return value
+	}
+	return converted.Elem().Interface()
+}
+
+// structValue dereferences an entity to its struct value
+// The second result is false for nil pointers and non-struct values
+func (m *modelMetadata) structValue(entity interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(entity)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct && v.Type() == m.Type
+}
+
+// filterConditions builds "col = ? AND ..." for the non-zero fields of a filter entity
+// Mirrors GORM's struct conditions (table-qualified columns) without re-parsing the struct on every call;
+// tagColumns selects the BaseRepository convention of json-tag columns on top-level fields
+func (m *modelMetadata) filterConditions(filter interface{}, tagColumns bool) (string, []interface{}) {
+	v, ok := m.structValue(filter)
+	if !ok {
+		return "", nil
+	}
+
+	var builder strings.Builder
+	var args []interface{}
+	for _, field := range m.Fields {
+		if tagColumns && len(field.Index) != 1 {
+			continue
+		}
+		value := v.FieldByIndex(field.Index)
+		if value.IsZero() {
+			continue
+		}
+
+		column := field.Qualified
+		if tagColumns {
+			column = field.TagColumn
+		}
+		if builder.Len() > 0 {
+			builder.WriteString(" AND ")
+		}
+		builder.WriteString(column)
+		builder.WriteString(" = ?")
+		args = append(args, value.Interface())
+	}
+
+	return builder.String(), args
+}
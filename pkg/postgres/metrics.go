@@ -0,0 +1,20 @@
+package postgres
+
+// Metrics receives operational counters from the Unit of Work
+// Implement it with Prometheus, OpenTelemetry or StatsD to export the values
+type Metrics interface {
+	IncCounter(name string, delta int64, labels map[string]string)
+}
+
+// nopMetrics discards all measurements
+type nopMetrics struct{}
+
+func (nopMetrics) IncCounter(string, int64, map[string]string) {}
+
+// metricsOf returns the configured metrics sink, never nil
+func metricsOf(config *Config) Metrics {
+	if config == nil || config.Metrics == nil {
+		return nopMetrics{}
+	}
+	return config.Metrics
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	namingMu       sync.RWMutex
+	namingStrategy schema.Namer = schema.NamingStrategy{}
+)
+
+// UseNamingStrategy records namer as the value NamingStrategy returns
+//
+// Deprecated: entity metadata, and with it filters, sorts and identifiers, resolves columns with
+// the naming strategy of the connection each unit of work runs on (gorm.Config.NamingStrategy,
+// set by Connect from Config.NamingStrategy), so pools named differently no longer affect each other
+func UseNamingStrategy(namer schema.Namer) {
+	if namer == nil {
+		namer = schema.NamingStrategy{}
+	}
+	namingMu.Lock()
+	defer namingMu.Unlock()
+	namingStrategy = namer
+}
+
+// NamingStrategy returns the strategy set with UseNamingStrategy, GORM's default otherwise
+//
+// Deprecated: read the connection's strategy, db.NamingStrategy
+func NamingStrategy() schema.Namer {
+	namingMu.RLock()
+	defer namingMu.RUnlock()
+	return namingStrategy
+}
+
+// columnName resolves a Go field or column name of the entity to its column
+// Names the entity does not declare are passed through the entity's naming strategy
+func (m *modelMetadata) columnName(name string) string {
+	if field, ok := m.Field(name); ok {
+		return field.Column
+	}
+	return m.namer.ColumnName(m.Table, name)
+}
+
+// resolveFields rewrites identifier conditions on Go field names, e.g. "UserID", to their columns
+// Column names and anything else are kept as written
+func (m *modelMetadata) resolveFields(id identifier.IIdentifier) identifier.IIdentifier {
+	return identifier.MapFields(id, func(field string) string {
+		if i, ok := m.byName[field]; ok {
+			return m.Fields[i].Column
+		}
+		return field
+	})
+}
+
+// quotedColumns accepts the entity's declared columns and Go field names, optionally qualified by
+// its table, and quotes them for db; anything else is rejected rather than pasted into the SQL
+func (m *modelMetadata) quotedColumns(db *gorm.DB) identifier.ColumnFunc {
+	return func(name string) (string, error) {
+		table, column, qualified := strings.Cut(name, ".")
+		if !qualified {
+			column = name
+		}
+		field, ok := m.Field(column)
+		if !ok || qualified && table != m.Table {
+			return "", fmt.Errorf("%w: %q is not a column of %s", uowerrors.ErrInvalidQuery, name, m.Table)
+		}
+		if qualified {
+			return db.Statement.Quote(m.Table + "." + field.Column), nil
+		}
+		return db.Statement.Quote(field.Column), nil
+	}
+}
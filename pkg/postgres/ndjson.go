@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+)
+
+// NDJSON defaults
+const (
+	DefaultJSONImportBatchSize = 500
+	defaultJSONProgressEvery   = 1000
+)
+
+// ExportJSON streams the entities matching a query to w as newline-delimited JSON
+// Returns the number of exported entities
+func (uow *UnitOfWork[T]) ExportJSON(ctx context.Context, query domain.QueryParams[T], options domain.JSONExportOptions, w io.Writer) (int64, error) {
+	every := int64(options.ProgressEvery)
+	if every <= 0 {
+		every = defaultJSONProgressEvery
+	}
+
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+
+	db := uow.exportQuery(ctx, query)
+	if options.IncludeTrashed {
+		db = db.Unscoped()
+	}
+
+	var count int64
+	err := uow.streamEntities(ctx, db, func(entity T) error {
+		if err := encoder.Encode(entity); err != nil {
+			return fmt.Errorf("failed to encode entity: %w", err)
+		}
+		count++
+		if options.Progress != nil && count%every == 0 {
+			options.Progress(count)
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return count, fmt.Errorf("failed to write JSON: %w", err)
+	}
+	if f, ok := w.(flusher); ok {
+		f.Flush()
+	}
+	if options.Progress != nil && count%every != 0 {
+		options.Progress(count)
+	}
+	return count, nil
+}
+
+// ImportJSON reads newline-delimited JSON (or a JSON array) and upserts the entities in batches
+// Existing rows, matched on the conflict columns, are overwritten; returns the number of imported entities.
+// IDs and deleted_at present in the records are kept, so an IncludeTrashed export restores as it was.
+// Batches commit independently unless the unit of work is in a transaction. Under a context
+// deadline it stops before a batch not expected to finish in time with a *DeadlineBudgetError
+// whose Processed is the number of leading records imported, to skip when resuming
+func (uow *UnitOfWork[T]) ImportJSON(ctx context.Context, r io.Reader, options domain.JSONImportOptions) (int64, error) {
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultJSONImportBatchSize
+	}
+
+	reader := bufio.NewReader(r)
+	array, err := isJSONArray(reader)
+	if err != nil {
+		return 0, err
+	}
+	decoder := json.NewDecoder(reader)
+	if array {
+		if _, err := decoder.Token(); err != nil {
+			return 0, fmt.Errorf("failed to decode JSON array: %w", err)
+		}
+	}
+
+	var count int64
+	batch := make([]T, 0, batchSize)
+	budget := uow.deadlineBudget(ctx, "JSON import", 0)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := budget.admit(int(count), len(batch)); err != nil {
+			return err
+		}
+		if _, _, err := uow.BulkUpsert(ctx, batch, options.ConflictColumns...); err != nil {
+			return fmt.Errorf("failed to import records %d-%d: %w", count+1, count+int64(len(batch)), err)
+		}
+		count += int64(len(batch))
+		batch = batch[:0]
+		if options.Progress != nil {
+			options.Progress(count)
+		}
+		return nil
+	}
+
+	for {
+		if array && !decoder.More() {
+			break
+		}
+		entity := newEntity[T]()
+		if err := decoder.Decode(&entity); err == io.EOF {
+			break
+		} else if err != nil {
+			return count, fmt.Errorf("failed to decode record %d: %w", count+int64(len(batch))+1, err)
+		}
+
+		if batch = append(batch, entity); len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return count, err
+	}
+	if options.ResetSequence {
+		if err := uow.resetSequence(ctx); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// resetSequence points the primary key sequence after the highest existing ID
+// Entities without a sequence-backed key are left alone
+func (uow *UnitOfWork[T]) resetSequence(ctx context.Context) error {
+	db := uow.getActiveDB(ctx)
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	meta := uow.metadata()
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return nil
+	}
+	statement := fmt.Sprintf(
+		"SELECT setval(pg_get_serial_sequence(?, ?), COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false) WHERE pg_get_serial_sequence(?, ?) IS NOT NULL",
+		quoteIdentifier(primaryKey.Column), quoteIdentifier(meta.Table),
+	)
+	if err := db.Exec(statement, meta.Table, primaryKey.Column, meta.Table, primaryKey.Column).Error; err != nil {
+		return fmt.Errorf("failed to reset %s sequence: %w", meta.Table, err)
+	}
+	return nil
+}
+
+// isJSONArray reports whether the input is a JSON array rather than NDJSON
+func isJSONArray(reader *bufio.Reader) (bool, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read JSON: %w", err)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.Discard(1)
+		default:
+			return b[0] == '[', nil
+		}
+	}
+}
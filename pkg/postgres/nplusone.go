@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	nPlusOneName = "uow:n_plus_one"
+
+	// DefaultNPlusOneThreshold is how many identical single-row queries make a pattern when
+	// NPlusOneDetection.Threshold is not set
+	DefaultNPlusOneThreshold = 5
+)
+
+// NPlusOneDetection flags the classic N+1 pattern: the same single-row query issued over and over
+// within one unit of work (or one WithQueryScope scope), typically from a loop. Meant for development,
+// as it captures the call site of every single-row query
+type NPlusOneDetection struct {
+	// Threshold is how many identical single-row queries are reported, default 5
+	Threshold int
+	// Report receives each pattern once per scope; default: the standard logger
+	Report func(NPlusOneReport)
+}
+
+// NPlusOneReport describes one repeated single-row query
+type NPlusOneReport struct {
+	Table     string
+	SQL       string   // With placeholders; bound values are not recorded
+	Count     int      // Executions when reported
+	CallSites []string // Distinct file:line locations outside this package and GORM, in order of first use
+}
+
+// String formats the report with a suggested fix for logs
+func (r NPlusOneReport) String() string {
+	message := fmt.Sprintf("postgres: possible N+1: %d identical single-row queries on %s: %s\n"+
+		"  load the rows at once with FindByIDs, FindAllByIdentifier with an IN condition, or Preload/Include on the parent query",
+		r.Count, r.Table, r.SQL)
+	for _, site := range r.CallSites {
+		message += "\n  at " + site
+	}
+	return message
+}
+
+func (d *NPlusOneDetection) threshold() int {
+	if d.Threshold <= 0 {
+		return DefaultNPlusOneThreshold
+	}
+	return d.Threshold
+}
+
+func (d *NPlusOneDetection) report(r NPlusOneReport) {
+	if d.Report != nil {
+		d.Report(r)
+		return
+	}
+	log.Print(r.String())
+}
+
+// queryTrace counts single-row queries within one scope
+type queryTrace struct {
+	mu      sync.Mutex
+	queries map[string]*tracedQuery
+}
+
+type tracedQuery struct {
+	count    int
+	sites    []string
+	reported bool
+}
+
+type queryTraceKey struct{}
+
+// WithQueryScope starts an N+1 detection scope spanning every unit of work using the context,
+// e.g. one HTTP request; without it each unit of work is its own scope
+func WithQueryScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{})
+}
+
+func queryTraceOf(ctx context.Context) *queryTrace {
+	if ctx == nil {
+		return nil
+	}
+	trace, _ := ctx.Value(queryTraceKey{}).(*queryTrace)
+	return trace
+}
+
+// UseNPlusOneDetection watches single-row queries on db for repetition within a scope
+// Connect calls it when Config.NPlusOne is set; use it directly with externally managed pools
+func UseNPlusOneDetection(db *gorm.DB, detection *NPlusOneDetection) error {
+	if detection == nil {
+		return fmt.Errorf("no N+1 detection given")
+	}
+	return db.Use(&nPlusOnePlugin{detection: detection})
+}
+
+// nPlusOnePlugin is the gorm plugin behind UseNPlusOneDetection
+type nPlusOnePlugin struct {
+	detection *NPlusOneDetection
+}
+
+// Name implements gorm.Plugin
+func (p *nPlusOnePlugin) Name() string {
+	return nPlusOneName
+}
+
+// Initialize implements gorm.Plugin
+func (p *nPlusOnePlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Query().After("*").Register(nPlusOneName, p.observe)
+}
+
+// observe counts a query returning at most one row in the statement's scope
+func (p *nPlusOnePlugin) observe(tx *gorm.DB) {
+	trace := queryTraceOf(tx.Statement.Context)
+	if trace == nil || tx.Error != nil || tx.DryRun || tx.RowsAffected > 1 || tx.Statement.SQL.Len() == 0 {
+		return
+	}
+	sql := tx.Statement.SQL.String()
+	site := callSite()
+
+	trace.mu.Lock()
+	if trace.queries == nil {
+		trace.queries = make(map[string]*tracedQuery)
+	}
+	query := trace.queries[sql]
+	if query == nil {
+		query = &tracedQuery{}
+		trace.queries[sql] = query
+	}
+	query.count++
+	if site != "" && !containsString(query.sites, site) {
+		query.sites = append(query.sites, site)
+	}
+	due := !query.reported && query.count >= p.detection.threshold()
+	if due {
+		query.reported = true
+	}
+	report := NPlusOneReport{Table: tx.Statement.Table, SQL: sql, Count: query.count, CallSites: append([]string(nil), query.sites...)}
+	trace.mu.Unlock()
+
+	if due {
+		p.detection.report(report)
+	}
+}
+
+// callSite returns the first caller outside GORM and this package's non-test files
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "gorm.io/") ||
+			(strings.HasPrefix(frame.Function, packagePath+".") && !strings.HasSuffix(frame.File, "_test.go"))
+		if !internal && frame.Function != "" {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// packagePath prefixes the function names of this package in stack frames
+var packagePath = reflect.TypeOf(nPlusOnePlugin{}).PkgPath()
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
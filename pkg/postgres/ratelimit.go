@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+const rateLimiterName = "uow:rate_limiter"
+
+// OperationKind separates reads from writes so they can be limited independently
+type OperationKind string
+
+const (
+	OperationRead  OperationKind = "read"
+	OperationWrite OperationKind = "write"
+)
+
+// Operation describes a statement about to run, as seen by a RateLimiter
+type Operation struct {
+	Kind          OperationKind
+	Table         string // Empty for raw SQL
+	Rows          int    // Rows a write carries; 1 for everything else
+	Tenant        string // From WithTenant
+	User          string // From WithUser
+	InTransaction bool   // Waiting here holds the transaction's connection
+}
+
+// RateLimiter is invoked before every statement a unit of work runs
+// Wait blocks until the operation may proceed or returns an error to fail it
+type RateLimiter interface {
+	Wait(ctx context.Context, op Operation) error
+}
+
+// RateLimiterFunc adapts a function to RateLimiter
+type RateLimiterFunc func(ctx context.Context, op Operation) error
+
+// Wait implements RateLimiter
+func (f RateLimiterFunc) Wait(ctx context.Context, op Operation) error {
+	return f(ctx, op)
+}
+
+type tenantContextKey struct{}
+
+type userContextKey struct{}
+
+// WithTenant marks the context's operations as made on behalf of a tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set with WithTenant
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// WithUser marks the context's operations as made on behalf of a user
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFrom returns the user set with WithUser
+func UserFrom(ctx context.Context) string {
+	user, _ := ctx.Value(userContextKey{}).(string)
+	return user
+}
+
+// UseRateLimiter runs every statement on db past the limiter first
+// Connect calls it when Config.RateLimiter is set; use it directly with externally managed pools
+func UseRateLimiter(db *gorm.DB, limiter RateLimiter) error {
+	if limiter == nil {
+		return fmt.Errorf("no rate limiter given")
+	}
+	return db.Use(&rateLimiterPlugin{limiter: limiter})
+}
+
+// rateLimiterPlugin is the gorm plugin behind UseRateLimiter
+type rateLimiterPlugin struct {
+	limiter RateLimiter
+}
+
+// Name implements gorm.Plugin
+func (p *rateLimiterPlugin) Name() string {
+	return rateLimiterName
+}
+
+// Initialize implements gorm.Plugin
+func (p *rateLimiterPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for name, processor := range map[string]interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		"create": callbacks.Create().Before("*"),
+		"query":  callbacks.Query().Before("*"),
+		"update": callbacks.Update().Before("*"),
+		"delete": callbacks.Delete().Before("*"),
+		"row":    callbacks.Row().Before("*"),
+		"raw":    callbacks.Raw().Before("*"),
+	} {
+		kind := OperationWrite
+		if name == "query" || name == "row" {
+			kind = OperationRead
+		}
+		if err := processor.Register(rateLimiterName+":"+name, p.wait(kind)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait builds the callback for one kind of statement
+func (p *rateLimiterPlugin) wait(kind OperationKind) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.DryRun {
+			return
+		}
+		ctx := db.Statement.Context
+		op := Operation{
+			Kind:          kind,
+			Table:         db.Statement.Table,
+			Rows:          1,
+			Tenant:        TenantFrom(ctx),
+			User:          UserFrom(ctx),
+			InTransaction: inTransaction(db),
+		}
+		if kind == OperationWrite {
+			if value := db.Statement.ReflectValue; value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+				op.Rows = max(value.Len(), 1)
+			}
+		}
+		if err := p.limiter.Wait(ctx, op); err != nil {
+			if !errors.Is(err, uowerrors.ErrRateLimited) {
+				err = fmt.Errorf("%w: %w", uowerrors.ErrRateLimited, err)
+			}
+			db.AddError(err)
+		}
+	}
+}
+
+// Limit is a token bucket: Rate operations (or rows, for writes) per second with bursts up to Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// TokenBucketLimiter keeps one token bucket per tenant (or user, without a tenant) and operation kind,
+// so one noisy tenant's bulk writes only slow that tenant down
+type TokenBucketLimiter struct {
+	// Limits per operation kind; kinds without a limit are not throttled
+	Limits map[OperationKind]Limit
+	// MaxWait fails operations that would wait longer instead of queueing them; default: wait for the context
+	MaxWait time.Duration
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*tokenBucket
+	now     func() time.Time
+}
+
+type bucketKey struct {
+	owner string
+	kind  OperationKind
+}
+
+type tokenBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// NewTokenBucketLimiter creates a limiter with the given limits per operation kind
+func NewTokenBucketLimiter(limits map[OperationKind]Limit) *TokenBucketLimiter {
+	return &TokenBucketLimiter{Limits: limits}
+}
+
+// Wait implements RateLimiter
+// A write costs one token per row, capped at the burst so oversized batches still get through
+func (l *TokenBucketLimiter) Wait(ctx context.Context, op Operation) error {
+	limit, ok := l.Limits[op.Kind]
+	if !ok || limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(max(limit.Burst, 1))
+	cost := min(float64(max(op.Rows, 1)), burst)
+
+	owner := op.Tenant
+	if owner == "" {
+		owner = op.User
+	}
+	key := bucketKey{owner: owner, kind: op.Kind}
+
+	l.mu.Lock()
+	now := l.clock()
+	bucket := l.bucket(key, burst, now)
+	bucket.tokens = min(burst, bucket.tokens+now.Sub(bucket.at).Seconds()*limit.Rate)
+	bucket.at = now
+	delay := time.Duration((cost - bucket.tokens) / limit.Rate * float64(time.Second))
+	if delay > 0 && l.MaxWait > 0 && delay > l.MaxWait {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: %s for %q would wait %s", uowerrors.ErrRateLimited, op.Kind, owner, delay.Round(time.Millisecond))
+	}
+	// Reserve the tokens now so concurrent callers queue behind this one
+	bucket.tokens -= cost
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		bucket.tokens = min(burst, bucket.tokens+cost)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// bucket returns the bucket for key, starting full; the caller holds l.mu
+func (l *TokenBucketLimiter) bucket(key bucketKey, burst float64, now time.Time) *tokenBucket {
+	if l.buckets == nil {
+		l.buckets = make(map[bucketKey]*tokenBucket)
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, at: now}
+		l.buckets[key] = bucket
+	}
+	return bucket
+}
+
+func (l *TokenBucketLimiter) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+// readOnlyUnitOfWork exposes only the query methods, so callers cannot type-assert their way back to mutations
+type readOnlyUnitOfWork[T domain.BaseModel] struct {
+	persistence.IReadOnlyUnitOfWork[T]
+}
+
+// ReadOnly returns a query-only session sharing this unit of work's pool and settings
+// Its transactions are opened READ ONLY and, when replicas are configured, on a replica
+// unless recent writes through the same context pin it to the primary
+func (uow *UnitOfWork[T]) ReadOnly() persistence.IReadOnlyUnitOfWork[T] {
+	session := uow.session(uow.ctx)
+	session.readOnly = true
+	return readOnlyUnitOfWork[T]{session}
+}
+
+// CreateReadOnly creates a query-only unit of work for reporting code
+func (f *UnitOfWorkFactory[T]) CreateReadOnly(ctx context.Context) persistence.IReadOnlyUnitOfWork[T] {
+	uow := f.CreateWithContext(ctx).(*UnitOfWork[T])
+	uow.readOnly = true
+	return readOnlyUnitOfWork[T]{uow}
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reassign moves the child rows of every entity matching from over to the owner toOwnerID, in one
+// transaction: each relation of the config's model registry pointing at this table (associations
+// and DeclareRelations entries) gets its foreign key rewritten. Matching owners may be trashed;
+// the new owner must be live. The owners themselves are left alone, so merging duplicate accounts
+// is a Reassign followed by deleting the duplicates. Inside a transaction it joins it
+func (uow *UnitOfWork[T]) Reassign(ctx context.Context, from identifier.IIdentifier, toOwnerID int) (domain.ReassignResult, error) {
+	result := domain.ReassignResult{To: toOwnerID, Moved: make(map[string]int64)}
+	if from == nil || from.IsEmpty() {
+		return result, fmt.Errorf("%w: reassign needs an identifier for the previous owners", uowerrors.ErrInvalidQueryParams)
+	}
+	meta := uow.metadata()
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return result, fmt.Errorf("%w: %s has no primary key", uowerrors.ErrInvalidEntity, meta.Table)
+	}
+
+	db := uow.getActiveDB(ctx)
+	var owners int64
+	if err := db.Model(newEntity[T]()).Where(primaryKey.Qualified+" = ?", toOwnerID).Count(&owners).Error; err != nil {
+		return result, fmt.Errorf("failed to find new owner: %w", err)
+	}
+	if owners == 0 {
+		return result, fmt.Errorf("%w: %s %d", uowerrors.ErrEntityNotFound, meta.Table, toOwnerID)
+	}
+	err := applyEntityIdentifier[T](db.Unscoped().Model(newEntity[T]()), from).
+		Where(primaryKey.Qualified+" <> ?", toOwnerID).
+		Order(primaryKey.Qualified).
+		Pluck(primaryKey.Qualified, &result.From).Error
+	if err != nil {
+		return result, fmt.Errorf("failed to find previous owners: %w", err)
+	}
+	if len(result.From) == 0 {
+		return result, nil
+	}
+
+	relations, err := uow.config.ModelRegistry().Relations(db)
+	if err != nil {
+		return result, err
+	}
+	previous := make([]interface{}, len(result.From))
+	for i, id := range result.From {
+		previous[i] = id
+	}
+	move := func(tx *gorm.DB) error {
+		for _, relation := range relations {
+			if relation.Parent != meta.Table || relation.ParentKey != primaryKey.Column {
+				continue
+			}
+			moved := tx.Table(relation.Child).
+				Where(clause.IN{Column: clause.Column{Name: relation.ForeignKey}, Values: previous}).
+				UpdateColumn(relation.ForeignKey, toOwnerID)
+			if moved.Error != nil {
+				return fmt.Errorf("failed to reassign %s: %w", relation, moved.Error)
+			}
+			result.Moved[relation.String()] = moved.RowsAffected
+		}
+		return nil
+	}
+
+	// Child tables are written without this entity's default scopes
+	if uow.inTx && uow.tx != nil {
+		err = move(uow.tx.WithContext(ctx))
+	} else {
+		err = uow.db.WithContext(uow.pinned(ctx)).Transaction(move)
+	}
+	if err != nil {
+		result.Moved = make(map[string]int64)
+		return result, err
+	}
+	return result, nil
+}
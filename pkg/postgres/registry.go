@@ -0,0 +1,253 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Seeder inserts reference or fixture rows for one model
+// Seeders run inside one transaction, parents before children
+type Seeder func(ctx context.Context, tx *gorm.DB) error
+
+// ModelRegistry collects an application's models so migrations, index checks and seeding
+// cover the full set in foreign-key order instead of lists passed around ad hoc
+//
+//	postgres.NewUnitOfWorkFactory[*User](config).RegisterModel(&Address{})
+//	postgres.NewUnitOfWorkFactory[*Order](config).RegisterModel()
+//	err := config.ModelRegistry().Migrate(ctx, db)
+type ModelRegistry struct {
+	mu        sync.Mutex
+	models    []registeredModel
+	indexOf   map[reflect.Type]int
+	relations []Relation // Declared with DeclareRelations
+	profiles  map[string]SeedProfile
+}
+
+type registeredModel struct {
+	model   interface{}
+	seeders []profileSeeder
+}
+
+// DefaultModelRegistry collects models for configs without their own registry
+var DefaultModelRegistry = NewModelRegistry()
+
+// NewModelRegistry creates an empty registry
+func NewModelRegistry() *ModelRegistry {
+	return &ModelRegistry{indexOf: make(map[reflect.Type]int)}
+}
+
+// ModelRegistry returns the registry factories built from this config register into
+func (c *Config) ModelRegistry() *ModelRegistry {
+	if c == nil || c.Models == nil {
+		return DefaultModelRegistry
+	}
+	return c.Models
+}
+
+// Register adds models, given as pointers to their structs; registering a type again is a no-op
+func (r *ModelRegistry) Register(models ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, model := range models {
+		r.register(model)
+	}
+}
+
+func (r *ModelRegistry) register(model interface{}) int {
+	t := modelType(model)
+	if i, ok := r.indexOf[t]; ok {
+		return i
+	}
+	r.indexOf[t] = len(r.models)
+	r.models = append(r.models, registeredModel{model: reflect.New(t).Interface()})
+	return len(r.models) - 1
+}
+
+// RegisterSeeder registers the model if needed and adds a seeder for it
+// A model's seeders run in registration order
+func (r *ModelRegistry) RegisterSeeder(model interface{}, seeder Seeder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.register(model)
+	r.models[i].seeders = append(r.models[i].seeders, profileSeeder{
+		run: func(ctx context.Context, tx *gorm.DB, _ SeedProfile) error { return seeder(ctx, tx) },
+	})
+}
+
+// Models returns the registered models, referenced tables before the tables referencing them
+// Models in a foreign-key cycle keep their registration order
+func (r *ModelRegistry) Models() []interface{} {
+	return r.modelsIn(nil)
+}
+
+// modelsIn returns the registered models in foreign-key order, relationships resolved with namer
+func (r *ModelRegistry) modelsIn(namer schema.Namer) []interface{} {
+	ordered := r.ordered(namer)
+	models := make([]interface{}, len(ordered))
+	for i, registered := range ordered {
+		models[i] = registered.model
+	}
+	return models
+}
+
+// Migrate auto-migrates every registered model in foreign-key order
+func (r *ModelRegistry) Migrate(ctx context.Context, db *gorm.DB) error {
+	models := r.modelsIn(namerOf(db))
+	if len(models) == 0 {
+		return nil
+	}
+	if err := db.WithContext(ctx).AutoMigrate(models...); err != nil {
+		return fmt.Errorf("failed to migrate models: %w", err)
+	}
+	return nil
+}
+
+// EnsureIndexes creates the indexes declared in model tags that are missing from the database
+// Returns the names of the created indexes; tables must already exist
+func (r *ModelRegistry) EnsureIndexes(ctx context.Context, db *gorm.DB) ([]string, error) {
+	migrator := db.WithContext(ctx).Migrator()
+	var created []string
+	for _, model := range r.modelsIn(namerOf(db)) {
+		s, err := schema.Parse(model, &sync.Map{}, namerOf(db))
+		if err != nil {
+			return created, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		for _, index := range s.ParseIndexes() {
+			if migrator.HasIndex(model, index.Name) {
+				continue
+			}
+			if err := migrator.CreateIndex(model, index.Name); err != nil {
+				return created, fmt.Errorf("failed to create index %s: %w", index.Name, err)
+			}
+			created = append(created, index.Name)
+		}
+	}
+	return created, nil
+}
+
+// Seed runs the minimal profile: every seeder registered for all profiles or for SeedMinimal
+func (r *ModelRegistry) Seed(ctx context.Context, db *gorm.DB) error {
+	profile, err := r.Profile(SeedMinimal)
+	if err != nil {
+		return err
+	}
+	return r.SeedWithProfile(ctx, db, profile)
+}
+
+// ordered sorts a snapshot of the models topologically by their relationships, resolved with namer
+func (r *ModelRegistry) ordered(namer schema.Namer) []registeredModel {
+	r.mu.Lock()
+	models := append([]registeredModel(nil), r.models...)
+	r.mu.Unlock()
+
+	values := make([]interface{}, len(models))
+	for i, registered := range models {
+		values[i] = registered.model
+	}
+	ordered := make([]registeredModel, 0, len(models))
+	for _, i := range dependencyOrder(values, namer) {
+		ordered = append(ordered, models[i])
+	}
+	return ordered
+}
+
+// dependencyOrder returns the indexes of models with referenced tables before the tables referencing them
+// Models in a foreign-key cycle keep their given order; a nil namer is GORM's default
+func dependencyOrder(models []interface{}, namer schema.Namer) []int {
+	if namer == nil {
+		namer = schema.NamingStrategy{}
+	}
+	indexOf := make(map[reflect.Type]int, len(models))
+	for i, model := range models {
+		indexOf[modelType(model)] = i
+	}
+
+	// dependsOn[i] holds the models whose tables model i references
+	dependsOn := make([]map[int]bool, len(models))
+	for i := range models {
+		dependsOn[i] = make(map[int]bool)
+	}
+	for i, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, namer)
+		if err != nil {
+			continue
+		}
+		for _, relationship := range s.Relationships.Relations {
+			j, ok := indexOf[relationship.FieldSchema.ModelType]
+			if !ok || j == i {
+				continue
+			}
+			switch relationship.Type {
+			case schema.BelongsTo:
+				dependsOn[i][j] = true
+			case schema.HasOne, schema.HasMany:
+				dependsOn[j][i] = true
+			}
+		}
+	}
+
+	order := make([]int, 0, len(models))
+	placed := make([]bool, len(models))
+	for len(order) < len(models) {
+		progress := false
+		for i := range models {
+			if placed[i] || !ready(dependsOn[i], placed) {
+				continue
+			}
+			placed[i] = true
+			order = append(order, i)
+			progress = true
+		}
+		if !progress {
+			// A cycle: place the earliest remaining model and continue
+			for i := range models {
+				if !placed[i] {
+					placed[i] = true
+					order = append(order, i)
+					break
+				}
+			}
+		}
+	}
+	return order
+}
+
+func ready(dependencies map[int]bool, placed []bool) bool {
+	for j := range dependencies {
+		if !placed[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// modelType resolves a model value or pointer to its struct type
+func modelType(model interface{}) reflect.Type {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// RegisterModel adds the factory's entity type and any related models to the config's model registry
+func (f *UnitOfWorkFactory[T]) RegisterModel(models ...interface{}) *UnitOfWorkFactory[T] {
+	registry := f.Config.ModelRegistry()
+	registry.Register(new(T))
+	registry.Register(models...)
+	return f
+}
+
+// Migrate auto-migrates every model in the config's registry over the factory's pool
+func (f *UnitOfWorkFactory[T]) Migrate(ctx context.Context) error {
+	db, err := f.connection()
+	if err != nil {
+		return err
+	}
+	return f.Config.ModelRegistry().Migrate(ctx, db)
+}
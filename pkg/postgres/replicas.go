@@ -0,0 +1,342 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultReadYourWritesWindow is how long reads stay on the primary after a write when no window is configured
+	DefaultReadYourWritesWindow = 5 * time.Second
+
+	// DefaultReplicaLagCheckInterval is how long a replica's measured lag is reused when no interval is configured
+	DefaultReplicaLagCheckInterval = time.Second
+
+	// MetricReplicaLagFallbacks counts reads sent to the primary because every replica lagged
+	MetricReplicaLagFallbacks = "uow_replica_lag_fallbacks_total"
+)
+
+// ReplicaConfig locates a read replica; credentials, database and pool settings come from the primary's Config
+type ReplicaConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port"` // Default: the primary's port
+}
+
+// ReplicaOptions configures read routing for UseReplicas
+type ReplicaOptions struct {
+	// ReadYourWritesWindow pins a context to the primary for this long after it writes; default 5s
+	ReadYourWritesWindow time.Duration
+	// TrackLSN releases the pin early once the chosen replica has replayed the write's WAL position
+	TrackLSN bool
+	// MaxLagBytes skips replicas whose pg_last_wal_replay_lsn trails the primary's WAL position by
+	// more than this; reads fall back to the primary when all of them do. Zero disables the check
+	MaxLagBytes int64
+	// LagCheckInterval is how long a measured lag is reused; default 1s
+	LagCheckInterval time.Duration
+	// Metrics counts lag fallbacks as MetricReplicaLagFallbacks
+	Metrics Metrics
+}
+
+// replicaRouterName registers the router as a GORM plugin
+const replicaRouterName = "uow:replica_router"
+
+// replicaRouter sends reads outside transactions to replicas unless the context is pinned to the primary
+// Only Find/First/Count-style queries are routed; Raw(...).Scan and row queries stay on the primary
+type replicaRouter struct {
+	replicas []*gorm.DB
+	options  ReplicaOptions
+	next     atomic.Uint64
+	primary  gorm.ConnPool
+	lags     []replicaLag
+	// measureLag returns how many WAL bytes replica trails primary; replaced in tests
+	measureLag func(ctx context.Context, primary, replica gorm.ConnPool) (int64, error)
+}
+
+// replicaLag caches the last lag measurement of one replica
+type replicaLag struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	exceeded  bool
+}
+
+// UseReplicas routes reads on db to the replica connections
+// Connect calls it when Config.Replicas is set; use it directly with externally managed pools
+func UseReplicas(db *gorm.DB, replicas []*gorm.DB, options ReplicaOptions) error {
+	if len(replicas) == 0 {
+		return fmt.Errorf("no replicas given")
+	}
+	if options.ReadYourWritesWindow <= 0 {
+		options.ReadYourWritesWindow = DefaultReadYourWritesWindow
+	}
+	if options.LagCheckInterval <= 0 {
+		options.LagCheckInterval = DefaultReplicaLagCheckInterval
+	}
+	if options.Metrics == nil {
+		options.Metrics = nopMetrics{}
+	}
+	return db.Use(&replicaRouter{
+		replicas:   replicas,
+		options:    options,
+		lags:       make([]replicaLag, len(replicas)),
+		measureLag: walReplayLag,
+	})
+}
+
+// Name implements gorm.Plugin
+func (r *replicaRouter) Name() string {
+	return replicaRouterName
+}
+
+// Initialize implements gorm.Plugin
+func (r *replicaRouter) Initialize(db *gorm.DB) error {
+	r.primary = db.Statement.ConnPool
+	if err := db.Callback().Query().Before("gorm:query").Register("uow:route_read", r.route); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().After("gorm:create").Register("uow:pin_primary", r.written); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("uow:pin_primary", r.written); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("uow:pin_primary", r.written); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("gorm:raw").Register("uow:pin_primary", r.written)
+}
+
+// route switches a read to the next replica unless it runs in a transaction, locks rows, or is pinned
+func (r *replicaRouter) route(db *gorm.DB) {
+	if db.Error != nil || inTransaction(db) {
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+
+	replica := r.replica(db.Statement.Context)
+	if replica == nil {
+		return
+	}
+	if pin := pinOf(db.Statement.Context); pin != nil && pin.holds(db.Statement.Context, replica) {
+		return
+	}
+	db.Statement.ConnPool = replica.Statement.ConnPool
+}
+
+// replica picks the next replica round-robin, skipping those lagging more than MaxLagBytes
+// Returns nil, and counts a fallback, when every replica lags
+func (r *replicaRouter) replica(ctx context.Context) *gorm.DB {
+	start := r.next.Add(1) - 1
+	for i := range uint64(len(r.replicas)) {
+		index := (start + i) % uint64(len(r.replicas))
+		if !r.lagging(ctx, int(index)) {
+			return r.replicas[index]
+		}
+	}
+	r.options.Metrics.IncCounter(MetricReplicaLagFallbacks, 1, nil)
+	return nil
+}
+
+// lagging reports whether a replica trails the primary by more than MaxLagBytes, measuring at
+// most once per LagCheckInterval; a failed measurement counts as lagging
+func (r *replicaRouter) lagging(ctx context.Context, index int) bool {
+	if r.options.MaxLagBytes <= 0 {
+		return false
+	}
+	lag := &r.lags[index]
+	lag.mu.Lock()
+	defer lag.mu.Unlock()
+	if time.Since(lag.checkedAt) < r.options.LagCheckInterval {
+		return lag.exceeded
+	}
+
+	bytes, err := r.measureLag(ctx, r.primary, r.replicas[index].Statement.ConnPool)
+	lag.checkedAt = time.Now()
+	lag.exceeded = err != nil || bytes > r.options.MaxLagBytes
+	return lag.exceeded
+}
+
+// walReplayLag compares the primary's WAL insert position with the replica's replay position
+func walReplayLag(ctx context.Context, primary, replica gorm.ConnPool) (int64, error) {
+	var lsn string
+	if err := primary.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return 0, err
+	}
+	var lag int64
+	err := replica.QueryRowContext(ctx, "SELECT COALESCE(pg_wal_lsn_diff($1::pg_lsn, pg_last_wal_replay_lsn()), 0)::bigint", lsn).Scan(&lag)
+	return lag, err
+}
+
+// written pins the statement's context after a successful write
+func (r *replicaRouter) written(db *gorm.DB) {
+	if db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	pin := pinOf(db.Statement.Context)
+	if pin == nil {
+		return
+	}
+	if inTransaction(db) {
+		// The WAL position is only meaningful once the transaction commits
+		pin.extend(r.options.ReadYourWritesWindow, "", true)
+		return
+	}
+	pin.extend(r.options.ReadYourWritesWindow, r.currentLSN(db.Statement.Context, db.Statement.ConnPool), false)
+}
+
+// committed restarts the pin window when a transaction that wrote commits
+func (r *replicaRouter) committed(ctx context.Context, primary *gorm.DB) {
+	pin := pinOf(ctx)
+	if pin == nil || !pin.takePending() {
+		return
+	}
+	pin.extend(r.options.ReadYourWritesWindow, r.currentLSN(ctx, primary.Statement.ConnPool), false)
+}
+
+// currentLSN reads the primary's WAL insert position, or "" when LSN tracking is off or unavailable
+func (r *replicaRouter) currentLSN(ctx context.Context, pool gorm.ConnPool) string {
+	if !r.options.TrackLSN {
+		return ""
+	}
+	var lsn string
+	if err := pool.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return ""
+	}
+	return lsn
+}
+
+// closeReplicas closes the replica pools installed on db
+func closeReplicas(db *gorm.DB) error {
+	router := replicaRouterOf(db)
+	if router == nil {
+		return nil
+	}
+	var firstErr error
+	for _, replica := range router.replicas {
+		sqlDB, err := replica.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// replicaRouterOf returns the router installed on db, if any
+func replicaRouterOf(db *gorm.DB) *replicaRouter {
+	if db == nil || db.Config == nil {
+		return nil
+	}
+	router, _ := db.Config.Plugins[replicaRouterName].(*replicaRouter)
+	return router
+}
+
+// inTransaction reports whether a statement runs on a transaction connection
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// primaryPin tracks the last write made through a context
+type primaryPin struct {
+	mu      sync.Mutex
+	until   time.Time
+	lsn     string
+	pending bool // written inside a transaction that has not committed yet
+}
+
+type primaryPinKey struct{}
+
+// ReadYourWrites returns a context whose reads go to the primary for a window after any write made through it
+// Units of work pin their own context automatically; use this to share one pin across
+// several units of work, e.g. for the duration of an HTTP request
+func ReadYourWrites(ctx context.Context) context.Context {
+	if pinOf(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, primaryPinKey{}, &primaryPin{})
+}
+
+func pinOf(ctx context.Context) *primaryPin {
+	if ctx == nil {
+		return nil
+	}
+	pin, _ := ctx.Value(primaryPinKey{}).(*primaryPin)
+	return pin
+}
+
+func (p *primaryPin) extend(window time.Duration, lsn string, pending bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.until = time.Now().Add(window)
+	p.lsn = lsn
+	p.pending = p.pending || pending
+}
+
+func (p *primaryPin) takePending() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pending := p.pending
+	p.pending = false
+	return pending
+}
+
+// holds reports whether reads must stay on the primary rather than use replica
+func (p *primaryPin) holds(ctx context.Context, replica *gorm.DB) bool {
+	p.mu.Lock()
+	until, lsn := p.until, p.lsn
+	p.mu.Unlock()
+
+	if !time.Now().Before(until) {
+		return false
+	}
+	if lsn == "" {
+		return true
+	}
+
+	var caughtUp bool
+	err := replica.Statement.ConnPool.QueryRowContext(ctx, "SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)", lsn).Scan(&caughtUp)
+	return err != nil || !caughtUp
+}
+
+// connectReplicas opens the configured replicas and installs the router on the primary
+func connectReplicas(config *Config, primary *gorm.DB) error {
+	replicas := make([]*gorm.DB, 0, len(config.Replicas))
+	for _, replicaConfig := range config.Replicas {
+		replica := *config
+		replica.Host = replicaConfig.Host
+		if replicaConfig.Port != 0 {
+			replica.Port = replicaConfig.Port
+		}
+
+		db, err := gorm.Open(postgres.Open(replica.DSN()), config.gormConfig())
+		if err != nil {
+			return fmt.Errorf("failed to connect to replica %s: %w", replicaConfig.Host, err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get SQL DB instance for replica %s: %w", replicaConfig.Host, err)
+		}
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+		replicas = append(replicas, db)
+	}
+
+	return UseReplicas(primary, replicas, ReplicaOptions{
+		ReadYourWritesWindow: config.ReadYourWritesWindow,
+		TrackLSN:             config.TrackReplicaLSN,
+		MaxLagBytes:          config.MaxReplicaLagBytes,
+		Metrics:              metricsOf(config),
+	})
+}
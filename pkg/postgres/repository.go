@@ -4,28 +4,74 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"strings"
 
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
 
 	"gorm.io/gorm"
 )
 
 // BaseRepository provides common CRUD operations for PostgreSQL
 // Optimized for performance with batch operations and prepared statements
-type BaseRepository struct {
-	db *gorm.DB
+//
+// Built over a connection with NewBaseRepository, or over a unit of work with
+// NewBaseRepositoryFromUnitOfWork so its queries join the unit's transaction.
+// Embed it to add custom finders:
+//
+//	type UserRepository struct {
+//		*postgres.BaseRepository[*User]
+//	}
+//
+//	func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+//		return r.UnitOfWork().FindOneByIdentifier(ctx, identifier.New().Equal("email", email))
+//	}
+type BaseRepository[T domain.BaseModel] struct {
+	db  func() *gorm.DB
+	uow persistence.IUnitOfWork[T] // Set when built over another IUnitOfWork implementation; operations delegate to it
 }
 
-// NewBaseRepository creates a new base repository
-func NewBaseRepository(db *gorm.DB) *BaseRepository {
-	return &BaseRepository{db: db}
+// NewBaseRepository creates a base repository over a database connection
+func NewBaseRepository[T domain.BaseModel](db *gorm.DB) *BaseRepository[T] {
+	return &BaseRepository[T]{db: func() *gorm.DB { return db }}
+}
+
+// NewBaseRepositoryFromUnitOfWork creates a base repository that runs on the unit of work's active connection
+// Operations issued while the unit of work has an open transaction are part of it
+func NewBaseRepositoryFromUnitOfWork[T domain.BaseModel](uow *UnitOfWork[T]) *BaseRepository[T] {
+	return &BaseRepository[T]{db: func() *gorm.DB { return uow.getActiveDB(uow.ctx) }, uow: uow}
+}
+
+// NewBaseRepositoryFor creates a base repository over any IUnitOfWork implementation, such as a test fake
+// Units of work from this package get the same repository as NewBaseRepositoryFromUnitOfWork;
+// other implementations serve every operation through their interface methods
+func NewBaseRepositoryFor[T domain.BaseModel](uow persistence.IUnitOfWork[T]) *BaseRepository[T] {
+	if concrete, ok := uow.(*UnitOfWork[T]); ok {
+		return NewBaseRepositoryFromUnitOfWork(concrete)
+	}
+	return &BaseRepository[T]{uow: uow}
+}
+
+// UnitOfWork returns the unit of work the repository was built over, or nil when built over a connection
+func (r *BaseRepository[T]) UnitOfWork() persistence.IUnitOfWork[T] {
+	return r.uow
+}
+
+// delegated reports whether operations go through the unit of work interface instead of GORM
+func (r *BaseRepository[T]) delegated() bool {
+	return r.db == nil
 }
 
 // Create inserts a new entity into the database
 // Uses GORM's optimized insert with returning clause
-func (r *BaseRepository) Create(ctx context.Context, entity interface{}) error {
-	result := r.db.WithContext(ctx).Create(entity)
+func (r *BaseRepository[T]) Create(ctx context.Context, entity T) error {
+	if r.delegated() {
+		if _, err := r.uow.Insert(ctx, entity); err != nil {
+			return fmt.Errorf("failed to create entity: %w", err)
+		}
+		return nil
+	}
+	result := r.db().WithContext(ctx).Create(entity)
 	if result.Error != nil {
 		return fmt.Errorf("failed to create entity: %w", result.Error)
 	}
@@ -34,28 +80,54 @@ func (r *BaseRepository) Create(ctx context.Context, entity interface{}) error {
 
 // GetByID retrieves an entity by its ID
 // Uses prepared statements for optimal performance
-func (r *BaseRepository) GetByID(ctx context.Context, id int64, entity interface{}) error {
-	result := r.db.WithContext(ctx).First(entity, id)
+func (r *BaseRepository[T]) GetByID(ctx context.Context, id int64) (T, error) {
+	if r.delegated() {
+		entity, err := r.uow.FindOneById(ctx, int(id))
+		if err != nil {
+			var zero T
+			return zero, fmt.Errorf("failed to get entity by ID: %w", err)
+		}
+		return entity, nil
+	}
+	entity := newEntity[T]()
+	result := r.db().WithContext(ctx).First(entity, id)
 	if result.Error != nil {
-		return fmt.Errorf("failed to get entity by ID: %w", result.Error)
+		var zero T
+		return zero, fmt.Errorf("failed to get entity by ID: %w", result.Error)
 	}
-	return nil
+	return entity, nil
 }
 
 // GetBySlug retrieves an entity by its slug
 // Uses index scan for optimal performance
-func (r *BaseRepository) GetBySlug(ctx context.Context, slug string, entity interface{}) error {
-	result := r.db.WithContext(ctx).Where("slug = ?", slug).First(entity)
+func (r *BaseRepository[T]) GetBySlug(ctx context.Context, slug string) (T, error) {
+	if r.delegated() {
+		entity, err := r.uow.FindOneByIdentifier(ctx, identifier.New().Equal("slug", slug))
+		if err != nil {
+			var zero T
+			return zero, fmt.Errorf("failed to get entity by slug: %w", err)
+		}
+		return entity, nil
+	}
+	entity := newEntity[T]()
+	result := r.db().WithContext(ctx).Where("slug = ?", slug).First(entity)
 	if result.Error != nil {
-		return fmt.Errorf("failed to get entity by slug: %w", result.Error)
+		var zero T
+		return zero, fmt.Errorf("failed to get entity by slug: %w", result.Error)
 	}
-	return nil
+	return entity, nil
 }
 
 // Update modifies an existing entity
 // Uses optimistic locking with updated_at field
-func (r *BaseRepository) Update(ctx context.Context, entity interface{}) error {
-	result := r.db.WithContext(ctx).Save(entity)
+func (r *BaseRepository[T]) Update(ctx context.Context, entity T) error {
+	if r.delegated() {
+		if _, err := r.uow.Update(ctx, identifier.New().Equal("id", entity.GetID()), entity); err != nil {
+			return fmt.Errorf("failed to update entity: %w", err)
+		}
+		return nil
+	}
+	result := r.db().WithContext(ctx).Save(entity)
 	if result.Error != nil {
 		return fmt.Errorf("failed to update entity: %w", result.Error)
 	}
@@ -63,8 +135,11 @@ func (r *BaseRepository) Update(ctx context.Context, entity interface{}) error {
 }
 
 // Delete removes an entity by ID (soft delete if supported)
-func (r *BaseRepository) Delete(ctx context.Context, id int64, entity interface{}) error {
-	result := r.db.WithContext(ctx).Delete(entity, id)
+func (r *BaseRepository[T]) Delete(ctx context.Context, id int64) error {
+	if r.delegated() {
+		return r.DeleteBatch(ctx, []int64{id})
+	}
+	result := r.db().WithContext(ctx).Delete(newEntity[T](), id)
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete entity: %w", result.Error)
 	}
@@ -73,31 +148,37 @@ func (r *BaseRepository) Delete(ctx context.Context, id int64, entity interface{
 
 // List retrieves entities with filtering, sorting, and pagination
 // Optimized query building with minimal allocations
-func (r *BaseRepository) List(ctx context.Context, entities interface{}, params interface{}) error {
-	query := r.db.WithContext(ctx)
-
-	// Apply query parameters if provided
-	if params != nil {
-		query = r.applyQueryParams(query, params)
+func (r *BaseRepository[T]) List(ctx context.Context, params domain.QueryParams[T]) ([]T, error) {
+	if r.delegated() {
+		entities, _, err := r.uow.FindAllWithPagination(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list entities: %w", err)
+		}
+		return entities, nil
 	}
+	query := r.applyQueryParams(r.db().WithContext(ctx), params)
 
-	result := query.Find(entities)
+	var entities []T
+	result := query.Find(&entities)
 	if result.Error != nil {
-		return fmt.Errorf("failed to list entities: %w", result.Error)
+		return nil, fmt.Errorf("failed to list entities: %w", result.Error)
 	}
 
-	return nil
+	return entities, nil
 }
 
-// Count returns the total number of entities matching the criteria
-// Uses optimized COUNT query without loading data
-func (r *BaseRepository) Count(ctx context.Context, entity interface{}, params interface{}) (int64, error) {
-	query := r.db.WithContext(ctx).Model(entity)
-
-	// Apply query parameters if provided
-	if params != nil {
-		query = r.applyQueryParams(query, params)
+// Count returns the total number of entities matching the filter
+// Uses optimized COUNT query without loading data; sorting and pagination are ignored
+func (r *BaseRepository[T]) Count(ctx context.Context, params domain.QueryParams[T]) (int64, error) {
+	if r.delegated() {
+		_, total, err := r.uow.FindAllWithPagination(ctx, domain.QueryParams[T]{Filter: params.Filter, Limit: 1})
+		if err != nil {
+			return 0, fmt.Errorf("failed to count entities: %w", err)
+		}
+		return int64(total), nil
 	}
+	query := r.db().WithContext(ctx).Model(newEntity[T]())
+	query = r.applyFilters(query, params.FilterValue())
 
 	var count int64
 	result := query.Count(&count)
@@ -108,10 +189,40 @@ func (r *BaseRepository) Count(ctx context.Context, entity interface{}, params i
 	return count, nil
 }
 
+// Exists reports whether any entity matches the filter; sorting and pagination are ignored
+// Stops at the first match instead of counting every row
+func (r *BaseRepository[T]) Exists(ctx context.Context, params domain.QueryParams[T]) (bool, error) {
+	if r.delegated() {
+		entities, _, err := r.uow.FindAllWithPagination(ctx, domain.QueryParams[T]{Filter: params.Filter, Limit: 1})
+		if err != nil {
+			return false, fmt.Errorf("failed to check entity existence: %w", err)
+		}
+		return len(entities) > 0, nil
+	}
+
+	query := r.db().WithContext(ctx).Model(newEntity[T]())
+	query = r.applyFilters(query, params.FilterValue())
+
+	var found []int
+	if err := query.Select("1").Limit(1).Scan(&found).Error; err != nil {
+		return false, fmt.Errorf("failed to check entity existence: %w", err)
+	}
+	return len(found) > 0, nil
+}
+
 // CreateBatch performs bulk insert for multiple entities
 // Uses batch insert for optimal performance - O(1) database round trip
-func (r *BaseRepository) CreateBatch(ctx context.Context, entities interface{}) error {
-	result := r.db.WithContext(ctx).CreateInBatches(entities, 100) // Optimal batch size
+func (r *BaseRepository[T]) CreateBatch(ctx context.Context, entities []T) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	if r.delegated() {
+		if _, err := r.uow.BulkInsert(ctx, entities); err != nil {
+			return fmt.Errorf("failed to create batch: %w", err)
+		}
+		return nil
+	}
+	result := r.db().WithContext(ctx).CreateInBatches(entities, 100) // Optimal batch size
 	if result.Error != nil {
 		return fmt.Errorf("failed to create batch: %w", result.Error)
 	}
@@ -120,20 +231,10 @@ func (r *BaseRepository) CreateBatch(ctx context.Context, entities interface{})
 
 // UpdateBatch performs bulk update for multiple entities
 // Uses prepared statements for optimal performance
-func (r *BaseRepository) UpdateBatch(ctx context.Context, entities interface{}) error {
+func (r *BaseRepository[T]) UpdateBatch(ctx context.Context, entities []T) error {
 	// GORM doesn't have direct bulk update, so we iterate
 	// This could be optimized with raw SQL for large datasets
-	v := reflect.ValueOf(entities)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-
-	if v.Kind() != reflect.Slice {
-		return fmt.Errorf("entities must be a slice")
-	}
-
-	for i := 0; i < v.Len(); i++ {
-		entity := v.Index(i).Interface()
+	for i, entity := range entities {
 		if err := r.Update(ctx, entity); err != nil {
 			return fmt.Errorf("failed to update entity at index %d: %w", i, err)
 		}
@@ -144,107 +245,68 @@ func (r *BaseRepository) UpdateBatch(ctx context.Context, entities interface{})
 
 // DeleteBatch performs bulk delete for multiple IDs
 // Uses IN clause for optimal performance
-func (r *BaseRepository) DeleteBatch(ctx context.Context, ids []int64, entity interface{}) error {
-	result := r.db.WithContext(ctx).Delete(entity, ids)
+func (r *BaseRepository[T]) DeleteBatch(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if r.delegated() {
+		values := make([]interface{}, len(ids))
+		for i, id := range ids {
+			values[i] = id
+		}
+		if err := r.uow.BulkSoftDelete(ctx, []identifier.IIdentifier{identifier.New().In("id", values)}); err != nil {
+			return fmt.Errorf("failed to delete batch: %w", err)
+		}
+		return nil
+	}
+	result := r.db().WithContext(ctx).Delete(newEntity[T](), ids)
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete batch: %w", result.Error)
 	}
 	return nil
 }
 
-// applyQueryParams applies filtering, sorting, and pagination
-// Optimized query building with type safety
-func (r *BaseRepository) applyQueryParams(query *gorm.DB, params interface{}) *gorm.DB {
-	v := reflect.ValueOf(params)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-
-	if v.Kind() != reflect.Struct {
-		return query
+// applyQueryParams applies filtering, sorting, preloading and pagination without reflection
+func (r *BaseRepository[T]) applyQueryParams(query *gorm.DB, options domain.QueryOptions) *gorm.DB {
+	if filter := options.FilterValue(); filter != nil {
+		query = r.applyFilters(query, filter)
 	}
 
-	// Apply filters
-	if filterField := v.FieldByName("Filter"); filterField.IsValid() && !filterField.IsZero() {
-		query = r.applyFilters(query, filterField.Interface())
+	if sortMap := options.SortFields(); len(sortMap) > 0 {
+		query = r.applySorting(query, sortMap)
 	}
 
-	// Apply sorting
-	if sortField := v.FieldByName("Sort"); sortField.IsValid() && !sortField.IsZero() {
-		if sortMap, ok := sortField.Interface().(domain.SortMap); ok {
-			query = r.applySorting(query, sortMap)
-		}
+	for _, include := range options.IncludeRelations() {
+		query = query.Preload(include)
 	}
 
-	// Apply includes (preloading)
-	if includeField := v.FieldByName("Include"); includeField.IsValid() && !includeField.IsZero() {
-		if includes, ok := includeField.Interface().([]string); ok {
-			for _, include := range includes {
-				query = query.Preload(include)
-			}
-		}
+	limit, offset := options.Pagination()
+	if limit > 0 {
+		query = query.Limit(limit)
 	}
-
-	// Apply pagination
-	if limitField := v.FieldByName("Limit"); limitField.IsValid() && !limitField.IsZero() {
-		if limit, ok := limitField.Interface().(int); ok && limit > 0 {
-			query = query.Limit(limit)
-		}
-	}
-
-	if offsetField := v.FieldByName("Offset"); offsetField.IsValid() && !offsetField.IsZero() {
-		if offset, ok := offsetField.Interface().(int); ok && offset > 0 {
-			query = query.Offset(offset)
-		}
+	if offset > 0 {
+		query = query.Offset(offset)
 	}
 
 	return query
 }
 
 // applyFilters applies filter conditions to the query
-// Uses reflection to build WHERE clauses dynamically
-func (r *BaseRepository) applyFilters(query *gorm.DB, filter interface{}) *gorm.DB {
-	v := reflect.ValueOf(filter)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-
-	if v.Kind() != reflect.Struct {
+// Column names and field offsets come from the per-type metadata cache
+func (r *BaseRepository[T]) applyFilters(query *gorm.DB, filter interface{}) *gorm.DB {
+	conditions, args := metadataFor(reflect.TypeOf(filter), namerOf(query)).filterConditions(filter, true)
+	if conditions == "" {
 		return query
 	}
-
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		value := v.Field(i)
-
-		if !field.IsExported() || value.IsZero() {
-			continue
-		}
-
-		// Get database column name from json tag or field name
-		columnName := field.Name
-		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
-			if tagName := strings.Split(jsonTag, ",")[0]; tagName != "-" {
-				columnName = tagName
-			}
-		}
-
-		// Convert to snake_case for database columns
-		columnName = toSnakeCase(columnName)
-
-		query = query.Where(fmt.Sprintf("%s = ?", columnName), value.Interface())
-	}
-
-	return query
+	return query.Where(conditions, args...)
 }
 
 // applySorting applies sort conditions to the query
 // Validates sort fields to prevent SQL injection
-func (r *BaseRepository) applySorting(query *gorm.DB, sortMap domain.SortMap) *gorm.DB {
+func (r *BaseRepository[T]) applySorting(query *gorm.DB, sortMap domain.SortMap) *gorm.DB {
 	for field, direction := range sortMap {
-		// Convert to snake_case and validate direction
-		columnName := toSnakeCase(field)
+		// Resolve the column through the naming strategy and validate direction
+		columnName := metadataIn[T](query).columnName(field)
 		if direction != "asc" && direction != "desc" {
 			direction = "asc" // Default to ascending
 		}
@@ -254,23 +316,3 @@ func (r *BaseRepository) applySorting(query *gorm.DB, sortMap domain.SortMap) *g
 
 	return query
 }
-
-// toSnakeCase converts CamelCase to snake_case
-// Optimized string conversion with minimal allocations
-func toSnakeCase(str string) string {
-	var result strings.Builder
-	result.Grow(len(str) + 5) // Pre-allocate with estimated size
-
-	for i, r := range str {
-		if i > 0 && r >= 'A' && r <= 'Z' {
-			result.WriteByte('_')
-		}
-		if r >= 'A' && r <= 'Z' {
-			result.WriteRune(r - 'A' + 'a')
-		} else {
-			result.WriteRune(r)
-		}
-	}
-
-	return result.String()
-}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+const (
+	// MetricTransactionRetries counts transactions run again after a serialization failure or deadlock
+	MetricTransactionRetries = "uow_transaction_retries_total"
+
+	// Defaults of a RetryPolicy's zero fields
+	DefaultRetryAttempts       = 3
+	DefaultRetryInitialBackoff = 10 * time.Millisecond
+	DefaultRetryMaxBackoff     = time.Second
+)
+
+// RetryPolicy runs a transaction again when PostgreSQL aborts it with a serialization failure
+// (40001) or a deadlock (40P01), as SERIALIZABLE and REPEATABLE READ transactions must expect.
+// The wait doubles after each attempt, from InitialBackoff up to MaxBackoff, and is spread by Jitter
+type RetryPolicy struct {
+	MaxAttempts    int           // Attempts including the first; default: DefaultRetryAttempts
+	InitialBackoff time.Duration // Wait before the second attempt; default: DefaultRetryInitialBackoff
+	MaxBackoff     time.Duration // Longest wait; default: DefaultRetryMaxBackoff
+	Jitter         float64       // Fraction of each wait drawn at random, 0 to 1, so clients that collided spread out
+	Metrics        Metrics       // Receives MetricTransactionRetries
+}
+
+// Do calls fn until it succeeds, fails with an error errors.IsRetryable rejects, or runs out of
+// attempts; fn must be safe to repeat. Waits end early when ctx is done
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultRetryAttempts
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !uowerrors.IsRetryable(err) {
+			return err
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (retry interrupted: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+		if p.Metrics != nil {
+			p.Metrics.IncCounter(MetricTransactionRetries, 1, nil)
+		}
+	}
+}
+
+// backoff returns the wait after the given failed attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait, limit := p.InitialBackoff, p.MaxBackoff
+	if wait <= 0 {
+		wait = DefaultRetryInitialBackoff
+	}
+	if limit <= 0 {
+		limit = DefaultRetryMaxBackoff
+	}
+	for i := 1; i < attempt && wait < limit; i++ {
+		wait *= 2
+	}
+	wait = min(wait, limit)
+
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		spread := time.Duration(float64(wait) * jitter)
+		wait = wait - spread + time.Duration(rand.Int64N(int64(spread)+1))
+	}
+	return wait
+}
+
+// TransactionWithRetry runs fn through Transaction under the policy, starting a new transaction
+// for each attempt. Inside an open transaction fn runs once: a failed transaction can only be
+// retried as a whole, by its outermost caller
+func (uow *UnitOfWork[T]) TransactionWithRetry(ctx context.Context, policy RetryPolicy, fn func(txUow persistence.IUnitOfWork[T]) error) error {
+	if uow.inTx {
+		return uow.nestedTransaction(ctx, fn)
+	}
+	if policy.Metrics == nil {
+		policy.Metrics = metricsOf(uow.config)
+	}
+	return policy.Do(ctx, func() error {
+		return uow.transaction(ctx, fn)
+	})
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// savepoint remembers how much deferred work existed when it was taken
+type savepoint struct {
+	name    string
+	changes int // len(pendingChanges)
+	hooks   int // len(afterCommit)
+	undo    int // len(afterRollback)
+}
+
+// Savepoint marks a point inside the current transaction that RollbackTo can return to
+// Taking a savepoint with an existing name shadows the earlier one until it is released
+func (uow *UnitOfWork[T]) Savepoint(ctx context.Context, name string) error {
+	if !uow.inTx || uow.tx == nil {
+		return fmt.Errorf("failed to create savepoint %q: %w", name, uowerrors.ErrTransactionNotStarted)
+	}
+
+	if err := uow.tx.WithContext(ctx).Exec("SAVEPOINT " + quoteIdentifier(name)).Error; err != nil {
+		return fmt.Errorf("failed to create savepoint %q: %w", name, err)
+	}
+
+	uow.savepoints = append(uow.savepoints, savepoint{name: name, changes: len(uow.pendingChanges), hooks: len(uow.afterCommit), undo: len(uow.afterRollback)})
+	return nil
+}
+
+// RollbackTo undoes everything done since the savepoint while keeping earlier work and the transaction open
+// Change notifications and AfterCommit hooks registered since the savepoint are discarded as well,
+// and AfterRollback hooks registered since run.
+// The savepoint stays usable; savepoints taken after it are released.
+func (uow *UnitOfWork[T]) RollbackTo(ctx context.Context, name string) error {
+	i, err := uow.findSavepoint("roll back to", name)
+	if err != nil {
+		return err
+	}
+
+	if err := uow.tx.WithContext(ctx).Exec("ROLLBACK TO SAVEPOINT " + quoteIdentifier(name)).Error; err != nil {
+		return fmt.Errorf("failed to roll back to savepoint %q: %w", name, err)
+	}
+
+	mark := uow.savepoints[i]
+	uow.pendingChanges = uow.pendingChanges[:mark.changes]
+	uow.afterCommit = uow.afterCommit[:mark.hooks]
+	undo := uow.afterRollback[mark.undo:]
+	uow.afterRollback = uow.afterRollback[:mark.undo]
+	for i := len(undo) - 1; i >= 0; i-- {
+		undo[i](ctx)
+	}
+	uow.savepoints = uow.savepoints[:i+1]
+	return nil
+}
+
+// ReleaseSavepoint forgets a savepoint and the ones taken after it, keeping their work
+func (uow *UnitOfWork[T]) ReleaseSavepoint(ctx context.Context, name string) error {
+	i, err := uow.findSavepoint("release", name)
+	if err != nil {
+		return err
+	}
+
+	if err := uow.tx.WithContext(ctx).Exec("RELEASE SAVEPOINT " + quoteIdentifier(name)).Error; err != nil {
+		return fmt.Errorf("failed to release savepoint %q: %w", name, err)
+	}
+
+	uow.savepoints = uow.savepoints[:i]
+	return nil
+}
+
+// findSavepoint returns the index of the most recent savepoint with the name
+func (uow *UnitOfWork[T]) findSavepoint(op, name string) (int, error) {
+	if !uow.inTx || uow.tx == nil {
+		return 0, fmt.Errorf("failed to %s savepoint %q: %w", op, name, uowerrors.ErrTransactionNotStarted)
+	}
+	for i := len(uow.savepoints) - 1; i >= 0; i-- {
+		if uow.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to %s savepoint %q: %w", op, name, uowerrors.ErrSavepointNotFound)
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+
+	"gorm.io/gorm"
+)
+
+// Scope narrows every query a unit of work issues for an entity type
+// It receives the caller's context, so tenant or user filters can be read from it
+type Scope func(ctx context.Context, db *gorm.DB) *gorm.DB
+
+// WhereScope returns a scope adding a fixed condition, e.g. WhereScope("status <> ?", "draft")
+func WhereScope(query string, args ...interface{}) Scope {
+	return func(_ context.Context, db *gorm.DB) *gorm.DB {
+		return db.Where(query, args...)
+	}
+}
+
+// IdentifierScope returns a scope adding an identifier's conditions
+func IdentifierScope(id identifier.IIdentifier) Scope {
+	return func(_ context.Context, db *gorm.DB) *gorm.DB {
+		return applyIdentifier(db, id)
+	}
+}
+
+// namedScope is a default scope registered on a factory
+type namedScope struct {
+	name  string
+	scope Scope
+}
+
+// entitySettings holds per-entity behaviour shared by a factory and the units of work it creates
+type entitySettings[T domain.BaseModel] struct {
+	mu           sync.RWMutex
+	scopes       []namedScope
+	listeners    []ChangeListener[T]
+	slugRetries  int
+	stateMachine *StateMachine[T]
+	idGenerators map[string]IDGenerator // Column -> generator, see GenerateIDs
+}
+
+func newEntitySettings[T domain.BaseModel]() *entitySettings[T] {
+	return &entitySettings[T]{}
+}
+
+// addScope registers or replaces a named default scope
+func (s *entitySettings[T]) addScope(name string, scope Scope) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.scopes {
+		if s.scopes[i].name == name {
+			s.scopes[i].scope = scope
+			return
+		}
+	}
+	s.scopes = append(s.scopes, namedScope{name: name, scope: scope})
+}
+
+// removeScope unregisters a named default scope
+func (s *entitySettings[T]) removeScope(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.scopes {
+		if s.scopes[i].name == name {
+			s.scopes = append(s.scopes[:i:i], s.scopes[i+1:]...)
+			return
+		}
+	}
+}
+
+// applyScopes adds the default scopes not disabled by the context
+func (s *entitySettings[T]) applyScopes(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if s == nil {
+		return db
+	}
+	s.mu.RLock()
+	scopes := s.scopes
+	s.mu.RUnlock()
+
+	if len(scopes) == 0 {
+		return db
+	}
+	skip := skippedScopes(ctx)
+	for _, scope := range scopes {
+		if skip.all || skip.names[scope.name] {
+			continue
+		}
+		db = scope.scope(ctx, db)
+	}
+	// Scopes return a chained DB whose Statement the next call would extend in place; a new
+	// session lets methods running several statements start each from the scoped conditions
+	return db.Session(&gorm.Session{})
+}
+
+// hasScopes reports whether default scopes apply to queries made with the context
+func (s *entitySettings[T]) hasScopes(ctx context.Context) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	scopes := s.scopes
+	s.mu.RUnlock()
+
+	skip := skippedScopes(ctx)
+	for _, scope := range scopes {
+		if !skip.all && !skip.names[scope.name] {
+			return true
+		}
+	}
+	return false
+}
+
+// AddDefaultScope registers a scope applied to every query of units of work created by the factory
+// Registering a name again replaces its scope; units of work already created see the change
+func (f *UnitOfWorkFactory[T]) AddDefaultScope(name string, scope Scope) *UnitOfWorkFactory[T] {
+	f.settings.addScope(name, scope)
+	return f
+}
+
+// RemoveDefaultScope unregisters a default scope
+func (f *UnitOfWorkFactory[T]) RemoveDefaultScope(name string) {
+	f.settings.removeScope(name)
+}
+
+// scopeSkipKey is the context key disabling default scopes
+type scopeSkipKey struct{}
+
+type scopeSkip struct {
+	all   bool
+	names map[string]bool
+}
+
+// WithoutDefaultScopes returns a context whose queries skip the named default scopes, or all of them when no names are given
+// The opt-out is per call, so it also works inside an open transaction
+func WithoutDefaultScopes(ctx context.Context, names ...string) context.Context {
+	skip := scopeSkip{all: len(names) == 0, names: make(map[string]bool)}
+	previous := skippedScopes(ctx)
+	skip.all = skip.all || previous.all
+	for name := range previous.names {
+		skip.names[name] = true
+	}
+	for _, name := range names {
+		skip.names[name] = true
+	}
+	return context.WithValue(ctx, scopeSkipKey{}, skip)
+}
+
+func skippedScopes(ctx context.Context) scopeSkip {
+	if ctx == nil {
+		return scopeSkip{}
+	}
+	skip, _ := ctx.Value(scopeSkipKey{}).(scopeSkip)
+	return skip
+}
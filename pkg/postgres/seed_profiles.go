@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Built-in seed profiles
+const (
+	SeedMinimal  = "minimal"   // Reference data only
+	SeedDemo     = "demo"      // A browsable data set for local development and demos
+	SeedLoadTest = "load-test" // Volume for benchmarks and capacity tests
+)
+
+// SeedProfile selects the seeders to run and sizes the data they generate
+type SeedProfile struct {
+	Name   string
+	Size   int            // Base row count profile seeders scale by
+	Params map[string]int // Named sizes, e.g. {"posts_per_user": 20}
+}
+
+// Param returns the named size, or fallback when the profile does not set it
+func (p SeedProfile) Param(name string, fallback int) int {
+	if value, ok := p.Params[name]; ok {
+		return value
+	}
+	return fallback
+}
+
+// With returns a copy of the profile with a named size overridden
+func (p SeedProfile) With(name string, value int) SeedProfile {
+	params := make(map[string]int, len(p.Params)+1)
+	for k, v := range p.Params {
+		params[k] = v
+	}
+	params[name] = value
+	p.Params = params
+	return p
+}
+
+// defaultSeedProfiles are available in every registry unless redefined
+var defaultSeedProfiles = map[string]SeedProfile{
+	SeedMinimal:  {Name: SeedMinimal, Size: 1},
+	SeedDemo:     {Name: SeedDemo, Size: 50},
+	SeedLoadTest: {Name: SeedLoadTest, Size: 100000},
+}
+
+// ProfileSeeder inserts rows for one model sized by the profile
+// Seeders run inside one transaction, parents before children; insert large sets with CreateInBatches
+type ProfileSeeder func(ctx context.Context, tx *gorm.DB, profile SeedProfile) error
+
+type profileSeeder struct {
+	run      ProfileSeeder
+	profiles []string // Empty for every profile
+}
+
+func (s profileSeeder) runsIn(profile string) bool {
+	return len(s.profiles) == 0 || containsString(s.profiles, profile)
+}
+
+// RegisterProfileSeeder registers the model if needed and adds a seeder run by the named profiles,
+// or by every profile when none are given
+//
+//	registry.RegisterProfileSeeder(&User{}, seedUsers, postgres.SeedDemo, postgres.SeedLoadTest)
+func (r *ModelRegistry) RegisterProfileSeeder(model interface{}, seeder ProfileSeeder, profiles ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.register(model)
+	r.models[i].seeders = append(r.models[i].seeders, profileSeeder{run: seeder, profiles: profiles})
+}
+
+// DefineSeedProfile adds a profile or replaces a built-in one's sizes
+func (r *ModelRegistry) DefineSeedProfile(profile SeedProfile) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.profiles == nil {
+		r.profiles = make(map[string]SeedProfile)
+	}
+	r.profiles[profile.Name] = profile
+}
+
+// Profile looks up a profile defined on the registry or built in
+func (r *ModelRegistry) Profile(name string) (SeedProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if profile, ok := r.profiles[name]; ok {
+		return profile, nil
+	}
+	if profile, ok := defaultSeedProfiles[name]; ok {
+		return profile, nil
+	}
+	return SeedProfile{}, fmt.Errorf("unknown seed profile %q (one of %v)", name, r.profileNames())
+}
+
+// profileNames lists the known profiles; the caller holds r.mu
+func (r *ModelRegistry) profileNames() []string {
+	names := make([]string, 0, len(defaultSeedProfiles)+len(r.profiles))
+	for name := range defaultSeedProfiles {
+		names = append(names, name)
+	}
+	for name := range r.profiles {
+		if _, ok := defaultSeedProfiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SeedWithProfile runs the seeders registered for the profile in foreign-key order inside one transaction
+func (r *ModelRegistry) SeedWithProfile(ctx context.Context, db *gorm.DB, profile SeedProfile) error {
+	ordered := r.ordered(namerOf(db))
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, registered := range ordered {
+			for _, seeder := range registered.seeders {
+				if !seeder.runsIn(profile.Name) {
+					continue
+				}
+				if err := seeder.run(ctx, tx, profile); err != nil {
+					return fmt.Errorf("failed to seed %T for profile %s: %w", registered.model, profile.Name, err)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// SetSessionVar sets a configuration parameter for the rest of the current transaction (SET LOCAL)
+// Triggers and row-level security policies read it with current_setting('app.request_id', true).
+// Custom keys need a prefix ("app.request_id"); values are rendered as text, times in RFC 3339
+func (uow *UnitOfWork[T]) SetSessionVar(ctx context.Context, key string, value interface{}) error {
+	if !settingName.MatchString(key) {
+		return fmt.Errorf("%w: invalid session variable %q", uowerrors.ErrInvalidQueryParams, key)
+	}
+	if !uow.inTx || uow.tx == nil {
+		return fmt.Errorf("failed to set session variable %s: %w", key, uowerrors.ErrTransactionNotStarted)
+	}
+
+	if err := uow.tx.WithContext(ctx).Exec("SELECT set_config(?, ?, true)", key, sessionVarText(value)).Error; err != nil {
+		return fmt.Errorf("failed to set session variable %s: %w", key, err)
+	}
+	return nil
+}
+
+// SessionVar returns the current value of a configuration parameter
+// ok is false when the variable is unset or empty
+func (uow *UnitOfWork[T]) SessionVar(ctx context.Context, key string) (value string, ok bool, err error) {
+	if !settingName.MatchString(key) {
+		return "", false, fmt.Errorf("%w: invalid session variable %q", uowerrors.ErrInvalidQueryParams, key)
+	}
+
+	var current sql.NullString
+	if err := uow.getActiveDB(ctx).Raw("SELECT current_setting(?, true)", key).Scan(&current).Error; err != nil {
+		return "", false, fmt.Errorf("failed to read session variable %s: %w", key, err)
+	}
+	return current.String, current.String != "", nil
+}
+
+// SessionVarInt returns a configuration parameter parsed as an integer
+func (uow *UnitOfWork[T]) SessionVarInt(ctx context.Context, key string) (int64, bool, error) {
+	value, ok, err := uow.SessionVar(ctx, key)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("session variable %s is not an integer: %w", key, err)
+	}
+	return parsed, true, nil
+}
+
+// SessionVarBool returns a configuration parameter parsed as a boolean
+// Accepts PostgreSQL's spellings: true/false, on/off, yes/no, 1/0
+func (uow *UnitOfWork[T]) SessionVarBool(ctx context.Context, key string) (bool, bool, error) {
+	value, ok, err := uow.SessionVar(ctx, key)
+	if err != nil || !ok {
+		return false, false, err
+	}
+	parsed, valid := parseSettingBool(value)
+	if !valid {
+		return false, false, fmt.Errorf("session variable %s is not a boolean: %q", key, value)
+	}
+	return parsed, true, nil
+}
+
+// sessionVarText renders a value the way set_config receives it
+func sessionVarText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseSettingBool parses boolean settings as PostgreSQL does
+func parseSettingBool(value string) (bool, bool) {
+	switch value {
+	case "true", "on", "yes", "1", "t", "y":
+		return true, true
+	case "false", "off", "no", "0", "f", "n":
+		return false, true
+	}
+	return false, false
+}
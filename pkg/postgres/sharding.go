@@ -0,0 +1,382 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ShardRouter maps shard key values to databases by hash
+// A key lives on shard fnv32a(fmt.Sprint(key)) mod the number of shards, so changing the number of
+// shards moves keys; plan resharding as a data migration. Primary keys are only unique per shard
+// unless IDs are generated client-side
+type ShardRouter struct {
+	configs []*Config
+
+	mu  sync.Mutex
+	dbs []*gorm.DB
+}
+
+// NewShardRouter creates a router over one database config per shard; pools are opened lazily
+func NewShardRouter(configs ...*Config) *ShardRouter {
+	return &ShardRouter{configs: configs, dbs: make([]*gorm.DB, len(configs))}
+}
+
+// NewShardRouterFromDB creates a router over externally managed pools, one per shard
+// Close leaves them open
+func NewShardRouterFromDB(dbs ...*gorm.DB) *ShardRouter {
+	return &ShardRouter{configs: make([]*Config, len(dbs)), dbs: dbs}
+}
+
+// Shards returns the number of shards
+func (r *ShardRouter) Shards() int {
+	return len(r.dbs)
+}
+
+// ShardFor returns the shard holding a shard key value
+func (r *ShardRouter) ShardFor(key interface{}) int {
+	if valuer, ok := key.(driver.Valuer); ok && !isNilPointer(key) {
+		if value, err := valuer.Value(); err == nil {
+			key = value
+		}
+	}
+	hash := fnv.New32a()
+	fmt.Fprint(hash, reflect.Indirect(reflect.ValueOf(key)))
+	return int(hash.Sum32() % uint32(len(r.dbs)))
+}
+
+// connection returns a shard's pool, opening it on first use; a failed attempt is retried on the next call
+func (r *ShardRouter) connection(shard int) (*gorm.DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dbs[shard] != nil {
+		return r.dbs[shard], nil
+	}
+	db, err := Connect(r.configs[shard])
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to shard %d: %w", shard, err)
+	}
+	r.dbs[shard] = db
+	return db, nil
+}
+
+// namer returns the naming strategy of the shards, which share one schema
+func (r *ShardRouter) namer() schema.Namer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.dbs) > 0 && r.dbs[0] != nil {
+		return namerOf(r.dbs[0])
+	}
+	if len(r.configs) > 0 && r.configs[0] != nil {
+		return r.configs[0].NamingStrategy
+	}
+	return nil
+}
+
+// Close closes the pools the router opened
+func (r *ShardRouter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for i, db := range r.dbs {
+		if db == nil || r.configs[i] == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+		r.dbs[i] = nil
+	}
+	return errors.Join(errs...)
+}
+
+type shardKeyContextKey struct{}
+
+// WithShardKey attaches the shard key value a ShardedFactory routes units of work created with ctx by
+func WithShardKey(ctx context.Context, key interface{}) context.Context {
+	return context.WithValue(ctx, shardKeyContextKey{}, key)
+}
+
+// ShardKeyFrom returns the shard key value attached to ctx
+func ShardKeyFrom(ctx context.Context) (interface{}, bool) {
+	key := ctx.Value(shardKeyContextKey{})
+	return key, key != nil
+}
+
+// ShardedFactory creates units of work on the shard owning a model's shard key, and fans queries
+// out to every shard when no key is known
+//
+//	router := postgres.NewShardRouter(shard0, shard1, shard2)
+//	orders, err := postgres.NewShardedFactory[*Order](router, "customer_id")
+//	uow := orders.CreateWithContext(postgres.WithShardKey(ctx, customerID))
+//	recent, total, err := orders.FindAllWithPagination(ctx, query) // every shard, merged
+type ShardedFactory[T domain.BaseModel] struct {
+	router   *ShardRouter
+	key      fieldMetadata
+	settings *entitySettings[T]
+}
+
+// NewShardedFactory creates a factory routing T by the given shard key column or field
+func NewShardedFactory[T domain.BaseModel](router *ShardRouter, shardKey string) (*ShardedFactory[T], error) {
+	if router.Shards() == 0 {
+		return nil, fmt.Errorf("no shards given")
+	}
+	field, ok := metadataWith[T](router.namer()).Field(shardKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown shard key %q", uowerrors.ErrInvalidQueryParams, shardKey)
+	}
+	return &ShardedFactory[T]{router: router, key: field, settings: newEntitySettings[T]()}, nil
+}
+
+// ShardKey returns the column T is sharded by
+func (f *ShardedFactory[T]) ShardKey() string {
+	return f.key.Column
+}
+
+// ShardOf returns the shard an entity lives on, from its shard key field
+func (f *ShardedFactory[T]) ShardOf(entity T) int {
+	v, _ := metadataOf[T]().structValue(entity)
+	return f.router.ShardFor(v.FieldByIndex(f.key.Index).Interface())
+}
+
+// Create is not routable without a shard key and panics; use CreateWithContext with WithShardKey
+func (f *ShardedFactory[T]) Create() persistence.IUnitOfWork[T] {
+	return f.CreateWithContext(context.Background())
+}
+
+// CreateWithContext creates a unit of work on the shard of the context's shard key
+// Like UnitOfWorkFactory it panics when the shard cannot be connected, and also when ctx has no shard key
+func (f *ShardedFactory[T]) CreateWithContext(ctx context.Context) persistence.IUnitOfWork[T] {
+	key, ok := ShardKeyFrom(ctx)
+	if !ok {
+		panic(fmt.Errorf("%w: no shard key in context for %s; use WithShardKey or the fan-out queries", uowerrors.ErrInvalidQueryParams, entityName[T]()))
+	}
+	uow, err := f.shard(ctx, f.router.ShardFor(key))
+	if err != nil {
+		panic(err)
+	}
+	return uow
+}
+
+// CreateReadOnly creates a read-only unit of work on the shard of the context's shard key
+func (f *ShardedFactory[T]) CreateReadOnly(ctx context.Context) persistence.IReadOnlyUnitOfWork[T] {
+	uow := f.CreateWithContext(ctx).(*UnitOfWork[T])
+	uow.readOnly = true
+	return uow
+}
+
+// ForEntity creates a unit of work on the shard owning the entity, for inserting or updating it
+func (f *ShardedFactory[T]) ForEntity(ctx context.Context, entity T) persistence.IUnitOfWork[T] {
+	v, _ := metadataOf[T]().structValue(entity)
+	return f.CreateWithContext(WithShardKey(ctx, v.FieldByIndex(f.key.Index).Interface()))
+}
+
+// shard creates a unit of work on one shard
+func (f *ShardedFactory[T]) shard(ctx context.Context, shard int) (*UnitOfWork[T], error) {
+	db, err := f.router.connection(shard)
+	if err != nil {
+		return nil, err
+	}
+	uow := newUnitOfWork[T](f.router.configs[shard], db)
+	uow.ctx = ctx
+	uow.settings = f.settings
+	return uow, nil
+}
+
+// FanOut runs fn on every shard concurrently, each with a unit of work of its own
+// Errors are joined, tagged with their shard
+func (f *ShardedFactory[T]) FanOut(ctx context.Context, fn func(ctx context.Context, shard int, uow persistence.IUnitOfWork[T]) error) error {
+	errs := make([]error, f.router.Shards())
+	var wg sync.WaitGroup
+	for shard := range errs {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+			uow, err := f.shard(ctx, shard)
+			if err != nil {
+				errs[shard] = err
+				return
+			}
+			defer uow.Close()
+			if err := fn(ctx, shard, uow); err != nil {
+				errs[shard] = fmt.Errorf("shard %d: %w", shard, err)
+			}
+		}(shard)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// FindAllWithPagination queries every shard and merges the pages by query.Sort, then the primary key
+// Each shard returns up to Offset+Limit rows, so deep offsets cost every shard; the total sums the shards
+func (f *ShardedFactory[T]) FindAllWithPagination(ctx context.Context, query domain.QueryParams[T]) ([]T, uint, error) {
+	return f.mergePages(ctx, query, func(ctx context.Context, uow persistence.IUnitOfWork[T], query domain.QueryParams[T]) ([]T, uint, error) {
+		return uow.FindAllWithPagination(ctx, query)
+	})
+}
+
+// FindAllByIdentifier runs the identifier on every shard and merges the results like FindAllWithPagination
+func (f *ShardedFactory[T]) FindAllByIdentifier(ctx context.Context, id identifier.IIdentifier, query domain.QueryParams[T]) ([]T, uint, error) {
+	return f.mergePages(ctx, query, func(ctx context.Context, uow persistence.IUnitOfWork[T], query domain.QueryParams[T]) ([]T, uint, error) {
+		return uow.FindAllByIdentifier(ctx, id, query)
+	})
+}
+
+// mergePages fetches the first Offset+Limit rows of every shard, sorts them together and cuts the page
+func (f *ShardedFactory[T]) mergePages(ctx context.Context, query domain.QueryParams[T],
+	find func(ctx context.Context, uow persistence.IUnitOfWork[T], query domain.QueryParams[T]) ([]T, uint, error)) ([]T, uint, error) {
+	shardQuery := query
+	shardQuery.Offset = 0
+	if query.Limit > 0 {
+		shardQuery.Limit = query.Offset + query.Limit
+	}
+
+	pages := make([][]T, f.router.Shards())
+	totals := make([]uint, f.router.Shards())
+	err := f.FanOut(ctx, func(ctx context.Context, shard int, uow persistence.IUnitOfWork[T]) error {
+		var err error
+		pages[shard], totals[shard], err = find(ctx, uow, shardQuery)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var merged []T
+	var total uint
+	for shard := range pages {
+		merged = append(merged, pages[shard]...)
+		total += totals[shard]
+	}
+	sortEntities(metadataWith[T](f.router.namer()), merged, query.Sort)
+
+	if query.Offset >= len(merged) {
+		return []T{}, total, nil
+	}
+	merged = merged[query.Offset:]
+	if query.Limit > 0 && len(merged) > query.Limit {
+		merged = merged[:query.Limit]
+	}
+	return merged, total, nil
+}
+
+// sortEntities orders merged shard results by the sort columns, in name order, then the primary key
+func sortEntities[T domain.BaseModel](meta *modelMetadata, entities []T, sorting domain.SortMap) {
+	type key struct {
+		field fieldMetadata
+		desc  bool
+	}
+	names := make([]string, 0, len(sorting))
+	for name := range sorting {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var keys []key
+	for _, name := range names {
+		if field, ok := meta.Field(name); ok {
+			keys = append(keys, key{field: field, desc: strings.EqualFold(string(sorting[name]), string(domain.SortDesc))})
+		}
+	}
+	if primaryKey, ok := meta.primaryKey(); ok {
+		keys = append(keys, key{field: primaryKey})
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		a, _ := meta.structValue(entities[i])
+		b, _ := meta.structValue(entities[j])
+		for _, k := range keys {
+			c := compareValues(a.FieldByIndex(k.field.Index).Interface(), b.FieldByIndex(k.field.Index).Interface())
+			if c == 0 {
+				continue
+			}
+			if k.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// compareValues orders two column values; NULLs sort first
+func compareValues(a, b interface{}) int {
+	for _, v := range []*interface{}{&a, &b} {
+		if valuer, ok := (*v).(driver.Valuer); ok && !isNilPointer(*v) {
+			if value, err := valuer.Value(); err == nil {
+				*v = value
+			}
+		}
+		if rv := reflect.ValueOf(*v); rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				*v = nil
+			} else {
+				*v = rv.Elem().Interface()
+			}
+		}
+	}
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case va.CanInt() && vb.CanInt():
+		return compareOrdered(va.Int(), vb.Int())
+	case va.CanUint() && vb.CanUint():
+		return compareOrdered(va.Uint(), vb.Uint())
+	case va.CanFloat() && vb.CanFloat():
+		return compareOrdered(va.Float(), vb.Float())
+	case va.Kind() == reflect.Bool && vb.Kind() == reflect.Bool:
+		return compareOrdered(boolRank(va.Bool()), boolRank(vb.Bool()))
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered[V int64 | uint64 | float64 | int](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
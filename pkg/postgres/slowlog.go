@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	slowQueryLogName  = "uow:slow_query_log"
+	slowQueryStartKey = slowQueryLogName + ":start"
+
+	// DefaultSlowQueryCapacity is how many entries a slow query log keeps when no capacity is given
+	DefaultSlowQueryCapacity = 100
+)
+
+// SlowQuery is one statement that ran longer than the log's threshold
+// SQL keeps its placeholders; bound values are not recorded, so the log holds no PII
+type SlowQuery struct {
+	SQL      string        `json:"sql"`
+	Table    string        `json:"table,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Rows     int64         `json:"rows"`
+	Error    string        `json:"error,omitempty"`
+	At       time.Time     `json:"at"`
+	Tenant   string        `json:"tenant,omitempty"` // From WithTenant
+}
+
+// SlowQueryLog keeps the most recent statements slower than a threshold in a ring buffer
+//
+//	config.SlowQueries = postgres.NewSlowQueryLog(200*time.Millisecond, 0)
+//	db, err := postgres.Connect(config)
+//	recent := config.SlowQueries.Entries()
+type SlowQueryLog struct {
+	threshold time.Duration
+	mu        sync.Mutex
+	entries   []SlowQuery
+	next      int
+	full      bool
+}
+
+// NewSlowQueryLog creates a log of statements slower than threshold, keeping the last capacity
+// entries (default 100)
+func NewSlowQueryLog(threshold time.Duration, capacity int) *SlowQueryLog {
+	if capacity <= 0 {
+		capacity = DefaultSlowQueryCapacity
+	}
+	return &SlowQueryLog{threshold: threshold, entries: make([]SlowQuery, capacity)}
+}
+
+// Threshold returns the duration above which statements are logged
+func (l *SlowQueryLog) Threshold() time.Duration {
+	return l.threshold
+}
+
+// Entries returns the logged statements, newest first
+func (l *SlowQueryLog) Entries() []SlowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.next
+	if l.full {
+		n = len(l.entries)
+	}
+	entries := make([]SlowQuery, 0, n)
+	for i := 1; i <= n; i++ {
+		entries = append(entries, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return entries
+}
+
+// Reset drops every entry
+func (l *SlowQueryLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.next, l.full = 0, false
+}
+
+func (l *SlowQueryLog) add(entry SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// UseSlowQueryLog times every statement on db and records the slow ones in the log
+// Connect calls it when Config.SlowQueries is set; use it directly with externally managed pools
+func UseSlowQueryLog(db *gorm.DB, log *SlowQueryLog) error {
+	if log == nil {
+		return fmt.Errorf("no slow query log given")
+	}
+	return db.Use(&slowQueryPlugin{log: log})
+}
+
+// slowQueryPlugin is the gorm plugin behind UseSlowQueryLog
+type slowQueryPlugin struct {
+	log *SlowQueryLog
+}
+
+// Name implements gorm.Plugin
+func (p *slowQueryPlugin) Name() string {
+	return slowQueryLogName
+}
+
+// Initialize implements gorm.Plugin
+func (p *slowQueryPlugin) Initialize(db *gorm.DB) error {
+	start := func(tx *gorm.DB) {
+		tx.InstanceSet(slowQueryStartKey, time.Now())
+	}
+	type registrar interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}
+	callbacks := db.Callback()
+	for name, processor := range map[string][2]registrar{
+		"create": {callbacks.Create().Before("*"), callbacks.Create().After("*")},
+		"query":  {callbacks.Query().Before("*"), callbacks.Query().After("*")},
+		"update": {callbacks.Update().Before("*"), callbacks.Update().After("*")},
+		"delete": {callbacks.Delete().Before("*"), callbacks.Delete().After("*")},
+		"row":    {callbacks.Row().Before("*"), callbacks.Row().After("*")},
+		"raw":    {callbacks.Raw().Before("*"), callbacks.Raw().After("*")},
+	} {
+		if err := processor[0].Register(slowQueryLogName+":start:"+name, start); err != nil {
+			return err
+		}
+		if err := processor[1].Register(slowQueryLogName+":"+name, p.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record logs the statement when it ran past the threshold
+func (p *slowQueryPlugin) record(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(slowQueryStartKey)
+	if !ok || tx.DryRun || tx.Statement.SQL.Len() == 0 {
+		return
+	}
+	elapsed := time.Since(value.(time.Time))
+	if elapsed < p.log.threshold {
+		return
+	}
+	entry := SlowQuery{
+		SQL:      tx.Statement.SQL.String(),
+		Table:    tx.Statement.Table,
+		Duration: elapsed,
+		Rows:     tx.RowsAffected,
+		At:       time.Now(),
+		Tenant:   TenantFrom(tx.Statement.Context),
+	}
+	if tx.Error != nil {
+		entry.Error = tx.Error.Error()
+	}
+	p.log.add(entry)
+}
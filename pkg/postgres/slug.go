@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// uniqueViolation is the PostgreSQL SQLSTATE for unique constraint violations
+const uniqueViolation = "23505"
+
+// insertSavepoint isolates an insert attempt so a conflict does not abort the surrounding transaction
+const insertSavepoint = "uow_insert_attempt"
+
+// RetrySlugConflicts makes Insert retry with "<slug>-2", "<slug>-3", ... when the slug is already taken,
+// up to attempts extra tries; 0 disables retrying. Conflicts left after the last attempt fail with errors.ErrEntityExists
+func (f *UnitOfWorkFactory[T]) RetrySlugConflicts(attempts int) *UnitOfWorkFactory[T] {
+	f.settings.mu.Lock()
+	defer f.settings.mu.Unlock()
+	f.settings.slugRetries = attempts
+	return f
+}
+
+func (s *entitySettings[T]) slugRetryLimit() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slugRetries
+}
+
+// insertWithSlugRetry inserts the entity, renaming its slug after each slug conflict
+func (uow *UnitOfWork[T]) insertWithSlugRetry(ctx context.Context, entity T, retries int) (T, error) {
+	base := entity.GetSlug()
+	for attempt := 0; ; attempt++ {
+		err := uow.tryInsert(ctx, entity)
+		if err == nil {
+			uow.recordChanges(ctx, domain.ChangeCreated, entity)
+			return entity, nil
+		}
+		if !isSlugConflict(err) {
+			return entity, fmt.Errorf("failed to insert entity: %w", err)
+		}
+		if attempt == retries {
+			return entity, fmt.Errorf("%w: slug %q is still taken after %d retries", uowerrors.ErrEntityExists, entity.GetSlug(), retries)
+		}
+		entity.SetSlug(fmt.Sprintf("%s-%d", base, attempt+2))
+	}
+}
+
+// tryInsert runs one insert; inside a transaction it is wrapped in a savepoint so a failure can be retried
+func (uow *UnitOfWork[T]) tryInsert(ctx context.Context, entity T) error {
+	db := uow.getActiveDB(ctx)
+	if !uow.inTx {
+		return db.Create(&entity).Error
+	}
+
+	if err := db.Exec("SAVEPOINT " + quoteIdentifier(insertSavepoint)).Error; err != nil {
+		return err
+	}
+	if err := db.Create(&entity).Error; err != nil {
+		if rollbackErr := db.Exec("ROLLBACK TO SAVEPOINT " + quoteIdentifier(insertSavepoint)).Error; rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return db.Exec("RELEASE SAVEPOINT " + quoteIdentifier(insertSavepoint)).Error
+}
+
+// isSlugConflict reports whether an insert failed because the slug is taken
+func isSlugConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == uniqueViolation &&
+			(strings.Contains(pgErr.ConstraintName, "slug") || strings.Contains(pgErr.Detail, "(slug)"))
+	}
+	// SQLite, used by tests and embedded setups
+	message := err.Error()
+	return strings.Contains(message, "UNIQUE constraint failed") && strings.Contains(message, ".slug")
+}
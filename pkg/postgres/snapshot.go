@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// SnapshotFetchSize is how many rows ExportSnapshot fetches from its cursor per round trip
+const SnapshotFetchSize = 1000
+
+// ExportSnapshot calls fn for every entity matching a query as of one snapshot, so a long export sees
+// neither rows written nor rows changed after it started. It runs in a read-only REPEATABLE READ
+// transaction of its own and reads through a server-side cursor, SnapshotFetchSize rows per round
+// trip; SQLite streams its transaction's snapshot instead. query.Limit of 0 exports every matching
+// row; an error from fn stops the export. Returns the number of exported entities
+func (uow *UnitOfWork[T]) ExportSnapshot(ctx context.Context, query domain.QueryParams[T], fn func(entity T) error) (int64, error) {
+	if uow.inTx {
+		return 0, fmt.Errorf("%w: a snapshot export needs a transaction of its own", uowerrors.ErrTransactionAlreadyOpen)
+	}
+
+	cursor := uow.db.Dialector.Name() == "postgres"
+	options := &sql.TxOptions{}
+	if cursor {
+		options = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+	tx := uow.db.WithContext(uow.pinned(ctx)).Begin(options)
+	if tx.Error != nil {
+		return 0, fmt.Errorf("failed to begin snapshot transaction: %w", tx.Error)
+	}
+	// The snapshot transaction serves the unit of work's reads while the export runs; nothing is written
+	uow.tx, uow.inTx = tx, true
+	defer func() {
+		tx.Rollback()
+		uow.tx, uow.inTx = nil, false
+	}()
+
+	var count int64
+	visit := func(entity T) error {
+		count++
+		return fn(entity)
+	}
+	db := uow.exportQuery(ctx, query)
+	if !cursor {
+		return count, uow.streamEntities(ctx, db, visit)
+	}
+	return count, uow.streamCursor(ctx, db, visit)
+}
+
+// streamCursor declares a cursor for the query in the open transaction and fetches it in batches
+func (uow *UnitOfWork[T]) streamCursor(ctx context.Context, db *gorm.DB, fn func(T) error) error {
+	var entities []T
+	statement := db.Session(&gorm.Session{DryRun: true}).Find(&entities).Statement
+	if statement.Error != nil {
+		return fmt.Errorf("failed to build snapshot query: %w", statement.Error)
+	}
+
+	// Raw connection calls: the built SQL already carries the dialect's bind variables
+	conn := uow.tx.Statement.ConnPool
+	if _, err := conn.ExecContext(ctx, "DECLARE uow_snapshot NO SCROLL CURSOR FOR "+statement.SQL.String(), statement.Vars...); err != nil {
+		return fmt.Errorf("failed to open snapshot cursor: %w", err)
+	}
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM uow_snapshot", SnapshotFetchSize)
+	for {
+		rows, err := conn.QueryContext(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch snapshot rows: %w", err)
+		}
+		fetched := 0
+		for rows.Next() {
+			entity := newEntity[T]()
+			if err := db.ScanRows(rows, &entity); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan snapshot row: %w", err)
+			}
+			uow.maskResults(ctx, entity)
+			fetched++
+			if err := fn(entity); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to fetch snapshot rows: %w", err)
+		}
+		if fetched < SnapshotFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+// AnyState matches every state in OnTransition
+const AnyState = "*"
+
+// TransitionHook runs after an Update moved an entity from one state to another, in the same transaction
+// Returning an error rolls the update back
+type TransitionHook[T domain.BaseModel] func(ctx context.Context, uow persistence.IUnitOfWork[T], entity T, from, to string) error
+
+type transitionHook[T domain.BaseModel] struct {
+	from, to string
+	hook     TransitionHook[T]
+}
+
+// StateMachine declares the allowed transitions of an entity's state column
+//
+//	orders := postgres.NewStateMachine[*Order]("status").
+//		Allow("pending", "paid", "cancelled").
+//		Allow("paid", "shipped", "refunded").
+//		OnTransition("paid", "shipped", notifyCustomer)
+//	factory.UseStateMachine(orders)
+//
+// Updates that change the state to one not allowed from a matched row's current state fail with
+// an *errors.TransitionError. Updates leaving the state field at its zero value do not change it and are not checked.
+type StateMachine[T domain.BaseModel] struct {
+	field   fieldMetadata
+	allowed map[string]map[string]bool
+	hooks   []transitionHook[T]
+}
+
+// NewStateMachine creates a state machine over a column or Go field name; it panics when the entity has no such field
+func NewStateMachine[T domain.BaseModel](field string) *StateMachine[T] {
+	meta, ok := metadataOf[T]().Field(field)
+	if !ok {
+		panic(fmt.Sprintf("postgres: state machine field %q not found", field))
+	}
+	return &StateMachine[T]{field: meta, allowed: make(map[string]map[string]bool)}
+}
+
+// Allow permits moving from one state to each of the given states
+func (m *StateMachine[T]) Allow(from string, to ...string) *StateMachine[T] {
+	if m.allowed[from] == nil {
+		m.allowed[from] = make(map[string]bool)
+	}
+	for _, state := range to {
+		m.allowed[from][state] = true
+	}
+	return m
+}
+
+// OnTransition registers a hook for a transition; AnyState matches every state on either side
+func (m *StateMachine[T]) OnTransition(from, to string, hook TransitionHook[T]) *StateMachine[T] {
+	m.hooks = append(m.hooks, transitionHook[T]{from: from, to: to, hook: hook})
+	return m
+}
+
+// CanTransition reports whether an entity may move between two states; staying in a state is always allowed
+func (m *StateMachine[T]) CanTransition(from, to string) bool {
+	return from == to || m.allowed[from][to]
+}
+
+// state reads the entity's state and whether it is set
+func (m *StateMachine[T]) state(entity T) (string, bool) {
+	value, ok := metadataOf[T]().structValue(entity)
+	if !ok {
+		return "", false
+	}
+	field := value.FieldByIndex(m.field.Index)
+	if field.IsZero() {
+		return "", false
+	}
+	if field.Kind() == reflect.String {
+		return field.String(), true
+	}
+	return fmt.Sprint(field.Interface()), true
+}
+
+// runHooks calls the hooks matching a transition in registration order
+func (m *StateMachine[T]) runHooks(ctx context.Context, uow persistence.IUnitOfWork[T], entity T, from, to string) error {
+	for _, registered := range m.hooks {
+		if (registered.from != AnyState && registered.from != from) || (registered.to != AnyState && registered.to != to) {
+			continue
+		}
+		if err := registered.hook(ctx, uow, entity, from, to); err != nil {
+			return fmt.Errorf("transition hook %s -> %s failed: %w", from, to, err)
+		}
+	}
+	return nil
+}
+
+// UseStateMachine makes Update on units of work from the factory enforce the machine's transitions
+func (f *UnitOfWorkFactory[T]) UseStateMachine(machine *StateMachine[T]) *UnitOfWorkFactory[T] {
+	f.settings.mu.Lock()
+	defer f.settings.mu.Unlock()
+	f.settings.stateMachine = machine
+	return f
+}
+
+func (s *entitySettings[T]) stateMachineOf() *StateMachine[T] {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stateMachine
+}
+
+// updateWithTransition checks every matched row's transition, updates, then runs the hooks, all in one transaction
+// Rows are locked FOR UPDATE on PostgreSQL so a concurrent update cannot change the state in between
+func (uow *UnitOfWork[T]) updateWithTransition(ctx context.Context, identifier identifier.IIdentifier, entity T, machine *StateMachine[T], to string) (updated T, err error) {
+	if !uow.inTx {
+		if err := uow.BeginTransaction(ctx); err != nil {
+			return entity, err
+		}
+		defer func() {
+			if err != nil {
+				uow.RollbackTransaction(ctx)
+				return
+			}
+			if err = uow.CommitTransaction(ctx); err != nil {
+				updated = entity
+			}
+		}()
+	}
+
+	db := applyEntityIdentifier[T](uow.getActiveDB(ctx), identifier)
+	if db.Dialector.Name() == "postgres" {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var current []T
+	if err := db.Find(&current).Error; err != nil {
+		return entity, fmt.Errorf("failed to load entities for transition: %w", err)
+	}
+
+	from := make(map[int]string, len(current))
+	var moved []int
+	for _, row := range current {
+		state, _ := machine.state(row)
+		if !machine.CanTransition(state, to) {
+			return entity, &uowerrors.TransitionError{
+				Entity:   uow.metadata().Table,
+				EntityID: row.GetID(),
+				Field:    uow.metadata().columnName(machine.field.Name),
+				From:     state,
+				To:       to,
+			}
+		}
+		if state != to {
+			from[row.GetID()] = state
+			moved = append(moved, row.GetID())
+		}
+	}
+
+	updated, err = uow.update(ctx, identifier, entity)
+	if err != nil || len(moved) == 0 || len(machine.hooks) == 0 {
+		return updated, err
+	}
+
+	var rows []T
+	if err := uow.getActiveDB(ctx).Find(&rows, moved).Error; err != nil {
+		return entity, fmt.Errorf("failed to reload transitioned entities: %w", err)
+	}
+	for _, row := range rows {
+		if err := machine.runHooks(ctx, uow, row, from[row.GetID()], to); err != nil {
+			return entity, err
+		}
+	}
+	return updated, nil
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// Metric names reported for the prepared statement cache
+const (
+	MetricStatementExecutions  = "uow_statement_executions_total"
+	MetricStatementCacheMisses = "uow_statement_cache_misses_total"
+)
+
+const statementCachePluginName = "uow:statement_cache"
+
+// StatementCacheStats describes prepared statement reuse for a connection pool
+type StatementCacheStats struct {
+	Executions uint64 // Statements executed through the cache
+	Prepares   uint64 // Statements that had to be prepared (cache misses)
+}
+
+// Hits returns the number of executions served by an already prepared statement
+func (s StatementCacheStats) Hits() uint64 {
+	if s.Prepares > s.Executions {
+		return 0
+	}
+	return s.Executions - s.Prepares
+}
+
+// HitRate returns the fraction of executions served from the cache (0..1)
+func (s StatementCacheStats) HitRate() float64 {
+	if s.Executions == 0 {
+		return 0
+	}
+	return float64(s.Hits()) / float64(s.Executions)
+}
+
+// statementCache is a GORM plugin counting prepared statement reuse
+// Misses are counted where statements are prepared, executions after every callback chain
+type statementCache struct {
+	executions atomic.Uint64
+	prepares   atomic.Uint64
+	metrics    Metrics
+}
+
+// Name implements gorm.Plugin
+func (c *statementCache) Name() string {
+	return statementCachePluginName
+}
+
+// Initialize implements gorm.Plugin
+func (c *statementCache) Initialize(db *gorm.DB) error {
+	prepared, ok := db.ConnPool.(*gorm.PreparedStmtDB)
+	if !ok {
+		return nil
+	}
+	prepared.ConnPool = &countingConnPool{ConnPool: prepared.ConnPool, cache: c}
+
+	count := func(tx *gorm.DB) {
+		if tx.Statement.SQL.Len() == 0 || tx.DryRun {
+			return
+		}
+		c.executions.Add(1)
+		c.metrics.IncCounter(MetricStatementExecutions, 1, nil)
+	}
+
+	callbacks := db.Callback()
+	for name, processor := range map[string]interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		"create": callbacks.Create().After("*"),
+		"query":  callbacks.Query().After("*"),
+		"update": callbacks.Update().After("*"),
+		"delete": callbacks.Delete().After("*"),
+		"row":    callbacks.Row().After("*"),
+		"raw":    callbacks.Raw().After("*"),
+	} {
+		if err := processor.Register("uow:statement_cache:"+name, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// miss records a statement preparation
+func (c *statementCache) miss() {
+	c.prepares.Add(1)
+	c.metrics.IncCounter(MetricStatementCacheMisses, 1, nil)
+}
+
+// stats returns a snapshot of the counters
+func (c *statementCache) stats() StatementCacheStats {
+	return StatementCacheStats{
+		Executions: c.executions.Load(),
+		Prepares:   c.prepares.Load(),
+	}
+}
+
+// StatementCacheStatsOf returns prepared statement statistics for a connection
+// The second result is false when the connection was not opened with the statement cache
+func StatementCacheStatsOf(db *gorm.DB) (StatementCacheStats, bool) {
+	plugin, ok := db.Config.Plugins[statementCachePluginName]
+	if !ok {
+		return StatementCacheStats{}, false
+	}
+	return plugin.(*statementCache).stats(), true
+}
+
+// countingConnPool sits below GORM's prepared statement store and observes preparations
+type countingConnPool struct {
+	gorm.ConnPool
+	cache *statementCache
+}
+
+func (p *countingConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	p.cache.miss()
+	return p.ConnPool.PrepareContext(ctx, query)
+}
+
+// BeginTx keeps preparations inside transactions observable
+func (p *countingConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	beginner, ok := p.ConnPool.(gorm.TxBeginner)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &countingTx{Tx: tx, cache: p.cache}, nil
+}
+
+// GetDBConn implements gorm.GetDBConnector
+func (p *countingConnPool) GetDBConn() (*sql.DB, error) {
+	if db, ok := p.ConnPool.(*sql.DB); ok {
+		return db, nil
+	}
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// countingTx observes preparations made on a transaction
+type countingTx struct {
+	*sql.Tx
+	cache *statementCache
+}
+
+func (tx *countingTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	tx.cache.miss()
+	return tx.Tx.PrepareContext(ctx, query)
+}
@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	statementLogName     = "uow:statement_log"
+	statementLogStartKey = statementLogName + ":start"
+)
+
+// LoggedStatement is one statement handed to a StatementSink
+// SQL keeps its placeholders; Args holds the bound values only when StatementLog.Arguments is set
+type LoggedStatement struct {
+	SQL      string        `json:"sql"`
+	Args     []interface{} `json:"args,omitempty"`
+	Table    string        `json:"table,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Rows     int64         `json:"rows"`
+	Error    string        `json:"error,omitempty"`
+	At       time.Time     `json:"at"`
+	Tenant   string        `json:"tenant,omitempty"` // From WithTenant
+	User     string        `json:"user,omitempty"`   // From WithUser
+}
+
+// StatementSink receives sampled statements, e.g. to forward them to a SIEM
+// It is called on the statement's goroutine, so it should buffer rather than block
+type StatementSink interface {
+	LogStatement(ctx context.Context, statement LoggedStatement)
+}
+
+// StatementSinkFunc adapts a function to StatementSink
+type StatementSinkFunc func(ctx context.Context, statement LoggedStatement)
+
+// LogStatement implements StatementSink
+func (f StatementSinkFunc) LogStatement(ctx context.Context, statement LoggedStatement) {
+	f(ctx, statement)
+}
+
+// jsonStatementSink is the StatementSink behind NewJSONStatementSink
+type jsonStatementSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONStatementSink writes each statement to w as a line of JSON, for log shippers tailing stdout or a file
+func NewJSONStatementSink(w io.Writer) StatementSink {
+	return &jsonStatementSink{encoder: json.NewEncoder(w)}
+}
+
+// LogStatement implements StatementSink
+func (s *jsonStatementSink) LogStatement(_ context.Context, statement LoggedStatement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.encoder.Encode(statement)
+}
+
+// RedactionRule rewrites a bound value of a statement on the given table before it is logged
+type RedactionRule func(table string, value interface{}) interface{}
+
+// RedactTables masks every bound value of statements on the given tables
+func RedactTables(tables ...string) RedactionRule {
+	return func(table string, value interface{}) interface{} {
+		if slices.Contains(tables, table) {
+			return DefaultMask
+		}
+		return value
+	}
+}
+
+// RedactStrings masks every string and byte slice value, keeping numbers, booleans and times
+func RedactStrings() RedactionRule {
+	return func(_ string, value interface{}) interface{} {
+		switch value.(type) {
+		case string, []byte:
+			return DefaultMask
+		}
+		return value
+	}
+}
+
+// RedactPattern masks the parts of string values matching pattern, e.g. e-mail addresses or card numbers
+func RedactPattern(pattern *regexp.Regexp) RedactionRule {
+	return func(_ string, value interface{}) interface{} {
+		if s, ok := value.(string); ok {
+			return pattern.ReplaceAllString(s, DefaultMask)
+		}
+		return value
+	}
+}
+
+// StatementLog sends a sample of the statements run on a database to a sink
+// Bound values of PII columns are masked by the masking policy before the Redact rules run
+//
+//	config.StatementLog = &postgres.StatementLog{
+//		Sink:       postgres.NewJSONStatementSink(os.Stdout),
+//		SampleRate: 0.01,
+//		LogErrors:  true,
+//	}
+type StatementLog struct {
+	Sink       StatementSink
+	SampleRate float64         // Fraction of statements logged, e.g. 0.01; 0 logs none, 1 and above log every statement
+	LogErrors  bool            // Log failed statements regardless of sampling
+	Arguments  bool            // Include bound values, after masking and the Redact rules; off, only placeholders are logged
+	Redact     []RedactionRule // Applied in order to each bound value
+	Masking    *MaskingPolicy  // PII columns to mask in bound values; Connect defaults it to Config.Masking
+
+	random func() float64 // Sampling source; rand.Float64 by default
+}
+
+// sampled draws whether a statement is logged
+func (l *StatementLog) sampled() bool {
+	if l.SampleRate <= 0 {
+		return false
+	}
+	if l.SampleRate >= 1 {
+		return true
+	}
+	random := l.random
+	if random == nil {
+		random = rand.Float64
+	}
+	return random() < l.SampleRate
+}
+
+// args returns the statement's bound values after PII masking and redaction
+func (l *StatementLog) args(masking *MaskingPolicy, table, sql string, vars []interface{}) []interface{} {
+	if !l.Arguments || len(vars) == 0 {
+		return nil
+	}
+	if masking != nil {
+		vars = masking.MaskStatement(sql, vars)
+	}
+	args := make([]interface{}, len(vars))
+	for i, value := range vars {
+		for _, rule := range l.Redact {
+			value = rule(table, value)
+		}
+		args[i] = value
+	}
+	return args
+}
+
+// UseStatementLog sends a sample of every statement on db to the log's sink
+// Connect calls it when Config.StatementLog is set; use it directly with externally managed pools
+func UseStatementLog(db *gorm.DB, log *StatementLog) error {
+	if log == nil {
+		return fmt.Errorf("no statement log sink given")
+	}
+	return useStatementLog(db, log, log.Masking)
+}
+
+// useStatementLog installs the statement log, masking PII columns with the given policy
+func useStatementLog(db *gorm.DB, log *StatementLog, masking *MaskingPolicy) error {
+	if log == nil || log.Sink == nil {
+		return fmt.Errorf("no statement log sink given")
+	}
+	return db.Use(&statementLogPlugin{log: log, masking: masking})
+}
+
+// statementLogPlugin is the gorm plugin behind UseStatementLog
+type statementLogPlugin struct {
+	log     *StatementLog
+	masking *MaskingPolicy
+}
+
+// Name implements gorm.Plugin
+func (p *statementLogPlugin) Name() string {
+	return statementLogName
+}
+
+// Initialize implements gorm.Plugin
+func (p *statementLogPlugin) Initialize(db *gorm.DB) error {
+	start := func(tx *gorm.DB) {
+		tx.InstanceSet(statementLogStartKey, time.Now())
+	}
+	type registrar interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}
+	callbacks := db.Callback()
+	for name, processor := range map[string][2]registrar{
+		"create": {callbacks.Create().Before("*"), callbacks.Create().After("*")},
+		"query":  {callbacks.Query().Before("*"), callbacks.Query().After("*")},
+		"update": {callbacks.Update().Before("*"), callbacks.Update().After("*")},
+		"delete": {callbacks.Delete().Before("*"), callbacks.Delete().After("*")},
+		"row":    {callbacks.Row().Before("*"), callbacks.Row().After("*")},
+		"raw":    {callbacks.Raw().Before("*"), callbacks.Raw().After("*")},
+	} {
+		if err := processor[0].Register(statementLogName+":start:"+name, start); err != nil {
+			return err
+		}
+		if err := processor[1].Register(statementLogName+":"+name, p.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record hands the statement to the sink when it is sampled, or failed and LogErrors is set
+func (p *statementLogPlugin) record(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(statementLogStartKey)
+	if !ok || tx.DryRun || tx.Statement.SQL.Len() == 0 {
+		return
+	}
+	if !(tx.Error != nil && p.log.LogErrors) && !p.log.sampled() {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	sql := tx.Statement.SQL.String()
+	entry := LoggedStatement{
+		SQL:      sql,
+		Args:     p.log.args(p.masking, tx.Statement.Table, sql, tx.Statement.Vars),
+		Table:    tx.Statement.Table,
+		Duration: time.Since(value.(time.Time)),
+		Rows:     tx.RowsAffected,
+		At:       time.Now(),
+		Tenant:   TenantFrom(ctx),
+		User:     UserFrom(ctx),
+	}
+	if tx.Error != nil {
+		entry.Error = tx.Error.Error()
+	}
+	p.log.Sink.LogStatement(ctx, entry)
+}
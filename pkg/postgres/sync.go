@@ -0,0 +1,317 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm/clause"
+)
+
+// SyncSince returns the entities created, updated or soft-deleted after a watermark, plus the new watermark
+// A row's change time is its deleted_at when soft-deleted after its last update, otherwise its
+// updated_at; the entity must have both columns. Hard deletes are not observable this way.
+func (uow *UnitOfWork[T]) SyncSince(ctx context.Context, params domain.SyncParams) (domain.SyncResult[T], error) {
+	var result domain.SyncResult[T]
+	if err := params.Validate(); err != nil {
+		return result, err
+	}
+	result.Watermark = params.Since
+
+	meta := uow.metadata()
+	updatedAt, hasUpdatedAt := meta.Field("updated_at")
+	deletedAt, hasDeletedAt := meta.Field("deleted_at")
+	primaryKey, hasPrimaryKey := meta.primaryKey()
+	if !hasUpdatedAt || !hasDeletedAt || !hasPrimaryKey {
+		return result, fmt.Errorf("%w: sync requires id, updated_at and deleted_at columns", uowerrors.ErrInvalidQueryParams)
+	}
+
+	changedAt := fmt.Sprintf("(CASE WHEN %[1]s IS NOT NULL AND %[1]s > %[2]s THEN %[1]s ELSE %[2]s END)", deletedAt.Qualified, updatedAt.Qualified)
+
+	db := uow.getActiveDB(ctx).Unscoped().Model(new(T))
+	if !params.Since.Time.IsZero() {
+		db = db.Where(
+			fmt.Sprintf("%[1]s > ? OR (%[1]s = ? AND %[2]s > ?)", changedAt, primaryKey.Qualified),
+			params.Since.Time, params.Since.Time, params.Since.ID,
+		)
+	}
+
+	var entities []T
+	if err := db.Order(changedAt).Order(primaryKey.Qualified).Limit(params.Limit + 1).Find(&entities).Error; err != nil {
+		return result, fmt.Errorf("failed to sync entities: %w", err)
+	}
+
+	if len(entities) > params.Limit {
+		entities = entities[:params.Limit]
+		result.HasMore = true
+	}
+
+	for _, entity := range entities {
+		deleted := entity.GetArchivedAt()
+		changed := entity.GetUpdatedAt()
+		switch {
+		case deleted.Valid:
+			if deleted.Time.After(changed) {
+				changed = deleted.Time
+			}
+			result.Deleted = append(result.Deleted, entity)
+		case entity.GetCreatedAt().After(params.Since.Time):
+			result.Created = append(result.Created, entity)
+		default:
+			result.Updated = append(result.Updated, entity)
+		}
+		result.Watermark = domain.Watermark{Time: changed, ID: entity.GetID()}
+	}
+
+	uow.maskResults(ctx, entities...)
+	return result, nil
+}
+
+// versionColumn holds the row version ApplyChanges checks and bumps
+const versionColumn = "version"
+
+// ApplyChanges applies changes submitted by an offline client in one transaction. Updates and deletes
+// are checked against the row's integer version column: a change based on the current version is
+// applied and bumps it, an outdated one is a conflict resolved per options (the server wins by
+// default), and so is a change to a row deleted on the server. Creates keep client-generated IDs
+// (see GenerateIDs) and start at version 1; a create whose ID already exists is a conflict too.
+// Versions are only bumped here, so other writers of synced rows must bump them as well.
+// Inside a transaction it joins it
+func (uow *UnitOfWork[T]) ApplyChanges(ctx context.Context, changes []domain.ClientChange[T], options domain.SyncOptions[T]) (result domain.SyncApplyResult[T], err error) {
+	meta := uow.metadata()
+	version, hasVersion := meta.Field(versionColumn)
+	primaryKey, hasPrimaryKey := meta.primaryKey()
+	if !hasVersion || !hasPrimaryKey || !meta.Type.FieldByIndex(version.Index).Type.ConvertibleTo(reflect.TypeOf(int64(0))) {
+		return result, fmt.Errorf("%w: applying client changes requires id and integer version columns", uowerrors.ErrInvalidQueryParams)
+	}
+	switch options.Resolution {
+	case "":
+		options.Resolution = domain.ServerWins
+	case domain.ServerWins, domain.ClientWins:
+	case domain.MergeWith:
+		if options.Merge == nil {
+			return result, fmt.Errorf("%w: merge resolution needs a Merge function", uowerrors.ErrInvalidQueryParams)
+		}
+	default:
+		return result, fmt.Errorf("%w: unknown conflict resolution %q", uowerrors.ErrInvalidQueryParams, options.Resolution)
+	}
+
+	if !uow.inTx {
+		if err := uow.BeginTransaction(ctx); err != nil {
+			return result, err
+		}
+		defer func() {
+			if err != nil {
+				uow.RollbackTransaction(ctx)
+				return
+			}
+			err = uow.CommitTransaction(ctx)
+		}()
+	}
+
+	apply := &changeApplier[T]{uow: uow, ctx: ctx, meta: meta, version: version, primaryKey: primaryKey, options: options}
+	for i, change := range changes {
+		if _, ok := meta.structValue(change.Entity); !ok {
+			return result, fmt.Errorf("%w: change %d has no entity", uowerrors.ErrInvalidEntity, i)
+		}
+		applied, conflict, err := apply.change(change)
+		if err != nil {
+			return result, fmt.Errorf("failed to apply change %d (%s %d): %w", i, change.Operation, change.Entity.GetID(), err)
+		}
+		if conflict != nil {
+			result.Conflicts = append(result.Conflicts, *conflict)
+		}
+		if applied != nil {
+			result.Applied = append(result.Applied, *applied)
+		}
+	}
+	uow.maskResults(ctx, result.Applied...)
+	return result, nil
+}
+
+// changeApplier applies client changes inside the open transaction
+type changeApplier[T domain.BaseModel] struct {
+	uow        *UnitOfWork[T]
+	ctx        context.Context
+	meta       *modelMetadata
+	version    fieldMetadata
+	primaryKey fieldMetadata
+	options    domain.SyncOptions[T]
+}
+
+// change applies one change; it returns the written row, if any, and the conflict, if any
+func (a *changeApplier[T]) change(change domain.ClientChange[T]) (*T, *domain.SyncConflict[T], error) {
+	if change.Operation != domain.SyncDelete {
+		if err := a.uow.rejectMasked(change.Entity); err != nil {
+			return nil, nil, err
+		}
+	}
+	if change.Operation == domain.SyncCreate {
+		if err := a.uow.assignIDs(a.ctx, change.Entity); err != nil {
+			return nil, nil, err
+		}
+		if err := a.uow.validate(false, change.Entity); err != nil {
+			return nil, nil, err
+		}
+	}
+	server, found, err := a.load(change.Entity.GetID())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conflict := &domain.SyncConflict[T]{Change: change, Resolution: domain.ServerWins}
+	if found {
+		conflict.Server, conflict.ServerVersion = server, a.versionOf(server)
+	}
+	switch change.Operation {
+	case domain.SyncCreate:
+		if !found {
+			a.setVersion(change.Entity, 1)
+			if err := a.uow.getActiveDB(a.ctx).Create(&change.Entity).Error; err != nil {
+				return nil, nil, err
+			}
+			applied, err := a.reloadRecorded(change.Entity.GetID(), domain.ChangeCreated)
+			return applied, nil, err
+		}
+		conflict.Reason = "already exists"
+	case domain.SyncUpdate, domain.SyncDelete:
+		switch {
+		case !found:
+			conflict.Reason = "not found on server"
+			return nil, conflict, nil // Nothing left to write to
+		case server.GetArchivedAt().Valid:
+			conflict.Reason = "deleted on server"
+		case conflict.ServerVersion != change.BaseVersion:
+			conflict.Reason = fmt.Sprintf("based on version %d, server is at %d", change.BaseVersion, conflict.ServerVersion)
+		case change.Operation == domain.SyncDelete:
+			applied, err := a.delete(server)
+			return applied, nil, err
+		default:
+			applied, err := a.write(server, change.Entity, change.Fields)
+			return applied, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown sync operation %q", uowerrors.ErrInvalidQueryParams, change.Operation)
+	}
+
+	switch {
+	case a.options.Resolution == domain.ClientWins && change.Operation == domain.SyncDelete:
+		if server.GetArchivedAt().Valid {
+			return nil, conflict, nil // Already deleted
+		}
+		conflict.Resolution = domain.ClientWins
+		applied, err := a.delete(server)
+		return applied, conflict, err
+	case a.options.Resolution == domain.ClientWins:
+		conflict.Resolution = domain.ClientWins
+		applied, err := a.write(server, change.Entity, change.Fields)
+		return applied, conflict, err
+	case a.options.Resolution == domain.MergeWith && change.Operation != domain.SyncDelete:
+		merged, err := a.options.Merge(cloneEntity(server), change.Entity)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to merge conflict: %w", err)
+		}
+		conflict.Resolution = domain.MergeWith
+		applied, err := a.write(server, merged, nil)
+		return applied, conflict, err
+	}
+	return nil, conflict, nil
+}
+
+// load reads the server row, trashed or not, locking it on PostgreSQL
+func (a *changeApplier[T]) load(id int) (T, bool, error) {
+	var server T
+	if id == 0 {
+		return server, false, nil
+	}
+	db := a.uow.getActiveDB(a.ctx).Unscoped()
+	if db.Dialector.Name() == "postgres" {
+		db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	var rows []T
+	if err := db.Where(a.primaryKey.Qualified+" = ?", id).Limit(1).Find(&rows).Error; err != nil {
+		return server, false, err
+	}
+	if len(rows) == 0 {
+		return server, false, nil
+	}
+	return rows[0], true, nil
+}
+
+// write stores the client's columns over the server row, restoring it if trashed, and bumps the version
+func (a *changeApplier[T]) write(server, client T, fields []string) (*T, error) {
+	source, _ := a.meta.structValue(client)
+	updates := map[string]interface{}{versionColumn: a.versionOf(server) + 1}
+	if len(fields) == 0 {
+		for _, field := range a.meta.Fields {
+			if a.writable(field) {
+				updates[field.Column] = source.FieldByIndex(field.Index).Interface()
+			}
+		}
+	}
+	for _, name := range fields {
+		field, ok := a.meta.Field(name)
+		if !ok || !a.writable(field) {
+			return nil, fmt.Errorf("%w: cannot sync column %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		updates[field.Column] = source.FieldByIndex(field.Index).Interface()
+	}
+	if server.GetArchivedAt().Valid {
+		updates["deleted_at"] = nil
+	}
+
+	db := a.uow.getActiveDB(a.ctx).Unscoped().Model(newEntity[T]()).Where(a.primaryKey.Qualified+" = ?", server.GetID())
+	if err := db.Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return a.reloadRecorded(server.GetID(), domain.ChangeUpdated)
+}
+
+// delete soft-deletes the server row and bumps its version
+func (a *changeApplier[T]) delete(server T) (*T, error) {
+	db := a.uow.getActiveDB(a.ctx)
+	where := a.primaryKey.Qualified + " = ?"
+	if err := db.Model(newEntity[T]()).Where(where, server.GetID()).UpdateColumn(versionColumn, a.versionOf(server)+1).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where(where, server.GetID()).Delete(newEntity[T]()).Error; err != nil {
+		return nil, err
+	}
+	return a.reloadRecorded(server.GetID(), domain.ChangeDeleted)
+}
+
+// reloadRecorded reads the written row back and reports it to change listeners
+func (a *changeApplier[T]) reloadRecorded(id int, kind domain.ChangeKind) (*T, error) {
+	entity, found, err := a.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s %d vanished while syncing", uowerrors.ErrEntityNotFound, a.meta.Table, id)
+	}
+	a.uow.recordChanges(a.ctx, kind, entity)
+	return &entity, nil
+}
+
+// writable reports whether a client may set the column
+func (a *changeApplier[T]) writable(field fieldMetadata) bool {
+	switch {
+	case field.PrimaryKey, field.Column == versionColumn, field.Column == "created_at", field.Column == "updated_at":
+		return false
+	}
+	return a.meta.Type.FieldByIndex(field.Index).Type != deletedAtType
+}
+
+func (a *changeApplier[T]) versionOf(entity T) int64 {
+	v, _ := a.meta.structValue(entity)
+	return v.FieldByIndex(a.version.Index).Convert(reflect.TypeOf(int64(0))).Int()
+}
+
+func (a *changeApplier[T]) setVersion(entity T, version int64) {
+	v, _ := a.meta.structValue(entity)
+	target := v.FieldByIndex(a.version.Index)
+	target.Set(reflect.ValueOf(version).Convert(target.Type()))
+}
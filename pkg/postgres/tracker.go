@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultTrackerBatchSize is how many rows one statement of a tracked commit writes
+const DefaultTrackerBatchSize = 500
+
+// ChangeTracker records inserts, updates and deletes of entities of any type and writes them
+// in one commit. The commit planner orders tables by their foreign keys (parents are inserted
+// first and deleted last) and writes each table's rows in batched statements, so a large object
+// graph costs a few round trips per table instead of one per entity.
+//
+// New children pick up the generated keys of new parents they point to through belongs-to
+// pointers, or that hold them in has-one/has-many pointer fields. Entities must be pointers
+// to structs; associations are never written implicitly.
+type ChangeTracker struct {
+	mu        sync.Mutex
+	conn      func(ctx context.Context) *gorm.DB // Database or open transaction to write to
+	batchSize int
+	order     []reflect.Type // Tables in the order they were first tracked
+	pending   map[reflect.Type]*trackedRows
+}
+
+type trackedRows struct {
+	inserts, updates, deletes []interface{}
+}
+
+// NewChangeTracker creates a tracker that commits over db in a transaction of its own
+func NewChangeTracker(db *gorm.DB) *ChangeTracker {
+	return newChangeTracker(func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	})
+}
+
+// Track creates a change tracker writing through this unit of work
+// Inside a transaction the tracked commit runs in a savepoint of it; otherwise in its own transaction
+func (uow *UnitOfWork[T]) Track() *ChangeTracker {
+	return newChangeTracker(func(ctx context.Context) *gorm.DB {
+		if uow.inTx && uow.tx != nil {
+			return uow.tx.WithContext(ctx)
+		}
+		return uow.db.WithContext(uow.pinned(ctx))
+	})
+}
+
+func newChangeTracker(conn func(ctx context.Context) *gorm.DB) *ChangeTracker {
+	return &ChangeTracker{conn: conn, batchSize: DefaultTrackerBatchSize, pending: make(map[reflect.Type]*trackedRows)}
+}
+
+// WithBatchSize sets how many rows one statement writes
+func (t *ChangeTracker) WithBatchSize(size int) *ChangeTracker {
+	if size > 0 {
+		t.batchSize = size
+	}
+	return t
+}
+
+// RegisterNew tracks entities to insert
+func (t *ChangeTracker) RegisterNew(entities ...interface{}) error {
+	return t.register(domain.ChangeCreated, entities)
+}
+
+// RegisterDirty tracks existing entities to update with all of their columns
+func (t *ChangeTracker) RegisterDirty(entities ...interface{}) error {
+	return t.register(domain.ChangeUpdated, entities)
+}
+
+// RegisterDeleted tracks entities to delete; soft-deletable ones are soft deleted
+func (t *ChangeTracker) RegisterDeleted(entities ...interface{}) error {
+	return t.register(domain.ChangeDeleted, entities)
+}
+
+func (t *ChangeTracker) register(kind domain.ChangeKind, entities []interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, entity := range entities {
+		v := reflect.ValueOf(entity)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return fmt.Errorf("%w: tracked entities must be non-nil struct pointers, got %T", uowerrors.ErrInvalidEntity, entity)
+		}
+		rows, ok := t.pending[v.Type()]
+		if !ok {
+			rows = &trackedRows{}
+			t.pending[v.Type()] = rows
+			t.order = append(t.order, v.Type())
+		}
+		switch kind {
+		case domain.ChangeCreated:
+			rows.inserts = append(rows.inserts, entity)
+		case domain.ChangeUpdated:
+			rows.updates = append(rows.updates, entity)
+		case domain.ChangeDeleted:
+			rows.deletes = append(rows.deletes, entity)
+		}
+	}
+	return nil
+}
+
+// PlanStep is one table's share of a tracked commit
+type PlanStep struct {
+	Kind       domain.ChangeKind
+	Table      string
+	Rows       int
+	Statements int
+
+	entities  []interface{}
+	modelType reflect.Type
+}
+
+// CommitPlan lists the steps of a tracked commit in execution order
+type CommitPlan struct {
+	Steps []PlanStep
+}
+
+// Statements counts the statements the plan executes
+func (p CommitPlan) Statements() int {
+	total := 0
+	for _, step := range p.Steps {
+		total += step.Statements
+	}
+	return total
+}
+
+// Plan returns the order and batching of the pending changes without writing them:
+// inserts and updates parents first, then deletes children first
+func (t *ChangeTracker) Plan() CommitPlan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	models := make([]interface{}, len(t.order))
+	for i, typ := range t.order {
+		models[i] = reflect.New(typ.Elem()).Interface()
+	}
+	namer := t.conn(context.Background()).NamingStrategy
+	order := dependencyOrder(models, namer)
+
+	var plan CommitPlan
+	add := func(kind domain.ChangeKind, typ reflect.Type, entities []interface{}) {
+		if len(entities) == 0 {
+			return
+		}
+		table := namer.TableName(typ.Elem().Name())
+		if s, err := schema.Parse(reflect.New(typ.Elem()).Interface(), &sync.Map{}, namer); err == nil {
+			table = s.Table
+		}
+		statements := (len(entities) + t.batchSize - 1) / t.batchSize
+		plan.Steps = append(plan.Steps, PlanStep{
+			Kind: kind, Table: table, Rows: len(entities), Statements: statements,
+			entities: append([]interface{}(nil), entities...), modelType: typ,
+		})
+	}
+	for _, i := range order {
+		add(domain.ChangeCreated, t.order[i], t.pending[t.order[i]].inserts)
+	}
+	for _, i := range order {
+		add(domain.ChangeUpdated, t.order[i], t.pending[t.order[i]].updates)
+	}
+	for k := len(order) - 1; k >= 0; k-- {
+		add(domain.ChangeDeleted, t.order[order[k]], t.pending[t.order[order[k]]].deletes)
+	}
+	return plan
+}
+
+// Commit writes the pending changes as planned in one transaction and clears them
+// A failure rolls the whole commit back and keeps the changes pending
+func (t *ChangeTracker) Commit(ctx context.Context) (CommitPlan, error) {
+	plan := t.Plan()
+	if len(plan.Steps) == 0 {
+		return plan, nil
+	}
+
+	err := t.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, step := range plan.Steps {
+			if err := t.execute(ctx, tx, step); err != nil {
+				return fmt.Errorf("failed to %s %s: %w", planVerb(step.Kind), step.Table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return plan, err
+	}
+
+	t.mu.Lock()
+	t.order = nil
+	t.pending = make(map[reflect.Type]*trackedRows)
+	t.mu.Unlock()
+	return plan, nil
+}
+
+// execute runs one plan step
+func (t *ChangeTracker) execute(ctx context.Context, tx *gorm.DB, step PlanStep) error {
+	s, err := schema.Parse(reflect.New(step.modelType.Elem()).Interface(), &sync.Map{}, tx.NamingStrategy)
+	if err != nil {
+		return err
+	}
+	rows := reflect.MakeSlice(reflect.SliceOf(step.modelType), 0, len(step.entities))
+	for _, entity := range step.entities {
+		rows = reflect.Append(rows, reflect.ValueOf(entity))
+	}
+
+	switch step.Kind {
+	case domain.ChangeCreated:
+		for _, entity := range step.entities {
+			adoptParentKeys(ctx, s, reflect.ValueOf(entity).Elem())
+		}
+		if err := tx.Omit(clause.Associations).CreateInBatches(rows.Interface(), t.batchSize).Error; err != nil {
+			return err
+		}
+		for _, entity := range step.entities {
+			shareKeyWithChildren(ctx, s, reflect.ValueOf(entity).Elem())
+		}
+		return nil
+
+	case domain.ChangeUpdated:
+		if len(s.PrimaryFields) == 0 {
+			return fmt.Errorf("%w: %s has no primary key", uowerrors.ErrInvalidEntity, s.Name)
+		}
+		now := time.Now()
+		for _, entity := range step.entities {
+			v := reflect.ValueOf(entity).Elem()
+			for _, field := range s.Fields {
+				if field.AutoUpdateTime > 0 && field.FieldType == reflect.TypeOf(now) {
+					field.Set(ctx, v, now)
+				}
+			}
+		}
+		// Upserting on the primary key updates every row of a batch in one statement
+		columns := make([]clause.Column, len(s.PrimaryFields))
+		for i, field := range s.PrimaryFields {
+			columns[i] = clause.Column{Name: field.DBName}
+		}
+		return tx.Omit(clause.Associations).
+			Clauses(clause.OnConflict{Columns: columns, UpdateAll: true}).
+			CreateInBatches(rows.Interface(), t.batchSize).Error
+
+	case domain.ChangeDeleted:
+		if s.PrioritizedPrimaryField == nil {
+			return fmt.Errorf("%w: %s has no single-column primary key", uowerrors.ErrInvalidEntity, s.Name)
+		}
+		keys := make([]interface{}, len(step.entities))
+		for i, entity := range step.entities {
+			keys[i], _ = s.PrioritizedPrimaryField.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+		}
+		model := reflect.New(step.modelType.Elem()).Interface()
+		column := clause.Column{Table: clause.CurrentTable, Name: s.PrioritizedPrimaryField.DBName}
+		for start := 0; start < len(keys); start += t.batchSize {
+			batch := keys[start:min(start+t.batchSize, len(keys))]
+			if err := tx.Where(clause.IN{Column: column, Values: batch}).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// adoptParentKeys copies the keys of parents referenced through belongs-to fields into the foreign keys
+func adoptParentKeys(ctx context.Context, s *schema.Schema, v reflect.Value) {
+	for _, rel := range s.Relationships.BelongsTo {
+		parent := reflect.Indirect(rel.Field.ReflectValueOf(ctx, v))
+		if !parent.IsValid() || parent.Kind() != reflect.Struct {
+			continue
+		}
+		for _, ref := range rel.References {
+			if ref.PrimaryKey == nil || ref.OwnPrimaryKey {
+				continue
+			}
+			if key, zero := ref.PrimaryKey.ValueOf(ctx, parent); !zero {
+				ref.ForeignKey.Set(ctx, v, key)
+			}
+		}
+	}
+}
+
+// shareKeyWithChildren sets the new parent's key on children held in has-one/has-many pointer fields
+func shareKeyWithChildren(ctx context.Context, s *schema.Schema, v reflect.Value) {
+	for _, rel := range s.Relationships.Relations {
+		if rel.Type != schema.HasOne && rel.Type != schema.HasMany {
+			continue
+		}
+		var children []reflect.Value
+		field := rel.Field.ReflectValueOf(ctx, v)
+		switch {
+		case field.Kind() == reflect.Ptr && !field.IsNil():
+			children = append(children, field.Elem())
+		case field.Kind() == reflect.Slice:
+			for i := 0; i < field.Len(); i++ {
+				if item := field.Index(i); item.Kind() == reflect.Ptr && !item.IsNil() {
+					children = append(children, item.Elem())
+				}
+			}
+		}
+		for _, ref := range rel.References {
+			if ref.PrimaryKey == nil || !ref.OwnPrimaryKey {
+				continue
+			}
+			key, zero := ref.PrimaryKey.ValueOf(ctx, v)
+			if zero {
+				continue
+			}
+			for _, child := range children {
+				ref.ForeignKey.Set(ctx, child, key)
+			}
+		}
+	}
+}
+
+func planVerb(kind domain.ChangeKind) string {
+	switch kind {
+	case domain.ChangeCreated:
+		return "insert into"
+	case domain.ChangeUpdated:
+		return "update"
+	default:
+		return "delete from"
+	}
+}
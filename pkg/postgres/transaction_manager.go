@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+
+	"gorm.io/gorm"
+)
+
+// TransactionManager runs one database transaction shared by units of work of different entity
+// types, so that work on e.g. users and their posts commits or rolls back as one unit.
+// Get typed views with UnitOfWorkIn or a factory's JoinTransaction; a view serves the open
+// transaction, or the next one when bound before Begin, and is released when it ends.
+// Like a unit of work it is meant for one goroutine at a time
+type TransactionManager struct {
+	config *Config
+	db     *gorm.DB
+
+	mu    sync.Mutex
+	tx    *gorm.DB
+	views []sharedView
+}
+
+// sharedView is a unit of work bound to a TransactionManager
+type sharedView interface {
+	attach(ctx context.Context, tx *gorm.DB)
+	detach(ctx context.Context, committed bool)
+}
+
+// NewTransactionManager creates a manager opening its transactions on db
+func NewTransactionManager(config *Config, db *gorm.DB) *TransactionManager {
+	return &TransactionManager{config: config, db: db}
+}
+
+// TransactionManager creates a manager over the factory's connection pool
+func (f *UnitOfWorkFactory[T]) TransactionManager() (*TransactionManager, error) {
+	db, err := f.connection()
+	if err != nil {
+		return nil, err
+	}
+	return NewTransactionManager(f.Config, db), nil
+}
+
+// UnitOfWorkIn returns a unit of work for T bound to the manager's transaction
+// The view cannot begin or commit on its own; rolling it back rolls back the shared transaction,
+// and closing it leaves the transaction open. Once the transaction ends it runs without one
+func UnitOfWorkIn[T domain.BaseModel](m *TransactionManager) persistence.IUnitOfWork[T] {
+	return bindUnitOfWork(m, newUnitOfWork[T](m.config, m.db))
+}
+
+// JoinTransaction returns a unit of work bound to the manager's transaction, with the factory's
+// entity settings; see UnitOfWorkIn
+func (f *UnitOfWorkFactory[T]) JoinTransaction(m *TransactionManager) persistence.IUnitOfWork[T] {
+	uow := newUnitOfWork[T](m.config, m.db)
+	uow.settings = f.settings
+	return bindUnitOfWork(m, uow)
+}
+
+func bindUnitOfWork[T domain.BaseModel](m *TransactionManager, uow *UnitOfWork[T]) *UnitOfWork[T] {
+	uow.shared = m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.views = append(m.views, uow)
+	if m.tx != nil {
+		uow.attach(m.tx.Statement.Context, m.tx)
+	}
+	return uow
+}
+
+// release forgets a closed view
+func (m *TransactionManager) release(view sharedView) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, v := range m.views {
+		if v == view {
+			m.views = append(m.views[:i], m.views[i+1:]...)
+			return
+		}
+	}
+}
+
+// Begin starts the shared transaction with the options of Config.TxOptions
+func (m *TransactionManager) Begin(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tx != nil {
+		return fmt.Errorf("failed to begin shared transaction: %w", uowerrors.ErrTransactionAlreadyOpen)
+	}
+
+	options := sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	if m.config != nil && m.config.TxOptions != nil {
+		options = *m.config.TxOptions
+	}
+	tx := m.db.WithContext(ctx).Begin(&options)
+	if tx.Error != nil {
+		return fmt.Errorf("failed to begin shared transaction: %w", tx.Error)
+	}
+	m.tx = tx
+	for _, view := range m.views {
+		view.attach(ctx, tx)
+	}
+	return nil
+}
+
+// Commit commits the shared transaction, then runs the views' change notifications and AfterCommit hooks
+func (m *TransactionManager) Commit(ctx context.Context) error {
+	m.mu.Lock()
+	tx := m.tx
+	m.mu.Unlock()
+	if tx == nil {
+		return fmt.Errorf("failed to commit shared transaction: %w", uowerrors.ErrTransactionNotStarted)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		m.end(ctx, false)
+		return fmt.Errorf("failed to commit shared transaction: %w", err)
+	}
+	m.end(ctx, true)
+	return nil
+}
+
+// Rollback rolls back the shared transaction; a no-op when none is open
+func (m *TransactionManager) Rollback(ctx context.Context) {
+	m.mu.Lock()
+	tx := m.tx
+	m.mu.Unlock()
+	if tx == nil {
+		return
+	}
+	tx.Rollback()
+	m.end(ctx, false)
+}
+
+// InTransaction reports whether the shared transaction is open
+func (m *TransactionManager) InTransaction() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tx != nil
+}
+
+// Run runs fn in a shared transaction, committing when it returns nil and rolling back when it
+// returns an error or panics; the panic is re-raised after the rollback
+func (m *TransactionManager) Run(ctx context.Context, fn func(m *TransactionManager) error) error {
+	if err := m.Begin(ctx); err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			m.Rollback(ctx)
+		}
+	}()
+
+	if err := fn(m); err != nil {
+		return err
+	}
+	committed = true
+	return m.Commit(ctx)
+}
+
+// end releases the views from the finished transaction
+func (m *TransactionManager) end(ctx context.Context, committed bool) {
+	m.mu.Lock()
+	m.tx = nil
+	views := m.views
+	m.views = nil
+	m.mu.Unlock()
+	for _, view := range views {
+		view.detach(ctx, committed)
+	}
+}
+
+// attach binds the unit of work to a transaction of its TransactionManager
+func (uow *UnitOfWork[T]) attach(ctx context.Context, tx *gorm.DB) {
+	uow.tx = tx
+	uow.ctx = ctx
+	uow.inTx = true
+}
+
+// detach releases the unit of work from its TransactionManager's finished transaction
+func (uow *UnitOfWork[T]) detach(ctx context.Context, committed bool) {
+	uow.tx = nil
+	uow.inTx = false
+	uow.savepoints = nil
+	if !committed {
+		uow.rolledBack(ctx)
+		return
+	}
+	if router := replicaRouterOf(uow.db); router != nil {
+		router.committed(uow.pinned(ctx), uow.db)
+	}
+	uow.committed(ctx)
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// treeColumns resolves the quoted table, primary key and parent columns for a hierarchy query
+type treeColumns struct {
+	table, id, parent string
+	parentColumn      string
+	liveOnly          string // Soft-delete condition for alias "n", empty without deleted_at
+}
+
+func treeColumnsOf(meta *modelMetadata, options domain.TreeOptions) (treeColumns, error) {
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return treeColumns{}, fmt.Errorf("%w: tree queries require a primary key", uowerrors.ErrInvalidQueryParams)
+	}
+	parent, ok := meta.Field(options.ParentField)
+	if !ok {
+		return treeColumns{}, fmt.Errorf("%w: unknown parent field %q", uowerrors.ErrInvalidQueryParams, options.ParentField)
+	}
+
+	columns := treeColumns{
+		table:        quoteIdentifier(meta.Table),
+		id:           quoteIdentifier(primaryKey.Column),
+		parent:       quoteIdentifier(parent.Column),
+		parentColumn: parent.Column,
+	}
+	if deletedAt, ok := meta.Field("deleted_at"); ok {
+		columns.liveOnly = " AND n." + quoteIdentifier(deletedAt.Column) + " IS NULL"
+	}
+	return columns, nil
+}
+
+// treeRow is one node found by a recursive walk
+type treeRow struct {
+	ID    int
+	Depth int
+}
+
+// Ancestors returns the live ancestors of an entity, root first
+// The walk stops at a soft-deleted ancestor, which orphans the rest of the path
+func (uow *UnitOfWork[T]) Ancestors(ctx context.Context, id int, options domain.TreeOptions) ([]T, error) {
+	options.Validate()
+	columns, err := treeColumnsOf(uow.metadata(), options)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`WITH RECURSIVE tree (id, parent, depth) AS (
+	SELECT n.%[2]s, n.%[3]s, 0 FROM %[1]s n WHERE n.%[2]s = ?%[4]s
+	UNION ALL
+	SELECT n.%[2]s, n.%[3]s, tree.depth + 1 FROM %[1]s n JOIN tree ON n.%[2]s = tree.parent WHERE tree.depth < ?%[4]s
+)
+SELECT id, depth FROM tree WHERE depth > 0`, columns.table, columns.id, columns.parent, columns.liveOnly)
+
+	entities, err := uow.walkTree(ctx, query, id, options.MaxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ancestors: %w", err)
+	}
+	// Deepest ancestor first means root first
+	for i, j := 0, len(entities)-1; i < j; i, j = i+1, j-1 {
+		entities[i], entities[j] = entities[j], entities[i]
+	}
+	return entities, nil
+}
+
+// Descendants returns the live descendants of an entity level by level, up to options.MaxDepth levels down
+// Soft-deleted nodes are skipped together with their subtrees
+func (uow *UnitOfWork[T]) Descendants(ctx context.Context, id int, options domain.TreeOptions) ([]T, error) {
+	options.Validate()
+	columns, err := treeColumnsOf(uow.metadata(), options)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := uow.walkTree(ctx, descendantsQuery(columns), id, options.MaxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find descendants: %w", err)
+	}
+	return entities, nil
+}
+
+// MoveSubtree re-parents an entity, carrying its descendants along; newParentID 0 makes it a root
+// Moving a node below itself or one of its descendants fails with errors.ErrInvalidQueryParams
+func (uow *UnitOfWork[T]) MoveSubtree(ctx context.Context, id, newParentID int, options domain.TreeOptions) error {
+	options.Validate()
+	columns, err := treeColumnsOf(uow.metadata(), options)
+	if err != nil {
+		return err
+	}
+
+	var parent interface{}
+	if newParentID != 0 {
+		if newParentID == id {
+			return fmt.Errorf("%w: cannot move %d below itself", uowerrors.ErrInvalidQueryParams, id)
+		}
+		var rows []treeRow
+		if err := uow.getActiveDB(ctx).Raw(descendantsQuery(columns), id, options.MaxDepth).Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to check subtree: %w", err)
+		}
+		for _, row := range rows {
+			if row.ID == newParentID {
+				return fmt.Errorf("%w: cannot move %d below its descendant %d", uowerrors.ErrInvalidQueryParams, id, newParentID)
+			}
+		}
+		parent = newParentID
+	}
+
+	result := uow.getActiveDB(ctx).Model(newEntity[T]()).Where(columns.id+" = ?", id).Update(columns.parentColumn, parent)
+	if result.Error != nil {
+		return fmt.Errorf("failed to move subtree: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to move subtree: %w", uowerrors.ErrEntityNotFound)
+	}
+	return nil
+}
+
+func descendantsQuery(columns treeColumns) string {
+	return fmt.Sprintf(`WITH RECURSIVE tree (id, depth) AS (
+	SELECT n.%[2]s, 1 FROM %[1]s n WHERE n.%[3]s = ?%[4]s
+	UNION ALL
+	SELECT n.%[2]s, tree.depth + 1 FROM %[1]s n JOIN tree ON n.%[3]s = tree.id WHERE tree.depth < ?%[4]s
+)
+SELECT id, depth FROM tree`, columns.table, columns.id, columns.parent, columns.liveOnly)
+}
+
+// walkTree runs a recursive query yielding (id, depth) rows and loads the entities ordered by depth, then ID
+// Entities are loaded through the regular query path, so default scopes and masking apply
+func (uow *UnitOfWork[T]) walkTree(ctx context.Context, query string, id, maxDepth int) ([]T, error) {
+	var rows []treeRow
+	if err := uow.getActiveDB(ctx).Raw(query, id, maxDepth).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	depths := make(map[int]int, len(rows))
+	ids := make([]int, 0, len(rows))
+	for _, row := range rows {
+		if _, seen := depths[row.ID]; !seen {
+			ids = append(ids, row.ID)
+		}
+		depths[row.ID] = row.Depth
+	}
+
+	entities, err := uow.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entities, func(i, j int) bool {
+		a, b := entities[i], entities[j]
+		if depths[a.GetID()] != depths[b.GetID()] {
+			return depths[a.GetID()] < depths[b.GetID()]
+		}
+		return a.GetID() < b.GetID()
+	})
+	return entities, nil
+}
@@ -3,55 +3,133 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 // UnitOfWork implements IUnitOfWork for PostgreSQL with generics
+//
+// A unit of work tracks one transaction and is meant to be used by one goroutine at a time.
+// Derive independent units from the same pool with Session or WithContext (or a factory)
+// instead of sharing one across goroutines.
 type UnitOfWork[T domain.BaseModel] struct {
+	config       *Config
 	db           *gorm.DB
 	tx           *gorm.DB
 	ctx          context.Context
 	repositories map[string]interface{}
 	mu           sync.RWMutex
 	inTx         bool
+	ownsDB       bool                // Close releases the pool only when this unit of work opened it
+	settings     *entitySettings[T]  // Default scopes and other per-entity behaviour from the factory
+	pin          *primaryPin         // Keeps reads on the primary after writes when replicas are configured
+	savepoints   []savepoint         // Open savepoints of the current transaction, oldest first
+	readOnly     bool                // Transactions are opened READ ONLY, on a replica when one is configured
+	cacheWrites  *cacheWrites        // Tables to invalidate in the second-level cache on commit
+	leak         *leakWatch          // Reports the unit of work if it is never closed
+	txLeak       *leakWatch          // Reports the open transaction if it is never finished
+	queries      *queryTrace         // N+1 detection scope when the config enables it
+	shared       *TransactionManager // Begins and ends the transaction of a view bound with UnitOfWorkIn
+
+	pendingChanges []domain.Change[T]          // Changes reported to listeners on commit
+	afterCommit    []func(ctx context.Context) // Hooks run on commit
+	afterRollback  []func(ctx context.Context) // Hooks run on rollback
 }
 
 // NewUnitOfWork creates a new PostgreSQL unit of work
 func NewUnitOfWork[T domain.BaseModel](config *Config) (*UnitOfWork[T], error) {
-	db, err := gorm.Open(postgres.Open(config.DSN()), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
+	db, err := Connect(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	return &UnitOfWork[T]{
+	uow := newUnitOfWork[T](config, db)
+	uow.ownsDB = true
+	return uow, nil
+}
+
+// NewUnitOfWorkFromDB creates a unit of work over an externally managed pool, such as an
+// application-wide pool or SQLite in tests; Close leaves it open
+func NewUnitOfWorkFromDB[T domain.BaseModel](db *gorm.DB) *UnitOfWork[T] {
+	return newUnitOfWork[T](nil, db)
+}
+
+// newUnitOfWork builds a unit of work over an existing connection pool
+func newUnitOfWork[T domain.BaseModel](config *Config, db *gorm.DB) *UnitOfWork[T] {
+	if config != nil && config.Masking != nil {
+		config.Masking.RegisterWith(new(T), namerOf(db))
+	}
+
+	uow := &UnitOfWork[T]{
+		config:       config,
 		db:           db,
 		ctx:          context.Background(),
 		repositories: make(map[string]interface{}),
-	}, nil
+	}
+	if replicaRouterOf(db) != nil {
+		uow.pin = &primaryPin{}
+	}
+	if config != nil && config.Cache != nil {
+		uow.cacheWrites = &cacheWrites{}
+	}
+	if config != nil && config.NPlusOne != nil {
+		uow.queries = &queryTrace{}
+	}
+	uow.leak = watchLeak(config, LeakUnitOfWork, entityName[T]())
+	return uow
 }
 
-// BeginTransaction starts a new database transaction
+// BeginTransaction starts a new database transaction with the options of Config.TxOptions
 func (uow *UnitOfWork[T]) BeginTransaction(ctx context.Context) error {
+	return uow.BeginTransactionWithOptions(ctx, nil)
+}
+
+// BeginReadOnlyTransaction starts a READ ONLY transaction for query-only flows; with replicas
+// configured it runs on one, unless the context is pinned to the primary
+func (uow *UnitOfWork[T]) BeginReadOnlyTransaction(ctx context.Context) error {
+	options := uow.txOptions(nil)
+	options.ReadOnly = true
+	return uow.BeginTransactionWithOptions(ctx, &options)
+}
+
+// BeginTransactionWithOptions starts a new database transaction with an isolation level such as
+// sql.LevelRepeatableRead or sql.LevelSerializable; nil options use Config.TxOptions.
+// Read-only units of work always open READ ONLY transactions
+func (uow *UnitOfWork[T]) BeginTransactionWithOptions(ctx context.Context, options *sql.TxOptions) error {
 	if uow.inTx {
 		return fmt.Errorf("transaction already in progress")
 	}
+	if uow.shared != nil {
+		return fmt.Errorf("%w: begin the shared transaction through its TransactionManager", uowerrors.ErrTransactionNotStarted)
+	}
 
-	tx := uow.db.WithContext(ctx).Begin(&sql.TxOptions{
-		Isolation: sql.LevelReadCommitted,
-		ReadOnly:  false,
-	})
+	txOptions := uow.txOptions(options)
+	db := uow.db
+	if router := replicaRouterOf(db); router != nil && txOptions.ReadOnly {
+		if replica := router.replica(ctx); replica != nil {
+			if pin := pinOf(uow.pinned(ctx)); pin == nil || !pin.holds(ctx, replica) {
+				db = replica
+			}
+		}
+	}
+
+	tx := db.WithContext(ctx).Begin(&txOptions)
 
 	if tx.Error != nil {
 		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
@@ -60,14 +138,30 @@ func (uow *UnitOfWork[T]) BeginTransaction(ctx context.Context) error {
 	uow.tx = tx
 	uow.ctx = ctx
 	uow.inTx = true
+	uow.txLeak = watchLeak(uow.config, LeakTransaction, entityName[T]())
 	return nil
 }
 
+// txOptions resolves the options of a transaction: the given ones, else Config.TxOptions, else READ COMMITTED
+func (uow *UnitOfWork[T]) txOptions(options *sql.TxOptions) sql.TxOptions {
+	resolved := sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	if options != nil {
+		resolved = *options
+	} else if uow.config != nil && uow.config.TxOptions != nil {
+		resolved = *uow.config.TxOptions
+	}
+	resolved.ReadOnly = resolved.ReadOnly || uow.readOnly
+	return resolved
+}
+
 // CommitTransaction commits the current transaction
 func (uow *UnitOfWork[T]) CommitTransaction(ctx context.Context) error {
 	if !uow.inTx {
 		return fmt.Errorf("no active transaction to commit")
 	}
+	if uow.shared != nil {
+		return fmt.Errorf("%w: commit the shared transaction through its TransactionManager", uowerrors.ErrTransactionCommitFailed)
+	}
 
 	if err := uow.tx.Commit().Error; err != nil {
 		uow.RollbackTransaction(ctx)
@@ -76,29 +170,151 @@ func (uow *UnitOfWork[T]) CommitTransaction(ctx context.Context) error {
 
 	uow.tx = nil
 	uow.inTx = false
+	uow.savepoints = nil
+	uow.txLeak.stop()
+	if router := replicaRouterOf(uow.db); router != nil {
+		router.committed(uow.pinned(ctx), uow.db)
+	}
+	uow.committed(ctx)
 	return nil
 }
 
-// RollbackTransaction rolls back the current transaction
+// RollbackTransaction rolls back the current transaction, the whole shared one for a TransactionManager view
 func (uow *UnitOfWork[T]) RollbackTransaction(ctx context.Context) {
 	if !uow.inTx || uow.tx == nil {
 		return
 	}
+	if uow.shared != nil {
+		uow.shared.Rollback(ctx)
+		return
+	}
 
 	uow.tx.Rollback()
 	uow.tx = nil
 	uow.inTx = false
+	uow.savepoints = nil
+	uow.txLeak.stop()
+	uow.rolledBack(ctx)
+}
+
+// Transaction runs fn in a transaction, committing when it returns nil and rolling back when it
+// returns an error or panics; the panic is re-raised after the rollback. Called inside an open
+// transaction, fn runs under a savepoint instead and only its own work is undone on failure.
+// With Config.TransactionRetry set, serialization failures and deadlocks run fn again in a new
+// transaction, see TransactionWithRetry
+func (uow *UnitOfWork[T]) Transaction(ctx context.Context, fn func(txUow persistence.IUnitOfWork[T]) error) error {
+	if uow.inTx {
+		return uow.nestedTransaction(ctx, fn)
+	}
+	if uow.config != nil && uow.config.TransactionRetry != nil {
+		return uow.TransactionWithRetry(ctx, *uow.config.TransactionRetry, fn)
+	}
+	return uow.transaction(ctx, fn)
+}
+
+// transaction runs fn in a new transaction
+func (uow *UnitOfWork[T]) transaction(ctx context.Context, fn func(txUow persistence.IUnitOfWork[T]) error) error {
+	if err := uow.BeginTransaction(ctx); err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			uow.RollbackTransaction(ctx)
+		}
+	}()
+
+	if err := fn(uow); err != nil {
+		return err
+	}
+	committed = true
+	return uow.CommitTransaction(ctx)
+}
+
+// nestedTransaction runs fn under a savepoint of the open transaction
+func (uow *UnitOfWork[T]) nestedTransaction(ctx context.Context, fn func(txUow persistence.IUnitOfWork[T]) error) error {
+	name := fmt.Sprintf("uow_tx_%d", len(uow.savepoints)+1)
+	if err := uow.Savepoint(ctx, name); err != nil {
+		return err
+	}
+
+	released := false
+	defer func() {
+		if !released {
+			_ = uow.RollbackTo(ctx, name)
+			_ = uow.ReleaseSavepoint(ctx, name)
+		}
+	}()
+
+	if err := fn(uow); err != nil {
+		return err
+	}
+	released = true
+	return uow.ReleaseSavepoint(ctx, name)
+}
+
+// AsRole switches the database role for the rest of the current transaction
+// Issues SET LOCAL ROLE so least-privilege roles can be used per operation type;
+// an empty role reverts to the session role
+func (uow *UnitOfWork[T]) AsRole(ctx context.Context, role string) error {
+	if !uow.inTx || uow.tx == nil {
+		return fmt.Errorf("failed to set role: %w", uowerrors.ErrTransactionNotStarted)
+	}
+
+	statement := "SET LOCAL ROLE NONE"
+	if role != "" {
+		statement = "SET LOCAL ROLE " + quoteIdentifier(role)
+	}
+
+	if err := uow.tx.WithContext(ctx).Exec(statement).Error; err != nil {
+		return fmt.Errorf("failed to set role %q: %w", role, err)
+	}
+
+	return nil
+}
+
+// DeferConstraints defers checking of the named constraints, or of all deferrable ones when no names
+// are given, until the current transaction commits; lets cyclic rows (user <-> primary_address) be inserted
+// in any order. Constraints must be declared DEFERRABLE; names may be schema-qualified
+func (uow *UnitOfWork[T]) DeferConstraints(ctx context.Context, names ...string) error {
+	if !uow.inTx || uow.tx == nil {
+		return fmt.Errorf("failed to defer constraints: %w", uowerrors.ErrTransactionNotStarted)
+	}
+
+	if err := uow.tx.WithContext(ctx).Exec(deferConstraintsStatement(names)).Error; err != nil {
+		return fmt.Errorf("failed to defer constraints: %w", err)
+	}
+
+	return nil
+}
+
+// deferConstraintsStatement builds SET CONSTRAINTS ... DEFERRED with quoted, optionally schema-qualified names
+func deferConstraintsStatement(names []string) string {
+	if len(names) == 0 {
+		return "SET CONSTRAINTS ALL DEFERRED"
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		parts := strings.Split(name, ".")
+		for j, part := range parts {
+			parts[j] = quoteIdentifier(part)
+		}
+		quoted[i] = strings.Join(parts, ".")
+	}
+	return "SET CONSTRAINTS " + strings.Join(quoted, ", ") + " DEFERRED"
 }
 
 // FindAll retrieves all entities of type T
 func (uow *UnitOfWork[T]) FindAll(ctx context.Context) ([]T, error) {
 	var entities []T
-	db := uow.getActiveDB()
+	db := uow.getActiveDB(ctx)
 
 	if err := db.Find(&entities).Error; err != nil {
 		return nil, fmt.Errorf("failed to find all entities: %w", err)
 	}
 
+	uow.maskResults(ctx, entities...)
 	return entities, nil
 }
 
@@ -107,11 +323,19 @@ func (uow *UnitOfWork[T]) FindAllWithPagination(ctx context.Context, query domai
 	var entities []T
 	var total int64
 
-	db := uow.getActiveDB()
+	db, done, err := uow.hintedDB(ctx, query.Hints, query.TxOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	if db, err = trashScope[T](db, query); err != nil {
+		return nil, 0, err
+	}
 
 	// Apply filters if provided
-	if !reflect.ValueOf(query.Filter).IsZero() {
-		db = db.Where(query.Filter)
+	if conditions, args := uow.metadata().filterConditions(query.Filter, false); conditions != "" {
+		db = db.Where(conditions, args...)
 	}
 
 	// Count total records
@@ -138,55 +362,335 @@ func (uow *UnitOfWork[T]) FindAllWithPagination(ctx context.Context, query domai
 	for _, include := range query.Include {
 		db = db.Preload(include)
 	}
+	if db, err = withCounts[T](db, query.Counts); err != nil {
+		return nil, 0, err
+	}
 
 	if err := db.Find(&entities).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to find entities with pagination: %w", err)
 	}
 
+	uow.maskResults(ctx, entities...)
 	return entities, uint(total), nil
 }
 
+// FindAllByIdentifier retrieves the entities matching an identifier with pagination
+// The query supplies sorting, pagination and includes; its filter is combined with the identifier
+func (uow *UnitOfWork[T]) FindAllByIdentifier(ctx context.Context, identifier identifier.IIdentifier, query domain.QueryParams[T]) ([]T, uint, error) {
+	var entities []T
+	var total int64
+
+	db, done, err := uow.hintedDB(ctx, query.Hints, query.TxOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	if db, err = trashScope[T](db, query); err != nil {
+		return nil, 0, err
+	}
+	db = applyEntityIdentifier[T](db, identifier)
+	if conditions, args := uow.metadata().filterConditions(query.Filter, false); conditions != "" {
+		db = db.Where(conditions, args...)
+	}
+
+	if err := db.Model(new(T)).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count entities: %w", err)
+	}
+
+	for field, direction := range query.Sort {
+		db = db.Order(fmt.Sprintf("%s %s", field, direction))
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+	for _, include := range query.Include {
+		db = db.Preload(include)
+	}
+	if db, err = withCounts[T](db, query.Counts); err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.Find(&entities).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find entities by identifier: %w", err)
+	}
+
+	uow.maskResults(ctx, entities...)
+	return entities, uint(total), nil
+}
+
+// trashScope widens a query to soft-deleted rows when it asks for them
+func trashScope[T domain.BaseModel](db *gorm.DB, query domain.QueryParams[T]) (*gorm.DB, error) {
+	switch {
+	case query.OnlyTrashed:
+		deletedAt, ok := metadataIn[T](db).Field("deleted_at")
+		if !ok {
+			return nil, fmt.Errorf("%w: %s has no deleted_at column", uowerrors.ErrInvalidQueryParams, entityName[T]())
+		}
+		return db.Unscoped().Where(deletedAt.Qualified + " IS NOT NULL"), nil
+	case query.IncludeTrashed:
+		return db.Unscoped(), nil
+	}
+	return db, nil
+}
+
+// FindPage is FindAllWithPagination returning a domain.Page
+func (uow *UnitOfWork[T]) FindPage(ctx context.Context, query domain.QueryParams[T]) (domain.Page[T], error) {
+	entities, total, err := uow.FindAllWithPagination(ctx, query)
+	if err != nil {
+		return domain.Page[T]{}, err
+	}
+	return domain.NewPage(entities, total, query), nil
+}
+
+// FindPageByIdentifier is FindAllByIdentifier returning a domain.Page
+func (uow *UnitOfWork[T]) FindPageByIdentifier(ctx context.Context, identifier identifier.IIdentifier, query domain.QueryParams[T]) (domain.Page[T], error) {
+	entities, total, err := uow.FindAllByIdentifier(ctx, identifier, query)
+	if err != nil {
+		return domain.Page[T]{}, err
+	}
+	return domain.NewPage(entities, total, query), nil
+}
+
+// FindPageWithKeyset is FindAllWithKeyset returning a domain.Page whose NextCursor continues it
+func (uow *UnitOfWork[T]) FindPageWithKeyset(ctx context.Context, query domain.KeysetParams[T]) (domain.Page[T], error) {
+	query.Validate()
+	page, err := uow.FindAllWithKeyset(ctx, query)
+	if err != nil {
+		return domain.Page[T]{}, err
+	}
+	return domain.NewKeysetPage(page, query), nil
+}
+
+// FindAllWithKeyset retrieves a page of entities positioned by cursors instead of offsets
+// Fetches one extra row to report whether more rows exist in the paging direction
+func (uow *UnitOfWork[T]) FindAllWithKeyset(ctx context.Context, query domain.KeysetParams[T]) (domain.KeysetPage[T], error) {
+	var page domain.KeysetPage[T]
+	query.Validate()
+
+	meta := uow.metadata()
+	sortField, ok := meta.Field(query.SortField)
+	if !ok {
+		return page, fmt.Errorf("%w: unknown keyset sort field %q", uowerrors.ErrInvalidQueryParams, query.SortField)
+	}
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return page, fmt.Errorf("%w: keyset pagination requires a primary key", uowerrors.ErrInvalidQueryParams)
+	}
+
+	db := uow.getActiveDB(ctx)
+	if conditions, args := meta.filterConditions(query.Filter, false); conditions != "" {
+		db = db.Where(conditions, args...)
+	}
+
+	// Cursors compare the (sort column, primary key) row value, or the primary key alone
+	keys, placeholder := primaryKey.Qualified, "?"
+	cursorArgs := func(cursor *domain.Cursor) []interface{} {
+		return []interface{}{cursor.ID}
+	}
+	if sortField.Column != primaryKey.Column {
+		keys, placeholder = "("+sortField.Qualified+", "+primaryKey.Qualified+")", "(?, ?)"
+		cursorArgs = func(cursor *domain.Cursor) []interface{} {
+			return []interface{}{sortField.convert(meta.Type, cursor.Value), cursor.ID}
+		}
+	}
+
+	after, before := ">", "<"
+	if query.Direction == domain.SortDesc {
+		after, before = "<", ">"
+	}
+	if query.After != nil {
+		db = db.Where(keys+" "+after+" "+placeholder, cursorArgs(query.After)...)
+	}
+	if query.Before != nil {
+		db = db.Where(keys+" "+before+" "+placeholder, cursorArgs(query.Before)...)
+	}
+
+	direction := query.Direction
+	if query.Backward {
+		direction = domain.SortAsc
+		if query.Direction == domain.SortAsc {
+			direction = domain.SortDesc
+		}
+	}
+	if sortField.Column != primaryKey.Column {
+		db = db.Order(sortField.Qualified + " " + string(direction))
+	}
+	db = db.Order(primaryKey.Qualified + " " + string(direction))
+
+	var entities []T
+	if err := db.Limit(query.Limit + 1).Find(&entities).Error; err != nil {
+		return page, fmt.Errorf("failed to find entities with keyset: %w", err)
+	}
+
+	page = keysetPage(query, entities, meta, sortField, primaryKey)
+	uow.maskResults(ctx, page.Items...)
+	return page, nil
+}
+
+// keysetPage trims the extra row fetched past the limit, restores the requested order and sets the cursors
+func keysetPage[T domain.BaseModel](query domain.KeysetParams[T], entities []T, meta *modelMetadata, sortField, primaryKey fieldMetadata) domain.KeysetPage[T] {
+	var page domain.KeysetPage[T]
+	more := len(entities) > query.Limit
+	if more {
+		entities = entities[:query.Limit]
+	}
+	if query.Backward {
+		for i, j := 0, len(entities)-1; i < j; i, j = i+1, j-1 {
+			entities[i], entities[j] = entities[j], entities[i]
+		}
+		page.HasPrevious = more
+		page.HasNext = query.Before != nil
+	} else {
+		page.HasNext = more
+		page.HasPrevious = query.After != nil
+	}
+
+	page.Items = entities
+	page.Cursors = make([]domain.Cursor, len(entities))
+	for i, entity := range entities {
+		page.Cursors[i] = domain.Cursor{ID: entity.GetID()}
+		if sortField.Column != primaryKey.Column {
+			if v, ok := meta.structValue(entity); ok {
+				page.Cursors[i].Value = v.FieldByIndex(sortField.Index).Interface()
+			}
+		}
+	}
+
+	return page
+}
+
 // FindOne retrieves a single entity by filter
-func (uow *UnitOfWork[T]) FindOne(ctx context.Context, filter T) (T, error) {
-	var entity T
-	db := uow.getActiveDB()
+func (uow *UnitOfWork[T]) FindOne(ctx context.Context, filter T, options ...domain.FindOption) (T, error) {
+	load := func() (T, error) {
+		var entity T
+		db, err := uow.findOneDB(ctx, options)
+		if err != nil {
+			return entity, err
+		}
+		if err := db.Where(filter).First(&entity).Error; err != nil {
+			return entity, fmt.Errorf("failed to find entity: %w", err)
+		}
+		return entity, nil
+	}
 
-	if err := db.Where(filter).First(&entity).Error; err != nil {
-		return entity, fmt.Errorf("failed to find entity: %w", err)
+	var entity T
+	var err error
+	if policy, cached := uow.cachePolicyOf(ctx); cached && len(options) == 0 {
+		if column, value, keyed := cacheKeyOf(uow.metadata(), policy, filter); keyed {
+			entity, err = uow.cachedFind(ctx, policy, column, value, load)
+		} else {
+			entity, err = load()
+		}
+	} else {
+		entity, err = load()
+	}
+	if err != nil {
+		return entity, err
 	}
 
+	uow.maskResults(ctx, entity)
 	return entity, nil
 }
 
 // FindOneById retrieves a single entity by ID
-func (uow *UnitOfWork[T]) FindOneById(ctx context.Context, id int) (T, error) {
-	var entity T
-	db := uow.getActiveDB()
+func (uow *UnitOfWork[T]) FindOneById(ctx context.Context, id int, options ...domain.FindOption) (T, error) {
+	load := func() (T, error) {
+		var entity T
+		db, err := uow.findOneDB(ctx, options)
+		if err != nil {
+			return entity, err
+		}
+		if err := db.First(&entity, id).Error; err != nil {
+			return entity, fmt.Errorf("failed to find entity by id: %w", err)
+		}
+		return entity, nil
+	}
 
-	if err := db.First(&entity, id).Error; err != nil {
-		return entity, fmt.Errorf("failed to find entity by id: %w", err)
+	var entity T
+	var err error
+	if policy, cached := uow.cachePolicyOf(ctx); cached && len(options) == 0 {
+		entity, err = uow.cachedFind(ctx, policy, "id", id, load)
+	} else {
+		entity, err = load()
+	}
+	if err != nil {
+		return entity, err
 	}
 
+	uow.maskResults(ctx, entity)
 	return entity, nil
 }
 
+// findOneDB applies single-entity lookup options
+// Selected fields must exist on the entity; relations are validated by GORM when the query runs
+func (uow *UnitOfWork[T]) findOneDB(ctx context.Context, options []domain.FindOption) (*gorm.DB, error) {
+	db := uow.getActiveDB(ctx)
+	find := domain.ApplyFindOptions(options...)
+
+	if len(find.Select) > 0 {
+		meta := uow.metadata()
+		columns := make([]string, 0, len(find.Select)+1)
+		if primaryKey, ok := meta.primaryKey(); ok {
+			columns = append(columns, primaryKey.Qualified)
+		}
+		for _, field := range find.Select {
+			column, ok := meta.Field(field)
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown select field %q", uowerrors.ErrInvalidQueryParams, field)
+			}
+			if !column.PrimaryKey {
+				columns = append(columns, column.Qualified)
+			}
+		}
+		db = db.Select(columns)
+	}
+	for _, relation := range find.Preload {
+		db = db.Preload(relation)
+	}
+	return db, nil
+}
+
+// FindByIDs retrieves the entities with the given IDs in a single query
+// Missing IDs are skipped; the result order is not guaranteed
+func (uow *UnitOfWork[T]) FindByIDs(ctx context.Context, ids []int) ([]T, error) {
+	var entities []T
+	if len(ids) == 0 {
+		return entities, nil
+	}
+
+	db := uow.getActiveDB(ctx)
+	if err := db.Where("id IN ?", ids).Find(&entities).Error; err != nil {
+		return nil, fmt.Errorf("failed to find entities by ids: %w", err)
+	}
+
+	uow.maskResults(ctx, entities...)
+	return entities, nil
+}
+
 // FindOneByIdentifier retrieves a single entity by identifier
-func (uow *UnitOfWork[T]) FindOneByIdentifier(ctx context.Context, identifier identifier.IIdentifier) (T, error) {
+func (uow *UnitOfWork[T]) FindOneByIdentifier(ctx context.Context, identifier identifier.IIdentifier, options ...domain.FindOption) (T, error) {
 	var entity T
-	db := uow.getActiveDB()
+	db, err := uow.findOneDB(ctx, options)
+	if err != nil {
+		return entity, err
+	}
 
-	queryMap := identifier.ToMap()
-	if err := db.Where(queryMap).First(&entity).Error; err != nil {
+	if err := applyEntityIdentifier[T](db, identifier).First(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to find entity by identifier: %w", err)
 	}
 
+	uow.maskResults(ctx, entity)
 	return entity, nil
 }
 
 // ResolveIDByUniqueField resolves an ID by a unique field
 func (uow *UnitOfWork[T]) ResolveIDByUniqueField(ctx context.Context, model domain.BaseModel, field string, value interface{}) (int, error) {
 	var entity T
-	db := uow.getActiveDB()
+	db := uow.getActiveDB(ctx)
 
 	if err := db.Where(field+" = ?", value).First(&entity).Error; err != nil {
 		return 0, fmt.Errorf("failed to resolve ID by unique field: %w", err)
@@ -195,118 +699,848 @@ func (uow *UnitOfWork[T]) ResolveIDByUniqueField(ctx context.Context, model doma
 	return entity.GetID(), nil
 }
 
+// ResolveIDByUniqueFields resolves an ID by a natural key spanning several columns, e.g. tenant_id + slug
+// Fields are column or Go field names; unknown fields fail with errors.ErrInvalidQueryParams
+func (uow *UnitOfWork[T]) ResolveIDByUniqueFields(ctx context.Context, fields map[string]interface{}) (int, error) {
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%w: no unique fields given", uowerrors.ErrInvalidQueryParams)
+	}
+
+	meta := uow.metadata()
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	db := uow.getActiveDB(ctx)
+	for _, name := range names {
+		column, ok := meta.Field(name)
+		if !ok {
+			return 0, fmt.Errorf("%w: unknown field %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		if fields[name] == nil {
+			db = db.Where(column.Qualified + " IS NULL")
+			continue
+		}
+		db = db.Where(column.Qualified+" = ?", fields[name])
+	}
+	return uow.resolveID(db, fmt.Sprint(names))
+}
+
+// resolveBatchSize caps the values bound per query, well below PostgreSQL's 65535 parameter limit
+const resolveBatchSize = 30000
+
+// ResolveIDsByUniqueField maps many values of a unique field to entity IDs in one query per 30000 values
+// The map is keyed by the given values; values without an entity are absent. A value matching
+// several entities, an unknown field or an unhashable value fails with errors.ErrInvalidQueryParams
+func (uow *UnitOfWork[T]) ResolveIDsByUniqueField(ctx context.Context, field string, values []interface{}) (map[interface{}]int, error) {
+	meta := uow.metadata()
+	column, ok := meta.Field(field)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown field %q", uowerrors.ErrInvalidQueryParams, field)
+	}
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return nil, fmt.Errorf("%w: entity has no primary key", uowerrors.ErrInvalidQueryParams)
+	}
+
+	// Database values come back as the field's Go type, so both sides are matched by their printed form
+	requested := make(map[string][]interface{}, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		if !reflect.TypeOf(value).Comparable() {
+			return nil, fmt.Errorf("%w: value of type %T cannot be a map key", uowerrors.ErrInvalidQueryParams, value)
+		}
+		key := fmt.Sprint(value)
+		requested[key] = append(requested[key], value)
+	}
+
+	keys := make([]string, 0, len(requested))
+	for key := range requested {
+		keys = append(keys, key)
+	}
+
+	ids := make(map[interface{}]int, len(values))
+	for start := 0; start < len(keys); start += resolveBatchSize {
+		end := min(start+resolveBatchSize, len(keys))
+		batch := make([]interface{}, 0, end-start)
+		for _, key := range keys[start:end] {
+			batch = append(batch, requested[key][0])
+		}
+
+		var entities []T
+		err := uow.getActiveDB(ctx).
+			Select(primaryKey.Qualified, column.Qualified).
+			Where(column.Qualified+" IN ?", batch).
+			Find(&entities).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve IDs by %s: %w", field, err)
+		}
+
+		for _, entity := range entities {
+			value, _ := meta.structValue(entity)
+			key := fmt.Sprint(value.FieldByIndex(column.Index).Interface())
+			for _, original := range requested[key] {
+				if id, seen := ids[original]; seen && id != entity.GetID() {
+					return nil, fmt.Errorf("%w: %s = %v matches more than one entity", uowerrors.ErrInvalidQueryParams, field, original)
+				}
+				ids[original] = entity.GetID()
+			}
+		}
+	}
+	return ids, nil
+}
+
+// IsUnique reports whether no live entity other than excludingID (0 for none) holds value in field
+// Soft-deleted rows are ignored, as are rows outside the field's partial unique index, so the answer
+// matches what the constraint will enforce on insert
+func (uow *UnitOfWork[T]) IsUnique(ctx context.Context, field string, value interface{}, excludingID int) (bool, error) {
+	meta := uow.metadata()
+	column, ok := meta.Field(field)
+	if !ok {
+		return false, fmt.Errorf("%w: unknown field %q", uowerrors.ErrInvalidQueryParams, field)
+	}
+	primaryKey, ok := meta.primaryKey()
+	if !ok {
+		return false, fmt.Errorf("%w: entity has no primary key", uowerrors.ErrInvalidQueryParams)
+	}
+	// NULLs never collide in a unique index
+	if value == nil {
+		return true, nil
+	}
+
+	db := uow.getActiveDB(ctx).Model(newEntity[T]()).Where(column.Qualified+" = ?", value)
+	if excludingID != 0 {
+		db = db.Where(primaryKey.Qualified+" <> ?", excludingID)
+	}
+	if column.UniqueIf != "" {
+		db = db.Where(column.UniqueIf)
+	}
+
+	var found int
+	if err := db.Select("1").Limit(1).Scan(&found).Error; err != nil {
+		return false, fmt.Errorf("failed to check uniqueness of %s: %w", field, err)
+	}
+	return found == 0, nil
+}
+
+// ResolveIDByIdentifier resolves the ID of the single entity matching an identifier
+func (uow *UnitOfWork[T]) ResolveIDByIdentifier(ctx context.Context, identifier identifier.IIdentifier) (int, error) {
+	if identifier == nil || identifier.IsEmpty() {
+		return 0, fmt.Errorf("%w: empty identifier", uowerrors.ErrInvalidQueryParams)
+	}
+	return uow.resolveID(applyEntityIdentifier[T](uow.getActiveDB(ctx), identifier), identifier.String())
+}
+
+// resolveID reads the primary key of the only row matched by db
+// No match fails with errors.ErrEntityNotFound; several matches mean the key is not unique
+func (uow *UnitOfWork[T]) resolveID(db *gorm.DB, key string) (int, error) {
+	primaryKey, ok := uow.metadata().primaryKey()
+	if !ok {
+		return 0, fmt.Errorf("%w: entity has no primary key", uowerrors.ErrInvalidQueryParams)
+	}
+
+	var ids []int
+	if err := db.Model(newEntity[T]()).Limit(2).Pluck(primaryKey.Qualified, &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to resolve ID by %s: %w", key, err)
+	}
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("failed to resolve ID by %s: %w", key, uowerrors.ErrEntityNotFound)
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, fmt.Errorf("%w: %s matches more than one entity", uowerrors.ErrInvalidQueryParams, key)
+	}
+}
+
 // Insert creates a new entity
+// Like every write, it first checks the entity's validate tags and fails with a *ValidationError
 func (uow *UnitOfWork[T]) Insert(ctx context.Context, entity T) (T, error) {
-	db := uow.getActiveDB()
+	if err := uow.assignIDs(ctx, entity); err != nil {
+		return entity, err
+	}
+	if err := uow.validate(false, entity); err != nil {
+		return entity, err
+	}
+	if retries := uow.settings.slugRetryLimit(); retries > 0 && entity.GetSlug() != "" {
+		return uow.insertWithSlugRetry(ctx, entity, retries)
+	}
+
+	db := uow.getActiveDB(ctx)
+
+	if err := db.Create(&entity).Error; err != nil {
+		return entity, fmt.Errorf("failed to insert entity: %w", err)
+	}
 
+	uow.recordChanges(ctx, domain.ChangeCreated, entity)
+	return entity, nil
+}
+
+// InsertReturning inserts an entity and reads back only the given columns, e.g. database-computed defaults
+// Without columns only the primary key is returned. Associations are not saved and slug retries do not apply;
+// use it on hot ingestion paths where Insert's full write-back is not needed
+func (uow *UnitOfWork[T]) InsertReturning(ctx context.Context, entity T, columns ...string) (T, error) {
+	meta := uow.metadata()
+	returning := make([]clause.Column, 0, len(columns)+1)
+	if len(columns) == 0 {
+		primaryKey, ok := meta.primaryKey()
+		if !ok {
+			return entity, fmt.Errorf("%w: entity has no primary key", uowerrors.ErrInvalidQueryParams)
+		}
+		columns = []string{primaryKey.Column}
+	}
+	for _, name := range columns {
+		field, ok := meta.Field(name)
+		if !ok {
+			return entity, fmt.Errorf("%w: unknown returning column %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		returning = append(returning, clause.Column{Name: field.Column})
+	}
+
+	if err := uow.assignIDs(ctx, entity); err != nil {
+		return entity, err
+	}
+	if err := uow.validate(false, entity); err != nil {
+		return entity, err
+	}
+	db := uow.getActiveDB(ctx).Omit(clause.Associations).Clauses(clause.Returning{Columns: returning})
 	if err := db.Create(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to insert entity: %w", err)
 	}
 
+	uow.recordChanges(ctx, domain.ChangeCreated, entity)
 	return entity, nil
 }
 
+// Clone copies the entity matching the identifier and inserts the copy, inside the current transaction if any
+// The copy gets a new ID, fresh timestamps and, when the entity has a slug, a free "<slug>-copy[-n]" slug.
+// Mutators run on the copy before it is inserted; associations are not copied.
+func (uow *UnitOfWork[T]) Clone(ctx context.Context, identifier identifier.IIdentifier, mutators ...func(T)) (T, error) {
+	var source T
+	db := uow.getActiveDB(ctx)
+	if err := applyEntityIdentifier[T](db, identifier).First(&source).Error; err != nil {
+		return source, fmt.Errorf("failed to find entity to clone: %w", err)
+	}
+
+	copied := cloneEntity(source)
+	meta := uow.metadata()
+	if v, ok := meta.structValue(copied); ok {
+		for _, field := range meta.Fields {
+			switch {
+			case field.PrimaryKey, field.Column == "created_at", field.Column == "updated_at", field.Column == "deleted_at":
+				target := v.FieldByIndex(field.Index)
+				target.Set(reflect.Zero(target.Type()))
+			}
+		}
+	}
+
+	if slug := source.GetSlug(); slug != "" {
+		free, err := uow.copySlug(ctx, slug)
+		if err != nil {
+			return source, err
+		}
+		copied.SetSlug(free)
+	}
+	for _, mutate := range mutators {
+		mutate(copied)
+	}
+	if err := uow.assignIDs(ctx, copied); err != nil {
+		return copied, err
+	}
+	if err := uow.validate(false, copied); err != nil {
+		return copied, err
+	}
+
+	if err := uow.getActiveDB(ctx).Omit(clause.Associations).Create(copied).Error; err != nil {
+		return copied, fmt.Errorf("failed to insert clone: %w", err)
+	}
+
+	uow.recordChanges(ctx, domain.ChangeCreated, copied)
+	uow.maskResults(ctx, copied)
+	return copied, nil
+}
+
+// copySlug returns the first of "<slug>-copy", "<slug>-copy-2", ... not used by any row, trashed ones included
+func (uow *UnitOfWork[T]) copySlug(ctx context.Context, slug string) (string, error) {
+	base := slug + "-copy"
+	if _, ok := uow.metadata().Field("slug"); !ok {
+		return base, nil
+	}
+
+	var taken []string
+	err := uow.getActiveDB(ctx).Unscoped().Model(newEntity[T]()).
+		Where("slug = ? OR slug LIKE ?", base, base+"-%").
+		Pluck("slug", &taken).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free slug: %w", err)
+	}
+
+	used := make(map[string]bool, len(taken))
+	for _, s := range taken {
+		used[s] = true
+	}
+	candidate := base
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+	return candidate, nil
+}
+
 // Update updates an existing entity
+// Validate tags are checked on the non-zero fields only, since GORM leaves zero fields unwritten
+// Uses UPDATE ... RETURNING * where the dialect supports it, otherwise updates and re-reads the row
+// When the factory has a state machine, a changed state must be an allowed transition (see StateMachine)
 func (uow *UnitOfWork[T]) Update(ctx context.Context, identifier identifier.IIdentifier, entity T) (T, error) {
-	db := uow.getActiveDB()
+	if err := uow.validate(true, entity); err != nil {
+		return entity, err
+	}
+	if machine := uow.settings.stateMachineOf(); machine != nil {
+		if to, changed := machine.state(entity); changed {
+			return uow.updateWithTransition(ctx, identifier, entity, machine, to)
+		}
+	}
+	return uow.update(ctx, identifier, entity)
+}
+
+func (uow *UnitOfWork[T]) update(ctx context.Context, identifier identifier.IIdentifier, entity T) (T, error) {
+	db := uow.getActiveDB(ctx)
+
+	if supportsReturning(db) {
+		updatedEntity := cloneEntity(entity)
+		result := applyEntityIdentifier[T](db, identifier).Clauses(clause.Returning{}).Updates(&updatedEntity)
+		if result.Error != nil {
+			return entity, fmt.Errorf("failed to update entity: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return entity, fmt.Errorf("failed to retrieve updated entity: %w", gorm.ErrRecordNotFound)
+		}
 
-	queryMap := identifier.ToMap()
-	if err := db.Where(queryMap).Updates(&entity).Error; err != nil {
+		uow.recordChanges(ctx, domain.ChangeUpdated, updatedEntity)
+		uow.maskResults(ctx, updatedEntity)
+		return updatedEntity, nil
+	}
+
+	if err := applyEntityIdentifier[T](db, identifier).Updates(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to update entity: %w", err)
 	}
 
 	// Retrieve the updated entity
 	var updatedEntity T
-	if err := db.Where(queryMap).First(&updatedEntity).Error; err != nil {
+	if err := applyEntityIdentifier[T](db, identifier).First(&updatedEntity).Error; err != nil {
 		return entity, fmt.Errorf("failed to retrieve updated entity: %w", err)
 	}
 
+	uow.recordChanges(ctx, domain.ChangeUpdated, updatedEntity)
+	uow.maskResults(ctx, updatedEntity)
 	return updatedEntity, nil
 }
 
 // Delete removes an entity (hard delete)
 func (uow *UnitOfWork[T]) Delete(ctx context.Context, identifier identifier.IIdentifier) error {
-	db := uow.getActiveDB()
+	db, err := uow.scopedMutation(uow.getActiveDB(ctx).Unscoped(), "delete", identifier)
+	if err != nil {
+		return err
+	}
 
-	queryMap := identifier.ToMap()
-	if err := db.Unscoped().Where(queryMap).Delete(new(T)).Error; err != nil {
+	if err := db.Delete(new(T)).Error; err != nil {
 		return fmt.Errorf("failed to delete entity: %w", err)
 	}
 
 	return nil
 }
 
+// UpdateWhere applies column updates to every entity matching the identifier
+// Returns the number of affected rows; build CASE and column-to-column assignments with NewUpdate
+func (uow *UnitOfWork[T]) UpdateWhere(ctx context.Context, identifier identifier.IIdentifier, updates map[string]interface{}) (int64, error) {
+	db, err := uow.scopedMutation(uow.getActiveDB(ctx).Model(newEntity[T]()), "update where", identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Updates(updates)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to update entities: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Touch sets timestamps on the matching entities to now in one statement and returns the affected row count
+// Without columns it bumps updated_at; with columns (e.g. "last_seen_at") it sets only those and
+// leaves updated_at alone, so activity tracking does not look like a content change
+func (uow *UnitOfWork[T]) Touch(ctx context.Context, identifier identifier.IIdentifier, columns ...string) (int64, error) {
+	if len(columns) == 0 {
+		columns = []string{"updated_at"}
+	}
+
+	meta := uow.metadata()
+	now := time.Now()
+	updates := make(map[string]interface{}, len(columns))
+	for _, name := range columns {
+		column, ok := meta.Field(name)
+		if !ok {
+			return 0, fmt.Errorf("%w: unknown touch column %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		updates[column.Column] = now
+	}
+
+	db, err := uow.scopedMutation(uow.getActiveDB(ctx).Model(newEntity[T]()), "touch", identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.UpdateColumns(updates)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to touch entities: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 // SoftDelete performs a soft delete on an entity
 func (uow *UnitOfWork[T]) SoftDelete(ctx context.Context, identifier identifier.IIdentifier) (T, error) {
 	var entity T
-	db := uow.getActiveDB()
-
-	queryMap := identifier.ToMap()
+	db := uow.getActiveDB(ctx)
 
 	// First find the entity
-	if err := db.Where(queryMap).First(&entity).Error; err != nil {
+	if err := applyEntityIdentifier[T](db, identifier).First(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to find entity for soft delete: %w", err)
 	}
 
 	// Perform soft delete
-	if err := db.Where(queryMap).Delete(&entity).Error; err != nil {
+	if err := applyEntityIdentifier[T](db, identifier).Delete(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to soft delete entity: %w", err)
 	}
 
+	uow.recordChanges(ctx, domain.ChangeDeleted, entity)
 	return entity, nil
 }
 
+// SoftDeletePreviewSize is how many matching entities a SoftDeleteWhere preview returns
+const SoftDeletePreviewSize = 10
+
+// SoftDeleteWhere soft-deletes every entity matching the identifier in one statement
+// With preview set nothing changes: the result holds the match count and the first
+// SoftDeletePreviewSize matches, so admins can check a mass archive before running it
+func (uow *UnitOfWork[T]) SoftDeleteWhere(ctx context.Context, identifier identifier.IIdentifier, preview bool) (domain.SoftDeleteResult[T], error) {
+	result := domain.SoftDeleteResult[T]{Preview: preview}
+	db, err := uow.scopedMutation(uow.getActiveDB(ctx).Model(newEntity[T]()), "soft delete where", identifier)
+	if err != nil {
+		return result, err
+	}
+
+	if !preview {
+		deleted := db.Delete(newEntity[T]())
+		if deleted.Error != nil {
+			return result, fmt.Errorf("failed to soft delete entities: %w", deleted.Error)
+		}
+		result.Affected = deleted.RowsAffected
+		return result, nil
+	}
+
+	if err := db.Count(&result.Affected).Error; err != nil {
+		return result, fmt.Errorf("failed to count entities to soft delete: %w", err)
+	}
+	sample := db.Limit(SoftDeletePreviewSize)
+	if primaryKey, ok := uow.metadata().primaryKey(); ok {
+		sample = sample.Order(primaryKey.Qualified)
+	}
+	if err := sample.Find(&result.Sample).Error; err != nil {
+		return result, fmt.Errorf("failed to sample entities to soft delete: %w", err)
+	}
+	uow.maskResults(ctx, result.Sample...)
+	return result, nil
+}
+
 // HardDelete performs a hard delete on an entity
 func (uow *UnitOfWork[T]) HardDelete(ctx context.Context, identifier identifier.IIdentifier) (T, error) {
 	var entity T
-	db := uow.getActiveDB()
+	db := uow.getActiveDB(ctx)
 
-	queryMap := identifier.ToMap()
+	if _, err := uow.scopedMutation(db, "hard delete", identifier); err != nil {
+		return entity, err
+	}
 
 	// First find the entity
-	if err := db.Where(queryMap).First(&entity).Error; err != nil {
+	if err := applyEntityIdentifier[T](db, identifier).First(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to find entity for hard delete: %w", err)
 	}
 
 	// Perform hard delete
-	if err := db.Unscoped().Where(queryMap).Delete(&entity).Error; err != nil {
+	if err := applyEntityIdentifier[T](db.Unscoped(), identifier).Delete(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to hard delete entity: %w", err)
 	}
 
+	uow.recordChanges(ctx, domain.ChangeDeleted, entity)
 	return entity, nil
 }
 
+// bulkInsertBatchSize is how many rows one statement of BulkInsert writes
+const bulkInsertBatchSize = 100
+
 // BulkInsert creates multiple entities
+// Under a context deadline the batches are budgeted: when the next one is not expected to finish
+// in time it stops with a *DeadlineBudgetError, returning the entities written so far, which
+// outside a transaction are committed batch by batch
 func (uow *UnitOfWork[T]) BulkInsert(ctx context.Context, entities []T) ([]T, error) {
-	db := uow.getActiveDB()
+	if err := uow.assignIDs(ctx, entities...); err != nil {
+		return nil, err
+	}
+	if err := uow.validate(false, entities...); err != nil {
+		return nil, err
+	}
+	db := uow.getActiveDB(ctx)
 
-	if err := db.CreateInBatches(&entities, 100).Error; err != nil {
-		return nil, fmt.Errorf("failed to bulk insert entities: %w", err)
+	budget := uow.deadlineBudget(ctx, "bulk insert", len(entities))
+	if budget == nil {
+		if err := db.CreateInBatches(&entities, bulkInsertBatchSize).Error; err != nil {
+			return nil, fmt.Errorf("failed to bulk insert entities: %w", err)
+		}
+		uow.recordChanges(ctx, domain.ChangeCreated, entities...)
+		return entities, nil
+	}
+
+	for start := 0; start < len(entities); start += bulkInsertBatchSize {
+		batch := entities[start:min(start+bulkInsertBatchSize, len(entities))]
+		if err := budget.admit(start, len(batch)); err != nil {
+			uow.recordChanges(ctx, domain.ChangeCreated, entities[:start]...)
+			return entities[:start], err
+		}
+		if err := db.Create(&batch).Error; err != nil {
+			uow.recordChanges(ctx, domain.ChangeCreated, entities[:start]...)
+			return nil, fmt.Errorf("failed to bulk insert entities %d-%d: %w", start+1, start+len(batch), err)
+		}
 	}
 
+	uow.recordChanges(ctx, domain.ChangeCreated, entities...)
 	return entities, nil
 }
 
-// BulkUpdate updates multiple entities
+// Upsert inserts an entity or, when a row with the same conflict columns exists, overwrites it
+// Conflict columns default to the primary key; the primary key and created_at are never overwritten
+func (uow *UnitOfWork[T]) Upsert(ctx context.Context, entity T, conflictColumns ...string) (T, domain.UpsertResult, error) {
+	entities, result, err := uow.BulkUpsert(ctx, []T{entity}, conflictColumns...)
+	if err != nil {
+		return entity, result, err
+	}
+	return entities[0], result, nil
+}
+
+// BulkUpsert inserts or overwrites entities in batched INSERT ... ON CONFLICT statements
+// The result tells inserted from updated rows by RETURNING (xmax = 0) on PostgreSQL, and by looking
+// up the conflict keys beforehand on other dialects. Change listeners receive each entity as created
+// or updated; entities skipped by the conflict clause are not reported
+func (uow *UnitOfWork[T]) BulkUpsert(ctx context.Context, entities []T, conflictColumns ...string) ([]T, domain.UpsertResult, error) {
+	var result domain.UpsertResult
+	if len(entities) == 0 {
+		return entities, result, nil
+	}
+
+	meta := uow.metadata()
+	onConflict, err := upsertClause(meta, conflictColumns)
+	if err != nil {
+		return nil, result, err
+	}
+	if err := uow.assignIDs(ctx, entities...); err != nil {
+		return nil, result, err
+	}
+	if err := uow.validate(false, entities...); err != nil {
+		return nil, result, err
+	}
+
+	db := uow.getActiveDB(ctx)
+	var kinds []domain.ChangeKind
+	if db.Dialector.Name() == "postgres" {
+		if kinds, err = upsertReturning(db, meta, onConflict, entities); err != nil {
+			return nil, result, fmt.Errorf("failed to upsert entities: %w", err)
+		}
+		uow.invalidateCache(ctx, meta.Table)
+	} else {
+		existing, err := existingKeys(db, meta, onConflict.Columns, entities)
+		if err != nil {
+			return nil, result, err
+		}
+		if err := db.Clauses(onConflict).Create(&entities).Error; err != nil {
+			return nil, result, fmt.Errorf("failed to upsert entities: %w", err)
+		}
+		kinds = make([]domain.ChangeKind, len(entities))
+		fields := conflictFields(meta, onConflict.Columns)
+		for i, entity := range entities {
+			v, _ := meta.structValue(entity)
+			switch {
+			case !existing[conflictKey(fields, v)]:
+				kinds[i] = domain.ChangeCreated
+			case !onConflict.DoNothing:
+				kinds[i] = domain.ChangeUpdated
+			}
+		}
+	}
+
+	var created, updated []T
+	for i, kind := range kinds {
+		switch kind {
+		case domain.ChangeCreated:
+			created = append(created, entities[i])
+		case domain.ChangeUpdated:
+			updated = append(updated, entities[i])
+		}
+	}
+	result.Inserted, result.Updated = int64(len(created)), int64(len(updated))
+	result.Skipped = int64(len(entities)) - result.Inserted - result.Updated
+
+	uow.recordChanges(ctx, domain.ChangeCreated, created...)
+	uow.recordChanges(ctx, domain.ChangeUpdated, updated...)
+	return entities, result, nil
+}
+
+// upsertedFlag names the RETURNING column telling rows PostgreSQL inserted from rows it updated
+const upsertedFlag = "uow_inserted"
+
+// upsertReturning upserts entities on PostgreSQL in batches of INSERT ... ON CONFLICT ... RETURNING *,
+// (xmax = 0), all in one transaction, and returns what happened to each entity: created, updated, or
+// "" when the conflict clause skipped it. xmax is read as each row is written, zero for a row the
+// statement inserted and its own lock for a row it updated, so later lockers cannot skew it.
+// Returned columns are read back into the entities; save and create hooks run around the statement
+// and checksums are signed as Create does
+func upsertReturning[T domain.BaseModel](db *gorm.DB, meta *modelMetadata, onConflict clause.OnConflict, entities []T) ([]domain.ChangeKind, error) {
+	kinds := make([]domain.ChangeKind, len(entities))
+	fields := conflictFields(meta, onConflict.Columns)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, entity := range entities {
+			if err := callHooks(tx, entity, "BeforeSave", "BeforeCreate"); err != nil {
+				return err
+			}
+		}
+
+		// Values are rendered the way Create renders them, including timestamps and default values
+		statement := tx.Session(&gorm.Session{NewDB: true}).Model(newEntity[T]()).Statement
+		if err := statement.Parse(newEntity[T]()); err != nil {
+			return err
+		}
+		var keys []interface{}
+		for start := 0; start < len(entities); start += bulkInsertBatchSize {
+			batch := entities[start:min(start+bulkInsertBatchSize, len(entities))]
+			statement.ReflectValue = reflect.ValueOf(batch)
+			values := callbacks.ConvertToCreateValues(statement)
+			if statement.Error != nil {
+				return statement.Error
+			}
+
+			pending := make(map[string]int, len(batch))
+			for i, entity := range batch {
+				v, _ := meta.structValue(entity)
+				pending[conflictKey(fields, v)] = start + i
+			}
+			query := tx.Raw("? ? ? RETURNING *, (xmax = 0) AS "+upsertedFlag, clause.Insert{Table: clause.Table{Name: statement.Table}}, values, onConflict)
+			rows, err := query.Rows()
+			if err != nil {
+				return err
+			}
+			scanned, err := scanUpserted(rows, statement.Schema, meta, fields, pending, entities, kinds)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, scanned...)
+			if router := replicaRouterOf(db); router != nil {
+				query.RowsAffected = int64(len(scanned))
+				router.written(query)
+			}
+		}
+
+		if plugin := rowChecksumPluginOf(tx); plugin != nil {
+			if layout, ok := checksumLayoutOf(statement.Schema); ok {
+				if err := plugin.sign(tx, layout, keys); err != nil {
+					return fmt.Errorf("failed to sign rows: %w", err)
+				}
+			}
+		}
+		for _, entity := range entities {
+			if err := callHooks(tx, entity, "AfterCreate", "AfterSave"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return kinds, err
+}
+
+// scanUpserted reads the rows an upsert returned back into the entities they were written from,
+// matched by conflict key, records their change kinds, and returns their primary keys
+func scanUpserted[T domain.BaseModel](rows *sql.Rows, s *schema.Schema, meta *modelMetadata, fields []fieldMetadata, pending map[string]int, entities []T, kinds []domain.ChangeKind) ([]interface{}, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []interface{}
+	for rows.Next() {
+		var inserted bool
+		returned := make([]*schema.Field, len(columns))
+		values := make([]interface{}, len(columns))
+		for i, column := range columns {
+			if column == upsertedFlag {
+				values[i] = &inserted
+			} else if returned[i] = s.LookUpField(column); returned[i] != nil {
+				values[i] = returned[i].NewValuePool.Get()
+			} else {
+				values[i] = new(interface{})
+			}
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+
+		row := reflect.New(s.ModelType).Elem()
+		for i, field := range returned {
+			if field != nil {
+				if err := field.Set(context.Background(), row, values[i]); err != nil {
+					return nil, err
+				}
+				field.NewValuePool.Put(values[i])
+			}
+		}
+		index, ok := pending[conflictKey(fields, row)]
+		if !ok {
+			return nil, fmt.Errorf("returned row matches no upserted entity")
+		}
+		target, _ := meta.structValue(entities[index])
+		for _, field := range returned {
+			if field != nil {
+				value, _ := field.ValueOf(context.Background(), row)
+				if err := field.Set(context.Background(), target, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		kinds[index] = domain.ChangeUpdated
+		if inserted {
+			kinds[index] = domain.ChangeCreated
+		}
+		if s.PrioritizedPrimaryField != nil {
+			key, _ := s.PrioritizedPrimaryField.ValueOf(context.Background(), row)
+			keys = append(keys, key)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// callHooks calls the named gorm hooks an entity implements, in order, stopping at the first error
+func callHooks(tx *gorm.DB, entity interface{}, hooks ...string) error {
+	for _, hook := range hooks {
+		var err error
+		switch hook {
+		case "BeforeSave":
+			if h, ok := entity.(callbacks.BeforeSaveInterface); ok {
+				err = h.BeforeSave(tx)
+			}
+		case "BeforeCreate":
+			if h, ok := entity.(callbacks.BeforeCreateInterface); ok {
+				err = h.BeforeCreate(tx)
+			}
+		case "AfterCreate":
+			if h, ok := entity.(callbacks.AfterCreateInterface); ok {
+				err = h.AfterCreate(tx)
+			}
+		case "AfterSave":
+			if h, ok := entity.(callbacks.AfterSaveInterface); ok {
+				err = h.AfterSave(tx)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conflictFields returns the fields of the conflict columns
+func conflictFields(meta *modelMetadata, columns []clause.Column) []fieldMetadata {
+	fields := make([]fieldMetadata, len(columns))
+	for i, column := range columns {
+		fields[i], _ = meta.Field(column.Name)
+	}
+	return fields
+}
+
+// conflictKey renders a row's conflict column values as one comparable key
+func conflictKey(fields []fieldMetadata, v reflect.Value) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = canonicalValue(v.FieldByIndex(field.Index).Interface())
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// existingKeys returns the conflict keys of the rows, trashed or not, that entities conflict with
+func existingKeys[T domain.BaseModel](db *gorm.DB, meta *modelMetadata, columns []clause.Column, entities []T) (map[string]bool, error) {
+	fields := conflictFields(meta, columns)
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Qualified
+	}
+	keys := make([][]interface{}, len(entities))
+	for i, entity := range entities {
+		v, _ := meta.structValue(entity)
+		keys[i] = make([]interface{}, len(fields))
+		for j, field := range fields {
+			keys[i][j] = v.FieldByIndex(field.Index).Interface()
+		}
+	}
+
+	var rows []T
+	err := db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(new(T)).
+		Where("("+strings.Join(names, ", ")+") IN ?", keys).Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up conflicting rows: %w", err)
+	}
+	existing := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		v, _ := meta.structValue(row)
+		existing[conflictKey(fields, v)] = true
+	}
+	return existing, nil
+}
+
+// BulkUpdate updates multiple entities, budgeted like BulkInsert under a context deadline
 func (uow *UnitOfWork[T]) BulkUpdate(ctx context.Context, entities []T) ([]T, error) {
-	db := uow.getActiveDB()
+	if err := uow.validate(false, entities...); err != nil {
+		return nil, err
+	}
+	db := uow.getActiveDB(ctx)
 
+	budget := uow.deadlineBudget(ctx, "bulk update", len(entities))
 	for i := range entities {
+		if err := budget.admit(i, 1); err != nil {
+			uow.recordChanges(ctx, domain.ChangeUpdated, entities[:i]...)
+			return entities[:i], err
+		}
 		if err := db.Save(&entities[i]).Error; err != nil {
 			return nil, fmt.Errorf("failed to bulk update entity at index %d: %w", i, err)
 		}
 	}
 
+	uow.recordChanges(ctx, domain.ChangeUpdated, entities...)
 	return entities, nil
 }
 
-// BulkSoftDelete performs soft delete on multiple entities
+// BulkSoftDelete performs soft delete on multiple entities, budgeted like BulkInsert under a context deadline
 func (uow *UnitOfWork[T]) BulkSoftDelete(ctx context.Context, identifiers []identifier.IIdentifier) error {
-	db := uow.getActiveDB()
+	db := uow.getActiveDB(ctx)
 
-	for _, id := range identifiers {
-		queryMap := id.ToMap()
-		if err := db.Where(queryMap).Delete(new(T)).Error; err != nil {
+	budget := uow.deadlineBudget(ctx, "bulk soft delete", len(identifiers))
+	for i, id := range identifiers {
+		if err := budget.admit(i, 1); err != nil {
+			return err
+		}
+		scoped, err := uow.scopedMutation(db, "bulk soft delete", id)
+		if err != nil {
+			return err
+		}
+		if err := scoped.Delete(new(T)).Error; err != nil {
 			return fmt.Errorf("failed to bulk soft delete entity: %w", err)
 		}
 	}
@@ -314,13 +1548,20 @@ func (uow *UnitOfWork[T]) BulkSoftDelete(ctx context.Context, identifiers []iden
 	return nil
 }
 
-// BulkHardDelete performs hard delete on multiple entities
+// BulkHardDelete performs hard delete on multiple entities, budgeted like BulkInsert under a context deadline
 func (uow *UnitOfWork[T]) BulkHardDelete(ctx context.Context, identifiers []identifier.IIdentifier) error {
-	db := uow.getActiveDB()
+	db := uow.getActiveDB(ctx)
 
-	for _, id := range identifiers {
-		queryMap := id.ToMap()
-		if err := db.Unscoped().Where(queryMap).Delete(new(T)).Error; err != nil {
+	budget := uow.deadlineBudget(ctx, "bulk hard delete", len(identifiers))
+	for i, id := range identifiers {
+		if err := budget.admit(i, 1); err != nil {
+			return err
+		}
+		scoped, err := uow.scopedMutation(db.Unscoped(), "bulk hard delete", id)
+		if err != nil {
+			return err
+		}
+		if err := scoped.Delete(new(T)).Error; err != nil {
 			return fmt.Errorf("failed to bulk hard delete entity: %w", err)
 		}
 	}
@@ -331,40 +1572,70 @@ func (uow *UnitOfWork[T]) BulkHardDelete(ctx context.Context, identifiers []iden
 // GetTrashed retrieves all soft-deleted entities
 func (uow *UnitOfWork[T]) GetTrashed(ctx context.Context) ([]T, error) {
 	var entities []T
-	db := uow.getActiveDB()
+	db := uow.getActiveDB(ctx)
 
 	if err := db.Unscoped().Where("deleted_at IS NOT NULL").Find(&entities).Error; err != nil {
 		return nil, fmt.Errorf("failed to get trashed entities: %w", err)
 	}
+	if err := uow.attachArchiveInfo(ctx, entities); err != nil {
+		return nil, err
+	}
 
+	uow.maskResults(ctx, entities...)
 	return entities, nil
 }
 
 // GetTrashedWithPagination retrieves soft-deleted entities with pagination
+// Without a sort the most recently deleted entities come first; see GetTrashedWhere
 func (uow *UnitOfWork[T]) GetTrashedWithPagination(ctx context.Context, query domain.QueryParams[T]) ([]T, uint, error) {
+	return uow.GetTrashedWhere(ctx, nil, query)
+}
+
+// GetTrashedWhere searches soft-deleted entities matching an identifier, with filtering, sorting and pagination
+// Sort fields must be entity columns; without a sort the most recently deleted entities come first
+func (uow *UnitOfWork[T]) GetTrashedWhere(ctx context.Context, identifier identifier.IIdentifier, query domain.QueryParams[T]) ([]T, uint, error) {
 	var entities []T
 	var total int64
 
-	db := uow.getActiveDB().Unscoped().Where("deleted_at IS NOT NULL")
+	meta := uow.metadata()
+	order := make([]string, 0, len(query.Sort)+2)
+	for field, direction := range query.Sort {
+		column, ok := meta.Field(field)
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: unknown sort field %q", uowerrors.ErrInvalidQueryParams, field)
+		}
+		if direction != domain.SortAsc && direction != domain.SortDesc {
+			return nil, 0, fmt.Errorf("%w: invalid sort direction %q", uowerrors.ErrInvalidQueryParams, direction)
+		}
+		order = append(order, column.Qualified+" "+string(direction))
+	}
+	if len(order) == 0 {
+		if deletedAt, ok := meta.Field("deleted_at"); ok {
+			order = append(order, deletedAt.Qualified+" DESC")
+		}
+	}
+	if primaryKey, ok := meta.primaryKey(); ok {
+		order = append(order, primaryKey.Qualified)
+	}
 
-	// Apply filters if provided
-	if !reflect.ValueOf(query.Filter).IsZero() {
-		db = db.Where(query.Filter)
+	db, done, err := uow.hintedDB(ctx, query.Hints, query.TxOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	db = applyEntityIdentifier[T](db.Unscoped().Where("deleted_at IS NOT NULL"), identifier)
+	if conditions, args := meta.filterConditions(query.Filter, false); conditions != "" {
+		db = db.Where(conditions, args...)
 	}
 
-	// Count total records
 	if err := db.Model(new(T)).Count(&total).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to count trashed entities: %w", err)
 	}
 
-	// Apply sorting
-	if query.Sort != nil {
-		for field, direction := range query.Sort {
-			db = db.Order(fmt.Sprintf("%s %s", field, direction))
-		}
+	for _, orderBy := range order {
+		db = db.Order(orderBy)
 	}
-
-	// Apply pagination
 	if query.Limit > 0 {
 		db = db.Limit(query.Limit)
 	}
@@ -373,44 +1644,145 @@ func (uow *UnitOfWork[T]) GetTrashedWithPagination(ctx context.Context, query do
 	}
 
 	if err := db.Find(&entities).Error; err != nil {
-		return nil, 0, fmt.Errorf("failed to get trashed entities with pagination: %w", err)
+		return nil, 0, fmt.Errorf("failed to search trashed entities: %w", err)
+	}
+	if err := uow.attachArchiveInfo(ctx, entities); err != nil {
+		return nil, 0, err
 	}
 
+	uow.maskResults(ctx, entities...)
 	return entities, uint(total), nil
 }
 
 // Restore restores a soft-deleted entity
 func (uow *UnitOfWork[T]) Restore(ctx context.Context, identifier identifier.IIdentifier) (T, error) {
 	var entity T
-	db := uow.getActiveDB()
-
-	queryMap := identifier.ToMap()
+	db := uow.getActiveDB(ctx)
 
 	// Find the soft-deleted entity
-	if err := db.Unscoped().Where(queryMap).Where("deleted_at IS NOT NULL").First(&entity).Error; err != nil {
+	if err := applyEntityIdentifier[T](db.Unscoped(), identifier).Where("deleted_at IS NOT NULL").First(&entity).Error; err != nil {
 		return entity, fmt.Errorf("failed to find trashed entity: %w", err)
 	}
 
 	// Restore the entity
-	if err := db.Unscoped().Model(&entity).Update("deleted_at", nil).Error; err != nil {
+	if err := db.Unscoped().Model(&entity).Updates(uow.restoreUpdates()).Error; err != nil {
 		return entity, fmt.Errorf("failed to restore entity: %w", err)
 	}
+	if err := uow.clearArchiveInfo(ctx, []int{entity.GetID()}); err != nil {
+		return entity, err
+	}
 
+	uow.recordChanges(ctx, domain.ChangeUpdated, entity)
 	return entity, nil
 }
 
+// PurgeTrashed permanently deletes soft-deleted entities matching the identifier and returns the number of purged rows
+// Purging every trashed row requires an identifier built with AllowFullTableOperation(),
+// regardless of Config.GuardUnscopedMutations
+func (uow *UnitOfWork[T]) PurgeTrashed(ctx context.Context, identifier identifier.IIdentifier) (int64, error) {
+	if identifier == nil || (identifier.IsEmpty() && !identifier.IsFullTableOperationAllowed()) {
+		return 0, fmt.Errorf("failed to purge trashed entities: %w", uowerrors.ErrUnscopedOperation)
+	}
+
+	db, err := uow.scopedMutation(uow.getActiveDB(ctx).Unscoped().Where("deleted_at IS NOT NULL"), "purge trashed", identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Delete(newEntity[T]())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge trashed entities: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// RestoreAllWhere restores the soft-deleted entities matching an identifier and returns how many were restored
+// Restoring the whole table requires an identifier built with AllowFullTableOperation(),
+// regardless of Config.GuardUnscopedMutations
+func (uow *UnitOfWork[T]) RestoreAllWhere(ctx context.Context, identifier identifier.IIdentifier) (int64, error) {
+	if identifier == nil || (identifier.IsEmpty() && !identifier.IsFullTableOperationAllowed()) {
+		return 0, fmt.Errorf("failed to restore entities: %w", uowerrors.ErrUnscopedOperation)
+	}
+
+	db, err := uow.scopedMutation(uow.getActiveDB(ctx).Unscoped().Model(new(T)).Where("deleted_at IS NOT NULL"), "restore", identifier)
+	if err != nil {
+		return 0, err
+	}
+	primaryKey, ok := uow.metadata().primaryKey()
+	if !ok {
+		return 0, fmt.Errorf("%w: restoring entities requires a primary key", uowerrors.ErrInvalidQueryParams)
+	}
+
+	// Restore by the keys found, so the changes recorded are exactly the rows restored
+	var entities []T
+	if err := db.Find(&entities).Error; err != nil {
+		return 0, fmt.Errorf("failed to find trashed entities: %w", err)
+	}
+	if len(entities) == 0 {
+		return 0, nil
+	}
+	ids := make([]int, len(entities))
+	for i, entity := range entities {
+		ids[i] = entity.GetID()
+	}
+
+	restored := uow.getActiveDB(ctx).Unscoped().Model(new(T)).
+		Where(primaryKey.Qualified+" IN ? AND deleted_at IS NOT NULL", ids).
+		Updates(uow.restoreUpdates())
+	if restored.Error != nil {
+		return 0, fmt.Errorf("failed to restore entities: %w", restored.Error)
+	}
+	if err := uow.clearArchiveInfo(ctx, ids); err != nil {
+		return 0, err
+	}
+
+	var reloaded []T
+	if err := uow.getActiveDB(ctx).Where(primaryKey.Qualified+" IN ?", ids).Find(&reloaded).Error; err != nil {
+		return 0, fmt.Errorf("failed to reload restored entities: %w", err)
+	}
+	uow.recordChanges(ctx, domain.ChangeUpdated, reloaded...)
+	return restored.RowsAffected, nil
+}
+
 // RestoreAll restores all soft-deleted entities
+//
+// Deprecated: use RestoreAllWhere, with identifier.New().AllowFullTableOperation() to restore every row
 func (uow *UnitOfWork[T]) RestoreAll(ctx context.Context) error {
-	db := uow.getActiveDB()
+	_, err := uow.RestoreAllWhere(ctx, identifier.New().AllowFullTableOperation())
+	return err
+}
 
-	if err := db.Unscoped().Model(new(T)).Where("deleted_at IS NOT NULL").Update("deleted_at", nil).Error; err != nil {
-		return fmt.Errorf("failed to restore all entities: %w", err)
+// Parallel runs independent read operations concurrently on separate pooled connections
+// Each function receives its own unit of work outside any transaction; errors are joined
+func (uow *UnitOfWork[T]) Parallel(ctx context.Context, funcs ...func(persistence.IUnitOfWork[T]) error) error {
+	errs := make([]error, len(funcs))
+
+	var wg sync.WaitGroup
+	for i, fn := range funcs {
+		wg.Add(1)
+		go func(i int, fn func(persistence.IUnitOfWork[T]) error) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("parallel operation %d panicked: %v", i, r)
+				}
+			}()
+
+			worker := uow.session(ctx)
+			defer worker.Close()
+			if err := fn(worker); err != nil {
+				errs[i] = fmt.Errorf("parallel operation %d failed: %w", i, err)
+			}
+		}(i, fn)
 	}
+	wg.Wait()
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // GetRepository returns a repository for the specified entity type
+// Keys without a registered repository get a *BaseRepository[T] bound to this unit of work
 func (uow *UnitOfWork[T]) GetRepository(entityType string) interface{} {
 	uow.mu.RLock()
 	repo, exists := uow.repositories[entityType]
@@ -427,7 +1799,7 @@ func (uow *UnitOfWork[T]) GetRepository(entityType string) interface{} {
 		return repo
 	}
 
-	repo = NewBaseRepository(uow.getActiveDB())
+	repo = NewBaseRepositoryFromUnitOfWork(uow)
 	uow.repositories[entityType] = repo
 	return repo
 }
@@ -439,16 +1811,54 @@ func (uow *UnitOfWork[T]) RegisterRepository(entityType string, repo interface{}
 	uow.repositories[entityType] = repo
 }
 
-// WithContext creates a new unit of work with the specified context
+// RepositoryProvider is implemented by units of work that hold named repositories
+type RepositoryProvider interface {
+	GetRepository(entityType string) interface{}
+	RegisterRepository(entityType string, repo interface{})
+}
+
+// GetTypedRepository returns a registered repository as R without a type assertion at the call site
+// Unregistered keys resolve to a *BaseRepository[T] like GetRepository; any other mismatch
+// returns errors.ErrInvalidRepositoryType
+func GetTypedRepository[R any](provider RepositoryProvider, entityType string) (R, error) {
+	repo := provider.GetRepository(entityType)
+	typed, ok := repo.(R)
+	if !ok {
+		return typed, fmt.Errorf("%w: repository %q is %T, not %T", uowerrors.ErrInvalidRepositoryType, entityType, repo, typed)
+	}
+	return typed, nil
+}
+
+// RegisterTypedRepository registers a repository so it can be retrieved with GetTypedRepository[R]
+func RegisterTypedRepository[R any](provider RepositoryProvider, entityType string, repo R) {
+	provider.RegisterRepository(entityType, repo)
+}
+
+// WithContext returns an independent unit of work bound to ctx
+// The copy shares the connection pool and configuration but starts outside any transaction
+// with its own repository registry: a transaction begun, committed or rolled back on either
+// unit of work is never observed by the other. The original is left unchanged.
 func (uow *UnitOfWork[T]) WithContext(ctx context.Context) persistence.IUnitOfWork[T] {
-	newUow := &UnitOfWork[T]{
-		db:           uow.db,
-		tx:           uow.tx,
-		ctx:          ctx,
-		repositories: uow.repositories,
-		inTx:         uow.inTx,
+	return uow.session(ctx)
+}
+
+// Session returns a cheap, independent unit of work on the same pool for use by another goroutine
+// Equivalent to WithContext with this unit's context; only the pool and configuration are shared
+func (uow *UnitOfWork[T]) Session() persistence.IUnitOfWork[T] {
+	return uow.session(uow.ctx)
+}
+
+// session builds an independent unit of work sharing the pool and configuration
+func (uow *UnitOfWork[T]) session(ctx context.Context) *UnitOfWork[T] {
+	session := newUnitOfWork[T](uow.config, uow.db)
+	session.ctx = ctx
+	session.settings = uow.settings
+	session.readOnly = uow.readOnly
+	if uow.pin != nil {
+		// Sessions act for the same caller, so they see each other's writes
+		session.pin = uow.pin
 	}
-	return newUow
+	return session
 }
 
 // GetContext returns the current context
@@ -461,23 +1871,211 @@ func (uow *UnitOfWork[T]) IsInTransaction() bool {
 	return uow.inTx
 }
 
-// Close closes the database connection
+// NamingStrategy returns the naming strategy of the unit of work's connection
+func (uow *UnitOfWork[T]) NamingStrategy() schema.Namer {
+	return namerOf(uow.db)
+}
+
+// Close rolls back any open transaction and closes the database connection
+// Units of work created by a factory share its pool, which stays open
 func (uow *UnitOfWork[T]) Close() error {
+	if uow.shared != nil {
+		// The shared transaction outlives its views
+		uow.shared.release(uow)
+		uow.leak.stop()
+		return nil
+	}
 	if uow.inTx {
 		uow.RollbackTransaction(uow.ctx)
 	}
+	uow.leak.stop()
+
+	if !uow.ownsDB {
+		return nil
+	}
 
 	sqlDB, err := uow.db.DB()
 	if err != nil {
 		return err
 	}
+	if err := closeReplicas(uow.db); err != nil {
+		return err
+	}
 	return sqlDB.Close()
 }
 
-// getActiveDB returns the appropriate database connection
-func (uow *UnitOfWork[T]) getActiveDB() *gorm.DB {
+// scopedMutation applies the identifier to a mutating query and enforces the unscoped-mutation guard
+// An empty identifier only reaches the database when acknowledged with AllowFullTableOperation()
+func (uow *UnitOfWork[T]) scopedMutation(db *gorm.DB, op string, id identifier.IIdentifier) (*gorm.DB, error) {
+	if id != nil && !id.IsEmpty() {
+		return applyEntityIdentifier[T](db, id), nil
+	}
+	if id != nil && id.IsFullTableOperationAllowed() {
+		return db.Session(&gorm.Session{AllowGlobalUpdate: true}), nil
+	}
+	if uow.config == nil || uow.config.GuardUnscopedMutations {
+		return nil, fmt.Errorf("failed to %s: %w", op, uowerrors.ErrUnscopedOperation)
+	}
+	return db, nil
+}
+
+// maskResults redacts PII fields of loaded entities when the policy requires it
+func (uow *UnitOfWork[T]) maskResults(ctx context.Context, entities ...T) {
+	if uow.config == nil || uow.config.Masking == nil || !uow.config.Masking.MaskResults {
+		return
+	}
+	if HasPIIAccess(ctx) {
+		return
+	}
+	for _, entity := range entities {
+		uow.config.Masking.MaskEntity(entity)
+	}
+}
+
+// rejectMasked fails writes of entities whose PII was masked on read, which would store the mask
+// text over the real values
+func (uow *UnitOfWork[T]) rejectMasked(entities ...T) error {
+	if uow.config == nil || uow.config.Masking == nil {
+		return nil
+	}
+	for _, entity := range entities {
+		if field, masked := uow.config.Masking.MaskedField(entity); masked {
+			return fmt.Errorf("%w: %s holds masked PII; load the entity with WithPIIAccess before writing it", uowerrors.ErrInvalidEntity, field)
+		}
+	}
+	return nil
+}
+
+// getActiveDB returns the appropriate database connection bound to the caller's context
+// Deadlines and cancellation of ctx apply to the query, inside or outside a transaction;
+// a nil ctx falls back to the unit of work's own context. Default scopes are applied here.
+func (uow *UnitOfWork[T]) getActiveDB(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		ctx = uow.ctx
+	}
+	db := uow.db
+	if uow.inTx && uow.tx != nil {
+		db = uow.tx
+	}
+	ctx = uow.pinned(ctx)
+	if uow.inTx && uow.cacheWrites != nil {
+		ctx = context.WithValue(ctx, cacheWritesKey{}, uow.cacheWrites)
+	}
+	if uow.queries != nil && queryTraceOf(ctx) == nil {
+		ctx = context.WithValue(ctx, queryTraceKey{}, uow.queries)
+	}
+	return uow.settings.applyScopes(ctx, db.WithContext(ctx))
+}
+
+// connection is the pool, or the open transaction, without the entity's default scopes, for
+// statements on this package's own tables
+func (uow *UnitOfWork[T]) connection(ctx context.Context) *gorm.DB {
+	db := uow.db
 	if uow.inTx && uow.tx != nil {
-		return uow.tx
+		db = uow.tx
+	}
+	ctx = uow.pinned(ctx)
+	if uow.inTx && uow.cacheWrites != nil {
+		ctx = context.WithValue(ctx, cacheWritesKey{}, uow.cacheWrites)
+	}
+	return db.WithContext(ctx)
+}
+
+// pinned attaches the unit of work's read-your-writes pin unless the context already carries one
+func (uow *UnitOfWork[T]) pinned(ctx context.Context) context.Context {
+	if uow.pin == nil || pinOf(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, primaryPinKey{}, uow.pin)
+}
+
+// quoteIdentifier quotes a SQL identifier (role, table, column) for safe interpolation
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// applyIdentifier adds the identifier's conditions to a query
+// Fields must be plain column names and are quoted for the dialect; conditions it cannot render
+// fail the query instead of widening it
+func applyIdentifier(db *gorm.DB, id identifier.IIdentifier) *gorm.DB {
+	return applyRendered(db, id, identifier.PlainColumns(func(name string) string {
+		return db.Statement.Quote(name)
+	}))
+}
+
+// applyRendered adds the identifier's conditions, with fields passed through column
+func applyRendered(db *gorm.DB, id identifier.IIdentifier, column identifier.ColumnFunc) *gorm.DB {
+	conditions, args, err := identifier.Render(id, column)
+	if err != nil {
+		db.AddError(err)
+		return db
+	}
+	if conditions == "" {
+		return db
+	}
+	return db.Where(conditions, args...)
+}
+
+// upsertClause builds the ON CONFLICT clause overwriting every column except the keys and created_at
+func upsertClause(meta *modelMetadata, conflictColumns []string) (clause.OnConflict, error) {
+	primaryKey, hasPrimaryKey := meta.primaryKey()
+	if len(conflictColumns) == 0 {
+		if !hasPrimaryKey {
+			return clause.OnConflict{}, fmt.Errorf("%w: upsert requires conflict columns", uowerrors.ErrInvalidQueryParams)
+		}
+		conflictColumns = []string{primaryKey.Column}
+	}
+
+	conflict := make(map[string]bool, len(conflictColumns))
+	columns := make([]clause.Column, len(conflictColumns))
+	for i, name := range conflictColumns {
+		field, ok := meta.Field(name)
+		if !ok {
+			return clause.OnConflict{}, fmt.Errorf("%w: unknown conflict column %q", uowerrors.ErrInvalidQueryParams, name)
+		}
+		conflict[field.Column] = true
+		columns[i] = clause.Column{Name: field.Column}
+	}
+
+	var updates []string
+	for _, field := range meta.Fields {
+		if conflict[field.Column] || field.PrimaryKey || field.Column == "created_at" {
+			continue
+		}
+		updates = append(updates, field.Column)
+	}
+
+	// With nothing to overwrite, conflicting rows are skipped
+	return clause.OnConflict{Columns: columns, DoUpdates: clause.AssignmentColumns(updates), DoNothing: len(updates) == 0}, nil
+}
+
+// supportsReturning reports whether the dialect returns rows from UPDATE ... RETURNING
+func supportsReturning(db *gorm.DB) bool {
+	switch dialector := db.Dialector.(type) {
+	case *postgres.Dialector:
+		return dialector.Config != nil && !dialector.WithoutReturning
+	case postgres.Dialector:
+		return dialector.Config != nil && !dialector.WithoutReturning
+	}
+	return db.Dialector.Name() == "sqlite"
+}
+
+// cloneEntity returns a shallow copy of a pointer entity so results don't alias the caller's value
+func cloneEntity[T domain.BaseModel](entity T) T {
+	v := reflect.ValueOf(entity)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return entity
+	}
+	clone := reflect.New(v.Elem().Type())
+	clone.Elem().Set(v.Elem())
+	return clone.Interface().(T)
+}
+
+// newEntity allocates a zero entity, following pointer element types
+func newEntity[T domain.BaseModel]() T {
+	var entity T
+	if t := reflect.TypeOf(entity); t != nil && t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(T)
 	}
-	return uow.db.WithContext(uow.ctx)
+	return entity
 }
@@ -1,18 +1,32 @@
 package postgres
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/enum"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
 	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // TestUser implements BaseModel for testing
@@ -20,7 +34,7 @@ type TestUser struct {
 	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
 	Slug      string         `gorm:"uniqueIndex;size:100;not null" json:"slug"`
 	Name      string         `gorm:"size:255;not null" json:"name"`
-	Email     string         `gorm:"uniqueIndex;size:255;not null" json:"email"`
+	Email     string         `gorm:"uniqueIndex;size:255;not null" json:"email" pii:"true"`
 	Active    bool           `gorm:"default:true" json:"active"`
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
@@ -48,11 +62,7 @@ func setupTestDB(t *testing.T) *UnitOfWork[*TestUser] {
 	err = db.AutoMigrate(&TestUser{})
 	require.NoError(t, err)
 
-	return &UnitOfWork[*TestUser]{
-		db:           db,
-		ctx:          context.Background(),
-		repositories: make(map[string]interface{}),
-	}
+	return NewUnitOfWorkFromDB[*TestUser](db)
 }
 
 func TestUnitOfWork_BeginTransaction(t *testing.T) {
@@ -313,3 +323,4239 @@ func TestUnitOfWork_TransactionCommit(t *testing.T) {
 	assert.Len(t, users, 1)
 	assert.Equal(t, "Commit Test", users[0].GetName())
 }
+
+func TestMaskingPolicy_MaskStatement(t *testing.T) {
+	policy := &MaskingPolicy{}
+	policy.Register(&TestUser{})
+
+	// PostgreSQL placeholders in a WHERE clause
+	params := policy.MaskStatement(`SELECT * FROM "test_users" WHERE "test_users"."email" = $1 AND name = $2`, []interface{}{"bob@example.com", "Bob"})
+	assert.Equal(t, []interface{}{DefaultMask, "Bob"}, params)
+
+	// Positional placeholders in an INSERT column list
+	params = policy.MaskStatement("INSERT INTO `test_users` (`slug`,`name`,`email`) VALUES (?,?,?),(?,?,?)",
+		[]interface{}{"a", "A", "a@example.com", "b", "B", "b@example.com"})
+	assert.Equal(t, []interface{}{"a", "A", DefaultMask, "b", "B", DefaultMask}, params)
+
+	// IN lists
+	params = policy.MaskStatement(`SELECT * FROM users WHERE email IN ($1,$2) AND id = $3`, []interface{}{"x", "y", 1})
+	assert.Equal(t, []interface{}{DefaultMask, DefaultMask, 1}, params)
+}
+
+func TestUnitOfWork_MaskResults(t *testing.T) {
+	uow := setupTestDB(t)
+	uow.config = &Config{Masking: &MaskingPolicy{MaskResults: true}}
+	ctx := context.Background()
+
+	inserted, err := uow.Insert(ctx, &TestUser{Name: "Masked", Email: "masked@example.com", Slug: "masked"})
+	require.NoError(t, err)
+
+	// Callers without PII access receive redacted values
+	found, err := uow.FindOneById(ctx, inserted.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMask, found.Email)
+	assert.Equal(t, "Masked", found.Name)
+
+	// Callers with PII access receive the stored values
+	found, err = uow.FindOneById(WithPIIAccess(ctx), inserted.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, "masked@example.com", found.Email)
+
+	// Masked entities cannot be written back over the stored values
+	masked, err := uow.FindOneById(ctx, inserted.GetID())
+	require.NoError(t, err)
+	masked.Name = "Renamed"
+	_, err = uow.Update(ctx, identifier.New().Equal("id", masked.ID), masked)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidEntity)
+	_, err = uow.BulkUpdate(ctx, []*TestUser{masked})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidEntity)
+	_, _, err = uow.Upsert(ctx, masked, "email")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidEntity)
+
+	found, err = uow.FindOneById(WithPIIAccess(ctx), inserted.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, "masked@example.com", found.Email)
+	assert.Equal(t, "Masked", found.Name)
+
+	// Reloaded with PII access the entity can be updated
+	found.Name = "Renamed"
+	_, err = uow.Update(ctx, identifier.New().Equal("id", found.ID), found)
+	require.NoError(t, err)
+}
+
+// testPatient holds PII of several kinds, synced through its version column
+type testPatient struct {
+	ID        int            `gorm:"primaryKey" json:"id"`
+	Name      string         `json:"name"`
+	Phone     string         `json:"phone" pii:"true"`
+	BirthYear int            `json:"birth_year" pii:"true"`
+	Version   int64          `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (c *testPatient) GetID() int                    { return c.ID }
+func (c *testPatient) GetSlug() string               { return "" }
+func (c *testPatient) SetSlug(string)                {}
+func (c *testPatient) GetCreatedAt() time.Time       { return c.CreatedAt }
+func (c *testPatient) GetUpdatedAt() time.Time       { return c.UpdatedAt }
+func (c *testPatient) GetArchivedAt() gorm.DeletedAt { return c.DeletedAt }
+func (c *testPatient) GetName() string               { return c.Name }
+
+func TestUnitOfWork_RejectsMaskedWrites(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testPatient{}))
+	uow := newUnitOfWork[*testPatient](&Config{Masking: &MaskingPolicy{MaskResults: true}}, db)
+	ctx := context.Background()
+
+	// A stored value equal to the mask text is not mistaken for a masked one
+	inserted, err := uow.Insert(ctx, &testPatient{Name: "Ada", Phone: DefaultMask, BirthYear: 1815})
+	require.NoError(t, err)
+	inserted.Name = "Ada L."
+	_, err = uow.Update(ctx, identifier.New().Equal("id", inserted.ID), inserted)
+	require.NoError(t, err)
+
+	// Masked reads are refused whichever kind of PII field still holds its redacted value
+	masked, err := uow.FindOneById(ctx, inserted.ID)
+	require.NoError(t, err)
+	require.Zero(t, masked.BirthYear)
+	_, err = uow.Update(ctx, identifier.New().Equal("id", masked.ID), masked)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidEntity)
+	masked.Phone = "+44 20 7946 0000"
+	_, err = uow.Update(ctx, identifier.New().Equal("id", masked.ID), masked)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidEntity)
+	assert.ErrorContains(t, err, "BirthYear")
+	_, err = uow.ApplyChanges(ctx, []domain.ClientChange[*testPatient]{
+		{Operation: domain.SyncUpdate, Entity: masked, BaseVersion: masked.Version, Fields: []string{"name"}},
+	}, domain.SyncOptions[*testPatient]{})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidEntity)
+
+	// Once every PII field is replaced the entity no longer overwrites stored values with redactions
+	masked.BirthYear = 1816
+	_, err = uow.Update(ctx, identifier.New().Equal("id", masked.ID), masked)
+	require.NoError(t, err)
+	found, err := uow.FindOneById(WithPIIAccess(ctx), inserted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "+44 20 7946 0000", found.Phone)
+	assert.Equal(t, 1816, found.BirthYear)
+}
+
+func TestUnitOfWork_AsRoleRequiresTransaction(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	err := uow.AsRole(ctx, "reporting_reader")
+	assert.ErrorIs(t, err, uowerrors.ErrTransactionNotStarted)
+}
+
+func TestUnitOfWork_DeferConstraints(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	err := uow.DeferConstraints(ctx)
+	assert.ErrorIs(t, err, uowerrors.ErrTransactionNotStarted)
+
+	assert.Equal(t, "SET CONSTRAINTS ALL DEFERRED", deferConstraintsStatement(nil))
+	assert.Equal(t, `SET CONSTRAINTS "users_primary_address_fk", "public"."addresses_user_fk" DEFERRED`,
+		deferConstraintsStatement([]string{"users_primary_address_fk", "public.addresses_user_fk"}))
+}
+
+func TestUnitOfWork_SetSessionVar(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	err := uow.SetSessionVar(ctx, "app.request_id", "req-1")
+	assert.ErrorIs(t, err, uowerrors.ErrTransactionNotStarted)
+
+	err = uow.SetSessionVar(ctx, "app.request_id'; RESET ALL; --", "x")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, _, err = uow.SessionVar(ctx, "bad key")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	assert.Equal(t, "42", sessionVarText(42))
+	assert.Equal(t, "true", sessionVarText(true))
+	assert.Equal(t, "2024-05-01T10:00:00Z", sessionVarText(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)))
+	for _, value := range []string{"on", "true", "1"} {
+		parsed, ok := parseSettingBool(value)
+		assert.True(t, parsed && ok, value)
+	}
+	_, ok := parseSettingBool("maybe")
+	assert.False(t, ok)
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, `"app_writer"`, quoteIdentifier("app_writer"))
+	assert.Equal(t, `"evil"";DROP"`, quoteIdentifier(`evil";DROP`))
+}
+
+func TestUnitOfWork_UpdateWhere(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		_, err := uow.Insert(ctx, &TestUser{
+			Name:  fmt.Sprintf("User %d", i),
+			Email: fmt.Sprintf("user%d@example.com", i),
+			Slug:  fmt.Sprintf("user-%d", i),
+		})
+		require.NoError(t, err)
+	}
+
+	// Operator keys compile to real predicates
+	affected, err := uow.UpdateWhere(ctx, identifier.NewIdentifier().GreaterThan("id", 1), map[string]interface{}{"active": false})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	inactive, err := uow.FindOneByIdentifier(ctx, identifier.NewIdentifier().Equal("active", false).LessThan("id", 3))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, inactive.GetID())
+
+	// Conditions that cannot be rendered fail the mutation instead of widening it
+	malformed := identifier.New().Equal("id", 1).Add("name ~", "^User")
+	_, err = uow.UpdateWhere(ctx, malformed, map[string]interface{}{"name": "Clobbered"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery)
+	_, err = uow.Update(ctx, identifier.New().Add("id IN", 1), &TestUser{Name: "Clobbered"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery)
+	_, err = uow.SoftDelete(ctx, malformed)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery)
+	_, err = uow.HardDelete(ctx, malformed)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery)
+	_, err = uow.Restore(ctx, identifier.New().Add("deleted_at BETWEEN", "yesterday"))
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery)
+
+	// Fields must be declared columns; nothing else reaches the SQL
+	for _, field := range []string{"(1=1)OR(id", "password", "other_table.id", "id = 1 OR id"} {
+		_, err = uow.UpdateWhere(ctx, identifier.New().Equal(field, 1), map[string]interface{}{"name": "Clobbered"})
+		assert.ErrorIs(t, err, uowerrors.ErrInvalidQuery, field)
+	}
+
+	users, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+	for _, user := range users {
+		assert.NotEqual(t, "Clobbered", user.Name)
+	}
+
+	// Go field names and qualified columns resolve; nil compares with IS NULL and lists with IN
+	affected, err = uow.UpdateWhere(ctx, identifier.New().Equal("ID", []int{1, 3}).Equal("test_users.deleted_at", nil), map[string]interface{}{"name": "Listed"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+}
+
+func TestUnitOfWork_GuardUnscopedMutations(t *testing.T) {
+	uow := setupTestDB(t)
+	uow.config = &Config{GuardUnscopedMutations: true}
+	ctx := context.Background()
+
+	_, err := uow.Insert(ctx, &TestUser{Name: "Keep", Email: "keep@example.com", Slug: "keep"})
+	require.NoError(t, err)
+
+	// Empty identifiers are rejected
+	err = uow.Delete(ctx, identifier.NewIdentifier())
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+
+	_, err = uow.HardDelete(ctx, identifier.NewIdentifier())
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+
+	_, err = uow.UpdateWhere(ctx, identifier.NewIdentifier(), map[string]interface{}{"active": false})
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+
+	// Acknowledged full-table operations run
+	affected, err := uow.UpdateWhere(ctx, identifier.NewIdentifier().AllowFullTableOperation(), map[string]interface{}{"active": false})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	err = uow.Delete(ctx, identifier.NewIdentifier().AllowFullTableOperation())
+	assert.NoError(t, err)
+
+	users, err := uow.FindAll(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, users, 0)
+
+	// Units of work without a Config always guard
+	_, err = NewUnitOfWorkFromDB[*TestUser](uow.db).UpdateWhere(ctx, identifier.NewIdentifier(), map[string]interface{}{"active": true})
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+}
+
+func TestStatementCache_ReusesPreparedStatements(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{PrepareStmt: true})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(&statementCache{metrics: nopMetrics{}}))
+	require.NoError(t, db.AutoMigrate(&TestUser{}))
+
+	uow := &UnitOfWork[*TestUser]{db: db, ctx: context.Background(), repositories: make(map[string]interface{})}
+	ctx := context.Background()
+
+	inserted, err := uow.Insert(ctx, &TestUser{Name: "Cached", Email: "cached@example.com", Slug: "cached"})
+	require.NoError(t, err)
+
+	before, ok := StatementCacheStatsOf(db)
+	require.True(t, ok)
+
+	for i := 0; i < 3; i++ {
+		_, err := uow.FindOneById(ctx, inserted.GetID())
+		require.NoError(t, err)
+	}
+
+	after, _ := StatementCacheStatsOf(db)
+	assert.Equal(t, uint64(3), after.Executions-before.Executions)
+	assert.Equal(t, uint64(1), after.Prepares-before.Prepares)
+	assert.Greater(t, after.HitRate(), 0.0)
+
+	// Transactions prepare through the same store
+	require.NoError(t, uow.BeginTransaction(ctx))
+	_, err = uow.FindOneById(ctx, inserted.GetID())
+	assert.NoError(t, err)
+	assert.NoError(t, uow.CommitTransaction(ctx))
+}
+
+func TestLoader_BatchesConcurrentLoads(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	var ids []int
+	for i := 1; i <= 3; i++ {
+		user, err := uow.Insert(ctx, &TestUser{
+			Name:  fmt.Sprintf("Loader %d", i),
+			Email: fmt.Sprintf("loader%d@example.com", i),
+			Slug:  fmt.Sprintf("loader-%d", i),
+		})
+		require.NoError(t, err)
+		ids = append(ids, user.GetID())
+	}
+
+	queries := 0
+	require.NoError(t, uow.db.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		queries++
+	}))
+
+	loader := NewLoader[*TestUser](uow, 5*time.Millisecond, 0)
+	users, errs := loader.LoadMany(ctx, append(ids, 999))
+
+	assert.Equal(t, 1, queries)
+	for i, id := range ids {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, id, users[i].GetID())
+	}
+	assert.ErrorIs(t, errs[3], gorm.ErrRecordNotFound)
+}
+
+func TestUnitOfWork_FindAllWithPaginationFilter(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 4; i++ {
+		_, err := uow.Insert(ctx, &TestUser{
+			Name:  fmt.Sprintf("Filter %d", i%2),
+			Email: fmt.Sprintf("filter%d@example.com", i),
+			Slug:  fmt.Sprintf("filter-%d", i),
+		})
+		require.NoError(t, err)
+	}
+
+	users, total, err := uow.FindAllWithPagination(ctx, domain.QueryParams[*TestUser]{
+		Filter: &TestUser{Name: "Filter 1"},
+		Limit:  10,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), total)
+	assert.Len(t, users, 2)
+}
+
+// legacyApplyFilters is the per-call reflective filter builder replaced by the metadata cache
+// Kept for benchmark comparison only
+func legacyApplyFilters(query *gorm.DB, filter interface{}) *gorm.DB {
+	v := reflect.ValueOf(filter)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		if !field.IsExported() || value.IsZero() {
+			continue
+		}
+		columnName := field.Name
+		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
+			if tagName := strings.Split(jsonTag, ",")[0]; tagName != "-" {
+				columnName = tagName
+			}
+		}
+		query = query.Where(fmt.Sprintf("%s = ?", schema.NamingStrategy{}.ColumnName("", columnName)), value.Interface())
+	}
+	return query
+}
+
+func benchmarkDB(b *testing.B) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(b, err)
+	return db.Session(&gorm.Session{DryRun: true})
+}
+
+func BenchmarkApplyFilters_Reflect(b *testing.B) {
+	db := benchmarkDB(b)
+	filter := &TestUser{Name: "Bench", Email: "bench@example.com", Active: true}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var users []*TestUser
+		legacyApplyFilters(db, filter).Find(&users)
+	}
+}
+
+func BenchmarkApplyFilters_Metadata(b *testing.B) {
+	db := benchmarkDB(b)
+	repo := NewBaseRepository[*TestUser](db)
+	filter := &TestUser{Name: "Bench", Email: "bench@example.com", Active: true}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var users []*TestUser
+		repo.applyFilters(db, filter).Find(&users)
+	}
+}
+
+func BenchmarkApplyQueryParams(b *testing.B) {
+	db := benchmarkDB(b)
+	repo := NewBaseRepository[*TestUser](db)
+	params := domain.QueryParams[*TestUser]{
+		Filter: &TestUser{Name: "Bench", Active: true},
+		Sort:   domain.SortMap{"created_at": domain.SortDesc},
+		Limit:  20,
+		Offset: 40,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var users []*TestUser
+		repo.applyQueryParams(db, params).Find(&users)
+	}
+}
+
+// setupSharedTestDB creates a named in-memory SQLite database visible to every pooled connection
+func setupSharedTestDB(t *testing.T) *UnitOfWork[*TestUser] {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TestUser{}))
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return &UnitOfWork[*TestUser]{
+		db:           db,
+		ctx:          context.Background(),
+		repositories: make(map[string]interface{}),
+	}
+}
+
+func TestUnitOfWork_Parallel(t *testing.T) {
+	uow := setupSharedTestDB(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		_, err := uow.Insert(ctx, &TestUser{
+			Name:  fmt.Sprintf("Parallel %d", i),
+			Email: fmt.Sprintf("parallel%d@example.com", i),
+			Slug:  fmt.Sprintf("parallel-%d", i),
+		})
+		require.NoError(t, err)
+	}
+
+	var all []*TestUser
+	var first *TestUser
+	err := uow.Parallel(ctx,
+		func(w persistence.IUnitOfWork[*TestUser]) (err error) {
+			all, err = w.FindAll(ctx)
+			return err
+		},
+		func(w persistence.IUnitOfWork[*TestUser]) (err error) {
+			first, err = w.FindOneById(ctx, 1)
+			return err
+		},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "Parallel 1", first.GetName())
+
+	// Failures are joined and reported together
+	err = uow.Parallel(ctx,
+		func(w persistence.IUnitOfWork[*TestUser]) error {
+			_, err := w.FindOneById(ctx, 999)
+			return err
+		},
+		func(w persistence.IUnitOfWork[*TestUser]) error { return nil },
+	)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestWarmUp_PreEstablishesConnections(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxIdleConns(3)
+
+	ctx := context.Background()
+	require.NoError(t, warmUp(ctx, sqlDB, 3, []string{"SELECT 1"}))
+	assert.Equal(t, 3, sqlDB.Stats().Idle)
+
+	// Invalid statements surface at startup instead of on the first request
+	err = warmUp(ctx, sqlDB, 1, []string{"SELEC 1"})
+	assert.Error(t, err)
+
+	config := &Config{WarmUpConnections: 50, MaxIdleConns: 10, MaxOpenConns: 5}
+	assert.Equal(t, 5, config.warmUpTarget())
+}
+
+func TestUnitOfWork_UpdateReturning(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	inserted, err := uow.Insert(ctx, &TestUser{Name: "Original", Email: "returning@example.com", Slug: "returning"})
+	require.NoError(t, err)
+
+	queries := 0
+	require.NoError(t, uow.db.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		queries++
+	}))
+
+	changes := &TestUser{Name: "Renamed"}
+	updated, err := uow.Update(ctx, identifier.ByID(inserted.GetID()), changes)
+	require.NoError(t, err)
+
+	// The row comes back from the UPDATE itself, including untouched columns
+	assert.Equal(t, 0, queries)
+	assert.Equal(t, "Renamed", updated.Name)
+	assert.Equal(t, "returning@example.com", updated.Email)
+	assert.Equal(t, "returning", updated.Slug)
+	assert.Empty(t, changes.Email)
+
+	_, err = uow.Update(ctx, identifier.ByID(999), &TestUser{Name: "Missing"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestUnitOfWork_FindAllWithKeyset(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	// Duplicate names exercise the primary key tie-breaker
+	for i, name := range []string{"d", "a", "c", "b", "c", "e", "a"} {
+		_, err := uow.Insert(ctx, &TestUser{Name: name, Email: fmt.Sprintf("k%d@example.com", i), Slug: fmt.Sprintf("k%d", i)})
+		require.NoError(t, err)
+	}
+
+	var names []string
+	query := domain.KeysetParams[*TestUser]{SortField: "name", Limit: 3}
+	for {
+		page, err := uow.FindAllWithKeyset(ctx, query)
+		require.NoError(t, err)
+		for _, user := range page.Items {
+			names = append(names, user.Name)
+		}
+		if !page.HasNext {
+			break
+		}
+
+		// Round-trip the cursor the way an API client would
+		cursor, err := domain.DecodeCursor(domain.EncodeCursor(page.Cursors[len(page.Cursors)-1]))
+		require.NoError(t, err)
+		query.After = &cursor
+	}
+	assert.Equal(t, []string{"a", "a", "b", "c", "c", "d", "e"}, names)
+
+	// Backward paging returns the rows just before the cursor, in sort order
+	last, err := uow.FindAllWithKeyset(ctx, domain.KeysetParams[*TestUser]{SortField: "created_at", Direction: domain.SortDesc, Backward: true, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, last.Items, 2)
+	assert.Equal(t, []int{2, 1}, []int{last.Items[0].ID, last.Items[1].ID})
+	assert.True(t, last.HasPrevious)
+
+	cursor, err := domain.DecodeCursor(domain.EncodeCursor(last.Cursors[0]))
+	require.NoError(t, err)
+	before, err := uow.FindAllWithKeyset(ctx, domain.KeysetParams[*TestUser]{SortField: "created_at", Direction: domain.SortDesc, Before: &cursor, Backward: true, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 3}, []int{before.Items[0].ID, before.Items[1].ID})
+	assert.True(t, before.HasNext)
+
+	_, err = uow.FindAllWithKeyset(ctx, domain.KeysetParams[*TestUser]{SortField: "name; DROP TABLE test_users"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_Export(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Ada", "Grace, Hopper", "Linus"} {
+		_, err := uow.Insert(ctx, &TestUser{Name: name, Email: fmt.Sprintf("e%d@example.com", i), Slug: fmt.Sprintf("e%d", i)})
+		require.NoError(t, err)
+	}
+
+	var out strings.Builder
+	err := uow.Export(ctx, domain.QueryParams[*TestUser]{Sort: domain.SortMap{"name": domain.SortDesc}}, domain.CSVWriterOptions{
+		Columns:   []string{"id", "Name", "deleted_at"},
+		Headers:   map[string]string{"name": "Full name"},
+		NullValue: "NULL",
+	}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "id,Full name,deleted_at\n3,Linus,NULL\n2,\"Grace, Hopper\",NULL\n1,Ada,NULL\n", out.String())
+
+	// Soft-deleted rows are not exported
+	_, err = uow.SoftDelete(ctx, identifier.ByID(2))
+	require.NoError(t, err)
+	out.Reset()
+	require.NoError(t, uow.Export(ctx, domain.QueryParams[*TestUser]{}, domain.CSVWriterOptions{Columns: []string{"slug"}, NoHeader: true}, &out))
+	assert.Equal(t, "e0\ne2\n", out.String())
+
+	err = uow.Export(ctx, domain.QueryParams[*TestUser]{}, domain.CSVWriterOptions{Columns: []string{"password"}}, &out)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_ExportImportJSON(t *testing.T) {
+	source := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err := source.Insert(ctx, &TestUser{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("j%d@example.com", i), Slug: fmt.Sprintf("j%d", i)})
+		require.NoError(t, err)
+	}
+
+	var progress []int64
+	var out strings.Builder
+	n, err := source.ExportJSON(ctx, domain.QueryParams[*TestUser]{}, domain.JSONExportOptions{
+		ProgressEvery: 2,
+		Progress:      func(processed int64) { progress = append(progress, processed) },
+	}, &out)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, []int64{2, 4, 5}, progress)
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 5)
+
+	// Importing into a database that already holds a stale copy of one row overwrites it
+	target := setupTestDB(t)
+	_, err = target.Insert(ctx, &TestUser{Name: "Stale", Email: "j0@example.com", Slug: "j0"})
+	require.NoError(t, err)
+
+	progress = nil
+	n, err = target.ImportJSON(ctx, strings.NewReader(out.String()), domain.JSONImportOptions{
+		BatchSize: 2,
+		Progress:  func(processed int64) { progress = append(progress, processed) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, []int64{2, 4, 5}, progress)
+
+	users, err := target.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 5)
+	first, err := target.FindOneById(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "User 0", first.Name)
+
+	// A JSON array is accepted too, and re-importing is idempotent
+	n, err = target.ImportJSON(ctx, strings.NewReader(`[{"id": 2, "name": "Renamed", "email": "j1@example.com", "slug": "j1"}]`), domain.JSONImportOptions{ConflictColumns: []string{"slug"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	second, err := target.FindOneById(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", second.Name)
+
+	_, err = target.ImportJSON(ctx, strings.NewReader("{\"id\": 9}\n{not json"), domain.JSONImportOptions{})
+	assert.Error(t, err)
+}
+
+func TestUnitOfWork_FindAllByIdentifierAndPurgeTrashed(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("p%d@example.com", i), Slug: fmt.Sprintf("p%d", i)})
+		require.NoError(t, err)
+	}
+
+	users, total, err := uow.FindAllByIdentifier(ctx, identifier.New().In("slug", []interface{}{"p0", "p2"}), domain.QueryParams[*TestUser]{
+		Sort:  domain.SortMap{"id": domain.SortDesc},
+		Limit: 1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, "User 2", users[0].Name)
+
+	_, err = uow.SoftDelete(ctx, identifier.ByID(1))
+	require.NoError(t, err)
+	_, err = uow.SoftDelete(ctx, identifier.ByID(2))
+	require.NoError(t, err)
+
+	// Only trashed rows matching the identifier are removed
+	purged, err := uow.PurgeTrashed(ctx, identifier.ByID(1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	purged, err = uow.PurgeTrashed(ctx, identifier.ByID(3))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), purged)
+
+	// Emptying the whole trash needs the explicit acknowledgement
+	_, err = uow.PurgeTrashed(ctx, nil)
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+	_, err = uow.PurgeTrashed(ctx, identifier.New())
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+
+	purged, err = uow.PurgeTrashed(ctx, identifier.New().AllowFullTableOperation())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	trashed, total, err := uow.GetTrashedWithPagination(ctx, domain.QueryParams[*TestUser]{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+	assert.Equal(t, uint(0), total)
+
+	remaining, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, remaining, 2)
+}
+
+type userRepository struct {
+	uow *UnitOfWork[*TestUser]
+}
+
+func TestGetTypedRepository(t *testing.T) {
+	uow := setupTestDB(t)
+
+	RegisterTypedRepository(uow, "users", &userRepository{uow: uow})
+	repo, err := GetTypedRepository[*userRepository](uow, "users")
+	require.NoError(t, err)
+	assert.Same(t, uow, repo.uow)
+
+	_, err = GetTypedRepository[*BaseRepository[*TestUser]](uow, "users")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidRepositoryType)
+
+	// Unregistered keys fall back to the generic repository
+	base, err := GetTypedRepository[*BaseRepository[*TestUser]](uow, "posts")
+	require.NoError(t, err)
+	assert.NotNil(t, base)
+}
+
+func TestBaseRepository(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	// Built from a unit of work, the repository joins its transaction
+	repo := NewBaseRepositoryFromUnitOfWork(uow)
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, repo.CreateBatch(ctx, []*TestUser{
+		{Name: "Ada", Email: "ada@example.com", Slug: "ada"},
+		{Name: "Grace", Email: "grace@example.com", Slug: "grace"},
+	}))
+	uow.RollbackTransaction(ctx)
+
+	count, err := repo.Count(ctx, domain.QueryParams[*TestUser]{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	require.NoError(t, repo.CreateBatch(ctx, []*TestUser{
+		{Name: "Ada", Email: "ada@example.com", Slug: "ada"},
+		{Name: "Grace", Email: "grace@example.com", Slug: "grace"},
+	}))
+
+	// Built from a connection, it sees the same rows
+	direct := NewBaseRepository[*TestUser](uow.db)
+	user, err := direct.GetBySlug(ctx, "grace")
+	require.NoError(t, err)
+	assert.Equal(t, "Grace", user.Name)
+
+	users, err := direct.List(ctx, domain.QueryParams[*TestUser]{
+		Filter: &TestUser{Name: "Ada"},
+		Sort:   domain.SortMap{"name": domain.SortAsc},
+	})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "ada", users[0].Slug)
+
+	count, err = direct.Count(ctx, domain.QueryParams[*TestUser]{Filter: &TestUser{Name: "Ada"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	require.NoError(t, direct.DeleteBatch(ctx, []int64{int64(user.ID)}))
+	_, err = direct.GetByID(ctx, int64(user.ID))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestUnitOfWork_WithContextIsIsolated(t *testing.T) {
+	uow := setupSharedTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, uow.BeginTransaction(ctx))
+	defer uow.RollbackTransaction(ctx)
+
+	type ctxKey struct{}
+	scoped := uow.WithContext(context.WithValue(ctx, ctxKey{}, "request")).(*UnitOfWork[*TestUser])
+	assert.False(t, scoped.IsInTransaction())
+	assert.Equal(t, "request", scoped.GetContext().Value(ctxKey{}))
+
+	// The copy's transaction lifecycle never touches the original's
+	assert.Error(t, scoped.CommitTransaction(ctx))
+	require.NoError(t, scoped.BeginTransaction(ctx))
+	scoped.RollbackTransaction(ctx)
+	assert.True(t, uow.IsInTransaction())
+
+	// Repositories are bound per unit of work
+	uow.RegisterRepository("users", "original")
+	assert.NotEqual(t, "original", scoped.GetRepository("users"))
+
+	_, err := uow.Insert(ctx, &TestUser{Name: "Tx", Email: "tx@example.com", Slug: "tx"})
+	require.NoError(t, err)
+	require.NoError(t, uow.CommitTransaction(ctx))
+	assert.False(t, uow.IsInTransaction())
+}
+
+// Run with -race: sessions must not share transaction state
+func TestUnitOfWork_SessionsAreGoroutineSafe(t *testing.T) {
+	uow := setupSharedTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Name: fmt.Sprintf("Session %d", i), Email: fmt.Sprintf("s%d@example.com", i), Slug: fmt.Sprintf("s%d", i)})
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session := uow.Session()
+			if err := session.BeginTransaction(ctx); err != nil {
+				errs <- err
+				return
+			}
+			defer session.RollbackTransaction(ctx)
+
+			users, err := session.FindAll(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(users) != 5 {
+				errs <- fmt.Errorf("expected 5 users, got %d", len(users))
+			}
+			if !session.(*UnitOfWork[*TestUser]).IsInTransaction() {
+				errs <- errors.New("session lost its transaction")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.False(t, uow.IsInTransaction())
+}
+
+func TestUnitOfWork_UsesCallerContext(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := uow.Insert(ctx, &TestUser{Name: "Ctx", Email: "ctx@example.com", Slug: "ctx"})
+	require.NoError(t, err)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	expired, cancelExpired := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	_, err = uow.FindAll(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = uow.FindOneById(expired, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = uow.Insert(cancelled, &TestUser{Name: "Late", Email: "late@example.com", Slug: "late"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, err = uow.FindAllWithPagination(cancelled, domain.QueryParams[*TestUser]{Limit: 10})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Inside a transaction the per-call context still applies
+	require.NoError(t, uow.BeginTransaction(ctx))
+	_, err = uow.FindAll(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+	users, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	uow.RollbackTransaction(ctx)
+}
+
+type tenantKey struct{}
+
+func TestUnitOfWorkFactory_DefaultScopes(t *testing.T) {
+	base := setupTestDB(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Ada", "Grace", "Draft"} {
+		_, err := base.Insert(ctx, &TestUser{Name: name, Email: fmt.Sprintf("d%d@example.com", i), Slug: fmt.Sprintf("d%d", i), Active: true})
+		require.NoError(t, err)
+	}
+
+	factory := &UnitOfWorkFactory[*TestUser]{db: base.db, settings: newEntitySettings[*TestUser]()}
+	factory.
+		AddDefaultScope("published", WhereScope("name <> ?", "Draft")).
+		AddDefaultScope("tenant", func(ctx context.Context, db *gorm.DB) *gorm.DB {
+			if slug, ok := ctx.Value(tenantKey{}).(string); ok {
+				return db.Where("slug = ?", slug)
+			}
+			return db
+		})
+	uow := factory.CreateWithContext(ctx)
+
+	users, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	tenantCtx := context.WithValue(ctx, tenantKey{}, "d1")
+	users, _, err = uow.FindAllWithPagination(tenantCtx, domain.QueryParams[*TestUser]{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "Grace", users[0].Name)
+
+	// Mutations are scoped too
+	affected, err := uow.UpdateWhere(tenantCtx, identifier.New().AllowFullTableOperation(), map[string]interface{}{"active": false})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	// Opting out per call, selectively or entirely
+	users, err = uow.FindAll(WithoutDefaultScopes(ctx, "published"))
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+	users, err = uow.FindAll(WithoutDefaultScopes(tenantCtx))
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+
+	_, err = uow.FindOneByIdentifier(ctx, identifier.New().Equal("name", "Draft"))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Multi-statement mutations run each statement under the scopes once
+	deleted, err := uow.SoftDelete(ctx, identifier.New().Equal("name", "Ada"))
+	require.NoError(t, err)
+	assert.True(t, deleted.DeletedAt.Valid)
+	restored, err := uow.Restore(ctx, identifier.New().Equal("name", "Ada"))
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+	_, err = uow.HardDelete(ctx, identifier.New().Equal("name", "Draft"))
+	assert.Error(t, err)
+	removed, err := uow.HardDelete(ctx, identifier.New().Equal("name", "Ada"))
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", removed.Name)
+	users, err = uow.FindAll(WithoutDefaultScopes(ctx))
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	// Sessions keep the factory's scopes; removing a scope affects existing units of work
+	users, err = uow.Session().FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	factory.RemoveDefaultScope("published")
+	users, err = uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+}
+
+func TestUnitOfWork_GetTrashedWhere(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Ada", "Alan", "Grace", "Kept"} {
+		_, err := uow.Insert(ctx, &TestUser{Name: name, Email: fmt.Sprintf("t%d@example.com", i), Slug: fmt.Sprintf("t%d", i)})
+		require.NoError(t, err)
+	}
+	for _, id := range []int{1, 2, 3} {
+		_, err := uow.SoftDelete(ctx, identifier.ByID(id))
+		require.NoError(t, err)
+	}
+
+	users, total, err := uow.GetTrashedWhere(ctx, identifier.New().Like("name", "A%"), domain.QueryParams[*TestUser]{
+		Sort:  domain.SortMap{"name": domain.SortDesc},
+		Limit: 1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, "Alan", users[0].Name)
+
+	users, total, err = uow.GetTrashedWhere(ctx, nil, domain.QueryParams[*TestUser]{})
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), total)
+	assert.Len(t, users, 3)
+
+	_, _, err = uow.GetTrashedWhere(ctx, nil, domain.QueryParams[*TestUser]{Sort: domain.SortMap{"name; DROP TABLE test_users": domain.SortAsc}})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_RestoreAllWhere(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Name: fmt.Sprintf("R%d", i), Email: fmt.Sprintf("r%d@example.com", i), Slug: fmt.Sprintf("r%d", i)})
+		require.NoError(t, err)
+		_, err = uow.SoftDelete(ctx, identifier.ByID(i+1))
+		require.NoError(t, err)
+	}
+
+	restored, err := uow.RestoreAllWhere(ctx, identifier.New().In("id", []interface{}{1, 2}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), restored)
+
+	// The unfiltered restore needs the explicit acknowledgement
+	_, err = uow.RestoreAllWhere(ctx, nil)
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+	_, err = uow.RestoreAllWhere(ctx, identifier.New())
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+
+	restored, err = uow.RestoreAllWhere(ctx, identifier.New().AllowFullTableOperation())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), restored)
+
+	users, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+
+	// The deprecated RestoreAll still restores every row
+	_, err = uow.SoftDelete(ctx, identifier.New().In("id", []interface{}{2, 3}))
+	require.NoError(t, err)
+	require.NoError(t, uow.RestoreAll(ctx))
+	users, err = uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+}
+
+func TestUnitOfWork_SyncSince(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Name: fmt.Sprintf("Sync %d", i), Email: fmt.Sprintf("sync%d@example.com", i), Slug: fmt.Sprintf("sync%d", i)})
+		require.NoError(t, err)
+	}
+
+	// Initial load in pages
+	first, err := uow.SyncSince(ctx, domain.SyncParams{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, first.Created, 2)
+	assert.True(t, first.HasMore)
+
+	second, err := uow.SyncSince(ctx, domain.SyncParams{Since: first.Watermark, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, second.Created, 1)
+	assert.Equal(t, 3, second.Created[0].ID)
+	assert.False(t, second.HasMore)
+
+	// Nothing changed since the last watermark
+	idle, err := uow.SyncSince(ctx, domain.SyncParams{Since: second.Watermark})
+	require.NoError(t, err)
+	assert.Empty(t, idle.Created)
+	assert.Equal(t, second.Watermark, idle.Watermark)
+
+	_, err = uow.Update(ctx, identifier.ByID(1), &TestUser{Name: "Renamed"})
+	require.NoError(t, err)
+	_, err = uow.SoftDelete(ctx, identifier.ByID(2))
+	require.NoError(t, err)
+	_, err = uow.Insert(ctx, &TestUser{Name: "New", Email: "sync-new@example.com", Slug: "sync-new"})
+	require.NoError(t, err)
+
+	changes, err := uow.SyncSince(ctx, domain.SyncParams{Since: second.Watermark})
+	require.NoError(t, err)
+	require.Len(t, changes.Updated, 1)
+	assert.Equal(t, "Renamed", changes.Updated[0].Name)
+	require.Len(t, changes.Deleted, 1)
+	assert.Equal(t, 2, changes.Deleted[0].ID)
+	require.Len(t, changes.Created, 1)
+	assert.Equal(t, "New", changes.Created[0].Name)
+	assert.Equal(t, 4, changes.Watermark.ID)
+}
+
+func TestUnitOfWork_ChangeListeners(t *testing.T) {
+	uow := setupTestDB(t)
+	uow.settings = newEntitySettings[*TestUser]()
+	ctx := context.Background()
+
+	var received []domain.Change[*TestUser]
+	uow.settings.addListener(func(_ context.Context, changes []domain.Change[*TestUser]) {
+		received = append(received, changes...)
+	})
+
+	// Outside a transaction changes are delivered immediately
+	user, err := uow.Insert(ctx, &TestUser{Name: "Hooked", Email: "hooked@example.com", Slug: "hooked"})
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, domain.ChangeCreated, received[0].Kind)
+	assert.Equal(t, user.ID, received[0].Entity.ID)
+
+	// Rolled back changes and hooks are dropped
+	received = nil
+	ran := false
+	require.NoError(t, uow.BeginTransaction(ctx))
+	_, err = uow.Insert(ctx, &TestUser{Name: "Dropped", Email: "dropped@example.com", Slug: "dropped"})
+	require.NoError(t, err)
+	uow.AfterCommit(ctx, func(context.Context) { ran = true })
+	assert.Empty(t, received)
+	uow.RollbackTransaction(ctx)
+	assert.Empty(t, received)
+	assert.False(t, ran)
+
+	// Committed changes are delivered once, followed by the hooks
+	require.NoError(t, uow.BeginTransaction(ctx))
+	user.Name = "Renamed"
+	_, err = uow.Update(ctx, identifier.New().Equal("id", user.ID), user)
+	require.NoError(t, err)
+	_, err = uow.SoftDelete(ctx, identifier.New().Equal("id", user.ID))
+	require.NoError(t, err)
+	uow.AfterCommit(ctx, func(context.Context) { ran = true })
+	require.NoError(t, uow.CommitTransaction(ctx))
+	require.Len(t, received, 2)
+	assert.Equal(t, domain.ChangeUpdated, received[0].Kind)
+	assert.Equal(t, "Renamed", received[0].Entity.Name)
+	assert.Equal(t, domain.ChangeDeleted, received[1].Kind)
+	assert.True(t, ran)
+}
+
+func TestUpdateBuilder(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for _, slug := range []string{"alpha", "beta", "gamma"} {
+		_, err := uow.Insert(ctx, &TestUser{Name: slug, Email: slug + "@example.com", Slug: slug})
+		require.NoError(t, err)
+	}
+
+	updates, err := NewUpdate().
+		Set("name", Column("slug")).
+		Case("name").
+		When(identifier.New().Equal("slug", "alpha"), "First").
+		When(identifier.New().In("slug", []interface{}{"beta"}), Column("email")).
+		End().
+		Build()
+	require.NoError(t, err)
+
+	affected, err := uow.UpdateWhere(ctx, identifier.New().In("slug", []interface{}{"alpha", "beta", "gamma"}), updates)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+
+	names := make(map[string]string)
+	users, _, err := uow.FindAllByIdentifier(ctx, identifier.New().Like("slug", "%a%"), domain.QueryParams[*TestUser]{})
+	require.NoError(t, err)
+	for _, user := range users {
+		names[user.Slug] = user.Name
+	}
+	// Case replaces the earlier Set on the same column; unmatched rows keep their value
+	assert.Equal(t, map[string]string{"alpha": "First", "beta": "beta@example.com", "gamma": "gamma"}, names)
+
+	// Column arithmetic renders against the quoted columns
+	updates, err = NewUpdate().Decrement("active", Column("id")).Increment("id", 1).Build()
+	require.NoError(t, err)
+	stmt := uow.db.Session(&gorm.Session{DryRun: true}).Model(&TestUser{}).Where("id = ?", 1).Updates(updates).Statement
+	assert.Contains(t, stmt.SQL.String(), "`active`=`active` - `id`")
+	assert.Contains(t, stmt.SQL.String(), "`id`=`id` + ?")
+
+	for _, builder := range []*UpdateBuilder{
+		NewUpdate(),
+		NewUpdate().Set("name; DROP TABLE test_users", "x"),
+		NewUpdate().Set("name", Column("1=1")),
+		NewUpdate().Case("name").End(),
+		NewUpdate().Case("name").When(identifier.New(), "x").End(),
+	} {
+		_, err := builder.Build()
+		assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	}
+}
+
+func TestUnitOfWork_FindTopPerGroup(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Ann", "Bob", "Ann", "Ann", "Bob", "Cy"} {
+		_, err := uow.Insert(ctx, &TestUser{Name: name, Email: fmt.Sprintf("top%d@example.com", i), Slug: fmt.Sprintf("top%d", i)})
+		require.NoError(t, err)
+	}
+	_, err := uow.SoftDelete(ctx, identifier.New().Equal("slug", "top3"))
+	require.NoError(t, err)
+
+	users, err := uow.FindTopPerGroup(ctx, identifier.New().Like("slug", "top%"), domain.TopNParams[*TestUser]{
+		PartitionBy: []string{"name"},
+		OrderBy:     []domain.OrderField{{Field: "slug", Direction: domain.SortDesc}},
+		N:           1,
+	})
+	require.NoError(t, err)
+
+	slugs := make([]string, len(users))
+	for i, user := range users {
+		slugs[i] = user.Slug
+	}
+	// The newest live row per name; the soft-deleted top3 is not ranked
+	assert.Equal(t, []string{"top2", "top4", "top5"}, slugs)
+
+	users, err = uow.FindTopPerGroup(ctx, nil, domain.TopNParams[*TestUser]{
+		OrderBy: []domain.OrderField{{Field: "Slug"}},
+		N:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "top0", users[0].Slug)
+
+	_, err = uow.FindTopPerGroup(ctx, nil, domain.TopNParams[*TestUser]{PartitionBy: []string{"missing"}, OrderBy: []domain.OrderField{{Field: "id"}}, N: 1})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = uow.FindTopPerGroup(ctx, nil, domain.TopNParams[*TestUser]{OrderBy: []domain.OrderField{{Field: "id"}}})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_ReadYourWritesWithReplicas(t *testing.T) {
+	primary := setupTestDB(t).db
+	replica, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, replica.AutoMigrate(&TestUser{}))
+	require.NoError(t, UseReplicas(primary, []*gorm.DB{replica}, ReplicaOptions{ReadYourWritesWindow: 100 * time.Millisecond}))
+
+	ctx := context.Background()
+	writer := newUnitOfWork[*TestUser](nil, primary)
+	other := newUnitOfWork[*TestUser](nil, primary)
+
+	_, err = writer.Insert(ctx, &TestUser{Name: "Fresh", Email: "fresh@example.com", Slug: "fresh"})
+	require.NoError(t, err)
+
+	// The writer reads its own write from the primary; others read the lagging replica
+	users, err := writer.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	users, err = other.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	// Once the window passes the writer reads from the replica as well
+	time.Sleep(150 * time.Millisecond)
+	users, err = writer.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	// A shared context pins every unit of work using it, and a commit restarts the window
+	shared := ReadYourWrites(ctx)
+	require.NoError(t, other.BeginTransaction(shared))
+	_, err = other.Insert(shared, &TestUser{Name: "Tx", Email: "tx@example.com", Slug: "tx"})
+	require.NoError(t, err)
+	require.NoError(t, other.CommitTransaction(shared))
+
+	users, err = writer.FindAll(shared)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+}
+
+func TestUnitOfWork_Savepoints(t *testing.T) {
+	uow := setupTestDB(t)
+	uow.settings = newEntitySettings[*TestUser]()
+	ctx := context.Background()
+
+	var received []string
+	uow.settings.addListener(func(_ context.Context, changes []domain.Change[*TestUser]) {
+		for _, change := range changes {
+			received = append(received, change.Entity.Slug)
+		}
+	})
+
+	assert.ErrorIs(t, uow.Savepoint(ctx, "outside"), uowerrors.ErrTransactionNotStarted)
+
+	require.NoError(t, uow.BeginTransaction(ctx))
+	_, err := uow.Insert(ctx, &TestUser{Name: "Kept", Email: "kept@example.com", Slug: "kept"})
+	require.NoError(t, err)
+
+	require.NoError(t, uow.Savepoint(ctx, "item"))
+	_, err = uow.Insert(ctx, &TestUser{Name: "Undone", Email: "undone@example.com", Slug: "undone"})
+	require.NoError(t, err)
+	hookRan := false
+	uow.AfterCommit(ctx, func(context.Context) { hookRan = true })
+	require.NoError(t, uow.RollbackTo(ctx, "item"))
+
+	// The savepoint survives a rollback to it and can be released afterwards
+	require.NoError(t, uow.ReleaseSavepoint(ctx, "item"))
+	assert.ErrorIs(t, uow.RollbackTo(ctx, "item"), uowerrors.ErrSavepointNotFound)
+	require.NoError(t, uow.CommitTransaction(ctx))
+
+	users, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "kept", users[0].Slug)
+	assert.Equal(t, []string{"kept"}, received)
+	assert.False(t, hookRan)
+}
+
+// testCategory is a self-referencing tree node
+type testCategory struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Slug      string         `json:"slug"`
+	Name      string         `json:"name"`
+	ParentID  *int           `gorm:"index" json:"parent_id"`
+	Children  []testCategory `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (c *testCategory) GetID() int                    { return c.ID }
+func (c *testCategory) GetSlug() string               { return c.Slug }
+func (c *testCategory) SetSlug(slug string)           { c.Slug = slug }
+func (c *testCategory) GetCreatedAt() time.Time       { return c.CreatedAt }
+func (c *testCategory) GetUpdatedAt() time.Time       { return c.UpdatedAt }
+func (c *testCategory) GetArchivedAt() gorm.DeletedAt { return c.DeletedAt }
+func (c *testCategory) GetName() string               { return c.Name }
+
+func TestUnitOfWork_Tree(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testCategory{}))
+	uow := newUnitOfWork[*testCategory](nil, db)
+	ctx := context.Background()
+
+	// root(1) -> books(2) -> fiction(3) -> scifi(4)
+	//         -> music(5)
+	parents := map[string]int{"root": 0, "books": 1, "fiction": 2, "scifi": 3, "music": 1}
+	for _, name := range []string{"root", "books", "fiction", "scifi", "music"} {
+		category := &testCategory{Name: name, Slug: name}
+		if parent := parents[name]; parent != 0 {
+			category.ParentID = &parent
+		}
+		_, err := uow.Insert(ctx, category)
+		require.NoError(t, err)
+	}
+	names := func(categories []*testCategory) []string {
+		result := make([]string, len(categories))
+		for i, category := range categories {
+			result[i] = category.Name
+		}
+		return result
+	}
+
+	ancestors, err := uow.Ancestors(ctx, 4, domain.TreeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root", "books", "fiction"}, names(ancestors))
+
+	descendants, err := uow.Descendants(ctx, 1, domain.TreeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"books", "music", "fiction", "scifi"}, names(descendants))
+
+	descendants, err = uow.Descendants(ctx, 1, domain.TreeOptions{ParentField: "ParentID", MaxDepth: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"books", "music"}, names(descendants))
+
+	// Cycles are rejected; moving fiction under music carries scifi along
+	assert.ErrorIs(t, uow.MoveSubtree(ctx, 2, 4, domain.TreeOptions{}), uowerrors.ErrInvalidQueryParams)
+	require.NoError(t, uow.MoveSubtree(ctx, 3, 5, domain.TreeOptions{}))
+	ancestors, err = uow.Ancestors(ctx, 4, domain.TreeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"root", "music", "fiction"}, names(ancestors))
+
+	// Soft-deleted nodes hide their subtrees; moving to 0 makes a root
+	_, err = uow.SoftDelete(ctx, identifier.New().Equal("id", 5))
+	require.NoError(t, err)
+	descendants, err = uow.Descendants(ctx, 1, domain.TreeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"books"}, names(descendants))
+
+	require.NoError(t, uow.MoveSubtree(ctx, 3, 0, domain.TreeOptions{}))
+	ancestors, err = uow.Ancestors(ctx, 3, domain.TreeOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, ancestors)
+
+	assert.ErrorIs(t, uow.MoveSubtree(ctx, 99, 0, domain.TreeOptions{}), uowerrors.ErrEntityNotFound)
+	_, err = uow.Descendants(ctx, 1, domain.TreeOptions{ParentField: "owner_id"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+type ticketStatus string
+
+var ticketStatuses = enum.Define[ticketStatus]("ticket_status", "open", "closed")
+
+func (s ticketStatus) Valid() bool                  { return ticketStatuses.Valid(s) }
+func (s ticketStatus) Value() (driver.Value, error) { return ticketStatuses.Value(s) }
+func (s *ticketStatus) Scan(src interface{}) error  { return ticketStatuses.Scan(s, src) }
+func (ticketStatus) GormDataType() string           { return ticketStatuses.Name() }
+
+type testTicket struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Slug      string         `json:"slug"`
+	Name      string         `json:"name"`
+	Status    ticketStatus   `json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (t *testTicket) GetID() int                    { return t.ID }
+func (t *testTicket) GetSlug() string               { return t.Slug }
+func (t *testTicket) SetSlug(slug string)           { t.Slug = slug }
+func (t *testTicket) GetCreatedAt() time.Time       { return t.CreatedAt }
+func (t *testTicket) GetUpdatedAt() time.Time       { return t.UpdatedAt }
+func (t *testTicket) GetArchivedAt() gorm.DeletedAt { return t.DeletedAt }
+func (t *testTicket) GetName() string               { return t.Name }
+
+func TestUnitOfWork_EnumColumns(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testTicket{}))
+	uow := newUnitOfWork[*testTicket](nil, db)
+	ctx := context.Background()
+
+	_, err := uow.Insert(ctx, &testTicket{Name: "Login broken", Status: "open"})
+	require.NoError(t, err)
+	_, err = uow.Insert(ctx, &testTicket{Name: "Typo", Status: "opne"})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityValidation)
+
+	found, err := uow.FindOneByIdentifier(ctx, identifier.New().Equal("status", "open"))
+	require.NoError(t, err)
+	assert.Equal(t, ticketStatus("open"), found.Status)
+
+	// Undeclared values are rejected before the query runs, as plain strings or typed values
+	_, err = uow.FindOneByIdentifier(ctx, identifier.New().Equal("status", "opne"))
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, _, err = uow.FindAllByIdentifier(ctx, identifier.New().In("status", []interface{}{"open", ticketStatus("shut")}), domain.QueryParams[*testTicket]{})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = uow.UpdateWhere(ctx, identifier.New().Equal("status", "gone"), map[string]interface{}{"name": "x"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_Aggregate(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Ann", "Bob", "Ann", "Ann", "Bob"} {
+		_, err := uow.Insert(ctx, &TestUser{Name: name, Email: fmt.Sprintf("agg%d@example.com", i), Slug: fmt.Sprintf("agg%d", i)})
+		require.NoError(t, err)
+	}
+
+	rows, err := uow.Aggregate(ctx, identifier.New().Like("slug", "agg%"), domain.AggregateParams[*TestUser]{
+		GroupBy: []string{"name"},
+		Metrics: []domain.Metric{{Func: domain.Count}, {Func: domain.Max, Field: "id", As: "last_id"}},
+		OrderBy: []domain.OrderField{{Field: "count", Direction: domain.SortDesc}},
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "Ann", rows[0]["name"])
+	assert.EqualValues(t, 3, rows[0]["count"])
+	assert.EqualValues(t, 4, rows[0]["last_id"])
+	assert.EqualValues(t, 2, rows[1]["count"])
+
+	// time_bucket needs TimescaleDB, so only the generated statement is checked here
+	dryRun := uow.db.Session(&gorm.Session{DryRun: true})
+	query, err := aggregateQuery[*TestUser](dryRun, nil, domain.AggregateParams[*TestUser]{
+		Bucket:  &domain.TimeBucket{Field: "created_at", Width: time.Hour},
+		Metrics: []domain.Metric{{Func: domain.Count}},
+	})
+	require.NoError(t, err)
+	var out []map[string]interface{}
+	stmt := query.Find(&out).Statement
+	assert.Contains(t, stmt.SQL.String(), `time_bucket(CAST(? AS interval), "test_users"."created_at") AS "bucket", COUNT(*) AS "count"`)
+	assert.Contains(t, stmt.SQL.String(), "GROUP BY 1 ORDER BY 1")
+	assert.Equal(t, "3600000000 microseconds", stmt.Vars[0])
+
+	for _, params := range []domain.AggregateParams[*TestUser]{
+		{},
+		{Metrics: []domain.Metric{{Func: domain.Sum}}},
+		{Metrics: []domain.Metric{{Func: "median", Field: "id"}}},
+		{GroupBy: []string{"missing"}, Metrics: []domain.Metric{{Func: domain.Count}}},
+		{Metrics: []domain.Metric{{Func: domain.Count}}, OrderBy: []domain.OrderField{{Field: "name"}}},
+	} {
+		_, err := uow.Aggregate(ctx, nil, params)
+		assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	}
+}
+
+func TestUnitOfWork_ResolveIDByUniqueFields(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Ann", "Bob", "Ann"} {
+		_, err := uow.Insert(ctx, &TestUser{Name: name, Email: fmt.Sprintf("key%d@example.com", i), Slug: fmt.Sprintf("key%d", i)})
+		require.NoError(t, err)
+	}
+
+	id, err := uow.ResolveIDByUniqueFields(ctx, map[string]interface{}{"name": "Ann", "Slug": "key2"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, id)
+
+	id, err = uow.ResolveIDByIdentifier(ctx, identifier.New().Equal("name", "Bob").Like("email", "key%"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, id)
+
+	_, err = uow.ResolveIDByUniqueFields(ctx, map[string]interface{}{"name": "Ann", "slug": "key1"})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityNotFound)
+	_, err = uow.ResolveIDByUniqueFields(ctx, map[string]interface{}{"name": "Ann"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = uow.ResolveIDByUniqueFields(ctx, map[string]interface{}{"tenant_id": 1})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = uow.ResolveIDByIdentifier(ctx, identifier.New())
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_FindOneOptions(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testCategory{}))
+	uow := newUnitOfWork[*testCategory](nil, db)
+	ctx := context.Background()
+
+	root, err := uow.Insert(ctx, &testCategory{Name: "root", Slug: "root"})
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		_, err := uow.Insert(ctx, &testCategory{Name: name, Slug: name, ParentID: &root.ID})
+		require.NoError(t, err)
+	}
+
+	plain, err := uow.FindOneById(ctx, root.ID)
+	require.NoError(t, err)
+	assert.Empty(t, plain.Children)
+
+	loaded, err := uow.FindOneById(ctx, root.ID, domain.WithPreload("Children"), domain.WithSelect("name"))
+	require.NoError(t, err)
+	assert.Len(t, loaded.Children, 2)
+	assert.Equal(t, "root", loaded.Name)
+	assert.Empty(t, loaded.Slug, "unselected columns stay empty")
+
+	found, err := uow.FindOneByIdentifier(ctx, identifier.New().Equal("slug", "root"), domain.WithPreload("Children"))
+	require.NoError(t, err)
+	assert.Len(t, found.Children, 2)
+
+	_, err = uow.FindOne(ctx, &testCategory{Slug: "root"}, domain.WithSelect("missing"))
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_Touch(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := uow.Insert(ctx, &TestUser{Name: "Toucher", Email: "touch@example.com", Slug: "touch"})
+	require.NoError(t, err)
+	created := user.CreatedAt
+
+	time.Sleep(5 * time.Millisecond)
+	affected, err := uow.Touch(ctx, identifier.New().Equal("id", user.ID))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	touched, err := uow.FindOneById(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, touched.UpdatedAt.After(user.UpdatedAt))
+	assert.Equal(t, "Toucher", touched.Name)
+
+	// A custom column is set on its own; updated_at stays put
+	time.Sleep(5 * time.Millisecond)
+	_, err = uow.Touch(ctx, identifier.New().Equal("id", user.ID), "CreatedAt")
+	require.NoError(t, err)
+	softTouched, err := uow.FindOneById(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, softTouched.CreatedAt.After(created))
+	assert.True(t, softTouched.UpdatedAt.Equal(touched.UpdatedAt))
+
+	_, err = uow.Touch(ctx, identifier.New().Equal("id", user.ID), "last_seen_at")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_Clone(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	original, err := uow.Insert(ctx, &TestUser{Name: "Template", Email: "template@example.com", Slug: "template"})
+	require.NoError(t, err)
+
+	require.NoError(t, uow.BeginTransaction(ctx))
+	first, err := uow.Clone(ctx, identifier.New().Equal("id", original.ID), func(u *TestUser) {
+		u.Email = "template-copy@example.com"
+	})
+	require.NoError(t, err)
+	second, err := uow.Clone(ctx, identifier.New().Equal("id", original.ID), func(u *TestUser) {
+		u.Email = "template-copy2@example.com"
+	})
+	require.NoError(t, err)
+	require.NoError(t, uow.CommitTransaction(ctx))
+
+	assert.NotEqual(t, original.ID, first.ID)
+	assert.Equal(t, "Template", first.Name)
+	assert.Equal(t, "template-copy", first.Slug)
+	assert.Equal(t, "template-copy-2", second.Slug)
+	assert.False(t, first.CreatedAt.IsZero())
+
+	// Trashed copies still hold their slug
+	_, err = uow.SoftDelete(ctx, identifier.New().Equal("id", second.ID))
+	require.NoError(t, err)
+	third, err := uow.Clone(ctx, identifier.New().Equal("id", original.ID), func(u *TestUser) {
+		u.Email = "template-copy3@example.com"
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "template-copy-3", third.Slug)
+
+	_, err = uow.Clone(ctx, identifier.New().Equal("id", 999))
+	assert.Error(t, err)
+}
+
+func TestUnitOfWork_SlugConflictRetry(t *testing.T) {
+	base := setupTestDB(t)
+	factory := (&UnitOfWorkFactory[*TestUser]{db: base.db, settings: newEntitySettings[*TestUser]()}).RetrySlugConflicts(2)
+	uow := newUnitOfWork[*TestUser](nil, base.db)
+	uow.settings = factory.settings
+	ctx := context.Background()
+
+	for i, want := range []string{"news", "news-2", "news-3"} {
+		user, err := uow.Insert(ctx, &TestUser{Name: "News", Email: fmt.Sprintf("news%d@example.com", i), Slug: "news"})
+		require.NoError(t, err)
+		assert.Equal(t, want, user.Slug)
+	}
+
+	// Retries are exhausted; inside a transaction the earlier work survives the failed attempts
+	require.NoError(t, uow.BeginTransaction(ctx))
+	kept, err := uow.Insert(ctx, &TestUser{Name: "Kept", Email: "kept@example.com", Slug: "kept"})
+	require.NoError(t, err)
+	_, err = uow.Insert(ctx, &TestUser{Name: "News", Email: "news9@example.com", Slug: "news"})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityExists)
+	require.NoError(t, uow.CommitTransaction(ctx))
+	_, err = uow.FindOneById(ctx, kept.ID)
+	require.NoError(t, err)
+
+	// Other unique violations are not retried
+	_, err = uow.Insert(ctx, &TestUser{Name: "Dup", Email: "kept@example.com", Slug: "other"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, uowerrors.ErrEntityExists)
+}
+
+type testDocument struct {
+	ID            int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name          string         `json:"name"`
+	DeletedReason string         `json:"deleted_reason"`
+	DeletedBy     string         `json:"deleted_by"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (d *testDocument) GetID() int                    { return d.ID }
+func (d *testDocument) GetSlug() string               { return "" }
+func (d *testDocument) SetSlug(string)                {}
+func (d *testDocument) GetCreatedAt() time.Time       { return d.CreatedAt }
+func (d *testDocument) GetUpdatedAt() time.Time       { return d.UpdatedAt }
+func (d *testDocument) GetArchivedAt() gorm.DeletedAt { return d.DeletedAt }
+func (d *testDocument) GetName() string               { return d.Name }
+
+// testNote keeps archive metadata in uow_archive_metadata
+type testNote struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string         `json:"name"`
+	Reason    string         `gorm:"-" json:"reason"`
+	Actor     string         `gorm:"-" json:"actor"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (n *testNote) GetID() int                          { return n.ID }
+func (n *testNote) GetSlug() string                     { return "" }
+func (n *testNote) SetSlug(string)                      {}
+func (n *testNote) GetCreatedAt() time.Time             { return n.CreatedAt }
+func (n *testNote) GetUpdatedAt() time.Time             { return n.UpdatedAt }
+func (n *testNote) GetArchivedAt() gorm.DeletedAt       { return n.DeletedAt }
+func (n *testNote) GetName() string                     { return n.Name }
+func (n *testNote) SetArchiveInfo(reason, actor string) { n.Reason, n.Actor = reason, actor }
+
+func TestUnitOfWork_SoftDeleteWithReason(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testDocument{}, &testNote{}, &domain.ArchiveMetadata{}))
+	ctx := context.Background()
+
+	// Dedicated columns
+	documents := newUnitOfWork[*testDocument](nil, db)
+	document, err := documents.Insert(ctx, &testDocument{Name: "Contract"})
+	require.NoError(t, err)
+	deleted, err := documents.SoftDeleteWithReason(ctx, identifier.New().Equal("id", document.ID), "expired", "alice")
+	require.NoError(t, err)
+	assert.True(t, deleted.DeletedAt.Valid)
+	assert.Equal(t, "expired", deleted.DeletedReason)
+
+	trashedDocuments, err := documents.GetTrashed(ctx)
+	require.NoError(t, err)
+	require.Len(t, trashedDocuments, 1)
+	assert.Equal(t, "alice", trashedDocuments[0].DeletedBy)
+
+	var records int64
+	require.NoError(t, db.Model(&domain.ArchiveMetadata{}).Count(&records).Error)
+	assert.Zero(t, records)
+
+	// Archive metadata table
+	notes := newUnitOfWork[*testNote](nil, db)
+	note, err := notes.Insert(ctx, &testNote{Name: "Draft"})
+	require.NoError(t, err)
+	_, err = notes.SoftDeleteWithReason(ctx, identifier.New().Equal("id", note.ID), "duplicate", "bob")
+	require.NoError(t, err)
+
+	trashedNotes, _, err := notes.GetTrashedWhere(ctx, identifier.New(), domain.QueryParams[*testNote]{})
+	require.NoError(t, err)
+	require.Len(t, trashedNotes, 1)
+	assert.Equal(t, "duplicate", trashedNotes[0].Reason)
+	assert.Equal(t, "bob", trashedNotes[0].Actor)
+
+	_, err = notes.Restore(ctx, identifier.New().Equal("id", note.ID))
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&domain.ArchiveMetadata{}).Count(&records).Error)
+	assert.Zero(t, records)
+}
+
+func TestUnitOfWork_RestoreAllWhereClearsArchive(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testDocument{}, &testNote{}, &domain.ArchiveMetadata{}))
+	ctx := context.Background()
+
+	// Dedicated columns are reset along with deleted_at
+	documents := newUnitOfWork[*testDocument](nil, db)
+	documents.settings = newEntitySettings[*testDocument]()
+	var restoredDocuments []domain.Change[*testDocument]
+	documents.settings.addListener(func(_ context.Context, changes []domain.Change[*testDocument]) {
+		restoredDocuments = append(restoredDocuments, changes...)
+	})
+	for _, name := range []string{"Contract", "Invoice"} {
+		document, err := documents.Insert(ctx, &testDocument{Name: name})
+		require.NoError(t, err)
+		_, err = documents.SoftDeleteWithReason(ctx, identifier.New().Equal("id", document.ID), "expired", "alice")
+		require.NoError(t, err)
+	}
+	restoredDocuments = nil
+
+	restored, err := documents.RestoreAllWhere(ctx, identifier.New().AllowFullTableOperation())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), restored)
+	require.Len(t, restoredDocuments, 2)
+	for _, change := range restoredDocuments {
+		assert.Equal(t, domain.ChangeUpdated, change.Kind)
+		assert.False(t, change.Entity.DeletedAt.Valid)
+		assert.Empty(t, change.Entity.DeletedReason)
+		assert.Empty(t, change.Entity.DeletedBy)
+	}
+	var archived int64
+	require.NoError(t, db.Model(&testDocument{}).Where("deleted_reason <> '' OR deleted_by <> ''").Count(&archived).Error)
+	assert.Zero(t, archived)
+
+	// The archive metadata table loses the restored rows' records only
+	notes := newUnitOfWork[*testNote](nil, db)
+	notes.settings = newEntitySettings[*testNote]()
+	var restoredNotes []domain.Change[*testNote]
+	notes.settings.addListener(func(_ context.Context, changes []domain.Change[*testNote]) {
+		restoredNotes = append(restoredNotes, changes...)
+	})
+	ids := make([]int, 2)
+	for i := range ids {
+		note, err := notes.Insert(ctx, &testNote{Name: fmt.Sprintf("Draft %d", i)})
+		require.NoError(t, err)
+		_, err = notes.SoftDeleteWithReason(ctx, identifier.New().Equal("id", note.ID), "duplicate", "bob")
+		require.NoError(t, err)
+		ids[i] = note.ID
+	}
+	restoredNotes = nil
+
+	restored, err = notes.RestoreAllWhere(ctx, identifier.New().Equal("id", ids[0]))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), restored)
+	require.Len(t, restoredNotes, 1)
+	assert.Equal(t, ids[0], restoredNotes[0].Entity.ID)
+
+	var records []domain.ArchiveMetadata
+	require.NoError(t, db.Find(&records).Error)
+	require.Len(t, records, 1)
+	assert.Equal(t, ids[1], records[0].EntityID)
+}
+
+func TestUnitOfWork_CopyInsertFallback(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	users := []*TestUser{
+		{Name: "Ada", Email: "ada@example.com", Slug: "ada"},
+		{Name: "Grace", Email: "grace@example.com", Slug: "grace"},
+	}
+	written, err := uow.CopyInsert(ctx, users, "slug")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), written)
+
+	// Re-importing the same keys updates instead of failing
+	_, err = uow.CopyInsert(ctx, []*TestUser{{Name: "Ada Lovelace", Email: "ada@example.com", Slug: "ada"}}, "slug")
+	require.NoError(t, err)
+
+	all, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	ada, err := uow.FindOneByIdentifier(ctx, identifier.New().Equal("slug", "ada"))
+	require.NoError(t, err)
+	assert.Equal(t, "Ada Lovelace", ada.Name)
+
+	_, err = uow.CopyInsert(ctx, []*TestUser{nil})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestMergeStatement(t *testing.T) {
+	onConflict, err := upsertClause(metadataOf[*TestUser](), []string{"slug"})
+	require.NoError(t, err)
+
+	sql := mergeStatement("test_users", "uow_copy_test_users", []string{"name", "slug", "created_at"}, onConflict)
+	assert.Equal(t, `INSERT INTO "test_users" ("name", "slug", "created_at") SELECT DISTINCT ON ("slug") "name", "slug", "created_at" `+
+		`FROM "uow_copy_test_users" ORDER BY "slug", uow_copy_seq DESC ON CONFLICT ("slug") DO UPDATE SET "name" = EXCLUDED."name"`, sql)
+}
+
+func TestUnitOfWork_ReadOnly(t *testing.T) {
+	primary := setupTestDB(t).db
+	replica, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, replica.AutoMigrate(&TestUser{}))
+	require.NoError(t, UseReplicas(primary, []*gorm.DB{replica}, ReplicaOptions{ReadYourWritesWindow: time.Minute}))
+
+	ctx := context.Background()
+	writer := newUnitOfWork[*TestUser](nil, primary)
+	_, err = writer.Insert(ctx, &TestUser{Name: "Primary", Email: "primary@example.com", Slug: "primary"})
+	require.NoError(t, err)
+
+	reader := newUnitOfWork[*TestUser](nil, primary).ReadOnly()
+	_, mutable := reader.(persistence.IUnitOfWork[*TestUser])
+	assert.False(t, mutable)
+
+	// Read-only transactions run on the replica
+	require.NoError(t, reader.BeginTransaction(ctx))
+	users, err := reader.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	require.NoError(t, reader.CommitTransaction(ctx))
+
+	// ...unless the context is pinned to the primary by a recent write
+	shared := ReadYourWrites(ctx)
+	_, err = writer.Insert(shared, &TestUser{Name: "Pinned", Email: "pinned@example.com", Slug: "pinned"})
+	require.NoError(t, err)
+	require.NoError(t, reader.BeginTransaction(shared))
+	users, err = reader.FindAll(shared)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	reader.RollbackTransaction(shared)
+}
+
+// wrappedUnitOfWork hides the concrete type, as a decorator or test fake would
+type wrappedUnitOfWork struct {
+	persistence.IUnitOfWork[*TestUser]
+}
+
+func TestBaseRepository_Exists(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	for _, repo := range []*BaseRepository[*TestUser]{
+		NewBaseRepositoryFor[*TestUser](uow),
+		NewBaseRepositoryFor[*TestUser](wrappedUnitOfWork{uow}),
+	} {
+		require.NoError(t, uow.db.Exec("DELETE FROM test_users").Error)
+		require.NoError(t, repo.CreateBatch(ctx, []*TestUser{
+			{Name: "Ada", Email: "ada@example.com", Slug: "ada"},
+			{Name: "Grace", Email: "grace@example.com", Slug: "grace"},
+		}))
+
+		exists, err := repo.Exists(ctx, domain.QueryParams[*TestUser]{Filter: &TestUser{Name: "Ada"}})
+		require.NoError(t, err)
+		assert.True(t, exists)
+		exists, err = repo.Exists(ctx, domain.QueryParams[*TestUser]{Filter: &TestUser{Name: "Linus"}})
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		count, err := repo.Count(ctx, domain.QueryParams[*TestUser]{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		grace, err := repo.GetBySlug(ctx, "grace")
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(ctx, int64(grace.ID)))
+		_, err = repo.GetByID(ctx, int64(grace.ID))
+		assert.Error(t, err)
+		assert.NotNil(t, repo.UnitOfWork())
+	}
+}
+
+func TestUnitOfWork_StateMachine(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testTicket{}))
+	factory := &UnitOfWorkFactory[*testTicket]{db: db, settings: newEntitySettings[*testTicket]()}
+	ctx := context.Background()
+
+	var transitions []string
+	failHook := false
+	factory.UseStateMachine(NewStateMachine[*testTicket]("status").
+		Allow("open", "closed").
+		OnTransition("open", AnyState, func(ctx context.Context, uow persistence.IUnitOfWork[*testTicket], ticket *testTicket, from, to string) error {
+			if failHook {
+				return errors.New("notification failed")
+			}
+			transitions = append(transitions, fmt.Sprintf("%d:%s->%s", ticket.ID, from, to))
+			_, err := uow.UpdateWhere(ctx, identifier.New().Equal("id", ticket.ID), map[string]interface{}{"name": ticket.Name + " (done)"})
+			return err
+		}))
+
+	uow := factory.Create()
+	ticket, err := uow.Insert(ctx, &testTicket{Name: "Login broken", Status: "open"})
+	require.NoError(t, err)
+	byID := identifier.New().Equal("id", ticket.ID)
+
+	// Changes that leave the state alone are not checked
+	_, err = uow.Update(ctx, byID, &testTicket{Name: "Login broken on iOS"})
+	require.NoError(t, err)
+
+	// A failing hook rolls the transition back
+	failHook = true
+	_, err = uow.Update(ctx, byID, &testTicket{Status: "closed"})
+	require.Error(t, err)
+	reloaded, err := uow.FindOneById(ctx, ticket.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ticketStatus("open"), reloaded.Status)
+
+	failHook = false
+	updated, err := uow.Update(ctx, byID, &testTicket{Status: "closed"})
+	require.NoError(t, err)
+	assert.Equal(t, ticketStatus("closed"), updated.Status)
+	assert.Equal(t, []string{fmt.Sprintf("%d:open->closed", ticket.ID)}, transitions)
+	reloaded, err = uow.FindOneById(ctx, ticket.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Login broken on iOS (done)", reloaded.Name)
+
+	_, err = uow.Update(ctx, byID, &testTicket{Status: "open"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidTransition)
+	var transitionErr *uowerrors.TransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	assert.Equal(t, "closed", transitionErr.From)
+	assert.Equal(t, "status", transitionErr.Field)
+}
+
+func TestUnitOfWork_ResolveIDsByUniqueField(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	ada, err := uow.Insert(ctx, &TestUser{Name: "Ada", Email: "ada@example.com", Slug: "ada"})
+	require.NoError(t, err)
+	grace, err := uow.Insert(ctx, &TestUser{Name: "Grace", Email: "grace@example.com", Slug: "grace"})
+	require.NoError(t, err)
+
+	ids, err := uow.ResolveIDsByUniqueField(ctx, "slug", []interface{}{"ada", "grace", "linus", "ada", nil})
+	require.NoError(t, err)
+	assert.Equal(t, map[interface{}]int{"ada": ada.ID, "grace": grace.ID}, ids)
+
+	ids, err = uow.ResolveIDsByUniqueField(ctx, "ID", []interface{}{ada.ID, int64(grace.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, map[interface{}]int{ada.ID: ada.ID, int64(grace.ID): grace.ID}, ids)
+
+	_, err = uow.ResolveIDsByUniqueField(ctx, "active", []interface{}{true})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = uow.ResolveIDsByUniqueField(ctx, "nickname", []interface{}{"ada"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_InsertReturning(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := uow.InsertReturning(ctx, &TestUser{Name: "Ada", Email: "ada@example.com", Slug: "ada"})
+	require.NoError(t, err)
+	assert.NotZero(t, user.ID)
+
+	// Active is left to its database default and read back
+	user, err = uow.InsertReturning(ctx, &TestUser{Name: "Grace", Email: "grace@example.com", Slug: "grace"}, "id", "active")
+	require.NoError(t, err)
+	assert.NotZero(t, user.ID)
+	assert.True(t, user.Active)
+
+	_, err = uow.InsertReturning(ctx, &TestUser{Name: "Linus", Slug: "linus"}, "nickname")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+func TestUnitOfWork_TrashedTemporalQueries(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, name := range []string{"old", "middle", "recent"} {
+		user, err := uow.Insert(ctx, &TestUser{Name: name, Email: name + "@example.com", Slug: name})
+		require.NoError(t, err)
+		deletedAt := base.Add(time.Duration(i) * 24 * time.Hour)
+		require.NoError(t, uow.db.Unscoped().Model(user).UpdateColumn("deleted_at", deletedAt).Error)
+	}
+
+	// Most recently deleted first
+	trashed, total, err := uow.GetTrashedWithPagination(ctx, domain.QueryParams[*TestUser]{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), total)
+	require.Len(t, trashed, 2)
+	assert.Equal(t, "recent", trashed[0].Name)
+	assert.Equal(t, "middle", trashed[1].Name)
+
+	trashed, _, err = uow.GetTrashedWithPagination(ctx, domain.QueryParams[*TestUser]{Sort: domain.SortMap{"deleted_at": domain.SortAsc}})
+	require.NoError(t, err)
+	assert.Equal(t, "old", trashed[0].Name)
+
+	trashed, total, err = uow.GetTrashedWhere(ctx, identifier.TrashedSince(base.Add(24*time.Hour)), domain.QueryParams[*TestUser]{})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), total)
+	assert.Equal(t, "recent", trashed[0].Name)
+
+	trashed, total, err = uow.GetTrashedWhere(ctx, identifier.DeletedBetween(base, base.Add(time.Hour)), domain.QueryParams[*TestUser]{})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), total)
+	assert.Equal(t, "old", trashed[0].Name)
+}
+
+type testAuthor struct {
+	ID    int `gorm:"primaryKey"`
+	Name  string
+	Books []testBook `gorm:"foreignKey:AuthorID"`
+}
+
+type testBook struct {
+	ID          int `gorm:"primaryKey"`
+	AuthorID    int
+	PublisherID int
+	Title       string `gorm:"index:idx_test_books_title"`
+	Publisher   testPublisher
+}
+
+type testPublisher struct {
+	ID   int `gorm:"primaryKey"`
+	Name string
+}
+
+func TestModelRegistry(t *testing.T) {
+	db := setupTestDB(t).db
+	registry := NewModelRegistry()
+	factory := &UnitOfWorkFactory[*TestUser]{Config: &Config{Models: registry}, db: db, settings: newEntitySettings[*TestUser]()}
+
+	var seeded []string
+	registry.RegisterSeeder(&testBook{}, func(ctx context.Context, tx *gorm.DB) error {
+		seeded = append(seeded, "books")
+		return tx.Create(&testBook{ID: 1, AuthorID: 1, PublisherID: 1, Title: "Notes"}).Error
+	})
+	registry.Register(&testAuthor{}, testPublisher{}, &testBook{})
+	registry.RegisterSeeder(&testAuthor{}, func(ctx context.Context, tx *gorm.DB) error {
+		seeded = append(seeded, "authors")
+		return tx.Create(&testAuthor{ID: 1, Name: "Ada"}).Error
+	})
+	registry.RegisterSeeder(&testPublisher{}, func(ctx context.Context, tx *gorm.DB) error {
+		seeded = append(seeded, "publishers")
+		return tx.Create(&testPublisher{ID: 1, Name: "Acme"}).Error
+	})
+	factory.RegisterModel()
+
+	// Books reference both authors and publishers, so they come last
+	names := make([]string, 0)
+	for _, model := range registry.Models() {
+		names = append(names, reflect.TypeOf(model).Elem().Name())
+	}
+	assert.Equal(t, []string{"testAuthor", "testPublisher", "TestUser", "testBook"}, names)
+
+	ctx := context.Background()
+	require.NoError(t, factory.Migrate(ctx))
+	require.NoError(t, db.Migrator().DropIndex(&testBook{}, "idx_test_books_title"))
+	created, err := registry.EnsureIndexes(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"idx_test_books_title"}, created)
+
+	require.NoError(t, registry.Seed(ctx, db))
+	assert.Equal(t, []string{"authors", "publishers", "books"}, seeded)
+}
+
+func TestLeakDetection(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TestUser{}))
+
+	reports := make(chan LeakReport, 4)
+	config := &Config{LeakDetection: &LeakDetection{
+		GracePeriod:   20 * time.Millisecond,
+		CaptureStacks: true,
+		Report:        func(r LeakReport) { reports <- r },
+	}}
+	ctx := context.Background()
+
+	closed := newUnitOfWork[*TestUser](config, db)
+	require.NoError(t, closed.BeginTransaction(ctx))
+	require.NoError(t, closed.CommitTransaction(ctx))
+	require.NoError(t, closed.Close())
+
+	leaked := newUnitOfWork[*TestUser](config, db)
+	require.NoError(t, leaked.BeginTransaction(ctx))
+
+	seen := map[LeakKind]LeakReport{}
+	for len(seen) < 2 {
+		select {
+		case r := <-reports:
+			seen[r.Kind] = r
+		case <-time.After(time.Second):
+			t.Fatalf("expected two leak reports, got %v", seen)
+		}
+	}
+	assert.Equal(t, "TestUser", seen[LeakUnitOfWork].Entity)
+	assert.GreaterOrEqual(t, seen[LeakTransaction].OpenFor, 20*time.Millisecond)
+	assert.Contains(t, seen[LeakUnitOfWork].Stack, "TestLeakDetection")
+
+	leaked.RollbackTransaction(ctx)
+	select {
+	case r := <-reports:
+		t.Fatalf("unexpected extra report %v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestUseRateLimiter(t *testing.T) {
+	uow := setupTestDB(t)
+	var ops []Operation
+	require.NoError(t, UseRateLimiter(uow.db, RateLimiterFunc(func(ctx context.Context, op Operation) error {
+		ops = append(ops, op)
+		if op.Tenant == "noisy" && op.Kind == OperationWrite {
+			return errors.New("quota exhausted")
+		}
+		return nil
+	})))
+
+	ctx := WithUser(WithTenant(context.Background(), "acme"), "u-1")
+	_, err := uow.BulkInsert(ctx, []*TestUser{
+		{Name: "A", Slug: "a", Email: "a@example.com"},
+		{Name: "B", Slug: "b", Email: "b@example.com"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, ops)
+	assert.Equal(t, Operation{Kind: OperationWrite, Table: "test_users", Rows: 2, Tenant: "acme", User: "u-1"}, ops[0])
+
+	_, err = uow.Insert(WithTenant(context.Background(), "noisy"), &TestUser{Name: "C", Slug: "c", Email: "c@example.com"})
+	assert.ErrorIs(t, err, uowerrors.ErrRateLimited)
+
+	users, err := uow.FindAll(WithTenant(context.Background(), "noisy"))
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewTokenBucketLimiter(map[OperationKind]Limit{OperationWrite: {Rate: 10, Burst: 5}})
+	limiter.MaxWait = 50 * time.Millisecond
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	noisy := Operation{Kind: OperationWrite, Tenant: "noisy", Rows: 5}
+	require.NoError(t, limiter.Wait(ctx, noisy))
+	assert.ErrorIs(t, limiter.Wait(ctx, noisy), uowerrors.ErrRateLimited)
+
+	// Other tenants and reads keep their own budget
+	require.NoError(t, limiter.Wait(ctx, Operation{Kind: OperationWrite, Tenant: "quiet", Rows: 5}))
+	require.NoError(t, limiter.Wait(ctx, Operation{Kind: OperationRead, Tenant: "noisy"}))
+
+	now = now.Add(time.Second)
+	require.NoError(t, limiter.Wait(ctx, Operation{Kind: OperationWrite, Tenant: "noisy", Rows: 1000}))
+}
+
+// testMember keeps emails unique among active members only
+type testMember struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Slug      string         `json:"slug"`
+	Name      string         `json:"name"`
+	Email     string         `gorm:"uniqueIndex:idx_test_members_email,where:active = true" json:"email"`
+	Active    bool           `json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (m *testMember) GetID() int                    { return m.ID }
+func (m *testMember) GetSlug() string               { return m.Slug }
+func (m *testMember) SetSlug(slug string)           { m.Slug = slug }
+func (m *testMember) GetCreatedAt() time.Time       { return m.CreatedAt }
+func (m *testMember) GetUpdatedAt() time.Time       { return m.UpdatedAt }
+func (m *testMember) GetArchivedAt() gorm.DeletedAt { return m.DeletedAt }
+func (m *testMember) GetName() string               { return m.Name }
+
+func TestUnitOfWork_IsUnique(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	ada, err := uow.Insert(ctx, &TestUser{Name: "Ada", Slug: "ada", Email: "ada@example.com"})
+	require.NoError(t, err)
+	gone, err := uow.Insert(ctx, &TestUser{Name: "Gone", Slug: "gone", Email: "gone@example.com"})
+	require.NoError(t, err)
+	_, err = uow.SoftDelete(ctx, identifier.New().Equal("id", gone.ID))
+	require.NoError(t, err)
+
+	unique, err := uow.IsUnique(ctx, "email", "ada@example.com", 0)
+	require.NoError(t, err)
+	assert.False(t, unique)
+
+	unique, err = uow.IsUnique(ctx, "Email", "ada@example.com", ada.ID)
+	require.NoError(t, err)
+	assert.True(t, unique, "the entity being edited does not collide with itself")
+
+	unique, err = uow.IsUnique(ctx, "email", "gone@example.com", 0)
+	require.NoError(t, err)
+	assert.True(t, unique, "trashed rows are ignored")
+
+	_, err = uow.IsUnique(ctx, "password", "x", 0)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	// Partial unique index: inactive members may share an email
+	require.NoError(t, uow.db.AutoMigrate(&testMember{}))
+	members := newUnitOfWork[*testMember](nil, uow.db)
+	_, err = members.Insert(ctx, &testMember{Name: "Old", Email: "bo@example.com", Active: false})
+	require.NoError(t, err)
+
+	unique, err = members.IsUnique(ctx, "email", "bo@example.com", 0)
+	require.NoError(t, err)
+	assert.True(t, unique)
+
+	_, err = members.Insert(ctx, &testMember{Name: "Bo", Email: "bo@example.com", Active: true})
+	require.NoError(t, err)
+	unique, err = members.IsUnique(ctx, "email", "bo@example.com", 0)
+	require.NoError(t, err)
+	assert.False(t, unique)
+}
+
+func TestDiff(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	before := &TestUser{ID: 1, Name: "Ada", Slug: "ada", Email: "ada@example.com", Active: true, CreatedAt: created}
+	after := cloneEntity(before)
+	after.Name = "Ada Lovelace"
+	after.Active = false
+	after.CreatedAt = created.In(time.FixedZone("CET", 3600))
+	after.DeletedAt = gorm.DeletedAt{Time: created, Valid: true}
+
+	changes, err := Diff(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.FieldChange{
+		"name":       {Old: "Ada", New: "Ada Lovelace"},
+		"active":     {Old: true, New: false},
+		"deleted_at": {Old: gorm.DeletedAt{}, New: gorm.DeletedAt{Time: created, Valid: true}},
+	}, changes)
+
+	changes, err = Diff(before, cloneEntity(before))
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = Diff(before, nil)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidEntity)
+}
+
+func TestUnitOfWork_QueryHints(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	_, err := uow.Insert(ctx, &TestUser{Name: "Ada", Slug: "ada", Email: "ada@example.com"})
+	require.NoError(t, err)
+
+	var statements []string
+	require.NoError(t, uow.db.Callback().Query().After("gorm:query").Register("test:capture", func(db *gorm.DB) {
+		statements = append(statements, db.Statement.SQL.String())
+	}))
+
+	users, total, err := uow.FindAllWithPagination(ctx, domain.QueryParams[*TestUser]{
+		Hints: &domain.QueryHints{
+			Plan:     "IndexScan(test_users idx_test_users_email)",
+			Settings: map[string]string{"enable_seqscan": "off"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, uint(1), total)
+	require.NotEmpty(t, statements)
+	assert.True(t, strings.HasPrefix(statements[len(statements)-1], "/*+ IndexScan(test_users idx_test_users_email) */ SELECT"))
+
+	_, _, err = uow.FindAllWithPagination(ctx, domain.QueryParams[*TestUser]{
+		Hints: &domain.QueryHints{Settings: map[string]string{"enable_seqscan = off; DROP TABLE x; --": "on"}},
+	})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	_, _, err = uow.GetTrashedWhere(ctx, nil, domain.QueryParams[*TestUser]{Hints: &domain.QueryHints{Plan: "*/ DELETE"}})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+// testCountry is served from the second-level cache by ID and slug
+type testCountry struct {
+	ID        int            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Slug      string         `gorm:"uniqueIndex" json:"slug"`
+	Name      string         `json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (c *testCountry) GetID() int                    { return c.ID }
+func (c *testCountry) GetSlug() string               { return c.Slug }
+func (c *testCountry) SetSlug(slug string)           { c.Slug = slug }
+func (c *testCountry) GetCreatedAt() time.Time       { return c.CreatedAt }
+func (c *testCountry) GetUpdatedAt() time.Time       { return c.UpdatedAt }
+func (c *testCountry) GetArchivedAt() gorm.DeletedAt { return c.DeletedAt }
+func (c *testCountry) GetName() string               { return c.Name }
+
+func (c *testCountry) CachePolicy() domain.CachePolicy {
+	return domain.CachePolicy{TTL: time.Minute, KeyFields: []string{"slug"}}
+}
+
+func TestUnitOfWork_EntityCache(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testCountry{}))
+	cache := NewMemoryCache()
+	require.NoError(t, UseCache(db, cache))
+	uow := newUnitOfWork[*testCountry](&Config{Cache: cache}, db)
+	ctx := context.Background()
+
+	nl, err := uow.Insert(ctx, &testCountry{Slug: "nl", Name: "Netherlands"})
+	require.NoError(t, err)
+
+	// Writes that bypass gorm are invisible until the entry is invalidated
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	found, err := uow.FindOneById(ctx, nl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Netherlands", found.Name)
+	_, err = sqlDB.Exec("UPDATE test_countries SET name = 'Holland' WHERE id = ?", nl.ID)
+	require.NoError(t, err)
+	found, err = uow.FindOneById(ctx, nl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Netherlands", found.Name, "served from the cache")
+	bySlug, err := uow.FindOne(ctx, &testCountry{Slug: "nl"})
+	require.NoError(t, err)
+	assert.Equal(t, "Holland", bySlug.Name, "first lookup by slug loads")
+	InvalidateCache(ctx, db, "test_countries")
+	found, err = uow.FindOneById(ctx, nl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Holland", found.Name, "explicitly invalidated")
+
+	// Raw statements through gorm invalidate the table they write
+	require.NoError(t, db.Exec(`UPDATE "test_countries" SET name = ? WHERE id = ?`, "Pays-Bas", nl.ID).Error)
+	found, err = uow.FindOneById(ctx, nl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Pays-Bas", found.Name)
+
+	// Schema-qualified writes invalidate the entries cached under the bare table name
+	require.NoError(t, db.Exec(`UPDATE main."test_countries" SET name = ? WHERE id = ?`, "Nederlân", nl.ID).Error)
+	found, err = uow.FindOneById(ctx, nl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Nederlân", found.Name)
+	assert.Equal(t, "test_countries", writtenTable(`delete from only public."test_countries" where id = 1`))
+	assert.Equal(t, generationKey("test_countries"), generationKey(`public."test_countries"`))
+	assert.Empty(t, writtenTable("SELECT name FROM test_countries"))
+
+	// Writes through gorm invalidate once committed
+	require.NoError(t, uow.BeginTransaction(ctx))
+	_, err = uow.Update(ctx, identifier.New().Equal("id", nl.ID), &testCountry{Name: "Nederland"})
+	require.NoError(t, err)
+	reader := newUnitOfWork[*testCountry](&Config{Cache: cache}, db)
+	found, err = reader.FindOneById(ctx, nl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Nederlân", found.Name, "uncommitted writes keep the cache")
+	require.NoError(t, uow.CommitTransaction(ctx))
+
+	found, err = reader.FindOneById(ctx, nl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Nederland", found.Name)
+	bySlug, err = reader.FindOne(ctx, &testCountry{Slug: "nl"})
+	require.NoError(t, err)
+	assert.Equal(t, "Nederland", bySlug.Name)
+}
+
+func TestUnitOfWork_SoftDeleteWhere(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	for i := 0; i < 12; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Name: fmt.Sprintf("User %d", i), Slug: fmt.Sprintf("user-%d", i), Email: fmt.Sprintf("u%d@example.com", i)})
+		require.NoError(t, err)
+	}
+	// Every fourth user is inactive; active defaults to true on insert
+	require.NoError(t, uow.db.Model(&TestUser{}).Where("id > ?", 0).Update("active", gorm.Expr("id % 4 <> 1")).Error)
+	inactive := identifier.New().Equal("active", false)
+
+	preview, err := uow.SoftDeleteWhere(ctx, inactive, true)
+	require.NoError(t, err)
+	assert.True(t, preview.Preview)
+	assert.Equal(t, int64(3), preview.Affected)
+	require.Len(t, preview.Sample, 3)
+	assert.Equal(t, 1, preview.Sample[0].ID)
+	all, err := uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 12, "preview deletes nothing")
+
+	result, err := uow.SoftDeleteWhere(ctx, inactive, false)
+	require.NoError(t, err)
+	assert.False(t, result.Preview)
+	assert.Equal(t, int64(3), result.Affected)
+	trashed, err := uow.GetTrashed(ctx)
+	require.NoError(t, err)
+	assert.Len(t, trashed, 3)
+
+	guarded := newUnitOfWork[*TestUser](&Config{GuardUnscopedMutations: true}, uow.db)
+	_, err = guarded.SoftDeleteWhere(ctx, identifier.New(), true)
+	assert.ErrorIs(t, err, uowerrors.ErrUnscopedOperation)
+}
+
+func TestUnitOfWork_ExportImportWithTrashed(t *testing.T) {
+	source := setupTestDB(t)
+	ctx := context.Background()
+	for _, slug := range []string{"ada", "bob", "cy"} {
+		_, err := source.Insert(ctx, &TestUser{Name: slug, Slug: slug, Email: slug + "@example.com"})
+		require.NoError(t, err)
+	}
+	_, err := source.SoftDelete(ctx, identifier.New().Equal("slug", "bob"))
+	require.NoError(t, err)
+
+	var live bytes.Buffer
+	exported, err := source.ExportJSON(ctx, domain.QueryParams[*TestUser]{}, domain.JSONExportOptions{}, &live)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), exported)
+
+	var all bytes.Buffer
+	exported, err = source.ExportJSON(ctx, domain.QueryParams[*TestUser]{}, domain.JSONExportOptions{IncludeTrashed: true}, &all)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), exported)
+
+	target := setupTestDB(t)
+	imported, err := target.ImportJSON(ctx, &all, domain.JSONImportOptions{ResetSequence: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), imported)
+
+	users, err := target.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	trashed, err := target.GetTrashed(ctx)
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, 2, trashed[0].ID)
+	assert.Equal(t, "bob", trashed[0].Slug)
+
+	next, err := target.Insert(ctx, &TestUser{Name: "dee", Slug: "dee", Email: "dee@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, next.ID)
+}
+
+type productAttributes struct {
+	Color string         `json:"color"`
+	Sizes []string       `json:"sizes"`
+	Specs map[string]int `json:"specs,omitempty"`
+}
+
+// testProduct keeps semi-structured attributes in a jsonb column
+type testProduct struct {
+	ID         int                             `gorm:"primaryKey;autoIncrement" json:"id"`
+	Slug       string                          `gorm:"uniqueIndex" json:"slug"`
+	Name       string                          `json:"name"`
+	Attributes domain.JSONB[productAttributes] `json:"attributes"`
+	CreatedAt  time.Time                       `json:"created_at"`
+	UpdatedAt  time.Time                       `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt                  `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (p *testProduct) GetID() int                    { return p.ID }
+func (p *testProduct) GetSlug() string               { return p.Slug }
+func (p *testProduct) SetSlug(slug string)           { p.Slug = slug }
+func (p *testProduct) GetCreatedAt() time.Time       { return p.CreatedAt }
+func (p *testProduct) GetUpdatedAt() time.Time       { return p.UpdatedAt }
+func (p *testProduct) GetArchivedAt() gorm.DeletedAt { return p.DeletedAt }
+func (p *testProduct) GetName() string               { return p.Name }
+
+func TestUnitOfWork_JSONBPatch(t *testing.T) {
+	base := setupTestDB(t)
+	require.NoError(t, base.db.AutoMigrate(&testProduct{}))
+	uow := newUnitOfWork[*testProduct](nil, base.db)
+	ctx := context.Background()
+
+	product, err := uow.Insert(ctx, &testProduct{Slug: "mug", Name: "Mug", Attributes: domain.NewJSONB(productAttributes{
+		Color: "white",
+		Sizes: []string{"S", "M"},
+		Specs: map[string]int{"volume": 300, "height": 9},
+	})})
+	require.NoError(t, err)
+	id := identifier.NewIdentifier().Equal("id", product.ID)
+
+	affected, err := uow.JSONBSet(ctx, id, "attributes.color", "black")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	_, err = uow.JSONBSet(ctx, id, "attributes.sizes.1", "L")
+	require.NoError(t, err)
+	_, err = uow.JSONBRemove(ctx, id, "attributes.specs.height")
+	require.NoError(t, err)
+
+	found, err := uow.FindOneById(ctx, product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, productAttributes{Color: "black", Sizes: []string{"S", "L"}, Specs: map[string]int{"volume": 300}}, found.Attributes.Data)
+
+	encoded, err := json.Marshal(found)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"attributes":{"color":"black"`)
+
+	_, err = uow.JSONBSet(ctx, id, "attributes", "x")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = uow.JSONBSet(ctx, id, "missing.key", "x")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	assert.Equal(t, `{"sizes","0"}`, postgresTextArray([]string{"sizes", "0"}))
+	assert.Equal(t, `{"say \"hi\""}`, postgresTextArray([]string{`say "hi"`}))
+	assert.Equal(t, `$."sizes"[0]`, sqliteJSONPath([]string{"sizes", "0"}))
+}
+
+type testTeam struct {
+	ID        int `gorm:"primaryKey"`
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+// testPlayer references teams without a database constraint
+type testPlayer struct {
+	ID        int `gorm:"primaryKey"`
+	TeamID    *int
+	Team      *testTeam `gorm:"constraint:false"`
+	DeletedAt gorm.DeletedAt
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	db := setupTestDB(t).db
+	db.Config.DisableForeignKeyConstraintWhenMigrating = true
+	registry := NewModelRegistry()
+	registry.Register(&testPlayer{}, &testTeam{})
+	ctx := context.Background()
+	require.NoError(t, registry.Migrate(ctx, db))
+
+	team := func(id int) *int { return &id }
+	require.NoError(t, db.Create([]testTeam{{ID: 1, Name: "live"}, {ID: 2, Name: "trashed"}, {ID: 3, Name: "purged"}}).Error)
+	require.NoError(t, db.Create([]testPlayer{
+		{ID: 1, TeamID: team(1)},
+		{ID: 2, TeamID: team(2)},
+		{ID: 3, TeamID: team(3)},
+		{ID: 4},
+		{ID: 5, TeamID: team(3)},
+	}).Error)
+	require.NoError(t, db.Delete(&testTeam{ID: 2}).Error)
+	require.NoError(t, db.Unscoped().Delete(&testTeam{ID: 3}).Error)
+	require.NoError(t, db.Delete(&testPlayer{ID: 5}).Error)
+
+	relations, err := registry.Relations(db)
+	require.NoError(t, err)
+	require.Len(t, relations, 1)
+	assert.Equal(t, Relation{
+		Child: "test_players", ChildKey: "id", ForeignKey: "team_id",
+		Parent: "test_teams", ParentKey: "id",
+		ChildDeletedAt: "deleted_at", ParentDeletedAt: "deleted_at",
+	}, relations[0])
+
+	report, err := registry.CheckIntegrity(ctx, db, nil, IntegrityOptions{Repair: true})
+	require.NoError(t, err)
+	require.Len(t, report.Relations, 1)
+	players := report.Relations[0]
+	assert.Equal(t, int64(1), players.Missing)
+	assert.Equal(t, int64(1), players.Trashed)
+	assert.Equal(t, []interface{}{int64(3)}, players.MissingSample)
+	assert.Equal(t, []interface{}{int64(2)}, players.TrashedSample)
+	assert.Equal(t, "no repair declared", players.RepairSkipped)
+	assert.False(t, report.Clean())
+
+	report, err = registry.CheckIntegrity(ctx, db, map[string]OrphanRepair{
+		"test_players.team_id -> test_teams.id": RepairNullify,
+	}, IntegrityOptions{Repair: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Relations[0].Repaired)
+	assert.True(t, report.Clean())
+
+	var cleared []int
+	require.NoError(t, db.Model(&testPlayer{}).Where("team_id IS NULL").Order("id").Pluck("id", &cleared).Error)
+	assert.Equal(t, []int{2, 3, 4}, cleared)
+
+	report, err = registry.CheckIntegrity(ctx, db, nil, IntegrityOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, report.Relations[0].Orphans())
+}
+
+func TestChangeTracker(t *testing.T) {
+	base := setupTestDB(t)
+	db := base.db
+	db.Config.DisableForeignKeyConstraintWhenMigrating = true
+	require.NoError(t, db.AutoMigrate(&testTeam{}, &testPlayer{}))
+	ctx := context.Background()
+
+	tracker := base.Track().WithBatchSize(2)
+	team := &testTeam{Name: "Rovers"}
+	players := []*testPlayer{{Team: team}, {Team: team}, {Team: team}}
+	for _, player := range players {
+		require.NoError(t, tracker.RegisterNew(player))
+	}
+	require.NoError(t, tracker.RegisterNew(team))
+	assert.ErrorIs(t, tracker.RegisterNew(testTeam{}), uowerrors.ErrInvalidEntity)
+
+	plan := tracker.Plan()
+	require.Len(t, plan.Steps, 2)
+	assert.Equal(t, "test_teams", plan.Steps[0].Table)
+	assert.Equal(t, domain.ChangeCreated, plan.Steps[1].Kind)
+	assert.Equal(t, "test_players", plan.Steps[1].Table)
+	assert.Equal(t, 3, plan.Steps[1].Rows)
+	assert.Equal(t, 3, plan.Statements())
+
+	_, err := tracker.Commit(ctx)
+	require.NoError(t, err)
+	require.NotZero(t, team.ID)
+	for _, player := range players {
+		require.NotNil(t, player.TeamID)
+		assert.Equal(t, team.ID, *player.TeamID, "children adopt the generated parent key")
+	}
+	assert.Empty(t, tracker.Plan().Steps)
+
+	// Deletes run children first, after updates
+	team.Name = "United"
+	require.NoError(t, tracker.RegisterDeleted(team))
+	require.NoError(t, tracker.RegisterDirty(team))
+	require.NoError(t, tracker.RegisterDeleted(players[0], players[1]))
+	plan = tracker.Plan()
+	require.Len(t, plan.Steps, 3)
+	assert.Equal(t, []domain.ChangeKind{domain.ChangeUpdated, domain.ChangeDeleted, domain.ChangeDeleted},
+		[]domain.ChangeKind{plan.Steps[0].Kind, plan.Steps[1].Kind, plan.Steps[2].Kind})
+	assert.Equal(t, "test_players", plan.Steps[1].Table)
+	assert.Equal(t, "test_teams", plan.Steps[2].Table)
+
+	require.NoError(t, base.BeginTransaction(ctx))
+	_, err = tracker.Commit(ctx)
+	require.NoError(t, err)
+	require.NoError(t, base.CommitTransaction(ctx))
+
+	var stored testTeam
+	require.NoError(t, db.Unscoped().First(&stored, team.ID).Error)
+	assert.Equal(t, "United", stored.Name)
+	assert.True(t, stored.DeletedAt.Valid)
+	var live int64
+	require.NoError(t, db.Model(&testPlayer{}).Count(&live).Error)
+	assert.Equal(t, int64(1), live)
+}
+
+func TestUnitOfWork_FindOneAsOf(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	tick := func() time.Time {
+		time.Sleep(5 * time.Millisecond)
+		at := time.Now()
+		time.Sleep(5 * time.Millisecond)
+		return at
+	}
+
+	early, err := uow.Insert(ctx, &TestUser{Name: "Early", Email: "early@example.com", Slug: "early"})
+	require.NoError(t, err)
+	require.NoError(t, EnableHistory[*TestUser](ctx, uow.db))
+	require.NoError(t, EnableHistory[*TestUser](ctx, uow.db), "enabling again is harmless")
+
+	beforeCreate := tick()
+	user, err := uow.Insert(ctx, &TestUser{Name: "Ada", Email: "ada@example.com", Slug: "ada"})
+	require.NoError(t, err)
+	created := tick()
+	_, err = uow.UpdateWhere(ctx, identifier.NewIdentifier().Equal("id", user.ID), map[string]interface{}{"name": "Ada Lovelace", "active": false})
+	require.NoError(t, err)
+	renamed := tick()
+	_, err = uow.SoftDelete(ctx, identifier.NewIdentifier().Equal("id", user.ID))
+	require.NoError(t, err)
+	deleted := tick()
+
+	id := identifier.NewIdentifier().Equal("slug", "ada")
+	_, err = uow.FindOneAsOf(ctx, id, beforeCreate)
+	assert.ErrorIs(t, err, uowerrors.ErrEntityNotFound)
+
+	version, err := uow.FindOneAsOf(ctx, id, created)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", version.Name)
+	assert.True(t, version.Active)
+	assert.WithinDuration(t, user.CreatedAt, version.CreatedAt, time.Millisecond)
+	assert.False(t, version.DeletedAt.Valid)
+
+	version, err = uow.FindOneAsOf(ctx, id, deleted)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada Lovelace", version.Name)
+	assert.True(t, version.DeletedAt.Valid)
+
+	changes, err := uow.DiffBetween(ctx, id, created, renamed)
+	require.NoError(t, err)
+	assert.Equal(t, domain.FieldChange{Old: "Ada", New: "Ada Lovelace"}, changes["name"])
+	assert.Equal(t, domain.FieldChange{Old: true, New: false}, changes["active"])
+	assert.NotContains(t, changes, "email")
+
+	// Rows present when history was enabled have a snapshot version
+	version, err = uow.FindOneAsOf(ctx, identifier.NewIdentifier().Equal("id", early.ID), created)
+	require.NoError(t, err)
+	assert.Equal(t, "Early", version.Name)
+
+	// Hard-deleted rows close their last version
+	require.NoError(t, uow.db.Unscoped().Delete(&TestUser{}, early.ID).Error)
+	_, err = uow.FindOneAsOf(ctx, identifier.NewIdentifier().Equal("id", early.ID), time.Now())
+	assert.ErrorIs(t, err, uowerrors.ErrEntityNotFound)
+}
+
+// testUserNote belongs to an author through an association and names a reviewer through a plain column
+type testUserNote struct {
+	ID         int `gorm:"primaryKey"`
+	AuthorID   int
+	Author     *TestUser `gorm:"constraint:false"`
+	ReviewerID *int
+}
+
+func TestUnitOfWork_Reassign(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	registry := NewModelRegistry()
+	registry.Register(&TestUser{}, &testUserNote{})
+	registry.DeclareRelations(Relation{Child: "test_user_notes", ChildKey: "id", ForeignKey: "reviewer_id", Parent: "test_users", ParentKey: "id"})
+	uow.config = &Config{Models: registry}
+	require.NoError(t, uow.db.AutoMigrate(&testUserNote{}))
+
+	users := []*TestUser{
+		{Slug: "keep", Name: "Keep", Email: "keep@example.com"},
+		{Slug: "dup-1", Name: "Dup", Email: "dup1@example.com"},
+		{Slug: "dup-2", Name: "Dup", Email: "dup2@example.com"},
+		{Slug: "other", Name: "Other", Email: "other@example.com"},
+	}
+	require.NoError(t, uow.db.Create(users).Error)
+	require.NoError(t, uow.db.Delete(users[2]).Error)
+	reviewer := users[1].ID
+	notes := []*testUserNote{
+		{AuthorID: users[1].ID, ReviewerID: &reviewer},
+		{AuthorID: users[2].ID},
+		{AuthorID: users[3].ID},
+		{AuthorID: users[0].ID},
+	}
+	require.NoError(t, uow.db.Create(notes).Error)
+
+	_, err := uow.Reassign(ctx, identifier.New().Equal("name", "Dup"), 999)
+	assert.ErrorIs(t, err, uowerrors.ErrEntityNotFound)
+	_, err = uow.Reassign(ctx, identifier.New(), users[0].ID)
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	result, err := uow.Reassign(ctx, identifier.New().Equal("name", "Dup"), users[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{users[1].ID, users[2].ID}, result.From, "trashed duplicates are included")
+	assert.Equal(t, map[string]int64{
+		"test_user_notes.author_id -> test_users.id":   2,
+		"test_user_notes.reviewer_id -> test_users.id": 1,
+	}, result.Moved)
+	assert.Equal(t, int64(3), result.Total())
+
+	var authors []int
+	require.NoError(t, uow.db.Model(&testUserNote{}).Order("id").Pluck("author_id", &authors).Error)
+	assert.Equal(t, []int{users[0].ID, users[0].ID, users[3].ID, users[0].ID}, authors)
+	var moved testUserNote
+	require.NoError(t, uow.db.First(&moved, notes[0].ID).Error)
+	assert.Equal(t, users[0].ID, *moved.ReviewerID)
+
+	// Joins an open transaction and rolls back with it
+	require.NoError(t, uow.BeginTransaction(ctx))
+	result, err = uow.Reassign(ctx, identifier.New().Equal("slug", "other"), users[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Total())
+	uow.RollbackTransaction(ctx)
+	var kept testUserNote
+	require.NoError(t, uow.db.First(&kept, notes[2].ID).Error)
+	assert.Equal(t, users[3].ID, kept.AuthorID)
+}
+
+func TestUnitOfWork_Merge(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	registry := NewModelRegistry()
+	registry.Register(&TestUser{}, &testUserNote{})
+	uow.config = &Config{Models: registry}
+	require.NoError(t, uow.db.AutoMigrate(&testUserNote{}, &domain.MergeRecord{}))
+
+	users := []*TestUser{
+		{Slug: "ann", Name: "Ann", Email: "ann@example.com"},
+		{Slug: "ann-smith", Name: "Ann Smith", Email: "ann.smith@example.com"},
+		{Slug: "a-smith", Name: "A. Smith", Email: "a.smith@example.com"},
+	}
+	require.NoError(t, uow.db.Create(users).Error)
+	require.NoError(t, uow.db.Model(users[0]).UpdateColumn("active", false).Error)
+	require.NoError(t, uow.db.Model(users[1]).UpdateColumn("updated_at", time.Now().Add(time.Hour)).Error)
+	notes := []*testUserNote{{AuthorID: users[1].ID}, {AuthorID: users[2].ID}, {AuthorID: users[2].ID}}
+	require.NoError(t, uow.db.Create(notes).Error)
+
+	_, err := uow.Merge(ctx, identifier.New().Equal("slug", "ann"), identifier.New().Equal("name", "x"),
+		domain.MergeStrategy{Columns: map[string]domain.MergeRule{"nickname": domain.MergeNewest}})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	_, err = uow.Merge(ctx, identifier.New().Equal("slug", "ann"), identifier.New().Equal("slug", "ann"), domain.MergeStrategy{})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityNotFound)
+	assert.False(t, uow.inTx, "the failed merge is rolled back")
+
+	result, err := uow.Merge(ctx, identifier.New().Equal("slug", "ann"), identifier.New().In("slug", []interface{}{"ann-smith", "a-smith"}), domain.MergeStrategy{
+		Default: domain.MergeFillEmpty,
+		Columns: map[string]domain.MergeRule{"name": domain.MergeNewest},
+		Actor:   "support@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, users[0].ID, result.Survivor.GetID())
+	assert.Equal(t, "Ann Smith", result.Survivor.Name, "the most recently updated name wins")
+	assert.True(t, result.Survivor.Active, "empty survivor values are filled")
+	assert.Equal(t, "ann", result.Survivor.Slug, "set survivor values are kept")
+	assert.Equal(t, []int{users[1].ID, users[2].ID}, result.Duplicates)
+	assert.Equal(t, map[string]domain.FieldChange{
+		"name":   {Old: "Ann", New: "Ann Smith"},
+		"active": {Old: false, New: true},
+	}, result.Changes)
+	assert.Equal(t, map[string]int64{"test_user_notes.author_id -> test_users.id": 3}, result.Moved)
+
+	var authors []int
+	require.NoError(t, uow.db.Model(&testUserNote{}).Distinct().Pluck("author_id", &authors).Error)
+	assert.Equal(t, []int{users[0].ID}, authors)
+	var live int64
+	require.NoError(t, uow.db.Model(&TestUser{}).Count(&live).Error)
+	assert.Equal(t, int64(1), live, "duplicates are soft deleted")
+
+	var log []domain.MergeRecord
+	require.NoError(t, uow.db.Order("duplicate_id").Find(&log).Error)
+	require.Len(t, log, 2)
+	assert.Equal(t, "test_users", log[0].EntityTable)
+	assert.Equal(t, users[0].ID, log[0].SurvivorID)
+	assert.Equal(t, "support@example.com", log[0].Actor)
+	assert.JSONEq(t, `{"name": {"old": "Ann", "new": "Ann Smith"}, "active": {"old": false, "new": true}}`, log[0].Changes)
+	assert.JSONEq(t, `{"test_user_notes.author_id -> test_users.id": 1}`, log[0].Moved)
+	assert.JSONEq(t, `{}`, log[1].Changes)
+	assert.JSONEq(t, `{"test_user_notes.author_id -> test_users.id": 2}`, log[1].Moved)
+}
+
+func TestUnitOfWork_ExportSnapshot(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, uow.db.Create(&TestUser{Slug: fmt.Sprintf("snap-%d", i), Name: "Snap", Email: fmt.Sprintf("snap%d@example.com", i)}).Error)
+	}
+	require.NoError(t, uow.db.Model(&TestUser{}).Where("slug = ?", "snap-5").Update("active", false).Error)
+
+	var slugs []string
+	count, err := uow.ExportSnapshot(ctx, domain.QueryParams[*TestUser]{
+		Filter: &TestUser{Active: true},
+		Sort:   domain.SortMap{"slug": domain.SortDesc},
+	}, func(user *TestUser) error {
+		slugs = append(slugs, user.Slug)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	assert.Equal(t, []string{"snap-4", "snap-3", "snap-2", "snap-1"}, slugs)
+	assert.False(t, uow.inTx, "the snapshot transaction is closed")
+
+	stop := errors.New("stop")
+	count, err = uow.ExportSnapshot(ctx, domain.QueryParams[*TestUser]{}, func(*TestUser) error { return stop })
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, int64(1), count)
+
+	require.NoError(t, uow.BeginTransaction(ctx))
+	defer uow.RollbackTransaction(ctx)
+	_, err = uow.ExportSnapshot(ctx, domain.QueryParams[*TestUser]{}, func(*TestUser) error { return nil })
+	assert.ErrorIs(t, err, uowerrors.ErrTransactionAlreadyOpen)
+}
+
+func TestShardedFactory(t *testing.T) {
+	ctx := context.Background()
+	shards := make([]*gorm.DB, 3)
+	for i := range shards {
+		shards[i] = setupTestDB(t).db
+	}
+	router := NewShardRouterFromDB(shards...)
+	_, err := NewShardedFactory[*TestUser](router, "nickname")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	factory, err := NewShardedFactory[*TestUser](router, "Email")
+	require.NoError(t, err)
+	assert.Equal(t, "email", factory.ShardKey())
+
+	for i := 0; i < 12; i++ {
+		user := &TestUser{Slug: fmt.Sprintf("user-%02d", i), Name: fmt.Sprintf("User %02d", i), Email: fmt.Sprintf("user%02d@example.com", i)}
+		uow := factory.ForEntity(ctx, user)
+		_, err := uow.Insert(ctx, user)
+		require.NoError(t, err)
+		require.NoError(t, uow.Close())
+	}
+	used := 0
+	for shard, db := range shards {
+		var emails []string
+		require.NoError(t, db.Model(&TestUser{}).Pluck("email", &emails).Error)
+		for _, email := range emails {
+			assert.Equal(t, shard, router.ShardFor(email), "rows live on their key's shard")
+		}
+		if len(emails) > 0 {
+			used++
+		}
+	}
+	assert.Greater(t, used, 1, "keys spread over shards")
+
+	uow := factory.CreateWithContext(WithShardKey(ctx, "user07@example.com"))
+	found, err := uow.FindOneByIdentifier(ctx, identifier.New().Equal("email", "user07@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "user-07", found.Slug)
+	require.NoError(t, uow.Close())
+
+	page, total, err := factory.FindAllWithPagination(ctx, domain.QueryParams[*TestUser]{
+		Sort:   domain.SortMap{"name": domain.SortDesc},
+		Offset: 2,
+		Limit:  3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(12), total)
+	require.Len(t, page, 3)
+	assert.Equal(t, []string{"user-09", "user-08", "user-07"}, []string{page[0].Slug, page[1].Slug, page[2].Slug})
+
+	matched, total, err := factory.FindAllByIdentifier(ctx, identifier.New().In("slug", []interface{}{"user-03", "user-01", "user-11"}), domain.QueryParams[*TestUser]{})
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), total)
+	require.Len(t, matched, 3)
+	assert.Equal(t, []string{"user-01", "user-03", "user-11"}, []string{matched[0].Slug, matched[1].Slug, matched[2].Slug})
+
+	assert.Panics(t, func() { factory.Create() }, "no shard key to route by")
+}
+
+func TestUnitOfWork_GenerateIDs(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	snowflake, err := NewSnowflake(7)
+	require.NoError(t, err)
+	_, err = NewSnowflake(MaxSnowflakeNode + 1)
+	assert.Error(t, err)
+
+	uow.settings = newEntitySettings[*TestUser]()
+	uow.settings.setIDGenerator("id", snowflake)
+	uow.settings.setIDGenerator("slug", NewULID())
+
+	user, err := uow.Insert(ctx, &TestUser{Name: "Generated", Email: "generated@example.com"})
+	require.NoError(t, err)
+	assert.Greater(t, user.ID, 1<<22, "snowflake IDs carry the time above node and sequence bits")
+	assert.Equal(t, int64(7), int64(user.ID)>>12&MaxSnowflakeNode)
+	assert.Len(t, user.Slug, 26)
+
+	preset, err := uow.Insert(ctx, &TestUser{ID: 5, Slug: "preset", Name: "Preset", Email: "preset@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, preset.ID, "set values are kept")
+	assert.Equal(t, "preset", preset.Slug)
+
+	batch, err := uow.BulkInsert(ctx, []*TestUser{
+		{Name: "A", Email: "a@example.com"},
+		{Name: "B", Email: "b@example.com"},
+		{Name: "C", Email: "c@example.com"},
+	})
+	require.NoError(t, err)
+	for i := 1; i < len(batch); i++ {
+		assert.Greater(t, batch[i].ID, batch[i-1].ID, "IDs of one generator increase")
+		assert.Greater(t, batch[i].Slug, batch[i-1].Slug, "ULIDs of one generator sort in creation order")
+	}
+	var stored int64
+	require.NoError(t, uow.db.Model(&TestUser{}).Where("id IN ?", []int{user.ID, batch[2].ID}).Count(&stored).Error)
+	assert.Equal(t, int64(2), stored)
+
+	id, err := NewUUIDv7().Next()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+
+	uow.settings.setIDGenerator("nickname", NewUUIDv7())
+	_, err = uow.Insert(ctx, &TestUser{Name: "D", Email: "d@example.com"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+// testTodo is synced with offline clients through its version column
+type testTodo struct {
+	ID        int            `gorm:"primaryKey" json:"id"`
+	Title     string         `json:"title"`
+	Done      bool           `json:"done"`
+	Version   int64          `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (t *testTodo) GetID() int                    { return t.ID }
+func (t *testTodo) GetSlug() string               { return "" }
+func (t *testTodo) SetSlug(string)                {}
+func (t *testTodo) GetCreatedAt() time.Time       { return t.CreatedAt }
+func (t *testTodo) GetUpdatedAt() time.Time       { return t.UpdatedAt }
+func (t *testTodo) GetArchivedAt() gorm.DeletedAt { return t.DeletedAt }
+func (t *testTodo) GetName() string               { return t.Title }
+
+func TestUnitOfWork_ApplyChanges(t *testing.T) {
+	base := setupTestDB(t)
+	require.NoError(t, base.db.AutoMigrate(&testTodo{}))
+	uow := newUnitOfWork[*testTodo](nil, base.db)
+	ctx := context.Background()
+
+	result, err := uow.ApplyChanges(ctx, []domain.ClientChange[*testTodo]{
+		{Operation: domain.SyncCreate, Entity: &testTodo{ID: 101, Title: "Buy milk"}},
+		{Operation: domain.SyncCreate, Entity: &testTodo{ID: 102, Title: "Call mom"}},
+		{Operation: domain.SyncCreate, Entity: &testTodo{ID: 103, Title: "Water plants"}},
+	}, domain.SyncOptions[*testTodo]{})
+	require.NoError(t, err)
+	require.Len(t, result.Applied, 3)
+	assert.Equal(t, int64(1), result.Applied[0].Version)
+	assert.Empty(t, result.Conflicts)
+
+	// Client edits based on version 1; the server moved 102 to version 2 meanwhile
+	require.NoError(t, base.db.Model(&testTodo{}).Where("id = ?", 102).Updates(map[string]interface{}{"title": "Call mom tonight", "version": 2}).Error)
+	changes := []domain.ClientChange[*testTodo]{
+		{Operation: domain.SyncUpdate, Entity: &testTodo{ID: 101, Title: "ignored", Done: true}, BaseVersion: 1, Fields: []string{"done"}},
+		{Operation: domain.SyncUpdate, Entity: &testTodo{ID: 102, Title: "Call mom", Done: true}, BaseVersion: 1},
+		{Operation: domain.SyncDelete, Entity: &testTodo{ID: 103}, BaseVersion: 1},
+		{Operation: domain.SyncCreate, Entity: &testTodo{ID: 101, Title: "Buy milk"}},
+	}
+	result, err = uow.ApplyChanges(ctx, changes, domain.SyncOptions[*testTodo]{})
+	require.NoError(t, err)
+	require.Len(t, result.Applied, 2)
+	assert.Equal(t, "Buy milk", result.Applied[0].Title, "only the listed fields are written")
+	assert.True(t, result.Applied[0].Done)
+	assert.Equal(t, int64(2), result.Applied[0].Version)
+	assert.True(t, result.Applied[1].DeletedAt.Valid)
+	require.Len(t, result.Conflicts, 2)
+	assert.Equal(t, domain.ServerWins, result.Conflicts[0].Resolution)
+	assert.Equal(t, int64(2), result.Conflicts[0].ServerVersion)
+	assert.Equal(t, "Call mom tonight", result.Conflicts[0].Server.Title)
+	assert.Equal(t, "already exists", result.Conflicts[1].Reason)
+
+	var stored testTodo
+	require.NoError(t, base.db.First(&stored, 102).Error)
+	assert.Equal(t, "Call mom tonight", stored.Title, "the server wins by default")
+
+	// A merge callback combines both sides
+	result, err = uow.ApplyChanges(ctx, changes[1:2], domain.SyncOptions[*testTodo]{
+		Resolution: domain.MergeWith,
+		Merge: func(server, client *testTodo) (*testTodo, error) {
+			server.Done = client.Done
+			return server, nil
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, domain.MergeWith, result.Conflicts[0].Resolution)
+	assert.Equal(t, "Call mom tonight", result.Applied[0].Title)
+	assert.True(t, result.Applied[0].Done)
+	assert.Equal(t, int64(3), result.Applied[0].Version)
+
+	// The client wins over a server-side delete by restoring the row
+	result, err = uow.ApplyChanges(ctx, []domain.ClientChange[*testTodo]{
+		{Operation: domain.SyncUpdate, Entity: &testTodo{ID: 103, Title: "Water plants twice"}, BaseVersion: 1},
+	}, domain.SyncOptions[*testTodo]{Resolution: domain.ClientWins})
+	require.NoError(t, err)
+	assert.Equal(t, "deleted on server", result.Conflicts[0].Reason)
+	assert.False(t, result.Applied[0].DeletedAt.Valid)
+	assert.Equal(t, "Water plants twice", result.Applied[0].Title)
+
+	// Failures roll the whole batch back
+	_, err = uow.ApplyChanges(ctx, []domain.ClientChange[*testTodo]{
+		{Operation: domain.SyncCreate, Entity: &testTodo{ID: 104, Title: "Rolled back"}},
+		{Operation: "rename", Entity: &testTodo{ID: 101}},
+	}, domain.SyncOptions[*testTodo]{})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	var count int64
+	require.NoError(t, base.db.Model(&testTodo{}).Where("id = ?", 104).Count(&count).Error)
+	assert.Zero(t, count)
+
+	_, err = uow.ApplyChanges(ctx, nil, domain.SyncOptions[*testTodo]{Resolution: domain.MergeWith})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+// testBlogger counts its posts and tags into read-only fields
+type testBlogger struct {
+	ID         int `gorm:"primaryKey"`
+	Name       string
+	Posts      []testPost
+	Tags       []testTag `gorm:"many2many:test_blogger_tags"`
+	PostsCount int       `gorm:"->;-:migration"`
+	TagsCount  int       `gorm:"->;-:migration"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt
+}
+
+func (b *testBlogger) GetID() int                    { return b.ID }
+func (b *testBlogger) GetSlug() string               { return "" }
+func (b *testBlogger) SetSlug(string)                {}
+func (b *testBlogger) GetCreatedAt() time.Time       { return b.CreatedAt }
+func (b *testBlogger) GetUpdatedAt() time.Time       { return b.UpdatedAt }
+func (b *testBlogger) GetArchivedAt() gorm.DeletedAt { return b.DeletedAt }
+func (b *testBlogger) GetName() string               { return b.Name }
+
+type testPost struct {
+	ID            int `gorm:"primaryKey"`
+	TestBloggerID int
+	Title         string
+	DeletedAt     gorm.DeletedAt
+}
+
+type testTag struct {
+	ID        int `gorm:"primaryKey"`
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+func TestUnitOfWork_WithCounts(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testBlogger{}, &testPost{}, &testTag{}))
+	uow := newUnitOfWork[*testBlogger](nil, db)
+	ctx := context.Background()
+
+	tags := []testTag{{Name: "go"}, {Name: "sql"}, {Name: "old"}}
+	bloggers := []*testBlogger{
+		{Name: "Ada", Posts: []testPost{{Title: "One"}, {Title: "Two"}, {Title: "Gone"}}, Tags: tags},
+		{Name: "Bob"},
+	}
+	require.NoError(t, db.Create(bloggers).Error)
+	require.NoError(t, db.Where("title = ?", "Gone").Delete(&testPost{}).Error)
+	require.NoError(t, db.Where("name = ?", "old").Delete(&testTag{}).Error)
+
+	found, total, err := uow.FindAllWithPagination(ctx, domain.QueryParams[*testBlogger]{
+		Sort: domain.SortMap{"name": domain.SortAsc},
+	}.WithCounts("Posts", "Tags"))
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), total)
+	require.Len(t, found, 2)
+	assert.Equal(t, 2, found[0].PostsCount, "soft-deleted children are not counted")
+	assert.Equal(t, 2, found[0].TagsCount)
+	assert.Zero(t, found[1].PostsCount)
+
+	found, _, err = uow.FindAllByIdentifier(ctx, identifier.New().Equal("name", "Ada"), domain.QueryParams[*testBlogger]{}.WithCounts("Posts"))
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, 2, found[0].PostsCount)
+	assert.Zero(t, found[0].TagsCount, "only requested relations are counted")
+
+	_, _, err = uow.FindAllWithPagination(ctx, domain.QueryParams[*testBlogger]{}.WithCounts("Followers"))
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+}
+
+type testContact struct {
+	ID        int     `gorm:"primaryKey"`
+	Name      string  `validate:"required,max=10"`
+	Email     string  `validate:"required,email"`
+	Website   *string `validate:"url"`
+	Tier      string  `validate:"oneof=free pro"`
+	Seats     int     `validate:"min=1,max=50"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (c *testContact) GetID() int                    { return c.ID }
+func (c *testContact) GetSlug() string               { return "" }
+func (c *testContact) SetSlug(string)                {}
+func (c *testContact) GetCreatedAt() time.Time       { return c.CreatedAt }
+func (c *testContact) GetUpdatedAt() time.Time       { return c.UpdatedAt }
+func (c *testContact) GetArchivedAt() gorm.DeletedAt { return c.DeletedAt }
+func (c *testContact) GetName() string               { return c.Name }
+
+func TestUnitOfWork_ValidateTags(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testContact{}))
+	uow := newUnitOfWork[*testContact](nil, db)
+	ctx := context.Background()
+
+	site := "https://example.com"
+	created, err := uow.Insert(ctx, &testContact{Name: "Ada", Email: "ada@example.com", Website: &site, Tier: "pro", Seats: 3})
+	require.NoError(t, err)
+
+	_, err = uow.Insert(ctx, &testContact{Name: "A much too long name", Email: "not-an-email", Tier: "gold"})
+	require.ErrorIs(t, err, uowerrors.ErrEntityValidation)
+	var invalid *ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []FieldViolation{
+		{Column: "name", Rule: "max", Param: "10"},
+		{Column: "email", Rule: "email"},
+		{Column: "tier", Rule: "oneof", Param: "free pro"},
+	}, invalid.Violations, "zero values only fail required")
+
+	bad := "example"
+	_, err = uow.BulkInsert(ctx, []*testContact{{Email: "bob@example.com", Website: &bad, Seats: 99}})
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, []FieldViolation{
+		{Column: "name", Rule: "required"},
+		{Column: "website", Rule: "url"},
+		{Column: "seats", Rule: "max", Param: "50"},
+	}, invalid.Violations)
+
+	// Updates check only the fields they write
+	byID := identifier.New().Equal("id", created.ID)
+	_, err = uow.Update(ctx, byID, &testContact{Seats: 5})
+	require.NoError(t, err)
+	_, err = uow.Update(ctx, byID, &testContact{Email: "ada@"})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityValidation)
+
+	RegisterValidationRule("lowercase", func(v reflect.Value, _ string) bool { return strings.ToLower(v.String()) == v.String() })
+	type lowercased struct {
+		testContact
+		Handle string `validate:"lowercase"`
+	}
+	assert.Empty(t, validationPlanOf(metadataOf[*lowercased]()).err)
+	type misspelled struct {
+		testContact
+		Handle string `validate:"lowercse"`
+	}
+	assert.ErrorIs(t, validationPlanOf(metadataOf[*misspelled]()).err, uowerrors.ErrInvalidEntity)
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	uow := setupTestDB(t)
+	log := NewSlowQueryLog(0, 2)
+	require.NoError(t, UseSlowQueryLog(uow.db, log))
+
+	ctx := WithTenant(context.Background(), "acme")
+	for _, sql := range []string{"SELECT 1", "SELECT 2", "SELECT 3"} {
+		require.NoError(t, uow.db.WithContext(ctx).Exec(sql).Error)
+	}
+	entries := log.Entries()
+	require.Len(t, entries, 2, "only the last entries are kept")
+	assert.Equal(t, "SELECT 3", entries[0].SQL)
+	assert.Equal(t, "SELECT 2", entries[1].SQL)
+	assert.Equal(t, "acme", entries[0].Tenant)
+
+	log.Reset()
+	assert.Empty(t, log.Entries())
+	slow := NewSlowQueryLog(time.Hour, 0)
+	require.NoError(t, UseSlowQueryLog(setupTestDB(t).db, slow))
+	assert.Empty(t, slow.Entries(), "fast statements are not logged")
+}
+
+func TestUnitOfWork_RunWithCompensation(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+
+	var calls []string
+	external := func(name string, fail error) domain.Step {
+		return domain.Step{
+			Name:       name,
+			Run:        func(context.Context) error { calls = append(calls, name); return nil },
+			Compensate: func(context.Context) error { calls = append(calls, "undo "+name); return fail },
+		}
+	}
+	insert := func(slug string) domain.Step {
+		return domain.Step{Name: "insert " + slug, Run: func(ctx context.Context) error {
+			_, err := uow.Insert(ctx, &TestUser{Slug: slug, Name: slug, Email: slug + "@example.com"})
+			return err
+		}}
+	}
+
+	require.NoError(t, uow.RunWithCompensation(ctx, []domain.Step{insert("kept"), external("charge", nil)}))
+	assert.Equal(t, []string{"charge"}, calls, "nothing is compensated after a commit")
+
+	calls = nil
+	err := uow.RunWithCompensation(ctx, []domain.Step{
+		external("reserve", nil),
+		insert("dropped"),
+		external("charge", errors.New("refund API down")),
+		insert("kept"), // Unique slug violation
+		external("notify", nil),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step insert kept failed")
+	var compensation *domain.CompensationError
+	require.ErrorAs(t, err, &compensation)
+	assert.Equal(t, "charge", compensation.Step)
+	assert.Equal(t, []string{"reserve", "charge", "undo charge", "undo reserve"}, calls)
+	_, err = uow.FindOneByIdentifier(ctx, identifier.New().Equal("slug", "dropped"))
+	assert.Error(t, err, "database steps are rolled back")
+
+	calls = nil
+	assert.Panics(t, func() {
+		_ = uow.RunWithCompensation(ctx, []domain.Step{external("reserve", nil), {Name: "boom", Run: func(context.Context) error { panic("boom") }}})
+	})
+	assert.Equal(t, []string{"reserve", "undo reserve"}, calls)
+
+	require.NoError(t, uow.BeginTransaction(ctx))
+	defer uow.RollbackTransaction(ctx)
+	assert.ErrorIs(t, uow.RunWithCompensation(ctx, nil), uowerrors.ErrTransactionAlreadyOpen)
+}
+
+func TestMemoryStore_KeysetMatchesDatabase(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore[*TestUser](NewFakeClock(start, time.Minute))
+	fake := store.Create()
+
+	names := []string{"mia", "ana", "zoe", "ana", "bob", "mia", "cy", "ana", "dee", "bob", "eve"}
+	for i, name := range names {
+		user := func() *TestUser {
+			return &TestUser{
+				Slug: fmt.Sprintf("u%d", i), Name: name, Email: fmt.Sprintf("u%d@example.com", i),
+				CreatedAt: start.Add(time.Duration(i%4) * time.Hour),
+			}
+		}
+		_, err := uow.Insert(ctx, user())
+		require.NoError(t, err)
+		_, err = fake.Insert(ctx, user())
+		require.NoError(t, err)
+	}
+	err := uow.Delete(ctx, identifier.New().Equal("id", 5))
+	require.NoError(t, err)
+	store.SoftDelete(5)
+
+	type step struct {
+		IDs               []int
+		HasNext, HasPrior bool
+	}
+	walk := func(source persistence.IUnitOfWork[*TestUser], params domain.KeysetParams[*TestUser]) []step {
+		var steps []step
+		for range names {
+			page, err := source.FindAllWithKeyset(ctx, params)
+			require.NoError(t, err)
+			ids := make([]int, len(page.Items))
+			for i, item := range page.Items {
+				ids[i] = item.ID
+			}
+			steps = append(steps, step{IDs: ids, HasNext: page.HasNext, HasPrior: page.HasPrevious})
+			if len(page.Cursors) == 0 {
+				break
+			}
+			// Round-trip cursors through their encoding, as an API would
+			if params.Backward {
+				if !page.HasPrevious {
+					break
+				}
+				cursor, err := domain.DecodeCursor(domain.EncodeCursor(page.Cursors[0]))
+				require.NoError(t, err)
+				params.Before = &cursor
+			} else {
+				if !page.HasNext {
+					break
+				}
+				cursor, err := domain.DecodeCursor(domain.EncodeCursor(page.Cursors[len(page.Cursors)-1]))
+				require.NoError(t, err)
+				params.After = &cursor
+			}
+		}
+		return steps
+	}
+
+	for _, params := range []domain.KeysetParams[*TestUser]{
+		{Limit: 3},
+		{Limit: 4, Direction: domain.SortDesc},
+		{Limit: 3, SortField: "name"},
+		{Limit: 2, SortField: "name", Direction: domain.SortDesc},
+		{Limit: 3, SortField: "created_at"},
+		{Limit: 3, SortField: "created_at", Backward: true},
+		{Limit: 2, SortField: "name", Backward: true, Direction: domain.SortDesc},
+		{Limit: 2, SortField: "created_at", Filter: &TestUser{Name: "ana"}},
+		{Limit: 3, SortField: "name", After: &domain.Cursor{Value: "bob", ID: 5}, Before: &domain.Cursor{Value: "mia", ID: 1}},
+	} {
+		assert.Equal(t, walk(uow, params), walk(fake, params), "%+v", params)
+	}
+
+	_, err = fake.FindAllWithKeyset(ctx, domain.KeysetParams[*TestUser]{SortField: "nickname"})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	found, err := fake.FindOneById(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "ana", found.Name)
+	_, err = fake.FindOneById(ctx, 5)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestNPlusOneDetection(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TestUser{}))
+
+	var reports []NPlusOneReport
+	config := &Config{NPlusOne: &NPlusOneDetection{Threshold: 3, Report: func(r NPlusOneReport) { reports = append(reports, r) }}}
+	require.NoError(t, UseNPlusOneDetection(db, config.NPlusOne))
+	ctx := context.Background()
+
+	uow := newUnitOfWork[*TestUser](config, db)
+	var ids []int
+	for i := 0; i < 4; i++ {
+		user, err := uow.Insert(ctx, &TestUser{Slug: fmt.Sprintf("n1-%d", i), Name: "N", Email: fmt.Sprintf("n1-%d@example.com", i)})
+		require.NoError(t, err)
+		ids = append(ids, user.ID)
+	}
+	_, err = uow.FindByIDs(ctx, ids)
+	require.NoError(t, err)
+	assert.Empty(t, reports, "one batched query is not a pattern")
+
+	for _, id := range ids {
+		_, err := uow.FindOneById(ctx, id)
+		require.NoError(t, err)
+	}
+	require.Len(t, reports, 1, "reported once per scope")
+	assert.Equal(t, "test_users", reports[0].Table)
+	assert.Equal(t, 3, reports[0].Count)
+	require.Len(t, reports[0].CallSites, 1)
+	assert.Contains(t, reports[0].CallSites[0], "unit_of_work_test.go")
+	assert.Contains(t, reports[0].String(), "FindByIDs")
+
+	// A request scope spans units of work
+	reports = nil
+	scope := WithQueryScope(ctx)
+	for _, id := range ids[:3] {
+		_, err := newUnitOfWork[*TestUser](config, db).FindOneById(scope, id)
+		require.NoError(t, err)
+	}
+	assert.Len(t, reports, 1)
+}
+
+func TestUnitOfWork_FindPage(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Slug: fmt.Sprintf("page-%d", i), Name: "Paged", Email: fmt.Sprintf("page-%d@example.com", i)})
+		require.NoError(t, err)
+	}
+
+	page, err := uow.FindPage(ctx, domain.QueryParams[*TestUser]{Sort: domain.SortMap{"id": domain.SortAsc}, Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	assert.Equal(t, uint(5), page.Total)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, "page-2", page.Items[0].Slug)
+	assert.True(t, page.HasMore())
+
+	page, err = uow.FindPageByIdentifier(ctx, identifier.NewIdentifier().Equal("slug", "page-4"), domain.QueryParams[*TestUser]{})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), page.Total)
+	assert.False(t, page.HasMore())
+
+	keyset, err := uow.FindPageWithKeyset(ctx, domain.KeysetParams[*TestUser]{Limit: 3})
+	require.NoError(t, err)
+	require.NotEmpty(t, keyset.NextCursor)
+	cursor, err := domain.DecodeCursor(keyset.NextCursor)
+	require.NoError(t, err)
+	rest, err := uow.FindPageWithKeyset(ctx, domain.KeysetParams[*TestUser]{After: &cursor, Limit: 3})
+	require.NoError(t, err)
+	assert.Len(t, rest.Items, 2)
+	assert.Empty(t, rest.NextCursor)
+
+	empty, err := uow.FindPage(ctx, domain.QueryParams[*TestUser]{Filter: &TestUser{Slug: "missing"}})
+	require.NoError(t, err)
+	data, err := json.Marshal(empty)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[],"total":0,"limit":0,"offset":0}`, string(data))
+}
+
+func TestModelRegistry_SeedProfiles(t *testing.T) {
+	db := setupTestDB(t).db
+	registry := NewModelRegistry()
+	registry.RegisterSeeder(&TestUser{}, func(ctx context.Context, tx *gorm.DB) error {
+		return tx.Create(&TestUser{Slug: "admin", Name: "Admin", Email: "admin@example.com"}).Error
+	})
+	registry.RegisterProfileSeeder(&TestUser{}, func(ctx context.Context, tx *gorm.DB, profile SeedProfile) error {
+		for i := 0; i < profile.Size*profile.Param("per_size", 1); i++ {
+			slug := fmt.Sprintf("%s-%d", profile.Name, i)
+			if err := tx.Create(&TestUser{Slug: slug, Name: "Seeded", Email: slug + "@example.com"}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}, SeedDemo)
+
+	count := func() int64 {
+		var n int64
+		require.NoError(t, db.Model(&TestUser{}).Count(&n).Error)
+		return n
+	}
+	ctx := context.Background()
+	require.NoError(t, registry.Seed(ctx, db))
+	assert.Equal(t, int64(1), count(), "minimal runs only seeders for every profile")
+
+	require.NoError(t, db.Exec("DELETE FROM test_users").Error)
+	registry.DefineSeedProfile(SeedProfile{Name: SeedDemo, Size: 2})
+	demo, err := registry.Profile(SeedDemo)
+	require.NoError(t, err)
+	require.NoError(t, registry.SeedWithProfile(ctx, db, demo.With("per_size", 3)))
+	assert.Equal(t, int64(7), count())
+
+	_, err = registry.Profile("staging")
+	assert.ErrorContains(t, err, "load-test")
+}
+
+func TestUnitOfWork_TrashedViews(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Slug: fmt.Sprintf("view-%d", i), Name: "View", Email: fmt.Sprintf("view-%d@example.com", i)})
+		require.NoError(t, err)
+	}
+	_, err := uow.SoftDelete(ctx, identifier.NewIdentifier().Equal("slug", "view-0"))
+	require.NoError(t, err)
+
+	slugs := func(query domain.QueryParams[*TestUser]) []string {
+		query.Sort = domain.SortMap{"id": domain.SortAsc}
+		users, total, err := uow.FindAllWithPagination(ctx, query)
+		require.NoError(t, err)
+		result := make([]string, len(users))
+		for i, user := range users {
+			result[i] = user.Slug
+		}
+		assert.Equal(t, uint(len(users)), total)
+		return result
+	}
+	assert.Equal(t, []string{"view-1", "view-2"}, slugs(domain.QueryParams[*TestUser]{}))
+	assert.Equal(t, []string{"view-0", "view-1", "view-2"}, slugs(domain.QueryParams[*TestUser]{IncludeTrashed: true}))
+	assert.Equal(t, []string{"view-0"}, slugs(domain.QueryParams[*TestUser]{OnlyTrashed: true, IncludeTrashed: true}))
+
+	page, err := uow.FindPageByIdentifier(ctx, identifier.NewIdentifier().Like("slug", "view-%"), domain.QueryParams[*TestUser]{OnlyTrashed: true})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.True(t, page.Items[0].DeletedAt.Valid)
+}
+
+func TestUnitOfWork_UpsertResult(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, err := uow.Insert(ctx, &TestUser{Slug: fmt.Sprintf("upsert-%d", i), Name: "Before", Email: fmt.Sprintf("upsert-%d@example.com", i)})
+		require.NoError(t, err)
+	}
+
+	uow.settings = newEntitySettings[*TestUser]()
+	kinds := make(map[string]domain.ChangeKind)
+	uow.settings.addListener(func(_ context.Context, changes []domain.Change[*TestUser]) {
+		for _, change := range changes {
+			kinds[change.Entity.Slug] = change.Kind
+		}
+	})
+
+	batch := make([]*TestUser, 3)
+	for i := range batch {
+		batch[i] = &TestUser{Slug: fmt.Sprintf("upsert-%d", i+1), Name: "After", Email: fmt.Sprintf("upsert-%d@example.com", i+1)}
+	}
+	upserted, result, err := uow.BulkUpsert(ctx, batch, "slug")
+	require.NoError(t, err)
+	assert.Equal(t, domain.UpsertResult{Inserted: 2, Updated: 1}, result)
+	require.Len(t, upserted, 3)
+	assert.Equal(t, map[string]domain.ChangeKind{
+		"upsert-1": domain.ChangeUpdated,
+		"upsert-2": domain.ChangeCreated,
+		"upsert-3": domain.ChangeCreated,
+	}, kinds)
+
+	var total domain.UpsertResult
+	total.Add(result)
+	_, result, err = uow.Upsert(ctx, &TestUser{Slug: "upsert-0", Name: "After", Email: "upsert-0@example.com"}, "slug")
+	require.NoError(t, err)
+	assert.Equal(t, domain.UpsertResult{Updated: 1}, result)
+	total.Add(result)
+	assert.Equal(t, int64(4), total.Total())
+
+	stored, err := uow.FindOneByIdentifier(ctx, identifier.NewIdentifier().Equal("slug", "upsert-0"))
+	require.NoError(t, err)
+	assert.Equal(t, "After", stored.Name)
+}
+
+type testNoteReply struct {
+	ID     int `gorm:"primaryKey"`
+	NoteID int
+}
+
+func TestUnitOfWork_HardDeleteWithDependents(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	registry := NewModelRegistry()
+	registry.Register(&TestUser{}, &testUserNote{}, &testNoteReply{})
+	registry.DeclareRelations(
+		Relation{Child: "test_user_notes", ChildKey: "id", ForeignKey: "reviewer_id", Parent: "test_users", ParentKey: "id"},
+		Relation{Child: "test_note_replies", ChildKey: "id", ForeignKey: "note_id", Parent: "test_user_notes", ParentKey: "id"},
+	)
+	uow.config = &Config{Models: registry}
+	require.NoError(t, uow.db.AutoMigrate(&testUserNote{}, &testNoteReply{}))
+
+	users := []*TestUser{
+		{Slug: "owner", Name: "Owner", Email: "owner@example.com"},
+		{Slug: "loner", Name: "Loner", Email: "loner@example.com"},
+	}
+	require.NoError(t, uow.db.Create(users).Error)
+	owner := users[0].ID
+	notes := []*testUserNote{{AuthorID: owner}, {AuthorID: users[1].ID, ReviewerID: &owner}}
+	require.NoError(t, uow.db.Create(notes).Error)
+	require.NoError(t, uow.db.Create([]*testNoteReply{{NoteID: notes[0].ID}, {NoteID: notes[0].ID}, {NoteID: notes[1].ID}}).Error)
+
+	_, err := uow.HardDeleteWithDependents(ctx, identifier.New().Equal("slug", "owner"), "orphan")
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+
+	_, err = uow.HardDeleteWithDependents(ctx, identifier.New().Equal("slug", "owner"), domain.DeleteRestrict)
+	require.ErrorIs(t, err, uowerrors.ErrHasDependents)
+	var dependents *DependentsError
+	require.ErrorAs(t, err, &dependents)
+	require.Len(t, dependents.Dependents, 2)
+	assert.Equal(t, "test_user_notes.author_id -> test_users.id", dependents.Dependents[0].Relation.String())
+	assert.Equal(t, int64(1), dependents.Dependents[0].Count)
+	assert.Equal(t, "test_user_notes.reviewer_id -> test_users.id", dependents.Dependents[1].Relation.String())
+
+	result, err := uow.HardDeleteWithDependents(ctx, identifier.New().Equal("slug", "owner"), domain.DeleteCascade)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"test_users": 1, "test_user_notes": 2, "test_note_replies": 3}, result.Deleted)
+	assert.Equal(t, int64(6), result.Total())
+
+	result, err = uow.HardDeleteWithDependents(ctx, identifier.New().Equal("slug", "loner"), domain.DeleteRestrict)
+	require.NoError(t, err, "the loner's note went with the owner's cascade")
+	assert.Equal(t, map[string]int64{"test_users": 1}, result.Deleted)
+	var remaining int64
+	require.NoError(t, uow.db.Unscoped().Model(&TestUser{}).Count(&remaining).Error)
+	assert.Zero(t, remaining)
+}
+
+type testLink struct {
+	ID        int    `gorm:"primaryKey" json:"id"`
+	OwnerID   int    `json:"ownerID"`
+	ImageURL  string `json:"imageURL" pii:"true"`
+	DeletedAt gorm.DeletedAt
+}
+
+func (l *testLink) GetID() int                    { return l.ID }
+func (l *testLink) GetSlug() string               { return "" }
+func (l *testLink) SetSlug(string)                {}
+func (l *testLink) GetCreatedAt() time.Time       { return time.Time{} }
+func (l *testLink) GetUpdatedAt() time.Time       { return time.Time{} }
+func (l *testLink) GetArchivedAt() gorm.DeletedAt { return l.DeletedAt }
+func (l *testLink) GetName() string               { return "" }
+
+func TestNamingStrategy(t *testing.T) {
+	ctx := context.Background()
+	open := func(t *testing.T, config *gorm.Config) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(":memory:"), config)
+		require.NoError(t, err)
+		require.NoError(t, db.AutoMigrate(&testLink{}))
+		require.NoError(t, db.Create([]*testLink{{OwnerID: 1, ImageURL: "b.png"}, {OwnerID: 1, ImageURL: "a.png"}, {OwnerID: 2, ImageURL: "c.png"}}).Error)
+		return db
+	}
+	check := func(t *testing.T, db *gorm.DB, table, imageColumn string) {
+		require.True(t, db.Migrator().HasColumn(&testLink{}, imageColumn))
+		assert.Equal(t, table, metadataIn[*testLink](db).Table)
+
+		// Contiguous capitals resolve like GORM's own column names
+		repo := NewBaseRepository[*testLink](db)
+		links, err := repo.List(ctx, domain.QueryParams[*testLink]{Filter: &testLink{OwnerID: 1}, Sort: domain.SortMap{"ImageURL": domain.SortAsc}})
+		require.NoError(t, err)
+		require.Len(t, links, 2)
+		assert.Equal(t, "a.png", links[0].ImageURL)
+
+		policy := &MaskingPolicy{}
+		uow := newUnitOfWork[*testLink](&Config{Masking: policy}, db)
+		found, total, err := uow.FindAllByIdentifier(ctx, identifier.New().Equal("OwnerID", 1).Like("ImageURL", "b%"), domain.QueryParams[*testLink]{})
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), total)
+		assert.Equal(t, "b.png", found[0].ImageURL)
+		assert.True(t, policy.IsPIIColumn(imageColumn))
+		assert.Equal(t, imageColumn, uow.NamingStrategy().ColumnName(table, "ImageURL"))
+	}
+
+	// Pools with different strategies coexist: neither leaks into the other's column resolution
+	defaults := open(t, &gorm.Config{})
+	prefixed := open(t, &gorm.Config{NamingStrategy: schema.NamingStrategy{TablePrefix: "app_", NameReplacer: strings.NewReplacer("URL", "Uri")}})
+	check(t, defaults, "test_links", "image_url")
+	check(t, prefixed, "app_test_links", "image_uri")
+	check(t, defaults, "test_links", "image_url")
+}
+
+func TestUnitOfWork_Transaction(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	insert := func(slug string) func(persistence.IUnitOfWork[*TestUser]) error {
+		return func(txUow persistence.IUnitOfWork[*TestUser]) error {
+			_, err := txUow.Insert(ctx, &TestUser{Slug: slug, Name: "Tx", Email: slug + "@example.com"})
+			return err
+		}
+	}
+	exists := func(slug string) bool {
+		var count int64
+		require.NoError(t, uow.db.Model(&TestUser{}).Where("slug = ?", slug).Count(&count).Error)
+		return count == 1
+	}
+
+	require.NoError(t, uow.Transaction(ctx, insert("tx-commit")))
+	assert.True(t, exists("tx-commit"))
+	assert.False(t, uow.IsInTransaction())
+
+	failure := errors.New("boom")
+	err := uow.Transaction(ctx, func(txUow persistence.IUnitOfWork[*TestUser]) error {
+		require.NoError(t, insert("tx-error")(txUow))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.False(t, exists("tx-error"))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = uow.Transaction(ctx, func(txUow persistence.IUnitOfWork[*TestUser]) error {
+			require.NoError(t, insert("tx-panic")(txUow))
+			panic("boom")
+		})
+	})
+	assert.False(t, uow.IsInTransaction())
+	assert.False(t, exists("tx-panic"))
+
+	err = uow.Transaction(ctx, func(txUow persistence.IUnitOfWork[*TestUser]) error {
+		require.NoError(t, insert("tx-outer")(txUow))
+		assert.ErrorIs(t, txUow.Transaction(ctx, func(inner persistence.IUnitOfWork[*TestUser]) error {
+			require.NoError(t, insert("tx-inner")(inner))
+			return failure
+		}), failure)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, exists("tx-outer"))
+	assert.False(t, exists("tx-inner"))
+}
+
+func TestUnitOfWork_DeadlineBudget(t *testing.T) {
+	uow := setupTestDB(t)
+	users := func(prefix string, n int) []*TestUser {
+		result := make([]*TestUser, n)
+		for i := range result {
+			slug := fmt.Sprintf("%s-%d", prefix, i)
+			result[i] = &TestUser{Slug: slug, Name: "Budget", Email: slug + "@example.com"}
+		}
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	inserted, err := uow.BulkInsert(ctx, users("roomy", 250))
+	require.NoError(t, err)
+	assert.Len(t, inserted, 250)
+
+	tight, cancel := context.WithTimeout(context.Background(), DefaultDeadlineReserve/2)
+	defer cancel()
+	inserted, err = uow.BulkInsert(tight, users("tight", 10))
+	require.ErrorIs(t, err, uowerrors.ErrDeadlineBudgetExceeded)
+	var budgetErr *DeadlineBudgetError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, 0, budgetErr.Processed)
+	assert.Equal(t, 10, budgetErr.Total)
+	assert.Empty(t, inserted)
+	var count int64
+	require.NoError(t, uow.db.Model(&TestUser{}).Where("slug LIKE ?", "tight-%").Count(&count).Error)
+	assert.Zero(t, count)
+
+	// Two seconds for the first 100 rows leaves room for 10 more but not another 100 in the last second
+	budget := &deadlineBudget{operation: "bulk insert", total: 300, start: time.Now().Add(-2 * time.Second),
+		deadline: time.Now().Add(time.Second), reserve: DefaultDeadlineReserve}
+	assert.NoError(t, budget.admit(100, 10))
+	err = budget.admit(100, 100)
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, 100, budgetErr.Processed)
+	assert.Contains(t, err.Error(), "stopped after 100 of 300 rows")
+}
+
+type countingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (m *countingMetrics) IncCounter(name string, delta int64, _ map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]int64)
+	}
+	m.counts[name] += delta
+}
+
+func (m *countingMetrics) count(name string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[name]
+}
+
+func TestReplicaLagFallback(t *testing.T) {
+	primary := setupTestDB(t).db
+	replica, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, replica.AutoMigrate(&TestUser{}))
+	metrics := &countingMetrics{}
+	require.NoError(t, UseReplicas(primary, []*gorm.DB{replica}, ReplicaOptions{
+		MaxLagBytes:      1024,
+		LagCheckInterval: time.Hour,
+		Metrics:          metrics,
+	}))
+	var lag int64 = 4096
+	replicaRouterOf(primary).measureLag = func(context.Context, gorm.ConnPool, gorm.ConnPool) (int64, error) {
+		return lag, nil
+	}
+	require.NoError(t, primary.Create(&TestUser{Name: "Primary", Email: "primary@example.com", Slug: "primary"}).Error)
+
+	// Reads stay on the primary while the replica lags, measured once per interval
+	uow := newUnitOfWork[*TestUser](nil, primary)
+	for i := 0; i < 2; i++ {
+		users, err := uow.FindAll(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+	}
+	assert.Equal(t, int64(2), metrics.count(MetricReplicaLagFallbacks))
+
+	// A caught-up replica serves reads again after the next measurement
+	lag = 0
+	router := replicaRouterOf(primary)
+	router.lags[0].checkedAt = time.Time{}
+	users, err := uow.FindAll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	assert.Equal(t, int64(2), metrics.count(MetricReplicaLagFallbacks))
+}
+
+func TestCounter(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	require.NoError(t, MigrateCounters(ctx, uow.db))
+
+	views := uow.Counter("post:1:views", 4)
+	for i := 0; i < 40; i++ {
+		require.NoError(t, views.Increment(ctx))
+	}
+	require.NoError(t, views.Add(ctx, -5))
+	require.NoError(t, uow.Counter("post:2:views", 0).Add(ctx, 7))
+
+	value, err := views.ReadApprox(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(35), value)
+	var shards int64
+	require.NoError(t, uow.db.Model(&CounterShard{}).Where("name = ?", views.Name()).Count(&shards).Error)
+	assert.LessOrEqual(t, shards, int64(4))
+
+	require.NoError(t, views.ConsolidateShards(ctx))
+	var rows []CounterShard
+	require.NoError(t, uow.db.Where("name = ?", views.Name()).Find(&rows).Error)
+	assert.Equal(t, []CounterShard{{Name: "post:1:views", Shard: 0, Value: 35}}, rows)
+	value, err = uow.Counter("post:2:views", 0).ReadApprox(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), value)
+
+	// Increments join the unit of work's transaction
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, views.Add(ctx, 100))
+	uow.RollbackTransaction(ctx)
+	value, err = views.ReadApprox(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(35), value)
+}
+
+func TestTransactionManager(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testNote{}))
+	ctx := context.Background()
+	manager := NewTransactionManager(nil, db)
+	count := func(model interface{}) int64 {
+		var n int64
+		require.NoError(t, db.Model(model).Count(&n).Error)
+		return n
+	}
+
+	// Work on both entity types commits as one unit
+	var committed []string
+	err := manager.Run(ctx, func(m *TransactionManager) error {
+		users, notes := UnitOfWorkIn[*TestUser](m), UnitOfWorkIn[*testNote](m)
+		if _, err := users.Insert(ctx, &TestUser{Slug: "shared", Name: "Shared", Email: "shared@example.com"}); err != nil {
+			return err
+		}
+		notes.AfterCommit(ctx, func(context.Context) { committed = append(committed, "notes") })
+		_, err := notes.Insert(ctx, &testNote{Name: "shared"})
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count(&TestUser{}))
+	assert.Equal(t, int64(1), count(&testNote{}))
+	assert.Equal(t, []string{"notes"}, committed)
+
+	// A failure on one entity type rolls back the other's work as well
+	users := UnitOfWorkIn[*TestUser](manager)
+	notes := UnitOfWorkIn[*testNote](manager)
+	require.NoError(t, manager.Begin(ctx))
+	assert.True(t, users.(*UnitOfWork[*TestUser]).IsInTransaction())
+	_, err = users.Insert(ctx, &TestUser{Slug: "rolled-back", Name: "Rolled back", Email: "rolled-back@example.com"})
+	require.NoError(t, err)
+	_, err = notes.Insert(ctx, &testNote{Name: "rolled back"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, notes.CommitTransaction(ctx), uowerrors.ErrTransactionCommitFailed)
+	notes.RollbackTransaction(ctx)
+	assert.False(t, manager.InTransaction())
+	assert.False(t, users.(*UnitOfWork[*TestUser]).IsInTransaction())
+	assert.Equal(t, int64(1), count(&TestUser{}))
+	assert.Equal(t, int64(1), count(&testNote{}))
+	assert.ErrorIs(t, users.BeginTransaction(ctx), uowerrors.ErrTransactionNotStarted)
+
+	// Views are released when their transaction ends, or when closed before
+	assert.Empty(t, manager.views)
+	require.NoError(t, manager.Begin(ctx))
+	closed := UnitOfWorkIn[*TestUser](manager)
+	require.NoError(t, closed.Close())
+	assert.True(t, manager.InTransaction())
+	assert.Empty(t, manager.views)
+	manager.Rollback(ctx)
+}
+
+func TestUnitOfWorkFactoryFromDB(t *testing.T) {
+	db := setupTestDB(t).db
+	factory := NewUnitOfWorkFactoryFromDB[*TestUser](db)
+	ctx := context.Background()
+
+	_, err := factory.CreateWithContext(ctx).Insert(ctx, &TestUser{Slug: "injected", Name: "Injected", Email: "injected@example.com"})
+	require.NoError(t, err)
+	users, err := factory.Create().FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	// The injected pool belongs to the caller
+	require.NoError(t, factory.Close())
+	require.NoError(t, NewUnitOfWorkFromDB[*TestUser](db).Close())
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	assert.NoError(t, sqlDB.Ping())
+}
+
+type fakeBlobStore struct {
+	deleted []string
+	fail    bool
+}
+
+func (s *fakeBlobStore) Delete(_ context.Context, key string) error {
+	if s.fail {
+		return errors.New("storage unavailable")
+	}
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func TestBlobRefs(t *testing.T) {
+	uow := setupSharedTestDB(t)
+	ctx := context.Background()
+	require.NoError(t, MigrateBlobRefs(ctx, uow.db))
+	store := &fakeBlobStore{}
+	blobs := uow.BlobRefs(store)
+	orphans := func() []string {
+		var keys []string
+		require.NoError(t, uow.db.Model(&OrphanBlob{}).Order("key").Pluck("key", &keys).Error)
+		return keys
+	}
+
+	assert.ErrorIs(t, blobs.Track(ctx, "avatars/outside.png"), uowerrors.ErrTransactionNotStarted)
+
+	// A committed upload is kept, a rolled back one deleted
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, blobs.Track(ctx, "avatars/1.png"))
+	_, err := uow.Insert(ctx, &TestUser{Slug: "blob", Name: "Blob", Email: "blob@example.com"})
+	require.NoError(t, err)
+	require.NoError(t, uow.CommitTransaction(ctx))
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, blobs.Track(ctx, "avatars/2.png"))
+	uow.RollbackTransaction(ctx)
+	assert.Equal(t, []string{"avatars/2.png"}, store.deleted)
+	assert.Empty(t, orphans())
+
+	// A released object goes only once the transaction commits
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, blobs.Release(ctx, "avatars/1.png"))
+	uow.RollbackTransaction(ctx)
+	assert.Equal(t, []string{"avatars/2.png"}, store.deleted)
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, blobs.Release(ctx, "avatars/1.png"))
+	require.NoError(t, uow.CommitTransaction(ctx))
+	assert.Equal(t, []string{"avatars/2.png", "avatars/1.png"}, store.deleted)
+
+	// Rolling back to a savepoint deletes the uploads tracked since
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, uow.Savepoint(ctx, "upload"))
+	require.NoError(t, blobs.Track(ctx, "avatars/3.png"))
+	require.NoError(t, uow.RollbackTo(ctx, "upload"))
+	require.NoError(t, uow.CommitTransaction(ctx))
+	assert.Equal(t, []string{"avatars/2.png", "avatars/1.png", "avatars/3.png"}, store.deleted)
+	assert.Empty(t, orphans())
+
+	// Failed deletions are left for the sweeper
+	store.fail = true
+	require.NoError(t, uow.BeginTransaction(ctx))
+	require.NoError(t, blobs.Track(ctx, "avatars/4.png"))
+	uow.RollbackTransaction(ctx)
+	assert.Equal(t, []string{"avatars/4.png"}, orphans())
+	_, err = SweepOrphanBlobs(ctx, uow.db, store, 0)
+	assert.Error(t, err)
+	store.fail = false
+	swept, err := SweepOrphanBlobs(ctx, uow.db, store, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, swept)
+	assert.Empty(t, orphans())
+}
+
+func TestUnitOfWork_TxOptions(t *testing.T) {
+	uow := setupTestDB(t)
+	assert.Equal(t, sql.TxOptions{Isolation: sql.LevelReadCommitted}, uow.txOptions(nil))
+	uow.config = &Config{TxOptions: &sql.TxOptions{Isolation: sql.LevelSerializable}}
+	assert.Equal(t, sql.TxOptions{Isolation: sql.LevelSerializable}, uow.txOptions(nil))
+	assert.Equal(t, sql.TxOptions{Isolation: sql.LevelRepeatableRead}, uow.txOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead}))
+	uow.readOnly = true
+	assert.Equal(t, sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}, uow.txOptions(nil))
+
+	primary := setupTestDB(t).db
+	replica, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, replica.AutoMigrate(&TestUser{}))
+	require.NoError(t, UseReplicas(primary, []*gorm.DB{replica}, ReplicaOptions{}))
+	ctx := context.Background()
+	writer := newUnitOfWork[*TestUser](nil, primary)
+
+	require.NoError(t, writer.BeginTransactionWithOptions(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead}))
+	_, err = writer.Insert(ctx, &TestUser{Name: "Repeatable", Email: "repeatable@example.com", Slug: "repeatable"})
+	require.NoError(t, err)
+	require.NoError(t, writer.CommitTransaction(ctx))
+
+	// Query-only flows of a writable unit of work can run READ ONLY on a replica
+	other := newUnitOfWork[*TestUser](nil, primary)
+	require.NoError(t, other.BeginReadOnlyTransaction(ctx))
+	users, err := other.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	require.NoError(t, other.CommitTransaction(ctx))
+
+	// A count and page read in a transaction of their own
+	users, total, err := writer.FindAllWithPagination(ctx, domain.QueryParams[*TestUser]{
+		Limit:     10,
+		TxOptions: &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), total)
+	assert.Len(t, users, 1)
+	assert.False(t, writer.IsInTransaction())
+}
+
+type testPayment struct {
+	ID          int       `gorm:"primaryKey;autoIncrement"`
+	Name        string    `checksum:"true"`
+	Amount      int64     `checksum:"true"`
+	DueAt       time.Time `checksum:"true"`
+	Note        string
+	RowChecksum string `gorm:"size:64"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt
+}
+
+func (p *testPayment) GetID() int                    { return p.ID }
+func (p *testPayment) GetSlug() string               { return "" }
+func (p *testPayment) SetSlug(string)                {}
+func (p *testPayment) GetCreatedAt() time.Time       { return p.CreatedAt }
+func (p *testPayment) GetUpdatedAt() time.Time       { return p.UpdatedAt }
+func (p *testPayment) GetArchivedAt() gorm.DeletedAt { return p.DeletedAt }
+func (p *testPayment) GetName() string               { return p.Name }
+
+func TestRowChecksums(t *testing.T) {
+	db := setupTestDB(t).db
+	require.NoError(t, db.AutoMigrate(&testPayment{}))
+	payments := NewUnitOfWorkFromDB[*testPayment](db)
+	ctx := context.Background()
+
+	_, err := payments.VerifyIntegrity(ctx, domain.QueryParams[*testPayment]{})
+	require.Error(t, err)
+
+	// Rows written before checksums were enabled stay unsigned
+	var legacy []interface{}
+	for _, name := range []string{"legacy", "archive"} {
+		payment, err := payments.Insert(ctx, &testPayment{Name: name, Amount: 50, DueAt: time.Now()})
+		require.NoError(t, err)
+		legacy = append(legacy, payment.ID)
+	}
+	require.NoError(t, UseRowChecksums(db, []byte("secret")))
+
+	var ids []int
+	for i, name := range []string{"rent", "salary", "refund"} {
+		payment, err := payments.Insert(ctx, &testPayment{Name: name, Amount: int64(100 * (i + 1)), DueAt: time.Now()})
+		require.NoError(t, err)
+		ids = append(ids, payment.ID)
+	}
+	report, err := payments.VerifyIntegrity(ctx, domain.QueryParams[*testPayment]{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), report.Checked)
+	assert.Empty(t, report.Mismatched)
+	assert.Equal(t, legacy, report.Unsigned)
+
+	// Application updates, by entity and by condition, re-sign the rows
+	_, err = payments.Update(ctx, identifier.New().Equal("id", ids[0]), &testPayment{Amount: 150})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&testPayment{}).Where("amount >= ?", 200).Update("amount", gorm.Expr("amount + 1")).Error)
+
+	// A change made outside the application is reported and not laundered by later updates
+	require.NoError(t, db.Exec("UPDATE test_payments SET amount = 999999 WHERE id = ?", ids[1]).Error)
+	require.NoError(t, db.Model(&testPayment{}).Where("id = ?", ids[1]).Update("note", "reviewed").Error)
+	require.NoError(t, db.Exec("UPDATE test_payments SET amount = 1, row_checksum = NULL WHERE id = ?", ids[2]).Error)
+
+	report, err = payments.VerifyIntegrity(ctx, domain.QueryParams[*testPayment]{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), report.Checked)
+	assert.Equal(t, []interface{}{ids[1]}, report.Mismatched)
+	assert.Equal(t, append(legacy, ids[2]), report.Unsigned)
+
+	// Only the queried rows are checked
+	report, err = payments.VerifyIntegrity(ctx, domain.QueryParams[*testPayment]{Filter: &testPayment{Name: "rent"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Checked)
+	assert.True(t, report.Clean())
+
+	// The backfill needs an explicit selection, and never signs a row updated since checksums were
+	// enabled, whose checksum can only have been cleared outside the application
+	_, err = payments.SignRows(ctx, SignOptions{})
+	assert.ErrorIs(t, err, uowerrors.ErrInvalidQueryParams)
+	signed, err := payments.SignRows(ctx, SignOptions{IDs: []interface{}{ids[2]}})
+	require.NoError(t, err)
+	assert.Zero(t, signed)
+	signed, err = payments.SignRows(ctx, SignOptions{CreatedBefore: time.Now(), BatchSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), signed)
+
+	// Backfilled rows stay signed through later updates
+	_, err = payments.Update(ctx, identifier.New().Equal("id", legacy[0]), &testPayment{Amount: 75})
+	require.NoError(t, err)
+	report, err = payments.VerifyIntegrity(ctx, domain.QueryParams[*testPayment]{})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{ids[2]}, report.Unsigned)
+	assert.Equal(t, []interface{}{ids[1]}, report.Mismatched)
+	signed, err = payments.SignRows(ctx, SignOptions{CreatedBefore: time.Now()})
+	require.NoError(t, err)
+	assert.Zero(t, signed)
+}
+
+func TestUnitOfWork_TransactionWithRetry(t *testing.T) {
+	serialization := fmt.Errorf("failed to update entity: %w", &pgconn.PgError{Code: "40001"})
+	assert.True(t, uowerrors.IsRetryable(serialization))
+	assert.True(t, uowerrors.IsRetryable(&pgconn.PgError{Code: "40P01"}))
+	assert.True(t, uowerrors.IsRetryable(uowerrors.ErrDatabaseDeadlock))
+	assert.False(t, uowerrors.IsRetryable(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, uowerrors.IsRetryable(nil))
+
+	policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 30*time.Millisecond, policy.backoff(5))
+	policy.Jitter = 0.5
+	for range 20 {
+		wait := policy.backoff(2)
+		assert.True(t, wait >= 10*time.Millisecond && wait <= 20*time.Millisecond, wait)
+	}
+
+	uow := setupTestDB(t)
+	ctx := context.Background()
+	metrics := &countingMetrics{}
+	policy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Metrics: metrics}
+
+	// Each attempt runs in a new transaction; the failed ones leave nothing behind
+	attempts := 0
+	err := uow.TransactionWithRetry(ctx, policy, func(tx persistence.IUnitOfWork[*TestUser]) error {
+		attempts++
+		if _, err := tx.Insert(ctx, &TestUser{Name: "Retry", Email: "retry@example.com", Slug: "retry"}); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return serialization
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, int64(2), metrics.count(MetricTransactionRetries))
+	var count int64
+	require.NoError(t, uow.db.Model(&TestUser{}).Where("slug = ?", "retry").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Attempts run out
+	attempts = 0
+	err = uow.TransactionWithRetry(ctx, policy, func(persistence.IUnitOfWork[*TestUser]) error {
+		attempts++
+		return serialization
+	})
+	assert.True(t, uowerrors.IsRetryable(err))
+	assert.Equal(t, 3, attempts)
+
+	// Other errors are returned at once
+	attempts = 0
+	err = uow.TransactionWithRetry(ctx, policy, func(persistence.IUnitOfWork[*TestUser]) error {
+		attempts++
+		return uowerrors.ErrEntityValidation
+	})
+	assert.ErrorIs(t, err, uowerrors.ErrEntityValidation)
+	assert.Equal(t, 1, attempts)
+
+	// Transaction retries under Config.TransactionRetry, but not inside an open transaction
+	uow.config = &Config{TransactionRetry: &policy}
+	attempts = 0
+	require.NoError(t, uow.Transaction(ctx, func(persistence.IUnitOfWork[*TestUser]) error {
+		if attempts++; attempts == 1 {
+			return serialization
+		}
+		return nil
+	}))
+	assert.Equal(t, 2, attempts)
+
+	require.NoError(t, uow.BeginTransaction(ctx))
+	attempts = 0
+	err = uow.Transaction(ctx, func(persistence.IUnitOfWork[*TestUser]) error {
+		attempts++
+		return serialization
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+	uow.RollbackTransaction(ctx)
+}
+
+func TestUseStatementLog(t *testing.T) {
+	uow := setupTestDB(t)
+	ctx := WithUser(WithTenant(context.Background(), "acme"), "alice")
+	var logged []LoggedStatement
+	masking := &MaskingPolicy{}
+	masking.Register(&TestUser{})
+	log := &StatementLog{
+		Sink: StatementSinkFunc(func(_ context.Context, statement LoggedStatement) {
+			logged = append(logged, statement)
+		}),
+		SampleRate: 1,
+		Arguments:  true,
+		Redact:     []RedactionRule{RedactPattern(regexp.MustCompile(`^logged$`)), RedactTables("secrets")},
+		Masking:    masking,
+	}
+	assert.Error(t, UseStatementLog(uow.db, &StatementLog{}))
+	require.NoError(t, UseStatementLog(uow.db, log))
+
+	_, err := uow.Insert(ctx, &TestUser{Name: "Logged", Email: "logged@example.com", Slug: "logged"})
+	require.NoError(t, err)
+	require.NotEmpty(t, logged)
+	insert := logged[len(logged)-1]
+	assert.Contains(t, insert.SQL, "INSERT INTO")
+	assert.Equal(t, "test_users", insert.Table)
+	assert.Equal(t, "acme", insert.Tenant)
+	assert.Equal(t, "alice", insert.User)
+	assert.Contains(t, insert.Args, "Logged")
+	assert.Contains(t, insert.Args, DefaultMask)
+	assert.NotContains(t, insert.Args, "logged")
+	// PII columns are masked even though no Redact rule matches them
+	assert.NotContains(t, insert.Args, "logged@example.com")
+
+	// Only a sample is logged, but failures always are
+	log.SampleRate = 0.25
+	draws := []float64{0.1, 0.5, 0.9, 0.2}
+	log.random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+	logged = nil
+	for range 4 {
+		_, err := uow.FindAll(ctx)
+		require.NoError(t, err)
+	}
+	assert.Len(t, logged, 2)
+
+	log.LogErrors = true
+	log.random = func() float64 { return 1 }
+	logged = nil
+	require.Error(t, uow.db.WithContext(ctx).Exec("SELECT * FROM missing_table").Error)
+	require.Len(t, logged, 1)
+	assert.Contains(t, logged[0].Error, "missing_table")
+
+	// A zero rate logs only failures
+	log.SampleRate = 0
+	logged = nil
+	_, err = uow.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, logged)
+	require.Error(t, uow.db.WithContext(ctx).Exec("SELECT * FROM missing_table").Error)
+	assert.Len(t, logged, 1)
+
+	// Without Arguments only placeholders are logged
+	log.Arguments, log.SampleRate = false, 1
+	logged = nil
+	_, err = uow.FindOneById(ctx, 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, logged)
+	assert.Nil(t, logged[0].Args)
+
+	var buf bytes.Buffer
+	NewJSONStatementSink(&buf).LogStatement(ctx, LoggedStatement{SQL: "SELECT 1", Rows: 1})
+	var decoded LoggedStatement
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "SELECT 1", decoded.SQL)
+}
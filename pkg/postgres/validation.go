@@ -0,0 +1,230 @@
+package postgres
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// ValidationRule checks one field value against a tag parameter, e.g. "100" in max=100
+// The value is never a nil pointer; pointers are dereferenced before rules run
+type ValidationRule func(value reflect.Value, param string) bool
+
+var (
+	rulesMu         sync.RWMutex
+	validationRules = map[string]ValidationRule{
+		"email": func(v reflect.Value, _ string) bool {
+			address, err := mail.ParseAddress(v.String())
+			return err == nil && address.Address == v.String()
+		},
+		"url": func(v reflect.Value, _ string) bool {
+			u, err := url.Parse(v.String())
+			return err == nil && u.Scheme != "" && u.Host != ""
+		},
+		"uuid": func(v reflect.Value, _ string) bool {
+			return uuidPattern.MatchString(v.String())
+		},
+		"min": func(v reflect.Value, param string) bool {
+			size, limit, ok := measure(v, param)
+			return ok && size >= limit
+		},
+		"max": func(v reflect.Value, param string) bool {
+			size, limit, ok := measure(v, param)
+			return ok && size <= limit
+		},
+		"len": func(v reflect.Value, param string) bool {
+			size, limit, ok := measure(v, param)
+			return ok && size == limit
+		},
+		"oneof": func(v reflect.Value, param string) bool {
+			value := fmt.Sprint(v.Interface())
+			for _, allowed := range strings.Fields(param) {
+				if value == allowed {
+					return true
+				}
+			}
+			return false
+		},
+	}
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// RegisterValidationRule makes a rule available to validate tags under a name, e.g. "slug" for
+// validate:"slug"; registering a built-in name replaces it
+func RegisterValidationRule(name string, rule ValidationRule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	validationRules[name] = rule
+}
+
+func validationRule(name string) (ValidationRule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	rule, ok := validationRules[name]
+	return rule, ok
+}
+
+// measure returns the size a min/max/len rule compares: characters of strings, elements of
+// slices and maps, or the number itself
+func measure(v reflect.Value, param string) (float64, float64, bool) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), limit, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), limit, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), limit, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), limit, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), limit, true
+	}
+	return 0, 0, false
+}
+
+// FieldViolation is one failed rule of one column
+type FieldViolation struct {
+	Column string
+	Rule   string // Rule name, e.g. "max"
+	Param  string // Rule parameter, e.g. "100"
+}
+
+func (v FieldViolation) String() string {
+	if v.Rule == "required" {
+		return v.Column + " is required"
+	}
+	if v.Param != "" {
+		return fmt.Sprintf("%s fails %s=%s", v.Column, v.Rule, v.Param)
+	}
+	return fmt.Sprintf("%s fails %s", v.Column, v.Rule)
+}
+
+// ValidationError lists every violated validate tag of an entity
+// It matches errors.ErrEntityValidation with errors.Is
+type ValidationError struct {
+	Entity     string
+	Violations []FieldViolation
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
+	}
+	return fmt.Sprintf("%v: %s: %s", uowerrors.ErrEntityValidation, e.Entity, strings.Join(messages, "; "))
+}
+
+// Is matches errors.ErrEntityValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == uowerrors.ErrEntityValidation
+}
+
+// fieldRules are the parsed validate tag of one column
+type fieldRules struct {
+	field    fieldMetadata
+	required bool
+	checks   []fieldCheck
+}
+
+type fieldCheck struct {
+	name, param string
+	rule        ValidationRule
+}
+
+var validationCache sync.Map // *modelMetadata -> validationPlan
+
+type validationPlan struct {
+	fields []fieldRules
+	err    error // Unknown rule in a tag
+}
+
+// validationPlanOf parses the validate tags of an entity type once
+func validationPlanOf(meta *modelMetadata) validationPlan {
+	if cached, ok := validationCache.Load(meta); ok {
+		return cached.(validationPlan)
+	}
+	var plan validationPlan
+	for _, field := range meta.Fields {
+		tag := meta.Type.FieldByIndex(field.Index).Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		parsed := fieldRules{field: field}
+		for _, part := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch name {
+			case "":
+				continue
+			case "required":
+				parsed.required = true
+				continue
+			}
+			rule, ok := validationRule(name)
+			if !ok {
+				plan.err = fmt.Errorf("%w: unknown validation rule %q on %s.%s", uowerrors.ErrInvalidEntity, name, meta.Type.Name(), field.Name)
+				break
+			}
+			parsed.checks = append(parsed.checks, fieldCheck{name: name, param: param, rule: rule})
+		}
+		plan.fields = append(plan.fields, parsed)
+	}
+	actual, _ := validationCache.LoadOrStore(meta, plan)
+	return actual.(validationPlan)
+}
+
+// validate checks entities against their validate tags, e.g. validate:"required,email,max=100"
+// Zero values pass every rule but required. Partial checks skip zero fields entirely, for updates
+// that leave them unwritten. Entities holding PII masked on read are rejected
+func (uow *UnitOfWork[T]) validate(partial bool, entities ...T) error {
+	if err := uow.rejectMasked(entities...); err != nil {
+		return err
+	}
+	meta := uow.metadata()
+	plan := validationPlanOf(meta)
+	if plan.err != nil {
+		return plan.err
+	}
+	if len(plan.fields) == 0 {
+		return nil
+	}
+	for _, entity := range entities {
+		v, ok := meta.structValue(entity)
+		if !ok {
+			continue
+		}
+		var violations []FieldViolation
+		for _, rules := range plan.fields {
+			value := v.FieldByIndex(rules.field.Index)
+			if value.IsZero() {
+				if rules.required && !partial {
+					violations = append(violations, FieldViolation{Column: rules.field.Column, Rule: "required"})
+				}
+				continue
+			}
+			value = reflect.Indirect(value)
+			for _, check := range rules.checks {
+				if !check.rule(value, check.param) {
+					violations = append(violations, FieldViolation{Column: rules.field.Column, Rule: check.name, Param: check.param})
+				}
+			}
+		}
+		if len(violations) > 0 {
+			return &ValidationError{Entity: meta.Table, Violations: violations}
+		}
+	}
+	return nil
+}
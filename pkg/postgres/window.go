@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+)
+
+// rankColumn holds the window function result in the ranked subquery
+const rankColumn = "window_rank"
+
+// FindTopPerGroup returns the first N live entities of every group matching the identifier
+// Results are ordered by group, then by rank within the group
+func (uow *UnitOfWork[T]) FindTopPerGroup(ctx context.Context, identifier identifier.IIdentifier, params domain.TopNParams[T]) ([]T, error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", uowerrors.ErrInvalidQueryParams, err)
+	}
+
+	meta := uow.metadata()
+	partition := make([]string, len(params.PartitionBy))
+	outerOrder := make([]clause.OrderByColumn, 0, len(params.PartitionBy)+1)
+	for i, field := range params.PartitionBy {
+		column, ok := meta.Field(field)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown partition field %q", uowerrors.ErrInvalidQueryParams, field)
+		}
+		partition[i] = column.Qualified
+		outerOrder = append(outerOrder, clause.OrderByColumn{Column: clause.Column{Name: column.Column}})
+	}
+	outerOrder = append(outerOrder, clause.OrderByColumn{Column: clause.Column{Name: rankColumn}})
+
+	order := make([]string, 0, len(params.OrderBy)+1)
+	for _, term := range params.OrderBy {
+		column, ok := meta.Field(term.Field)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown order field %q", uowerrors.ErrInvalidQueryParams, term.Field)
+		}
+		direction := "ASC"
+		if term.Direction == domain.SortDesc {
+			direction = "DESC"
+		}
+		order = append(order, column.Qualified+" "+direction)
+	}
+	if primaryKey, ok := meta.primaryKey(); ok {
+		order = append(order, primaryKey.Qualified)
+	}
+
+	over := "ORDER BY " + strings.Join(order, ", ")
+	if len(partition) > 0 {
+		over = "PARTITION BY " + strings.Join(partition, ", ") + " " + over
+	}
+	window := fmt.Sprintf("%s() OVER (%s) AS %s", strings.ToUpper(string(params.Function)), over, rankColumn)
+
+	ranked := applyEntityIdentifier[T](uow.getActiveDB(ctx).Model(new(T)), identifier).Select("*, " + window)
+	if conditions, args := meta.filterConditions(params.Filter, false); conditions != "" {
+		ranked = ranked.Where(conditions, args...)
+	}
+
+	// The subquery already applied soft-delete and default scopes; the outer query only filters by rank
+	var entities []T
+	err := ranked.Session(&gorm.Session{NewDB: true}).Unscoped().
+		Table("(?) AS ranked", ranked).
+		Where(rankColumn+" <= ?", params.N).
+		Order(clause.OrderBy{Columns: outerOrder}).
+		Find(&entities).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find top entities per group: %w", err)
+	}
+
+	uow.maskResults(ctx, entities...)
+	return entities, nil
+}
@@ -0,0 +1,222 @@
+// Package projection keeps denormalized read model tables up to date from source entities
+//
+// A projection maps every source entity to at most one read model row, keyed by the source's ID.
+// Follow refreshes the rows from a factory's committed changes, Apply accepts changes from any
+// other feed (e.g. a CDC consumer), and Rebuild recomputes the whole table. Readers query the
+// read model through a read-only unit of work:
+//
+//	type UserSummary struct {
+//		ID        int `gorm:"primaryKey;autoIncrement:false"`
+//		Name      string
+//		Orders    int
+//		CreatedAt time.Time
+//		UpdatedAt time.Time
+//		DeletedAt gorm.DeletedAt
+//	}
+//
+//	summaries := projection.New(summaryFactory, func(ctx context.Context, user *User) (*UserSummary, bool, error) {
+//		orders, err := countOrders(ctx, user.ID)
+//		return &UserSummary{ID: user.ID, Name: user.Name, Orders: orders}, true, err
+//	}, projection.Options{OnError: logProjectionError})
+//	summaries.Follow(userFactory)
+//	rows, total, err := summaries.ReadOnly(ctx).FindAllWithPagination(ctx, query)
+package projection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// DefaultBatchSize is the number of source entities projected per transaction during a rebuild
+const DefaultBatchSize = 500
+
+// Func computes the read model row of a source entity; ok false means the entity has no row
+// The row's primary key must be the source's ID
+type Func[S, R domain.BaseModel] func(ctx context.Context, source S) (row R, ok bool, err error)
+
+// Options configures a projection
+type Options struct {
+	BatchSize int                                  // Source entities per transaction during rebuild, default 500
+	OnError   func(ctx context.Context, err error) // Receives refresh failures after commit; they cannot fail the source transaction
+}
+
+func (o Options) batchSize() int {
+	if o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+// Projection maintains the read model R of the source entities S
+type Projection[S, R domain.BaseModel] struct {
+	target  persistence.IUnitOfWorkFactory[R]
+	project Func[S, R]
+	options Options
+}
+
+// New creates a projection writing read model rows through the target factory
+func New[S, R domain.BaseModel](target persistence.IUnitOfWorkFactory[R], project Func[S, R], options Options) *Projection[S, R] {
+	return &Projection[S, R]{target: target, project: project, options: options}
+}
+
+// Follow refreshes the read model after every commit of the source factory's units of work
+func (p *Projection[S, R]) Follow(source *postgres.UnitOfWorkFactory[S]) {
+	source.OnChange(p.Listener())
+}
+
+// Listener adapts the projection to a change listener, for wiring without a factory
+func (p *Projection[S, R]) Listener() postgres.ChangeListener[S] {
+	return func(ctx context.Context, changes []domain.Change[S]) {
+		if err := p.Apply(ctx, changes); err != nil && p.options.OnError != nil {
+			p.options.OnError(ctx, err)
+		}
+	}
+}
+
+// Apply refreshes the rows of changed source entities in one transaction
+// Created and updated entities are projected again; deleted ones lose their row. When an entity
+// changes several times, only its last change counts
+func (p *Projection[S, R]) Apply(ctx context.Context, changes []domain.Change[S]) error {
+	last := make(map[int]domain.Change[S], len(changes))
+	order := make([]int, 0, len(changes))
+	for _, change := range changes {
+		id := change.Entity.GetID()
+		if _, seen := last[id]; !seen {
+			order = append(order, id)
+		}
+		last[id] = change
+	}
+
+	var rows []R
+	var removed []interface{}
+	for _, id := range order {
+		change := last[id]
+		if change.Kind == domain.ChangeDeleted || change.Entity.GetArchivedAt().Valid {
+			removed = append(removed, id)
+			continue
+		}
+		row, ok, err := p.row(ctx, change.Entity)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			removed = append(removed, id)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return p.write(ctx, rows, removed)
+}
+
+// Rebuild projects every live source entity again, in keyset-ordered batches with one transaction
+// each, then removes rows whose source is gone. It returns the number of rows written
+func (p *Projection[S, R]) Rebuild(ctx context.Context, source persistence.IReadOnlyUnitOfWork[S]) (int64, error) {
+	var written int64
+	projected := make(map[int]struct{})
+	var after *domain.Cursor
+	for {
+		page, err := source.FindAllWithKeyset(ctx, domain.KeysetParams[S]{After: after, Limit: p.options.batchSize()})
+		if err != nil {
+			return written, err
+		}
+		var rows []R
+		for _, entity := range page.Items {
+			row, ok, err := p.row(ctx, entity)
+			if err != nil {
+				return written, err
+			}
+			if ok {
+				rows = append(rows, row)
+				projected[row.GetID()] = struct{}{}
+			}
+		}
+		if err := p.write(ctx, rows, nil); err != nil {
+			return written, err
+		}
+		written += int64(len(rows))
+		if len(page.Items) > 0 {
+			after = &page.Cursors[len(page.Cursors)-1]
+		}
+		if !page.HasNext {
+			break
+		}
+	}
+
+	// Remove rows left from deleted sources or sources that no longer project
+	reader := p.target.CreateReadOnly(ctx)
+	after = nil
+	for {
+		page, err := reader.FindAllWithKeyset(ctx, domain.KeysetParams[R]{After: after, Limit: p.options.batchSize()})
+		if err != nil {
+			return written, err
+		}
+		var stale []interface{}
+		for _, row := range page.Items {
+			if _, ok := projected[row.GetID()]; !ok {
+				stale = append(stale, row.GetID())
+			}
+		}
+		if err := p.write(ctx, nil, stale); err != nil {
+			return written, err
+		}
+		if len(page.Items) > 0 {
+			after = &page.Cursors[len(page.Cursors)-1]
+		}
+		if !page.HasNext {
+			return written, nil
+		}
+	}
+}
+
+// ReadOnly opens a query-only unit of work over the read model
+func (p *Projection[S, R]) ReadOnly(ctx context.Context) persistence.IReadOnlyUnitOfWork[R] {
+	return p.target.CreateReadOnly(ctx)
+}
+
+func (p *Projection[S, R]) row(ctx context.Context, source S) (R, bool, error) {
+	row, ok, err := p.project(ctx, source)
+	if err != nil {
+		return row, false, fmt.Errorf("projection of %d: %w", source.GetID(), err)
+	}
+	if ok && row.GetID() != source.GetID() {
+		return row, false, fmt.Errorf("projection of %d: row has ID %d, want the source's ID", source.GetID(), row.GetID())
+	}
+	return row, ok, nil
+}
+
+// write upserts rows and hard-deletes removed IDs in one transaction
+func (p *Projection[S, R]) write(ctx context.Context, rows []R, removed []interface{}) (err error) {
+	if len(rows) == 0 && len(removed) == 0 {
+		return nil
+	}
+	uow := p.target.CreateWithContext(ctx)
+	defer uow.Close()
+
+	if err := uow.BeginTransaction(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			uow.RollbackTransaction(ctx)
+			return
+		}
+		err = uow.CommitTransaction(ctx)
+	}()
+
+	if len(rows) > 0 {
+		if _, _, err := uow.BulkUpsert(ctx, rows); err != nil {
+			return fmt.Errorf("projection upsert: %w", err)
+		}
+	}
+	if len(removed) > 0 {
+		if err := uow.BulkHardDelete(ctx, []identifier.IIdentifier{identifier.New().In("id", removed)}); err != nil {
+			return fmt.Errorf("projection delete: %w", err)
+		}
+	}
+	return nil
+}
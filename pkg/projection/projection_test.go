@@ -0,0 +1,200 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+type customer struct {
+	ID        int
+	Name      string
+	Orders    []int
+	Hidden    bool
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (c *customer) GetID() int                    { return c.ID }
+func (c *customer) GetSlug() string               { return "" }
+func (c *customer) SetSlug(string)                {}
+func (c *customer) GetCreatedAt() time.Time       { return c.CreatedAt }
+func (c *customer) GetUpdatedAt() time.Time       { return c.CreatedAt }
+func (c *customer) GetArchivedAt() gorm.DeletedAt { return c.DeletedAt }
+func (c *customer) GetName() string               { return c.Name }
+
+type summary struct {
+	ID        int
+	Name      string
+	Orders    int
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (s *summary) GetID() int                    { return s.ID }
+func (s *summary) GetSlug() string               { return "" }
+func (s *summary) SetSlug(string)                {}
+func (s *summary) GetCreatedAt() time.Time       { return s.CreatedAt }
+func (s *summary) GetUpdatedAt() time.Time       { return s.CreatedAt }
+func (s *summary) GetArchivedAt() gorm.DeletedAt { return s.DeletedAt }
+func (s *summary) GetName() string               { return s.Name }
+
+// keyedUnitOfWork is an in-memory table served in ID order; unused methods panic through the nil embedded interface
+type keyedUnitOfWork[T domain.BaseModel] struct {
+	persistence.IUnitOfWork[T]
+	table *table[T]
+	fail  error
+}
+
+type table[T domain.BaseModel] struct {
+	rows    map[int]T
+	commits int
+}
+
+func (u *keyedUnitOfWork[T]) BeginTransaction(context.Context) error  { return nil }
+func (u *keyedUnitOfWork[T]) CommitTransaction(context.Context) error { u.table.commits++; return nil }
+func (u *keyedUnitOfWork[T]) RollbackTransaction(context.Context)     {}
+func (u *keyedUnitOfWork[T]) Close() error                            { return nil }
+
+func (u *keyedUnitOfWork[T]) FindAllWithKeyset(_ context.Context, params domain.KeysetParams[T]) (domain.KeysetPage[T], error) {
+	ids := make([]int, 0, len(u.table.rows))
+	for id := range u.table.rows {
+		if params.After == nil || id > params.After.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	page := domain.KeysetPage[T]{HasNext: len(ids) > params.Limit}
+	if page.HasNext {
+		ids = ids[:params.Limit]
+	}
+	for _, id := range ids {
+		page.Items = append(page.Items, u.table.rows[id])
+		page.Cursors = append(page.Cursors, domain.Cursor{ID: id})
+	}
+	return page, nil
+}
+
+func (u *keyedUnitOfWork[T]) BulkUpsert(_ context.Context, entities []T, _ ...string) ([]T, domain.UpsertResult, error) {
+	var result domain.UpsertResult
+	if u.fail != nil {
+		return nil, result, u.fail
+	}
+	for _, entity := range entities {
+		if _, ok := u.table.rows[entity.GetID()]; ok {
+			result.Updated++
+		} else {
+			result.Inserted++
+		}
+		u.table.rows[entity.GetID()] = entity
+	}
+	return entities, result, nil
+}
+
+func (u *keyedUnitOfWork[T]) BulkHardDelete(_ context.Context, ids []identifier.IIdentifier) error {
+	values, _ := ids[0].Get("id IN")
+	for _, id := range values.([]interface{}) {
+		delete(u.table.rows, id.(int))
+	}
+	return nil
+}
+
+type keyedFactory[T domain.BaseModel] struct {
+	table *table[T]
+	fail  error
+}
+
+func (f *keyedFactory[T]) Create() persistence.IUnitOfWork[T] {
+	return &keyedUnitOfWork[T]{table: f.table, fail: f.fail}
+}
+
+func (f *keyedFactory[T]) CreateWithContext(context.Context) persistence.IUnitOfWork[T] {
+	return f.Create()
+}
+
+func (f *keyedFactory[T]) CreateReadOnly(context.Context) persistence.IReadOnlyUnitOfWork[T] {
+	return f.Create()
+}
+
+func summarize(_ context.Context, c *customer) (*summary, bool, error) {
+	if c.Name == "broken" {
+		return nil, false, errors.New("no orders service")
+	}
+	return &summary{ID: c.ID, Name: c.Name, Orders: len(c.Orders)}, !c.Hidden, nil
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	read := &table[*summary]{rows: map[int]*summary{3: {ID: 3, Name: "Old"}, 4: {ID: 4, Name: "Hidden"}}}
+	summaries := New[*customer, *summary](&keyedFactory[*summary]{table: read}, summarize, Options{})
+
+	err := summaries.Apply(ctx, []domain.Change[*customer]{
+		{Kind: domain.ChangeCreated, Entity: &customer{ID: 1, Name: "Ada", Orders: []int{7}}},
+		{Kind: domain.ChangeCreated, Entity: &customer{ID: 2, Name: "Bob"}},
+		{Kind: domain.ChangeUpdated, Entity: &customer{ID: 1, Name: "Ada", Orders: []int{7, 8}}},
+		{Kind: domain.ChangeDeleted, Entity: &customer{ID: 3, Name: "Old"}},
+		{Kind: domain.ChangeUpdated, Entity: &customer{ID: 4, Name: "Hidden", Hidden: true}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, read.commits, "one transaction per batch of changes")
+	assert.Equal(t, map[int]*summary{
+		1: {ID: 1, Name: "Ada", Orders: 2},
+		2: {ID: 2, Name: "Bob"},
+	}, read.rows)
+
+	rows, err := summaries.ReadOnly(ctx).FindAllWithKeyset(ctx, domain.KeysetParams[*summary]{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, rows.Items, 2)
+
+	// Refresh failures after commit go to OnError
+	var reported []error
+	failing := New[*customer, *summary](&keyedFactory[*summary]{table: read}, summarize, Options{
+		OnError: func(_ context.Context, err error) { reported = append(reported, err) },
+	})
+	failing.Listener()(ctx, []domain.Change[*customer]{{Kind: domain.ChangeUpdated, Entity: &customer{ID: 1, Name: "broken"}}})
+	require.Len(t, reported, 1)
+	assert.Contains(t, reported[0].Error(), "projection of 1")
+
+	wrongKey := New[*customer, *summary](&keyedFactory[*summary]{table: read}, func(context.Context, *customer) (*summary, bool, error) {
+		return &summary{ID: 99}, true, nil
+	}, Options{})
+	assert.Error(t, wrongKey.Apply(ctx, []domain.Change[*customer]{{Kind: domain.ChangeCreated, Entity: &customer{ID: 1}}}))
+}
+
+func TestRebuild(t *testing.T) {
+	ctx := context.Background()
+	source := &table[*customer]{rows: map[int]*customer{
+		1: {ID: 1, Name: "Ada", Orders: []int{1, 2}},
+		2: {ID: 2, Name: "Bob"},
+		3: {ID: 3, Name: "Cy", Hidden: true},
+		4: {ID: 4, Name: "Dee", Orders: []int{5}},
+	}}
+	read := &table[*summary]{rows: map[int]*summary{
+		3: {ID: 3, Name: "Cy"},
+		9: {ID: 9, Name: "Gone"},
+	}}
+	summaries := New[*customer, *summary](&keyedFactory[*summary]{table: read}, summarize, Options{BatchSize: 2})
+
+	written, err := summaries.Rebuild(ctx, &keyedUnitOfWork[*customer]{table: source})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), written)
+	assert.Equal(t, map[int]*summary{
+		1: {ID: 1, Name: "Ada", Orders: 2},
+		2: {ID: 2, Name: "Bob"},
+		4: {ID: 4, Name: "Dee", Orders: 1},
+	}, read.rows, "rows of hidden and missing sources are removed")
+
+	broken := New[*customer, *summary](&keyedFactory[*summary]{table: read, fail: errors.New("read only")}, summarize, Options{})
+	_, err = broken.Rebuild(ctx, &keyedUnitOfWork[*customer]{table: source})
+	assert.ErrorContains(t, err, "projection upsert")
+}
@@ -0,0 +1,180 @@
+// Package recyclebin purges soft-deleted rows once they have been in the trash longer than a retention period
+//
+// A Scheduler runs the policies periodically. Every instance of a service may run one: a
+// PostgreSQL advisory lock elects a single leader per round, so rows are purged once.
+//
+//	scheduler := recyclebin.NewScheduler(recyclebin.AdvisoryLock(db, 7301), recyclebin.Options{Interval: time.Hour, Metrics: metrics},
+//		recyclebin.PurgePolicy[*User]("users", 30*24*time.Hour, userFactory),
+//		recyclebin.PurgePolicy[*Post]("posts", 90*24*time.Hour, postFactory),
+//	)
+//	go scheduler.Run(ctx)
+package recyclebin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// DefaultInterval is how often Run purges when Options.Interval is not set
+const DefaultInterval = time.Hour
+
+// Policy purges the trashed rows of one entity type deleted before a cutoff
+type Policy struct {
+	Name   string        // Entity label used in reports and metrics
+	Retain time.Duration // How long rows stay in the trash
+	Purge  func(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// PurgePolicy builds a policy purging through a unit of work from the factory
+func PurgePolicy[T domain.BaseModel](name string, retain time.Duration, factory persistence.IUnitOfWorkFactory[T]) Policy {
+	return Policy{
+		Name:   name,
+		Retain: retain,
+		Purge: func(ctx context.Context, deletedBefore time.Time) (int64, error) {
+			uow := factory.CreateWithContext(ctx)
+			defer uow.Close()
+			return uow.PurgeTrashed(ctx, identifier.New().LessThan("deleted_at", deletedBefore))
+		},
+	}
+}
+
+// Locker elects the instance allowed to purge in a round
+type Locker interface {
+	// TryLock acquires the lock without waiting; ok is false when another instance holds it
+	TryLock(ctx context.Context) (unlock func(), ok bool, err error)
+}
+
+// Options configures a scheduler
+type Options struct {
+	Interval time.Duration                        // Time between rounds, default 1 hour
+	Metrics  postgres.Metrics                     // Receives recyclebin_purged_rows and recyclebin_purge_errors per entity
+	OnError  func(ctx context.Context, err error) // Receives round failures from Run
+	Now      func() time.Time                     // Clock, default time.Now
+}
+
+// Report is the outcome of one round
+type Report struct {
+	Leader bool             // False when another instance held the lock and nothing was purged
+	Purged map[string]int64 // Rows purged per policy name
+}
+
+// Scheduler runs retention policies periodically
+type Scheduler struct {
+	locker   Locker
+	options  Options
+	policies []Policy
+}
+
+// NewScheduler creates a scheduler for the policies
+func NewScheduler(locker Locker, options Options, policies ...Policy) *Scheduler {
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return &Scheduler{locker: locker, options: options, policies: policies}
+}
+
+// Run purges immediately and then every interval until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && s.options.OnError != nil {
+			s.options.OnError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce runs every policy if this instance wins the lock
+// A failing policy does not stop the others; their errors are joined
+func (s *Scheduler) RunOnce(ctx context.Context) (Report, error) {
+	report := Report{Purged: make(map[string]int64)}
+
+	unlock, ok, err := s.locker.TryLock(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to acquire purge lock: %w", err)
+	}
+	if !ok {
+		return report, nil
+	}
+	defer unlock()
+	report.Leader = true
+
+	var errs []error
+	now := s.options.Now()
+	for _, policy := range s.policies {
+		labels := map[string]string{"entity": policy.Name}
+		purged, err := policy.Purge(ctx, now.Add(-policy.Retain))
+		if err != nil {
+			s.count("recyclebin_purge_errors", 1, labels)
+			errs = append(errs, fmt.Errorf("purge %s: %w", policy.Name, err))
+			continue
+		}
+		report.Purged[policy.Name] = purged
+		s.count("recyclebin_purged_rows", purged, labels)
+	}
+	return report, errors.Join(errs...)
+}
+
+func (s *Scheduler) count(name string, delta int64, labels map[string]string) {
+	if s.options.Metrics != nil {
+		s.options.Metrics.IncCounter(name, delta, labels)
+	}
+}
+
+// advisoryLock is a session-level PostgreSQL advisory lock held on one pooled connection
+type advisoryLock struct {
+	db  *gorm.DB
+	key int64
+}
+
+// AdvisoryLock returns a Locker using pg_try_advisory_lock(key); pick a key unique to this job
+func AdvisoryLock(db *gorm.DB, key int64) Locker {
+	return &advisoryLock{db: db, key: key}
+}
+
+// TryLock implements Locker; the lock and unlock run on the same connection, as session locks require
+func (l *advisoryLock) TryLock(ctx context.Context) (func(), bool, error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// The round's context may be cancelled by now; unlocking must still happen
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", l.key)
+		conn.Close()
+	}
+	return unlock, true, nil
+}
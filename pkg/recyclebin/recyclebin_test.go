@@ -0,0 +1,106 @@
+package recyclebin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+type note struct {
+	ID        int
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (n *note) GetID() int                    { return n.ID }
+func (n *note) GetSlug() string               { return "" }
+func (n *note) SetSlug(string)                {}
+func (n *note) GetCreatedAt() time.Time       { return n.CreatedAt }
+func (n *note) GetUpdatedAt() time.Time       { return n.CreatedAt }
+func (n *note) GetArchivedAt() gorm.DeletedAt { return n.DeletedAt }
+func (n *note) GetName() string               { return "" }
+
+// purgingUnitOfWork records PurgeTrashed calls; unused methods panic through the nil embedded interface
+type purgingUnitOfWork struct {
+	persistence.IUnitOfWork[*note]
+	calls *[]string
+}
+
+func (p *purgingUnitOfWork) PurgeTrashed(_ context.Context, id identifier.IIdentifier) (int64, error) {
+	sql, _ := id.ToSQL()
+	*p.calls = append(*p.calls, sql)
+	return 3, nil
+}
+
+func (p *purgingUnitOfWork) Close() error { return nil }
+
+type noteFactory struct{ calls []string }
+
+func (f *noteFactory) Create() persistence.IUnitOfWork[*note] {
+	return &purgingUnitOfWork{calls: &f.calls}
+}
+
+func (f *noteFactory) CreateWithContext(context.Context) persistence.IUnitOfWork[*note] {
+	return f.Create()
+}
+
+func (f *noteFactory) CreateReadOnly(context.Context) persistence.IReadOnlyUnitOfWork[*note] {
+	return f.Create()
+}
+
+type fakeLocker struct{ held, unlocked bool }
+
+func (l *fakeLocker) TryLock(context.Context) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	return func() { l.unlocked = true }, true, nil
+}
+
+type recordingMetrics map[string]int64
+
+func (m recordingMetrics) IncCounter(name string, delta int64, labels map[string]string) {
+	m[name+"/"+labels["entity"]] += delta
+}
+
+func TestScheduler_RunOnce(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	factory := &noteFactory{}
+	var cutoff time.Time
+	metrics := recordingMetrics{}
+	locker := &fakeLocker{}
+
+	scheduler := NewScheduler(locker, Options{Metrics: metrics, Now: func() time.Time { return now }},
+		PurgePolicy[*note]("notes", 24*time.Hour, factory),
+		Policy{Name: "drafts", Retain: time.Hour, Purge: func(_ context.Context, before time.Time) (int64, error) {
+			cutoff = before
+			return 0, errors.New("unavailable")
+		}},
+	)
+
+	report, err := scheduler.RunOnce(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "purge drafts")
+	assert.True(t, report.Leader)
+	assert.Equal(t, map[string]int64{"notes": 3}, report.Purged)
+	assert.Len(t, factory.calls, 1)
+	assert.Contains(t, factory.calls[0], "deleted_at")
+	assert.Equal(t, now.Add(-time.Hour), cutoff)
+	assert.Equal(t, recordingMetrics{"recyclebin_purged_rows/notes": 3, "recyclebin_purge_errors/drafts": 1}, metrics)
+	assert.True(t, locker.unlocked)
+
+	// Another instance holds the lock: nothing runs
+	locker.held = true
+	report, err = scheduler.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Leader)
+	assert.Len(t, factory.calls, 1)
+}
@@ -0,0 +1,337 @@
+// Package retention enforces declarative data retention policies per entity type
+//
+// A policy names what happens to rows once a period has passed since a timestamp column:
+// hard delete, soft delete, or anonymize selected columns. A Scheduler runs the registered
+// policies periodically through units of work, in batched transactions, and reports every
+// application as an audit entry. Like the recycle bin, it runs on one leader at a time.
+//
+//	registry := retention.NewRegistry()
+//	registry.Register(
+//		retention.HardDelete[*AuditEvent]("audit-events", retention.Rule{Column: "created_at", After: 2 * 365 * 24 * time.Hour}, auditFactory),
+//		retention.Anonymize[*User]("closed-accounts", retention.Rule{
+//			Column: "closed_at", After: 30 * 24 * time.Hour,
+//			Where:  map[string]interface{}{"anonymized_at IS NULL": true},
+//			Mark:   "anonymized_at",
+//		}, userFactory, map[string]anonymize.Strategy{"email": anonymize.Fake(anonymize.FakeEmail), "phone": anonymize.Null()}),
+//	)
+//	scheduler := retention.NewScheduler(recyclebin.AdvisoryLock(db, 7302), retention.Options{Interval: time.Hour, Audit: sink}, registry)
+//	go scheduler.Run(ctx)
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/anonymize"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/recyclebin"
+)
+
+const (
+	// DefaultInterval is how often Run applies the policies when Options.Interval is not set
+	DefaultInterval = time.Hour
+	// DefaultBatchSize is how many rows one transaction handles when Rule.BatchSize is not set
+	DefaultBatchSize = 500
+)
+
+// Action is what a policy does with expired rows
+type Action string
+
+const (
+	ActionHardDelete Action = "hard_delete"
+	ActionSoftDelete Action = "soft_delete"
+	ActionAnonymize  Action = "anonymize"
+)
+
+// Rule selects the expired rows of an entity type
+type Rule struct {
+	Column    string                 // Timestamp column the period counts from, e.g. "created_at" or "closed_at"
+	After     time.Duration          // Rows expire once Column is older than this
+	Where     map[string]interface{} // Extra identifier conditions, e.g. {"anonymized_at IS NULL": true}
+	Mark      string                 // Anonymize only: timestamp column set to now on anonymized rows
+	BatchSize int                    // Rows per transaction, default 500
+}
+
+// Result is what one application of a policy changed
+type Result struct {
+	Rows int64
+	IDs  []int // Primary keys of the changed rows
+}
+
+// Policy applies one retention rule to one entity type
+type Policy struct {
+	Name   string // Unique label used in audit entries and metrics
+	Action Action
+	Column string
+	After  time.Duration
+	Apply  func(ctx context.Context, cutoff time.Time) (Result, error)
+}
+
+// HardDelete builds a policy hard-deleting expired rows
+func HardDelete[T domain.BaseModel](name string, rule Rule, factory persistence.IUnitOfWorkFactory[T]) Policy {
+	return newPolicy(name, ActionHardDelete, rule, factory, func(ctx context.Context, uow persistence.IUnitOfWork[T], batch []T, ids []interface{}) error {
+		return uow.BulkHardDelete(ctx, []identifier.IIdentifier{identifier.New().In("id", ids)})
+	})
+}
+
+// SoftDelete builds a policy moving expired rows to the trash, where the recycle bin purges them later
+func SoftDelete[T domain.BaseModel](name string, rule Rule, factory persistence.IUnitOfWorkFactory[T]) Policy {
+	return newPolicy(name, ActionSoftDelete, rule, factory, func(ctx context.Context, uow persistence.IUnitOfWork[T], batch []T, ids []interface{}) error {
+		return uow.BulkSoftDelete(ctx, []identifier.IIdentifier{identifier.New().In("id", ids)})
+	})
+}
+
+// Anonymize builds a policy rewriting columns of expired rows with anonymization strategies
+// Exclude rows already anonymized through Rule.Where, usually with a Rule.Mark column
+func Anonymize[T domain.BaseModel](name string, rule Rule, factory persistence.IUnitOfWorkFactory[T], columns map[string]anonymize.Strategy) Policy {
+	var namer schema.Namer = schema.NamingStrategy{}
+	if named, ok := factory.(interface{ NamingStrategy() schema.Namer }); ok {
+		namer = named.NamingStrategy()
+	}
+	s, parseErr := schema.Parse(new(T), &sync.Map{}, namer)
+	return newPolicy(name, ActionAnonymize, rule, factory, func(ctx context.Context, uow persistence.IUnitOfWork[T], batch []T, ids []interface{}) error {
+		if parseErr != nil {
+			return parseErr
+		}
+		now := time.Now()
+		for _, entity := range batch {
+			v := reflect.Indirect(reflect.ValueOf(entity))
+			updates := make(map[string]interface{}, len(columns)+1)
+			for column, strategy := range columns {
+				field := s.LookUpField(column)
+				if field == nil {
+					return fmt.Errorf("unknown column %q", column)
+				}
+				current, zero := field.ValueOf(ctx, v)
+				if zero && field.FieldType.Kind() == reflect.Ptr {
+					current = nil
+				}
+				replacement, err := strategy.Anonymize(field.DBName, entity.GetID(), current)
+				if err != nil {
+					return fmt.Errorf("anonymize %s of %d: %w", column, entity.GetID(), err)
+				}
+				updates[field.DBName] = replacement
+			}
+			if rule.Mark != "" {
+				updates[rule.Mark] = now
+			}
+			if _, err := uow.UpdateWhere(ctx, identifier.New().Equal("id", entity.GetID()), updates); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// newPolicy applies an action to the expired rows batch by batch, one transaction per batch, in ID order
+func newPolicy[T domain.BaseModel](name string, action Action, rule Rule, factory persistence.IUnitOfWorkFactory[T],
+	apply func(ctx context.Context, uow persistence.IUnitOfWork[T], batch []T, ids []interface{}) error) Policy {
+	batchSize := rule.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return Policy{
+		Name:   name,
+		Action: action,
+		Column: rule.Column,
+		After:  rule.After,
+		Apply: func(ctx context.Context, cutoff time.Time) (Result, error) {
+			var result Result
+			uow := factory.CreateWithContext(ctx)
+			defer uow.Close()
+
+			lastID := 0
+			for {
+				expired := identifier.New().LessThan(rule.Column, cutoff).GreaterThan("id", lastID)
+				for key, value := range rule.Where {
+					expired.Add(key, value)
+				}
+				if err := uow.BeginTransaction(ctx); err != nil {
+					return result, err
+				}
+				batch, _, err := uow.FindAllByIdentifier(ctx, expired, domain.QueryParams[T]{
+					Sort:  domain.SortMap{"id": domain.SortAsc},
+					Limit: batchSize,
+				})
+				if err != nil {
+					uow.RollbackTransaction(ctx)
+					return result, err
+				}
+				if len(batch) == 0 {
+					uow.RollbackTransaction(ctx)
+					return result, nil
+				}
+
+				ids := make([]interface{}, len(batch))
+				for i, entity := range batch {
+					ids[i] = entity.GetID()
+				}
+				if err := apply(ctx, uow, batch, ids); err != nil {
+					uow.RollbackTransaction(ctx)
+					return result, err
+				}
+				if err := uow.CommitTransaction(ctx); err != nil {
+					return result, err
+				}
+
+				for _, entity := range batch {
+					result.IDs = append(result.IDs, entity.GetID())
+				}
+				result.Rows += int64(len(batch))
+				lastID = batch[len(batch)-1].GetID()
+				if len(batch) < batchSize {
+					return result, nil
+				}
+			}
+		},
+	}
+}
+
+// Registry collects an application's retention policies
+type Registry struct {
+	mu       sync.Mutex
+	policies []Policy
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds policies; names must be unique
+func (r *Registry) Register(policies ...Policy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, policy := range policies {
+		for _, existing := range r.policies {
+			if existing.Name == policy.Name {
+				return fmt.Errorf("retention policy %q is already registered", policy.Name)
+			}
+		}
+		r.policies = append(r.policies, policy)
+	}
+	return nil
+}
+
+// Policies returns the registered policies in registration order
+func (r *Registry) Policies() []Policy {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Policy(nil), r.policies...)
+}
+
+// AuditEntry records one application of a policy
+type AuditEntry struct {
+	Policy    string
+	Action    Action
+	Cutoff    time.Time // Rows with Column before this expired
+	StartedAt time.Time
+	Duration  time.Duration
+	Rows      int64
+	IDs       []int
+	Error     string // Empty on success; rows of batches committed before a failure are still listed
+}
+
+// Options configures a scheduler
+type Options struct {
+	Interval time.Duration                               // Time between rounds, default 1 hour
+	Metrics  postgres.Metrics                            // Receives retention_rows and retention_errors per policy
+	Audit    func(ctx context.Context, entry AuditEntry) // Receives every policy application, successful or not
+	OnError  func(ctx context.Context, err error)        // Receives round failures from Run
+	Now      func() time.Time                            // Clock, default time.Now
+}
+
+// Report is the outcome of one round
+type Report struct {
+	Leader  bool // False when another instance held the lock and nothing ran
+	Entries []AuditEntry
+}
+
+// Scheduler runs the policies of a registry periodically
+type Scheduler struct {
+	locker   recyclebin.Locker
+	options  Options
+	registry *Registry
+}
+
+// NewScheduler creates a scheduler for the registry's policies
+// Policies registered later are picked up on the next round
+func NewScheduler(locker recyclebin.Locker, options Options, registry *Registry) *Scheduler {
+	if options.Interval <= 0 {
+		options.Interval = DefaultInterval
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return &Scheduler{locker: locker, options: options, registry: registry}
+}
+
+// Run applies the policies immediately and then every interval until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && s.options.OnError != nil {
+			s.options.OnError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies every policy if this instance wins the lock
+// A failing policy does not stop the others; their errors are joined
+func (s *Scheduler) RunOnce(ctx context.Context) (Report, error) {
+	var report Report
+
+	unlock, ok, err := s.locker.TryLock(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to acquire retention lock: %w", err)
+	}
+	if !ok {
+		return report, nil
+	}
+	defer unlock()
+	report.Leader = true
+
+	var errs []error
+	for _, policy := range s.registry.Policies() {
+		started := s.options.Now()
+		entry := AuditEntry{Policy: policy.Name, Action: policy.Action, Cutoff: started.Add(-policy.After), StartedAt: started}
+		result, err := policy.Apply(ctx, entry.Cutoff)
+		entry.Duration = s.options.Now().Sub(started)
+		entry.Rows, entry.IDs = result.Rows, result.IDs
+
+		labels := map[string]string{"policy": policy.Name, "action": string(policy.Action)}
+		if err != nil {
+			entry.Error = err.Error()
+			s.count("retention_errors", 1, labels)
+			errs = append(errs, fmt.Errorf("retention %s: %w", policy.Name, err))
+		}
+		s.count("retention_rows", result.Rows, labels)
+		if s.options.Audit != nil {
+			s.options.Audit(ctx, entry)
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	return report, errors.Join(errs...)
+}
+
+func (s *Scheduler) count(name string, delta int64, labels map[string]string) {
+	if s.options.Metrics != nil {
+		s.options.Metrics.IncCounter(name, delta, labels)
+	}
+}
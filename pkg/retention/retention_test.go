@@ -0,0 +1,197 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/anonymize"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+type account struct {
+	ID           int
+	Email        string
+	ClosedAt     *time.Time
+	AnonymizedAt *time.Time
+	CreatedAt    time.Time
+	DeletedAt    gorm.DeletedAt
+}
+
+func (a *account) GetID() int                    { return a.ID }
+func (a *account) GetSlug() string               { return "" }
+func (a *account) SetSlug(string)                {}
+func (a *account) GetCreatedAt() time.Time       { return a.CreatedAt }
+func (a *account) GetUpdatedAt() time.Time       { return a.CreatedAt }
+func (a *account) GetArchivedAt() gorm.DeletedAt { return a.DeletedAt }
+func (a *account) GetName() string               { return a.Email }
+
+// accountStore is an in-memory table behind fake units of work; unused methods panic through the nil embedded interface
+type accountStore struct {
+	rows    map[int]*account
+	commits int
+}
+
+type accountUnitOfWork struct {
+	persistence.IUnitOfWork[*account]
+	store *accountStore
+}
+
+func (u *accountUnitOfWork) BeginTransaction(context.Context) error  { return nil }
+func (u *accountUnitOfWork) CommitTransaction(context.Context) error { u.store.commits++; return nil }
+func (u *accountUnitOfWork) RollbackTransaction(context.Context)     {}
+func (u *accountUnitOfWork) Close() error                            { return nil }
+
+// FindAllByIdentifier understands the conditions the policies build
+func (u *accountUnitOfWork) FindAllByIdentifier(_ context.Context, id identifier.IIdentifier, query domain.QueryParams[*account]) ([]*account, uint, error) {
+	var matched []*account
+	for key := 1; key <= 10; key++ {
+		row, ok := u.store.rows[key]
+		if !ok || row.DeletedAt.Valid {
+			continue
+		}
+		if after, _ := id.Get("id >"); row.ID <= after.(int) {
+			continue
+		}
+		if before, ok := id.Get("created_at <"); ok && !row.CreatedAt.Before(before.(time.Time)) {
+			continue
+		}
+		if before, ok := id.Get("closed_at <"); ok && (row.ClosedAt == nil || !row.ClosedAt.Before(before.(time.Time))) {
+			continue
+		}
+		if id.Has("anonymized_at IS NULL") && row.AnonymizedAt != nil {
+			continue
+		}
+		matched = append(matched, row)
+	}
+	if len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+	return matched, uint(len(matched)), nil
+}
+
+func (u *accountUnitOfWork) BulkHardDelete(_ context.Context, ids []identifier.IIdentifier) error {
+	values, _ := ids[0].Get("id IN")
+	for _, id := range values.([]interface{}) {
+		delete(u.store.rows, id.(int))
+	}
+	return nil
+}
+
+func (u *accountUnitOfWork) UpdateWhere(_ context.Context, id identifier.IIdentifier, updates map[string]interface{}) (int64, error) {
+	key, _ := id.Get("id")
+	row := u.store.rows[key.(int)]
+	row.Email = updates["email"].(string)
+	marked := updates["anonymized_at"].(time.Time)
+	row.AnonymizedAt = &marked
+	return 1, nil
+}
+
+type accountFactory struct{ store *accountStore }
+
+func (f *accountFactory) Create() persistence.IUnitOfWork[*account] {
+	return &accountUnitOfWork{store: f.store}
+}
+
+func (f *accountFactory) CreateWithContext(context.Context) persistence.IUnitOfWork[*account] {
+	return f.Create()
+}
+
+func (f *accountFactory) CreateReadOnly(context.Context) persistence.IReadOnlyUnitOfWork[*account] {
+	return f.Create()
+}
+
+type fakeLocker struct{ held bool }
+
+func (l *fakeLocker) TryLock(context.Context) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	return func() {}, true, nil
+}
+
+type recordingMetrics map[string]int64
+
+func (m recordingMetrics) IncCounter(name string, delta int64, labels map[string]string) {
+	m[name+"/"+labels["policy"]] += delta
+}
+
+func TestSchedulerAppliesPolicies(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	closed := func(ago time.Duration) *time.Time {
+		at := now.Add(-ago)
+		return &at
+	}
+	store := &accountStore{rows: map[int]*account{
+		1: {ID: 1, Email: "old@corp.com", CreatedAt: now.Add(-800 * day)},
+		2: {ID: 2, Email: "closed@corp.com", CreatedAt: now.Add(-100 * day), ClosedAt: closed(40 * day)},
+		3: {ID: 3, Email: "recent@corp.com", CreatedAt: now.Add(-100 * day), ClosedAt: closed(5 * day)},
+		4: {ID: 4, Email: "open@corp.com", CreatedAt: now.Add(-100 * day)},
+		5: {ID: 5, Email: "ancient@corp.com", CreatedAt: now.Add(-900 * day)},
+	}}
+	factory := &accountFactory{store: store}
+
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(
+		HardDelete[*account]("stale-accounts", Rule{Column: "created_at", After: 730 * day, BatchSize: 1}, factory),
+		Anonymize[*account]("closed-accounts", Rule{
+			Column: "closed_at", After: 30 * day,
+			Where: map[string]interface{}{"anonymized_at IS NULL": true},
+			Mark:  "anonymized_at",
+		}, factory, map[string]anonymize.Strategy{"email": anonymize.Fake(anonymize.FakeEmail)}),
+		Policy{Name: "broken", Action: ActionSoftDelete, Apply: func(context.Context, time.Time) (Result, error) {
+			return Result{}, errors.New("unavailable")
+		}},
+	))
+	assert.Error(t, registry.Register(Policy{Name: "broken"}), "names are unique")
+
+	var audit []AuditEntry
+	metrics := recordingMetrics{}
+	scheduler := NewScheduler(&fakeLocker{}, Options{
+		Metrics: metrics,
+		Now:     func() time.Time { return now },
+		Audit:   func(_ context.Context, entry AuditEntry) { audit = append(audit, entry) },
+	}, registry)
+
+	report, err := scheduler.RunOnce(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retention broken")
+	assert.True(t, report.Leader)
+	assert.Equal(t, report.Entries, audit)
+	require.Len(t, audit, 3)
+
+	assert.Equal(t, AuditEntry{Policy: "stale-accounts", Action: ActionHardDelete, Cutoff: now.Add(-730 * day), StartedAt: now, Rows: 2, IDs: []int{1, 5}}, audit[0])
+	assert.Equal(t, []int{2}, audit[1].IDs)
+	assert.Equal(t, "unavailable", audit[2].Error)
+	assert.Equal(t, 3, store.commits, "one transaction per batch")
+
+	assert.NotContains(t, store.rows, 1)
+	assert.NotContains(t, store.rows, 5)
+	assert.Contains(t, store.rows[2].Email, "@example.invalid")
+	assert.NotNil(t, store.rows[2].AnonymizedAt)
+	assert.Equal(t, "recent@corp.com", store.rows[3].Email)
+	assert.Equal(t, recordingMetrics{
+		"retention_rows/stale-accounts":  2,
+		"retention_rows/closed-accounts": 1,
+		"retention_rows/broken":          0,
+		"retention_errors/broken":        1,
+	}, metrics)
+
+	// Already anonymized rows are not touched again
+	report, _ = scheduler.RunOnce(context.Background())
+	assert.Zero(t, report.Entries[1].Rows)
+
+	scheduler = NewScheduler(&fakeLocker{held: true}, Options{}, registry)
+	report, err = scheduler.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Leader)
+	assert.Empty(t, report.Entries)
+}
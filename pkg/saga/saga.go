@@ -0,0 +1,258 @@
+// Package saga runs workflows that span more than one transaction as a sequence of steps,
+// undoing the completed ones with compensations when a later step fails
+//
+// Each step runs in its own transaction together with the update of the saga's persisted
+// state, so local writes and progress commit as one. After a crash, Resume picks up every
+// unfinished saga where it stopped. Steps that call other services may run again after a
+// crash and should be idempotent.
+//
+//	checkout := saga.New[Order](db, "checkout",
+//		saga.Step[Order]{Name: "reserve", Action: reserveStock, Compensate: releaseStock},
+//		saga.Step[Order]{Name: "charge", Action: chargeCard, Compensate: refundCard},
+//		saga.Step[Order]{Name: "confirm", Action: confirmOrder},
+//	)
+//	err := checkout.Start(ctx, orderKey, order)
+//	...
+//	checkout.Resume(ctx, time.Minute) // on startup
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+// Status is where a saga instance stands
+type Status string
+
+const (
+	StatusRunning      Status = "running"      // Executing steps forward
+	StatusCompensating Status = "compensating" // A step failed; undoing completed steps
+	StatusCompleted    Status = "completed"    // Every step succeeded
+	StatusCompensated  Status = "compensated"  // Every completed step was undone
+)
+
+var (
+	// ErrCompensated reports a saga that failed and had its completed steps undone
+	ErrCompensated = errors.New("saga compensated")
+	// ErrConflict reports that another process advanced the saga instance first
+	ErrConflict = errors.New("saga instance was advanced concurrently")
+)
+
+// State is the persisted progress of one saga instance
+type State struct {
+	ID        string `gorm:"primaryKey;size:191"`
+	Saga      string `gorm:"size:100;not null;index"`
+	Status    Status `gorm:"size:20;not null;index"`
+	Step      int    // Next step to run, or last step to compensate
+	Data      string `gorm:"type:jsonb"`
+	Error     string // The step failure that started compensation, then the last compensation failure
+	Version   int    `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName stores saga state in one table for every saga
+func (State) TableName() string {
+	return "uow_sagas"
+}
+
+// Migrate creates the saga state table
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).AutoMigrate(&State{})
+}
+
+// Step is one unit of a saga
+// Action and Compensate receive the step's transaction and the saga data; changes to the
+// data are persisted with the step
+type Step[D any] struct {
+	Name       string
+	Action     func(ctx context.Context, tx *gorm.DB, data *D) error
+	Compensate func(ctx context.Context, tx *gorm.DB, data *D) error // Optional
+}
+
+// Saga is a named sequence of steps over data of type D
+type Saga[D any] struct {
+	name  string
+	db    *gorm.DB
+	steps []Step[D]
+}
+
+// New defines a saga; its name keys the persisted instances, so keep it stable across releases
+func New[D any](db *gorm.DB, name string, steps ...Step[D]) *Saga[D] {
+	return &Saga[D]{name: name, db: db, steps: steps}
+}
+
+// Name returns the saga's name
+func (s *Saga[D]) Name() string {
+	return s.name
+}
+
+// Start persists a new instance under id and runs it to completion or full compensation
+// A compensated saga returns ErrCompensated wrapping the failure of the step that triggered it
+func (s *Saga[D]) Start(ctx context.Context, id string, data D) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: saga %s data: %v", uowerrors.ErrInvalidEntity, s.name, err)
+	}
+	state := State{ID: id, Saga: s.name, Status: StatusRunning, Data: string(encoded)}
+	result := s.db.WithContext(ctx).Where(State{ID: id}).Attrs(state).FirstOrCreate(&state)
+	if result.Error != nil {
+		return fmt.Errorf("failed to start saga %s/%s: %w", s.name, id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: saga instance %s/%s", uowerrors.ErrEntityExists, s.name, id)
+	}
+	return s.run(ctx, &state)
+}
+
+// Load returns the persisted state and data of an instance
+func (s *Saga[D]) Load(ctx context.Context, id string) (State, D, error) {
+	var state State
+	var data D
+	err := s.db.WithContext(ctx).Where("saga = ? AND id = ?", s.name, id).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return state, data, fmt.Errorf("%w: saga instance %s/%s", uowerrors.ErrEntityNotFound, s.name, id)
+	}
+	if err != nil {
+		return state, data, err
+	}
+	err = json.Unmarshal([]byte(state.Data), &data)
+	return state, data, err
+}
+
+// Resume continues every unfinished instance not updated for at least idle
+// The idle period keeps Resume away from instances a live process is still running
+// Returns the failures of resumed instances joined together
+func (s *Saga[D]) Resume(ctx context.Context, idle time.Duration) error {
+	var states []State
+	err := s.db.WithContext(ctx).
+		Where("saga = ? AND status IN ? AND updated_at <= ?", s.name, []Status{StatusRunning, StatusCompensating}, time.Now().Add(-idle)).
+		Order("created_at").
+		Find(&states).Error
+	if err != nil {
+		return fmt.Errorf("failed to load unfinished %s sagas: %w", s.name, err)
+	}
+
+	var errs []error
+	for i := range states {
+		if err := s.run(ctx, &states[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run drives an instance forward, then backward once a step fails
+func (s *Saga[D]) run(ctx context.Context, state *State) error {
+	var data D
+	if err := json.Unmarshal([]byte(state.Data), &data); err != nil {
+		return fmt.Errorf("saga %s/%s has unreadable data: %w", s.name, state.ID, err)
+	}
+
+	var failure error
+	for state.Status == StatusRunning {
+		if state.Step >= len(s.steps) {
+			return s.save(ctx, s.db, state, StatusCompleted, state.Step, data)
+		}
+		step := s.steps[state.Step]
+		before := *state
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := step.Action(ctx, tx, &data); err != nil {
+				return err
+			}
+			return s.save(ctx, tx, state, StatusRunning, state.Step+1, data)
+		})
+		if err != nil {
+			*state = before
+		}
+		if errors.Is(err, ErrConflict) {
+			return err
+		}
+		if err != nil {
+			// The step's writes rolled back; undo the steps before it
+			failure = fmt.Errorf("step %q: %w", step.Name, err)
+			state.Error = fmt.Sprintf("step %q: %v", step.Name, err)
+			if err := s.reload(&data, state); err != nil {
+				return err
+			}
+			if err := s.save(ctx, s.db, state, StatusCompensating, state.Step-1, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if failure == nil {
+		failure = errors.New(state.Error)
+	}
+	for state.Status == StatusCompensating {
+		if state.Step < 0 {
+			if err := s.save(ctx, s.db, state, StatusCompensated, state.Step, data); err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: %s/%s after %w", ErrCompensated, s.name, state.ID, failure)
+		}
+		step := s.steps[state.Step]
+		before := *state
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if step.Compensate != nil {
+				if err := step.Compensate(ctx, tx, &data); err != nil {
+					return err
+				}
+			}
+			return s.save(ctx, tx, state, StatusCompensating, state.Step-1, data)
+		})
+		if err != nil {
+			*state = before
+			// Left compensating so a later Resume retries it
+			if !errors.Is(err, ErrConflict) {
+				state.Error = fmt.Sprintf("compensating step %q: %v", step.Name, err)
+				s.db.WithContext(ctx).Model(&State{}).Where("id = ?", state.ID).Update("error", state.Error)
+			}
+			return fmt.Errorf("saga %s/%s failed to compensate step %q: %w", s.name, state.ID, step.Name, err)
+		}
+	}
+	return nil
+}
+
+// save advances the persisted state if nobody else did since it was read
+func (s *Saga[D]) save(ctx context.Context, db *gorm.DB, state *State, status Status, step int, data D) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: saga %s data: %v", uowerrors.ErrInvalidEntity, s.name, err)
+	}
+	result := db.WithContext(ctx).Model(&State{}).
+		Where("id = ? AND version = ?", state.ID, state.Version).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"step":       step,
+			"data":       string(encoded),
+			"error":      state.Error,
+			"version":    state.Version + 1,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to save saga %s/%s: %w", s.name, state.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s/%s", ErrConflict, s.name, state.ID)
+	}
+	state.Status, state.Step, state.Data, state.Version = status, step, string(encoded), state.Version+1
+	return nil
+}
+
+// reload discards in-memory changes a failed step made to the data
+func (s *Saga[D]) reload(data *D, state *State) error {
+	var fresh D
+	if err := json.Unmarshal([]byte(state.Data), &fresh); err != nil {
+		return fmt.Errorf("saga %s/%s has unreadable data: %w", s.name, state.ID, err)
+	}
+	*data = fresh
+	return nil
+}
@@ -0,0 +1,135 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+)
+
+type order struct {
+	Items    int
+	Reserved bool
+	Charge   string
+}
+
+type stockRow struct {
+	ID       int `gorm:"primaryKey"`
+	Reserved int
+}
+
+func setupDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, Migrate(context.Background(), db))
+	require.NoError(t, db.AutoMigrate(&stockRow{}))
+	require.NoError(t, db.Create(&stockRow{ID: 1}).Error)
+	return db
+}
+
+func reserved(t *testing.T, db *gorm.DB) int {
+	t.Helper()
+	var row stockRow
+	require.NoError(t, db.First(&row, 1).Error)
+	return row.Reserved
+}
+
+func checkout(db *gorm.DB, charge func(*order) error) *Saga[order] {
+	return New[order](db, "checkout",
+		Step[order]{
+			Name: "reserve",
+			Action: func(ctx context.Context, tx *gorm.DB, o *order) error {
+				o.Reserved = true
+				return tx.Model(&stockRow{ID: 1}).Update("reserved", gorm.Expr("reserved + ?", o.Items)).Error
+			},
+			Compensate: func(ctx context.Context, tx *gorm.DB, o *order) error {
+				o.Reserved = false
+				return tx.Model(&stockRow{ID: 1}).Update("reserved", gorm.Expr("reserved - ?", o.Items)).Error
+			},
+		},
+		Step[order]{
+			Name: "charge",
+			Action: func(ctx context.Context, tx *gorm.DB, o *order) error {
+				return charge(o)
+			},
+		},
+	)
+}
+
+func TestSagaCompletes(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	s := checkout(db, func(o *order) error {
+		o.Charge = "ch_1"
+		return nil
+	})
+
+	require.NoError(t, s.Start(ctx, "order-1", order{Items: 3}))
+	state, data, err := s.Load(ctx, "order-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, order{Items: 3, Reserved: true, Charge: "ch_1"}, data)
+	assert.Equal(t, 3, reserved(t, db))
+
+	assert.ErrorIs(t, s.Start(ctx, "order-1", order{Items: 3}), uowerrors.ErrEntityExists)
+}
+
+func TestSagaCompensates(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+	declined := errors.New("card declined")
+	s := checkout(db, func(o *order) error {
+		o.Charge = "half-written"
+		return declined
+	})
+
+	err := s.Start(ctx, "order-2", order{Items: 2})
+	assert.ErrorIs(t, err, ErrCompensated)
+	assert.ErrorIs(t, err, declined)
+
+	state, data, err := s.Load(ctx, "order-2")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompensated, state.Status)
+	assert.Contains(t, state.Error, "card declined")
+	assert.Equal(t, order{Items: 2}, data)
+	assert.Equal(t, 0, reserved(t, db))
+}
+
+func TestSagaResumesAfterCrash(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+
+	// A process that died after reserving stock, before charging
+	crashed := checkout(db, func(*order) error {
+		panic("process killed")
+	})
+	assert.Panics(t, func() { crashed.Start(ctx, "order-3", order{Items: 1}) })
+	state, _, err := crashed.Load(ctx, "order-3")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, state.Status)
+	assert.Equal(t, 1, state.Step)
+
+	restarted := checkout(db, func(o *order) error {
+		o.Charge = "ch_3"
+		return nil
+	})
+	require.NoError(t, restarted.Resume(ctx, time.Hour))
+	state, _, err = restarted.Load(ctx, "order-3")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, state.Status, "recently updated instances are left alone")
+
+	require.NoError(t, restarted.Resume(ctx, 0))
+	state, data, err := restarted.Load(ctx, "order-3")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, "ch_3", data.Charge)
+	assert.Equal(t, 1, reserved(t, db), "the reserve step ran once")
+}
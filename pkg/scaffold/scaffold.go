@@ -0,0 +1,341 @@
+// Package scaffold generates IXxxRepository interfaces and Unit of Work backed implementations
+//
+// The generated code follows examples/repositories.go: CRUD, pagination, batch and
+// soft delete methods, plus GetByXxx lookups for unique columns and finders for
+// indexed foreign keys. cmd/uowgen wraps it for use with go:generate.
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Model describes the entity a repository is generated for
+type Model struct {
+	Package string  // Package of the generated file
+	Name    string  // Entity type name, e.g. User
+	Lookups []Field // Unique columns, generated as GetByXxx returning one entity
+	Finders []Field // Indexed foreign keys, generated as GetByXxx returning a slice
+}
+
+// Field is a struct field used by a generated lookup method
+type Field struct {
+	Name   string // Go field name, e.g. Email
+	Column string // Database column, e.g. email
+	Type   string // Go type expression, e.g. string
+}
+
+// Param returns the lower camel case parameter name for the field
+func (f Field) Param() string {
+	return lowerFirst(f.Name)
+}
+
+// Var returns the lower camel case variable name of the entity
+func (m Model) Var() string {
+	return lowerFirst(m.Name)
+}
+
+// Plural returns the lower camel case plural used for slices of the entity
+func (m Model) Plural() string {
+	return plural(m.Var())
+}
+
+// Words returns the lower case, space separated entity name used in doc comments
+func (m Model) Words() string {
+	return strings.Join(splitWords(m.Name), " ")
+}
+
+// FileName returns the conventional file name for the generated repository, e.g. user_repository.go
+func (m Model) FileName() string {
+	return strings.Join(splitWords(m.Name), "_") + "_repository.go"
+}
+
+// ParseFile finds a struct type in a Go source file and derives its Model
+// Unique columns come from gorm uniqueIndex/unique tags, finders from indexed XxxID fields
+func ParseFile(path, typeName string) (Model, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return Model{}, err
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if typeSpec.Name.Name != typeName {
+				continue
+			}
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				return Model{}, fmt.Errorf("%s: %s is not a struct", filepath.Base(path), typeName)
+			}
+			return modelOf(file.Name.Name, typeName, structType), nil
+		}
+	}
+	return Model{}, fmt.Errorf("%s: type %s not found", filepath.Base(path), typeName)
+}
+
+func modelOf(pkg, name string, structType *ast.StructType) Model {
+	model := Model{Package: pkg, Name: name}
+	for _, field := range structType.Fields.List {
+		if len(field.Names) != 1 || !field.Names[0].IsExported() || field.Tag == nil {
+			continue
+		}
+		fieldName := field.Names[0].Name
+		if fieldName == "ID" || fieldName == "Slug" {
+			continue // covered by GetByID and the Unit of Work's slug handling
+		}
+
+		ident, ok := field.Type.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		settings := gormSettings(tag.Get("gorm"))
+		column := settings["column"]
+		if column == "" {
+			column = strings.Join(splitWords(fieldName), "_")
+		}
+		f := Field{Name: fieldName, Column: column, Type: ident.Name}
+
+		_, uniqueIndex := settings["uniqueindex"]
+		_, unique := settings["unique"]
+		_, index := settings["index"]
+		switch {
+		case uniqueIndex || unique:
+			model.Lookups = append(model.Lookups, f)
+		case index && strings.HasSuffix(fieldName, "ID"):
+			model.Finders = append(model.Finders, f)
+		}
+	}
+	return model
+}
+
+// gormSettings parses a gorm tag into lower-cased keys
+func gormSettings(tag string) map[string]string {
+	settings := make(map[string]string)
+	for _, setting := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(setting), ":")
+		if key != "" {
+			settings[strings.ToLower(key)] = value
+		}
+	}
+	return settings
+}
+
+// Generate renders the repository source for a model, formatted with gofmt
+func Generate(model Model) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := repositoryTemplate.Execute(&buf, model); err != nil {
+		return nil, err
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated repository: %w", err)
+	}
+	return source, nil
+}
+
+// splitWords splits a Go identifier into lower case words, keeping initialisms together
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		if unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, strings.ToLower(string(runes[start:i])))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(string(runes[start:])))
+}
+
+// lowerFirst lower-cases the leading word, e.g. UserID -> userID, HTTPLog -> httpLog
+func lowerFirst(name string) string {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+	return words[0] + name[len(words[0]):]
+}
+
+func plural(word string) string {
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+var repositoryTemplate = template.Must(template.New("repository").Parse(`// Code generated by uowgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+// I{{.Name}}Repository defines {{.Words}}-specific repository operations
+type I{{.Name}}Repository interface {
+	// Basic CRUD
+	Create(ctx context.Context, {{.Var}} *{{.Name}}) (*{{.Name}}, error)
+	GetByID(ctx context.Context, id int) (*{{.Name}}, error)
+{{- range .Lookups}}
+	GetBy{{.Name}}(ctx context.Context, {{.Param}} {{.Type}}) (*{{$.Name}}, error)
+{{- end}}
+	Update(ctx context.Context, {{.Var}} *{{.Name}}) (*{{.Name}}, error)
+	Delete(ctx context.Context, id int) error
+
+	// Queries
+	FindAll(ctx context.Context) ([]*{{.Name}}, error)
+	FindWithPagination(ctx context.Context, page, pageSize int) ([]*{{.Name}}, uint, error)
+	Search(ctx context.Context, filter *{{.Name}}, limit int) ([]*{{.Name}}, error)
+{{- range .Finders}}
+	GetBy{{.Name}}(ctx context.Context, {{.Param}} {{.Type}}) ([]*{{$.Name}}, error)
+{{- end}}
+
+	// Batch operations
+	BatchCreate(ctx context.Context, {{.Plural}} []*{{.Name}}) ([]*{{.Name}}, error)
+	BatchUpdate(ctx context.Context, {{.Plural}} []*{{.Name}}) ([]*{{.Name}}, error)
+
+	// Soft delete operations
+	SoftDelete(ctx context.Context, id int) (*{{.Name}}, error)
+	GetTrashed(ctx context.Context) ([]*{{.Name}}, error)
+	Restore(ctx context.Context, id int) (*{{.Name}}, error)
+}
+
+// {{.Name}}Repository implements I{{.Name}}Repository using Unit of Work
+type {{.Name}}Repository struct {
+	uow persistence.IUnitOfWork[*{{.Name}}]
+}
+
+// New{{.Name}}Repository creates a new {{.Words}} repository
+func New{{.Name}}Repository(uow persistence.IUnitOfWork[*{{.Name}}]) I{{.Name}}Repository {
+	return &{{.Name}}Repository{
+		uow: uow,
+	}
+}
+
+// Create inserts a new {{.Words}}
+func (r *{{.Name}}Repository) Create(ctx context.Context, {{.Var}} *{{.Name}}) (*{{.Name}}, error) {
+	return r.uow.Insert(ctx, {{.Var}})
+}
+
+// GetByID retrieves a {{.Words}} by ID
+func (r *{{.Name}}Repository) GetByID(ctx context.Context, id int) (*{{.Name}}, error) {
+	return r.uow.FindOneById(ctx, id)
+}
+{{range .Lookups}}
+// GetBy{{.Name}} retrieves a {{$.Words}} by {{.Column}}
+func (r *{{$.Name}}Repository) GetBy{{.Name}}(ctx context.Context, {{.Param}} {{.Type}}) (*{{$.Name}}, error) {
+	{{.Param}}Identifier := identifier.NewIdentifier().Equal("{{.Column}}", {{.Param}})
+	return r.uow.FindOneByIdentifier(ctx, {{.Param}}Identifier)
+}
+{{end}}
+// Update modifies an existing {{.Words}}
+func (r *{{.Name}}Repository) Update(ctx context.Context, {{.Var}} *{{.Name}}) (*{{.Name}}, error) {
+	idIdentifier := identifier.NewIdentifier().Equal("id", {{.Var}}.ID)
+	return r.uow.Update(ctx, idIdentifier, {{.Var}})
+}
+
+// Delete removes a {{.Words}} (hard delete)
+func (r *{{.Name}}Repository) Delete(ctx context.Context, id int) error {
+	idIdentifier := identifier.NewIdentifier().Equal("id", id)
+	return r.uow.Delete(ctx, idIdentifier)
+}
+
+// FindAll retrieves all {{.Plural}}
+func (r *{{.Name}}Repository) FindAll(ctx context.Context) ([]*{{.Name}}, error) {
+	return r.uow.FindAll(ctx)
+}
+
+// FindWithPagination retrieves {{.Plural}} with pagination
+func (r *{{.Name}}Repository) FindWithPagination(ctx context.Context, page, pageSize int) ([]*{{.Name}}, uint, error) {
+	params := domain.QueryParams[*{{.Name}}]{
+		Sort: domain.SortMap{
+			"created_at": domain.SortDesc,
+		},
+		Limit:  pageSize,
+		Offset: (page - 1) * pageSize,
+	}
+
+	return r.uow.FindAllWithPagination(ctx, params)
+}
+
+// Search finds {{.Plural}} matching the given criteria
+func (r *{{.Name}}Repository) Search(ctx context.Context, filter *{{.Name}}, limit int) ([]*{{.Name}}, error) {
+	params := domain.QueryParams[*{{.Name}}]{
+		Filter: filter,
+		Sort: domain.SortMap{
+			"created_at": domain.SortDesc,
+		},
+		Limit: limit,
+	}
+
+	{{.Plural}}, _, err := r.uow.FindAllWithPagination(ctx, params)
+	return {{.Plural}}, err
+}
+{{range .Finders}}
+// GetBy{{.Name}} retrieves all {{$.Plural}} with the given {{.Column}}
+func (r *{{$.Name}}Repository) GetBy{{.Name}}(ctx context.Context, {{.Param}} {{.Type}}) ([]*{{$.Name}}, error) {
+	filter := &{{$.Name}}{ {{- .Name}}: {{.Param -}} }
+	params := domain.QueryParams[*{{$.Name}}]{
+		Filter: filter,
+		Sort: domain.SortMap{
+			"created_at": domain.SortDesc,
+		},
+	}
+
+	{{$.Plural}}, _, err := r.uow.FindAllWithPagination(ctx, params)
+	return {{$.Plural}}, err
+}
+{{end}}
+// BatchCreate performs bulk {{.Words}} creation
+func (r *{{.Name}}Repository) BatchCreate(ctx context.Context, {{.Plural}} []*{{.Name}}) ([]*{{.Name}}, error) {
+	return r.uow.BulkInsert(ctx, {{.Plural}})
+}
+
+// BatchUpdate performs bulk {{.Words}} updates
+func (r *{{.Name}}Repository) BatchUpdate(ctx context.Context, {{.Plural}} []*{{.Name}}) ([]*{{.Name}}, error) {
+	return r.uow.BulkUpdate(ctx, {{.Plural}})
+}
+
+// SoftDelete soft deletes a {{.Words}}
+func (r *{{.Name}}Repository) SoftDelete(ctx context.Context, id int) (*{{.Name}}, error) {
+	idIdentifier := identifier.NewIdentifier().Equal("id", id)
+	return r.uow.SoftDelete(ctx, idIdentifier)
+}
+
+// GetTrashed retrieves all soft-deleted {{.Plural}}
+func (r *{{.Name}}Repository) GetTrashed(ctx context.Context) ([]*{{.Name}}, error) {
+	return r.uow.GetTrashed(ctx)
+}
+
+// Restore restores a soft-deleted {{.Words}}
+func (r *{{.Name}}Repository) Restore(ctx context.Context, id int) (*{{.Name}}, error) {
+	idIdentifier := identifier.NewIdentifier().Equal("id", id)
+	return r.uow.Restore(ctx, idIdentifier)
+}
+`))
@@ -0,0 +1,72 @@
+package scaffold
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const modelSource = `package billing
+
+type InvoiceLine struct {
+	ID         int    ` + "`gorm:\"primaryKey\" json:\"id\"`" + `
+	Slug       string ` + "`gorm:\"uniqueIndex\" json:\"slug\"`" + `
+	Reference  string ` + "`gorm:\"uniqueIndex;size:64\" json:\"reference\"`" + `
+	ExternalID string ` + "`gorm:\"column:ext_id;unique\" json:\"external_id\"`" + `
+	CustomerID int    ` + "`gorm:\"index;not null\" json:\"customer_id\"`" + `
+	Amount     int64  ` + "`json:\"amount\"`" + `
+}
+`
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.go")
+	require.NoError(t, os.WriteFile(path, []byte(modelSource), 0o644))
+
+	model, err := ParseFile(path, "InvoiceLine")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", model.Package)
+	assert.Equal(t, []Field{
+		{Name: "Reference", Column: "reference", Type: "string"},
+		{Name: "ExternalID", Column: "ext_id", Type: "string"},
+	}, model.Lookups)
+	assert.Equal(t, []Field{{Name: "CustomerID", Column: "customer_id", Type: "int"}}, model.Finders)
+
+	assert.Equal(t, "invoiceLine", model.Var())
+	assert.Equal(t, "invoiceLines", model.Plural())
+	assert.Equal(t, "invoice line", model.Words())
+	assert.Equal(t, "invoice_line_repository.go", model.FileName())
+	assert.Equal(t, "externalID", model.Lookups[1].Param())
+
+	_, err = ParseFile(path, "Missing")
+	assert.Error(t, err)
+}
+
+func TestGenerate(t *testing.T) {
+	model := Model{
+		Package: "billing",
+		Name:    "Category",
+		Lookups: []Field{{Name: "Code", Column: "code", Type: "string"}},
+		Finders: []Field{{Name: "ParentID", Column: "parent_id", Type: "int"}},
+	}
+
+	source, err := Generate(model)
+	require.NoError(t, err)
+
+	file, err := parser.ParseFile(token.NewFileSet(), "category_repository.go", source, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", file.Name.Name)
+
+	code := string(source)
+	assert.Contains(t, code, "type ICategoryRepository interface")
+	assert.Contains(t, code, "func NewCategoryRepository(uow persistence.IUnitOfWork[*Category]) ICategoryRepository")
+	assert.Contains(t, code, "GetByCode(ctx context.Context, code string) (*Category, error)")
+	assert.Contains(t, code, `identifier.NewIdentifier().Equal("code", code)`)
+	assert.Contains(t, code, "GetByParentID(ctx context.Context, parentID int) ([]*Category, error)")
+	assert.Contains(t, code, "filter := &Category{ParentID: parentID}")
+	assert.Contains(t, code, "BatchCreate(ctx context.Context, categories []*Category)")
+}
@@ -0,0 +1,167 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+)
+
+// Meilisearch indexes entities into a Meilisearch index through its HTTP API
+// Documents are the entities' JSON encoding, keyed by their "id" field
+type Meilisearch[T domain.BaseModel] struct {
+	client  *http.Client
+	baseURL string
+	index   string
+	apiKey  string
+}
+
+// NewMeilisearch creates a Meilisearch indexer; apiKey may be empty for unsecured instances
+func NewMeilisearch[T domain.BaseModel](client *http.Client, baseURL, index, apiKey string) *Meilisearch[T] {
+	return &Meilisearch[T]{client: client, baseURL: strings.TrimRight(baseURL, "/"), index: index, apiKey: apiKey}
+}
+
+// Index adds or replaces documents
+func (m *Meilisearch[T]) Index(ctx context.Context, entities []T) error {
+	body, err := json.Marshal(entities)
+	if err != nil {
+		return fmt.Errorf("meilisearch: encode documents: %w", err)
+	}
+	return m.post(ctx, "/indexes/"+url.PathEscape(m.index)+"/documents?primaryKey=id", "application/json", body)
+}
+
+// Delete removes documents by ID
+func (m *Meilisearch[T]) Delete(ctx context.Context, ids []int) error {
+	body, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("meilisearch: encode ids: %w", err)
+	}
+	return m.post(ctx, "/indexes/"+url.PathEscape(m.index)+"/documents/delete-batch", "application/json", body)
+}
+
+func (m *Meilisearch[T]) post(ctx context.Context, path, contentType string, body []byte) error {
+	header := http.Header{"Content-Type": {contentType}}
+	if m.apiKey != "" {
+		header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	_, err := send(ctx, m.client, m.baseURL+path, header, body)
+	if err != nil {
+		return fmt.Errorf("meilisearch: %w", err)
+	}
+	return nil
+}
+
+// Elasticsearch indexes entities into an Elasticsearch (or OpenSearch) index through the bulk API
+// Documents are the entities' JSON encoding with the entity ID as _id
+type Elasticsearch[T domain.BaseModel] struct {
+	client  *http.Client
+	baseURL string
+	index   string
+	header  http.Header
+}
+
+// NewElasticsearch creates an Elasticsearch indexer
+// header carries authentication, e.g. {"Authorization": {"ApiKey ..."}}; it may be nil
+func NewElasticsearch[T domain.BaseModel](client *http.Client, baseURL, index string, header http.Header) *Elasticsearch[T] {
+	return &Elasticsearch[T]{client: client, baseURL: strings.TrimRight(baseURL, "/"), index: index, header: header}
+}
+
+// Index adds or replaces documents
+func (e *Elasticsearch[T]) Index(ctx context.Context, entities []T) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, entity := range entities {
+		action := map[string]map[string]string{"index": {"_index": e.index, "_id": strconv.Itoa(entity.GetID())}}
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("elasticsearch: encode action: %w", err)
+		}
+		if err := encoder.Encode(entity); err != nil {
+			return fmt.Errorf("elasticsearch: encode document: %w", err)
+		}
+	}
+	return e.bulk(ctx, body.Bytes())
+}
+
+// Delete removes documents by ID
+func (e *Elasticsearch[T]) Delete(ctx context.Context, ids []int) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, id := range ids {
+		action := map[string]map[string]string{"delete": {"_index": e.index, "_id": strconv.Itoa(id)}}
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("elasticsearch: encode action: %w", err)
+		}
+	}
+	return e.bulk(ctx, body.Bytes())
+}
+
+// bulk sends an NDJSON bulk request; item failures are reported even though the request succeeds
+func (e *Elasticsearch[T]) bulk(ctx context.Context, body []byte) error {
+	header := e.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/x-ndjson")
+
+	response, err := send(ctx, e.client, e.baseURL+"/_bulk", header, body)
+	if err != nil {
+		return fmt.Errorf("elasticsearch: %w", err)
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  *struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return fmt.Errorf("elasticsearch: decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	for _, item := range result.Items {
+		for action, outcome := range item {
+			// Deleting a document that is not indexed is not a failure
+			if outcome.Error != nil && !(action == "delete" && outcome.Status == http.StatusNotFound) {
+				return fmt.Errorf("elasticsearch: %s %s: %s", action, outcome.ID, outcome.Error.Reason)
+			}
+		}
+	}
+	return nil
+}
+
+// send posts a request body and returns the response body, failing on non-2xx statuses
+func send(ctx context.Context, client *http.Client, target string, header http.Header, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header = header
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned %s: %s", target, response.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
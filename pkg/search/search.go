@@ -0,0 +1,97 @@
+// Package search keeps a search index (Elasticsearch, Meilisearch, ...) in sync with an entity table
+//
+// Sync pushes committed changes from a factory's units of work to an Indexer, and
+// ReindexAll rebuilds the index by streaming the table with keyset pagination:
+//
+//	index := search.NewMeilisearch[*User](http.DefaultClient, "http://localhost:7700", "users", apiKey)
+//	search.Sync(factory, index, search.Options{OnError: logIndexError})
+//	n, err := search.ReindexAll(ctx, factory.Create(), index, search.Options{})
+package search
+
+import (
+	"context"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// DefaultBatchSize is the number of entities sent to the indexer per request during a reindex
+const DefaultBatchSize = 500
+
+// Indexer writes documents to a search engine
+type Indexer[T domain.BaseModel] interface {
+	Index(ctx context.Context, entities []T) error
+	Delete(ctx context.Context, ids []int) error
+}
+
+// Options configures synchronization
+type Options struct {
+	BatchSize int                                  // Entities per indexer call during reindex, default 500
+	OnError   func(ctx context.Context, err error) // Receives indexing failures after commit; they cannot fail the transaction
+}
+
+func (o Options) batchSize() int {
+	if o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+func (o Options) report(ctx context.Context, err error) {
+	if err != nil && o.OnError != nil {
+		o.OnError(ctx, err)
+	}
+}
+
+// Sync registers a change listener pushing committed changes to the indexer
+// Created and updated entities are indexed; soft- and hard-deleted ones are removed
+func Sync[T domain.BaseModel](factory *postgres.UnitOfWorkFactory[T], indexer Indexer[T], options Options) {
+	factory.OnChange(Listener(indexer, options))
+}
+
+// Listener adapts an indexer to a change listener, for wiring without a factory
+func Listener[T domain.BaseModel](indexer Indexer[T], options Options) postgres.ChangeListener[T] {
+	return func(ctx context.Context, changes []domain.Change[T]) {
+		var upserts []T
+		var deletes []int
+		for _, change := range changes {
+			if change.Kind == domain.ChangeDeleted {
+				deletes = append(deletes, change.Entity.GetID())
+				continue
+			}
+			upserts = append(upserts, change.Entity)
+		}
+
+		if len(upserts) > 0 {
+			options.report(ctx, indexer.Index(ctx, upserts))
+		}
+		if len(deletes) > 0 {
+			options.report(ctx, indexer.Delete(ctx, deletes))
+		}
+	}
+}
+
+// ReindexAll streams every live entity to the indexer in keyset-ordered batches and returns how many were sent
+// Memory use is bounded by the batch size regardless of table size
+func ReindexAll[T domain.BaseModel](ctx context.Context, uow persistence.IUnitOfWork[T], indexer Indexer[T], options Options) (int64, error) {
+	var indexed int64
+	var after *domain.Cursor
+
+	for {
+		page, err := uow.FindAllWithKeyset(ctx, domain.KeysetParams[T]{After: after, Limit: options.batchSize()})
+		if err != nil {
+			return indexed, err
+		}
+		if len(page.Items) > 0 {
+			if err := indexer.Index(ctx, page.Items); err != nil {
+				return indexed, err
+			}
+			indexed += int64(len(page.Items))
+			after = &page.Cursors[len(page.Cursors)-1]
+		}
+		if !page.HasNext {
+			return indexed, nil
+		}
+	}
+}
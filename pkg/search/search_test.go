@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+type article struct {
+	ID        int            `json:"id"`
+	Title     string         `json:"title"`
+	CreatedAt time.Time      `json:"-"`
+	DeletedAt gorm.DeletedAt `json:"-"`
+}
+
+func (a *article) GetID() int                    { return a.ID }
+func (a *article) GetSlug() string               { return "" }
+func (a *article) SetSlug(string)                {}
+func (a *article) GetCreatedAt() time.Time       { return a.CreatedAt }
+func (a *article) GetUpdatedAt() time.Time       { return a.CreatedAt }
+func (a *article) GetArchivedAt() gorm.DeletedAt { return a.DeletedAt }
+func (a *article) GetName() string               { return a.Title }
+
+type fakeIndexer struct {
+	batches [][]int
+	deleted []int
+	err     error
+}
+
+func (f *fakeIndexer) Index(_ context.Context, entities []*article) error {
+	ids := make([]int, len(entities))
+	for i, entity := range entities {
+		ids[i] = entity.ID
+	}
+	f.batches = append(f.batches, ids)
+	return f.err
+}
+
+func (f *fakeIndexer) Delete(_ context.Context, ids []int) error {
+	f.deleted = append(f.deleted, ids...)
+	return f.err
+}
+
+// pagedUnitOfWork serves keyset pages over a fixed number of rows; unused methods panic through the nil embedded interface
+type pagedUnitOfWork struct {
+	persistence.IUnitOfWork[*article]
+	rows int
+}
+
+func (p *pagedUnitOfWork) FindAllWithKeyset(_ context.Context, params domain.KeysetParams[*article]) (domain.KeysetPage[*article], error) {
+	start := 1
+	if params.After != nil {
+		start = params.After.ID + 1
+	}
+	page := domain.KeysetPage[*article]{}
+	for id := start; id <= p.rows && len(page.Items) < params.Limit; id++ {
+		page.Items = append(page.Items, &article{ID: id})
+		page.Cursors = append(page.Cursors, domain.Cursor{ID: id})
+	}
+	page.HasNext = start+len(page.Items) <= p.rows
+	return page, nil
+}
+
+func TestReindexAll(t *testing.T) {
+	indexer := &fakeIndexer{}
+	n, err := ReindexAll[*article](context.Background(), &pagedUnitOfWork{rows: 5}, indexer, Options{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, indexer.batches)
+
+	failing := &fakeIndexer{err: errors.New("unavailable")}
+	n, err = ReindexAll[*article](context.Background(), &pagedUnitOfWork{rows: 5}, failing, Options{BatchSize: 2})
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestListener(t *testing.T) {
+	indexer := &fakeIndexer{err: errors.New("unavailable")}
+	var reported []error
+	listener := Listener[*article](indexer, Options{OnError: func(_ context.Context, err error) { reported = append(reported, err) }})
+
+	listener(context.Background(), []domain.Change[*article]{
+		{Kind: domain.ChangeCreated, Entity: &article{ID: 1}},
+		{Kind: domain.ChangeDeleted, Entity: &article{ID: 2}},
+		{Kind: domain.ChangeUpdated, Entity: &article{ID: 3}},
+	})
+
+	assert.Equal(t, [][]int{{1, 3}}, indexer.batches)
+	assert.Equal(t, []int{2}, indexer.deleted)
+	assert.Len(t, reported, 2)
+}
+
+func TestMeilisearch(t *testing.T) {
+	var paths, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.RequestURI())
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	index := NewMeilisearch[*article](server.Client(), server.URL+"/", "articles", "secret")
+	require.NoError(t, index.Index(context.Background(), []*article{{ID: 1, Title: "Hello"}}))
+	require.NoError(t, index.Delete(context.Background(), []int{2, 3}))
+
+	assert.Equal(t, []string{"/indexes/articles/documents?primaryKey=id", "/indexes/articles/documents/delete-batch"}, paths)
+	assert.JSONEq(t, `[{"id":1,"title":"Hello"}]`, bodies[0])
+	assert.JSONEq(t, `[2,3]`, bodies[1])
+}
+
+func TestElasticsearch(t *testing.T) {
+	var lines []string
+	response := `{"errors":false,"items":[]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		lines = strings.Split(strings.TrimSpace(string(body)), "\n")
+		_, _ = io.WriteString(w, response)
+	}))
+	defer server.Close()
+
+	index := NewElasticsearch[*article](server.Client(), server.URL, "articles", nil)
+	require.NoError(t, index.Index(context.Background(), []*article{{ID: 1, Title: "Hello"}}))
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"index":{"_index":"articles","_id":"1"}}`, lines[0])
+	assert.JSONEq(t, `{"id":1,"title":"Hello"}`, lines[1])
+
+	// Missing documents do not fail a delete, other item errors do
+	response = `{"errors":true,"items":[{"delete":{"_id":"2","status":404,"error":{"reason":"not found"}}}]}`
+	require.NoError(t, index.Delete(context.Background(), []int{2}))
+	assert.JSONEq(t, `{"delete":{"_index":"articles","_id":"2"}}`, lines[0])
+
+	failure, _ := json.Marshal(map[string]interface{}{
+		"errors": true,
+		"items":  []interface{}{map[string]interface{}{"index": map[string]interface{}{"_id": "1", "status": 400, "error": map[string]string{"reason": "mapper_parsing_exception"}}}},
+	})
+	response = string(failure)
+	err := index.Index(context.Background(), []*article{{ID: 1}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mapper_parsing_exception")
+}
@@ -0,0 +1,153 @@
+// Package timescale manages TimescaleDB hypertables and their compression and retention policies
+//
+// Metrics and event tables can live next to the entity tables and still be queried through
+// the Unit of Work; time_bucket aggregation goes through Aggregate with a domain.TimeBucket:
+//
+//	err := timescale.CreateHypertable(ctx, db, &Reading{}, "recorded_at", timescale.HypertableOptions{ChunkInterval: 24 * time.Hour})
+//	err = timescale.EnableCompression(ctx, db, &Reading{}, timescale.CompressionOptions{SegmentBy: []string{"sensor_id"}, After: 7 * 24 * time.Hour})
+//	err = timescale.AddRetentionPolicy(ctx, db, &Reading{}, 90*24*time.Hour)
+//
+// All helpers are idempotent, so they can run on every migration.
+package timescale
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HypertableOptions configures CreateHypertable
+type HypertableOptions struct {
+	ChunkInterval time.Duration // Time range per chunk; default TimescaleDB's 7 days
+	MigrateData   bool          // Move existing rows into chunks; required when the table is not empty
+}
+
+// CompressionOptions configures EnableCompression
+type CompressionOptions struct {
+	SegmentBy []string      // Columns whose values are stored together, typically the series key
+	OrderBy   string        // Order inside a segment, e.g. "recorded_at DESC"; default the time column descending
+	After     time.Duration // Compress chunks older than this; zero enables compression without a policy
+}
+
+// CreateHypertable turns a table (given by name or model) into a hypertable partitioned on timeColumn
+// The extension must already exist: CREATE EXTENSION IF NOT EXISTS timescaledb
+func CreateHypertable(ctx context.Context, db *gorm.DB, model interface{}, timeColumn string, options HypertableOptions) error {
+	table, err := tableName(db, model)
+	if err != nil {
+		return err
+	}
+
+	query := "SELECT create_hypertable(CAST(? AS regclass), ?, if_not_exists => TRUE, migrate_data => ?"
+	args := []interface{}{table, timeColumn, options.MigrateData}
+	if options.ChunkInterval > 0 {
+		query += ", chunk_time_interval => CAST(? AS interval)"
+		args = append(args, interval(options.ChunkInterval))
+	}
+	query += ")"
+
+	if err := db.WithContext(ctx).Exec(query, args...).Error; err != nil {
+		return fmt.Errorf("failed to create hypertable %s: %w", table, err)
+	}
+	return nil
+}
+
+// EnableCompression enables native compression on a hypertable and optionally schedules it
+func EnableCompression(ctx context.Context, db *gorm.DB, model interface{}, options CompressionOptions) error {
+	table, err := tableName(db, model)
+	if err != nil {
+		return err
+	}
+
+	if err := db.WithContext(ctx).Exec(compressionStatement(table, options)).Error; err != nil {
+		return fmt.Errorf("failed to enable compression on %s: %w", table, err)
+	}
+	if options.After <= 0 {
+		return nil
+	}
+
+	err = db.WithContext(ctx).Exec(
+		"SELECT add_compression_policy(CAST(? AS regclass), CAST(? AS interval), if_not_exists => TRUE)",
+		table, interval(options.After),
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to add compression policy on %s: %w", table, err)
+	}
+	return nil
+}
+
+// AddRetentionPolicy schedules dropping chunks older than dropAfter
+func AddRetentionPolicy(ctx context.Context, db *gorm.DB, model interface{}, dropAfter time.Duration) error {
+	table, err := tableName(db, model)
+	if err != nil {
+		return err
+	}
+	if dropAfter <= 0 {
+		return fmt.Errorf("retention interval must be positive")
+	}
+
+	err = db.WithContext(ctx).Exec(
+		"SELECT add_retention_policy(CAST(? AS regclass), CAST(? AS interval), if_not_exists => TRUE)",
+		table, interval(dropAfter),
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to add retention policy on %s: %w", table, err)
+	}
+	return nil
+}
+
+// RemoveRetentionPolicy unschedules the retention policy, if any
+func RemoveRetentionPolicy(ctx context.Context, db *gorm.DB, model interface{}) error {
+	table, err := tableName(db, model)
+	if err != nil {
+		return err
+	}
+	if err := db.WithContext(ctx).Exec("SELECT remove_retention_policy(CAST(? AS regclass), if_exists => TRUE)", table).Error; err != nil {
+		return fmt.Errorf("failed to remove retention policy on %s: %w", table, err)
+	}
+	return nil
+}
+
+// compressionStatement builds the ALTER TABLE enabling compression
+// Segment and order columns are passed as a single string option, so they are quoted inside it
+func compressionStatement(table string, options CompressionOptions) string {
+	settings := []string{"timescaledb.compress"}
+	if len(options.SegmentBy) > 0 {
+		columns := make([]string, len(options.SegmentBy))
+		for i, column := range options.SegmentBy {
+			columns[i] = quoteIdentifier(column)
+		}
+		settings = append(settings, "timescaledb.compress_segmentby = "+quoteLiteral(strings.Join(columns, ", ")))
+	}
+	if options.OrderBy != "" {
+		settings = append(settings, "timescaledb.compress_orderby = "+quoteLiteral(options.OrderBy))
+	}
+	return fmt.Sprintf("ALTER TABLE %s SET (%s)", quoteIdentifier(table), strings.Join(settings, ", "))
+}
+
+// tableName resolves a table name string or a model's table
+func tableName(db *gorm.DB, model interface{}) (string, error) {
+	if name, ok := model.(string); ok {
+		return name, nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", fmt.Errorf("failed to resolve table for %T: %w", model, err)
+	}
+	return stmt.Schema.Table, nil
+}
+
+// interval renders a duration as a PostgreSQL interval literal
+func interval(d time.Duration) string {
+	return fmt.Sprintf("%d microseconds", d.Microseconds())
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
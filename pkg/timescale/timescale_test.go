@@ -0,0 +1,40 @@
+package timescale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type reading struct {
+	ID         int
+	SensorID   int
+	RecordedAt time.Time
+}
+
+func TestCompressionStatement(t *testing.T) {
+	assert.Equal(t, "ALTER TABLE \"readings\" SET (timescaledb.compress)", compressionStatement("readings", CompressionOptions{}))
+	assert.Equal(t,
+		`ALTER TABLE "readings" SET (timescaledb.compress, timescaledb.compress_segmentby = '"sensor_id", "site"', timescaledb.compress_orderby = 'recorded_at DESC')`,
+		compressionStatement("readings", CompressionOptions{SegmentBy: []string{"sensor_id", "site"}, OrderBy: "recorded_at DESC"}),
+	)
+}
+
+func TestTableNameAndInterval(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	table, err := tableName(db, &reading{})
+	require.NoError(t, err)
+	assert.Equal(t, "readings", table)
+	table, err = tableName(db, "events")
+	require.NoError(t, err)
+	assert.Equal(t, "events", table)
+
+	assert.Equal(t, "86400000000 microseconds", interval(24*time.Hour))
+	assert.Error(t, AddRetentionPolicy(t.Context(), db, &reading{}, 0))
+}
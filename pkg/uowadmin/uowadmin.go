@@ -0,0 +1,336 @@
+// Package uowadmin provides a mountable HTTP handler with a minimal operator surface
+//
+// The handler serves JSON endpoints for database health, connection pool statistics, the slow
+// query log and trashed-row counts, plus restore and purge actions on registered entities.
+// Every endpoint except /health goes through the Authorize hook, which New requires:
+//
+//	admin, err := uowadmin.New(db, uowadmin.Options{
+//		Authorize:   requireRole("ops"),
+//		SlowQueries: config.SlowQueries,
+//	})
+//	uowadmin.Register[*User](admin, "users", userFactory)
+//	mux.Handle("/admin/", http.StripPrefix("/admin", admin))
+//
+// Endpoints:
+//
+//	GET  /health                                   {"status": "ok"}, 503 when the database does not answer
+//	GET  /pool                                     database/sql pool statistics
+//	GET  /slow-queries                             slow query log, newest first
+//	GET  /trash                                    trashed rows per registered entity
+//	POST /trash/{entity}/restore?id=N | ?all=true  restore trashed rows
+//	POST /trash/{entity}/purge?id=N&older_than=720h&confirm=true
+package uowadmin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+// DefaultHealthTimeout bounds the database ping of /health when Options.HealthTimeout is not set
+const DefaultHealthTimeout = 2 * time.Second
+
+// Options configures the admin handler
+type Options struct {
+	// Authorize admits a request or returns an error, answered with 403; required
+	Authorize func(r *http.Request) error
+	// SlowQueries backs /slow-queries; without it the endpoint answers 404
+	SlowQueries *postgres.SlowQueryLog
+	// HealthTimeout bounds the database ping, default 2 seconds
+	HealthTimeout time.Duration
+}
+
+// Handler serves the admin endpoints
+type Handler struct {
+	db       *gorm.DB
+	options  Options
+	mux      *http.ServeMux
+	mu       sync.RWMutex
+	entities map[string]entity
+}
+
+// entity adapts a registered model type to the trash endpoints
+type entity interface {
+	trashed(ctx context.Context) (uint, error)
+	restore(ctx context.Context, id identifier.IIdentifier, all bool) (int64, error)
+	purge(ctx context.Context, id identifier.IIdentifier) (int64, error)
+}
+
+// New creates the admin handler for a database
+func New(db *gorm.DB, options Options) (*Handler, error) {
+	if options.Authorize == nil {
+		return nil, errors.New("uowadmin: an Authorize hook is required")
+	}
+	if options.HealthTimeout <= 0 {
+		options.HealthTimeout = DefaultHealthTimeout
+	}
+	h := &Handler{db: db, options: options, mux: http.NewServeMux(), entities: make(map[string]entity)}
+	h.mux.HandleFunc("GET /health", h.health)
+	h.mux.HandleFunc("GET /pool", h.authorized(h.pool))
+	h.mux.HandleFunc("GET /slow-queries", h.authorized(h.slowQueries))
+	h.mux.HandleFunc("GET /trash", h.authorized(h.trash))
+	h.mux.HandleFunc("POST /trash/{entity}/restore", h.authorized(h.restore))
+	h.mux.HandleFunc("POST /trash/{entity}/purge", h.authorized(h.purge))
+	return h, nil
+}
+
+// Register exposes an entity type to the trash endpoints under a name
+func Register[T domain.BaseModel](h *Handler, name string, factory persistence.IUnitOfWorkFactory[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entities[name] = &typedEntity[T]{factory: factory}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.options.Authorize(r); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *Handler) health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.options.HealthTimeout)
+	defer cancel()
+	sqlDB, err := h.db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// poolStats is sql.DBStats with durations in readable form
+type poolStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+
+	StatementExecutions *uint64  `json:"statement_executions,omitempty"`
+	StatementHitRate    *float64 `json:"statement_hit_rate,omitempty"`
+}
+
+func (h *Handler) pool(w http.ResponseWriter, _ *http.Request) {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newPoolStats(sqlDB.Stats(), h.db))
+}
+
+func newPoolStats(stats sql.DBStats, db *gorm.DB) poolStats {
+	result := poolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration.String(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+	if cache, ok := postgres.StatementCacheStatsOf(db); ok {
+		executions, hitRate := cache.Executions, cache.HitRate()
+		result.StatementExecutions, result.StatementHitRate = &executions, &hitRate
+	}
+	return result
+}
+
+func (h *Handler) slowQueries(w http.ResponseWriter, _ *http.Request) {
+	if h.options.SlowQueries == nil {
+		writeError(w, http.StatusNotFound, errors.New("slow query log is not enabled"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"threshold": h.options.SlowQueries.Threshold().String(),
+		"entries":   h.options.SlowQueries.Entries(),
+	})
+}
+
+func (h *Handler) trash(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.entities))
+	for name := range h.entities {
+		names = append(names, name)
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+
+	counts := make(map[string]uint, len(names))
+	for _, name := range names {
+		n, err := h.entity(name).trashed(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		counts[name] = n
+	}
+	writeJSON(w, http.StatusOK, counts)
+}
+
+func (h *Handler) restore(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	id, err := rowIdentifier(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	all := r.URL.Query().Get("all") == "true"
+	if id.IsEmpty() && !all {
+		writeError(w, http.StatusBadRequest, errors.New("restore needs id=N or all=true"))
+		return
+	}
+	restored, err := e.restore(r.Context(), id, all)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"restored": restored})
+}
+
+func (h *Handler) purge(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	if r.URL.Query().Get("confirm") != "true" {
+		writeError(w, http.StatusBadRequest, errors.New("purge permanently deletes trashed rows; repeat with confirm=true"))
+		return
+	}
+	id, err := rowIdentifier(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if raw := r.URL.Query().Get("older_than"); raw != "" {
+		olderThan, err := time.ParseDuration(raw)
+		if err != nil || olderThan <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid older_than %q", raw))
+			return
+		}
+		id.LessThan("deleted_at", time.Now().Add(-olderThan))
+	}
+	// confirm=true acknowledges purging the whole trash when no id or age narrows it
+	purged, err := e.purge(r.Context(), id.AllowFullTableOperation())
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"purged": purged})
+}
+
+func (h *Handler) entity(name string) entity {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.entities[name]
+}
+
+// lookup resolves the {entity} path segment, answering 404 for unknown names
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request) (entity, bool) {
+	name := r.PathValue("entity")
+	e := h.entity(name)
+	if e == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown entity %q", name))
+		return nil, false
+	}
+	return e, true
+}
+
+// rowIdentifier reads the optional id query parameter
+func rowIdentifier(r *http.Request) (identifier.IIdentifier, error) {
+	id := identifier.New()
+	raw := r.URL.Query().Get("id")
+	if raw == "" {
+		return id, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q", raw)
+	}
+	return id.Equal("id", n), nil
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, uowerrors.ErrEntityNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, uowerrors.ErrInvalidQueryParams), errors.Is(err, uowerrors.ErrUnscopedOperation):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// typedEntity runs the trash endpoints through units of work of one entity type
+type typedEntity[T domain.BaseModel] struct {
+	factory persistence.IUnitOfWorkFactory[T]
+}
+
+func (e *typedEntity[T]) trashed(ctx context.Context) (uint, error) {
+	uow := e.factory.CreateReadOnly(ctx)
+	_, total, err := uow.GetTrashedWithPagination(ctx, domain.QueryParams[T]{Limit: 1})
+	return total, err
+}
+
+func (e *typedEntity[T]) restore(ctx context.Context, id identifier.IIdentifier, all bool) (int64, error) {
+	uow := e.factory.CreateWithContext(ctx)
+	defer uow.Close()
+	if all {
+		return uow.RestoreAllWhere(ctx, id.AllowFullTableOperation())
+	}
+	if _, err := uow.Restore(ctx, id); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (e *typedEntity[T]) purge(ctx context.Context, id identifier.IIdentifier) (int64, error) {
+	uow := e.factory.CreateWithContext(ctx)
+	defer uow.Close()
+	return uow.PurgeTrashed(ctx, id)
+}
@@ -0,0 +1,158 @@
+package uowadmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	uowerrors "github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/errors"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+type invoice struct {
+	ID        int
+	DeletedAt gorm.DeletedAt
+}
+
+func (i *invoice) GetID() int                    { return i.ID }
+func (i *invoice) GetSlug() string               { return "" }
+func (i *invoice) SetSlug(string)                {}
+func (i *invoice) GetCreatedAt() time.Time       { return time.Time{} }
+func (i *invoice) GetUpdatedAt() time.Time       { return time.Time{} }
+func (i *invoice) GetArchivedAt() gorm.DeletedAt { return i.DeletedAt }
+func (i *invoice) GetName() string               { return "" }
+
+// trashUnitOfWork serves a fixed set of trashed IDs; unused methods panic through the nil embedded interface
+type trashUnitOfWork struct {
+	persistence.IUnitOfWork[*invoice]
+	trashed map[int]bool
+	purges  []map[string]interface{}
+}
+
+func (u *trashUnitOfWork) Close() error { return nil }
+
+func (u *trashUnitOfWork) GetTrashedWithPagination(context.Context, domain.QueryParams[*invoice]) ([]*invoice, uint, error) {
+	return nil, uint(len(u.trashed)), nil
+}
+
+func (u *trashUnitOfWork) Restore(_ context.Context, id identifier.IIdentifier) (*invoice, error) {
+	value, _ := id.Get("id")
+	if !u.trashed[value.(int)] {
+		return nil, uowerrors.ErrEntityNotFound
+	}
+	delete(u.trashed, value.(int))
+	return &invoice{ID: value.(int)}, nil
+}
+
+func (u *trashUnitOfWork) RestoreAllWhere(context.Context, identifier.IIdentifier) (int64, error) {
+	n := int64(len(u.trashed))
+	u.trashed = map[int]bool{}
+	return n, nil
+}
+
+func (u *trashUnitOfWork) PurgeTrashed(_ context.Context, id identifier.IIdentifier) (int64, error) {
+	u.purges = append(u.purges, id.ToMap())
+	return 2, nil
+}
+
+type trashFactory struct{ uow *trashUnitOfWork }
+
+func (f *trashFactory) Create() persistence.IUnitOfWork[*invoice] { return f.uow }
+
+func (f *trashFactory) CreateWithContext(context.Context) persistence.IUnitOfWork[*invoice] {
+	return f.uow
+}
+
+func (f *trashFactory) CreateReadOnly(context.Context) persistence.IReadOnlyUnitOfWork[*invoice] {
+	return f.uow
+}
+
+func serve(t *testing.T, h http.Handler, method, target string) (int, map[string]interface{}) {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("X-Role", "ops")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHandler(t *testing.T) {
+	_, err := New(nil, Options{})
+	assert.Error(t, err, "authorization is required")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	slow := postgres.NewSlowQueryLog(0, 10)
+	require.NoError(t, postgres.UseSlowQueryLog(db, slow))
+	require.NoError(t, db.Exec("SELECT 1").Error)
+
+	uow := &trashUnitOfWork{trashed: map[int]bool{1: true, 2: true, 3: true}}
+	admin, err := New(db, Options{
+		SlowQueries: slow,
+		Authorize: func(r *http.Request) error {
+			if r.Header.Get("X-Role") != "ops" {
+				return errors.New("operators only")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	Register[*invoice](admin, "invoices", &trashFactory{uow: uow})
+
+	status, body := serve(t, admin, http.MethodGet, "/health")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", body["status"])
+
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pool", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	status, body = serve(t, admin, http.MethodGet, "/pool")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "open_connections")
+
+	status, body = serve(t, admin, http.MethodGet, "/slow-queries")
+	assert.Equal(t, http.StatusOK, status)
+	require.NotEmpty(t, body["entries"])
+	assert.Equal(t, "SELECT 1", body["entries"].([]interface{})[0].(map[string]interface{})["sql"])
+
+	status, body = serve(t, admin, http.MethodGet, "/trash")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"invoices": 3.0}, body)
+
+	status, _ = serve(t, admin, http.MethodPost, "/trash/invoices/restore")
+	assert.Equal(t, http.StatusBadRequest, status, "restore needs an id or all")
+	status, body = serve(t, admin, http.MethodPost, "/trash/invoices/restore?id=2")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1.0, body["restored"])
+	status, _ = serve(t, admin, http.MethodPost, "/trash/invoices/restore?id=2")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, body = serve(t, admin, http.MethodPost, "/trash/invoices/restore?all=true")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 2.0, body["restored"])
+	status, _ = serve(t, admin, http.MethodPost, "/trash/orders/restore?all=true")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = serve(t, admin, http.MethodPost, "/trash/invoices/purge?id=4")
+	assert.Equal(t, http.StatusBadRequest, status, "purge needs confirmation")
+	status, body = serve(t, admin, http.MethodPost, "/trash/invoices/purge?id=4&older_than=720h&confirm=true")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 2.0, body["purged"])
+	require.Len(t, uow.purges, 1)
+	assert.Equal(t, 4, uow.purges[0]["id"])
+	assert.Contains(t, uow.purges[0], "deleted_at <")
+}
@@ -0,0 +1,613 @@
+// Package uowcli implements an operational command line tool over registered entities
+//
+// Build a binary for your own models by registering them and calling Main:
+//
+//	app := uowcli.New(postgres.NewConfig())
+//	uowcli.Register[*User](app, "users")
+//	os.Exit(app.Main(os.Args[1:]))
+package uowcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/identifier"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const usage = `usage: %s [connection flags] <command> [arguments]
+
+Commands:
+  entities                                  list registered entities
+  list     <entity> [-limit N] [-offset N] [-sort col,-col]
+  get      <entity> <id>
+  query    <entity> <filter expression> [-limit N] [-sort col,-col]
+  trashed  <entity> [-limit N] [-offset N]
+  restore  <entity> <id> | -all
+  purge    <entity> [<id>] [-older-than 720h] -yes
+  export   <entity> [-with-trashed] [-o file] NDJSON to stdout or a file
+  import   <entity> [-i file] [-batch N]    NDJSON or a JSON array, keeping IDs and deleted_at
+  migrate  [entity ...]                     all registered models when no entity is given
+  seed     [-profile minimal|demo|load-test] [-size N] [-set name=N]
+                                            run the seeders registered for a profile
+
+Filter expressions: status eq 'active' and created_at gt '2024-01-01'
+Connection flags default to UOW_HOST, UOW_PORT, UOW_USER, UOW_PASSWORD, UOW_DATABASE and UOW_SSLMODE.
+
+Connection flags:
+`
+
+// errUsage signals that usage was printed for a malformed command
+var errUsage = errors.New("invalid usage")
+
+// App holds the connection configuration and the registered entities
+type App struct {
+	Config *postgres.Config
+	Out    io.Writer
+	Err    io.Writer
+
+	// DB, when set before registering entities, is used instead of connecting with Config and is
+	// left open; for tools embedded in an application, or SQLite in tests
+	DB *gorm.DB
+
+	entities map[string]entity
+	models   map[string]interface{}
+}
+
+// entity adapts a registered model type to the commands
+type entity interface {
+	list(ctx context.Context, id identifier.IIdentifier, limit, offset int, sort domain.SortMap) (interface{}, uint, error)
+	get(ctx context.Context, id int) (interface{}, error)
+	trashed(ctx context.Context, limit, offset int) (interface{}, uint, error)
+	restore(ctx context.Context, id int) (interface{}, error)
+	restoreAll(ctx context.Context) (int64, error)
+	exportJSON(ctx context.Context, w io.Writer, withTrashed bool) (int64, error)
+	importJSON(ctx context.Context, r io.Reader, batchSize int) (int64, error)
+	purge(ctx context.Context, id identifier.IIdentifier) (int64, error)
+	columns() []string
+}
+
+// New creates an application using the given configuration as flag defaults
+func New(config *postgres.Config) *App {
+	return &App{
+		Config:   config,
+		Out:      os.Stdout,
+		Err:      os.Stderr,
+		entities: make(map[string]entity),
+		models:   make(map[string]interface{}),
+	}
+}
+
+// Register exposes an entity type to the commands under a name
+// The type is also added to the config's model registry, so migrate and seed cover it
+func Register[T domain.BaseModel](app *App, name string) {
+	var model T
+	factory := postgres.NewUnitOfWorkFactory[T](app.Config)
+	if app.DB != nil {
+		factory = postgres.NewUnitOfWorkFactoryFromDB[T](app.DB)
+		factory.Config = app.Config
+	}
+	app.entities[name] = &typedEntity[T]{factory: factory.RegisterModel()}
+	app.models[name] = model
+}
+
+// Main runs the command line and returns a process exit code
+func (a *App) Main(args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := a.Run(ctx, args); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(a.Err, "error:", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+// Run parses connection flags and executes one command
+func (a *App) Run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("uow", flag.ContinueOnError)
+	flags.SetOutput(a.Err)
+	flags.Usage = func() {
+		fmt.Fprintf(a.Err, usage, flags.Name())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&a.Config.Host, "host", env("UOW_HOST", a.Config.Host), "database host")
+	flags.IntVar(&a.Config.Port, "port", envInt("UOW_PORT", a.Config.Port), "database port")
+	flags.StringVar(&a.Config.User, "user", env("UOW_USER", a.Config.User), "database user")
+	flags.StringVar(&a.Config.Password, "password", env("UOW_PASSWORD", a.Config.Password), "database password")
+	flags.StringVar(&a.Config.Database, "database", env("UOW_DATABASE", a.Config.Database), "database name")
+	flags.StringVar(&a.Config.SSLMode, "sslmode", env("UOW_SSLMODE", a.Config.SSLMode), "SSL mode")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "entities":
+		return a.printJSON(a.names())
+	case "migrate":
+		return a.migrate(ctx, args)
+	case "seed":
+		return a.seed(ctx, args)
+	case "list", "get", "query", "trashed", "restore", "purge", "export", "import":
+	default:
+		flags.Usage()
+		return errUsage
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("%s: missing entity name (one of %s)", command, strings.Join(a.names(), ", "))
+	}
+	e, ok := a.entities[args[0]]
+	if !ok {
+		return fmt.Errorf("%s: unknown entity %q (one of %s)", command, args[0], strings.Join(a.names(), ", "))
+	}
+	args = args[1:]
+
+	switch command {
+	case "list":
+		return a.list(ctx, e, args)
+	case "get":
+		return a.get(ctx, e, args)
+	case "query":
+		return a.query(ctx, e, args)
+	case "trashed":
+		return a.trashed(ctx, e, args)
+	case "restore":
+		return a.restore(ctx, e, args)
+	case "export":
+		return a.exportJSON(ctx, e, args)
+	case "import":
+		return a.importJSON(ctx, e, args)
+	default:
+		return a.purge(ctx, e, args)
+	}
+}
+
+func (a *App) list(ctx context.Context, e entity, args []string) error {
+	flags, limit, offset, sortSpec := pagingFlags("list")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	sort, err := parseSort(*sortSpec, e.columns())
+	if err != nil {
+		return err
+	}
+
+	items, total, err := e.list(ctx, nil, *limit, *offset, sort)
+	if err != nil {
+		return err
+	}
+	return a.printJSON(map[string]interface{}{"total": total, "items": items})
+}
+
+func (a *App) get(ctx context.Context, e entity, args []string) error {
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+	item, err := e.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.printJSON(item)
+}
+
+func (a *App) query(ctx context.Context, e entity, args []string) error {
+	if len(args) == 0 {
+		return errors.New("query: missing filter expression")
+	}
+	expr, args := args[0], args[1:]
+
+	flags, limit, offset, sortSpec := pagingFlags("query")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	sort, err := parseSort(*sortSpec, e.columns())
+	if err != nil {
+		return err
+	}
+	id, err := identifier.ParseFilter(expr, identifier.AllowFields(e.columns()...))
+	if err != nil {
+		return err
+	}
+
+	items, total, err := e.list(ctx, id, *limit, *offset, sort)
+	if err != nil {
+		return err
+	}
+	return a.printJSON(map[string]interface{}{"total": total, "items": items})
+}
+
+func (a *App) trashed(ctx context.Context, e entity, args []string) error {
+	flags, limit, offset, _ := pagingFlags("trashed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	items, total, err := e.trashed(ctx, *limit, *offset)
+	if err != nil {
+		return err
+	}
+	return a.printJSON(map[string]interface{}{"total": total, "items": items})
+}
+
+func (a *App) restore(ctx context.Context, e entity, args []string) error {
+	if len(args) == 1 && args[0] == "-all" {
+		restored, err := e.restoreAll(ctx)
+		if err != nil {
+			return err
+		}
+		return a.printJSON(map[string]int64{"restored": restored})
+	}
+
+	id, err := parseID(args)
+	if err != nil {
+		return err
+	}
+	item, err := e.restore(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.printJSON(item)
+}
+
+func (a *App) purge(ctx context.Context, e entity, args []string) error {
+	var rowID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		rowID, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	olderThan := flags.Duration("older-than", 0, "only purge rows deleted longer ago than this")
+	yes := flags.Bool("yes", false, "confirm the permanent deletion")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !*yes {
+		return errors.New("purge: permanently deletes trashed rows; re-run with -yes to confirm")
+	}
+
+	id := identifier.New()
+	if rowID != "" {
+		n, err := strconv.Atoi(rowID)
+		if err != nil {
+			return fmt.Errorf("purge: invalid id %q", rowID)
+		}
+		id.Equal("id", n)
+	}
+	if *olderThan > 0 {
+		id.LessThan("deleted_at", time.Now().Add(-*olderThan))
+	}
+
+	// -yes acknowledges purging the whole trash when no id or age narrows it
+	purged, err := e.purge(ctx, id.AllowFullTableOperation())
+	if err != nil {
+		return err
+	}
+	return a.printJSON(map[string]int64{"purged": purged})
+}
+
+// exportJSON writes an entity set, optionally with its trashed rows, for cloning into another environment
+func (a *App) exportJSON(ctx context.Context, e entity, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	withTrashed := flags.Bool("with-trashed", false, "include soft-deleted rows with their deleted_at")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	w := a.Out
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	exported, err := e.exportJSON(ctx, w, *withTrashed)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if *output != "" {
+		return a.printJSON(map[string]int64{"exported": exported})
+	}
+	return nil
+}
+
+// importJSON loads an export, keeping IDs, and moves the ID sequence past them
+func (a *App) importJSON(ctx context.Context, e entity, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	input := flags.String("i", "", "read from this file instead of stdin")
+	batch := flags.Int("batch", postgres.DefaultJSONImportBatchSize, "rows per upsert statement")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	imported, err := e.importJSON(ctx, r, *batch)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	return a.printJSON(map[string]int64{"imported": imported})
+}
+
+func (a *App) migrate(ctx context.Context, names []string) error {
+	registry := a.Config.ModelRegistry()
+	if len(names) > 0 {
+		registry = postgres.NewModelRegistry()
+		for _, name := range names {
+			model, ok := a.models[name]
+			if !ok {
+				return fmt.Errorf("migrate: unknown entity %q", name)
+			}
+			registry.Register(model)
+		}
+	}
+
+	db, closeDB, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if err := registry.Migrate(ctx, db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	tables := make([]string, 0)
+	for _, model := range registry.Models() {
+		if s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy); err == nil {
+			tables = append(tables, s.Table)
+		}
+	}
+	return a.printJSON(map[string][]string{"migrated": tables})
+}
+
+func (a *App) seed(ctx context.Context, args []string) error {
+	registry := a.Config.ModelRegistry()
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	name := flags.String("profile", postgres.SeedMinimal, "seed profile")
+	size := flags.Int("size", 0, "override the profile's base row count")
+	params := make(map[string]int)
+	flags.Func("set", "override a named size, e.g. posts_per_user=20 (repeatable)", func(value string) error {
+		key, n, ok := strings.Cut(value, "=")
+		count, err := strconv.Atoi(n)
+		if !ok || key == "" || err != nil {
+			return fmt.Errorf("expected name=N, got %q", value)
+		}
+		params[key] = count
+		return nil
+	})
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	profile, err := registry.Profile(*name)
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	if *size > 0 {
+		profile.Size = *size
+	}
+	for key, count := range params {
+		profile = profile.With(key, count)
+	}
+
+	db, closeDB, err := a.connect()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if err := registry.SeedWithProfile(ctx, db, profile); err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	return a.printJSON(map[string]interface{}{"seeded": true, "profile": profile.Name, "size": profile.Size})
+}
+
+func (a *App) connect() (*gorm.DB, func(), error) {
+	if a.DB != nil {
+		return a.DB, func() {}, nil
+	}
+	db, err := postgres.Connect(a.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}, nil
+}
+
+func (a *App) names() []string {
+	names := make([]string, 0, len(a.entities))
+	for name := range a.entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *App) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(a.Out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// typedEntity runs commands through a unit of work for one entity type
+type typedEntity[T domain.BaseModel] struct {
+	factory *postgres.UnitOfWorkFactory[T]
+
+	once sync.Once
+	cols []string
+}
+
+// uow creates the unit of work for one command; callers close it when done
+func (e *typedEntity[T]) uow(ctx context.Context) persistence.IUnitOfWork[T] {
+	return e.factory.CreateWithContext(ctx)
+}
+
+func (e *typedEntity[T]) list(ctx context.Context, id identifier.IIdentifier, limit, offset int, sort domain.SortMap) (interface{}, uint, error) {
+	query := domain.QueryParams[T]{Limit: limit, Offset: offset, Sort: sort}
+	if err := query.Validate(); err != nil {
+		return nil, 0, err
+	}
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.FindAllByIdentifier(ctx, id, query)
+}
+
+func (e *typedEntity[T]) get(ctx context.Context, id int) (interface{}, error) {
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.FindOneById(ctx, id)
+}
+
+func (e *typedEntity[T]) trashed(ctx context.Context, limit, offset int) (interface{}, uint, error) {
+	query := domain.QueryParams[T]{Limit: limit, Offset: offset}
+	if err := query.Validate(); err != nil {
+		return nil, 0, err
+	}
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.GetTrashedWithPagination(ctx, query)
+}
+
+func (e *typedEntity[T]) restore(ctx context.Context, id int) (interface{}, error) {
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.Restore(ctx, identifier.ByID(id))
+}
+
+func (e *typedEntity[T]) restoreAll(ctx context.Context) (int64, error) {
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.RestoreAllWhere(ctx, identifier.New().AllowFullTableOperation())
+}
+
+func (e *typedEntity[T]) exportJSON(ctx context.Context, w io.Writer, withTrashed bool) (int64, error) {
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.ExportJSON(ctx, domain.QueryParams[T]{}, domain.JSONExportOptions{IncludeTrashed: withTrashed}, w)
+}
+
+func (e *typedEntity[T]) importJSON(ctx context.Context, r io.Reader, batchSize int) (int64, error) {
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.ImportJSON(ctx, r, domain.JSONImportOptions{BatchSize: batchSize, ResetSequence: true})
+}
+
+func (e *typedEntity[T]) purge(ctx context.Context, id identifier.IIdentifier) (int64, error) {
+	uow := e.uow(ctx)
+	defer uow.Close()
+	return uow.PurgeTrashed(ctx, id)
+}
+
+// columns lists the entity's database columns, used as the filter and sort whitelist
+func (e *typedEntity[T]) columns() []string {
+	e.once.Do(func() {
+		var model T
+		s, err := schema.Parse(model, &sync.Map{}, e.factory.NamingStrategy())
+		if err != nil {
+			return
+		}
+		e.cols = s.DBNames
+	})
+	return e.cols
+}
+
+// pagingFlags declares the -limit, -offset and -sort flags shared by listing commands
+func pagingFlags(name string) (*flag.FlagSet, *int, *int, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "maximum number of rows")
+	offset := flags.Int("offset", 0, "rows to skip")
+	sort := flags.String("sort", "", "comma separated columns, prefix with - for descending")
+	return flags, limit, offset, sort
+}
+
+// parseSort parses "name,-created_at" against the entity's columns
+func parseSort(spec string, columns []string) (domain.SortMap, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	sort := make(domain.SortMap)
+	for _, field := range strings.Split(spec, ",") {
+		direction := domain.SortAsc
+		if strings.HasPrefix(field, "-") {
+			field, direction = field[1:], domain.SortDesc
+		}
+		if !contains(columns, field) {
+			return nil, fmt.Errorf("unknown sort column %q", field)
+		}
+		sort[field] = direction
+	}
+	return sort, nil
+}
+
+func parseID(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("expected exactly one id")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", args[0])
+	}
+	return id, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func env(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
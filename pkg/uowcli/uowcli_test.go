@@ -0,0 +1,173 @@
+package uowcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/postgres"
+)
+
+type ticket struct {
+	ID        int            `gorm:"primaryKey" json:"id"`
+	Title     string         `json:"title"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+func (t *ticket) GetID() int                    { return t.ID }
+func (t *ticket) GetSlug() string               { return "" }
+func (t *ticket) SetSlug(string)                {}
+func (t *ticket) GetCreatedAt() time.Time       { return t.CreatedAt }
+func (t *ticket) GetUpdatedAt() time.Time       { return t.UpdatedAt }
+func (t *ticket) GetArchivedAt() gorm.DeletedAt { return t.DeletedAt }
+func (t *ticket) GetName() string               { return t.Title }
+
+// testApp drives an App over a SQLite file, capturing its output
+type testApp struct {
+	*App
+	db  *gorm.DB
+	out *bytes.Buffer
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "uow.db")), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	app := New(&postgres.Config{Models: postgres.NewModelRegistry()})
+	app.DB = db
+	out := &bytes.Buffer{}
+	app.Out, app.Err = out, &bytes.Buffer{}
+	Register[*ticket](app, "tickets")
+
+	a := &testApp{App: app, db: db, out: out}
+	a.run(t, "migrate")
+	return a
+}
+
+// run executes a command that must succeed and decodes its JSON output
+func (a *testApp) run(t *testing.T, args ...string) map[string]interface{} {
+	t.Helper()
+	a.out.Reset()
+	require.NoError(t, a.Run(context.Background(), args))
+	var result map[string]interface{}
+	if a.out.Len() > 0 {
+		require.NoError(t, json.Unmarshal(a.out.Bytes(), &result))
+	}
+	return result
+}
+
+func (a *testApp) seed(t *testing.T, titles ...string) []int {
+	t.Helper()
+	ids := make([]int, 0, len(titles))
+	for _, title := range titles {
+		row := &ticket{Title: title}
+		require.NoError(t, a.db.Create(row).Error)
+		ids = append(ids, row.ID)
+	}
+	return ids
+}
+
+func (a *testApp) count(t *testing.T, trashed bool) int64 {
+	t.Helper()
+	var n int64
+	db := a.db.Unscoped().Model(&ticket{})
+	if trashed {
+		db = db.Where("deleted_at IS NOT NULL")
+	} else {
+		db = db.Where("deleted_at IS NULL")
+	}
+	require.NoError(t, db.Count(&n).Error)
+	return n
+}
+
+func TestApp_Commands(t *testing.T) {
+	app := newTestApp(t)
+	ids := app.seed(t, "Printer jam", "VPN down", "Coffee machine")
+
+	assert.Equal(t, []interface{}{"tickets"}, app.run(t, "migrate")["migrated"])
+	assert.Equal(t, float64(3), app.run(t, "list", "tickets", "-sort", "-id")["total"])
+	assert.Equal(t, "VPN down", app.run(t, "get", "tickets", "2")["title"])
+	result := app.run(t, "query", "tickets", "title eq 'VPN down'")
+	assert.Equal(t, float64(1), result["total"])
+
+	ctx := context.Background()
+	assert.Error(t, app.Run(ctx, []string{"list", "orders"}))
+	assert.Error(t, app.Run(ctx, []string{"list", "tickets", "-sort", "password"}))
+	assert.Error(t, app.Run(ctx, []string{"query", "tickets", "password eq 'x'"}))
+	assert.Error(t, app.Run(ctx, []string{"get", "tickets", strconv.Itoa(ids[2] + 100)}))
+}
+
+func TestApp_Restore(t *testing.T) {
+	app := newTestApp(t)
+	ids := app.seed(t, "a", "b", "c", "d")
+	require.NoError(t, app.db.Delete(&ticket{}, ids[:3]).Error)
+
+	assert.Equal(t, float64(3), app.run(t, "trashed", "tickets")["total"])
+	assert.Equal(t, "a", app.run(t, "restore", "tickets", "1")["title"])
+	assert.Equal(t, int64(2), app.count(t, true))
+
+	assert.Equal(t, float64(2), app.run(t, "restore", "tickets", "-all")["restored"])
+	assert.Zero(t, app.count(t, true))
+	assert.Equal(t, int64(4), app.count(t, false))
+}
+
+func TestApp_Purge(t *testing.T) {
+	app := newTestApp(t)
+	ids := app.seed(t, "a", "b", "c", "live")
+	require.NoError(t, app.db.Delete(&ticket{}, ids[:3]).Error)
+	ctx := context.Background()
+
+	// Nothing is deleted without confirmation
+	assert.Error(t, app.Run(ctx, []string{"purge", "tickets"}))
+	assert.Error(t, app.Run(ctx, []string{"purge", "tickets", "x", "-yes"}))
+	assert.Equal(t, int64(3), app.count(t, true))
+
+	assert.Equal(t, float64(1), app.run(t, "purge", "tickets", "1", "-yes")["purged"])
+	assert.Equal(t, float64(0), app.run(t, "purge", "tickets", "-older-than", "1h", "-yes")["purged"])
+	assert.Equal(t, float64(2), app.run(t, "purge", "tickets", "-yes")["purged"])
+	assert.Zero(t, app.count(t, true))
+
+	// Live rows are never purged
+	assert.Equal(t, float64(0), app.run(t, "purge", "tickets", "4", "-yes")["purged"])
+	assert.Equal(t, int64(1), app.count(t, false))
+}
+
+func TestApp_ExportImport(t *testing.T) {
+	source := newTestApp(t)
+	ids := source.seed(t, "a", "b", "c")
+	require.NoError(t, source.db.Delete(&ticket{}, ids[1]).Error)
+
+	file := filepath.Join(t.TempDir(), "tickets.ndjson")
+	assert.Equal(t, float64(3), source.run(t, "export", "tickets", "-with-trashed", "-o", file)["exported"])
+	// NDJSON on stdout, live rows only
+	source.out.Reset()
+	require.NoError(t, source.Run(context.Background(), []string{"export", "tickets"}))
+	assert.Equal(t, 2, bytes.Count(source.out.Bytes(), []byte("\n")))
+
+	// The import keeps IDs and soft deletes, and is idempotent
+	target := newTestApp(t)
+	assert.Equal(t, float64(3), target.run(t, "import", "tickets", "-i", file, "-batch", "2")["imported"])
+	assert.Equal(t, float64(3), target.run(t, "import", "tickets", "-i", file)["imported"])
+	assert.Equal(t, int64(2), target.count(t, false))
+	assert.Equal(t, int64(1), target.count(t, true))
+	assert.Equal(t, "c", target.run(t, "get", "tickets", "3")["title"])
+
+	assert.Error(t, target.Run(context.Background(), []string{"import", "tickets", "-i", filepath.Join(t.TempDir(), "missing")}))
+}
@@ -0,0 +1,167 @@
+// Package uowhttp provides a unit of work per HTTP request
+//
+// Middleware opens a transaction before the handler runs and finishes it when the handler
+// writes its status: 2xx commits, anything else (or a panic) rolls back. The commit happens
+// before the status reaches the client, so a failed commit is reported as a 500 rather than
+// lost behind a success response. Handlers fetch the unit of work with FromContext.
+//
+// The middleware has the standard func(http.Handler) http.Handler shape, so it plugs into
+// net/http, chi, gorilla/mux and any other router built on http.Handler.
+package uowhttp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/domain"
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+// Options configures Middleware
+type Options struct {
+	// SkipTransaction leaves the unit of work without a transaction for matching requests,
+	// e.g. safe methods; default: every request gets one
+	SkipTransaction func(r *http.Request) bool
+	// OnError writes the response when beginning or committing the transaction fails;
+	// default: a plain 500
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// SafeMethods skips the transaction for GET, HEAD and OPTIONS requests
+func SafeMethods(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func (o Options) onError(w http.ResponseWriter, r *http.Request, err error) {
+	if o.OnError != nil {
+		o.OnError(w, r, err)
+		return
+	}
+	log.Printf("uowhttp: %s %s: %v", r.Method, r.URL.Path, err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// uowKey is a per-entity-type context key
+type uowKey[T domain.BaseModel] struct{}
+
+// WithUnitOfWork returns a context carrying the unit of work for the entity type
+func WithUnitOfWork[T domain.BaseModel](ctx context.Context, uow persistence.IUnitOfWork[T]) context.Context {
+	return context.WithValue(ctx, uowKey[T]{}, uow)
+}
+
+// FromContext returns the request's unit of work for the entity type
+func FromContext[T domain.BaseModel](ctx context.Context) (persistence.IUnitOfWork[T], bool) {
+	uow, ok := ctx.Value(uowKey[T]{}).(persistence.IUnitOfWork[T])
+	return uow, ok
+}
+
+// MustFromContext is FromContext for handlers mounted behind Middleware; it panics otherwise
+func MustFromContext[T domain.BaseModel](ctx context.Context) persistence.IUnitOfWork[T] {
+	uow, ok := FromContext[T](ctx)
+	if !ok {
+		var zero T
+		panic(fmt.Sprintf("uowhttp: no unit of work for %T in context; is the middleware installed?", zero))
+	}
+	return uow
+}
+
+// Middleware creates a unit of work per request from the factory and finishes it with the response
+// Stack one per entity type a handler needs; each commits independently
+func Middleware[T domain.BaseModel](factory persistence.IUnitOfWorkFactory[T], options ...Options) func(http.Handler) http.Handler {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			uow := factory.CreateWithContext(ctx)
+			defer uow.Close()
+
+			if opts.SkipTransaction != nil && opts.SkipTransaction(r) {
+				next.ServeHTTP(w, r.WithContext(WithUnitOfWork(ctx, uow)))
+				return
+			}
+			if err := uow.BeginTransaction(ctx); err != nil {
+				opts.onError(w, r, err)
+				return
+			}
+
+			rw := &responseWriter{ResponseWriter: w}
+			rw.finish = func(status int) bool {
+				if status < 200 || status > 299 {
+					uow.RollbackTransaction(ctx)
+					return true
+				}
+				if err := uow.CommitTransaction(ctx); err != nil {
+					opts.onError(w, r, err)
+					return false
+				}
+				return true
+			}
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					if !rw.finished {
+						rw.finished = true
+						uow.RollbackTransaction(ctx)
+					}
+					panic(recovered)
+				}
+				// A handler that wrote nothing answers 200
+				if !rw.finished {
+					rw.WriteHeader(http.StatusOK)
+				}
+			}()
+
+			next.ServeHTTP(rw, r.WithContext(WithUnitOfWork[T](ctx, uow)))
+		})
+	}
+}
+
+// responseWriter finishes the transaction when the handler commits to a status
+type responseWriter struct {
+	http.ResponseWriter
+	finish   func(status int) bool // Reports false when it already wrote an error response
+	finished bool
+	discard  bool // The handler's output is dropped after a failed commit
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *responseWriter) WriteHeader(status int) {
+	if w.finished {
+		return
+	}
+	// Informational responses are not final
+	if status >= 100 && status <= 199 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.finished = true
+	if !w.finish(status) {
+		w.discard = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.finished {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discard {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,146 @@
+package uowhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/arash-mosavi/postgrs-unit-of-work-system/pkg/persistence"
+)
+
+type widget struct {
+	ID int
+}
+
+func (w *widget) GetID() int                    { return w.ID }
+func (w *widget) GetSlug() string               { return "" }
+func (w *widget) SetSlug(string)                {}
+func (w *widget) GetCreatedAt() time.Time       { return time.Time{} }
+func (w *widget) GetUpdatedAt() time.Time       { return time.Time{} }
+func (w *widget) GetArchivedAt() gorm.DeletedAt { return gorm.DeletedAt{} }
+func (w *widget) GetName() string               { return "" }
+
+// recordingUnitOfWork logs transaction calls; unused methods panic through the nil embedded interface
+type recordingUnitOfWork struct {
+	persistence.IUnitOfWork[*widget]
+	calls     []string
+	commitErr error
+}
+
+func (u *recordingUnitOfWork) BeginTransaction(context.Context) error {
+	u.calls = append(u.calls, "begin")
+	return nil
+}
+
+func (u *recordingUnitOfWork) CommitTransaction(context.Context) error {
+	u.calls = append(u.calls, "commit")
+	return u.commitErr
+}
+
+func (u *recordingUnitOfWork) RollbackTransaction(context.Context) {
+	u.calls = append(u.calls, "rollback")
+}
+
+func (u *recordingUnitOfWork) Close() error {
+	u.calls = append(u.calls, "close")
+	return nil
+}
+
+type widgetFactory struct{ uow *recordingUnitOfWork }
+
+func (f *widgetFactory) Create() persistence.IUnitOfWork[*widget] {
+	return f.uow
+}
+
+func (f *widgetFactory) CreateWithContext(context.Context) persistence.IUnitOfWork[*widget] {
+	return f.uow
+}
+
+func (f *widgetFactory) CreateReadOnly(context.Context) persistence.IReadOnlyUnitOfWork[*widget] {
+	return f.uow
+}
+
+func serve(t *testing.T, uow *recordingUnitOfWork, options Options, method string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	h := Middleware[*widget](&widgetFactory{uow: uow}, options)(handler)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, "/widgets", nil))
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("commits before a 2xx status is sent", func(t *testing.T) {
+		uow := &recordingUnitOfWork{}
+		rec := serve(t, uow, Options{}, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			assert.Same(t, uow, MustFromContext[*widget](r.Context()))
+			assert.Equal(t, []string{"begin"}, uow.calls)
+			w.WriteHeader(http.StatusCreated)
+			assert.Equal(t, []string{"begin", "commit"}, uow.calls)
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, []string{"begin", "commit", "close"}, uow.calls)
+	})
+
+	t.Run("commits when the handler writes nothing", func(t *testing.T) {
+		uow := &recordingUnitOfWork{}
+		rec := serve(t, uow, Options{}, http.MethodPost, func(http.ResponseWriter, *http.Request) {})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"begin", "commit", "close"}, uow.calls)
+	})
+
+	t.Run("rolls back error statuses", func(t *testing.T) {
+		uow := &recordingUnitOfWork{}
+		rec := serve(t, uow, Options{}, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad input", http.StatusUnprocessableEntity)
+		})
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, []string{"begin", "rollback", "close"}, uow.calls)
+	})
+
+	t.Run("rolls back and re-panics", func(t *testing.T) {
+		uow := &recordingUnitOfWork{}
+		assert.PanicsWithValue(t, "boom", func() {
+			serve(t, uow, Options{}, http.MethodPost, func(http.ResponseWriter, *http.Request) { panic("boom") })
+		})
+		assert.Equal(t, []string{"begin", "rollback", "close"}, uow.calls)
+	})
+
+	t.Run("reports a failed commit instead of the handler's response", func(t *testing.T) {
+		uow := &recordingUnitOfWork{commitErr: errors.New("serialization failure")}
+		var reported error
+		options := Options{OnError: func(w http.ResponseWriter, r *http.Request, err error) {
+			reported = err
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+		}}
+		rec := serve(t, uow, options, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`{"ok":true}`))
+			require.NoError(t, err)
+		})
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "try again\n", rec.Body.String())
+		assert.EqualError(t, reported, "serialization failure")
+	})
+
+	t.Run("skips the transaction for safe methods", func(t *testing.T) {
+		uow := &recordingUnitOfWork{}
+		rec := serve(t, uow, Options{SkipTransaction: SafeMethods}, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			_, ok := FromContext[*widget](r.Context())
+			assert.True(t, ok)
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"close"}, uow.calls)
+	})
+}
+
+func TestFromContextWithoutMiddleware(t *testing.T) {
+	_, ok := FromContext[*widget](context.Background())
+	assert.False(t, ok)
+	assert.Panics(t, func() { MustFromContext[*widget](context.Background()) })
+}